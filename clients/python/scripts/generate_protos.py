@@ -0,0 +1,55 @@
+"""Generate the orchion.proto package from shared/proto.
+
+The protos are staged under orchion/proto/ before running protoc so the
+generated modules import each other as orchion.proto.v1/v2 rather than as
+top-level v1/v2 packages.
+
+Usage: python scripts/generate_protos.py
+"""
+
+import shutil
+import sys
+from pathlib import Path
+
+from grpc_tools import protoc
+
+ROOT = Path(__file__).resolve().parent.parent
+SHARED_PROTO = ROOT.parent.parent / "shared" / "proto"
+VERSIONS = ["v1", "v2"]
+
+
+def main() -> int:
+    staging = ROOT / "build" / "proto"
+    shutil.rmtree(staging, ignore_errors=True)
+    out = ROOT / "src"
+    package = out / "orchion" / "proto"
+    shutil.rmtree(package, ignore_errors=True)
+
+    files = []
+    for version in VERSIONS:
+        target = staging / "orchion" / "proto" / version
+        target.mkdir(parents=True)
+        shutil.copy(SHARED_PROTO / version / "orchestrator.proto", target)
+        files.append(f"orchion/proto/{version}/orchestrator.proto")
+        (package / version).mkdir(parents=True, exist_ok=True)
+        (package / version / "__init__.py").touch()
+    (package / "__init__.py").touch()
+
+    # grpc_tools ships the well-known types (google/protobuf/*.proto)
+    well_known = Path(protoc.__file__).parent / "_proto"
+    code = protoc.main([
+        "grpc_tools.protoc",
+        f"-I{staging}",
+        f"-I{well_known}",
+        f"--python_out={out}",
+        f"--pyi_out={out}",
+        f"--grpc_python_out={out}",
+        *files,
+    ])
+    if code != 0:
+        print("protoc failed", file=sys.stderr)
+    return code
+
+
+if __name__ == "__main__":
+    sys.exit(main())
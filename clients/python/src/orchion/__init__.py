@@ -0,0 +1,25 @@
+"""Python client for Orchion.
+
+Two entry points cover most uses:
+
+- `orchion.openai_client` returns an OpenAI SDK client pointed at the Orchion
+  gateway, so existing OpenAI code runs on the cluster unchanged.
+- `orchion.Client` talks to the orchestrator's v2 gRPC API to list nodes and
+  submit and track queued jobs.
+
+The generated protobuf modules are available as `orchion.proto.v1` and
+`orchion.proto.v2`.
+"""
+
+from orchion.client import Client, JobFailedError
+from orchion.gateway import DEFAULT_GATEWAY_URL, configure_openai, openai_client
+
+__version__ = "0.1.0"
+
+__all__ = [
+    "Client",
+    "JobFailedError",
+    "DEFAULT_GATEWAY_URL",
+    "configure_openai",
+    "openai_client",
+]
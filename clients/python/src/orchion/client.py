@@ -0,0 +1,207 @@
+"""Client for the orchestrator's v2 gRPC API.
+
+    import orchion
+
+    with orchion.Client("orchion.internal:50051") as client:
+        for node in client.list_nodes(status="online", gpu=True):
+            print(node.id, node.last_seen_time.ToDatetime())
+
+        job = client.submit_embeddings("nomic-embed-text", ["first", "second"])
+        result = client.wait_for_job(job.job_id)
+        vectors = [list(e.embedding) for e in result.data]
+
+Messages are the generated `orchion.proto.v2` types; job payloads and results
+are `orchion.proto.v1` types.
+"""
+
+import time
+import uuid
+from typing import Iterable, Iterator, List, Mapping, Optional, Sequence, Union
+
+import grpc
+
+from orchion.proto.v1 import orchestrator_pb2 as pb
+from orchion.proto.v2 import orchestrator_pb2 as pbv2
+from orchion.proto.v2 import orchestrator_pb2_grpc as pbv2_grpc
+
+DEFAULT_ADDRESS = "localhost:50051"
+"""Orchestrator gRPC address used when none is passed."""
+
+_PAGE_SIZE = 500
+
+# Result message for each job type
+_RESULT_TYPES = {
+    pbv2.JOB_TYPE_CHAT_COMPLETION: pb.ChatCompletionResponse,
+    pbv2.JOB_TYPE_EMBEDDINGS: pb.EmbeddingResponse,
+    pbv2.JOB_TYPE_PIPELINE: pb.PipelineResponse,
+}
+
+Message = Union[Mapping[str, str], "pb.ChatMessage"]
+
+
+class JobFailedError(Exception):
+    """Raised by Client.wait_for_job when a job fails."""
+
+    def __init__(self, status: "pbv2.GetJobStatusResponse"):
+        super().__init__(f"job {status.job_id} failed: {status.error_message}")
+        self.status = status
+
+
+class Client:
+    """Connection to an Orchion orchestrator.
+
+    Args:
+        address: gRPC address of the orchestrator.
+        channel: Use this channel instead of opening an insecure one to
+            address, e.g. a `grpc.secure_channel`.
+    """
+
+    def __init__(self, address: str = DEFAULT_ADDRESS, *, channel: Optional[grpc.Channel] = None):
+        self._owns_channel = channel is None
+        self._channel = channel if channel is not None else grpc.insecure_channel(address)
+        self._stub = pbv2_grpc.OrchestratorStub(self._channel)
+        self._job_types = {}  # Types of jobs submitted through this client
+
+    def close(self) -> None:
+        """Close the channel if the client opened it."""
+        if self._owns_channel:
+            self._channel.close()
+
+    def __enter__(self) -> "Client":
+        return self
+
+    def __exit__(self, *exc) -> None:
+        self.close()
+
+    # --- Nodes ---
+
+    def iter_nodes(
+        self,
+        *,
+        status: str = "",
+        labels: str = "",
+        gpu: Optional[bool] = None,
+        order_by: str = "",
+    ) -> Iterator["pbv2.Node"]:
+        """Yield nodes matching the filters, fetching pages as needed.
+
+        Args:
+            status: "online" or "stale"; empty matches any.
+            labels: Label selector, e.g. "zone=eu-west,tier!=spot".
+            gpu: Only nodes with (True) or without (False) a usable GPU.
+            order_by: "id", "vram_free" or "last_seen", "-" prefix for descending.
+        """
+        request = pbv2.ListNodesRequest(
+            page_size=_PAGE_SIZE,
+            status=status,
+            label_selector=labels,
+            order_by=order_by,
+        )
+        if gpu is not None:
+            request.gpu = gpu
+
+        while True:
+            response = self._stub.ListNodes(request)
+            yield from response.nodes
+            if not response.next_page_token:
+                return
+            request.page_token = response.next_page_token
+
+    def list_nodes(self, **filters) -> List["pbv2.Node"]:
+        """Return every node matching the filters accepted by iter_nodes."""
+        return list(self.iter_nodes(**filters))
+
+    # --- Jobs ---
+
+    def submit_job(self, job_type: int, payload, job_id: Optional[str] = None) -> "pbv2.SubmitJobResponse":
+        """Queue a job. payload is the orchion.proto.v1 request for job_type."""
+        response = self._stub.SubmitJob(pbv2.SubmitJobRequest(
+            job_id=job_id or f"job-{uuid.uuid4().hex}",
+            job_type=job_type,
+            payload=payload.SerializeToString(),
+        ))
+        self._job_types[response.job_id] = job_type
+        return response
+
+    def submit_chat(
+        self,
+        model: str,
+        messages: Iterable[Message],
+        *,
+        temperature: float = 0.0,
+        max_tokens: int = 0,
+        prompt_cache_key: str = "",
+    ) -> "pbv2.SubmitJobResponse":
+        """Queue a chat completion. messages are {"role": ..., "content": ...} dicts."""
+        request = pb.ChatCompletionRequest(
+            model=model,
+            messages=[_chat_message(m) for m in messages],
+            temperature=temperature,
+            max_tokens=max_tokens,
+            prompt_cache_key=prompt_cache_key,
+        )
+        return self.submit_job(pbv2.JOB_TYPE_CHAT_COMPLETION, request)
+
+    def submit_embeddings(self, model: str, inputs: Sequence[str]) -> "pbv2.SubmitJobResponse":
+        """Queue an embeddings job."""
+        request = pb.EmbeddingRequest(model=model, input=list(inputs))
+        return self.submit_job(pbv2.JOB_TYPE_EMBEDDINGS, request)
+
+    def submit_pipeline(self, pipeline: "pb.PipelineRequest") -> "pbv2.SubmitJobResponse":
+        """Queue a pipeline job."""
+        return self.submit_job(pbv2.JOB_TYPE_PIPELINE, pipeline)
+
+    def job_status(self, job_id: str) -> "pbv2.GetJobStatusResponse":
+        """Return the current state of a job."""
+        return self._stub.GetJobStatus(pbv2.GetJobStatusRequest(job_id=job_id))
+
+    def wait_for_job(self, job_id: str, *, poll_interval: float = 0.5, timeout: Optional[float] = None):
+        """Wait for a job to finish and return its decoded result.
+
+        The result is a pb.ChatCompletionResponse, pb.EmbeddingResponse or
+        pb.PipelineResponse depending on the job type.
+
+        Raises:
+            JobFailedError: The job failed.
+            TimeoutError: The job didn't finish within timeout seconds.
+        """
+        deadline = None if timeout is None else time.monotonic() + timeout
+        while True:
+            status = self.job_status(job_id)
+            if status.status == pbv2.JOB_STATUS_COMPLETED:
+                return self._decode_result(job_id, status)
+            if status.status == pbv2.JOB_STATUS_FAILED:
+                raise JobFailedError(status)
+            if deadline is not None and time.monotonic() >= deadline:
+                raise TimeoutError(f"job {job_id} did not finish within {timeout}s")
+            time.sleep(poll_interval)
+
+    def _decode_result(self, job_id: str, status: "pbv2.GetJobStatusResponse"):
+        job_type = self._job_types.get(job_id)
+        if job_type is None:
+            # Submitted elsewhere; look the type up
+            job_type = next((j.job_type for j in self.iter_jobs() if j.job_id == job_id), None)
+        result_type = _RESULT_TYPES.get(job_type)
+        if result_type is None:
+            raise ValueError(f"job {job_id} has an unknown type")
+        return result_type.FromString(status.result)
+
+    def iter_jobs(self) -> Iterator["pbv2.JobSummary"]:
+        """Yield every job, oldest first, fetching pages as needed."""
+        request = pbv2.ListJobsRequest(page_size=_PAGE_SIZE)
+        while True:
+            response = self._stub.ListJobs(request)
+            yield from response.jobs
+            if not response.next_page_token:
+                return
+            request.page_token = response.next_page_token
+
+    def list_jobs(self) -> List["pbv2.JobSummary"]:
+        """Return every job, oldest first."""
+        return list(self.iter_jobs())
+
+
+def _chat_message(message: Message) -> "pb.ChatMessage":
+    if isinstance(message, pb.ChatMessage):
+        return message
+    return pb.ChatMessage(role=message["role"], content=message["content"])
@@ -0,0 +1,101 @@
+"""Helpers for using the OpenAI SDK against the Orchion gateway.
+
+The gateway speaks the OpenAI chat completions and embeddings API, so the
+official `openai` package works once it is pointed at the gateway:
+
+    import orchion
+
+    client = orchion.openai_client("http://orchion.internal:8080")
+    reply = client.chat.completions.create(
+        model="llama3",
+        messages=[{"role": "user", "content": "Hello"}],
+    )
+
+Install the SDK with `pip install orchion[openai]`.
+"""
+
+import os
+from typing import Any, Dict, Optional
+
+DEFAULT_GATEWAY_URL = "http://localhost:8080"
+"""Gateway URL used when none is passed and ORCHION_GATEWAY_URL is unset."""
+
+TARGET_NODE_HEADER = "X-Orchion-Node"
+"""Header pinning a request to a node. Only honoured for admin API keys."""
+
+# The OpenAI SDK refuses to start without an API key, even though the gateway
+# may not require one
+_NO_API_KEY = "orchion-no-key"
+
+
+def gateway_base_url(gateway_url: Optional[str] = None) -> str:
+    """Return the OpenAI base URL (ending in /v1) for a gateway.
+
+    gateway_url defaults to the ORCHION_GATEWAY_URL environment variable, then
+    DEFAULT_GATEWAY_URL. A URL already ending in /v1 is used as is.
+    """
+    url = gateway_url or os.environ.get("ORCHION_GATEWAY_URL") or DEFAULT_GATEWAY_URL
+    url = url.rstrip("/")
+    if not url.endswith("/v1"):
+        url += "/v1"
+    return url
+
+
+def _api_key(api_key: Optional[str]) -> str:
+    return api_key or os.environ.get("ORCHION_API_KEY") or _NO_API_KEY
+
+
+def _client_kwargs(
+    gateway_url: Optional[str],
+    api_key: Optional[str],
+    node: Optional[str],
+    kwargs: Dict[str, Any],
+) -> Dict[str, Any]:
+    headers = dict(kwargs.pop("default_headers", None) or {})
+    if node:
+        headers[TARGET_NODE_HEADER] = node
+    return {
+        "base_url": gateway_base_url(gateway_url),
+        "api_key": _api_key(api_key),
+        "default_headers": headers or None,
+        **kwargs,
+    }
+
+
+def openai_client(
+    gateway_url: Optional[str] = None,
+    api_key: Optional[str] = None,
+    *,
+    node: Optional[str] = None,
+    asynchronous: bool = False,
+    **kwargs: Any,
+):
+    """Create an OpenAI SDK client that sends requests to the Orchion gateway.
+
+    Args:
+        gateway_url: Gateway URL, e.g. "http://orchion.internal:8080".
+            Defaults to ORCHION_GATEWAY_URL, then DEFAULT_GATEWAY_URL.
+        api_key: Gateway API key. Defaults to ORCHION_API_KEY; may be omitted
+            if the gateway doesn't require one.
+        node: Pin every request to this node ID (requires an admin API key).
+        asynchronous: Return an `openai.AsyncOpenAI` instead of `openai.OpenAI`.
+        **kwargs: Passed to the OpenAI client, e.g. timeout or max_retries.
+    """
+    try:
+        import openai
+    except ImportError as e:
+        raise ImportError("openai_client requires the OpenAI SDK: pip install orchion[openai]") from e
+
+    cls = openai.AsyncOpenAI if asynchronous else openai.OpenAI
+    return cls(**_client_kwargs(gateway_url, api_key, node, kwargs))
+
+
+def configure_openai(gateway_url: Optional[str] = None, api_key: Optional[str] = None) -> None:
+    """Point OpenAI clients created from now on at the Orchion gateway.
+
+    Sets the OPENAI_BASE_URL and OPENAI_API_KEY environment variables read by
+    the OpenAI SDK, which also covers libraries that create their own clients
+    (e.g. LangChain or LlamaIndex).
+    """
+    os.environ["OPENAI_BASE_URL"] = gateway_base_url(gateway_url)
+    os.environ["OPENAI_API_KEY"] = _api_key(api_key)
@@ -0,0 +1,95 @@
+from concurrent import futures
+
+import grpc
+import pytest
+
+import orchion
+from orchion.proto.v1 import orchestrator_pb2 as pb
+from orchion.proto.v2 import orchestrator_pb2 as pbv2
+from orchion.proto.v2 import orchestrator_pb2_grpc as pbv2_grpc
+
+
+class FakeOrchestrator(pbv2_grpc.OrchestratorServicer):
+    def __init__(self):
+        self.submitted = {}
+        self.polls = 0
+        self.fail = False
+
+    def ListNodes(self, request, context):
+        # Two pages of one node each
+        if not request.page_token:
+            return pbv2.ListNodesResponse(nodes=[pbv2.Node(id="node-1")], next_page_token="2")
+        return pbv2.ListNodesResponse(nodes=[pbv2.Node(id="node-2", labels={"status": request.status})])
+
+    def SubmitJob(self, request, context):
+        self.submitted[request.job_id] = request
+        return pbv2.SubmitJobResponse(job_id=request.job_id, status=pbv2.JOB_STATUS_PENDING)
+
+    def GetJobStatus(self, request, context):
+        self.polls += 1
+        if self.polls < 2:
+            return pbv2.GetJobStatusResponse(job_id=request.job_id, status=pbv2.JOB_STATUS_RUNNING)
+        if self.fail:
+            return pbv2.GetJobStatusResponse(
+                job_id=request.job_id, status=pbv2.JOB_STATUS_FAILED, error_message="engine crashed")
+        result = pb.EmbeddingResponse(model="nomic-embed-text", data=[pb.Embedding(embedding=[0.5])])
+        return pbv2.GetJobStatusResponse(
+            job_id=request.job_id, status=pbv2.JOB_STATUS_COMPLETED, result=result.SerializeToString())
+
+    def ListJobs(self, request, context):
+        return pbv2.ListJobsResponse(jobs=[
+            pbv2.JobSummary(job_id=job_id, job_type=job.job_type) for job_id, job in self.submitted.items()
+        ])
+
+
+@pytest.fixture
+def server():
+    fake = FakeOrchestrator()
+    grpc_server = grpc.server(futures.ThreadPoolExecutor(max_workers=2))
+    pbv2_grpc.add_OrchestratorServicer_to_server(fake, grpc_server)
+    port = grpc_server.add_insecure_port("localhost:0")
+    grpc_server.start()
+    fake.address = f"localhost:{port}"
+    yield fake
+    grpc_server.stop(None)
+
+
+def test_list_nodes_follows_pages(server):
+    with orchion.Client(server.address) as client:
+        nodes = client.list_nodes(status="online", gpu=True)
+    assert [n.id for n in nodes] == ["node-1", "node-2"]
+    assert nodes[1].labels["status"] == "online"
+
+
+def test_submit_and_wait(server):
+    with orchion.Client(server.address) as client:
+        job = client.submit_embeddings("nomic-embed-text", ["hello"])
+        submitted = server.submitted[job.job_id]
+        assert submitted.job_type == pbv2.JOB_TYPE_EMBEDDINGS
+        assert pb.EmbeddingRequest.FromString(submitted.payload).input == ["hello"]
+
+        result = client.wait_for_job(job.job_id, poll_interval=0.01)
+    assert list(result.data[0].embedding) == [0.5]
+
+
+def test_wait_for_job_submitted_elsewhere(server):
+    with orchion.Client(server.address) as submitter:
+        job = submitter.submit_embeddings("nomic-embed-text", ["hello"])
+
+    # A fresh client looks the job type up with ListJobs
+    with orchion.Client(server.address) as client:
+        result = client.wait_for_job(job.job_id, poll_interval=0.01)
+    assert isinstance(result, pb.EmbeddingResponse)
+
+
+def test_wait_for_failed_job(server):
+    server.fail = True
+    with orchion.Client(server.address) as client:
+        with pytest.raises(orchion.JobFailedError, match="engine crashed"):
+            client.wait_for_job("job-1", poll_interval=0.01)
+
+
+def test_wait_for_job_timeout(server):
+    with orchion.Client(server.address) as client:
+        with pytest.raises(TimeoutError):
+            client.wait_for_job("job-1", poll_interval=0.01, timeout=0)
@@ -0,0 +1,81 @@
+import sys
+import types
+
+import pytest
+
+from orchion import gateway
+
+
+@pytest.fixture(autouse=True)
+def clean_env(monkeypatch):
+    for name in ("ORCHION_GATEWAY_URL", "ORCHION_API_KEY", "OPENAI_BASE_URL", "OPENAI_API_KEY"):
+        monkeypatch.delenv(name, raising=False)
+
+
+@pytest.fixture
+def fake_openai(monkeypatch):
+    """Stand-in for the openai package recording client arguments."""
+
+    class FakeClient:
+        def __init__(self, **kwargs):
+            self.kwargs = kwargs
+
+    class FakeAsyncClient(FakeClient):
+        pass
+
+    module = types.SimpleNamespace(OpenAI=FakeClient, AsyncOpenAI=FakeAsyncClient)
+    monkeypatch.setitem(sys.modules, "openai", module)
+    return module
+
+
+@pytest.mark.parametrize("url, expected", [
+    (None, "http://localhost:8080/v1"),
+    ("http://gw:8080", "http://gw:8080/v1"),
+    ("http://gw:8080/", "http://gw:8080/v1"),
+    ("https://gw/v1", "https://gw/v1"),
+])
+def test_gateway_base_url(url, expected):
+    assert gateway.gateway_base_url(url) == expected
+
+
+def test_gateway_base_url_from_env(monkeypatch):
+    monkeypatch.setenv("ORCHION_GATEWAY_URL", "http://env-gw:9000")
+    assert gateway.gateway_base_url() == "http://env-gw:9000/v1"
+
+
+def test_openai_client(fake_openai):
+    client = gateway.openai_client("http://gw:8080", "secret", timeout=30)
+    assert isinstance(client, fake_openai.OpenAI)
+    assert client.kwargs == {
+        "base_url": "http://gw:8080/v1",
+        "api_key": "secret",
+        "default_headers": None,
+        "timeout": 30,
+    }
+
+
+def test_openai_client_without_key(fake_openai, monkeypatch):
+    # The SDK rejects empty keys even when the gateway needs none
+    assert gateway.openai_client().kwargs["api_key"]
+
+    monkeypatch.setenv("ORCHION_API_KEY", "from-env")
+    assert gateway.openai_client().kwargs["api_key"] == "from-env"
+
+
+def test_openai_client_node_and_async(fake_openai):
+    client = gateway.openai_client(node="node-7", asynchronous=True, default_headers={"X-Trace": "1"})
+    assert isinstance(client, fake_openai.AsyncOpenAI)
+    assert client.kwargs["default_headers"] == {"X-Trace": "1", "X-Orchion-Node": "node-7"}
+
+
+def test_openai_client_missing_sdk(monkeypatch):
+    monkeypatch.setitem(sys.modules, "openai", None)
+    with pytest.raises(ImportError, match="orchion\\[openai\\]"):
+        gateway.openai_client()
+
+
+def test_configure_openai():
+    gateway.configure_openai("http://gw:8080", "secret")
+    import os
+    assert os.environ["OPENAI_BASE_URL"] == "http://gw:8080/v1"
+    assert os.environ["OPENAI_API_KEY"] == "secret"
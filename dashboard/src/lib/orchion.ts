@@ -7,6 +7,69 @@ const getBaseUrl = (): string => {
 	return 'http://localhost:8080';
 };
 
+// Session token from /api/auth/login. It is kept in sessionStorage and expires
+// on its own, so the API key itself is never stored in the browser.
+const SESSION_KEY = 'orchion.session';
+
+interface Session {
+	token: string;
+	role: string;
+	expiresAtMs: number;
+}
+
+/** Error for responses rejected because the dashboard isn't logged in */
+export class UnauthorizedError extends Error {}
+
+function getSession(): Session | null {
+	if (typeof sessionStorage === 'undefined') {
+		return null;
+	}
+	const raw = sessionStorage.getItem(SESSION_KEY);
+	if (!raw) {
+		return null;
+	}
+	const session: Session = JSON.parse(raw);
+	if (session.expiresAtMs <= Date.now()) {
+		sessionStorage.removeItem(SESSION_KEY);
+		return null;
+	}
+	return session;
+}
+
+function authHeaders(): Record<string, string> {
+	const session = getSession();
+	return session ? { Authorization: `Bearer ${session.token}` } : {};
+}
+
+/** Exchanges an API key for a session token */
+export async function login(key: string): Promise<void> {
+	const res = await fetch(`${getBaseUrl()}/api/auth/login`, {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json' },
+		body: JSON.stringify({ key })
+	});
+	if (res.status === 401) {
+		throw new UnauthorizedError('Invalid API key');
+	}
+	if (!res.ok) {
+		throw new Error(`Login failed: ${res.status} ${res.statusText}`);
+	}
+
+	const body = await res.json();
+	const session: Session = {
+		token: body.token,
+		role: body.role,
+		expiresAtMs: body.expires_at_ms
+	};
+	sessionStorage.setItem(SESSION_KEY, JSON.stringify(session));
+}
+
+/** Ends the session */
+export async function logout(): Promise<void> {
+	sessionStorage.removeItem(SESSION_KEY);
+	await fetch(`${getBaseUrl()}/api/auth/logout`, { method: 'POST' });
+}
+
 export interface Node {
 	id: string;
 	hostname: string;
@@ -16,6 +79,8 @@ export interface Node {
 		os: string;
 	};
 	lastSeenUnix?: number;
+	notes?: string;
+	annotations?: Record<string, string>;
 }
 
 export async function getNodes(): Promise<Node[]> {
@@ -23,8 +88,11 @@ export async function getNodes(): Promise<Node[]> {
 	const url = `${baseUrl}/api/nodes`;
 
 	try {
-		const res = await fetch(url);
+		const res = await fetch(url, { headers: authHeaders() });
 
+		if (res.status === 401) {
+			throw new UnauthorizedError('Log in with an API key to see nodes');
+		}
 		if (!res.ok) {
 			throw new Error(`Failed to fetch nodes: ${res.status} ${res.statusText}`);
 		}
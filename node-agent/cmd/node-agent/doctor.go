@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Orchion/Orchion/node-agent/internal/doctor"
+)
+
+// runDoctor checks whether this machine can run the agent with the flags
+// given, prints the report and returns the exit code: 1 if any check failed
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	timeout := fs.Duration("timeout", doctor.DefaultTimeout, "Timeout of each check")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	d := doctor.New(doctor.Config{
+		OrchestratorAddr: *orchestratorAddr,
+		TunnelAddr:       *tunnelAddr,
+		AgentPort:        *agentPort,
+		AgentBind:        *bindAddr,
+		AdminAddr:        *adminAddr,
+		EnginePorts: map[string]int{
+			"ollama": 11434,
+			"vllm":   8000,
+			"piper":  5000,
+			"coqui":  5002,
+		},
+		Timeout: *timeout,
+	})
+	report := d.Run(context.Background())
+
+	var err error
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return 1
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
@@ -2,34 +2,103 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log"
 	"net"
+	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"sort"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
 	"github.com/google/uuid"
 
 	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
 	"github.com/Orchion/Orchion/node-agent/internal/executor"
 	"github.com/Orchion/Orchion/node-agent/internal/heartbeat"
 	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+	"github.com/Orchion/Orchion/node-agent/internal/tunnel"
+	"github.com/Orchion/Orchion/shared/diagnostics"
+	"github.com/Orchion/Orchion/shared/lifecycle"
 	"github.com/Orchion/Orchion/shared/logging"
+	"github.com/Orchion/Orchion/shared/tailnet"
 )
 
 var (
 	orchestratorAddr   = flag.String("orchestrator", "localhost:50051", "Orchestrator gRPC address")
 	heartbeatInterval  = flag.Duration("heartbeat-interval", 5*time.Second, "Heartbeat interval")
 	capabilityInterval = flag.Duration("capability-interval", 10*time.Second, "Capability update interval")
+	capabilityRefresh  = flag.Duration("capability-full-refresh", heartbeat.DefaultFullRefreshInterval, "Interval at which capabilities are sent even if unchanged")
+	vramThreshold      = flag.Float64("capability-vram-threshold", heartbeat.DefaultChangeThresholds.VRAMMB, "Minimum VRAM change in MB that triggers a capability update")
 	nodeID             = flag.String("node-id", "", "Node ID (auto-generated if empty)")
 	nodeHostname       = flag.String("hostname", "", "Node hostname (uses system hostname if empty)")
+	nodeLabels         = flag.String("labels", "", "Comma-separated key=value labels the orchestrator can filter nodes by, e.g. zone=eu-west,tier=spot")
+	gpuNodes           = flag.Bool("gpu-nodes", false, "Register each NVIDIA GPU, or each MIG slice of GPUs in MIG mode, as its own node (IDs <node-id>-gpu<N>[-mig<M>]) with engine containers bound to that GPU, heartbeated in one batched call")
+	supportedModels    = flag.String("models", "", "Comma-separated model name patterns this node serves, e.g. nomic-embed-text,phi3* (empty = any model)")
 	agentPort          = flag.String("agent-port", "50052", "Node agent gRPC server port")
+	bindAddr           = flag.String("bind-address", "", "Interface address the gRPC port listens on: an IP, an interface name such as tailscale0, or tailnet for this host's Tailscale or WireGuard address (default: all interfaces)")
+	advertiseAddr      = flag.String("advertise-address", "", "Host or IP (optionally host:port) the orchestrator reaches this agent at, or an interface name or tailnet as for -bind-address (default: the -bind-address IP, else IP of the interface used to reach the orchestrator)")
+	tailnetAddr        = flag.String("tailnet-address", heartbeat.TailnetAuto, "Host or IP (optionally host:port) the orchestrator reaches this agent at over Tailscale or WireGuard, used when it runs with -prefer-tailnet (auto = detect it; empty to report none)")
+	tunnelAddr         = flag.String("tunnel-address", "", "Orchestrator reverse tunnel address (its -tunnel-port), for agents behind NAT the orchestrator can't dial (empty to disable)")
+	shutdownTimeout    = flag.Duration("shutdown-timeout", 30*time.Second, "How long a graceful shutdown may take, e.g. for running requests to finish and containers to stop")
+	adminAddr          = flag.String("admin-addr", "127.0.0.1:50053", "Admin HTTP endpoint address (empty to disable)")
+	traceEngineHTTP    = flag.Bool("trace-engine-http", false, "Log full inference engine HTTP requests/responses and timings")
+	traceRedact        = flag.Bool("trace-redact", true, "Redact prompt and completion content in engine HTTP traces")
+	otlpEndpoint       = flag.String("otlp-endpoint", "", "OTLP gRPC collector address for metrics (empty to disable)")
+	metricsInterval    = flag.Duration("metrics-interval", 15*time.Second, "Interval at which metrics are exported")
+	kvCacheSizes       = flag.String("kv-cache-mb-per-1k-tokens", "", "Per-model KV-cache growth in MB per 1000 tokens for the VRAM guard, e.g. llama3:70b=320,mistralai/Mistral-7B-v0.1=128")
+	kvCacheDefault     = flag.Float64("kv-cache-default-mb-per-1k-tokens", 0, "KV-cache growth assumed for models not in -kv-cache-mb-per-1k-tokens (0 = don't guard them)")
+	vramHeadroom       = flag.Float64("vram-guard-headroom-mb", 256, "Free VRAM the guard keeps on top of a request's expected KV-cache growth")
+	throttleTemp       = flag.Float64("throttle-gpu-temp", 0, "GPU temperature in °C above which new requests are throttled (0 = no limit)")
+	throttlePower      = flag.Float64("throttle-gpu-power", 0, "GPU power draw in W above which new requests are throttled (0 = no limit)")
+	throttleConcurrent = flag.Int("throttle-concurrency", 1, "Requests served at once while throttled (0 = pause new requests until the GPU is back within limits)")
+	maxConcurrent      = flag.Int("max-concurrent-requests", 0, "Requests served at once per GPU node; waiting requests are admitted by priority, highest first (0 = unlimited)")
+	warmModels         = flag.String("warm-embedding-models", "", "Comma-separated embedding models kept loaded so their requests skip the model start path, e.g. nomic-embed-text")
+	warmInterval       = flag.Duration("warm-interval", executor.DefaultWarmInterval, "Interval at which warm embedding models are probed and restarted if needed")
+	ollamaImage        = flag.String("ollama-image", "", "Ollama container image, e.g. ollama/ollama:0.3.12, or pinned by digest as ollama/ollama@sha256:... (default: upstream image, or the L4T build on Jetson)")
+	vllmImage          = flag.String("vllm-image", "", "vLLM container image, e.g. vllm/vllm-openai:v0.6.3, or pinned by digest as vllm/vllm-openai@sha256:... (default: "+containers.DefaultVLLMImage+")")
+	piperImage         = flag.String("piper-image", "", "Image the Piper text-to-speech container runs, which installs Piper on first start (default: "+containers.DefaultPiperImage+")")
+	coquiImage         = flag.String("coqui-image", "", "Coqui TTS container image, e.g. ghcr.io/coqui-ai/tts for CUDA (default: "+containers.DefaultCoquiImage+")")
+	startupBudgets     = flag.String("engine-startup-budget", "", "How long engines may take to become ready after starting, per engine or model, e.g. vllm=15m,meta-llama/Llama-3.1-70B-Instruct=30m (defaults: ollama 2m, vllm 5m, piper and coqui 10m)")
+	probeIntervals     = flag.String("engine-probe-interval", "", "How often starting engines are probed for readiness, per engine or model, e.g. vllm=5s (default: 1s)")
+	probePaths         = flag.String("engine-probe-path", "", "Path or URL probed until a starting engine answers 200 OK, per engine or model, e.g. vllm=/health (defaults: ollama /api/tags, vllm /v1/models, piper /voices, coqui /)")
+	prepullImages      = flag.Bool("prepull-images", false, "Pull engine container images at startup, so the first model start doesn't wait for the download (images the orchestrator pins are always pulled ahead of use)")
+	modelEviction      = flag.String("model-eviction", "none", "What to do when a model doesn't fit in GPU memory beside loaded ones: none (fail the request) or lru (stop the least recently used model)")
+	maxMessageSize     = flag.Int("grpc-max-message-bytes", 16<<20, "Largest gRPC message the agent sends or receives (match the orchestrator's -grpc-max-message-bytes)")
+	journalFile        = flag.String("journal-file", "", "File recording the requests in flight, so those interrupted by a crash are reported to the orchestrator for retry on restart (empty to disable)")
+	grpcWindowSize     = flag.Int("grpc-initial-window-bytes", 0, "gRPC flow-control window per stream and connection (0 = gRPC's dynamic window)")
 )
 
+// grpcServerOptions applies the message size limit and flow-control window
+// flags. gRPC ignores windows under 64KB.
+func grpcServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if *maxMessageSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(*maxMessageSize), grpc.MaxSendMsgSize(*maxMessageSize))
+	}
+	if *grpcWindowSize >= 64<<10 {
+		opts = append(opts, grpc.InitialWindowSize(int32(*grpcWindowSize)), grpc.InitialConnWindowSize(int32(*grpcWindowSize)))
+	}
+	return opts
+}
+
+// resolveAdvertise resolves an -advertise-address naming a network interface,
+// or tailnet, to the interface's IP
+func resolveAdvertise(advertise string) (string, error) {
+	if _, _, err := net.SplitHostPort(advertise); err == nil {
+		return tailnet.ResolveAddr(advertise)
+	}
+	return tailnet.ResolveHost(advertise)
+}
+
 // startCapabilityUpdateLoop periodically updates node capabilities
 func startCapabilityUpdateLoop(ctx context.Context, client *heartbeat.Client, interval time.Duration, logger logging.Logger) {
 	ticker := time.NewTicker(interval)
@@ -49,9 +118,111 @@ func startCapabilityUpdateLoop(ctx context.Context, client *heartbeat.Client, in
 	}
 }
 
+// pullImages pulls engine images in the background, so models starting
+// later don't wait for the download
+func pullImages(ctx context.Context, service *executor.Service, images []string, logger logging.Logger) {
+	go func() {
+		for _, image := range images {
+			start := time.Now()
+			if err := service.PullImages(ctx, []string{image}); err != nil {
+				logger.Error("Failed to pull engine image", map[string]interface{}{
+					"image": image,
+					"error": err.Error(),
+				})
+				continue
+			}
+			logger.Info("Pulled engine image", map[string]interface{}{
+				"image":    image,
+				"duration": time.Since(start).String(),
+			})
+		}
+	}()
+}
+
+// uniqueImages returns images without duplicates, sorted, as GPU services
+// share their engine images
+func uniqueImages(images []string) []string {
+	seen := make(map[string]bool, len(images))
+	var unique []string
+	for _, image := range images {
+		if !seen[image] {
+			seen[image] = true
+			unique = append(unique, image)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// registerGPUNodes registers one logical node per NVIDIA GPU or MIG slice,
+// derived from the agent's node, so the orchestrator can schedule on each
+// device separately. It returns the devices in node order.
+func registerGPUNodes(ctx context.Context, client *heartbeat.Client, node *pb.Node, logger logging.Logger) (*heartbeat.Batch, []capabilities.GPUDevice, error) {
+	// The tunnel is keyed by a single node ID
+	if *tunnelAddr != "" {
+		err := fmt.Errorf("-gpu-nodes can't be combined with -tunnel-address")
+		logger.Error("Invalid flags", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, nil, err
+	}
+
+	devices := capabilities.DetectGPUDevices()
+	if len(devices) == 0 {
+		err := fmt.Errorf("-gpu-nodes requires NVIDIA GPUs, but nvidia-smi reported none")
+		logger.Error("No GPUs to register as nodes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, nil, err
+	}
+
+	nodes := heartbeat.GPUNodes(node, devices)
+	batch, err := client.RegisterBatch(ctx, nodes)
+	if err != nil {
+		logger.Error("Failed to register GPU nodes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, nil, err
+	}
+	for _, n := range nodes {
+		logger.Info("GPU node registered successfully", map[string]interface{}{
+			"node_id": n.Id,
+			"gpu":     n.Capabilities.GpuType,
+		})
+	}
+	return batch, devices, nil
+}
+
 func main() {
 	flag.Parse()
 
+	// Dry-run diagnostics: check the machine and exit without starting
+	if flag.Arg(0) == "doctor" {
+		os.Exit(runDoctor(flag.Args()[1:]))
+	}
+
+	prg := newProgram(runAgent)
+	svc, err := newService(prg)
+	if err != nil {
+		log.Fatalf("Failed to set up service: %v", err)
+	}
+
+	// Service management subcommands: install, uninstall, start, stop, restart, status
+	if action := flag.Arg(0); action != "" {
+		if err := controlService(svc, action); err != nil {
+			log.Fatalf("Service %s failed: %v", action, err)
+		}
+		return
+	}
+
+	// Run in the foreground, or under the service manager if started by one
+	if err := runService(svc, prg); err != nil {
+		log.Fatalf("Node agent failed: %v", err)
+	}
+}
+
+// runAgent runs the node agent until ctx is cancelled
+func runAgent(ctx context.Context) error {
 	// Generate or use provided node ID
 	if *nodeID == "" {
 		*nodeID = uuid.New().String()
@@ -82,8 +253,28 @@ func main() {
 		}
 	}
 
+	labels, err := heartbeat.ParseLabels(*nodeLabels)
+	if err != nil {
+		logger.Error("Invalid node labels", map[string]interface{}{
+			"labels": *nodeLabels,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	models, err := heartbeat.ParseSupportedModels(*supportedModels)
+	if err != nil {
+		logger.Error("Invalid supported models", map[string]interface{}{
+			"models": *supportedModels,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
 	logger.Info("Node information", map[string]interface{}{
-		"hostname": hostname,
+		"hostname":         hostname,
+		"labels":           labels,
+		"supported_models": models,
 	})
 
 	// Detect capabilities
@@ -102,7 +293,7 @@ func main() {
 			"orchestrator_addr": *orchestratorAddr,
 			"error":             err.Error(),
 		})
-		os.Exit(1)
+		return err
 	}
 	defer client.Close()
 
@@ -113,105 +304,530 @@ func main() {
 	// TODO: Setup log streaming to orchestrator
 	// For now, logs are only local. Streaming implementation pending.
 
+	// Listen on one interface only, e.g. the tailnet, when asked to
+	bind, err := tailnet.ResolveHost(*bindAddr)
+	if err != nil {
+		logger.Error("Invalid bind address", map[string]interface{}{
+			"bind_address": *bindAddr,
+			"error":        err.Error(),
+		})
+		return err
+	}
+	if *adminAddr != "" {
+		if *adminAddr, err = tailnet.ResolveAddr(*adminAddr); err != nil {
+			logger.Error("Invalid admin address", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return err
+		}
+	}
+
+	// Advertise an address the orchestrator can reach; hostnames often don't
+	// resolve from it (home NAT, mDNS)
+	advertise, err := resolveAdvertise(*advertiseAddr)
+	if err != nil {
+		logger.Error("Invalid advertise address", map[string]interface{}{
+			"advertise_address": *advertiseAddr,
+			"error":             err.Error(),
+		})
+		return err
+	}
+	if ip := net.ParseIP(bind); advertise == "" && ip != nil && !ip.IsUnspecified() {
+		advertise = bind
+	}
+	if advertise == "" {
+		ip, err := heartbeat.OutboundIP(*orchestratorAddr)
+		if err != nil {
+			logger.Warn("Failed to detect outbound IP, advertising hostname", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			advertise = ip
+		}
+	}
+	agentAddress, err := heartbeat.AgentAddress(advertise, hostname, *agentPort)
+	if err != nil {
+		logger.Error("Invalid advertise address", map[string]interface{}{
+			"advertise_address": *advertiseAddr,
+			"error":             err.Error(),
+		})
+		return err
+	}
+	tailnetAddress, err := heartbeat.TailnetAddress(*tailnetAddr, bind, *agentPort)
+	if err != nil {
+		logger.Error("Invalid tailnet address", map[string]interface{}{
+			"tailnet_address": *tailnetAddr,
+			"error":           err.Error(),
+		})
+		return err
+	}
+	logger.Info("Advertising agent address", map[string]interface{}{
+		"agent_address":   agentAddress,
+		"tailnet_address": tailnetAddress,
+	})
+
 	// Create node info
 	node := &pb.Node{
 		Id:           *nodeID,
 		Hostname:     hostname,
 		Capabilities: caps,
 		LastSeenUnix: time.Now().Unix(),
-		AgentAddress: fmt.Sprintf("%s:%s", hostname, *agentPort),
+		AgentAddress: agentAddress,
+		Labels:       labels,
+		Tunneled:     *tunnelAddr != "",
+
+		SupportedModels: models,
+		TailnetAddress:  tailnetAddress,
 	}
 
-	// Register with orchestrator
-	ctx := context.Background()
-	if err := client.RegisterNode(ctx, node); err != nil {
-		logger.Error("Failed to register node", map[string]interface{}{
-			"error": err.Error(),
-		})
-		os.Exit(1)
+	thresholds := heartbeat.DefaultChangeThresholds
+	thresholds.VRAMMB = *vramThreshold
+
+	// Find the requests a crash interrupted, to report them with the
+	// registration so the orchestrator retries exactly those
+	var journal *executor.Journal
+	if *journalFile != "" {
+		var interrupted []*pb.InterruptedRequest
+		journal, interrupted, err = executor.OpenJournal(*journalFile)
+		if err != nil {
+			logger.Error("Failed to open request journal", map[string]interface{}{
+				"journal_file": *journalFile,
+				"error":        err.Error(),
+			})
+			return err
+		}
+		defer journal.Close()
+		if len(interrupted) > 0 {
+			logger.Warn("Reporting requests interrupted by the last shutdown", map[string]interface{}{
+				"requests": len(interrupted),
+			})
+		}
+		client.ReportInterruptedRequests(interrupted)
+	}
+
+	// Register with orchestrator, as one logical node per GPU if requested
+	var batch *heartbeat.Batch
+	var devices []capabilities.GPUDevice
+	if *gpuNodes {
+		batch, devices, err = registerGPUNodes(ctx, client, node, logger)
+		if err != nil {
+			return err
+		}
+		batch.EnableCapabilityUpdates(capabilities.DetectPerGPU)
+		batch.SetCapabilityThresholds(thresholds, *capabilityRefresh)
+	} else {
+		if err := client.RegisterNode(ctx, node); err != nil {
+			logger.Error("Failed to register node", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return err
+		}
+		logger.Info("Node registered successfully", nil)
 	}
-	logger.Info("Node registered successfully", nil)
 
 	// Enable periodic capability updates
 	client.EnableCapabilityUpdates(capabilities.Detect)
+	client.SetCapabilityThresholds(thresholds, *capabilityRefresh)
 	logger.Info("Capability updates enabled", map[string]interface{}{
-		"interval": *capabilityInterval,
+		"interval":          *capabilityInterval,
+		"full_refresh":      *capabilityRefresh,
+		"vram_threshold_mb": *vramThreshold,
 	})
 
-	// Create executor service
-	executorService, err := executor.NewService()
+	// Export request and GPU metrics over OTLP
+	shutdownMetrics, err := telemetry.Setup(ctx, *otlpEndpoint, *metricsInterval)
+	if err != nil {
+		logger.Error("Failed to set up metrics export", map[string]interface{}{
+			"otlp_endpoint": *otlpEndpoint,
+			"error":         err.Error(),
+		})
+		return err
+	}
+	if *otlpEndpoint != "" {
+		logger.Info("Metrics export enabled", map[string]interface{}{
+			"otlp_endpoint": *otlpEndpoint,
+			"interval":      *metricsInterval,
+		})
+	}
+
+	// Create executor service, or one per GPU node with engines bound to its GPU
+	var services []*executor.Service
+	if batch != nil {
+		services, err = executor.NewGPUServices(devices)
+	} else {
+		var executorService *executor.Service
+		executorService, err = executor.NewService()
+		services = []*executor.Service{executorService}
+	}
 	if err != nil {
 		logger.Error("Failed to create executor service", map[string]interface{}{
 			"error": err.Error(),
 		})
-		os.Exit(1)
+		return err
 	}
 	logger.Info("Created executor service", map[string]interface{}{
 		"features": "container management",
+		"services": len(services),
 	})
 
-	// Setup gRPC server for NodeAgent service
-	grpcLis, err := net.Listen("tcp", ":"+*agentPort)
+	for _, service := range services {
+		service.SetLogger(logger)
+		if journal != nil {
+			service.SetJournal(journal)
+		}
+	}
+
+	// Apply the settings of the orchestrator's node config profiles, which
+	// replace the agent's own
+	configs := []*pb.NodeConfig{client.Config()}
+	if batch != nil {
+		configs = batch.Configs()
+	}
+	for i, config := range configs {
+		if config == nil {
+			continue
+		}
+		services[i].SetEngineRoutes(config.EngineRoutes)
+		logger.Info("Applying node config profile", map[string]interface{}{
+			"profile":                 config.Profile,
+			"service":                 i,
+			"engine_routes":           len(config.EngineRoutes),
+			"max_concurrent_requests": config.MaxConcurrentRequests,
+		})
+	}
+
+	// Start engines from the configured images, e.g. pinned by digest
+	for _, engine := range []struct{ name, image string }{
+		{"ollama", *ollamaImage}, {"vllm", *vllmImage}, {"piper", *piperImage}, {"coqui", *coquiImage},
+	} {
+		if engine.image == "" {
+			continue
+		}
+		for _, service := range services {
+			if err := service.SetImage(engine.name, engine.image); err != nil {
+				logger.Error("Failed to set engine image", map[string]interface{}{
+					"engine": engine.name,
+					"image":  engine.image,
+					"error":  err.Error(),
+				})
+				return err
+			}
+		}
+	}
+
+	// Serve NodeAgent calls, routing them to the GPU node they target, and
+	// report loaded models and their engine builds to the orchestrator
+	var agentServer pb.NodeAgentServer = services[0]
+	if batch != nil {
+		router := executor.NewRouter(batch.NodeIDs(), services)
+		batch.EnableModelReporting(router.Models)
+		agentServer = router
+	} else {
+		client.EnableModelReporting(services[0].Models)
+	}
+
+	// Follow the engine images the orchestrator pins fleet-wide, pulling them
+	// ahead of use. Loaded models keep their image until they restart, so
+	// rollouts don't interrupt them.
+	applyImagePins := func(pins []*pb.ImagePin) {
+		var switched []string
+		for _, service := range services {
+			switched = append(switched, service.ApplyImagePins(pins)...)
+		}
+		if len(switched) > 0 {
+			pullImages(ctx, services[0], uniqueImages(switched), logger)
+		}
+	}
+	if batch != nil {
+		batch.EnableImagePinning(applyImagePins)
+	} else {
+		client.EnableImagePinning(applyImagePins)
+	}
+
+	// Keep the share of capacity the orchestrator's GPU sharing schedule
+	// reserves for interactive requests free of batch work
+	var reserve float64
+	applySharing := func(sharing *pb.GpuSharing) {
+		if sharing.GetInteractiveReserve() == reserve {
+			return
+		}
+		reserve = sharing.GetInteractiveReserve()
+		for _, service := range services {
+			service.SetInteractiveReserve(reserve)
+		}
+		logger.Info("GPU sharing policy changed", map[string]interface{}{
+			"interactive_reserve": reserve,
+			"window":              sharing.GetWindow(),
+		})
+	}
+	if batch != nil {
+		batch.EnableGPUSharing(applySharing)
+	} else {
+		client.EnableGPUSharing(applySharing)
+	}
+
+	// Pull engine images ahead of the first model start
+	if *prepullImages {
+		var images []string
+		for _, service := range services {
+			images = append(images, service.Images()...)
+		}
+		images = uniqueImages(images)
+		logger.Info("Pre-pulling engine images", map[string]interface{}{
+			"images": images,
+		})
+		pullImages(ctx, services[0], images, logger)
+	}
+
+	eviction, err := executor.ParseEvictionPolicy(*modelEviction)
 	if err != nil {
-		logger.Error("Failed to listen on agent port", map[string]interface{}{
-			"port":  *agentPort,
+		logger.Error("Invalid model eviction policy", map[string]interface{}{
 			"error": err.Error(),
 		})
-		os.Exit(1)
+		return err
+	}
+	for _, service := range services {
+		service.SetEvictionPolicy(eviction)
+	}
+	if eviction != executor.EvictNone {
+		logger.Info("Model eviction enabled", map[string]interface{}{
+			"policy": string(eviction),
+		})
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterNodeAgentServer(grpcServer, executorService)
-	logger.Info("Node agent gRPC server listening", map[string]interface{}{
-		"port": *agentPort,
-	})
+	// Reject requests whose KV cache won't fit in free VRAM so the
+	// orchestrator retries them elsewhere instead of OOMing mid-stream
+	kvSizes, err := executor.ParseKVCacheSizes(*kvCacheSizes)
+	if err != nil {
+		logger.Error("Invalid KV cache sizes", map[string]interface{}{
+			"kv_cache_sizes": *kvCacheSizes,
+			"error":          err.Error(),
+		})
+		return err
+	}
+	if len(kvSizes) > 0 || *kvCacheDefault > 0 {
+		for _, service := range services {
+			service.SetMemoryGuard(executor.NewMemoryGuard(kvSizes, *kvCacheDefault, *vramHeadroom))
+		}
+		logger.Info("VRAM guard enabled", map[string]interface{}{
+			"models":      len(kvSizes),
+			"default_mb":  *kvCacheDefault,
+			"headroom_mb": *vramHeadroom,
+		})
+	}
 
-	// Start gRPC server
-	go func() {
-		if err := grpcServer.Serve(grpcLis); err != nil {
-			logger.Error("Failed to serve gRPC", map[string]interface{}{
-				"error": err.Error(),
-			})
-			os.Exit(1)
+	// Large models can take longer to load than the engines' default budgets
+	probes, err := executor.ParseReadinessProbes(*startupBudgets, *probeIntervals, *probePaths)
+	if err != nil {
+		logger.Error("Invalid engine readiness probes", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	for target, probe := range probes {
+		for _, service := range services {
+			service.SetReadinessProbe(target, probe)
 		}
-	}()
+	}
 
-	// Setup graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// Keep small embedding models loaded for low-latency embeddings
+	if models := executor.ParseWarmModels(*warmModels); len(models) > 0 {
+		for _, service := range services {
+			service.StartWarmPool(ctx, models, *warmInterval)
+		}
+		logger.Info("Embedding warm pool enabled", map[string]interface{}{
+			"models":   models,
+			"interval": *warmInterval,
+		})
+	}
 
-	// Start heartbeat loop
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	client.StartHeartbeatLoop(ctx, *heartbeatInterval)
-	logger.Info("Heartbeat loop started", map[string]interface{}{
-		"interval": *heartbeatInterval,
-	})
+	// Shed requests while the GPU runs over its temperature or power limits,
+	// e.g. on passively cooled cards, and tell the orchestrator in heartbeats
+	if *throttleTemp > 0 || *throttlePower > 0 {
+		for _, service := range services {
+			service.SetThrottle(executor.NewThrottle(*throttleTemp, *throttlePower, *throttleConcurrent))
+		}
+		if router, ok := agentServer.(*executor.Router); ok {
+			batch.EnableThrottleReporting(router.ThrottleState)
+		} else {
+			client.EnableThrottleReporting(services[0].ThrottleState)
+		}
+		logger.Info("GPU throttling enabled", map[string]interface{}{
+			"max_temp_c":     *throttleTemp,
+			"max_power_w":    *throttlePower,
+			"max_concurrent": *throttleConcurrent,
+		})
+	}
 
-	// Start capability update loop
-	go startCapabilityUpdateLoop(ctx, client, *capabilityInterval, logger)
-	logger.Info("Capability update loop started", map[string]interface{}{
-		"interval": *capabilityInterval,
+	// Bound concurrent requests so higher priority ones skip the wait when
+	// the node is saturated
+	for i, service := range services {
+		limit := *maxConcurrent
+		if configs[i] != nil && configs[i].MaxConcurrentRequests > 0 {
+			limit = int(configs[i].MaxConcurrentRequests)
+		}
+		if limit <= 0 {
+			continue
+		}
+		service.SetLimiter(executor.NewLimiter(limit))
+		logger.Info("Request concurrency limited", map[string]interface{}{
+			"service":        i,
+			"max_concurrent": limit,
+		})
+	}
+
+	// GPU services share a tracer
+	services[0].Tracer().Apply(executor.TraceSettings{
+		Enabled:       *traceEngineHTTP,
+		RedactPrompts: *traceRedact,
 	})
 
-	logger.Info("Node agent running, waiting for shutdown signal", nil)
+	// Components start in dependency order and stop in reverse: heartbeats
+	// stop, so the orchestrator sends no more work, then the servers, then
+	// the executors stop their containers
+	runner := lifecycle.NewRunner(*shutdownTimeout, logger)
+
+	// Setup admin HTTP endpoint for runtime debugging switches, profiles and
+	// process metrics. It stops last, so a stuck shutdown can still be
+	// profiled.
+	if *adminAddr != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/admin/trace", services[0].Tracer())
+		adminMux.Handle("/api/admin/loglevel", logging.NewLevelHandler(logger))
+		diagnostics.Register(adminMux)
+		adminServer := &http.Server{
+			Addr:    *adminAddr,
+			Handler: adminMux,
+		}
+		runner.Add(lifecycle.Component{
+			Name: "admin",
+			Start: func(ctx context.Context) error {
+				logger.Info("Admin endpoint listening", map[string]interface{}{
+					"addr": *adminAddr,
+				})
+				return nil
+			},
+			// The agent runs on without its debugging switches
+			Serve: func() error {
+				if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error("Failed to serve admin endpoint", map[string]interface{}{
+						"error": err.Error(),
+					})
+				}
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return adminServer.Close()
+			},
+		})
+	}
 
-	// Wait for shutdown signal
-	sig := <-sigChan
-	logger.Info("Received shutdown signal", map[string]interface{}{
-		"signal": sig.String(),
+	runner.Add(lifecycle.Component{
+		Name: "executors",
+		Stop: func(ctx context.Context) error {
+			var errs []error
+			for _, service := range services {
+				errs = append(errs, service.Shutdown(ctx))
+			}
+			return errors.Join(errs...)
+		},
+	})
+	runner.Add(lifecycle.Component{
+		Name: "metrics",
+		Stop: shutdownMetrics,
 	})
 
-	// Graceful shutdown
-	grpcServer.GracefulStop()
+	// Setup gRPC server for NodeAgent service
+	grpcLis, err := net.Listen("tcp", net.JoinHostPort(bind, *agentPort))
+	if err != nil {
+		logger.Error("Failed to listen on agent port", map[string]interface{}{
+			"port":  *agentPort,
+			"error": err.Error(),
+		})
+		return err
+	}
 
-	// Shutdown executor service (stops containers)
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCancel()
+	grpcServer := grpc.NewServer(grpcServerOptions()...)
+	pb.RegisterNodeAgentServer(grpcServer, agentServer)
 
-	if err := executorService.Shutdown(shutdownCtx); err != nil {
-		logger.Error("Error during executor shutdown", map[string]interface{}{
-			"error": err.Error(),
+	// Standard health checks and reflection for grpcurl, Kubernetes gRPC
+	// probes and load balancers
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	for name := range grpcServer.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	runner.Add(lifecycle.Component{
+		Name: "grpc",
+		Start: func(ctx context.Context) error {
+			logger.Info("Node agent gRPC server listening", map[string]interface{}{
+				"port":         *agentPort,
+				"bind_address": bind,
+			})
+			return nil
+		},
+		Serve: func() error { return grpcServer.Serve(grpcLis) },
+		Stop: func(ctx context.Context) error {
+			healthServer.Shutdown()
+			return lifecycle.Graceful(ctx, grpcServer.GracefulStop, grpcServer.Stop)
+		},
+	})
+
+	// Behind NAT, also serve over a tunnel the agent opens to the orchestrator
+	if *tunnelAddr != "" {
+		tunnelLis := tunnel.NewListener(*tunnelAddr, node.Id, logger)
+		runner.Add(lifecycle.Component{
+			Name: "tunnel",
+			Start: func(ctx context.Context) error {
+				logger.Info("Serving node agent over reverse tunnel", map[string]interface{}{
+					"tunnel_address": *tunnelAddr,
+				})
+				return nil
+			},
+			// Stopping the gRPC server closes the tunnel listener
+			Serve: func() error {
+				grpcServer.Serve(tunnelLis)
+				return nil
+			},
 		})
 	}
+
+	// Heartbeat loops
+	var stopHeartbeats context.CancelFunc
+	runner.Add(lifecycle.Component{
+		Name: "heartbeats",
+		Start: func(ctx context.Context) error {
+			ctx, stopHeartbeats = context.WithCancel(ctx)
+			if batch != nil {
+				// Capability updates ride along on the batched heartbeats
+				batch.StartLoop(ctx, *heartbeatInterval)
+				logger.Info("Batch heartbeat loop started", map[string]interface{}{
+					"interval": *heartbeatInterval,
+				})
+				return nil
+			}
+
+			client.StartHeartbeatLoop(ctx, *heartbeatInterval)
+			logger.Info("Heartbeat loop started", map[string]interface{}{
+				"interval": *heartbeatInterval,
+			})
+
+			// Start capability update loop
+			go startCapabilityUpdateLoop(ctx, client, *capabilityInterval, logger)
+			logger.Info("Capability update loop started", map[string]interface{}{
+				"interval": *capabilityInterval,
+			})
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			stopHeartbeats()
+			return nil
+		},
+	})
+
+	logger.Info("Node agent running, waiting for shutdown signal", nil)
+
+	// Run until the shutdown signal (delivered by the service manager or Ctrl+C)
+	return runner.Run(ctx)
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+
+	"github.com/kardianos/service"
+)
+
+// program adapts the node agent to the service manager lifecycle
+type program struct {
+	run    func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once run returned
+	err    error         // What run returned, once done is closed
+}
+
+// newProgram creates a program running the agent with run
+func newProgram(run func(ctx context.Context) error) *program {
+	return &program{run: run, done: make(chan struct{})}
+}
+
+// Start is called by the service manager and must not block
+func (p *program) Start(s service.Service) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	go func() {
+		p.err = p.run(ctx)
+		close(p.done)
+		if p.err == nil {
+			return
+		}
+		log.Printf("Node agent stopped: %v", p.err)
+		// The Windows service manager only calls Stop when asked to; on
+		// Unix, wait returns now and Run calls it
+		if runtime.GOOS == "windows" {
+			s.Stop()
+		}
+	}()
+	return nil
+}
+
+// Stop is called by the service manager and waits for the agent to shut down gracefully. It returns
+// the agent's error if it failed, so Run does and the process exits non-zero
+// for the service manager to restart it.
+func (p *program) Stop(s service.Service) error {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+	return p.err
+}
+
+// wait blocks until the agent is asked to stop or stops by itself. Service
+// managers on Unix run it after Start in place of only waiting for SIGTERM.
+func (p *program) wait() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	select {
+	case <-signals:
+	case <-p.done:
+	}
+}
+
+// newService creates the service wrapper running p. When installed, the
+// service runs the agent with the flags given on the install command line.
+func newService(p *program) (service.Service, error) {
+	config := &service.Config{
+		Name:        "orchion-node-agent",
+		DisplayName: "Orchion Node Agent",
+		Description: "Runs Orchion inference workloads on this machine and reports to the orchestrator.",
+		Arguments:   serviceArguments(os.Args[1:], flag.NArg()),
+		Dependencies: []string{
+			"After=network-online.target",
+			"Wants=network-online.target",
+		},
+		Option: service.KeyValue{
+			"Restart":                "on-failure", // systemd
+			"OnFailure":              "restart",    // Windows
+			"OnFailureDelayDuration": "5s",
+			"RunWait":                p.wait,
+		},
+	}
+
+	return service.New(p, config)
+}
+
+// runService runs the agent under the service manager that started it, or in
+// the foreground until Ctrl+C / SIGTERM. Either way it returns the agent's
+// error once it has shut down.
+func runService(svc service.Service, p *program) error {
+	if !service.Interactive() {
+		return svc.Run()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return p.run(ctx)
+}
+
+// serviceArguments returns the flags to pass to the installed service,
+// dropping the trailing subcommand (e.g. "install")
+func serviceArguments(args []string, nArgs int) []string {
+	if nArgs > len(args) {
+		return nil
+	}
+	return args[:len(args)-nArgs]
+}
+
+// controlService runs a service management subcommand
+func controlService(svc service.Service, action string) error {
+	if action == "status" {
+		status, err := svc.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Println(statusString(status))
+		return nil
+	}
+
+	for _, valid := range service.ControlAction {
+		if action == valid {
+			if err := service.Control(svc, action); err != nil {
+				return err
+			}
+			fmt.Printf("Service %s: ok\n", action)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown command %q (valid: install, uninstall, start, stop, restart, status)", action)
+}
+
+// statusString describes a service status
+func statusString(status service.Status) string {
+	switch status {
+	case service.StatusRunning:
+		return "running"
+	case service.StatusStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kardianos/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeService records the service management calls made on it
+type fakeService struct {
+	service.Service
+	calls  []string
+	status service.Status
+}
+
+func (f *fakeService) Install() error {
+	f.calls = append(f.calls, "install")
+	return nil
+}
+
+func (f *fakeService) Uninstall() error {
+	f.calls = append(f.calls, "uninstall")
+	return nil
+}
+
+func (f *fakeService) Status() (service.Status, error) {
+	f.calls = append(f.calls, "status")
+	return f.status, nil
+}
+
+func TestServiceArguments(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		nArgs int
+		want  []string
+	}{
+		{"install with flags", []string{"-orchestrator", "host:50051", "install"}, 1, []string{"-orchestrator", "host:50051"}},
+		{"install without flags", []string{"install"}, 1, []string{}},
+		{"uninstall", []string{"-api-key", "secret", "uninstall"}, 1, []string{"-api-key", "secret"}},
+		{"status", []string{"status"}, 1, []string{}},
+		{"more arguments than given", []string{}, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, serviceArguments(tt.args, tt.nArgs))
+		})
+	}
+}
+
+func TestControlService(t *testing.T) {
+	svc := &fakeService{status: service.StatusRunning}
+	require.NoError(t, controlService(svc, "install"))
+	require.NoError(t, controlService(svc, "uninstall"))
+	require.NoError(t, controlService(svc, "status"))
+	assert.Equal(t, []string{"install", "uninstall", "status"}, svc.calls)
+
+	err := controlService(svc, "reinstall")
+	assert.EqualError(t, err, `unknown command "reinstall" (valid: install, uninstall, start, stop, restart, status)`)
+}
+
+func TestStatusString(t *testing.T) {
+	assert.Equal(t, "running", statusString(service.StatusRunning))
+	assert.Equal(t, "stopped", statusString(service.StatusStopped))
+	assert.Equal(t, "unknown", statusString(service.StatusUnknown))
+}
+
+func TestProgram(t *testing.T) {
+	t.Run("stop shuts the agent down", func(t *testing.T) {
+		p := newProgram(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		require.NoError(t, p.Start(&fakeService{}))
+		assert.NoError(t, p.Stop(&fakeService{}))
+	})
+
+	t.Run("failure is returned to the service manager", func(t *testing.T) {
+		failure := errors.New("orchestrator unreachable")
+		p := newProgram(func(ctx context.Context) error { return failure })
+		require.NoError(t, p.Start(&fakeService{}))
+
+		waited := make(chan struct{})
+		go func() {
+			p.wait()
+			close(waited)
+		}()
+		select {
+		case <-waited:
+		case <-time.After(time.Second):
+			t.Fatal("wait didn't return after the agent failed")
+		}
+		assert.Equal(t, failure, p.Stop(&fakeService{}))
+	})
+
+	t.Run("stop before start", func(t *testing.T) {
+		assert.NoError(t, newProgram(nil).Stop(&fakeService{}))
+	})
+}
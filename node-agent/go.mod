@@ -3,17 +3,30 @@ module github.com/Orchion/Orchion/node-agent
 go 1.21
 
 require (
+	github.com/Orchion/Orchion/shared/diagnostics v0.0.0
+	github.com/Orchion/Orchion/shared/lifecycle v0.0.0
 	github.com/Orchion/Orchion/shared/logging v0.0.0
+	github.com/Orchion/Orchion/shared/tailnet v0.0.0
+	github.com/Orchion/Orchion/shared/units v0.0.0
 	github.com/google/uuid v1.6.0
+	github.com/kardianos/service v1.2.2
 	github.com/shirou/gopsutil/v3 v3.24.5
 	github.com/stretchr/testify v1.10.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc v1.28.0
+	go.opentelemetry.io/otel/metric v1.28.0
+	go.opentelemetry.io/otel/sdk/metric v1.28.0
 	google.golang.org/grpc v1.66.3
 	google.golang.org/protobuf v1.34.2
 )
 
 require (
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.20.0 // indirect
 	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
@@ -22,11 +35,23 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
+	go.opentelemetry.io/otel/sdk v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	go.opentelemetry.io/proto/otlp v1.3.1 // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
-	google.golang.org/genproto/googleapis/rpc v0.0.0-20240610135401-a8a62080eff3 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20240701130421-f6361c86f094 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240701130421-f6361c86f094 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
+replace github.com/Orchion/Orchion/shared/diagnostics => ../shared/diagnostics
+
+replace github.com/Orchion/Orchion/shared/lifecycle => ../shared/lifecycle
+
 replace github.com/Orchion/Orchion/shared/logging => ../shared/logging
+
+replace github.com/Orchion/Orchion/shared/tailnet => ../shared/tailnet
+
+replace github.com/Orchion/Orchion/shared/units => ../shared/units
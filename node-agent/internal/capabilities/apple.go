@@ -0,0 +1,71 @@
+package capabilities
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/mem"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// detectAppleSiliconGPU detects the integrated GPU of Apple Silicon Macs.
+// It shares unified memory with the CPU, so the whole memory pool is reported
+// as GPU memory.
+func detectAppleSiliconGPU() (GPUInfo, bool) {
+	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
+		return GPUInfo{}, false
+	}
+
+	chip := "Apple Silicon"
+	if output, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output(); err == nil {
+		if brand := strings.TrimSpace(string(output)); brand != "" {
+			chip = brand
+		}
+	}
+
+	// Every Apple Silicon GPU supports Metal; confirm when system_profiler is available
+	metal := true
+	if output, err := exec.Command("system_profiler", "SPDisplaysDataType").Output(); err == nil {
+		metal = hasMetalSupport(string(output))
+	}
+
+	var total, available uint64
+	if v, err := mem.VirtualMemory(); err == nil {
+		total, available = v.Total, v.Available
+	}
+
+	return appleSiliconGPUInfo(chip, metal, total, available), true
+}
+
+// hasMetalSupport reports whether system_profiler display output lists Metal support
+func hasMetalSupport(profilerOutput string) bool {
+	return strings.Contains(profilerOutput, "Metal")
+}
+
+// appleSiliconGPUInfo describes an Apple Silicon GPU backed by unified memory
+func appleSiliconGPUInfo(chip string, metal bool, totalBytes, availableBytes uint64) GPUInfo {
+	info := GPUInfo{
+		Type:          chip,
+		VRAMTotal:     "Unknown",
+		VRAMAvailable: "Unknown",
+		VRAMUsed:      "Unknown",
+		Temperature:   "N/A",
+		PowerUsage:    "N/A",
+		Backend:       pb.GpuBackend_GPU_BACKEND_CPU,
+		UnifiedMemory: true,
+	}
+	if metal {
+		info.Backend = pb.GpuBackend_GPU_BACKEND_METAL
+	}
+
+	if totalBytes > 0 {
+		info.VRAMTotal = units.FormatBytes(int64(totalBytes))
+		info.VRAMAvailable = units.FormatBytes(int64(availableBytes))
+		info.VRAMUsed = units.FormatBytes(int64(totalBytes - availableBytes))
+	}
+
+	return info
+}
@@ -0,0 +1,43 @@
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func Test_appleSiliconGPUInfo(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+
+	info := appleSiliconGPUInfo("Apple M2 Ultra", true, 192*gb, 150*gb)
+	assert.Equal(t, GPUInfo{
+		Type:          "Apple M2 Ultra",
+		VRAMTotal:     "192.0 GB",
+		VRAMAvailable: "150.0 GB",
+		VRAMUsed:      "42.0 GB",
+		Temperature:   "N/A",
+		PowerUsage:    "N/A",
+		Backend:       pb.GpuBackend_GPU_BACKEND_METAL,
+		UnifiedMemory: true,
+	}, info)
+
+	// Without Metal the GPU can't be used for inference
+	info = appleSiliconGPUInfo("Apple M1", false, 0, 0)
+	assert.Equal(t, pb.GpuBackend_GPU_BACKEND_CPU, info.Backend)
+	assert.Equal(t, "Unknown", info.VRAMTotal)
+}
+
+func Test_hasMetalSupport(t *testing.T) {
+	assert.True(t, hasMetalSupport("Chipset Model: Apple M2 Ultra\n      Metal Support: Metal 3\n"))
+	assert.False(t, hasMetalSupport("Chipset Model: Intel HD Graphics 3000\n"))
+}
+
+func Test_detectGPU_Backend(t *testing.T) {
+	info := detectGPU()
+
+	// The backend is always reported so schedulers never see an unspecified value
+	assert.NotEqual(t, pb.GpuBackend_GPU_BACKEND_UNSPECIFIED, info.Backend)
+	assert.NotEmpty(t, info.Type)
+}
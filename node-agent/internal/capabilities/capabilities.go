@@ -4,153 +4,183 @@ import (
 	"fmt"
 	"os/exec"
 	"runtime"
-	"strconv"
 	"strings"
 
 	"github.com/shirou/gopsutil/v3/mem"
 
 	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
 )
 
-// Detect returns the system capabilities
+// Detect returns the system capabilities using the default detector,
+// which caches GPU queries briefly
 func Detect() *pb.Capabilities {
+	return defaultDetector.Detect()
+}
+
+// Detect returns the system capabilities
+func (d *Detector) Detect() *pb.Capabilities {
 	// Get actual system memory
 	var memoryStr string
 	if v, err := mem.VirtualMemory(); err == nil {
-		totalMemGB := float64(v.Total) / (1024 * 1024 * 1024)
-		memoryStr = strconv.FormatFloat(totalMemGB, 'f', 2, 64) + " GB"
+		memoryStr = units.FormatBytes(int64(v.Total))
 	} else {
 		// Fallback to Go runtime memory if system call fails
 		var memStats runtime.MemStats
 		runtime.ReadMemStats(&memStats)
-		totalMemGB := float64(memStats.Sys) / (1024 * 1024 * 1024)
-		memoryStr = strconv.FormatFloat(totalMemGB, 'f', 2, 64) + " GB (approximate)"
+		memoryStr = units.FormatBytes(int64(memStats.Sys)) + " (approximate)"
 	}
 
 	// Detect GPU information
-	gpuType, gpuVramTotal, gpuVramAvailable, gpuVramUsed, gpuTemperature, gpuPowerUsage := detectGPU()
+	gpu := d.gpu.DetectGPU()
 
 	// Detect system power usage (deprecated, but kept for backward compatibility)
 	powerUsage := detectPowerUsage()
 
 	return &pb.Capabilities{
-		Cpu:              strconv.Itoa(runtime.NumCPU()) + " cores",
+		Cpu:              units.FormatCores(runtime.NumCPU()),
 		Memory:           memoryStr,
 		Os:               runtime.GOOS + "/" + runtime.GOARCH,
-		GpuType:          gpuType,
-		GpuVramTotal:     gpuVramTotal,
-		GpuVramAvailable: gpuVramAvailable,
-		GpuVramUsed:      gpuVramUsed,
-		GpuTemperature:   gpuTemperature,
-		GpuPowerUsage:    gpuPowerUsage,
+		GpuType:          gpu.Type,
+		GpuVramTotal:     gpu.VRAMTotal,
+		GpuVramAvailable: gpu.VRAMAvailable,
+		GpuVramUsed:      gpu.VRAMUsed,
+		GpuTemperature:   gpu.Temperature,
+		GpuPowerUsage:    gpu.PowerUsage,
+		GpuBackend:       gpu.Backend,
+		UnifiedMemory:    gpu.UnifiedMemory,
 		PowerUsage:       powerUsage,
 	}
 }
 
 // detectGPU attempts to detect GPU information using system commands
-func detectGPU() (gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage string) {
+func detectGPU() GPUInfo {
+	// Apple Silicon GPUs share unified memory and are driven through Metal
+	if info, ok := detectAppleSiliconGPU(); ok {
+		return info
+	}
+
+	// Jetson boards share memory with the CPU and report through tegrastats
+	if info, ok := detectJetsonGPU(); ok {
+		return info
+	}
+
 	// Try NVIDIA GPUs first
-	if gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage := detectNVIDIAGPU(); gpuType != "" {
-		return gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage
+	if info := queryNVIDIASMI(); info.Type != "" {
+		info.Backend = pb.GpuBackend_GPU_BACKEND_CUDA
+		return info
 	}
 
 	// Try AMD GPUs
 	if gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage := detectAMDGPU(); gpuType != "" {
-		return gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage
+		return newGPUInfo(pb.GpuBackend_GPU_BACKEND_ROCM, gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage)
 	}
 
-	// Try Intel GPUs
+	// Try Intel GPUs (no supported inference backend, so treated as CPU)
 	if gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage := detectIntelGPU(); gpuType != "" {
-		return gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage
+		return newGPUInfo(pb.GpuBackend_GPU_BACKEND_CPU, gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage)
 	}
 
-	// Fallback: try to detect any GPU
+	// Fallback: try to detect any GPU. Without vendor tools the driver stack is
+	// unknown, so inference engines can't be assumed to use it.
 	if gpuType := detectGenericGPU(); gpuType != "" {
-		return gpuType, "Unknown", "Unknown", "Unknown", "Unknown", "Unknown"
+		return newGPUInfo(pb.GpuBackend_GPU_BACKEND_CPU, gpuType, "Unknown", "Unknown", "Unknown", "Unknown", "Unknown")
 	}
 
-	return "No GPU detected", "N/A", "N/A", "N/A", "N/A", "N/A"
+	return newGPUInfo(pb.GpuBackend_GPU_BACKEND_CPU, "No GPU detected", "N/A", "N/A", "N/A", "N/A", "N/A")
+}
+
+// newGPUInfo builds a GPUInfo from the values returned by the vendor detectors
+func newGPUInfo(backend pb.GpuBackend, gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage string) GPUInfo {
+	return GPUInfo{
+		Type:          gpuType,
+		VRAMTotal:     vramTotal,
+		VRAMAvailable: vramAvailable,
+		VRAMUsed:      vramUsed,
+		Temperature:   temperature,
+		PowerUsage:    powerUsage,
+		Backend:       backend,
+	}
 }
 
+// nvidiaQueryFields are queried from nvidia-smi in a single invocation
+const nvidiaQueryFields = "name,memory.total,memory.free,memory.used,temperature.gpu,power.draw,utilization.gpu"
+
 // detectNVIDIAGPU detects NVIDIA GPUs using nvidia-smi
 func detectNVIDIAGPU() (gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage string) {
+	info := queryNVIDIASMI()
+	return info.Type, info.VRAMTotal, info.VRAMAvailable, info.VRAMUsed, info.Temperature, info.PowerUsage
+}
+
+// queryNVIDIASMI runs a batched nvidia-smi query, returning an empty GPUInfo
+// if nvidia-smi is unavailable
+func queryNVIDIASMI() GPUInfo {
+	output, ok := runNVIDIASMI()
+	if !ok {
+		return GPUInfo{}
+	}
+	return parseNVIDIASMI(output)
+}
+
+// runNVIDIASMI runs a batched nvidia-smi query, reporting false if nvidia-smi
+// is unavailable
+func runNVIDIASMI() (string, bool) {
 	// Check if nvidia-smi is available
 	if _, err := exec.LookPath("nvidia-smi"); err != nil {
-		return "", "", "", "", "", ""
+		return "", false
 	}
 
-	// Get GPU name
-	if output, err := exec.Command("nvidia-smi", "--query-gpu=name", "--format=csv,noheader,nounits").Output(); err == nil {
-		gpuType = strings.TrimSpace(string(output))
-		// Remove trailing newline if present
-		gpuType = strings.TrimSuffix(gpuType, "\n")
-		if strings.Contains(gpuType, ",") {
-			// Multiple GPUs, take the first one
-			gpuType = strings.Split(gpuType, ",")[0]
-		}
-	} else {
-		return "", "", "", "", "", ""
+	// Query all fields at once rather than spawning nvidia-smi per field
+	output, err := exec.Command("nvidia-smi", "--query-gpu="+nvidiaQueryFields, "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return "", false
 	}
+	return string(output), true
+}
 
-	// Get total VRAM
-	if output, err := exec.Command("nvidia-smi", "--query-gpu=memory.total", "--format=csv,noheader,nounits").Output(); err == nil {
-		vramTotalStr := strings.TrimSpace(string(output))
-		vramTotalStr = strings.TrimSuffix(vramTotalStr, "\n")
-		if strings.Contains(vramTotalStr, ",") {
-			vramTotalStr = strings.Split(vramTotalStr, ",")[0]
-		}
-		if vramMB, err := strconv.ParseFloat(strings.TrimSpace(vramTotalStr), 64); err == nil {
-			vramTotal = fmt.Sprintf("%.1f GB", vramMB/1024)
-		}
-	}
+// parseNVIDIASMI parses the CSV output of a batched nvidia-smi query.
+// With multiple GPUs, nvidia-smi prints one line per GPU; the first one is used.
+func parseNVIDIASMI(output string) GPUInfo {
+	return parseNVIDIASMILine(strings.SplitN(strings.TrimSpace(output), "\n", 2)[0])
+}
 
-	// Get available VRAM
-	if output, err := exec.Command("nvidia-smi", "--query-gpu=memory.free", "--format=csv,noheader,nounits").Output(); err == nil {
-		vramFreeStr := strings.TrimSpace(string(output))
-		vramFreeStr = strings.TrimSuffix(vramFreeStr, "\n")
-		if strings.Contains(vramFreeStr, ",") {
-			vramFreeStr = strings.Split(vramFreeStr, ",")[0]
-		}
-		if vramMB, err := strconv.ParseFloat(strings.TrimSpace(vramFreeStr), 64); err == nil {
-			vramAvailable = fmt.Sprintf("%.1f GB", vramMB/1024)
-		}
+// parseNVIDIASMILine parses one GPU's line of a batched nvidia-smi query
+func parseNVIDIASMILine(line string) GPUInfo {
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	if len(fields) < 6 {
+		return GPUInfo{}
 	}
-
-	// Get used VRAM (total - free)
-	if vramTotal != "" && vramAvailable != "" {
-		if totalMB, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(vramTotal), " GB"), 64); err == nil {
-			if availMB, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(vramAvailable), " GB"), 64); err == nil {
-				usedMB := totalMB - availMB
-				vramUsed = fmt.Sprintf("%.1f GB", usedMB)
-			}
-		}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
 	}
 
-	// Get GPU temperature
-	if output, err := exec.Command("nvidia-smi", "--query-gpu=temperature.gpu", "--format=csv,noheader,nounits").Output(); err == nil {
-		tempStr := strings.TrimSpace(string(output))
-		tempStr = strings.TrimSuffix(tempStr, "\n")
-		if strings.Contains(tempStr, ",") {
-			tempStr = strings.Split(tempStr, ",")[0]
-		}
-		if temp, err := strconv.ParseFloat(strings.TrimSpace(tempStr), 64); err == nil {
-			temperature = fmt.Sprintf("%.0f°C", temp)
-		}
+	info := GPUInfo{Type: fields[0]}
+	if info.Type == "" {
+		return GPUInfo{}
 	}
 
-	// Get GPU power usage
-	if output, err := exec.Command("nvidia-smi", "--query-gpu=power.draw", "--format=csv,noheader,nounits").Output(); err == nil {
-		powerStr := strings.TrimSpace(string(output))
-		powerStr = strings.TrimSuffix(powerStr, "\n")
-		if strings.Contains(powerStr, ",") {
-			powerStr = strings.Split(powerStr, ",")[0]
-		}
-		if power, err := strconv.ParseFloat(strings.TrimSpace(powerStr), 64); err == nil {
-			powerUsage = fmt.Sprintf("%.1f W", power)
+	if vramMB, ok := units.ParseMegabytes(fields[1]); ok {
+		info.VRAMTotal = units.FormatMegabytes(vramMB)
+	}
+	if vramMB, ok := units.ParseMegabytes(fields[2]); ok {
+		info.VRAMAvailable = units.FormatMegabytes(vramMB)
+	}
+	if vramMB, ok := units.ParseMegabytes(fields[3]); ok {
+		info.VRAMUsed = units.FormatMegabytes(vramMB)
+	}
+	if temp, ok := units.ParseNumber(fields[4]); ok {
+		info.Temperature = fmt.Sprintf("%.0f°C", temp)
+	}
+	if power, ok := units.ParseNumber(fields[5]); ok {
+		info.PowerUsage = fmt.Sprintf("%.1f W", power)
+	}
+	if len(fields) > 6 {
+		if util, ok := units.ParseNumber(fields[6]); ok {
+			info.Utilization = fmt.Sprintf("%.0f%%", util)
 		}
 	}
 
-	return gpuType, vramTotal, vramAvailable, vramUsed, temperature, powerUsage
+	return info
 }
 
 // detectAMDGPU detects AMD GPUs using rocm-smi
@@ -192,22 +222,19 @@ func detectAMDGPU() (gpuType, vramTotal, vramAvailable, vramUsed, temperature, p
 				// Parse "VRAM Total Memory (GB): 16.0"
 				parts := strings.Split(line, ":")
 				if len(parts) >= 2 {
-					if totalGB, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
-						vramTotal = fmt.Sprintf("%.1f GB", totalGB)
+					if totalGB, ok := units.ParseNumber(parts[1]); ok {
+						vramTotal = units.FormatMegabytes(totalGB * 1024)
 					}
 				}
 			} else if strings.Contains(line, "VRAM Total Used Memory") {
 				// Parse "VRAM Total Used Memory (GB): 2.1"
 				parts := strings.Split(line, ":")
 				if len(parts) >= 2 {
-					if usedGB, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
-						vramUsed = fmt.Sprintf("%.1f GB", usedGB)
+					if usedGB, ok := units.ParseNumber(parts[1]); ok {
+						vramUsed = units.FormatMegabytes(usedGB * 1024)
 						// Calculate available if we have total
-						if vramTotal != "" {
-							if totalGB, err := strconv.ParseFloat(strings.TrimSuffix(vramTotal, " GB"), 64); err == nil {
-								availGB := totalGB - usedGB
-								vramAvailable = fmt.Sprintf("%.1f GB", availGB)
-							}
+						if totalMB, ok := units.ParseMegabytes(vramTotal); ok {
+							vramAvailable = units.FormatMegabytes(totalMB - usedGB*1024)
 						}
 					}
 				}
@@ -225,11 +252,7 @@ func detectAMDGPU() (gpuType, vramTotal, vramAvailable, vramUsed, temperature, p
 				// Parse temperature line
 				parts := strings.Split(line, ":")
 				if len(parts) >= 2 {
-					tempStr := strings.TrimSpace(parts[1])
-					// Remove unit if present
-					tempStr = strings.TrimSuffix(tempStr, "c")
-					tempStr = strings.TrimSuffix(tempStr, "C")
-					if temp, err := strconv.ParseFloat(tempStr, 64); err == nil {
+					if temp, ok := units.ParseNumber(parts[1]); ok {
 						temperature = fmt.Sprintf("%.0f°C", temp)
 						break
 					}
@@ -248,7 +271,7 @@ func detectAMDGPU() (gpuType, vramTotal, vramAvailable, vramUsed, temperature, p
 				// Parse "Average Graphics Package Power (W): 45.0"
 				parts := strings.Split(line, ":")
 				if len(parts) >= 2 {
-					if power, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
+					if power, ok := units.ParseNumber(parts[1]); ok {
 						powerUsage = fmt.Sprintf("%.1f W", power)
 						break
 					}
@@ -380,7 +403,7 @@ func detectPowerUsageLinux() string {
 	if output, err := exec.Command("cat", "/sys/class/power_supply/BAT*/power_now", "2>/dev/null", "||", "echo", "No battery").Output(); err == nil {
 		powerStr := strings.TrimSpace(string(output))
 		if powerStr != "No battery" && powerStr != "" {
-			if powerW, err := strconv.ParseFloat(strings.TrimSpace(powerStr), 64); err == nil {
+			if powerW, ok := units.ParseNumber(powerStr); ok {
 				return fmt.Sprintf("%.2f W (battery)", powerW/1000000) // Convert from microwatts
 			}
 		}
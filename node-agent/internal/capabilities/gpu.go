@@ -0,0 +1,85 @@
+package capabilities
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// DefaultGPUCacheTTL is how long GPU query results are reused by Detect
+const DefaultGPUCacheTTL = 5 * time.Second
+
+// defaultDetector backs the package-level Detect function
+var defaultDetector = NewDetector(NewCachedGPUDetector(SystemGPUDetector{}, DefaultGPUCacheTTL))
+
+// GPUInfo describes the GPU of a node
+type GPUInfo struct {
+	Type          string
+	VRAMTotal     string
+	VRAMAvailable string
+	VRAMUsed      string
+	Temperature   string
+	PowerUsage    string
+	Utilization   string // GPU load, e.g. "45%"; empty if the vendor tool doesn't report it
+	Backend       pb.GpuBackend
+	UnifiedMemory bool // GPU shares system memory; VRAM values describe the shared pool
+}
+
+// GPUDetector detects GPU information
+type GPUDetector interface {
+	DetectGPU() GPUInfo
+}
+
+// SystemGPUDetector detects GPUs by querying vendor tools (nvidia-smi, rocm-smi, ...)
+type SystemGPUDetector struct{}
+
+// DetectGPU implements GPUDetector
+func (SystemGPUDetector) DetectGPU() GPUInfo {
+	return detectGPU()
+}
+
+// CachedGPUDetector reuses the results of another detector for a short time,
+// so frequent capability updates don't shell out to vendor tools every tick
+type CachedGPUDetector struct {
+	detector GPUDetector
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	info    GPUInfo
+	fetched time.Time
+}
+
+// NewCachedGPUDetector creates a detector caching results of detector for ttl
+func NewCachedGPUDetector(detector GPUDetector, ttl time.Duration) *CachedGPUDetector {
+	return &CachedGPUDetector{
+		detector: detector,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// DetectGPU implements GPUDetector
+func (c *CachedGPUDetector) DetectGPU() GPUInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && c.now().Sub(c.fetched) < c.ttl {
+		return c.info
+	}
+
+	c.info = c.detector.DetectGPU()
+	c.fetched = c.now()
+	return c.info
+}
+
+// Detector detects node capabilities
+type Detector struct {
+	gpu GPUDetector
+}
+
+// NewDetector creates a capability detector using the given GPU detector
+func NewDetector(gpu GPUDetector) *Detector {
+	return &Detector{gpu: gpu}
+}
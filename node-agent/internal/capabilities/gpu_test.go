@@ -0,0 +1,128 @@
+package capabilities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockGPUDetector is a mock implementation of GPUDetector for testing
+type MockGPUDetector struct {
+	mock.Mock
+}
+
+func (m *MockGPUDetector) DetectGPU() GPUInfo {
+	args := m.Called()
+	return args.Get(0).(GPUInfo)
+}
+
+func TestDetector_Detect(t *testing.T) {
+	gpu := &MockGPUDetector{}
+	gpu.On("DetectGPU").Return(GPUInfo{
+		Type:          "NVIDIA GeForce RTX 4090",
+		VRAMTotal:     "24.0 GB",
+		VRAMAvailable: "20.0 GB",
+		VRAMUsed:      "4.0 GB",
+		Temperature:   "55°C",
+		PowerUsage:    "120.0 W",
+	})
+
+	caps := NewDetector(gpu).Detect()
+
+	assert.Equal(t, "NVIDIA GeForce RTX 4090", caps.GpuType)
+	assert.Equal(t, "24.0 GB", caps.GpuVramTotal)
+	assert.Equal(t, "20.0 GB", caps.GpuVramAvailable)
+	assert.Equal(t, "4.0 GB", caps.GpuVramUsed)
+	assert.Equal(t, "55°C", caps.GpuTemperature)
+	assert.Equal(t, "120.0 W", caps.GpuPowerUsage)
+	assert.Contains(t, caps.Cpu, "cores")
+	gpu.AssertExpectations(t)
+}
+
+func TestCachedGPUDetector(t *testing.T) {
+	gpu := &MockGPUDetector{}
+	gpu.On("DetectGPU").Return(GPUInfo{Type: "Test GPU"})
+
+	now := time.Now()
+	cached := NewCachedGPUDetector(gpu, 5*time.Second)
+	cached.now = func() time.Time { return now }
+
+	assert.Equal(t, "Test GPU", cached.DetectGPU().Type)
+	assert.Equal(t, "Test GPU", cached.DetectGPU().Type)
+	gpu.AssertNumberOfCalls(t, "DetectGPU", 1)
+
+	// Results are refreshed once the TTL has elapsed
+	now = now.Add(6 * time.Second)
+	cached.DetectGPU()
+	gpu.AssertNumberOfCalls(t, "DetectGPU", 2)
+}
+
+func Test_parseNVIDIASMI(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   GPUInfo
+	}{
+		{
+			name:   "single GPU",
+			output: "NVIDIA GeForce RTX 4090, 24564, 20480, 4084, 55, 120.50\n",
+			want: GPUInfo{
+				Type:          "NVIDIA GeForce RTX 4090",
+				VRAMTotal:     "24.0 GB",
+				VRAMAvailable: "20.0 GB",
+				VRAMUsed:      "4.0 GB",
+				Temperature:   "55°C",
+				PowerUsage:    "120.5 W",
+			},
+		},
+		{
+			name:   "with utilization",
+			output: "NVIDIA GeForce RTX 4090, 24564, 20480, 4084, 55, 120.50, 87\n",
+			want: GPUInfo{
+				Type:          "NVIDIA GeForce RTX 4090",
+				VRAMTotal:     "24.0 GB",
+				VRAMAvailable: "20.0 GB",
+				VRAMUsed:      "4.0 GB",
+				Temperature:   "55°C",
+				PowerUsage:    "120.5 W",
+				Utilization:   "87%",
+			},
+		},
+		{
+			name:   "multiple GPUs uses the first",
+			output: "NVIDIA A100, 40960, 40960, 0, 30, 50.00\nNVIDIA T4, 16384, 16384, 0, 40, 20.00\n",
+			want: GPUInfo{
+				Type:          "NVIDIA A100",
+				VRAMTotal:     "40.0 GB",
+				VRAMAvailable: "40.0 GB",
+				VRAMUsed:      "0.0 GB",
+				Temperature:   "30°C",
+				PowerUsage:    "50.0 W",
+			},
+		},
+		{
+			name:   "unsupported fields",
+			output: "NVIDIA GeForce GTX 1050, 2048, 1024, 1024, 45, [N/A]\n",
+			want: GPUInfo{
+				Type:          "NVIDIA GeForce GTX 1050",
+				VRAMTotal:     "2.0 GB",
+				VRAMAvailable: "1.0 GB",
+				VRAMUsed:      "1.0 GB",
+				Temperature:   "45°C",
+			},
+		},
+		{
+			name:   "malformed output",
+			output: "No devices were found",
+			want:   GPUInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseNVIDIASMI(tt.output))
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package capabilities
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+const (
+	// tegraReleaseFile identifies NVIDIA Jetson boards and their L4T release
+	tegraReleaseFile = "/etc/nv_tegra_release"
+	// deviceTreeModelFile holds the board name on Jetson devices
+	deviceTreeModelFile = "/proc/device-tree/model"
+)
+
+var (
+	tegraReleaseRe = regexp.MustCompile(`R(\d+) \(release\), REVISION: (\d+)\.(\d+)`)
+	tegraRAMRe     = regexp.MustCompile(`RAM (\d+)/(\d+)MB`)
+	tegraGPUTempRe = regexp.MustCompile(`(?i)\bgpu@(-?[\d.]+)C`)
+	tegraGPULoadRe = regexp.MustCompile(`GR3D_FREQ (\d+)%`)
+	tegraRailRe    = regexp.MustCompile(`(\w+) (\d+)(?:mW)?/(\d+)(?:mW)?`)
+)
+
+// JetsonRelease returns the L4T release of an NVIDIA Jetson board (e.g. "r36.2.0").
+// The returned bool is false when not running on a Jetson.
+func JetsonRelease() (string, bool) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "arm64" {
+		return "", false
+	}
+
+	data, err := os.ReadFile(tegraReleaseFile)
+	if err != nil {
+		return "", false
+	}
+	return parseTegraRelease(string(data))
+}
+
+// parseTegraRelease parses /etc/nv_tegra_release, e.g.
+// "# R36 (release), REVISION: 2.0, GCID: ..., BOARD: generic, EABI: aarch64"
+func parseTegraRelease(content string) (string, bool) {
+	m := tegraReleaseRe.FindStringSubmatch(content)
+	if m == nil {
+		return "", false
+	}
+	return fmt.Sprintf("r%s.%s.%s", m[1], m[2], m[3]), true
+}
+
+// detectJetsonGPU detects the integrated GPU of NVIDIA Jetson boards. Jetsons
+// share memory between CPU and GPU and are monitored with tegrastats rather than nvidia-smi.
+func detectJetsonGPU() (GPUInfo, bool) {
+	if _, ok := JetsonRelease(); !ok {
+		return GPUInfo{}, false
+	}
+
+	model := "NVIDIA Jetson"
+	if data, err := os.ReadFile(deviceTreeModelFile); err == nil {
+		if name := strings.TrimSpace(strings.TrimRight(string(data), "\x00")); name != "" {
+			model = name
+		}
+	}
+
+	return parseTegrastats(model, readTegrastats()), true
+}
+
+// readTegrastats returns a single line of tegrastats output, or "" if unavailable
+func readTegrastats() string {
+	if _, err := exec.LookPath("tegrastats"); err != nil {
+		return ""
+	}
+
+	// tegrastats reports continuously; read one sample and stop it
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "tegrastats", "--interval", "100")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return ""
+	}
+	if err := cmd.Start(); err != nil {
+		return ""
+	}
+	defer func() {
+		cancel()
+		cmd.Wait()
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	if scanner.Scan() {
+		return scanner.Text()
+	}
+	return ""
+}
+
+// parseTegrastats builds GPU information from a tegrastats sample such as
+// "RAM 2448/7764MB ... GPU@41.5C ... VDD_IN 4783mW/4783mW VDD_CPU_GPU_CV 679mW/679mW".
+// Older boards report rails without units, e.g. "POM_5V_GPU 1200/1100".
+func parseTegrastats(model, line string) GPUInfo {
+	info := GPUInfo{
+		Type:          model,
+		VRAMTotal:     "Unknown",
+		VRAMAvailable: "Unknown",
+		VRAMUsed:      "Unknown",
+		Temperature:   "Unknown",
+		PowerUsage:    "Unknown",
+		Backend:       pb.GpuBackend_GPU_BACKEND_CUDA,
+		UnifiedMemory: true,
+	}
+
+	if m := tegraRAMRe.FindStringSubmatch(line); m != nil {
+		used, _ := units.ParseMegabytes(m[1])
+		total, _ := units.ParseMegabytes(m[2])
+		info.VRAMTotal = units.FormatMegabytes(total)
+		info.VRAMAvailable = units.FormatMegabytes(total - used)
+		info.VRAMUsed = units.FormatMegabytes(used)
+	}
+
+	if m := tegraGPUTempRe.FindStringSubmatch(line); m != nil {
+		if temp, ok := units.ParseNumber(m[1]); ok {
+			info.Temperature = fmt.Sprintf("%.0f°C", temp)
+		}
+	}
+
+	if m := tegraGPULoadRe.FindStringSubmatch(line); m != nil {
+		info.Utilization = m[1] + "%"
+	}
+
+	// Prefer a GPU-specific rail, falling back to total board input power
+	var gpuPower, inputPower string
+	for _, m := range tegraRailRe.FindAllStringSubmatch(line, -1) {
+		rail := strings.ToUpper(m[1])
+		mw, ok := units.ParseNumber(m[2])
+		if !ok {
+			continue
+		}
+		watts := fmt.Sprintf("%.1f W", mw/1000)
+		switch {
+		case strings.Contains(rail, "GPU") && gpuPower == "":
+			gpuPower = watts
+		case (rail == "VDD_IN" || rail == "POM_5V_IN") && inputPower == "":
+			inputPower = watts
+		}
+	}
+	if gpuPower != "" {
+		info.PowerUsage = gpuPower
+	} else if inputPower != "" {
+		info.PowerUsage = inputPower
+	}
+
+	return info
+}
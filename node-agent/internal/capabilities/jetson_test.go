@@ -0,0 +1,78 @@
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func Test_parseTegrastats(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		total       string
+		available   string
+		temperature string
+		power       string
+		utilization string
+	}{
+		{
+			name:        "Orin",
+			line:        "RAM 2448/7764MB (lfb 2x4MB) SWAP 0/3882MB (cached 0MB) CPU [1%@729,0%@729] GR3D_FREQ 0% cpu@47.5C soc2@45.3C gpu@46.1C VDD_IN 4783mW/4783mW VDD_CPU_GPU_CV 679mW/679mW VDD_SOC 1437mW/1437mW",
+			total:       "7.6 GB",
+			available:   "5.2 GB",
+			temperature: "46°C",
+			power:       "0.7 W",
+			utilization: "0%",
+		},
+		{
+			name:        "Nano without units",
+			line:        "RAM 1520/3964MB (lfb 4x4MB) CPU [2%@102,off,off,off] GPU@33C PLL@33C POM_5V_IN 2100/2100 POM_5V_GPU 40/40 POM_5V_CPU 321/321",
+			total:       "3.9 GB",
+			available:   "2.4 GB",
+			temperature: "33°C",
+			power:       "0.0 W",
+		},
+		{
+			name:        "board input only",
+			line:        "RAM 1000/2048MB VDD_IN 5000mW/5000mW",
+			total:       "2.0 GB",
+			available:   "1.0 GB",
+			temperature: "Unknown",
+			power:       "5.0 W",
+		},
+		{
+			name:        "no output",
+			line:        "",
+			total:       "Unknown",
+			available:   "Unknown",
+			temperature: "Unknown",
+			power:       "Unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseTegrastats("NVIDIA Jetson", tt.line)
+			assert.Equal(t, "NVIDIA Jetson", info.Type)
+			assert.Equal(t, tt.total, info.VRAMTotal)
+			assert.Equal(t, tt.available, info.VRAMAvailable)
+			assert.Equal(t, tt.temperature, info.Temperature)
+			assert.Equal(t, tt.power, info.PowerUsage)
+			assert.Equal(t, tt.utilization, info.Utilization)
+			assert.Equal(t, pb.GpuBackend_GPU_BACKEND_CUDA, info.Backend)
+			assert.True(t, info.UnifiedMemory)
+		})
+	}
+}
+
+func Test_parseTegraRelease(t *testing.T) {
+	release, ok := parseTegraRelease("# R36 (release), REVISION: 2.0, GCID: 35084178, BOARD: generic, EABI: aarch64, DATE: Tue Dec 19 05:55:03 UTC 2023\n")
+	assert.True(t, ok)
+	assert.Equal(t, "r36.2.0", release)
+
+	_, ok = parseTegraRelease("not a tegra release")
+	assert.False(t, ok)
+}
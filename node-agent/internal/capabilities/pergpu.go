@@ -0,0 +1,160 @@
+package capabilities
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// GPUDevice is an NVIDIA GPU, or a MIG slice of one, that an agent can serve
+// as its own logical node
+type GPUDevice struct {
+	Name       string // "gpu0", or "gpu0-mig1" for a MIG slice; suffixes the node ID
+	ID         string // Device given to container runtimes: the GPU's nvidia-smi index or the slice's MIG UUID
+	GPU        int    // nvidia-smi index of the (parent) GPU
+	MIGProfile string // MIG profile of a slice, e.g. "1g.5gb"; empty for a whole GPU
+	Info       GPUInfo
+}
+
+// DetectGPUDevices returns the NVIDIA GPUs in nvidia-smi order, with GPUs in
+// MIG mode replaced by their slices. It returns nil without NVIDIA GPUs.
+func DetectGPUDevices() []GPUDevice {
+	output, ok := runNVIDIASMI()
+	if !ok {
+		return nil
+	}
+	// nvidia-smi -L lists MIG slices, which the batched query doesn't
+	list, err := exec.Command("nvidia-smi", "-L").Output()
+	if err != nil {
+		list = nil
+	}
+	return gpuDevices(parseNVIDIASMIGPUs(output), parseMIGSlices(string(list)))
+}
+
+// DetectPerGPU returns the node's capabilities for each GPU device, in
+// DetectGPUDevices order, for agents registering one logical node per device.
+// CPU and memory are those of the whole machine.
+func DetectPerGPU() []*pb.Capabilities {
+	return DeviceCapabilities(Detect(), DetectGPUDevices())
+}
+
+// parseNVIDIASMIGPUs parses every GPU's line of a batched nvidia-smi query
+func parseNVIDIASMIGPUs(output string) []GPUInfo {
+	var gpus []GPUInfo
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if info := parseNVIDIASMILine(line); info.Type != "" {
+			gpus = append(gpus, info)
+		}
+	}
+	return gpus
+}
+
+// migSlice is a MIG device listed by nvidia-smi -L
+type migSlice struct {
+	profile string
+	uuid    string
+}
+
+var (
+	// e.g. "GPU 0: NVIDIA A100-SXM4-40GB (UUID: GPU-5d5ba0d6-...)"
+	nvidiaGPULine = regexp.MustCompile(`^GPU (\d+):`)
+	// e.g. "  MIG 1g.5gb     Device  0: (UUID: MIG-c6d4f1ef-...)"
+	nvidiaMIGLine = regexp.MustCompile(`^\s+MIG\s+(\S+)\s+Device\s+\d+:\s+\(UUID:\s+([^)\s]+)\)`)
+	// VRAM of a MIG profile, e.g. the 5 of "1g.5gb" or "1g.10gb+me"
+	migProfileMemory = regexp.MustCompile(`\.(\d+)gb`)
+)
+
+// parseMIGSlices parses nvidia-smi -L output into the MIG slices of each GPU
+// index. GPUs not in MIG mode have no slices.
+func parseMIGSlices(output string) map[int][]migSlice {
+	slices := make(map[int][]migSlice)
+	gpu := -1
+	for _, line := range strings.Split(output, "\n") {
+		if m := nvidiaGPULine.FindStringSubmatch(line); m != nil {
+			gpu, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if m := nvidiaMIGLine.FindStringSubmatch(line); m != nil && gpu >= 0 {
+			slices[gpu] = append(slices[gpu], migSlice{profile: m[1], uuid: m[2]})
+		}
+	}
+	return slices
+}
+
+// gpuDevices returns a device per GPU, or per MIG slice of GPUs in MIG mode.
+// Slices get their VRAM from the profile and the GPU's sensors; per-slice
+// usage isn't reported, so their free VRAM is left unknown.
+func gpuDevices(gpus []GPUInfo, slices map[int][]migSlice) []GPUDevice {
+	var devices []GPUDevice
+	for i, gpu := range gpus {
+		gpu.Backend = pb.GpuBackend_GPU_BACKEND_CUDA
+		if len(slices[i]) == 0 {
+			devices = append(devices, GPUDevice{Name: fmt.Sprintf("gpu%d", i), ID: strconv.Itoa(i), GPU: i, Info: gpu})
+			continue
+		}
+		for j, slice := range slices[i] {
+			info := GPUInfo{
+				Type:        gpu.Type + " MIG " + slice.profile,
+				Temperature: gpu.Temperature,
+				PowerUsage:  gpu.PowerUsage,
+				Backend:     gpu.Backend,
+			}
+			if m := migProfileMemory.FindStringSubmatch(slice.profile); m != nil {
+				gb, _ := units.ParseNumber(m[1])
+				info.VRAMTotal = units.FormatMegabytes(gb * 1024)
+			}
+			devices = append(devices, GPUDevice{
+				Name:       fmt.Sprintf("gpu%d-mig%d", i, j),
+				ID:         slice.uuid,
+				GPU:        i,
+				MIGProfile: slice.profile,
+				Info:       info,
+			})
+		}
+	}
+	return devices
+}
+
+// DeviceCapabilities returns a copy of base for each device, with the GPU
+// fields replaced by the device's
+func DeviceCapabilities(base *pb.Capabilities, devices []GPUDevice) []*pb.Capabilities {
+	var caps []*pb.Capabilities
+	for _, device := range devices {
+		gpu := device.Info
+		caps = append(caps, &pb.Capabilities{
+			Cpu:              base.GetCpu(),
+			Memory:           base.GetMemory(),
+			Os:               base.GetOs(),
+			GpuType:          gpu.Type,
+			GpuVramTotal:     gpu.VRAMTotal,
+			GpuVramAvailable: gpu.VRAMAvailable,
+			GpuVramUsed:      gpu.VRAMUsed,
+			GpuTemperature:   gpu.Temperature,
+			GpuPowerUsage:    gpu.PowerUsage,
+			GpuBackend:       gpu.Backend,
+			PowerUsage:       base.GetPowerUsage(),
+		})
+	}
+	return caps
+}
+
+// GPUDeviceDetector detects a single GPU device by name, for the engines of a
+// logical per-GPU node
+type GPUDeviceDetector struct {
+	Name string
+}
+
+// DetectGPU implements GPUDetector
+func (d GPUDeviceDetector) DetectGPU() GPUInfo {
+	for _, device := range DetectGPUDevices() {
+		if device.Name == d.Name {
+			return device.Info
+		}
+	}
+	return GPUInfo{}
+}
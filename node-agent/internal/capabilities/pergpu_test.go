@@ -0,0 +1,61 @@
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestDeviceCapabilities(t *testing.T) {
+	gpus := parseNVIDIASMIGPUs("NVIDIA A100, 40960, 30720, 10240, 30, 50.00\n\nNVIDIA T4, 16384, 16384, 0, 40, 20.00\nNo devices were found\n")
+	require.Len(t, gpus, 2)
+	devices := gpuDevices(gpus, nil)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "gpu1", devices[1].Name)
+	assert.Equal(t, "1", devices[1].ID)
+
+	base := &pb.Capabilities{Cpu: "64 cores", Memory: "512.00 GB", Os: "linux/amd64", GpuType: "NVIDIA A100"}
+	caps := DeviceCapabilities(base, devices)
+	require.Len(t, caps, 2)
+
+	assert.Equal(t, "NVIDIA A100", caps[0].GpuType)
+	assert.Equal(t, "30.0 GB", caps[0].GpuVramAvailable)
+	assert.Equal(t, "NVIDIA T4", caps[1].GpuType)
+	assert.Equal(t, "16.0 GB", caps[1].GpuVramTotal)
+	for _, c := range caps {
+		assert.Equal(t, "64 cores", c.Cpu)
+		assert.Equal(t, "512.00 GB", c.Memory)
+		assert.Equal(t, pb.GpuBackend_GPU_BACKEND_CUDA, c.GpuBackend)
+	}
+}
+
+func TestGPUDevices_MIG(t *testing.T) {
+	list := `GPU 0: NVIDIA A100-SXM4-40GB (UUID: GPU-5d5ba0d6-d33d-2b2c-524d-9e3d8d2b8a7f)
+  MIG 1g.5gb      Device  0: (UUID: MIG-c6d4f1ef-42e4-5de3-91c7-45d71c87eb3f)
+  MIG 3g.20gb     Device  1: (UUID: MIG-4e8c0f8a-2b6f-5b63-8d1a-1f0c9e0b8d2e)
+GPU 1: NVIDIA T4 (UUID: GPU-0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0)
+`
+	gpus := parseNVIDIASMIGPUs("NVIDIA A100-SXM4-40GB, 40960, 30720, 10240, 30, 50.00\nNVIDIA T4, 16384, 16384, 0, 40, 20.00\n")
+	devices := gpuDevices(gpus, parseMIGSlices(list))
+	require.Len(t, devices, 3)
+
+	assert.Equal(t, "gpu0-mig0", devices[0].Name)
+	assert.Equal(t, "MIG-c6d4f1ef-42e4-5de3-91c7-45d71c87eb3f", devices[0].ID)
+	assert.Equal(t, "1g.5gb", devices[0].MIGProfile)
+	assert.Equal(t, "NVIDIA A100-SXM4-40GB MIG 1g.5gb", devices[0].Info.Type)
+	assert.Equal(t, "5.0 GB", devices[0].Info.VRAMTotal)
+	assert.Empty(t, devices[0].Info.VRAMAvailable)
+
+	assert.Equal(t, "gpu0-mig1", devices[1].Name)
+	assert.Equal(t, "20.0 GB", devices[1].Info.VRAMTotal)
+	assert.Equal(t, 0, devices[1].GPU)
+
+	// GPUs outside MIG mode stay whole
+	assert.Equal(t, "gpu1", devices[2].Name)
+	assert.Equal(t, "1", devices[2].ID)
+	assert.Equal(t, "16.0 GB", devices[2].Info.VRAMTotal)
+	assert.Empty(t, devices[2].MIGProfile)
+}
@@ -14,6 +14,8 @@ type Manager interface {
 	StopContainer(ctx context.Context, name string) error
 	IsRunning(ctx context.Context, name string) (bool, error)
 	EnsureRunning(ctx context.Context, config *ContainerConfig) error
+	ImageDigest(ctx context.Context, image string) (string, error)
+	PullImage(ctx context.Context, image string) error
 	TestConnection() error
 }
 
@@ -27,6 +29,11 @@ type ContainerConfig struct {
 	Environment []string // Environment variables
 	Volumes     []string // Volume mounts
 	Args        []string // Additional arguments
+	Command     []string // Command and arguments run in the container, overriding the image default
+
+	// NVIDIARuntime requests GPU access through the NVIDIA container runtime,
+	// as required on Jetson boards, instead of GPU device flags
+	NVIDIARuntime bool
 }
 
 // ContainerRuntime represents the type of container runtime
@@ -133,6 +140,15 @@ func (m *ContainerManager) StartContainer(ctx context.Context, config *Container
 		}
 	}
 
+	// NVIDIA container runtime (Jetson)
+	if config.NVIDIARuntime {
+		if m.runtime == RuntimePodman {
+			args = append(args, "--device", "nvidia.com/gpu=all")
+		} else {
+			args = append(args, "--runtime", "nvidia")
+		}
+	}
+
 	// Environment variables
 	for _, env := range config.Environment {
 		args = append(args, "-e", env)
@@ -149,6 +165,9 @@ func (m *ContainerManager) StartContainer(ctx context.Context, config *Container
 	// Image
 	args = append(args, config.Image)
 
+	// Command override
+	args = append(args, config.Command...)
+
 	runtimeName := string(m.runtime)
 	log.Printf("Starting container %s: %s %s", config.Name, runtimeName, strings.Join(args, " "))
 
@@ -200,6 +219,56 @@ func (m *ContainerManager) EnsureRunning(ctx context.Context, config *ContainerC
 	return nil
 }
 
+// ImageDigest returns the content digest (e.g. "sha256:...") of a local image,
+// or an empty string for images built locally that were never pushed or pulled
+func (m *ContainerManager) ImageDigest(ctx context.Context, image string) (string, error) {
+	cmd := exec.CommandContext(ctx, m.runtimePath, "image", "inspect", "--format", "{{range .RepoDigests}}{{println .}}{{end}}", image)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+	return parseRepoDigest(string(output)), nil
+}
+
+// PullImage pulls an image from its registry, so containers using it start
+// without waiting for the download
+func (m *ContainerManager) PullImage(ctx context.Context, image string) error {
+	cmd := exec.CommandContext(ctx, m.runtimePath, "pull", image)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w\nOutput: %s", image, err, string(output))
+	}
+	return nil
+}
+
+// Repository returns an image reference without its tag or digest, e.g.
+// "vllm/vllm-openai" for "vllm/vllm-openai:latest". Registry ports are kept,
+// as in "registry:5000/team/ollama".
+func Repository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// PinImage returns an image reference pinned to a digest, e.g.
+// "vllm/vllm-openai@sha256:..." for "vllm/vllm-openai:latest"
+func PinImage(image, digest string) string {
+	return Repository(image) + "@" + digest
+}
+
+// parseRepoDigest extracts the digest from the first "repo@sha256:..." line
+// of image inspect output
+func parseRepoDigest(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if _, digest, ok := strings.Cut(strings.TrimSpace(line), "@"); ok {
+			return digest
+		}
+	}
+	return ""
+}
+
 // TestConnection tests if the container runtime is available and working
 func (m *ContainerManager) TestConnection() error {
 	cmd := exec.Command(m.runtimePath, "version")
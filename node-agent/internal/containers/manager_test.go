@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewContainerManager(t *testing.T) {
@@ -62,6 +63,46 @@ func TestContainerManager_TestConnection(t *testing.T) {
 	assert.True(t, true, "Test completed without crashing")
 }
 
+func TestParseRepoDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "single digest", output: "docker.io/vllm/vllm-openai@sha256:abc123\n", want: "sha256:abc123"},
+		{name: "first of several", output: "ollama/ollama@sha256:aaa\nmirror/ollama@sha256:bbb\n", want: "sha256:aaa"},
+		{name: "locally built image", output: "\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRepoDigest(tt.output))
+		})
+	}
+}
+
+func TestRepository(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"vllm/vllm-openai:latest", "vllm/vllm-openai"},
+		{"vllm/vllm-openai", "vllm/vllm-openai"},
+		{"ollama/ollama@sha256:abc", "ollama/ollama"},
+		{"ollama/ollama:0.3.12@sha256:abc", "ollama/ollama"},
+		{"registry:5000/team/ollama", "registry:5000/team/ollama"},
+		{"registry:5000/team/ollama:r36.2.0", "registry:5000/team/ollama"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, Repository(tt.image))
+		})
+	}
+
+	assert.Equal(t, "vllm/vllm-openai@sha256:abc", PinImage("vllm/vllm-openai:latest", "sha256:abc"))
+}
+
 func TestContainerConfig_Empty(t *testing.T) {
 	config := &ContainerConfig{}
 
@@ -74,4 +115,61 @@ func TestContainerConfig_Empty(t *testing.T) {
 	assert.Empty(t, config.Environment)
 	assert.Empty(t, config.Volumes)
 	assert.Empty(t, config.Args)
-}
\ No newline at end of file
+}
+func TestJetsonOllamaConfig(t *testing.T) {
+	cfg := JetsonOllamaConfig("r36.2.0")
+	assert.Equal(t, "dustynv/ollama:r36.2.0", cfg.Image)
+	assert.True(t, cfg.NVIDIARuntime)
+	assert.Empty(t, cfg.GPUs)
+
+	config := CreateOllamaContainerConfig(cfg)
+	assert.Equal(t, "dustynv/ollama:r36.2.0", config.Image)
+	assert.True(t, config.NVIDIARuntime)
+
+	// Regular hosts keep the upstream image
+	assert.Equal(t, DefaultOllamaImage, CreateOllamaContainerConfig(DefaultOllamaConfig()).Image)
+}
+
+func TestCreateVLLMRayContainerConfig(t *testing.T) {
+	head := CreateVLLMRayContainerConfig(&VLLMRayConfig{
+		DeploymentID:         "dep-1",
+		Model:                "meta-llama/Llama-3-70B",
+		Port:                 8000,
+		Head:                 true,
+		TensorParallelSize:   2,
+		PipelineParallelSize: 3,
+	})
+	assert.Equal(t, "orchion-vllm-meta-llama-Llama-3-70B", head.Name)
+	assert.Contains(t, head.Args, "host")
+	assert.Equal(t, []string{"-c", "ray start --head --port=6379 && exec vllm serve meta-llama/Llama-3-70B --port 8000 --host 0.0.0.0 --distributed-executor-backend ray --tensor-parallel-size 2 --pipeline-parallel-size 3"}, head.Command)
+
+	worker := CreateVLLMRayContainerConfig(&VLLMRayConfig{
+		DeploymentID: "dep-1",
+		Model:        "meta-llama/Llama-3-70B",
+		HeadAddress:  "gpu-box:6379",
+	})
+	assert.Equal(t, "orchion-vllm-ray-dep-1", worker.Name)
+	assert.Equal(t, []string{"-c", "ray start --block --address=gpu-box:6379"}, worker.Command)
+}
+
+func TestCreatePiperContainerConfig(t *testing.T) {
+	config := CreatePiperContainerConfig(&PiperConfig{Voice: "en_US-lessac-medium", Port: 5001, NameSuffix: "-gpu1"})
+	assert.Equal(t, "orchion-piper-gpu1", config.Name)
+	assert.Equal(t, DefaultPiperImage, config.Image)
+	assert.Equal(t, 5001, config.Port)
+	assert.Equal(t, []string{"piper-data:/data"}, config.Volumes)
+	require.Len(t, config.Command, 2)
+	assert.Contains(t, config.Command[1], "download_voices --data-dir /data en_US-lessac-medium")
+	assert.Contains(t, config.Command[1], "exec /data/venv/bin/python3 -m piper.http_server --host 0.0.0.0 --port 5001 --data-dir /data -m en_US-lessac-medium")
+}
+
+func TestCreateCoquiContainerConfig(t *testing.T) {
+	config := CreateCoquiContainerConfig(&CoquiConfig{Model: "tts_models/en/vctk/vits", Port: 5002})
+	assert.Equal(t, "orchion-coqui-tts-models-en-vctk-vits", config.Name)
+	assert.Equal(t, DefaultCoquiImage, config.Image)
+	assert.Equal(t, []string{"TTS/server/server.py", "--model_name", "tts_models/en/vctk/vits", "--port", "5002"}, config.Command)
+
+	gpu := CreateCoquiContainerConfig(&CoquiConfig{Model: "tts_models/en/vctk/vits", Port: 5002, GPUs: []string{"0"}, Image: "ghcr.io/coqui-ai/tts:latest"})
+	assert.Equal(t, "ghcr.io/coqui-ai/tts:latest", gpu.Image)
+	assert.Contains(t, gpu.Command, "--use_cuda")
+}
@@ -6,11 +6,20 @@ import (
 	"os/exec"
 )
 
+// DefaultOllamaImage is the Ollama image used on regular hosts
+const DefaultOllamaImage = "ollama/ollama:latest"
+
+// jetsonOllamaImageRepo provides Ollama builds for NVIDIA Jetson boards, tagged by L4T release
+const jetsonOllamaImageRepo = "dustynv/ollama"
+
 // OllamaConfig holds configuration for Ollama container
 type OllamaConfig struct {
-	Model string
-	Port  int
-	GPUs  []string
+	Model         string
+	Port          int
+	GPUs          []string
+	Image         string
+	NVIDIARuntime bool   // Use the NVIDIA container runtime instead of GPU device flags
+	NameSuffix    string // Appended to the container name, e.g. "-gpu1" for one engine per GPU
 }
 
 // DefaultOllamaConfig returns default Ollama configuration
@@ -19,24 +28,42 @@ func DefaultOllamaConfig() *OllamaConfig {
 		Model: "llama2",
 		Port:  11434,
 		GPUs:  []string{"all"},
+		Image: DefaultOllamaImage,
 	}
 }
 
+// JetsonOllamaConfig returns an Ollama configuration for NVIDIA Jetson boards
+// running the given L4T release (e.g. "r36.2.0"). Jetson images must match the
+// board's L4T release and get GPU access through the NVIDIA container runtime.
+func JetsonOllamaConfig(l4tRelease string) *OllamaConfig {
+	cfg := DefaultOllamaConfig()
+	cfg.GPUs = nil
+	cfg.Image = jetsonOllamaImageRepo + ":" + l4tRelease
+	cfg.NVIDIARuntime = true
+	return cfg
+}
+
 // CreateOllamaContainerConfig creates a ContainerConfig for Ollama
 func CreateOllamaContainerConfig(cfg *OllamaConfig) *ContainerConfig {
-	name := "orchion-ollama"
+	name := "orchion-ollama" + cfg.NameSuffix
+
+	image := cfg.Image
+	if image == "" {
+		image = DefaultOllamaImage
+	}
 
 	return &ContainerConfig{
-		Name:  name,
-		Image: "ollama/ollama:latest",
-		Port:  cfg.Port,
-		Model: cfg.Model,
-		GPUs:  cfg.GPUs,
+		Name:          name,
+		Image:         image,
+		Port:          cfg.Port,
+		Model:         cfg.Model,
+		GPUs:          cfg.GPUs,
+		NVIDIARuntime: cfg.NVIDIARuntime,
 		Volumes: []string{
 			"ollama-data:/root/.ollama",
 		},
 		Environment: []string{
-			"OLLAMA_HOST=0.0.0.0",
+			fmt.Sprintf("OLLAMA_HOST=0.0.0.0:%d", cfg.Port),
 		},
 	}
 }
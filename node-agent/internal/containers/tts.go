@@ -0,0 +1,123 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// DefaultPiperImage is the image the Piper container starts from. Piper has
+// no official server image, so the container installs the piper-tts package
+// into a virtualenv on its data volume on first start.
+const DefaultPiperImage = "python:3.11-slim"
+
+// DefaultCoquiImage is the Coqui TTS image, a CPU build; use
+// ghcr.io/coqui-ai/tts for CUDA
+const DefaultCoquiImage = "ghcr.io/coqui-ai/tts-cpu:latest"
+
+// piperDataDir holds the Piper virtualenv and voices inside the container
+const piperDataDir = "/data"
+
+// piperPython is the Python interpreter of the Piper virtualenv
+const piperPython = piperDataDir + "/venv/bin/python3"
+
+// PiperConfig holds configuration for the Piper container, which serves
+// every Piper voice of the node
+type PiperConfig struct {
+	Voice      string // Voice downloaded and served by default at startup, e.g. "en_US-lessac-medium"
+	Port       int
+	Image      string // Defaults to DefaultPiperImage
+	NameSuffix string // Appended to the container name, e.g. "-gpu1" for one engine per GPU
+}
+
+// DefaultPiperConfig returns default Piper configuration
+func DefaultPiperConfig() *PiperConfig {
+	return &PiperConfig{
+		Port:  5000,
+		Image: DefaultPiperImage,
+	}
+}
+
+// CreatePiperContainerConfig creates a ContainerConfig for Piper
+func CreatePiperContainerConfig(cfg *PiperConfig) *ContainerConfig {
+	image := cfg.Image
+	if image == "" {
+		image = DefaultPiperImage
+	}
+
+	script := fmt.Sprintf("[ -x %[1]s ] || (python3 -m venv %[2]s/venv && %[2]s/venv/bin/pip install --no-cache-dir 'piper-tts[http]') && "+
+		"%[1]s -m piper.download_voices --data-dir %[2]s %[3]s && "+
+		"exec %[1]s -m piper.http_server --host 0.0.0.0 --port %[4]d --data-dir %[2]s -m %[3]s",
+		piperPython, piperDataDir, cfg.Voice, cfg.Port)
+
+	return &ContainerConfig{
+		Name:    "orchion-piper" + cfg.NameSuffix,
+		Image:   image,
+		Port:    cfg.Port,
+		Model:   cfg.Voice,
+		Volumes: []string{"piper-data:" + piperDataDir},
+		Args:    []string{"--entrypoint", "/bin/sh"},
+		Command: []string{"-c", script},
+	}
+}
+
+// DownloadPiperVoice downloads a voice into the running Piper container, which
+// then serves it to requests naming it
+func DownloadPiperVoice(ctx context.Context, manager Manager, containerName, voice string) error {
+	// Type assert to get the runtime path
+	var runtimePath string
+	if cm, ok := manager.(*ContainerManager); ok {
+		runtimePath = cm.runtimePath
+	} else {
+		return fmt.Errorf("unsupported manager type for DownloadPiperVoice")
+	}
+
+	cmd := exec.CommandContext(ctx, runtimePath, "exec", containerName, piperPython, "-m", "piper.download_voices", "--data-dir", piperDataDir, voice)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to download Piper voice %s: %w\nOutput: %s", voice, err, string(output))
+	}
+	return nil
+}
+
+// CoquiConfig holds configuration for a Coqui TTS container, which serves one
+// model
+type CoquiConfig struct {
+	Model      string // Coqui model name, e.g. "tts_models/en/vctk/vits"
+	Port       int
+	GPUs       []string // Runs the model with CUDA if set; needs a CUDA image
+	Image      string   // Defaults to DefaultCoquiImage
+	NameSuffix string   // Appended to the container name, e.g. "-gpu1" for one engine per GPU
+}
+
+// CreateCoquiContainerConfig creates a ContainerConfig for Coqui TTS
+func CreateCoquiContainerConfig(cfg *CoquiConfig) *ContainerConfig {
+	image := cfg.Image
+	if image == "" {
+		image = DefaultCoquiImage
+	}
+
+	command := []string{
+		"TTS/server/server.py",
+		"--model_name", cfg.Model,
+		"--port", fmt.Sprintf("%d", cfg.Port),
+	}
+	if len(cfg.GPUs) > 0 {
+		command = append(command, "--use_cuda", "true")
+	}
+
+	return &ContainerConfig{
+		Name:    fmt.Sprintf("orchion-coqui-%s%s", sanitizeModelName(cfg.Model), cfg.NameSuffix),
+		Image:   image,
+		Port:    cfg.Port,
+		Model:   cfg.Model,
+		GPUs:    cfg.GPUs,
+		Volumes: []string{"coqui-data:/root/.local/share/tts"},
+		Args:    []string{"--entrypoint", "python3"},
+		Command: command,
+		Environment: []string{
+			// Accept the model license non-interactively, as server.py can't prompt
+			"COQUI_TOS_AGREED=1",
+		},
+	}
+}
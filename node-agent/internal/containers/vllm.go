@@ -5,6 +5,9 @@ import (
 	"strings"
 )
 
+// DefaultVLLMImage is the vLLM image used for single and multi-node deployments
+const DefaultVLLMImage = "vllm/vllm-openai:latest"
+
 // VLLMConfig holds configuration for vLLM container
 type VLLMConfig struct {
 	Model              string
@@ -12,6 +15,8 @@ type VLLMConfig struct {
 	GPUs               []string
 	TensorParallelSize int
 	MaxModelLen        int
+	NameSuffix         string // Appended to the container name, e.g. "-gpu1" for one engine per GPU
+	Image              string // Defaults to DefaultVLLMImage
 }
 
 // DefaultVLLMConfig returns default vLLM configuration
@@ -27,7 +32,7 @@ func DefaultVLLMConfig() *VLLMConfig {
 
 // CreateVLLMContainerConfig creates a ContainerConfig for vLLM
 func CreateVLLMContainerConfig(cfg *VLLMConfig) *ContainerConfig {
-	name := fmt.Sprintf("orchion-vllm-%s", sanitizeModelName(cfg.Model))
+	name := fmt.Sprintf("orchion-vllm-%s%s", sanitizeModelName(cfg.Model), cfg.NameSuffix)
 
 	// Build vLLM command arguments
 	args := []string{
@@ -46,7 +51,7 @@ func CreateVLLMContainerConfig(cfg *VLLMConfig) *ContainerConfig {
 
 	return &ContainerConfig{
 		Name:  name,
-		Image: "vllm/vllm-openai:latest",
+		Image: vllmImage(cfg.Image),
 		Port:  cfg.Port,
 		Model: cfg.Model,
 		GPUs:  cfg.GPUs,
@@ -57,6 +62,75 @@ func CreateVLLMContainerConfig(cfg *VLLMConfig) *ContainerConfig {
 	}
 }
 
+// DefaultRayPort is the port the Ray head of a multi-node vLLM deployment listens on
+const DefaultRayPort = 6379
+
+// VLLMRayConfig holds configuration for one node of a multi-node vLLM deployment.
+// The head node runs the Ray head and serves the model; workers join its Ray
+// cluster and contribute their GPUs.
+type VLLMRayConfig struct {
+	DeploymentID         string
+	Model                string
+	Port                 int
+	Head                 bool
+	HeadAddress          string // Ray head address (host:port) workers join
+	RayPort              int
+	TensorParallelSize   int    // GPUs used on each node
+	PipelineParallelSize int    // Number of nodes
+	Image                string // Defaults to DefaultVLLMImage
+}
+
+// CreateVLLMRayContainerConfig creates a ContainerConfig for a node of a multi-node
+// vLLM deployment. The head container uses the same name as a single-node vLLM
+// container for the model, so it is served like any other vLLM model.
+func CreateVLLMRayContainerConfig(cfg *VLLMRayConfig) *ContainerConfig {
+	rayPort := cfg.RayPort
+	if rayPort == 0 {
+		rayPort = DefaultRayPort
+	}
+
+	var name, script string
+	if cfg.Head {
+		name = fmt.Sprintf("orchion-vllm-%s", sanitizeModelName(cfg.Model))
+
+		serve := []string{
+			"vllm", "serve", cfg.Model,
+			"--port", fmt.Sprintf("%d", cfg.Port),
+			"--host", "0.0.0.0",
+			"--distributed-executor-backend", "ray",
+		}
+		if cfg.TensorParallelSize > 1 {
+			serve = append(serve, "--tensor-parallel-size", fmt.Sprintf("%d", cfg.TensorParallelSize))
+		}
+		if cfg.PipelineParallelSize > 1 {
+			serve = append(serve, "--pipeline-parallel-size", fmt.Sprintf("%d", cfg.PipelineParallelSize))
+		}
+
+		// vLLM waits for the workers to join the Ray cluster before loading the model
+		script = fmt.Sprintf("ray start --head --port=%d && exec %s", rayPort, strings.Join(serve, " "))
+	} else {
+		name = fmt.Sprintf("orchion-vllm-ray-%s", cfg.DeploymentID)
+		script = fmt.Sprintf("ray start --block --address=%s", cfg.HeadAddress)
+	}
+
+	return &ContainerConfig{
+		Name:  name,
+		Image: vllmImage(cfg.Image),
+		Model: cfg.Model,
+		GPUs:  []string{"all"},
+		// Ray and NCCL need to reach the other nodes directly
+		Args: []string{
+			"--network", "host",
+			"--shm-size", "10.24g",
+			"--entrypoint", "/bin/bash",
+		},
+		Command: []string{"-c", script},
+		Environment: []string{
+			"VLLM_USE_MODELSCOPE=false",
+		},
+	}
+}
+
 // sanitizeModelName converts model name to container-friendly string
 func sanitizeModelName(model string) string {
 	// Replace slashes and special chars with dashes
@@ -65,3 +139,11 @@ func sanitizeModelName(model string) string {
 	sanitized = strings.ReplaceAll(sanitized, "_", "-")
 	return sanitized
 }
+
+// vllmImage returns image, or the default vLLM image if it's empty
+func vllmImage(image string) string {
+	if image == "" {
+		return DefaultVLLMImage
+	}
+	return image
+}
@@ -0,0 +1,414 @@
+// Package doctor checks whether a machine is ready to run the node agent:
+// container runtime, GPU drivers and tools, ports, orchestrator reachability
+// and model cache volumes. It backs `node-agent doctor`, which prints a
+// report instead of starting the agent, so "agent won't serve requests"
+// problems are found before the agent registers.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/tailnet"
+)
+
+// DefaultTimeout bounds each network and command check
+const DefaultTimeout = 5 * time.Second
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn" // The agent runs, but something may not work
+	StatusFail Status = "fail" // The agent won't serve requests until it's fixed
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Check  string `json:"check"`
+	Status Status `json:"status"`
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // How to fix a warning or failure
+}
+
+// Report is the outcome of every check, in the order they ran
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed reports whether any check failed
+func (r Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report as a table, with hints under the checks they
+// belong to
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, result := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Status, result.Check, result.Detail)
+		if result.Hint != "" {
+			fmt.Fprintf(tw, "\t\thint: %s\n", result.Hint)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Config is what the agent would run with, as set by its flags
+type Config struct {
+	OrchestratorAddr string
+	TunnelAddr       string         // Empty unless the agent tunnels to the orchestrator
+	AgentPort        string         // gRPC port the agent listens on
+	AgentBind        string         // Host the agent port listens on, as -bind-address takes it; empty for all interfaces
+	AdminAddr        string         // Empty if the admin endpoint is disabled
+	EnginePorts      map[string]int // Ports engines are published on, by engine
+	Timeout          time.Duration  // Per check; DefaultTimeout if zero
+}
+
+// Doctor runs the checks. Its system access is swappable for tests.
+type Doctor struct {
+	config   Config
+	lookPath func(file string) (string, error)
+	run      func(ctx context.Context, name string, args ...string) (string, error)
+	gpu      func() capabilities.GPUInfo
+	exists   func(path string) bool
+
+	resolveHost func(host string) (string, error)
+}
+
+// New creates a doctor checking the machine it runs on
+func New(config Config) *Doctor {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Doctor{
+		config:   config,
+		lookPath: exec.LookPath,
+		run: func(ctx context.Context, name string, args ...string) (string, error) {
+			output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+			return strings.TrimSpace(string(output)), err
+		},
+		gpu: capabilities.SystemGPUDetector{}.DetectGPU,
+		exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+		resolveHost: tailnet.ResolveHost,
+	}
+}
+
+// Run runs every check
+func (d *Doctor) Run(ctx context.Context) Report {
+	var report Report
+	runtime, runtimeResult := d.checkRuntime(ctx)
+	report.Results = append(report.Results, runtimeResult)
+	report.Results = append(report.Results, d.checkGPU(ctx, runtime)...)
+	report.Results = append(report.Results, d.checkPorts()...)
+	report.Results = append(report.Results, d.checkOrchestrator(ctx, "orchestrator", d.config.OrchestratorAddr, true))
+	if d.config.TunnelAddr != "" {
+		report.Results = append(report.Results, d.checkOrchestrator(ctx, "tunnel", d.config.TunnelAddr, false))
+	}
+	report.Results = append(report.Results, d.checkModelCaches(ctx, runtime)...)
+	return report
+}
+
+// command runs a command with the check timeout
+func (d *Doctor) command(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+	return d.run(ctx, name, args...)
+}
+
+// checkRuntime finds the container runtime the agent would use, as
+// containers.NewContainerManager does, and checks that it works. It returns
+// the runtime's path, empty if there is none.
+func (d *Doctor) checkRuntime(ctx context.Context) (string, Result) {
+	result := Result{Check: "container runtime"}
+	for _, name := range []containers.ContainerRuntime{containers.RuntimePodman, containers.RuntimeDocker} {
+		path, err := d.lookPath(string(name))
+		if err != nil {
+			continue
+		}
+		version, err := d.command(ctx, path, "version", "--format", "{{.Client.Version}}")
+		if err != nil {
+			result.Status = StatusFail
+			result.Detail = fmt.Sprintf("%s at %s doesn't work: %s", name, path, firstLine(version, err))
+			result.Hint = "Start the container engine (e.g. the Docker daemon) and make sure this user may use it"
+			return "", result
+		}
+		result.Status = StatusOK
+		result.Detail = fmt.Sprintf("%s %s at %s", name, version, path)
+		return path, result
+	}
+
+	result.Status = StatusFail
+	result.Detail = "neither podman nor docker found in PATH"
+	result.Hint = "Install Podman (preferred) or Docker; engines run in containers"
+	return "", result
+}
+
+// checkGPU checks that a GPU is detected and, for NVIDIA GPUs, that
+// containers can use it
+func (d *Doctor) checkGPU(ctx context.Context, runtime string) []Result {
+	gpu := d.gpu()
+	if gpu.Type == "" || gpu.Backend == pb.GpuBackend_GPU_BACKEND_UNSPECIFIED {
+		result := Result{Check: "gpu", Status: StatusWarn, Detail: "no GPU detected; models run on the CPU"}
+		if _, err := d.lookPath("nvidia-smi"); err != nil {
+			result.Hint = "For NVIDIA GPUs, install the driver including nvidia-smi, which the agent detects GPUs with"
+		}
+		return []Result{result}
+	}
+
+	results := []Result{{Check: "gpu", Status: StatusOK, Detail: gpuDetail(gpu)}}
+	if gpu.Backend != pb.GpuBackend_GPU_BACKEND_CUDA {
+		return results
+	}
+
+	driver := Result{Check: "gpu driver"}
+	if path, err := d.lookPath("nvidia-smi"); err != nil {
+		driver.Status = StatusWarn
+		driver.Detail = "nvidia-smi not found in PATH"
+		driver.Hint = "Install the NVIDIA driver utilities; without nvidia-smi VRAM isn't reported"
+	} else if version, err := d.command(ctx, path, "--query-gpu=driver_version", "--format=csv,noheader"); err != nil {
+		driver.Status = StatusFail
+		driver.Detail = fmt.Sprintf("nvidia-smi failed: %s", firstLine(version, err))
+		driver.Hint = "The driver may not be loaded; reboot after installing or updating it"
+	} else {
+		driver.Status = StatusOK
+		driver.Detail = "NVIDIA driver " + firstLine(version, nil)
+	}
+	results = append(results, driver)
+
+	if runtime != "" {
+		results = append(results, d.checkGPUContainers(ctx, runtime))
+	}
+	return results
+}
+
+// cdiSpecs are where the NVIDIA Container Toolkit writes the CDI spec Podman
+// exposes GPUs with
+var cdiSpecs = []string{"/etc/cdi/nvidia.yaml", "/etc/cdi/nvidia.json", "/var/run/cdi/nvidia.yaml", "/var/run/cdi/nvidia.json"}
+
+// checkGPUContainers checks that the container runtime can give containers
+// NVIDIA GPUs: Podman through CDI, Docker through the NVIDIA runtime
+func (d *Doctor) checkGPUContainers(ctx context.Context, runtime string) Result {
+	result := Result{Check: "gpu containers"}
+	if strings.Contains(strings.ToLower(runtime), string(containers.RuntimePodman)) {
+		for _, spec := range cdiSpecs {
+			if d.exists(spec) {
+				result.Status = StatusOK
+				result.Detail = "NVIDIA CDI spec at " + spec
+				return result
+			}
+		}
+		result.Status = StatusFail
+		result.Detail = "no NVIDIA CDI spec found; Podman can't give containers GPUs"
+		result.Hint = "Install the NVIDIA Container Toolkit and run: nvidia-ctk cdi generate --output=/etc/cdi/nvidia.yaml"
+		return result
+	}
+
+	runtimes, err := d.command(ctx, runtime, "info", "--format", "{{json .Runtimes}}")
+	switch {
+	case err != nil:
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("couldn't list Docker runtimes: %s", firstLine(runtimes, err))
+	case strings.Contains(runtimes, "nvidia"):
+		result.Status = StatusOK
+		result.Detail = "Docker has the NVIDIA runtime"
+	default:
+		result.Status = StatusFail
+		result.Detail = "Docker has no NVIDIA runtime; containers can't use the GPU"
+		result.Hint = "Install the NVIDIA Container Toolkit and run: nvidia-ctk runtime configure --runtime=docker"
+	}
+	return result
+}
+
+// checkPorts checks that the agent can listen on its ports. Engine ports may
+// be held by engine containers left running, which the agent reuses.
+func (d *Doctor) checkPorts() []Result {
+	var results []Result
+	if d.config.AgentPort != "" {
+		if bind, err := d.resolveHost(d.config.AgentBind); err != nil {
+			results = append(results, Result{
+				Check:  "agent port",
+				Status: StatusFail,
+				Detail: fmt.Sprintf("can't listen on %s: %v", d.config.AgentBind, err),
+				Hint:   "Bring the tailnet up, e.g. with tailscale up, or pick another -bind-address",
+			})
+		} else {
+			results = append(results, checkListen("agent port", net.JoinHostPort(bind, d.config.AgentPort), StatusFail,
+				"Stop the process using it, e.g. another agent, or pick another -agent-port"))
+		}
+	}
+	if d.config.AdminAddr != "" {
+		results = append(results, checkListen("admin address", d.config.AdminAddr, StatusFail,
+			"Stop the process using it or pick another -admin-addr"))
+	}
+
+	engines := make([]string, 0, len(d.config.EnginePorts))
+	for engine := range d.config.EnginePorts {
+		engines = append(engines, engine)
+	}
+	sort.Strings(engines)
+	for _, engine := range engines {
+		results = append(results, checkListen(engine+" port", fmt.Sprintf(":%d", d.config.EnginePorts[engine]), StatusWarn,
+			"Fine if an Orchion engine container holds it; otherwise the engine can't start"))
+	}
+	return results
+}
+
+// checkListen checks that addr can be listened on, failing with status if
+// it can't
+func checkListen(check, addr string, status Status, hint string) Result {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return Result{Check: check, Status: status, Detail: fmt.Sprintf("%s is not available: %v", addr, err), Hint: hint}
+	}
+	listener.Close()
+	return Result{Check: check, Status: StatusOK, Detail: addr + " is free"}
+}
+
+// checkOrchestrator checks that addr accepts connections and, if health is
+// set, that it serves gRPC health checks as the orchestrator does
+func (d *Doctor) checkOrchestrator(ctx context.Context, check, addr string, health bool) Result {
+	result := Result{Check: check}
+	conn, err := net.DialTimeout("tcp", addr, d.config.Timeout)
+	if err != nil {
+		result.Status = StatusFail
+		result.Detail = fmt.Sprintf("can't connect to %s: %v", addr, err)
+		result.Hint = "Check the address and that firewalls between this machine and the orchestrator allow it"
+		return result
+	}
+	conn.Close()
+	if !health {
+		result.Status = StatusOK
+		result.Detail = addr + " accepts connections"
+		return result
+	}
+
+	client, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		result.Status = StatusFail
+		result.Detail = fmt.Sprintf("invalid address %s: %v", addr, err)
+		return result
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(client).Check(ctx, &healthpb.HealthCheckRequest{})
+	switch {
+	case err != nil:
+		result.Status = StatusFail
+		result.Detail = fmt.Sprintf("%s accepts connections but doesn't answer gRPC: %v", addr, err)
+		result.Hint = "Make sure -orchestrator names the orchestrator's gRPC port, not its HTTP port"
+	case resp.Status != healthpb.HealthCheckResponse_SERVING:
+		result.Status = StatusWarn
+		result.Detail = fmt.Sprintf("%s reports %s", addr, resp.Status)
+	default:
+		result.Status = StatusOK
+		result.Detail = addr + " is serving"
+	}
+	return result
+}
+
+// modelCacheVolumes returns the volumes engines keep downloaded models in,
+// by engine
+func modelCacheVolumes() map[string]string {
+	configs := map[string]*containers.ContainerConfig{
+		"ollama": containers.CreateOllamaContainerConfig(containers.DefaultOllamaConfig()),
+		"piper":  containers.CreatePiperContainerConfig(containers.DefaultPiperConfig()),
+		"coqui":  containers.CreateCoquiContainerConfig(&containers.CoquiConfig{}),
+	}
+	volumes := make(map[string]string, len(configs))
+	for engine, config := range configs {
+		for _, volume := range config.Volumes {
+			if name, _, ok := strings.Cut(volume, ":"); ok {
+				volumes[engine] = name
+			}
+		}
+	}
+	return volumes
+}
+
+// checkModelCaches reports where engines keep downloaded models. Volumes that
+// don't exist yet are created when their engine first starts, which then
+// downloads its models from scratch.
+func (d *Doctor) checkModelCaches(ctx context.Context, runtime string) []Result {
+	if runtime == "" {
+		return nil
+	}
+	volumes := modelCacheVolumes()
+	engines := make([]string, 0, len(volumes))
+	for engine := range volumes {
+		engines = append(engines, engine)
+	}
+	sort.Strings(engines)
+
+	var results []Result
+	for _, engine := range engines {
+		volume := volumes[engine]
+		result := Result{Check: engine + " model cache"}
+		mountpoint, err := d.command(ctx, runtime, "volume", "inspect", "--format", "{{.Mountpoint}}", volume)
+		if err != nil {
+			result.Status = StatusWarn
+			result.Detail = fmt.Sprintf("volume %s doesn't exist yet; %s's first start downloads its models", volume, engine)
+		} else {
+			result.Status = StatusOK
+			result.Detail = fmt.Sprintf("volume %s at %s", volume, firstLine(mountpoint, nil))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// gpuDetail describes a detected GPU
+func gpuDetail(gpu capabilities.GPUInfo) string {
+	detail := gpu.Type
+	if gpu.VRAMTotal != "" {
+		detail += ", " + gpu.VRAMTotal + " VRAM"
+	}
+	if gpu.VRAMAvailable != "" {
+		detail += " (" + gpu.VRAMAvailable + " free)"
+	}
+	return detail
+}
+
+// firstLine returns the first line of a command's output, or its error if
+// the output is empty
+func firstLine(output string, err error) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if line == "" && err != nil {
+		return err.Error()
+	}
+	return line
+}
@@ -0,0 +1,281 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// fakeSystem stands in for the commands and files of a machine
+type fakeSystem struct {
+	paths    map[string]string // Binaries in PATH
+	outputs  map[string]string // Command output, by command line
+	failures map[string]bool   // Failing command lines
+	files    map[string]bool
+	gpu      capabilities.GPUInfo
+}
+
+func (s *fakeSystem) doctor(config Config) *Doctor {
+	d := New(config)
+	d.lookPath = func(file string) (string, error) {
+		if path, ok := s.paths[file]; ok {
+			return path, nil
+		}
+		return "", errors.New("not found")
+	}
+	d.run = func(ctx context.Context, name string, args ...string) (string, error) {
+		line := strings.Join(append([]string{name}, args...), " ")
+		if s.failures[line] {
+			return "error: " + line, errors.New("exit status 1")
+		}
+		return s.outputs[line], nil
+	}
+	d.gpu = func() capabilities.GPUInfo { return s.gpu }
+	d.exists = func(path string) bool { return s.files[path] }
+	return d
+}
+
+// result returns the result of a check, failing the test if it didn't run
+func result(t *testing.T, report Report, check string) Result {
+	t.Helper()
+	for _, r := range report.Results {
+		if r.Check == check {
+			return r
+		}
+	}
+	t.Fatalf("check %q did not run", check)
+	return Result{}
+}
+
+// startOrchestrator serves gRPC health checks like the orchestrator
+func startOrchestrator(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestDoctor_HealthyMachine(t *testing.T) {
+	sys := &fakeSystem{
+		paths: map[string]string{"podman": "/usr/bin/podman", "nvidia-smi": "/usr/bin/nvidia-smi"},
+		outputs: map[string]string{
+			"/usr/bin/podman version --format {{.Client.Version}}":                 "4.9.3",
+			"/usr/bin/nvidia-smi --query-gpu=driver_version --format=csv,noheader": "550.54.14",
+			"/usr/bin/podman volume inspect --format {{.Mountpoint}} ollama-data":  "/var/lib/containers/storage/volumes/ollama-data/_data",
+			"/usr/bin/podman volume inspect --format {{.Mountpoint}} piper-data":   "/var/lib/containers/storage/volumes/piper-data/_data",
+			"/usr/bin/podman volume inspect --format {{.Mountpoint}} coqui-data":   "/var/lib/containers/storage/volumes/coqui-data/_data",
+		},
+		files: map[string]bool{"/etc/cdi/nvidia.yaml": true},
+		gpu:   capabilities.GPUInfo{Type: "NVIDIA RTX 4090", VRAMTotal: "24GB", Backend: pb.GpuBackend_GPU_BACKEND_CUDA},
+	}
+
+	report := sys.doctor(Config{OrchestratorAddr: startOrchestrator(t)}).Run(context.Background())
+
+	assert.False(t, report.Failed())
+	for _, r := range report.Results {
+		assert.Equal(t, StatusOK, r.Status, "%s: %s", r.Check, r.Detail)
+	}
+	assert.Equal(t, "podman 4.9.3 at /usr/bin/podman", result(t, report, "container runtime").Detail)
+	assert.Equal(t, "NVIDIA RTX 4090, 24GB VRAM", result(t, report, "gpu").Detail)
+	assert.Equal(t, "NVIDIA driver 550.54.14", result(t, report, "gpu driver").Detail)
+	assert.Contains(t, result(t, report, "gpu containers").Detail, "/etc/cdi/nvidia.yaml")
+	assert.Contains(t, result(t, report, "ollama model cache").Detail, "ollama-data/_data")
+}
+
+func TestDoctor_NoRuntime(t *testing.T) {
+	sys := &fakeSystem{}
+
+	report := sys.doctor(Config{OrchestratorAddr: startOrchestrator(t)}).Run(context.Background())
+
+	assert.True(t, report.Failed())
+	runtime := result(t, report, "container runtime")
+	assert.Equal(t, StatusFail, runtime.Status)
+	assert.NotEmpty(t, runtime.Hint)
+	assert.Equal(t, StatusWarn, result(t, report, "gpu").Status)
+	for _, r := range report.Results {
+		assert.NotContains(t, r.Check, "model cache", "caches are only checked with a runtime")
+	}
+}
+
+func TestDoctor_RuntimeNotWorking(t *testing.T) {
+	sys := &fakeSystem{
+		paths:    map[string]string{"docker": "/usr/bin/docker"},
+		failures: map[string]bool{"/usr/bin/docker version --format {{.Client.Version}}": true},
+	}
+
+	report := sys.doctor(Config{OrchestratorAddr: startOrchestrator(t)}).Run(context.Background())
+
+	runtime := result(t, report, "container runtime")
+	assert.Equal(t, StatusFail, runtime.Status)
+	assert.Contains(t, runtime.Detail, "docker at /usr/bin/docker doesn't work")
+}
+
+func TestDoctor_GPUContainers(t *testing.T) {
+	cuda := capabilities.GPUInfo{Type: "NVIDIA A100", Backend: pb.GpuBackend_GPU_BACKEND_CUDA}
+
+	t.Run("podman without CDI spec", func(t *testing.T) {
+		sys := &fakeSystem{paths: map[string]string{"podman": "/usr/bin/podman", "nvidia-smi": "/usr/bin/nvidia-smi"}, gpu: cuda}
+		report := sys.doctor(Config{}).Run(context.Background())
+		r := result(t, report, "gpu containers")
+		assert.Equal(t, StatusFail, r.Status)
+		assert.Contains(t, r.Hint, "nvidia-ctk cdi generate")
+	})
+
+	t.Run("docker with NVIDIA runtime", func(t *testing.T) {
+		sys := &fakeSystem{
+			paths:   map[string]string{"docker": "/usr/bin/docker", "nvidia-smi": "/usr/bin/nvidia-smi"},
+			outputs: map[string]string{"/usr/bin/docker info --format {{json .Runtimes}}": `{"nvidia":{},"runc":{}}`},
+			gpu:     cuda,
+		}
+		report := sys.doctor(Config{}).Run(context.Background())
+		assert.Equal(t, StatusOK, result(t, report, "gpu containers").Status)
+	})
+
+	t.Run("docker without NVIDIA runtime", func(t *testing.T) {
+		sys := &fakeSystem{
+			paths:   map[string]string{"docker": "/usr/bin/docker", "nvidia-smi": "/usr/bin/nvidia-smi"},
+			outputs: map[string]string{"/usr/bin/docker info --format {{json .Runtimes}}": `{"runc":{}}`},
+			gpu:     cuda,
+		}
+		report := sys.doctor(Config{}).Run(context.Background())
+		r := result(t, report, "gpu containers")
+		assert.Equal(t, StatusFail, r.Status)
+		assert.Contains(t, r.Hint, "nvidia-ctk runtime configure")
+	})
+
+	t.Run("driver not loaded", func(t *testing.T) {
+		sys := &fakeSystem{
+			paths:    map[string]string{"nvidia-smi": "/usr/bin/nvidia-smi"},
+			failures: map[string]bool{"/usr/bin/nvidia-smi --query-gpu=driver_version --format=csv,noheader": true},
+			gpu:      cuda,
+		}
+		report := sys.doctor(Config{}).Run(context.Background())
+		assert.Equal(t, StatusFail, result(t, report, "gpu driver").Status)
+	})
+}
+
+func TestDoctor_Ports(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	_, busyPort, err := net.SplitHostPort(busy.Addr().String())
+	require.NoError(t, err)
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	sys := &fakeSystem{}
+	report := sys.doctor(Config{
+		AgentPort:   "0",
+		AdminAddr:   "127.0.0.1:" + busyPort,
+		EnginePorts: map[string]int{"vllm": port},
+	}).Run(context.Background())
+
+	assert.Equal(t, StatusOK, result(t, report, "agent port").Status)
+	assert.Equal(t, StatusFail, result(t, report, "admin address").Status)
+	// Engine containers left running hold their ports, so that only warns
+	assert.Equal(t, StatusWarn, result(t, report, "vllm port").Status)
+}
+
+func TestDoctor_AgentBind(t *testing.T) {
+	sys := &fakeSystem{}
+	d := sys.doctor(Config{AgentPort: "0", AgentBind: "tailnet"})
+
+	d.resolveHost = func(host string) (string, error) { return "", errors.New("no tailnet address found") }
+	bad := result(t, d.Run(context.Background()), "agent port")
+	assert.Equal(t, StatusFail, bad.Status)
+	assert.Contains(t, bad.Detail, "no tailnet address found")
+
+	d.resolveHost = func(host string) (string, error) { return "127.0.0.1", nil }
+	assert.Equal(t, StatusOK, result(t, d.Run(context.Background()), "agent port").Status)
+}
+
+func TestDoctor_Orchestrator(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := lis.Addr().String()
+		lis.Close()
+
+		report := (&fakeSystem{}).doctor(Config{OrchestratorAddr: addr}).Run(context.Background())
+		r := result(t, report, "orchestrator")
+		assert.Equal(t, StatusFail, r.Status)
+		assert.Contains(t, r.Detail, "can't connect")
+	})
+
+	t.Run("not gRPC", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer lis.Close()
+		go func() {
+			for {
+				conn, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+				conn.Close()
+			}
+		}()
+
+		report := (&fakeSystem{}).doctor(Config{OrchestratorAddr: lis.Addr().String(), Timeout: 500 * time.Millisecond}).Run(context.Background())
+		r := result(t, report, "orchestrator")
+		assert.Equal(t, StatusFail, r.Status)
+		assert.Contains(t, r.Detail, "doesn't answer gRPC")
+	})
+
+	t.Run("tunnel", func(t *testing.T) {
+		addr := startOrchestrator(t)
+		report := (&fakeSystem{}).doctor(Config{OrchestratorAddr: addr, TunnelAddr: addr}).Run(context.Background())
+		assert.Equal(t, StatusOK, result(t, report, "tunnel").Status)
+	})
+}
+
+func TestDoctor_ModelCacheNotCreated(t *testing.T) {
+	sys := &fakeSystem{
+		paths:    map[string]string{"podman": "/usr/bin/podman"},
+		failures: map[string]bool{"/usr/bin/podman volume inspect --format {{.Mountpoint}} ollama-data": true},
+	}
+
+	report := sys.doctor(Config{}).Run(context.Background())
+
+	r := result(t, report, "ollama model cache")
+	assert.Equal(t, StatusWarn, r.Status)
+	assert.Contains(t, r.Detail, "ollama-data doesn't exist yet")
+}
+
+func TestReport_Output(t *testing.T) {
+	report := Report{Results: []Result{
+		{Check: "container runtime", Status: StatusOK, Detail: "podman 4.9.3"},
+		{Check: "gpu", Status: StatusWarn, Detail: "no GPU detected", Hint: "install the driver"},
+	}}
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "ok")
+	assert.Contains(t, text.String(), "container runtime")
+	assert.Contains(t, text.String(), "hint: install the driver")
+
+	var decoded Report
+	var js bytes.Buffer
+	require.NoError(t, report.WriteJSON(&js))
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Equal(t, report, decoded)
+	assert.False(t, report.Failed())
+}
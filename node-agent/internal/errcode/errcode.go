@@ -0,0 +1,79 @@
+// Package errcode attaches Orchion error codes to gRPC status errors so that
+// failure classes survive the hop from node agent to orchestrator to gateway.
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// New creates a gRPC status error carrying the given error code in its details
+func New(c codes.Code, code pb.ErrorCode, msg string) error {
+	st := status.New(c, msg)
+	if withDetails, err := st.WithDetails(&pb.ErrorInfo{Code: code}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// Retryable creates an error like New, marked as safe to retry on another
+// node. Only use it for requests rejected before any work was done.
+func Retryable(c codes.Code, code pb.ErrorCode, msg string) error {
+	st := status.New(c, msg)
+	if withDetails, err := st.WithDetails(&pb.ErrorInfo{Code: code, Retryable: true}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// Errorf is like New but formats the message
+func Errorf(c codes.Code, code pb.ErrorCode, format string, args ...interface{}) error {
+	return New(c, code, fmt.Sprintf(format, args...))
+}
+
+// Engine wraps a failure reported by an inference engine, classifying it
+// into an error code and matching gRPC status code
+func Engine(msg string, err error) error {
+	code := Classify(err)
+	return Errorf(grpcCode(code), code, "%s: %v", msg, err)
+}
+
+// Classify picks the error code for a failure reported by an inference engine
+func Classify(err error) pb.ErrorCode {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "out of memory"), strings.Contains(msg, "insufficient vram"):
+		return pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "deadline exceeded"):
+		return pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT
+	case strings.Contains(msg, "not found"), strings.Contains(msg, "pull model manifest"):
+		return pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND
+	default:
+		return pb.ErrorCode_ERROR_CODE_ENGINE_ERROR
+	}
+}
+
+// grpcCode returns the gRPC status code used for an error code
+func grpcCode(code pb.ErrorCode) codes.Code {
+	switch code {
+	case pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT:
+		return codes.DeadlineExceeded
+	case pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED:
+		return codes.ResourceExhausted
+	case pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND:
+		return codes.NotFound
+	default:
+		return codes.Internal
+	}
+}
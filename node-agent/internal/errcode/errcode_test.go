@@ -0,0 +1,58 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want pb.ErrorCode
+	}{
+		{"context deadline", fmt.Errorf("failed to call Ollama: %w", context.DeadlineExceeded), pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT},
+		{"client timeout", errors.New("Client.Timeout exceeded while awaiting headers"), pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT},
+		{"cuda oom", errors.New("CUDA error: out of memory"), pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED},
+		{"missing model", errors.New("pull model manifest: file does not exist"), pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND},
+		{"other", errors.New("Ollama returned status 500"), pb.ErrorCode_ERROR_CODE_ENGINE_ERROR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestEngine(t *testing.T) {
+	err := Engine("failed to start model llama3", errors.New("CUDA error: out of memory"))
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "failed to start model llama3: CUDA error: out of memory", st.Message())
+	require.Len(t, st.Details(), 1)
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, st.Details()[0].(*pb.ErrorInfo).Code)
+}
+
+func TestRetryable(t *testing.T) {
+	err := Retryable(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, "not enough free VRAM")
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*pb.ErrorInfo)
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, info.Code)
+	assert.True(t, info.Retryable)
+}
@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// CoquiExecutor manages Coqui TTS containers, one per model, and handles
+// text-to-speech requests. Models are named "coqui/<model>", e.g.
+// "coqui/tts_models/en/vctk/vits".
+type CoquiExecutor struct {
+	containerManager containers.Manager
+	basePort         int         // Starting port for Coqui containers
+	gpus             []string    // GPU device IDs given to containers; none runs models on the CPU
+	nameSuffix       string      // Appended to container names, e.g. "-gpu1"
+	runningPorts     *modelPorts // model -> port mapping
+	transport        http.RoundTripper
+	image            string       // Image Coqui containers are started from
+	imageMu          sync.RWMutex // Guards image, which image pins replace at runtime
+	readinessProbes               // Overrides of how Coqui's readiness is probed
+}
+
+// NewCoquiExecutor creates a new Coqui executor
+func NewCoquiExecutor(manager containers.Manager) *CoquiExecutor {
+	return &CoquiExecutor{
+		containerManager: manager,
+		basePort:         5002, // Default Coqui TTS server port
+		runningPorts:     newModelPorts(),
+		image:            containers.DefaultCoquiImage,
+	}
+}
+
+// containerConfig returns the configuration of the Coqui container serving a model
+func (e *CoquiExecutor) containerConfig(model string) *containers.ContainerConfig {
+	_, name, _ := speechEngine(model)
+	return containers.CreateCoquiContainerConfig(&containers.CoquiConfig{
+		Model:      name,
+		Port:       e.basePort,
+		GPUs:       e.gpus,
+		Image:      e.Image(),
+		NameSuffix: e.nameSuffix,
+	})
+}
+
+// StartModel starts a Coqui container for the specified model
+func (e *CoquiExecutor) StartModel(ctx context.Context, model string) error {
+	config := e.containerConfig(model)
+
+	// Coqui downloads the model before serving it
+	reportState(ctx, pb.ModelState_MODEL_STATE_DOWNLOADING)
+	if err := e.containerManager.EnsureRunning(ctx, config); err != nil {
+		return fmt.Errorf("failed to start Coqui container: %w", err)
+	}
+
+	// Wait for Coqui to be ready
+	probe := e.probe(model, defaultCoquiProbe)
+	if err := waitForEngineReady(ctx, "Coqui", model, fmt.Sprintf("http://localhost:%d", config.Port), probe); err != nil {
+		return fmt.Errorf("Coqui container failed to become ready: %w", err)
+	}
+
+	// Track the port
+	e.runningPorts.set(model, config.Port)
+
+	log.Printf("Coqui model %s ready on port %d", model, config.Port)
+	return nil
+}
+
+// StopModel stops the Coqui container for the specified model
+func (e *CoquiExecutor) StopModel(ctx context.Context, model string) error {
+	config := e.containerConfig(model)
+	if err := e.containerManager.StopContainer(ctx, config.Name); err != nil {
+		return fmt.Errorf("failed to stop Coqui container: %w", err)
+	}
+
+	e.runningPorts.remove(model)
+	log.Printf("Stopped Coqui container for model %s", model)
+	return nil
+}
+
+// IsModelRunning checks if the Coqui container is running for the specified model
+func (e *CoquiExecutor) IsModelRunning(ctx context.Context, model string) (bool, error) {
+	return e.containerManager.IsRunning(ctx, e.containerConfig(model).Name)
+}
+
+// Image returns the image Coqui containers are started from
+func (e *CoquiExecutor) Image() string {
+	e.imageMu.RLock()
+	defer e.imageMu.RUnlock()
+	return e.image
+}
+
+// SetImage sets the image Coqui containers are started from. Running
+// containers keep their image until they restart.
+func (e *CoquiExecutor) SetImage(image string) {
+	e.imageMu.Lock()
+	defer e.imageMu.Unlock()
+	e.image = image
+}
+
+// EngineInfo identifies the Coqui build serving a model. Coqui doesn't
+// report its version.
+func (e *CoquiExecutor) EngineInfo(ctx context.Context, model string) *pb.ModelEngine {
+	image := e.Image()
+	return &pb.ModelEngine{
+		Model:       model,
+		Engine:      "coqui",
+		Image:       image,
+		ImageDigest: imageDigest(ctx, e.containerManager, image),
+	}
+}
+
+// ChatCompletion fails, as Coqui only synthesizes speech
+func (e *CoquiExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error) {
+	return nil, errSpeechOnly
+}
+
+// Embeddings fails, as Coqui only synthesizes speech
+func (e *CoquiExecutor) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	return nil, errSpeechOnly
+}
+
+// Speech synthesizes speech using Coqui
+func (e *CoquiExecutor) Speech(ctx context.Context, model string, req *pb.SpeechRequest) ([]byte, error) {
+	port, exists := e.runningPorts.get(model)
+	if !exists {
+		return nil, fmt.Errorf("model %s is not running", model)
+	}
+
+	query := url.Values{"text": {req.Input}}
+	if speaker := speaker(req.Voice); speaker != "" {
+		// Multi-speaker models name speakers, e.g. "p225"
+		query.Set("speaker_id", speaker)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://localhost:%d/api/tts?%s", port, query.Encode()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute, Transport: e.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Coqui: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return readAudio(resp, "Coqui")
+}
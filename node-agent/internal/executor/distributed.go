@@ -0,0 +1,144 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// distributedInstance tracks this node's part in a multi-node vLLM deployment
+type distributedInstance struct {
+	container string
+	model     string // Set on the head node, which serves the model
+}
+
+// StartDistributed launches this node's part of a multi-node vLLM deployment.
+// The head returns as soon as its container is up; the model becomes ready once
+// all workers have joined its Ray cluster.
+func (e *VLLMExecutor) StartDistributed(ctx context.Context, req *pb.StartDistributedRequest) error {
+	head := req.Role == pb.DistributedRole_DISTRIBUTED_ROLE_HEAD
+	config := containers.CreateVLLMRayContainerConfig(&containers.VLLMRayConfig{
+		DeploymentID:         req.DeploymentId,
+		Model:                req.Model,
+		Port:                 e.basePort,
+		Head:                 head,
+		HeadAddress:          req.HeadAddress,
+		TensorParallelSize:   int(req.TensorParallelSize),
+		PipelineParallelSize: int(req.PipelineParallelSize),
+		Image:                e.Image(),
+	})
+
+	if err := e.containerManager.EnsureRunning(ctx, config); err != nil {
+		return fmt.Errorf("failed to start vLLM ray container: %w", err)
+	}
+
+	instance := &distributedInstance{container: config.Name}
+	if head {
+		instance.model = req.Model
+		e.runningPorts.set(req.Model, e.basePort)
+	}
+	e.distributed[req.DeploymentId] = instance
+
+	log.Printf("Started %s for distributed deployment %s of model %s", req.Role, req.DeploymentId, req.Model)
+	return nil
+}
+
+// StopDistributed stops this node's part of a multi-node vLLM deployment and
+// returns the model it served, if this node was the head
+func (e *VLLMExecutor) StopDistributed(ctx context.Context, deploymentID string) (string, error) {
+	instance, ok := e.distributed[deploymentID]
+	if !ok {
+		return "", nil
+	}
+
+	if err := e.containerManager.StopContainer(ctx, instance.container); err != nil {
+		return "", fmt.Errorf("failed to stop vLLM ray container: %w", err)
+	}
+
+	delete(e.distributed, deploymentID)
+	if instance.model != "" {
+		e.runningPorts.remove(instance.model)
+	}
+
+	log.Printf("Stopped distributed deployment %s", deploymentID)
+	return instance.model, nil
+}
+
+// StartDistributed handles requests from the orchestrator to join a multi-node deployment
+func (s *Service) StartDistributed(ctx context.Context, req *pb.StartDistributedRequest) (*pb.StartDistributedResponse, error) {
+	if req.DeploymentId == "" || req.Model == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "deployment_id and model are required")
+	}
+	switch req.Role {
+	case pb.DistributedRole_DISTRIBUTED_ROLE_HEAD:
+	case pb.DistributedRole_DISTRIBUTED_ROLE_WORKER:
+		if req.HeadAddress == "" {
+			return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "head_address is required for workers")
+		}
+	default:
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "role is required")
+	}
+
+	vllm, err := s.vllmExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := vllm.StartDistributed(ctx, req); err != nil {
+		return nil, errcode.Engine("failed to start distributed deployment", err)
+	}
+
+	// The head serves the model like a locally started one
+	if req.Role == pb.DistributedRole_DISTRIBUTED_ROLE_HEAD {
+		s.runningModels[req.Model] = &ModelInstance{
+			Model:     req.Model,
+			Executor:  vllm,
+			StartTime: time.Now(),
+			Engine:    engineInfo(ctx, vllm, req.Model),
+		}
+		s.setModelStateLocked(req.Model, pb.ModelState_MODEL_STATE_READY, nil)
+	}
+
+	return &pb.StartDistributedResponse{}, nil
+}
+
+// StopDistributed handles requests from the orchestrator to leave a multi-node deployment
+func (s *Service) StopDistributed(ctx context.Context, req *pb.StopDistributedRequest) (*pb.StopDistributedResponse, error) {
+	vllm, err := s.vllmExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	model, err := vllm.StopDistributed(ctx, req.DeploymentId)
+	if err != nil {
+		return nil, errcode.Engine("failed to stop distributed deployment", err)
+	}
+	if model != "" {
+		delete(s.runningModels, model)
+		delete(s.states, model)
+	}
+
+	return &pb.StopDistributedResponse{}, nil
+}
+
+// vllmExecutor returns the vLLM executor used for multi-node deployments
+func (s *Service) vllmExecutor() (*VLLMExecutor, error) {
+	vllm, ok := s.executors["vllm"].(*VLLMExecutor)
+	if !ok {
+		return nil, errcode.New(codes.FailedPrecondition, pb.ErrorCode_ERROR_CODE_ENGINE_ERROR, "vLLM executor is not available")
+	}
+	return vllm, nil
+}
@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// MockContainerManager records started containers without running anything
+type MockContainerManager struct {
+	running map[string]*containers.ContainerConfig
+	pulled  []string
+}
+
+func NewMockContainerManager() *MockContainerManager {
+	return &MockContainerManager{running: make(map[string]*containers.ContainerConfig)}
+}
+
+func (m *MockContainerManager) StartContainer(ctx context.Context, config *containers.ContainerConfig) error {
+	m.running[config.Name] = config
+	return nil
+}
+
+func (m *MockContainerManager) StopContainer(ctx context.Context, name string) error {
+	delete(m.running, name)
+	return nil
+}
+
+func (m *MockContainerManager) IsRunning(ctx context.Context, name string) (bool, error) {
+	_, ok := m.running[name]
+	return ok, nil
+}
+
+func (m *MockContainerManager) EnsureRunning(ctx context.Context, config *containers.ContainerConfig) error {
+	return m.StartContainer(ctx, config)
+}
+
+func (m *MockContainerManager) ImageDigest(ctx context.Context, image string) (string, error) {
+	return "sha256:" + image, nil
+}
+
+func (m *MockContainerManager) PullImage(ctx context.Context, image string) error {
+	m.pulled = append(m.pulled, image)
+	return nil
+}
+
+func (m *MockContainerManager) TestConnection() error {
+	return nil
+}
+
+func newTestService(manager containers.Manager) *Service {
+	return &Service{
+		containerManager: manager,
+		executors: map[string]Executor{
+			"vllm": NewVLLMExecutor(manager),
+		},
+		runningModels: make(map[string]*ModelInstance),
+		tracer:        NewTracer(),
+	}
+}
+
+func TestService_StartDistributed(t *testing.T) {
+	ctx := context.Background()
+	manager := NewMockContainerManager()
+	service := newTestService(manager)
+
+	t.Run("head serves the model", func(t *testing.T) {
+		_, err := service.StartDistributed(ctx, &pb.StartDistributedRequest{
+			DeploymentId:         "dep-1",
+			Model:                "meta-llama/Llama-3-70B",
+			Role:                 pb.DistributedRole_DISTRIBUTED_ROLE_HEAD,
+			PipelineParallelSize: 2,
+		})
+		require.NoError(t, err)
+
+		assert.Contains(t, service.runningModels, "meta-llama/Llama-3-70B")
+		assert.Contains(t, manager.running, "orchion-vllm-meta-llama-Llama-3-70B")
+
+		_, err = service.StopDistributed(ctx, &pb.StopDistributedRequest{DeploymentId: "dep-1"})
+		require.NoError(t, err)
+		assert.NotContains(t, service.runningModels, "meta-llama/Llama-3-70B")
+		assert.Empty(t, manager.running)
+	})
+
+	t.Run("worker joins the head", func(t *testing.T) {
+		_, err := service.StartDistributed(ctx, &pb.StartDistributedRequest{
+			DeploymentId: "dep-2",
+			Model:        "meta-llama/Llama-3-70B",
+			Role:         pb.DistributedRole_DISTRIBUTED_ROLE_WORKER,
+			HeadAddress:  "gpu-box:6379",
+		})
+		require.NoError(t, err)
+
+		assert.Empty(t, service.runningModels)
+		assert.Contains(t, manager.running, "orchion-vllm-ray-dep-2")
+	})
+
+	t.Run("worker without head address", func(t *testing.T) {
+		_, err := service.StartDistributed(ctx, &pb.StartDistributedRequest{
+			DeploymentId: "dep-3",
+			Model:        "meta-llama/Llama-3-70B",
+			Role:         pb.DistributedRole_DISTRIBUTED_ROLE_WORKER,
+		})
+		assert.ErrorContains(t, err, "head_address is required")
+	})
+
+	t.Run("missing role", func(t *testing.T) {
+		_, err := service.StartDistributed(ctx, &pb.StartDistributedRequest{
+			DeploymentId: "dep-4",
+			Model:        "meta-llama/Llama-3-70B",
+		})
+		assert.ErrorContains(t, err, "role is required")
+	})
+}
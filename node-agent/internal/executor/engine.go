@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// engineInfoTimeout bounds the calls made to identify an engine build
+const engineInfoTimeout = 5 * time.Second
+
+// EngineReporter is implemented by executors that can identify the engine
+// build serving a model, so the orchestrator can report and pin engine versions
+type EngineReporter interface {
+	EngineInfo(ctx context.Context, model string) *pb.ModelEngine
+}
+
+// Models returns the models currently loaded and the engines serving them, sorted by model
+func (s *Service) Models() []*pb.ModelEngine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	models := make([]*pb.ModelEngine, 0, len(s.runningModels))
+	for name, instance := range s.runningModels {
+		engine := instance.Engine
+		if engine == nil {
+			engine = &pb.ModelEngine{Model: name}
+		}
+		models = append(models, engine)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+	return models
+}
+
+// engineInfo identifies the engine build an executor runs a model on
+func engineInfo(ctx context.Context, executor Executor, model string) *pb.ModelEngine {
+	reporter, ok := executor.(EngineReporter)
+	if !ok {
+		return &pb.ModelEngine{Model: model}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, engineInfoTimeout)
+	defer cancel()
+	return reporter.EngineInfo(ctx, model)
+}
+
+// imageDigest returns the digest of a container image, or an empty string if
+// it can't be determined
+func imageDigest(ctx context.Context, manager containers.Manager, image string) string {
+	digest, err := manager.ImageDigest(ctx, image)
+	if err != nil {
+		log.Printf("Failed to determine digest of image %s: %v", image, err)
+		return ""
+	}
+	return digest
+}
+
+// fetchEngineVersion reads the "version" field an engine serves at url, as both
+// Ollama (/api/version) and vLLM (/version) do. It returns an empty string if
+// the engine doesn't answer.
+func fetchEngineVersion(ctx context.Context, transport http.RoundTripper, url string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return ""
+	}
+
+	client := &http.Client{Transport: transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to query engine version at %s: %v", url, err)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Engine version query at %s returned status %d", url, resp.StatusCode)
+		return ""
+	}
+
+	var body struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		log.Printf("Failed to decode engine version from %s: %v", url, err)
+		return ""
+	}
+	return body.Version
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// serverPort returns the port a test server listens on
+func serverPort(t *testing.T, server *httptest.Server) int {
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return port
+}
+
+func TestVLLMExecutor_EngineInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/version", r.URL.Path)
+		w.Write([]byte(`{"version": "0.6.3.post1"}`))
+	}))
+	defer server.Close()
+
+	e := NewVLLMExecutor(NewMockContainerManager())
+	e.runningPorts.set("mistralai/Mistral-7B-v0.1", serverPort(t, server))
+
+	info := e.EngineInfo(context.Background(), "mistralai/Mistral-7B-v0.1")
+	assert.Equal(t, &pb.ModelEngine{
+		Model:       "mistralai/Mistral-7B-v0.1",
+		Engine:      "vllm",
+		Image:       containers.DefaultVLLMImage,
+		ImageDigest: "sha256:" + containers.DefaultVLLMImage,
+		Version:     "0.6.3.post1",
+	}, info)
+}
+
+func TestOllamaExecutor_EngineInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"version": "0.3.12"}`))
+	}))
+	defer server.Close()
+
+	e := NewOllamaExecutor(NewMockContainerManager())
+	e.runningPorts.set("llama3", serverPort(t, server))
+
+	info := e.EngineInfo(context.Background(), "llama3")
+	assert.Equal(t, "ollama", info.Engine)
+	assert.Equal(t, containers.DefaultOllamaImage, info.Image)
+	assert.Equal(t, "0.3.12", info.Version)
+
+	// The version stays unknown while the engine doesn't answer
+	server.Close()
+	assert.Empty(t, e.EngineInfo(context.Background(), "llama3").Version)
+}
+
+func TestService_Models(t *testing.T) {
+	service := newTestService(NewMockContainerManager())
+	service.runningModels["mistralai/Mistral-7B-v0.1"] = &ModelInstance{
+		Model:  "mistralai/Mistral-7B-v0.1",
+		Engine: &pb.ModelEngine{Model: "mistralai/Mistral-7B-v0.1", Engine: "vllm", Version: "0.6.3"},
+	}
+	service.runningModels["llama3"] = &ModelInstance{Model: "llama3"}
+
+	models := service.Models()
+	require.Len(t, models, 2)
+	assert.Equal(t, "llama3", models[0].Model)
+	assert.Empty(t, models[0].Engine)
+	assert.Equal(t, "0.6.3", models[1].Version)
+}
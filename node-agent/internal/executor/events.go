@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// EventType is the kind of an Event
+type EventType int
+
+const (
+	EventToken    EventType = iota // Generated content
+	EventToolCall                  // A fragment of a tool call
+	EventUsage                     // Token usage reported by the engine
+	EventDone                      // The generation ended
+)
+
+// Event is what an executor streams while generating a chat completion.
+// Content and tool calls are reported as the engine generates them, usage
+// whenever the engine reports it, and a single done event ends the stream
+// with the finish reason or the error that ended the generation.
+type Event struct {
+	Type         EventType
+	Content      string       // EventToken
+	ToolCall     *pb.ToolCall // EventToolCall; arguments continue earlier fragments of the same index
+	Usage        Usage        // EventUsage; replaces usage reported earlier
+	FinishReason string       // EventDone: "stop", "length", "tool_calls", ...
+	Err          error        // EventDone, if the generation failed
+}
+
+// Usage is the token usage of a chat completion
+type Usage struct {
+	PromptTokens     int32
+	CompletionTokens int32
+	CachedTokens     int32 // Prompt tokens served from the engine's prefix cache
+}
+
+// emit sends an event unless the request was cancelled, reporting whether it
+// was sent, so executors don't block on callers that went away
+func emit(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// fail ends an event stream with an error
+func fail(ctx context.Context, events chan<- Event, err error) {
+	emit(ctx, events, Event{Type: EventDone, Err: err})
+}
+
+// chatStream turns executor events into chat completion responses: for
+// streaming requests, a chunk per token or tool call fragment and a last
+// chunk with the finish reason and usage; otherwise a single response with
+// the whole message once the generation is done.
+type chatStream struct {
+	id        string
+	model     string
+	created   int64
+	stream    bool
+	content   strings.Builder
+	toolCalls []*pb.ToolCall
+	usage     Usage
+	done      bool
+}
+
+// newChatStream creates the response stream of a chat completion request
+func newChatStream(req *pb.ChatCompletionRequest) *chatStream {
+	now := time.Now()
+	return &chatStream{
+		id:      fmt.Sprintf("chatcmpl-%d", now.UnixNano()),
+		model:   req.Model,
+		created: now.Unix(),
+		stream:  req.Stream,
+	}
+}
+
+// add takes an event and returns the response to send for it, if any. A done
+// event carrying an error returns that error.
+func (c *chatStream) add(event Event) (*pb.ChatCompletionResponse, error) {
+	switch event.Type {
+	case EventToken:
+		if !c.stream {
+			c.content.WriteString(event.Content)
+			return nil, nil
+		}
+		if event.Content == "" {
+			return nil, nil
+		}
+		return c.response("chat.completion.chunk", &pb.ChatMessage{Role: "assistant", Content: event.Content}, ""), nil
+	case EventToolCall:
+		if !c.stream {
+			c.mergeToolCall(event.ToolCall)
+			return nil, nil
+		}
+		message := &pb.ChatMessage{Role: "assistant", ToolCalls: []*pb.ToolCall{event.ToolCall}}
+		return c.response("chat.completion.chunk", message, ""), nil
+	case EventUsage:
+		c.usage = event.Usage
+		return nil, nil
+	case EventDone:
+		c.done = true
+		if event.Err != nil {
+			return nil, event.Err
+		}
+		finishReason := event.FinishReason
+		if finishReason == "" {
+			finishReason = "stop"
+		}
+		var resp *pb.ChatCompletionResponse
+		if c.stream {
+			resp = c.response("chat.completion.chunk", &pb.ChatMessage{Role: "assistant"}, finishReason)
+		} else {
+			message := &pb.ChatMessage{Role: "assistant", Content: c.content.String(), ToolCalls: c.toolCalls}
+			resp = c.response("chat.completion", message, finishReason)
+		}
+		resp.UsagePromptTokens = c.usage.PromptTokens
+		resp.UsageCompletionTokens = c.usage.CompletionTokens
+		resp.UsageCachedTokens = c.usage.CachedTokens
+		return resp, nil
+	}
+	return nil, nil
+}
+
+// close reports an error if the executor stopped without a done event
+func (c *chatStream) close() error {
+	if !c.done {
+		return errors.New("engine stopped before finishing the reply")
+	}
+	return nil
+}
+
+// mergeToolCall adds a tool call fragment to the call of the same index
+func (c *chatStream) mergeToolCall(fragment *pb.ToolCall) {
+	for _, call := range c.toolCalls {
+		if call.Index == fragment.Index {
+			if fragment.Id != "" {
+				call.Id = fragment.Id
+			}
+			if fragment.Name != "" {
+				call.Name = fragment.Name
+			}
+			call.Arguments += fragment.Arguments
+			return
+		}
+	}
+	c.toolCalls = append(c.toolCalls, &pb.ToolCall{
+		Index:     fragment.Index,
+		Id:        fragment.Id,
+		Name:      fragment.Name,
+		Arguments: fragment.Arguments,
+	})
+}
+
+// response builds a response with a single choice
+func (c *chatStream) response(object string, message *pb.ChatMessage, finishReason string) *pb.ChatCompletionResponse {
+	return &pb.ChatCompletionResponse{
+		Id:      c.id,
+		Model:   c.model,
+		Object:  object,
+		Created: c.created,
+		Choices: []*pb.ChatChoice{{Message: message, FinishReason: finishReason}},
+	}
+}
@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestChatStream(t *testing.T) {
+	events := []Event{
+		{Type: EventToken, Content: "Let me "},
+		{Type: EventToken, Content: "check."},
+		{Type: EventToolCall, ToolCall: &pb.ToolCall{Index: 0, Id: "call_1", Name: "get_weather", Arguments: `{"city":`}},
+		{Type: EventToolCall, ToolCall: &pb.ToolCall{Index: 0, Arguments: `"Oslo"}`}},
+		{Type: EventUsage, Usage: Usage{PromptTokens: 5}},
+		{Type: EventUsage, Usage: Usage{PromptTokens: 12, CompletionTokens: 9, CachedTokens: 8}},
+		{Type: EventDone, FinishReason: "tool_calls"},
+	}
+
+	t.Run("streaming", func(t *testing.T) {
+		chat := newChatStream(&pb.ChatCompletionRequest{Model: "llama3", Stream: true})
+		var responses []*pb.ChatCompletionResponse
+		for _, event := range events {
+			resp, err := chat.add(event)
+			require.NoError(t, err)
+			if resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		require.NoError(t, chat.close())
+
+		require.Len(t, responses, 5)
+		for _, resp := range responses {
+			assert.Equal(t, "chat.completion.chunk", resp.Object)
+			assert.Equal(t, responses[0].Id, resp.Id)
+		}
+		assert.Equal(t, "check.", responses[1].Choices[0].Message.Content)
+		assert.Empty(t, responses[1].Choices[0].FinishReason)
+		assert.Equal(t, `"Oslo"}`, responses[3].Choices[0].Message.ToolCalls[0].Arguments)
+
+		// The last chunk carries the finish reason and the final usage
+		last := responses[4]
+		assert.Equal(t, "tool_calls", last.Choices[0].FinishReason)
+		assert.Equal(t, int32(12), last.UsagePromptTokens)
+		assert.Equal(t, int32(9), last.UsageCompletionTokens)
+		assert.Equal(t, int32(8), last.UsageCachedTokens)
+	})
+
+	t.Run("non-streaming", func(t *testing.T) {
+		chat := newChatStream(&pb.ChatCompletionRequest{Model: "llama3"})
+		var responses []*pb.ChatCompletionResponse
+		for _, event := range events {
+			resp, err := chat.add(event)
+			require.NoError(t, err)
+			if resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+
+		require.Len(t, responses, 1)
+		resp := responses[0]
+		assert.Equal(t, "chat.completion", resp.Object)
+		assert.Equal(t, "Let me check.", resp.Choices[0].Message.Content)
+		require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+		call := resp.Choices[0].Message.ToolCalls[0]
+		assert.Equal(t, "call_1", call.Id)
+		assert.Equal(t, "get_weather", call.Name)
+		assert.Equal(t, `{"city":"Oslo"}`, call.Arguments)
+		assert.Equal(t, "tool_calls", resp.Choices[0].FinishReason)
+		assert.Equal(t, int32(9), resp.UsageCompletionTokens)
+	})
+
+	t.Run("failed generation", func(t *testing.T) {
+		chat := newChatStream(&pb.ChatCompletionRequest{Model: "llama3", Stream: true})
+		_, err := chat.add(Event{Type: EventDone, Err: errors.New("CUDA error: out of memory")})
+		assert.EqualError(t, err, "CUDA error: out of memory")
+	})
+
+	t.Run("stream ended early", func(t *testing.T) {
+		chat := newChatStream(&pb.ChatCompletionRequest{Model: "llama3", Stream: true})
+		_, err := chat.add(Event{Type: EventToken, Content: "Hel"})
+		require.NoError(t, err)
+		assert.Error(t, chat.close())
+	})
+}
+
+func TestOllamaEvents(t *testing.T) {
+	t.Run("streamed reply", func(t *testing.T) {
+		body := `{"message":{"role":"assistant","content":"Hel"},"done":false}
+{"message":{"role":"assistant","content":"lo"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":12,"eval_count":2}
+`
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			ollamaEvents(ctx, strings.NewReader(body), events)
+		})
+		assert.Equal(t, []Event{
+			{Type: EventToken, Content: "Hel"},
+			{Type: EventToken, Content: "lo"},
+			{Type: EventUsage, Usage: Usage{PromptTokens: 12, CompletionTokens: 2}},
+			{Type: EventDone, FinishReason: "length"},
+		}, events)
+	})
+
+	t.Run("tool calls", func(t *testing.T) {
+		body := `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Oslo"}}}]},"done":true,"done_reason":"stop"}`
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			ollamaEvents(ctx, strings.NewReader(body), events)
+		})
+		require.Len(t, events, 3)
+		assert.Equal(t, &pb.ToolCall{Id: "call_0", Name: "get_weather", Arguments: `{"city":"Oslo"}`}, events[0].ToolCall)
+		assert.Equal(t, "tool_calls", events[2].FinishReason)
+	})
+
+	t.Run("cut off reply", func(t *testing.T) {
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			ollamaEvents(ctx, strings.NewReader(`{"message":{"content":"Hel"},"done":false}`), events)
+		})
+		require.Len(t, events, 2)
+		assert.Error(t, events[1].Err)
+	})
+
+	t.Run("caller went away", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		events := make(chan Event)
+		ollamaEvents(ctx, strings.NewReader(`{"message":{"content":"Hi"},"done":true}`), events)
+	})
+}
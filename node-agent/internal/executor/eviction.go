@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// EvictionPolicy decides what happens when a model doesn't fit in GPU memory
+// beside the models already loaded
+type EvictionPolicy string
+
+const (
+	// EvictNone fails the request
+	EvictNone EvictionPolicy = "none"
+	// EvictLRU stops the least recently used models until the new one starts
+	EvictLRU EvictionPolicy = "lru"
+)
+
+// ParseEvictionPolicy parses an eviction policy name ("none" or "lru")
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	switch policy := EvictionPolicy(s); policy {
+	case EvictNone, EvictLRU:
+		return policy, nil
+	case "":
+		return EvictNone, nil
+	default:
+		return "", fmt.Errorf("invalid eviction policy %q (want none or lru)", s)
+	}
+}
+
+// SetEvictionPolicy sets how models that don't fit in GPU memory are handled
+func (s *Service) SetEvictionPolicy(policy EvictionPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eviction = policy
+}
+
+// startModel starts a model. If it fails for lack of GPU memory and the
+// eviction policy allows it, the least recently used models are stopped one
+// at a time until it starts. Callers must not hold mu.
+func (s *Service) startModel(ctx context.Context, executor Executor, model string) error {
+	for {
+		err := executor.StartModel(ctx, model)
+		if err == nil || errcode.Classify(err) != pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED {
+			return err
+		}
+
+		victim := s.evictionVictim(model)
+		if victim == nil {
+			return err
+		}
+
+		log.Printf("Model %s doesn't fit in GPU memory, evicting least recently used model %s (last used %s)",
+			model, victim.Model, victim.LastUsed.Format("15:04:05"))
+		if stopErr := victim.Executor.StopModel(ctx, victim.Model); stopErr != nil {
+			s.restoreModel(victim, stopErr)
+			return fmt.Errorf("%w (evicting model %s failed: %v)", err, victim.Model, stopErr)
+		}
+		s.forgetModelState(victim.Model)
+	}
+}
+
+// evictionVictim untracks and returns the model to stop to make room for
+// model, or nil if the eviction policy doesn't allow it or nothing is left to
+// stop. Untracking it first keeps concurrent starts from picking it too.
+func (s *Service) evictionVictim(model string) *ModelInstance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.eviction != EvictLRU {
+		return nil
+	}
+	victim := s.leastRecentlyUsed(model)
+	if victim != nil {
+		delete(s.runningModels, victim.Model)
+		s.setModelStateLocked(victim.Model, pb.ModelState_MODEL_STATE_STOPPING, nil)
+	}
+	return victim
+}
+
+// leastRecentlyUsed returns the running model used longest ago, other than
+// model and warm pool models, or nil if there is none. Callers must hold mu.
+func (s *Service) leastRecentlyUsed(model string) *ModelInstance {
+	var lru *ModelInstance
+	for name, instance := range s.runningModels {
+		if name == model || s.warm.pinned(name) {
+			continue
+		}
+		if lru == nil || instance.LastUsed.Before(lru.LastUsed) {
+			lru = instance
+		}
+	}
+	return lru
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// fakeEngine is an executor that can hold capacity models at a time
+type fakeEngine struct {
+	capacity int
+	loaded   map[string]bool
+	stopped  []string
+}
+
+func newFakeEngine(capacity int) *fakeEngine {
+	return &fakeEngine{capacity: capacity, loaded: make(map[string]bool)}
+}
+
+func (e *fakeEngine) StartModel(ctx context.Context, model string) error {
+	if len(e.loaded) >= e.capacity {
+		return errors.New("CUDA error: out of memory")
+	}
+	e.loaded[model] = true
+	return nil
+}
+
+func (e *fakeEngine) StopModel(ctx context.Context, model string) error {
+	delete(e.loaded, model)
+	e.stopped = append(e.stopped, model)
+	return nil
+}
+
+func (e *fakeEngine) IsModelRunning(ctx context.Context, model string) (bool, error) {
+	return e.loaded[model], nil
+}
+
+func (e *fakeEngine) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *fakeEngine) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestParseEvictionPolicy(t *testing.T) {
+	for input, want := range map[string]EvictionPolicy{"": EvictNone, "none": EvictNone, "lru": EvictLRU} {
+		policy, err := ParseEvictionPolicy(input)
+		require.NoError(t, err)
+		assert.Equal(t, want, policy)
+	}
+
+	_, err := ParseEvictionPolicy("fifo")
+	assert.Error(t, err)
+}
+
+func TestService_ensureModelRunning_Eviction(t *testing.T) {
+	ctx := context.Background()
+	newService := func(engine *fakeEngine, policy EvictionPolicy) *Service {
+		service := &Service{
+			executors:     map[string]Executor{"ollama": engine},
+			runningModels: make(map[string]*ModelInstance),
+		}
+		service.SetEvictionPolicy(policy)
+		return service
+	}
+
+	t.Run("least recently used model is evicted", func(t *testing.T) {
+		engine := newFakeEngine(2)
+		service := newService(engine, EvictLRU)
+		require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+		require.NoError(t, service.ensureModelRunning(ctx, "mistral"))
+		service.runningModels["llama3"].LastUsed = time.Now().Add(time.Minute)
+
+		require.NoError(t, service.ensureModelRunning(ctx, "phi3"))
+		assert.Equal(t, []string{"mistral"}, engine.stopped)
+		assert.Contains(t, service.runningModels, "llama3")
+		assert.Contains(t, service.runningModels, "phi3")
+		assert.NotContains(t, service.runningModels, "mistral")
+	})
+
+	t.Run("using a model keeps it loaded", func(t *testing.T) {
+		engine := newFakeEngine(2)
+		service := newService(engine, EvictLRU)
+		require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+		require.NoError(t, service.ensureModelRunning(ctx, "mistral"))
+		service.runningModels["llama3"].LastUsed = time.Now().Add(-time.Hour)
+		service.runningModels["mistral"].LastUsed = time.Now().Add(-time.Minute)
+
+		require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+		require.NoError(t, service.ensureModelRunning(ctx, "phi3"))
+		assert.Equal(t, []string{"mistral"}, engine.stopped)
+	})
+
+	t.Run("without eviction the request fails", func(t *testing.T) {
+		engine := newFakeEngine(1)
+		service := newService(engine, EvictNone)
+		require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+
+		err := service.ensureModelRunning(ctx, "phi3")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "out of memory")
+		assert.Empty(t, engine.stopped)
+	})
+
+	t.Run("fails once nothing is left to evict", func(t *testing.T) {
+		engine := newFakeEngine(0)
+		service := newService(engine, EvictLRU)
+
+		err := service.ensureModelRunning(ctx, "llama3")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "out of memory")
+	})
+}
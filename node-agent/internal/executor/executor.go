@@ -8,11 +8,15 @@ import (
 	"sync"
 	"time"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
 	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
 	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
 // Service implements the NodeAgent gRPC service using containerized inference engines
@@ -21,6 +25,22 @@ type Service struct {
 	containerManager containers.Manager
 	executors        map[string]Executor // model name -> executor
 	runningModels    map[string]*ModelInstance
+	starting         map[string]*modelStart     // Models being checked or started
+	states           map[string]*pb.ModelStatus // Lifecycle state of each model, for GetModelStatus
+	tracer           *Tracer
+	gpu              capabilities.GPUDetector
+	sampler          *telemetry.Sampler
+	metrics          *telemetry.Metrics
+	guard            *MemoryGuard
+	throttle         *Throttle
+	limiter          *Limiter
+	reserve          float64 // Fraction of capacity batch requests leave free for interactive ones
+	warm             *warmPool
+	eviction         EvictionPolicy
+	images           map[string]string // Engine images as configured, before image pins
+	journal          *Journal
+	routes           []*pb.EngineRoute // Checked before the default engine choice
+	logger           logging.Logger
 	mu               sync.RWMutex
 }
 
@@ -29,7 +49,7 @@ type Executor interface {
 	StartModel(ctx context.Context, model string) error
 	StopModel(ctx context.Context, model string) error
 	IsModelRunning(ctx context.Context, model string) (bool, error)
-	ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan *pb.ChatCompletionResponse, error)
+	ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error)
 	Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error)
 }
 
@@ -38,96 +58,350 @@ type ModelInstance struct {
 	Model     string
 	Executor  Executor
 	StartTime time.Time
+	LastUsed  time.Time       // Last request for the model, for LRU eviction
+	Engine    *pb.ModelEngine // Engine build serving the model, reported to the orchestrator
 }
 
+// modelStart is an ensureModelRunning call in progress, which concurrent
+// callers for the same model wait for instead of starting the model again
+type modelStart struct {
+	done chan struct{}
+	err  error
+}
+
+// ModelLoadingStatus is the status of chat responses sent while the model is
+// still starting
+const ModelLoadingStatus = "loading"
+
+// ModelLoadingInterval is how often a chat stream reports that its model is
+// still starting
+const ModelLoadingInterval = 5 * time.Second
+
 // NewService creates a new executor service
 func NewService() (*Service, error) {
+	return newService(NewTracer(), capabilities.SystemGPUDetector{})
+}
+
+// newService creates an executor service tracing engine traffic with tracer
+// and sampling the GPU through gpu
+func newService(tracer *Tracer, gpu capabilities.GPUDetector) (*Service, error) {
 	manager, err := containers.NewContainerManager()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container manager: %w", err)
 	}
 
+	cached := capabilities.NewCachedGPUDetector(gpu, telemetry.DefaultSampleTTL)
 	service := &Service{
 		containerManager: manager,
 		executors:        make(map[string]Executor),
 		runningModels:    make(map[string]*ModelInstance),
+		eviction:         EvictNone,
+		tracer:           tracer,
+		gpu:              cached,
+		sampler:          telemetry.NewSampler(cached),
 	}
 
-	// Register default executors
-	service.executors["ollama"] = NewOllamaExecutor(manager)
-	service.executors["vllm"] = NewVLLMExecutor(manager)
+	metrics, err := telemetry.NewMetrics(telemetry.Meter())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics: %w", err)
+	}
+	service.metrics = metrics
+
+	// Register default executors, routing engine traffic through the tracer
+	ollama := NewOllamaExecutor(manager)
+	ollama.transport = service.tracer.Transport(nil)
+	if release, ok := capabilities.JetsonRelease(); ok {
+		ollama.config = containers.JetsonOllamaConfig(release)
+		log.Printf("Detected NVIDIA Jetson (L4T %s), using Ollama image %s", release, ollama.config.Image)
+	}
+	service.executors["ollama"] = ollama
+
+	vllm := NewVLLMExecutor(manager)
+	vllm.transport = service.tracer.Transport(nil)
+	service.executors["vllm"] = vllm
+
+	piper := NewPiperExecutor(manager)
+	piper.transport = service.tracer.Transport(nil)
+	service.executors["piper"] = piper
+
+	coqui := NewCoquiExecutor(manager)
+	coqui.transport = service.tracer.Transport(nil)
+	service.executors["coqui"] = coqui
 
 	return service, nil
 }
 
+// Tracer returns the tracer used for engine HTTP traffic
+func (s *Service) Tracer() *Tracer {
+	return s.tracer
+}
+
 // ChatCompletion handles chat completion requests by routing to appropriate executor
 func (s *Service) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.NodeAgent_ChatCompletionServer) error {
 	if req.Model == "" {
-		return status.Error(codes.InvalidArgument, "model is required")
+		return errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
 	}
 
 	ctx := stream.Context()
 
-	// Ensure model is running
-	if err := s.ensureModelRunning(ctx, req.Model); err != nil {
-		return status.Error(codes.Internal, fmt.Sprintf("failed to start model %s: %v", req.Model, err))
+	// Record the job until it ends, so it can be retried if the agent crashes
+	defer s.journalJob(ctx, req.Model)()
+
+	// Shed requests while the GPU runs over its limits
+	release, err := s.acquireThrottle()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Wait for a slot when saturated, higher priority requests first
+	batch := isBatch(req.Batch, req.Priority)
+	releaseSlot, err := s.acquireLimiter(ctx, req.Priority, batch)
+	if err != nil {
+		return err
+	}
+	defer releaseSlot()
+
+	// Ensure model is running, telling the caller while it loads so clients
+	// don't give up on a long cold start
+	err = s.ensureModelRunningReporting(ctx, req.Model, ModelLoadingInterval, func() error {
+		return stream.Send(&pb.ChatCompletionResponse{Model: req.Model, Status: ModelLoadingStatus})
+	})
+	if err != nil {
+		return errcode.Engine(fmt.Sprintf("failed to start model %s", req.Model), err)
 	}
 
 	// Get executor for this model
 	executor, err := s.getExecutorForModel(req.Model)
 	if err != nil {
-		return status.Error(codes.Internal, fmt.Sprintf("no executor for model %s: %v", req.Model, err))
+		return errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no executor for model %s: %v", req.Model, err)
+	}
+
+	// Fail fast if the KV cache is unlikely to fit in free VRAM
+	if err := s.checkMemory(req.Model, chatTokens(req), batch); err != nil {
+		return err
 	}
 
 	// Execute request
-	responseChan, err := executor.ChatCompletion(ctx, req.Model, req)
+	usage := s.startUsage(req.Model, "chat")
+	events, err := executor.ChatCompletion(ctx, req.Model, req)
 	if err != nil {
-		return status.Error(codes.Internal, fmt.Sprintf("failed to execute chat completion: %v", err))
+		return errcode.Engine("failed to execute chat completion", err)
 	}
 
 	// Stream responses
-	for resp := range responseChan {
+	chat := newChatStream(req)
+	for event := range events {
+		resp, err := chat.add(event)
+		if err != nil {
+			return errcode.Engine("chat completion failed", err)
+		}
+		if resp == nil {
+			continue
+		}
 		if err := stream.Send(resp); err != nil {
 			return err
 		}
 	}
+	if err := chat.close(); err != nil {
+		return errcode.Engine("chat completion failed", err)
+	}
+
+	// Report GPU usage so the orchestrator can attach it to the job
+	if md := usage.finish(ctx); md != nil {
+		stream.SetTrailer(md)
+	}
 
 	return nil
 }
 
+// ensureModelRunningReporting ensures a model is running like
+// ensureModelRunning, calling report every interval until it is. An error
+// from report, e.g. because the caller went away, stops the wait.
+func (s *Service) ensureModelRunningReporting(ctx context.Context, model string, interval time.Duration, report func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ensureModelRunning(ctx, model)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if err := report(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 // Embeddings handles embedding requests by routing to appropriate executor
 func (s *Service) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
 	if req.Model == "" {
-		return nil, status.Error(codes.InvalidArgument, "model is required")
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
 	}
 
-	// Ensure model is running
-	if err := s.ensureModelRunning(ctx, req.Model); err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to start model %s: %v", req.Model, err))
+	// Record the job until it ends, so it can be retried if the agent crashes
+	defer s.journalJob(ctx, req.Model)()
+
+	// Shed requests while the GPU runs over its limits
+	release, err := s.acquireThrottle()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// Get executor for this model
-	executor, err := s.getExecutorForModel(req.Model)
+	releaseSlot, err := s.acquireLimiter(ctx, 0, req.Batch)
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("no executor for model %s: %v", req.Model, err))
+		return nil, err
+	}
+	defer releaseSlot()
+
+	// Warm pool models are known to be loaded and skip the model start path
+	executor, warm := s.warm.executor(req.Model)
+	if !warm {
+		// Ensure model is running
+		if err := s.ensureModelRunning(ctx, req.Model); err != nil {
+			return nil, errcode.Engine(fmt.Sprintf("failed to start model %s", req.Model), err)
+		}
+
+		// Get executor for this model
+		executor, err = s.getExecutorForModel(req.Model)
+		if err != nil {
+			return nil, errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no executor for model %s: %v", req.Model, err)
+		}
+	}
+
+	// Fail fast if the KV cache is unlikely to fit in free VRAM
+	if err := s.checkMemory(req.Model, embeddingTokens(req), req.Batch); err != nil {
+		return nil, err
 	}
 
 	// Execute request
-	return executor.Embeddings(ctx, req.Model, req)
+	usage := s.startUsage(req.Model, "embeddings")
+	resp, err := executor.Embeddings(ctx, req.Model, req)
+	if err != nil {
+		// Send the next requests through the start path until the model is
+		// probed again, in case its engine went away
+		if warm {
+			s.warm.cool(req.Model)
+		}
+		return nil, errcode.Engine("failed to execute embeddings", err)
+	}
+
+	// Report GPU usage so the orchestrator can attach it to the job
+	if md := usage.finish(ctx); md != nil {
+		grpc.SetTrailer(ctx, md)
+	}
+	return resp, nil
 }
 
-// ensureModelRunning ensures the specified model is running
-func (s *Service) ensureModelRunning(ctx context.Context, model string) error {
+// LoadModel starts a model ahead of traffic, e.g. for a warm standby replica
+func (s *Service) LoadModel(ctx context.Context, req *pb.LoadModelRequest) (*pb.LoadModelResponse, error) {
+	if req.Model == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
+	}
+
+	if err := s.ensureModelRunning(ctx, req.Model); err != nil {
+		return nil, errcode.Engine(fmt.Sprintf("failed to load model %s", req.Model), err)
+	}
+	return &pb.LoadModelResponse{}, nil
+}
+
+// UnloadModel stops a running model. Unloading a model that isn't running succeeds.
+func (s *Service) UnloadModel(ctx context.Context, req *pb.UnloadModelRequest) (*pb.UnloadModelResponse, error) {
+	if req.Model == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
+	}
+
+	s.mu.Lock()
+	instance, exists := s.runningModels[req.Model]
+	if !exists {
+		s.mu.Unlock()
+		return &pb.UnloadModelResponse{}, nil
+	}
+	if s.warm.pinned(req.Model) {
+		s.mu.Unlock()
+		log.Printf("Keeping warm model %s loaded", req.Model)
+		return &pb.UnloadModelResponse{}, nil
+	}
+	delete(s.runningModels, req.Model)
+	s.setModelStateLocked(req.Model, pb.ModelState_MODEL_STATE_STOPPING, nil)
+	s.mu.Unlock()
+
+	log.Printf("Unloading model: %s", req.Model)
+	if err := instance.Executor.StopModel(ctx, req.Model); err != nil {
+		s.restoreModel(instance, err)
+		return nil, errcode.Engine(fmt.Sprintf("failed to unload model %s", req.Model), err)
+	}
+	s.forgetModelState(req.Model)
+	return &pb.UnloadModelResponse{}, nil
+}
+
+// restoreModel tracks a model again, as degraded, after stopping it failed,
+// unless it was started again meanwhile
+func (s *Service) restoreModel(instance *ModelInstance, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, exists := s.runningModels[instance.Model]; !exists {
+		s.runningModels[instance.Model] = instance
+		s.setModelStateLocked(instance.Model, pb.ModelState_MODEL_STATE_DEGRADED, err)
+	}
+}
+
+// ensureModelRunning ensures the specified model is running. The model is
+// checked and started without holding mu, so requests for other models go
+// ahead meanwhile, and concurrent callers for the same model share a single
+// start. A start carries on if its caller gives up, for the callers still
+// waiting on it.
+func (s *Service) ensureModelRunning(ctx context.Context, model string) error {
+	s.mu.Lock()
+	if s.starting == nil {
+		s.starting = make(map[string]*modelStart)
+	}
+	start, ok := s.starting[model]
+	if !ok {
+		start = &modelStart{done: make(chan struct{})}
+		s.starting[model] = start
+		go func() {
+			start.err = s.runModel(context.WithoutCancel(ctx), model)
+
+			s.mu.Lock()
+			delete(s.starting, model)
+			s.mu.Unlock()
+			close(start.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-start.done:
+		return start.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// runModel starts a model unless its engine is already running it
+func (s *Service) runModel(ctx context.Context, model string) error {
 	// Check if already running
-	if instance, exists := s.runningModels[model]; exists {
+	s.mu.RLock()
+	instance, exists := s.runningModels[model]
+	s.mu.RUnlock()
+	if exists {
 		running, err := instance.Executor.IsModelRunning(ctx, model)
 		if err != nil {
 			log.Printf("Failed to check if model %s is running: %v", model, err)
 			// Continue with starting the model
 		} else if running {
+			s.mu.Lock()
+			instance.LastUsed = time.Now()
+			// The engine serves the model again, e.g. after a failed probe
+			s.setModelStateLocked(model, pb.ModelState_MODEL_STATE_READY, nil)
+			s.mu.Unlock()
 			return nil // Already running
 		}
 	}
@@ -138,18 +412,28 @@ func (s *Service) ensureModelRunning(ctx context.Context, model string) error {
 		return fmt.Errorf("no executor for model %s: %w", model, err)
 	}
 
-	// Start the model
+	// Start the model, letting the executor report e.g. a download
 	log.Printf("Starting model: %s", model)
-	if err := executor.StartModel(ctx, model); err != nil {
+	s.setModelState(model, pb.ModelState_MODEL_STATE_STARTING, nil)
+	ctx = withStateReporter(ctx, func(state pb.ModelState) { s.setModelState(model, state, nil) })
+	if err := s.startModel(ctx, executor, model); err != nil {
+		s.setModelState(model, pb.ModelState_MODEL_STATE_DEGRADED, err)
 		return fmt.Errorf("failed to start model %s: %w", model, err)
 	}
 
 	// Track the running model
-	s.runningModels[model] = &ModelInstance{
+	now := time.Now()
+	instance = &ModelInstance{
 		Model:     model,
 		Executor:  executor,
-		StartTime: time.Now(),
+		StartTime: now,
+		LastUsed:  now,
+		Engine:    engineInfo(ctx, executor, model),
 	}
+	s.mu.Lock()
+	s.runningModels[model] = instance
+	s.setModelStateLocked(model, pb.ModelState_MODEL_STATE_READY, nil)
+	s.mu.Unlock()
 
 	log.Printf("Model %s started successfully", model)
 	return nil
@@ -159,7 +443,20 @@ func (s *Service) ensureModelRunning(ctx context.Context, model string) error {
 func (s *Service) getExecutorForModel(model string) (Executor, error) {
 	// Simple routing logic - can be enhanced later
 	// For now: use Ollama for models without "/" (like "llama2", "mistral")
-	// and vLLM for models with "/" (like "mistralai/Mistral-7B")
+	// and vLLM for models with "/" (like "mistralai/Mistral-7B"), except
+	// text-to-speech models, which name their engine (like "piper/en_US-lessac-medium").
+	// Engine routes take precedence.
+
+	if executor, routed, err := s.routedExecutor(model); routed {
+		return executor, err
+	}
+
+	if engine, _, ok := speechEngine(model); ok {
+		if executor, exists := s.executors[engine]; exists {
+			return executor, nil
+		}
+		return nil, fmt.Errorf("no %s executor for model %s", engine, model)
+	}
 
 	if strings.Contains(model, "/") {
 		// Likely a HuggingFace model, use vLLM
@@ -199,6 +496,8 @@ func (s *Service) Shutdown(ctx context.Context) error {
 
 	// Clear running models
 	s.runningModels = make(map[string]*ModelInstance)
+	s.states = nil
+	s.warm.coolAll()
 
 	if lastErr != nil {
 		return fmt.Errorf("errors occurred during shutdown: %w", lastErr)
@@ -207,3 +506,25 @@ func (s *Service) Shutdown(ctx context.Context) error {
 	log.Printf("Executor service shutdown complete")
 	return nil
 }
+
+// SetLogger sets the logger whose level SetLogLevel adjusts
+func (s *Service) SetLogger(logger logging.Logger) {
+	s.logger = logger
+}
+
+// SetLogLevel changes the node agent's log level without a restart
+func (s *Service) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	if s.logger == nil {
+		return nil, errcode.New(codes.FailedPrecondition, pb.ErrorCode_ERROR_CODE_INTERNAL, "no logger configured")
+	}
+
+	if req.Level != "" {
+		level, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, err.Error())
+		}
+		s.logger.SetLevel(level)
+	}
+
+	return &pb.SetLogLevelResponse{Level: s.logger.GetLevel().String()}, nil
+}
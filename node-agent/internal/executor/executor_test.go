@@ -2,12 +2,17 @@ package executor
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
 func TestNewService(t *testing.T) {
@@ -61,4 +66,155 @@ func TestModelInstance_Structure(t *testing.T) {
 	assert.Equal(t, "test-model", instance.Model)
 	assert.NotZero(t, instance.StartTime)
 	assert.True(t, instance.StartTime.Before(time.Now().Add(time.Second)))
-}
\ No newline at end of file
+}
+func TestService_SetLogLevel(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{}
+
+	_, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	logger := logging.NewLogger(logging.Config{Level: logging.InfoLevel, Source: "test"})
+	service.SetLogger(logger)
+
+	resp, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "warn"})
+	assert.NoError(t, err)
+	assert.Equal(t, "warn", resp.Level)
+	assert.Equal(t, logging.WarnLevel, logger.GetLevel())
+
+	_, err = service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "chatty"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, logging.WarnLevel, logger.GetLevel())
+}
+
+func TestService_LoadAndUnloadModel(t *testing.T) {
+	ctx := context.Background()
+	engine := newFakeEngine(2)
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+	}
+
+	_, err := service.LoadModel(ctx, &pb.LoadModelRequest{})
+	assert.Error(t, err)
+
+	_, err = service.LoadModel(ctx, &pb.LoadModelRequest{Model: "llama3"})
+	require.NoError(t, err)
+	assert.True(t, engine.loaded["llama3"])
+	assert.Contains(t, service.runningModels, "llama3")
+
+	_, err = service.UnloadModel(ctx, &pb.UnloadModelRequest{Model: "llama3"})
+	require.NoError(t, err)
+	assert.False(t, engine.loaded["llama3"])
+	assert.NotContains(t, service.runningModels, "llama3")
+
+	// Unloading again is a no-op
+	_, err = service.UnloadModel(ctx, &pb.UnloadModelRequest{Model: "llama3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"llama3"}, engine.stopped)
+}
+
+// slowEngine takes a while to start models, like a large model's cold start
+type slowEngine struct {
+	*fakeEngine
+	delay time.Duration
+}
+
+func (e *slowEngine) StartModel(ctx context.Context, model string) error {
+	time.Sleep(e.delay)
+	return e.fakeEngine.StartModel(ctx, model)
+}
+
+func TestService_ensureModelRunningReporting(t *testing.T) {
+	ctx := context.Background()
+	engine := &slowEngine{fakeEngine: newFakeEngine(2), delay: 100 * time.Millisecond}
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+	}
+
+	reports := 0
+	report := func() error {
+		reports++
+		return nil
+	}
+	require.NoError(t, service.ensureModelRunningReporting(ctx, "llama3", 20*time.Millisecond, report))
+	assert.True(t, engine.loaded["llama3"])
+	assert.GreaterOrEqual(t, reports, 2)
+
+	// A running model is ready at once
+	reports = 0
+	require.NoError(t, service.ensureModelRunningReporting(ctx, "llama3", 20*time.Millisecond, report))
+	assert.Zero(t, reports)
+
+	// A failed report stops the wait
+	err := service.ensureModelRunningReporting(ctx, "mistral", 20*time.Millisecond, func() error {
+		return context.Canceled
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// gatedEngine holds model starts until their gate opens, counting starts
+type gatedEngine struct {
+	*fakeEngine
+	mu     sync.Mutex
+	gates  map[string]chan struct{}
+	starts map[string]int
+}
+
+func (e *gatedEngine) StartModel(ctx context.Context, model string) error {
+	e.mu.Lock()
+	e.starts[model]++
+	gate := e.gates[model]
+	e.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fakeEngine.StartModel(ctx, model)
+}
+
+func (e *gatedEngine) IsModelRunning(ctx context.Context, model string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fakeEngine.IsModelRunning(ctx, model)
+}
+
+func TestService_ensureModelRunning_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	gate := make(chan struct{})
+	engine := &gatedEngine{
+		fakeEngine: newFakeEngine(2),
+		gates:      map[string]chan struct{}{"llama3:70b": gate},
+		starts:     make(map[string]int),
+	}
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+	}
+
+	// Several requests wait for the slow model's start
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { errs <- service.ensureModelRunning(ctx, "llama3:70b") }()
+	}
+
+	// Other models start meanwhile
+	require.NoError(t, service.ensureModelRunning(ctx, "phi3"))
+	assert.Len(t, service.Models(), 1)
+
+	// A caller giving up doesn't cancel the start for the others
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, service.ensureModelRunning(canceled, "llama3:70b"), context.Canceled)
+
+	close(gate)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errs)
+	}
+	assert.Equal(t, 1, engine.starts["llama3:70b"])
+	assert.Len(t, service.Models(), 2)
+}
+
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+)
+
+// NewGPUServices creates one executor service per GPU device, i.e. NVIDIA GPU
+// or MIG slice, for an agent serving a logical node per device. Each service
+// runs its own engine containers bound to its device and samples only that
+// device; they share one tracer, so trace settings apply to all.
+func NewGPUServices(devices []capabilities.GPUDevice) ([]*Service, error) {
+	tracer := NewTracer()
+	services := make([]*Service, len(devices))
+	for i, device := range devices {
+		service, err := newService(tracer, capabilities.GPUDeviceDetector{Name: device.Name})
+		if err != nil {
+			return nil, err
+		}
+		service.bindGPU(device, i)
+		services[i] = service
+	}
+	return services, nil
+}
+
+// bindGPU gives the service's engine containers only the given device, by
+// nvidia-smi index or MIG UUID. Containers get the device name as suffix, e.g.
+// -gpu1 or -gpu0-mig1, and listen on their default port plus slot, so the
+// engines of each device run side by side.
+func (s *Service) bindGPU(device capabilities.GPUDevice, slot int) {
+	suffix := "-" + device.Name
+	gpus := []string{device.ID}
+
+	if ollama, ok := s.executors["ollama"].(*OllamaExecutor); ok && ollama.dockerAvailable {
+		config := *ollama.config
+		config.GPUs = gpus
+		config.NameSuffix = suffix
+		config.Port += slot
+		ollama.config = &config
+		ollama.basePort = config.Port
+	}
+	if vllm, ok := s.executors["vllm"].(*VLLMExecutor); ok {
+		vllm.gpus = gpus
+		vllm.nameSuffix = suffix
+		vllm.basePort += slot
+	}
+	if piper, ok := s.executors["piper"].(*PiperExecutor); ok {
+		config := *piper.config
+		config.NameSuffix = suffix
+		config.Port += slot
+		piper.config = &config
+	}
+	if coqui, ok := s.executors["coqui"].(*CoquiExecutor); ok {
+		coqui.gpus = gpus
+		coqui.nameSuffix = suffix
+		coqui.basePort += slot
+	}
+}
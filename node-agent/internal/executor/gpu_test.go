@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+)
+
+func TestService_bindGPU(t *testing.T) {
+	ollama := &OllamaExecutor{basePort: 11434, dockerAvailable: true, config: containers.DefaultOllamaConfig()}
+	vllm := NewVLLMExecutor(nil)
+	piper := NewPiperExecutor(nil)
+	coqui := NewCoquiExecutor(nil)
+	service := &Service{executors: map[string]Executor{"ollama": ollama, "vllm": vllm, "piper": piper, "coqui": coqui}}
+
+	service.bindGPU(capabilities.GPUDevice{Name: "gpu1", ID: "1", GPU: 1}, 1)
+
+	config := containers.CreateOllamaContainerConfig(ollama.config)
+	assert.Equal(t, "orchion-ollama-gpu1", config.Name)
+	assert.Equal(t, []string{"1"}, config.GPUs)
+	assert.Equal(t, 11435, config.Port)
+	assert.Contains(t, config.Environment, "OLLAMA_HOST=0.0.0.0:11435")
+	// Other services keep the default configuration
+	assert.Equal(t, 11434, containers.DefaultOllamaConfig().Port)
+
+	assert.Equal(t, []string{"1"}, vllm.gpus)
+	assert.Equal(t, 8001, vllm.basePort)
+	assert.Equal(t, "-gpu1", vllm.nameSuffix)
+
+	piperConfig := piper.containerConfig("en_US-lessac-medium")
+	assert.Equal(t, "orchion-piper-gpu1", piperConfig.Name)
+	assert.Equal(t, 5001, piperConfig.Port)
+
+	coquiConfig := coqui.containerConfig("coqui/tts_models/en/vctk/vits")
+	assert.Equal(t, "orchion-coqui-tts-models-en-vctk-vits-gpu1", coquiConfig.Name)
+	assert.Equal(t, []string{"1"}, coquiConfig.GPUs)
+	assert.Equal(t, 5003, coquiConfig.Port)
+}
+
+func TestService_bindGPU_MIG(t *testing.T) {
+	vllm := NewVLLMExecutor(nil)
+	service := &Service{executors: map[string]Executor{"vllm": vllm}}
+
+	service.bindGPU(capabilities.GPUDevice{Name: "gpu0-mig1", ID: "MIG-4e8c0f8a", MIGProfile: "3g.20gb"}, 1)
+
+	assert.Equal(t, []string{"MIG-4e8c0f8a"}, vllm.gpus)
+	assert.Equal(t, 8001, vllm.basePort)
+	assert.Equal(t, "-gpu0-mig1", vllm.nameSuffix)
+}
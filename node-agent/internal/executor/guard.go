@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// DefaultGuardMaxTokens is the completion length the memory guard assumes for
+// chat requests that don't set max_tokens
+const DefaultGuardMaxTokens = 512
+
+// charsPerToken roughly converts prompt characters to tokens
+const charsPerToken = 4
+
+// MemoryGuard rejects requests whose expected KV-cache growth doesn't fit in
+// the GPU's free memory, so they fail fast with a retryable error the
+// orchestrator can send to another node instead of running out of memory
+// mid-stream
+type MemoryGuard struct {
+	perModel   map[string]float64 // Model -> KV cache MB per 1000 tokens
+	defaultMB  float64            // For models without an entry; 0 leaves them unguarded
+	headroomMB float64            // Free memory kept on top of the expected growth
+}
+
+// NewMemoryGuard creates a guard from per-model KV-cache sizes in MB per 1000
+// tokens. Models without an entry use defaultMB.
+func NewMemoryGuard(perModel map[string]float64, defaultMB, headroomMB float64) *MemoryGuard {
+	if perModel == nil {
+		perModel = make(map[string]float64)
+	}
+	return &MemoryGuard{
+		perModel:   perModel,
+		defaultMB:  defaultMB,
+		headroomMB: headroomMB,
+	}
+}
+
+// ParseKVCacheSizes parses per-model KV-cache sizes given as
+// "model=MB,model=MB", e.g. "llama3:70b=320,mistralai/Mistral-7B-v0.1=128".
+// Sizes may also carry a unit, as in "llama3:70b=1.5 GB".
+func ParseKVCacheSizes(s string) (map[string]float64, error) {
+	sizes := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		model, value, ok := strings.Cut(pair, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("invalid KV cache size %q (want model=MB)", pair)
+		}
+		mb, ok := units.ParseMegabytes(value)
+		if !ok || mb < 0 {
+			return nil, fmt.Errorf("invalid KV cache size %q (want model=MB)", pair)
+		}
+		sizes[model] = mb
+	}
+	return sizes, nil
+}
+
+// ExpectedGrowthMB returns the KV-cache memory a request of the given length
+// in tokens is expected to need, and false if the model isn't guarded
+func (g *MemoryGuard) ExpectedGrowthMB(model string, tokens int) (float64, bool) {
+	perK, ok := g.perModel[model]
+	if !ok {
+		perK = g.defaultMB
+	}
+	if perK <= 0 {
+		return 0, false
+	}
+	return perK * float64(tokens) / 1000, true
+}
+
+// Check returns a retryable VRAM_EXHAUSTED error if sample shows less free GPU
+// memory than the request needs. Nodes without VRAM readings are not guarded.
+func (g *MemoryGuard) Check(model string, tokens int, sample *pb.GpuSample) error {
+	return g.CheckReserving(model, tokens, sample, 0)
+}
+
+// CheckReserving is Check for batch requests, which must also leave a
+// fraction of the GPU's memory free for interactive requests
+func (g *MemoryGuard) CheckReserving(model string, tokens int, sample *pb.GpuSample, reserve float64) error {
+	if sample == nil || sample.VramTotalMb <= 0 {
+		return nil
+	}
+	growth, ok := g.ExpectedGrowthMB(model, tokens)
+	if !ok {
+		return nil
+	}
+
+	free := sample.VramTotalMb - sample.VramUsedMb
+	reserved := sample.VramTotalMb * reserve
+	if free >= growth+g.headroomMB+reserved {
+		return nil
+	}
+	if reserved > 0 {
+		return errcode.Retryable(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED,
+			fmt.Sprintf("insufficient VRAM for batch request for model %s: %.0f MB free, request needs about %.0f MB (+%.0f MB headroom, %.0f MB reserved for interactive requests)",
+				model, free, growth, g.headroomMB, reserved))
+	}
+	return errcode.Retryable(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED,
+		fmt.Sprintf("insufficient VRAM for model %s: %.0f MB free, request needs about %.0f MB (+%.0f MB headroom)",
+			model, free, growth, g.headroomMB))
+}
+
+// SetMemoryGuard enables checking free VRAM before dispatching requests
+func (s *Service) SetMemoryGuard(guard *MemoryGuard) {
+	s.guard = guard
+}
+
+// checkMemory runs the memory guard, if any, against the current GPU state.
+// Batch requests also leave the interactive reserve free.
+func (s *Service) checkMemory(model string, tokens int, batch bool) error {
+	if s.guard == nil || s.sampler == nil {
+		return nil
+	}
+	var reserve float64
+	if batch {
+		reserve = s.interactiveReserve()
+	}
+	if err := s.guard.CheckReserving(model, tokens, s.sampler.Sample(), reserve); err != nil {
+		log.Printf("Rejecting request for model %s: %v", model, err)
+		return err
+	}
+	return nil
+}
+
+// chatTokens estimates the tokens a chat request will hold in the KV cache:
+// its prompt plus the completion
+func chatTokens(req *pb.ChatCompletionRequest) int {
+	chars := 0
+	for _, msg := range req.Messages {
+		chars += len(msg.Content)
+	}
+
+	completion := int(req.MaxTokens)
+	if completion <= 0 {
+		completion = DefaultGuardMaxTokens
+	}
+	return chars/charsPerToken + completion
+}
+
+// embeddingTokens estimates the tokens an embedding request will process
+func embeddingTokens(req *pb.EmbeddingRequest) int {
+	chars := 0
+	for _, input := range req.Input {
+		chars += len(input)
+	}
+	return chars / charsPerToken
+}
@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+)
+
+func TestParseKVCacheSizes(t *testing.T) {
+	sizes, err := ParseKVCacheSizes(" llama3:70b=320, mistralai/Mistral-7B-v0.1=128 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"llama3:70b": 320, "mistralai/Mistral-7B-v0.1": 128}, sizes)
+
+	sizes, err = ParseKVCacheSizes("llama3:70b=1.5 GB")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"llama3:70b": 1536}, sizes)
+
+	for _, invalid := range []string{"llama3", "=128", "llama3=lots", "llama3=-1"} {
+		_, err := ParseKVCacheSizes(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryGuard_Check(t *testing.T) {
+	guard := NewMemoryGuard(map[string]float64{"llama3:70b": 320}, 100, 512)
+
+	tests := []struct {
+		name      string
+		model     string
+		tokens    int
+		sample    *pb.GpuSample
+		wantError bool
+	}{
+		{"fits", "llama3:70b", 4000, &pb.GpuSample{VramTotalMb: 81920, VramUsedMb: 70000}, false},
+		{"growth exceeds free memory", "llama3:70b", 32000, &pb.GpuSample{VramTotalMb: 81920, VramUsedMb: 75000}, true},
+		{"headroom counts", "llama3:70b", 1000, &pb.GpuSample{VramTotalMb: 81920, VramUsedMb: 81200}, true},
+		{"default size", "phi3", 10000, &pb.GpuSample{VramTotalMb: 8192, VramUsedMb: 7000}, true},
+		{"no VRAM readings", "llama3:70b", 32000, &pb.GpuSample{UtilizationPercent: 50}, false},
+		{"no sample", "llama3:70b", 32000, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := guard.Check(tt.model, tt.tokens, tt.sample)
+			if !tt.wantError {
+				assert.NoError(t, err)
+				return
+			}
+
+			st, ok := status.FromError(err)
+			require.True(t, ok)
+			assert.Equal(t, codes.ResourceExhausted, st.Code())
+			require.Len(t, st.Details(), 1)
+			info := st.Details()[0].(*pb.ErrorInfo)
+			assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, info.Code)
+			assert.True(t, info.Retryable)
+		})
+	}
+
+	unguarded := NewMemoryGuard(nil, 0, 512)
+	assert.NoError(t, unguarded.Check("phi3", 100000, &pb.GpuSample{VramTotalMb: 8192, VramUsedMb: 8000}))
+}
+
+func TestService_checkMemory(t *testing.T) {
+	service := &Service{
+		sampler: telemetry.NewSampler(staticGPU{Type: "NVIDIA RTX 4090", VRAMTotal: "24.0 GB", VRAMUsed: "23.5 GB"}),
+	}
+	req := &pb.ChatCompletionRequest{
+		Model:     "llama3",
+		Messages:  []*pb.ChatMessage{{Role: "user", Content: "Hello"}},
+		MaxTokens: 2000,
+	}
+
+	assert.NoError(t, service.checkMemory(req.Model, chatTokens(req), false), "no guard configured")
+
+	service.SetMemoryGuard(NewMemoryGuard(map[string]float64{"llama3": 256}, 0, 0))
+	assert.Error(t, service.checkMemory(req.Model, chatTokens(req), false))
+
+	req.MaxTokens = 100
+	assert.NoError(t, service.checkMemory(req.Model, chatTokens(req), false))
+}
+
+func TestRequestTokens(t *testing.T) {
+	chat := &pb.ChatCompletionRequest{Messages: []*pb.ChatMessage{{Content: "12345678"}, {Content: "1234"}}}
+	assert.Equal(t, 3+DefaultGuardMaxTokens, chatTokens(chat))
+	chat.MaxTokens = 10
+	assert.Equal(t, 13, chatTokens(chat))
+
+	assert.Equal(t, 4, embeddingTokens(&pb.EmbeddingRequest{Input: []string{"12345678", "12345678"}}))
+}
@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// ImageExecutor is implemented by executors running their engine in a
+// container image, which can be replaced, e.g. to pin the engine by digest
+type ImageExecutor interface {
+	Image() string // Empty if the engine doesn't run in a container
+	SetImage(image string)
+}
+
+// SetImage sets the container image an engine's models start in, e.g.
+// "ollama/ollama@sha256:..." to pin the engine to one build. Image pins from
+// the orchestrator override it while they match its repository.
+func (s *Service) SetImage(engine, image string) error {
+	executor, ok := s.executors[engine].(ImageExecutor)
+	if !ok || executor.Image() == "" {
+		return fmt.Errorf("engine %s doesn't run in a container", engine)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configuredImagesLocked()[engine] = image
+	executor.SetImage(image)
+	return nil
+}
+
+// Images returns the container images the service's engines start models
+// in, sorted
+func (s *Service) Images() []string {
+	var images []string
+	for _, engine := range s.imageEngines() {
+		images = append(images, s.executors[engine].(ImageExecutor).Image())
+	}
+	sort.Strings(images)
+	return images
+}
+
+// ApplyImagePins starts models of engines whose image repository is pinned
+// from the pinned digest, and those of engines no longer pinned from their
+// configured image again. Loaded models keep their image until they restart.
+// Returns the images engines switched to, which callers should pull ahead of
+// use.
+func (s *Service) ApplyImagePins(pins []*pb.ImagePin) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configured := s.configuredImagesLocked()
+	var switched []string
+	for _, engine := range s.imageEngines() {
+		executor := s.executors[engine].(ImageExecutor)
+		image := configured[engine]
+		for _, pin := range pins {
+			if pin.Image == containers.Repository(image) && pin.Digest != "" {
+				image = containers.PinImage(image, pin.Digest)
+				break
+			}
+		}
+
+		if current := executor.Image(); image != current {
+			log.Printf("Switching %s image from %s to %s", engine, current, image)
+			executor.SetImage(image)
+			switched = append(switched, image)
+		}
+	}
+	return switched
+}
+
+// PullImages pulls container images, so models start without waiting for
+// the download. It tries every image and returns the first error.
+func (s *Service) PullImages(ctx context.Context, images []string) error {
+	var firstErr error
+	for _, image := range images {
+		if err := s.containerManager.PullImage(ctx, image); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// imageEngines returns the engines running in a container, sorted
+func (s *Service) imageEngines() []string {
+	var engines []string
+	for engine, executor := range s.executors {
+		if e, ok := executor.(ImageExecutor); ok && e.Image() != "" {
+			engines = append(engines, engine)
+		}
+	}
+	sort.Strings(engines)
+	return engines
+}
+
+// configuredImagesLocked returns the images engines were configured with,
+// before image pins, recording the current image of engines not seen yet
+func (s *Service) configuredImagesLocked() map[string]string {
+	if s.images == nil {
+		s.images = make(map[string]string)
+	}
+	for _, engine := range s.imageEngines() {
+		if _, ok := s.images[engine]; !ok {
+			s.images[engine] = s.executors[engine].(ImageExecutor).Image()
+		}
+	}
+	return s.images
+}
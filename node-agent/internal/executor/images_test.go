@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestService_ImagePins(t *testing.T) {
+	manager := NewMockContainerManager()
+	service := newTestService(manager)
+	service.executors["ollama"] = NewOllamaExecutor(manager)
+	require.NoError(t, service.SetImage("ollama", "ollama/ollama:0.3.12"))
+
+	assert.Equal(t, []string{"ollama/ollama:0.3.12", containers.DefaultVLLMImage}, service.Images())
+
+	// Pinned repositories switch to the digest, once
+	pins := []*pb.ImagePin{
+		{Image: "vllm/vllm-openai", Digest: "sha256:aaa"},
+		{Image: "dustynv/ollama", Digest: "sha256:bbb"},
+	}
+	assert.Equal(t, []string{"vllm/vllm-openai@sha256:aaa"}, service.ApplyImagePins(pins))
+	assert.Empty(t, service.ApplyImagePins(pins))
+	assert.Equal(t, []string{"ollama/ollama:0.3.12", "vllm/vllm-openai@sha256:aaa"}, service.Images())
+
+	// New models start from the pinned image
+	_, err := service.StartDistributed(context.Background(), &pb.StartDistributedRequest{
+		DeploymentId: "dep-1",
+		Model:        "meta-llama/Llama-3-70B",
+		Role:         pb.DistributedRole_DISTRIBUTED_ROLE_HEAD,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "vllm/vllm-openai@sha256:aaa", manager.running["orchion-vllm-meta-llama-Llama-3-70B"].Image)
+
+	// Rolling out a new digest, then unpinning
+	pins[0].Digest = "sha256:ccc"
+	assert.Equal(t, []string{"vllm/vllm-openai@sha256:ccc"}, service.ApplyImagePins(pins))
+	assert.Equal(t, []string{containers.DefaultVLLMImage}, service.ApplyImagePins(nil))
+
+	require.NoError(t, service.PullImages(context.Background(), service.Images()))
+	assert.Equal(t, []string{"ollama/ollama:0.3.12", containers.DefaultVLLMImage}, manager.pulled)
+
+	assert.Error(t, service.SetImage("llamacpp", "ghcr.io/ggerganov/llama.cpp:server"))
+}
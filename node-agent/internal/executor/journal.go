@@ -0,0 +1,186 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// JobIDMetadata is the gRPC metadata key with which the orchestrator names
+// the job a NodeAgent call runs
+const JobIDMetadata = "orchion-job-id"
+
+// maxJournalBytes is the size past which the journal is emptied once no
+// request is in flight
+const maxJournalBytes = 1 << 20
+
+// Journal records the jobs the agent is running in a local file, so that
+// after a crash the agent can tell the orchestrator exactly which jobs were
+// lost instead of leaving it to guess from a dropped stream. Each job adds a
+// line when it starts and another when it ends; jobs without an end line were
+// interrupted.
+type Journal struct {
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	inFlight int
+}
+
+// journalEntry is a line of the journal
+type journalEntry struct {
+	JobID     string `json:"job_id"`
+	NodeID    string `json:"node_id,omitempty"`
+	Model     string `json:"model,omitempty"`
+	StartedMs int64  `json:"started_ms,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+}
+
+// OpenJournal opens the journal at path, creating it if needed, and returns
+// the jobs a previous run left unfinished, oldest first. The journal starts
+// out empty.
+func OpenJournal(path string) (*Journal, []*pb.InterruptedRequest, error) {
+	interrupted, err := readJournal(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &Journal{file: file}, interrupted, nil
+}
+
+// readJournal returns the jobs started in the journal at path that never
+// ended. A missing journal has none.
+func readJournal(path string) ([]*pb.InterruptedRequest, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	defer file.Close()
+
+	running := make(map[string]*pb.InterruptedRequest)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry journalEntry
+		// The last line may be cut short by the crash
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.JobID == "" {
+			continue
+		}
+		if entry.Done {
+			delete(running, entry.JobID)
+			continue
+		}
+		running[entry.JobID] = &pb.InterruptedRequest{
+			JobId:         entry.JobID,
+			NodeId:        entry.NodeID,
+			Model:         entry.Model,
+			StartedUnixMs: entry.StartedMs,
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	interrupted := make([]*pb.InterruptedRequest, 0, len(running))
+	for _, req := range running {
+		interrupted = append(interrupted, req)
+	}
+	sort.Slice(interrupted, func(i, j int) bool {
+		if interrupted[i].StartedUnixMs != interrupted[j].StartedUnixMs {
+			return interrupted[i].StartedUnixMs < interrupted[j].StartedUnixMs
+		}
+		return interrupted[i].JobId < interrupted[j].JobId
+	})
+	return interrupted, nil
+}
+
+// begin records a job starting and returns the function recording its end
+func (j *Journal) begin(jobID, nodeID, model string) func() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.writeLocked(journalEntry{JobID: jobID, NodeID: nodeID, Model: model, StartedMs: time.Now().UnixMilli()})
+	j.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { j.end(jobID) })
+	}
+}
+
+// end records a job ending, emptying the journal once it has grown large and
+// no job is in flight
+func (j *Journal) end(jobID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.writeLocked(journalEntry{JobID: jobID, Done: true})
+	j.inFlight--
+	if j.inFlight == 0 && j.size > maxJournalBytes {
+		if err := j.file.Truncate(0); err != nil {
+			log.Printf("Failed to empty journal: %v", err)
+			return
+		}
+		j.size = 0
+	}
+}
+
+// writeLocked appends an entry. Failing to journal doesn't fail the request.
+func (j *Journal) writeLocked(entry journalEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to encode journal entry: %v", err)
+		return
+	}
+	n, err := j.file.Write(append(line, '\n'))
+	j.size += int64(n)
+	if err != nil {
+		log.Printf("Failed to write journal: %v", err)
+	}
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// SetJournal records the jobs the service runs in journal, so they can be
+// reported to the orchestrator if the agent crashes while running them
+func (s *Service) SetJournal(journal *Journal) {
+	s.journal = journal
+}
+
+// journalJob records a request in the journal if it runs an orchestrator
+// job. The returned function records its end.
+func (s *Service) journalJob(ctx context.Context, model string) func() {
+	if s.journal == nil {
+		return func() {}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	jobID := md.Get(JobIDMetadata)
+	if len(jobID) == 0 || jobID[0] == "" {
+		return func() {}
+	}
+	var nodeID string
+	if target := md.Get(TargetNodeMetadata); len(target) > 0 {
+		nodeID = target[0]
+	}
+	return s.journal.begin(jobID[0], nodeID, model)
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestJournal_ReportsInterruptedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	journal, interrupted, err := OpenJournal(path)
+	require.NoError(t, err)
+	assert.Empty(t, interrupted)
+
+	journal.begin("job-1", "", "llama3")
+	endSecond := journal.begin("job-2", "worker-1", "mistral")
+	endThird := journal.begin("job-3", "", "llama3")
+	endSecond()
+	endSecond()
+	endThird()
+	journal.begin("job-4", "", "qwen2")
+	require.NoError(t, journal.Close())
+
+	// A crash can cut the last line short
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"job_id":"job-5","mod`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	journal, interrupted, err = OpenJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	require.Len(t, interrupted, 2)
+	assert.Equal(t, "job-1", interrupted[0].JobId)
+	assert.Equal(t, "llama3", interrupted[0].Model)
+	assert.NotZero(t, interrupted[0].StartedUnixMs)
+	assert.Equal(t, "job-4", interrupted[1].JobId)
+
+	// Reported jobs aren't reported again
+	require.NoError(t, journal.Close())
+	_, interrupted, err = OpenJournal(path)
+	require.NoError(t, err)
+	assert.Empty(t, interrupted)
+}
+
+func TestService_JournalJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	journal, _, err := OpenJournal(path)
+	require.NoError(t, err)
+
+	service := newTestService(NewMockContainerManager())
+
+	// Without a journal, requests aren't recorded
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(JobIDMetadata, "job-0"))
+	service.journalJob(ctx, "llama3")()
+
+	service.SetJournal(journal)
+
+	// Requests not sent for a job aren't recorded
+	service.journalJob(context.Background(), "llama3")
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		JobIDMetadata, "job-1",
+		TargetNodeMetadata, "worker-1",
+	))
+	service.journalJob(ctx, "llama3")
+	require.NoError(t, journal.Close())
+
+	_, interrupted, err := OpenJournal(path)
+	require.NoError(t, err)
+	require.Len(t, interrupted, 1)
+	assert.Equal(t, "job-1", interrupted[0].JobId)
+	assert.Equal(t, "worker-1", interrupted[0].NodeId)
+	assert.Equal(t, "llama3", interrupted[0].Model)
+}
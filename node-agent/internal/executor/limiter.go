@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/status"
+)
+
+// Limiter bounds the requests an agent serves at once. Once saturated,
+// waiting requests are admitted by priority, highest first and in arrival
+// order within a priority, so interactive requests overtake queued batch
+// work instead of waiting behind it. With an interactive reserve, batch
+// requests only get the slots left after the reserve.
+type Limiter struct {
+	mu            sync.Mutex
+	capacity      int
+	inFlight      int
+	batchInFlight int
+	reserve       float64 // Fraction of slots batch requests leave free
+	seq           uint64
+	waiting       []*limiterWaiter
+}
+
+type limiterWaiter struct {
+	priority int32
+	batch    bool
+	seq      uint64
+	ready    chan struct{}
+}
+
+// NewLimiter creates a limiter admitting at most capacity requests at once
+func NewLimiter(capacity int) *Limiter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Limiter{capacity: capacity}
+}
+
+// SetInteractiveReserve sets the fraction of slots batch requests leave free
+// for interactive ones. Batch requests keep at least one slot, so they never
+// starve. Lowering the reserve admits waiting batch requests right away.
+func (l *Limiter) SetInteractiveReserve(reserve float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reserve = reserve
+	l.admitLocked()
+}
+
+// batchCapacity returns the slots batch requests may hold at once
+func (l *Limiter) batchCapacity() int {
+	if l.reserve <= 0 {
+		return l.capacity
+	}
+	n := int(float64(l.capacity) * (1 - l.reserve))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// acquire blocks until a request of the given priority may proceed. The
+// returned function releases it once the request is done. Returns the
+// context error if the context ends while waiting.
+func (l *Limiter) acquire(ctx context.Context, priority int32, batch bool) (func(), error) {
+	l.mu.Lock()
+	w := &limiterWaiter{priority: priority, batch: batch, seq: l.seq, ready: make(chan struct{})}
+	l.seq++
+	l.waiting = append(l.waiting, w)
+	l.admitLocked()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.releaseFunc(batch), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		removed := l.removeLocked(w)
+		l.mu.Unlock()
+		if !removed {
+			// Admitted concurrently with cancellation; hand the slot back
+			l.release(batch)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting returns the number of requests waiting for a slot
+func (l *Limiter) Waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiting)
+}
+
+// removeLocked drops a waiter that gave up. Returns false if it was already
+// admitted.
+func (l *Limiter) removeLocked(w *limiterWaiter) bool {
+	for i, waiting := range l.waiting {
+		if waiting == w {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// releaseFunc returns an idempotent function releasing one slot
+func (l *Limiter) releaseFunc(batch bool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.release(batch) })
+	}
+}
+
+// release frees a slot and admits the highest priority waiting requests
+func (l *Limiter) release(batch bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if batch {
+		l.batchInFlight--
+	}
+	l.admitLocked()
+}
+
+// admitLocked admits waiting requests by priority while slots are free.
+// Batch requests are skipped once they hold their share of the slots.
+func (l *Limiter) admitLocked() {
+	for l.inFlight < l.capacity {
+		batchFull := l.batchInFlight >= l.batchCapacity()
+		next := -1
+		for i, w := range l.waiting {
+			if w.batch && batchFull {
+				continue
+			}
+			if next < 0 {
+				next = i
+				continue
+			}
+			best := l.waiting[next]
+			if w.priority > best.priority || (w.priority == best.priority && w.seq < best.seq) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return
+		}
+
+		w := l.waiting[next]
+		l.waiting = append(l.waiting[:next], l.waiting[next+1:]...)
+		l.inFlight++
+		if w.batch {
+			l.batchInFlight++
+		}
+		close(w.ready)
+	}
+}
+
+// SetLimiter bounds the requests served at once, admitting waiting requests
+// by priority
+func (s *Service) SetLimiter(limiter *Limiter) {
+	limiter.SetInteractiveReserve(s.interactiveReserve())
+	s.limiter = limiter
+}
+
+// acquireLimiter waits for a slot in the limiter, if any. The returned
+// function releases it once the request is done.
+func (s *Service) acquireLimiter(ctx context.Context, priority int32, batch bool) (func(), error) {
+	if s.limiter == nil {
+		return func() {}, nil
+	}
+	release, err := s.limiter.acquire(ctx, priority, batch)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return release, nil
+}
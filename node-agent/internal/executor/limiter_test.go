@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiter_AdmitsByPriority(t *testing.T) {
+	l := NewLimiter(1)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, 0, false)
+	require.NoError(t, err)
+
+	// Queue requests one at a time so their arrival order is known
+	admitted := make(chan string, 4)
+	queue := func(name string, priority int32) {
+		waiting := l.Waiting()
+		go func() {
+			release, err := l.acquire(ctx, priority, false)
+			if !assert.NoError(t, err) {
+				return
+			}
+			admitted <- name
+			release()
+		}()
+		require.Eventually(t, func() bool { return l.Waiting() == waiting+1 }, time.Second, time.Millisecond)
+	}
+	queue("batch", -1)
+	queue("normal-1", 0)
+	queue("interactive", 1)
+	queue("normal-2", 0)
+
+	release()
+	release() // Releasing twice frees one slot
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-admitted)
+	}
+	assert.Equal(t, []string{"interactive", "normal-1", "normal-2", "batch"}, order)
+}
+
+func TestLimiter_CancelWhileWaiting(t *testing.T) {
+	l := NewLimiter(1)
+	release, err := l.acquire(context.Background(), 0, false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, 1, false)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Zero(t, l.Waiting())
+
+	// The slot is free again once released
+	release()
+	release, err = l.acquire(context.Background(), 0, false)
+	require.NoError(t, err)
+	release()
+}
+
+func TestService_AcquireLimiter(t *testing.T) {
+	s := &Service{}
+	release, err := s.acquireLimiter(context.Background(), 0, false)
+	require.NoError(t, err, "no limiter admits everything")
+	release()
+
+	s.SetLimiter(NewLimiter(1))
+	release, err = s.acquireLimiter(context.Background(), 0, false)
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.acquireLimiter(ctx, 0, false)
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
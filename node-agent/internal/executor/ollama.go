@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Orchion/Orchion/node-agent/internal/containers"
@@ -17,9 +18,14 @@ import (
 // OllamaExecutor manages Ollama containers and handles inference requests
 type OllamaExecutor struct {
 	containerManager containers.Manager
-	basePort         int            // Starting port for Ollama containers
-	runningPorts     map[string]int // model -> port mapping
-	dockerAvailable  bool           // Whether Docker is available
+	basePort         int         // Starting port for Ollama containers
+	runningPorts     *modelPorts // model -> port mapping
+	dockerAvailable  bool        // Whether Docker is available
+	config           *containers.OllamaConfig
+	imageMu          sync.RWMutex // Guards config.Image, which image pins replace at runtime
+	transport        http.RoundTripper
+	startMu          sync.Mutex // Serializes starting the container all models share
+	readinessProbes             // Overrides of how Ollama's readiness is probed
 }
 
 // NewOllamaExecutor creates a new Ollama executor
@@ -27,8 +33,9 @@ func NewOllamaExecutor(manager containers.Manager) *OllamaExecutor {
 	executor := &OllamaExecutor{
 		containerManager: manager,
 		basePort:         11434, // Default Ollama port
-		runningPorts:     make(map[string]int),
+		runningPorts:     newModelPorts(),
 		dockerAvailable:  true,
+		config:           containers.DefaultOllamaConfig(),
 	}
 
 	// Test if container runtime is available
@@ -45,46 +52,65 @@ func NewOllamaExecutor(manager containers.Manager) *OllamaExecutor {
 func (e *OllamaExecutor) StartModel(ctx context.Context, model string) error {
 	if e.dockerAvailable {
 		// Use container-based approach
-		config := containers.CreateOllamaContainerConfig(containers.DefaultOllamaConfig())
+		config := e.containerConfig()
 
-		// Ensure container is running
-		if err := e.containerManager.EnsureRunning(ctx, config); err != nil {
-			return fmt.Errorf("failed to start Ollama container: %w", err)
-		}
-
-		// Wait for Ollama to be ready
-		if err := e.waitForOllamaReady(ctx, config.Port); err != nil {
-			return fmt.Errorf("Ollama container failed to become ready: %w", err)
+		// Ensure container is running, once for models starting together
+		if err := e.ensureContainer(ctx, model, config); err != nil {
+			return err
 		}
 
 		// Pull the model
+		reportState(ctx, pb.ModelState_MODEL_STATE_DOWNLOADING)
 		if err := containers.PullOllamaModel(ctx, e.containerManager, config.Name, model); err != nil {
 			log.Printf("Warning: Failed to pull model %s: %v", model, err)
 			// Don't fail here - model might already be available
 		}
 
 		// Track the port
-		e.runningPorts[model] = config.Port
+		e.runningPorts.set(model, config.Port)
 
 		log.Printf("Ollama model %s ready on port %d (container)", model, config.Port)
 	} else {
 		// Assume Ollama is running externally
 		port := e.basePort
-		if err := e.waitForOllamaReady(ctx, port); err != nil {
+		if err := e.waitReady(ctx, model, port); err != nil {
 			return fmt.Errorf("external Ollama not available on port %d: %w", port, err)
 		}
 
-		e.runningPorts[model] = port
+		e.runningPorts.set(model, port)
 		log.Printf("Ollama model %s assumed ready on port %d (external)", model, port)
 	}
 
 	return nil
 }
 
+// ensureContainer starts the Ollama container if it isn't running and waits
+// until Ollama is ready for model
+func (e *OllamaExecutor) ensureContainer(ctx context.Context, model string, config *containers.ContainerConfig) error {
+	e.startMu.Lock()
+	defer e.startMu.Unlock()
+
+	if err := e.containerManager.EnsureRunning(ctx, config); err != nil {
+		return fmt.Errorf("failed to start Ollama container: %w", err)
+	}
+
+	// Wait for Ollama to be ready
+	if err := e.waitReady(ctx, model, config.Port); err != nil {
+		return fmt.Errorf("Ollama container failed to become ready: %w", err)
+	}
+	return nil
+}
+
+// waitReady waits for Ollama on port to be ready to accept requests
+func (e *OllamaExecutor) waitReady(ctx context.Context, model string, port int) error {
+	probe := e.probe(model, defaultOllamaProbe)
+	return waitForEngineReady(ctx, "Ollama", model, fmt.Sprintf("http://localhost:%d", port), probe)
+}
+
 // StopModel stops the Ollama container for the specified model
 func (e *OllamaExecutor) StopModel(ctx context.Context, model string) error {
 	if e.dockerAvailable {
-		config := containers.CreateOllamaContainerConfig(containers.DefaultOllamaConfig())
+		config := e.containerConfig()
 
 		if err := e.containerManager.StopContainer(ctx, config.Name); err != nil {
 			return fmt.Errorf("failed to stop Ollama container: %w", err)
@@ -95,27 +121,67 @@ func (e *OllamaExecutor) StopModel(ctx context.Context, model string) error {
 		log.Printf("Ollama assumed to be running externally, not stopping model %s", model)
 	}
 
-	delete(e.runningPorts, model)
+	e.runningPorts.remove(model)
 	return nil
 }
 
 // IsModelRunning checks if the Ollama container is running for the specified model
 func (e *OllamaExecutor) IsModelRunning(ctx context.Context, model string) (bool, error) {
-	config := containers.CreateOllamaContainerConfig(containers.DefaultOllamaConfig())
+	config := e.containerConfig()
 	return e.containerManager.IsRunning(ctx, config.Name)
 }
 
+// containerConfig returns the configuration of the Ollama container
+func (e *OllamaExecutor) containerConfig() *containers.ContainerConfig {
+	e.imageMu.RLock()
+	defer e.imageMu.RUnlock()
+	return containers.CreateOllamaContainerConfig(e.config)
+}
+
+// Image returns the image the Ollama container runs, or an empty string if
+// Ollama runs outside a container
+func (e *OllamaExecutor) Image() string {
+	if !e.dockerAvailable {
+		return ""
+	}
+	return e.containerConfig().Image
+}
+
+// SetImage sets the image the Ollama container is started from. A running
+// container keeps its image until it restarts.
+func (e *OllamaExecutor) SetImage(image string) {
+	e.imageMu.Lock()
+	defer e.imageMu.Unlock()
+
+	config := *e.config
+	config.Image = image
+	e.config = &config
+}
+
+// EngineInfo identifies the Ollama build serving a model
+func (e *OllamaExecutor) EngineInfo(ctx context.Context, model string) *pb.ModelEngine {
+	info := &pb.ModelEngine{Model: model, Engine: "ollama"}
+	if image := e.Image(); image != "" {
+		info.Image = image
+		info.ImageDigest = imageDigest(ctx, e.containerManager, image)
+	}
+	if port, ok := e.runningPorts.get(model); ok {
+		info.Version = fetchEngineVersion(ctx, e.transport, fmt.Sprintf("http://localhost:%d/api/version", port))
+	}
+	return info
+}
+
 // ChatCompletion executes a chat completion request using Ollama
-func (e *OllamaExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan *pb.ChatCompletionResponse, error) {
-	port, exists := e.runningPorts[model]
+func (e *OllamaExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error) {
+	port, exists := e.runningPorts.get(model)
 	if !exists {
 		return nil, fmt.Errorf("model %s is not running", model)
 	}
 
-	responseChan := make(chan *pb.ChatCompletionResponse, 10)
+	events := make(chan Event, 10)
 
 	go func() {
-		defer close(responseChan)
+		defer close(events)
 
 		// Convert messages to Ollama format
 		messages := make([]map[string]string, len(req.Messages))
@@ -132,16 +198,14 @@ func (e *OllamaExecutor) ChatCompletion(ctx context.Context, model string, req *
 			"messages": messages,
 			"stream":   req.Stream,
 		}
-		if req.Temperature > 0 {
-			ollamaReq["temperature"] = req.Temperature
-		}
-		if req.MaxTokens > 0 {
-			ollamaReq["num_predict"] = req.MaxTokens
+		if modelOptions := ollamaModelOptions(req); len(modelOptions) > 0 {
+			ollamaReq["options"] = modelOptions
 		}
+		applyOllamaOptions(ollamaReq, req.EngineOptions)
 
 		reqBody, err := json.Marshal(ollamaReq)
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to marshal request")
+			fail(ctx, events, fmt.Errorf("failed to marshal request: %w", err))
 			return
 		}
 
@@ -149,39 +213,34 @@ func (e *OllamaExecutor) ChatCompletion(ctx context.Context, model string, req *
 		url := fmt.Sprintf("http://localhost:%d/api/chat", port)
 		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to create request")
+			fail(ctx, events, fmt.Errorf("failed to create request: %w", err))
 			return
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 
-		client := &http.Client{Timeout: 10 * time.Minute}
+		client := &http.Client{Timeout: 10 * time.Minute, Transport: e.transport}
 		resp, err := client.Do(httpReq)
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to call Ollama")
+			fail(ctx, events, fmt.Errorf("failed to call Ollama: %w", err))
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			responseChan <- e.createErrorResponse(model, fmt.Sprintf("Ollama returned status %d", resp.StatusCode))
+			fail(ctx, events, fmt.Errorf("Ollama returned status %d", resp.StatusCode))
 			return
 		}
 
-		if req.Stream {
-			// Stream responses
-			e.handleStreamingResponse(resp.Body, model, responseChan)
-		} else {
-			// Non-streaming response
-			e.handleNonStreamingResponse(resp.Body, model, responseChan)
-		}
+		// A non-streaming reply is a single, final message
+		ollamaEvents(ctx, resp.Body, events)
 	}()
 
-	return responseChan, nil
+	return events, nil
 }
 
 // Embeddings executes an embeddings request using Ollama
 func (e *OllamaExecutor) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
-	port, exists := e.runningPorts[model]
+	port, exists := e.runningPorts.get(model)
 	if !exists {
 		return nil, fmt.Errorf("model %s is not running", model)
 	}
@@ -206,7 +265,7 @@ func (e *OllamaExecutor) Embeddings(ctx context.Context, model string, req *pb.E
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 
-		client := &http.Client{Timeout: 5 * time.Minute}
+		client := &http.Client{Timeout: 5 * time.Minute, Transport: e.transport}
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			return nil, fmt.Errorf("failed to call Ollama: %w", err)
@@ -247,128 +306,95 @@ func (e *OllamaExecutor) Embeddings(ctx context.Context, model string, req *pb.E
 	}, nil
 }
 
-// waitForOllamaReady waits for Ollama to be ready to accept requests
-func (e *OllamaExecutor) waitForOllamaReady(ctx context.Context, port int) error {
-	url := fmt.Sprintf("http://localhost:%d/api/tags", port)
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	// Try for up to 2 minutes
-	for i := 0; i < 120; i++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return err
-		}
-
-		resp, err := client.Do(req)
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return nil
-			}
-		}
-
-		time.Sleep(1 * time.Second)
+// ollamaModelOptions returns the generation parameters of a request as
+// Ollama model options; unset parameters are left to the model's defaults
+func ollamaModelOptions(req *pb.ChatCompletionRequest) map[string]interface{} {
+	options := make(map[string]interface{})
+	if req.Temperature > 0 {
+		options["temperature"] = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		options["num_predict"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+	if req.TopP > 0 {
+		options["top_p"] = req.TopP
 	}
+	if req.TopK > 0 {
+		options["top_k"] = req.TopK
+	}
+	if req.RepetitionPenalty > 0 {
+		options["repeat_penalty"] = req.RepetitionPenalty
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	return options
+}
 
-	return fmt.Errorf("timeout waiting for Ollama to be ready")
+// ollamaMessage is a message, or a streamed piece of one, of an /api/chat reply
+type ollamaMessage struct {
+	Message struct {
+		Content   string `json:"content"`
+		ToolCalls []struct {
+			Function struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int32  `json:"prompt_eval_count"`
+	EvalCount       int32  `json:"eval_count"`
 }
 
-// handleStreamingResponse processes streaming Ollama responses
-func (e *OllamaExecutor) handleStreamingResponse(body io.Reader, model string, responseChan chan<- *pb.ChatCompletionResponse) {
+// ollamaEvents reports the messages of an /api/chat reply as events. Ollama
+// sends tool calls whole, without IDs, so they get IDs by position; the last
+// message carries the finish reason and token counts.
+func ollamaEvents(ctx context.Context, body io.Reader, events chan<- Event) {
 	decoder := json.NewDecoder(body)
+	toolCalls := 0
 	for {
-		var ollamaResp map[string]interface{}
-		if err := decoder.Decode(&ollamaResp); err != nil {
+		var msg ollamaMessage
+		if err := decoder.Decode(&msg); err != nil {
 			if err == io.EOF {
-				break
+				err = io.ErrUnexpectedEOF
 			}
-			log.Printf("Error decoding streaming response: %v", err)
-			continue
+			fail(ctx, events, fmt.Errorf("failed to decode Ollama response: %w", err))
+			return
 		}
 
-		// Extract content from Ollama response
-		message, ok := ollamaResp["message"].(map[string]interface{})
-		if !ok {
-			continue
+		if msg.Message.Content != "" && !emit(ctx, events, Event{Type: EventToken, Content: msg.Message.Content}) {
+			return
 		}
-		content, _ := message["content"].(string)
-		done, _ := ollamaResp["done"].(bool)
-
-		responseChan <- &pb.ChatCompletionResponse{
-			Id:     e.generateID(),
-			Model:  model,
-			Object: "chat.completion.chunk",
-			Choices: []*pb.ChatChoice{
-				{
-					Index: 0,
-					Message: &pb.ChatMessage{
-						Role:    "assistant",
-						Content: content,
-					},
-					FinishReason: func() string {
-						if done {
-							return "stop"
-						}
-						return ""
-					}(),
-				},
-			},
-			Created: time.Now().Unix(),
+		for _, call := range msg.Message.ToolCalls {
+			toolCall := &pb.ToolCall{
+				Index:     int32(toolCalls),
+				Id:        fmt.Sprintf("call_%d", toolCalls),
+				Name:      call.Function.Name,
+				Arguments: string(call.Function.Arguments),
+			}
+			toolCalls++
+			if !emit(ctx, events, Event{Type: EventToolCall, ToolCall: toolCall}) {
+				return
+			}
 		}
-
-		if done {
-			break
+		if !msg.Done {
+			continue
 		}
-	}
-}
 
-// handleNonStreamingResponse processes non-streaming Ollama responses
-func (e *OllamaExecutor) handleNonStreamingResponse(body io.Reader, model string, responseChan chan<- *pb.ChatCompletionResponse) {
-	var ollamaResp map[string]interface{}
-	if err := json.NewDecoder(body).Decode(&ollamaResp); err != nil {
-		responseChan <- e.createErrorResponse(model, "failed to decode response")
+		usage := Usage{PromptTokens: msg.PromptEvalCount, CompletionTokens: msg.EvalCount}
+		if !emit(ctx, events, Event{Type: EventUsage, Usage: usage}) {
+			return
+		}
+		finishReason := msg.DoneReason
+		if toolCalls > 0 {
+			finishReason = "tool_calls"
+		}
+		emit(ctx, events, Event{Type: EventDone, FinishReason: finishReason})
 		return
 	}
-
-	message, _ := ollamaResp["message"].(map[string]interface{})
-	content, _ := message["content"].(string)
-
-	responseChan <- &pb.ChatCompletionResponse{
-		Id:     e.generateID(),
-		Model:  model,
-		Object: "chat.completion",
-		Choices: []*pb.ChatChoice{
-			{
-				Index: 0,
-				Message: &pb.ChatMessage{
-					Role:    "assistant",
-					Content: content,
-				},
-				FinishReason: "stop",
-			},
-		},
-		Created: time.Now().Unix(),
-	}
-}
-
-// createErrorResponse creates an error response
-func (e *OllamaExecutor) createErrorResponse(model, message string) *pb.ChatCompletionResponse {
-	return &pb.ChatCompletionResponse{
-		Id:      e.generateID(),
-		Model:   model,
-		Object:  "error",
-		Choices: []*pb.ChatChoice{{FinishReason: "error"}},
-		Created: time.Now().Unix(),
-	}
-}
-
-// generateID generates a unique ID for responses
-func (e *OllamaExecutor) generateID() string {
-	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
 }
@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// ollamaRequestOptions are engine options Ollama takes in the request body
+// rather than among its model options
+var ollamaRequestOptions = map[string]bool{
+	"keep_alive": true,
+	"format":     true,
+	"think":      true,
+}
+
+// ollamaOnlyOptions are Ollama options vLLM has no per-request equivalent
+// for; e.g. the context length and GPU offload are set when vLLM starts
+var ollamaOnlyOptions = map[string]bool{
+	"keep_alive":    true,
+	"format":        true,
+	"think":         true,
+	"num_ctx":       true,
+	"num_gpu":       true,
+	"num_thread":    true,
+	"num_batch":     true,
+	"num_keep":      true,
+	"num_predict":   true,
+	"mirostat":      true,
+	"mirostat_eta":  true,
+	"mirostat_tau":  true,
+	"repeat_last_n": true,
+	"use_mmap":      true,
+	"use_mlock":     true,
+	"numa":          true,
+}
+
+// engineOptionValue decodes an engine option's JSON value. Values that
+// aren't JSON, e.g. 10m, are taken as strings.
+func engineOptionValue(value string) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// applyOllamaOptions adds engine options to an /api/chat request body, in the
+// body or its model options as Ollama expects them. Options the request sets
+// itself, such as the temperature, take precedence.
+func applyOllamaOptions(body map[string]interface{}, options map[string]string) {
+	if len(options) == 0 {
+		return
+	}
+	modelOptions, _ := body["options"].(map[string]interface{})
+	if modelOptions == nil {
+		modelOptions = make(map[string]interface{})
+	}
+	for key, value := range options {
+		target := modelOptions
+		if ollamaRequestOptions[key] {
+			target = body
+		}
+		if _, set := target[key]; !set {
+			target[key] = engineOptionValue(value)
+		}
+	}
+	if len(modelOptions) > 0 {
+		body["options"] = modelOptions
+	}
+}
+
+// applyVLLMOptions adds engine options to an OpenAI-compatible vLLM request
+// body as extra sampling parameters, e.g. top_k or repetition_penalty. Ollama
+// options vLLM has no equivalent for are dropped, and options the request
+// sets itself take precedence.
+func applyVLLMOptions(body map[string]interface{}, options map[string]string) {
+	for key, value := range options {
+		if ollamaOnlyOptions[key] {
+			log.Printf("Ignoring engine option %s, which vLLM doesn't support", key)
+			continue
+		}
+		if _, set := body[key]; !set {
+			body[key] = engineOptionValue(value)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestEngineOptionValue(t *testing.T) {
+	assert.Equal(t, 8192.0, engineOptionValue("8192"))
+	assert.Equal(t, true, engineOptionValue("true"))
+	assert.Equal(t, "10m", engineOptionValue(`"10m"`))
+	assert.Equal(t, []interface{}{"User:"}, engineOptionValue(`["User:"]`))
+	// Values that aren't JSON are strings
+	assert.Equal(t, "10m", engineOptionValue("10m"))
+}
+
+func TestApplyOllamaOptions(t *testing.T) {
+	body := map[string]interface{}{
+		"model":   "llama3",
+		"options": map[string]interface{}{"temperature": float32(0.2)},
+	}
+	applyOllamaOptions(body, map[string]string{
+		"num_ctx":     "8192",
+		"num_gpu":     "99",
+		"mirostat":    "2",
+		"keep_alive":  `"30m"`,
+		"temperature": "0.9",
+	})
+
+	assert.Equal(t, "30m", body["keep_alive"])
+	assert.Equal(t, map[string]interface{}{
+		"temperature": float32(0.2), // The request's own setting wins
+		"num_ctx":     8192.0,
+		"num_gpu":     99.0,
+		"mirostat":    2.0,
+	}, body["options"])
+
+	// Without model options none are added
+	body = map[string]interface{}{"model": "llama3"}
+	applyOllamaOptions(body, map[string]string{"keep_alive": "-1"})
+	assert.Equal(t, -1.0, body["keep_alive"])
+	assert.NotContains(t, body, "options")
+}
+
+func TestApplyVLLMOptions(t *testing.T) {
+	body := map[string]interface{}{"model": "mistralai/Mistral-7B", "max_tokens": int32(100)}
+	applyVLLMOptions(body, map[string]string{
+		"top_k":              "40",
+		"repetition_penalty": "1.1",
+		"num_ctx":            "8192",
+		"keep_alive":         `"30m"`,
+		"max_tokens":         "5",
+		"model":              `"other"`,
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"model":              "mistralai/Mistral-7B",
+		"max_tokens":         int32(100),
+		"top_k":              40.0,
+		"repetition_penalty": 1.1,
+	}, body)
+}
+
+func TestSamplingParameters(t *testing.T) {
+	seed := int64(0)
+	req := &pb.ChatCompletionRequest{
+		Temperature:       0.7,
+		MaxTokens:         100,
+		Stop:              []string{"User:"},
+		TopP:              0.9,
+		TopK:              40,
+		RepetitionPenalty: 1.1,
+		Seed:              &seed,
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"temperature":    float32(0.7),
+		"num_predict":    int32(100),
+		"stop":           []string{"User:"},
+		"top_p":          float32(0.9),
+		"top_k":          int32(40),
+		"repeat_penalty": float32(1.1),
+		"seed":           int64(0),
+	}, ollamaModelOptions(req))
+
+	body := map[string]interface{}{}
+	setVLLMSampling(body, req)
+	assert.Equal(t, map[string]interface{}{
+		"temperature":        float32(0.7),
+		"max_tokens":         int32(100),
+		"stop":               []string{"User:"},
+		"top_p":              float32(0.9),
+		"top_k":              int32(40),
+		"repetition_penalty": float32(1.1),
+		"seed":               int64(0),
+	}, body)
+
+	// Unset parameters are left to the engine
+	assert.Empty(t, ollamaModelOptions(&pb.ChatCompletionRequest{}))
+	body = map[string]interface{}{}
+	setVLLMSampling(body, &pb.ChatCompletionRequest{})
+	assert.Empty(t, body)
+}
@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/node-agent/internal/containers"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// PiperExecutor manages the Piper container, which serves every Piper voice
+// of the node, and handles text-to-speech requests. Models are named
+// "piper/<voice>", e.g. "piper/en_US-lessac-medium".
+type PiperExecutor struct {
+	containerManager containers.Manager
+	config           *containers.PiperConfig
+	runningPorts     *modelPorts // model -> port mapping
+	transport        http.RoundTripper
+	startMu          sync.Mutex   // Serializes starting the container for voices loading together
+	imageMu          sync.RWMutex // Guards config, whose image pins replace at runtime
+	readinessProbes               // Overrides of how Piper's readiness is probed
+}
+
+// NewPiperExecutor creates a new Piper executor
+func NewPiperExecutor(manager containers.Manager) *PiperExecutor {
+	return &PiperExecutor{
+		containerManager: manager,
+		config:           containers.DefaultPiperConfig(),
+		runningPorts:     newModelPorts(),
+	}
+}
+
+// StartModel starts the Piper container if needed and downloads the model's voice
+func (e *PiperExecutor) StartModel(ctx context.Context, model string) error {
+	_, voice, _ := speechEngine(model)
+	config := e.containerConfig(voice)
+
+	e.startMu.Lock()
+	defer e.startMu.Unlock()
+
+	running, err := e.containerManager.IsRunning(ctx, config.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check Piper container: %w", err)
+	}
+	if running {
+		// The container serves voices it didn't start with once downloaded
+		reportState(ctx, pb.ModelState_MODEL_STATE_DOWNLOADING)
+		if err := containers.DownloadPiperVoice(ctx, e.containerManager, config.Name, voice); err != nil {
+			return err
+		}
+	} else {
+		// The container downloads the voice it starts with
+		reportState(ctx, pb.ModelState_MODEL_STATE_DOWNLOADING)
+		if err := e.containerManager.EnsureRunning(ctx, config); err != nil {
+			return fmt.Errorf("failed to start Piper container: %w", err)
+		}
+	}
+
+	probe := e.probe(model, defaultPiperProbe)
+	if err := waitForEngineReady(ctx, "Piper", model, fmt.Sprintf("http://localhost:%d", config.Port), probe); err != nil {
+		return fmt.Errorf("Piper container failed to become ready: %w", err)
+	}
+
+	// Track the port
+	e.runningPorts.set(model, config.Port)
+
+	log.Printf("Piper voice %s ready on port %d", voice, config.Port)
+	return nil
+}
+
+// StopModel forgets the model's voice, stopping the Piper container once it
+// serves no other voice
+func (e *PiperExecutor) StopModel(ctx context.Context, model string) error {
+	e.runningPorts.remove(model)
+	if e.runningPorts.count() > 0 {
+		log.Printf("Piper container serves other voices, not stopping it for model %s", model)
+		return nil
+	}
+
+	config := e.containerConfig("")
+	if err := e.containerManager.StopContainer(ctx, config.Name); err != nil {
+		return fmt.Errorf("failed to stop Piper container: %w", err)
+	}
+
+	log.Printf("Stopped Piper container for model %s", model)
+	return nil
+}
+
+// IsModelRunning checks if the Piper container is running
+func (e *PiperExecutor) IsModelRunning(ctx context.Context, model string) (bool, error) {
+	config := e.containerConfig("")
+	return e.containerManager.IsRunning(ctx, config.Name)
+}
+
+// containerConfig returns the configuration of the Piper container starting
+// with the given voice
+func (e *PiperExecutor) containerConfig(voice string) *containers.ContainerConfig {
+	e.imageMu.RLock()
+	defer e.imageMu.RUnlock()
+
+	config := *e.config
+	config.Voice = voice
+	return containers.CreatePiperContainerConfig(&config)
+}
+
+// Image returns the image the Piper container runs
+func (e *PiperExecutor) Image() string {
+	return e.containerConfig("").Image
+}
+
+// SetImage sets the image the Piper container is started from. A running
+// container keeps its image until it restarts.
+func (e *PiperExecutor) SetImage(image string) {
+	e.imageMu.Lock()
+	defer e.imageMu.Unlock()
+
+	config := *e.config
+	config.Image = image
+	e.config = &config
+}
+
+// EngineInfo identifies the Piper build serving a model. Piper doesn't
+// report its version.
+func (e *PiperExecutor) EngineInfo(ctx context.Context, model string) *pb.ModelEngine {
+	image := e.Image()
+	return &pb.ModelEngine{
+		Model:       model,
+		Engine:      "piper",
+		Image:       image,
+		ImageDigest: imageDigest(ctx, e.containerManager, image),
+	}
+}
+
+// ChatCompletion fails, as Piper only synthesizes speech
+func (e *PiperExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error) {
+	return nil, errSpeechOnly
+}
+
+// Embeddings fails, as Piper only synthesizes speech
+func (e *PiperExecutor) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	return nil, errSpeechOnly
+}
+
+// Speech synthesizes speech using Piper
+func (e *PiperExecutor) Speech(ctx context.Context, model string, req *pb.SpeechRequest) ([]byte, error) {
+	port, exists := e.runningPorts.get(model)
+	if !exists {
+		return nil, fmt.Errorf("model %s is not running", model)
+	}
+	_, voice, _ := speechEngine(model)
+
+	piperReq := map[string]interface{}{
+		"text":  req.Input,
+		"voice": voice,
+	}
+	if speaker := speaker(req.Voice); speaker != "" {
+		// Multi-speaker voices name speakers, e.g. "p225"
+		piperReq["speaker"] = speaker
+	}
+
+	reqBody, err := json.Marshal(piperReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("http://localhost:%d/", port)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Minute, Transport: e.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Piper: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return readAudio(resp, "Piper")
+}
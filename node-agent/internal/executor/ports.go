@@ -0,0 +1,44 @@
+package executor
+
+import "sync"
+
+// modelPorts maps running models to the port of the engine serving them.
+// Models start concurrently and serve requests while others start, so the
+// map is locked.
+type modelPorts struct {
+	mu    sync.RWMutex
+	ports map[string]int
+}
+
+func newModelPorts() *modelPorts {
+	return &modelPorts{ports: make(map[string]int)}
+}
+
+// get returns the port serving a model, and false if the model isn't running
+func (p *modelPorts) get(model string) (int, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	port, ok := p.ports[model]
+	return port, ok
+}
+
+// set records the port serving a model
+func (p *modelPorts) set(model string, port int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ports[model] = port
+}
+
+// remove forgets a stopped model
+func (p *modelPorts) remove(model string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.ports, model)
+}
+
+// count returns the number of running models
+func (p *modelPorts) count() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.ports)
+}
@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readinessProbeTimeout bounds a single readiness probe
+const readinessProbeTimeout = 10 * time.Second
+
+// readinessLogInterval is how often the agent logs that it is still waiting
+// for an engine
+const readinessLogInterval = 30 * time.Second
+
+// ReadinessProbe is how the agent waits for an engine to accept requests
+// after starting its container. Large models can legitimately take ten
+// minutes or more to load, so the budget is configurable per engine and
+// model.
+type ReadinessProbe struct {
+	Path     string        // Polled until it answers 200 OK, e.g. /v1/models; a full URL is used as is
+	Interval time.Duration // Between probes
+	Budget   time.Duration // How long the engine may take to become ready
+}
+
+// Default readiness probes of the engines
+var (
+	defaultOllamaProbe = ReadinessProbe{Path: "/api/tags", Interval: time.Second, Budget: 2 * time.Minute}
+	defaultVLLMProbe   = ReadinessProbe{Path: "/v1/models", Interval: time.Second, Budget: 5 * time.Minute}
+	// The first Piper start installs Piper, so allow for a slow download
+	defaultPiperProbe = ReadinessProbe{Path: "/voices", Interval: time.Second, Budget: 10 * time.Minute}
+	defaultCoquiProbe = ReadinessProbe{Path: "/", Interval: time.Second, Budget: 10 * time.Minute}
+)
+
+// merge returns p with the non-zero fields of override replacing its own
+func (p ReadinessProbe) merge(override ReadinessProbe) ReadinessProbe {
+	if override.Path != "" {
+		p.Path = override.Path
+	}
+	if override.Interval > 0 {
+		p.Interval = override.Interval
+	}
+	if override.Budget > 0 {
+		p.Budget = override.Budget
+	}
+	return p
+}
+
+// url returns the URL probed on an engine served at baseURL
+func (p ReadinessProbe) url(baseURL string) string {
+	if strings.Contains(p.Path, "://") {
+		return p.Path
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(p.Path, "/")
+}
+
+// ProbeExecutor is implemented by executors waiting for their engine to
+// become ready after starting it
+type ProbeExecutor interface {
+	SetReadinessProbe(model string, probe ReadinessProbe)
+}
+
+// readinessProbes are the probe overrides of an executor. The zero value
+// uses the engine's defaults.
+type readinessProbes struct {
+	mu     sync.RWMutex
+	engine ReadinessProbe            // Overrides for every model
+	models map[string]ReadinessProbe // Overrides for one model, over the engine's
+}
+
+// SetReadinessProbe overrides the probe used when starting model, or every
+// model if model is empty. Zero fields keep their defaults.
+func (p *readinessProbes) SetReadinessProbe(model string, probe ReadinessProbe) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if model == "" {
+		p.engine = probe
+		return
+	}
+	if p.models == nil {
+		p.models = make(map[string]ReadinessProbe)
+	}
+	p.models[model] = probe
+}
+
+// probe returns the probe for starting model, given the engine's defaults
+func (p *readinessProbes) probe(model string, defaults ReadinessProbe) ReadinessProbe {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return defaults.merge(p.engine).merge(p.models[model])
+}
+
+// SetReadinessProbe overrides how engines are probed after starting: for
+// every model of the engine target names, e.g. "vllm", or else for the model
+// target names, whichever engine serves it. Zero fields keep their defaults.
+func (s *Service) SetReadinessProbe(target string, probe ReadinessProbe) {
+	if executor, ok := s.executors[target].(ProbeExecutor); ok {
+		executor.SetReadinessProbe("", probe)
+		return
+	}
+	for _, executor := range s.executors {
+		if executor, ok := executor.(ProbeExecutor); ok {
+			executor.SetReadinessProbe(target, probe)
+		}
+	}
+}
+
+// ParseReadinessProbes parses per-engine or per-model probe settings, each
+// given as "target=value,target=value" where targets are engine or model
+// names, e.g. budgets "vllm=15m,meta-llama/Llama-3.1-70B-Instruct=30m",
+// intervals "vllm=5s" and paths "vllm=/health"
+func ParseReadinessProbes(budgets, intervals, paths string) (map[string]ReadinessProbe, error) {
+	probes := make(map[string]ReadinessProbe)
+	parse := func(s, setting string, set func(p *ReadinessProbe, value string) bool) error {
+		for _, pair := range strings.Split(s, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			target, value, ok := strings.Cut(pair, "=")
+			target = strings.TrimSpace(target)
+			probe := probes[target]
+			if !ok || target == "" || !set(&probe, strings.TrimSpace(value)) {
+				return fmt.Errorf("invalid readiness probe %s %q (want engine=%s or model=%s)", setting, pair, setting, setting)
+			}
+			probes[target] = probe
+		}
+		return nil
+	}
+	duration := func(field func(p *ReadinessProbe) *time.Duration) func(p *ReadinessProbe, value string) bool {
+		return func(p *ReadinessProbe, value string) bool {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return false
+			}
+			*field(p) = d
+			return true
+		}
+	}
+
+	if err := parse(budgets, "budget", duration(func(p *ReadinessProbe) *time.Duration { return &p.Budget })); err != nil {
+		return nil, err
+	}
+	if err := parse(intervals, "interval", duration(func(p *ReadinessProbe) *time.Duration { return &p.Interval })); err != nil {
+		return nil, err
+	}
+	err := parse(paths, "path", func(p *ReadinessProbe, value string) bool {
+		p.Path = value
+		return value != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	return probes, nil
+}
+
+// waitForEngineReady probes an engine served at baseURL until it answers
+// 200 OK, logging progress while it waits. Once the budget is spent it fails
+// with the last probe's error, which usually tells why the engine isn't
+// coming up.
+func waitForEngineReady(ctx context.Context, engine, model, baseURL string, probe ReadinessProbe) error {
+	url := probe.url(baseURL)
+	client := &http.Client{Timeout: readinessProbeTimeout}
+	start := time.Now()
+	deadline := start.Add(probe.Budget)
+	lastLog := start
+
+	for {
+		err := probeEngine(ctx, client, url)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		now := time.Now()
+		if !now.Before(deadline) {
+			return fmt.Errorf("%s not ready after %s: %w", engine, probe.Budget, err)
+		}
+		if now.Sub(lastLog) >= readinessLogInterval {
+			log.Printf("Waiting for %s to become ready for model %s: %s of %s elapsed, last probe: %v",
+				engine, model, now.Sub(start).Round(time.Second), probe.Budget, err)
+			lastLog = now
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(probe.Interval):
+		}
+	}
+}
+
+// probeEngine requests url once, failing unless it answers 200 OK
+func probeEngine(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
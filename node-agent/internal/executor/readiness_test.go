@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForEngineReady(t *testing.T) {
+	probe := ReadinessProbe{Path: "/v1/models", Interval: time.Millisecond, Budget: time.Second}
+
+	t.Run("ready after a few probes", func(t *testing.T) {
+		var probes int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/models", r.URL.Path)
+			if atomic.AddInt32(&probes, 1) < 3 {
+				http.Error(w, "loading", http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		require.NoError(t, waitForEngineReady(context.Background(), "vLLM", "llama3", server.URL, probe))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&probes))
+	})
+
+	t.Run("fails with the last probe error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "CUDA out of memory", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		short := probe
+		short.Budget = 20 * time.Millisecond
+		err := waitForEngineReady(context.Background(), "vLLM", "llama3", server.URL, short)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vLLM not ready after 20ms")
+		assert.Contains(t, err.Error(), "status 500: CUDA out of memory")
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		long := probe
+		long.Budget = time.Hour
+		assert.ErrorIs(t, waitForEngineReady(ctx, "vLLM", "llama3", server.URL, long), context.DeadlineExceeded)
+	})
+}
+
+func TestReadinessProbe_url(t *testing.T) {
+	assert.Equal(t, "http://localhost:8000/health", ReadinessProbe{Path: "/health"}.url("http://localhost:8000"))
+	assert.Equal(t, "http://localhost:8000/health", ReadinessProbe{Path: "health"}.url("http://localhost:8000/"))
+	assert.Equal(t, "http://10.0.0.5:9000/ready", ReadinessProbe{Path: "http://10.0.0.5:9000/ready"}.url("http://localhost:8000"))
+}
+
+func TestParseReadinessProbes(t *testing.T) {
+	probes, err := ParseReadinessProbes("vllm=15m, meta-llama/Llama-3.1-70B-Instruct=30m", "vllm=5s", "vllm=/health,llama3:70b=/api/version")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ReadinessProbe{
+		"vllm":                              {Path: "/health", Interval: 5 * time.Second, Budget: 15 * time.Minute},
+		"meta-llama/Llama-3.1-70B-Instruct": {Budget: 30 * time.Minute},
+		"llama3:70b":                        {Path: "/api/version"},
+	}, probes)
+
+	probes, err = ParseReadinessProbes("", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, probes)
+
+	for _, invalid := range []struct{ budgets, intervals, paths string }{
+		{budgets: "vllm"},
+		{budgets: "vllm=soon"},
+		{budgets: "=10m"},
+		{intervals: "vllm=-1s"},
+		{paths: "vllm="},
+	} {
+		_, err := ParseReadinessProbes(invalid.budgets, invalid.intervals, invalid.paths)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestService_SetReadinessProbe(t *testing.T) {
+	vllm := NewVLLMExecutor(NewMockContainerManager())
+	ollama := NewOllamaExecutor(NewMockContainerManager())
+	service := &Service{executors: map[string]Executor{"vllm": vllm, "ollama": ollama}}
+
+	service.SetReadinessProbe("vllm", ReadinessProbe{Budget: 15 * time.Minute})
+	service.SetReadinessProbe("meta-llama/Llama-3.1-70B-Instruct", ReadinessProbe{Budget: 30 * time.Minute, Interval: 5 * time.Second})
+
+	assert.Equal(t, ReadinessProbe{Path: "/v1/models", Interval: time.Second, Budget: 15 * time.Minute}, vllm.probe("mistral", defaultVLLMProbe))
+	assert.Equal(t, ReadinessProbe{Path: "/v1/models", Interval: 5 * time.Second, Budget: 30 * time.Minute}, vllm.probe("meta-llama/Llama-3.1-70B-Instruct", defaultVLLMProbe))
+	assert.Equal(t, defaultOllamaProbe, ollama.probe("llama3", defaultOllamaProbe))
+	assert.Equal(t, 30*time.Minute, ollama.probe("meta-llama/Llama-3.1-70B-Instruct", defaultOllamaProbe).Budget)
+}
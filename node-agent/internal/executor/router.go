@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// TargetNodeMetadata is the gRPC metadata key with which the orchestrator
+// names the node a NodeAgent call is for
+const TargetNodeMetadata = "orchion-target-node"
+
+// Router serves the NodeAgent service for an agent running several logical
+// nodes, e.g. one per GPU, passing each call to the executor service of the
+// node it targets. Calls that name no node, e.g. from older orchestrators, go
+// to the first node.
+type Router struct {
+	pb.UnimplementedNodeAgentServer
+	services map[string]*Service
+	fallback *Service
+}
+
+// NewRouter creates a router for services serving the nodes with the given
+// IDs, in the same order
+func NewRouter(nodeIDs []string, services []*Service) *Router {
+	r := &Router{services: make(map[string]*Service, len(services))}
+	for i, service := range services {
+		r.services[nodeIDs[i]] = service
+	}
+	if len(services) > 0 {
+		r.fallback = services[0]
+	}
+	return r
+}
+
+// service returns the executor service of the node a call targets
+func (r *Router) service(ctx context.Context) (*Service, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	target := md.Get(TargetNodeMetadata)
+	if len(target) == 0 {
+		if r.fallback == nil {
+			return nil, errcode.New(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "agent serves no nodes")
+		}
+		return r.fallback, nil
+	}
+	service, ok := r.services[target[0]]
+	if !ok {
+		return nil, errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "agent doesn't serve node %s", target[0])
+	}
+	return service, nil
+}
+
+// ChatCompletion passes the chat completion to the targeted node's service
+func (r *Router) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.NodeAgent_ChatCompletionServer) error {
+	service, err := r.service(stream.Context())
+	if err != nil {
+		return err
+	}
+	return service.ChatCompletion(req, stream)
+}
+
+// Embeddings passes the embeddings to the targeted node's service
+func (r *Router) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.Embeddings(ctx, req)
+}
+
+// Speech passes the speech synthesis to the targeted node's service
+func (r *Router) Speech(ctx context.Context, req *pb.SpeechRequest) (*pb.SpeechResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.Speech(ctx, req)
+}
+
+// StartDistributed passes the distributed deployment start to the targeted node's service
+func (r *Router) StartDistributed(ctx context.Context, req *pb.StartDistributedRequest) (*pb.StartDistributedResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.StartDistributed(ctx, req)
+}
+
+// StopDistributed passes the distributed deployment stop to the targeted node's service
+func (r *Router) StopDistributed(ctx context.Context, req *pb.StopDistributedRequest) (*pb.StopDistributedResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.StopDistributed(ctx, req)
+}
+
+// LoadModel passes the model load to the targeted node's service
+func (r *Router) LoadModel(ctx context.Context, req *pb.LoadModelRequest) (*pb.LoadModelResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.LoadModel(ctx, req)
+}
+
+// UnloadModel passes the model unload to the targeted node's service
+func (r *Router) UnloadModel(ctx context.Context, req *pb.UnloadModelRequest) (*pb.UnloadModelResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.UnloadModel(ctx, req)
+}
+
+// GetModelStatus reports the model states of the targeted node's service
+func (r *Router) GetModelStatus(ctx context.Context, req *pb.GetModelStatusRequest) (*pb.GetModelStatusResponse, error) {
+	service, err := r.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetModelStatus(ctx, req)
+}
+
+// SetLogLevel changes the agent's log level, which all nodes share
+func (r *Router) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	if r.fallback == nil {
+		return nil, errcode.New(codes.FailedPrecondition, pb.ErrorCode_ERROR_CODE_INTERNAL, "no logger configured")
+	}
+	return r.fallback.SetLogLevel(ctx, req)
+}
+
+// Models returns the models loaded for a node and the engines serving them
+func (r *Router) Models(nodeID string) []*pb.ModelEngine {
+	if service, ok := r.services[nodeID]; ok {
+		return service.Models()
+	}
+	return nil
+}
+
+// ThrottleState returns a node's throttling state, or nil if it isn't
+// throttled
+func (r *Router) ThrottleState(nodeID string) *pb.NodeThrottle {
+	if service, ok := r.services[nodeID]; ok {
+		return service.ThrottleState()
+	}
+	return nil
+}
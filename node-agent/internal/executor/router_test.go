@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRouter_service(t *testing.T) {
+	gpu0, gpu1 := &Service{}, &Service{}
+	router := NewRouter([]string{"box-gpu0", "box-gpu1"}, []*Service{gpu0, gpu1})
+
+	target := func(nodeID string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(TargetNodeMetadata, nodeID))
+	}
+
+	service, err := router.service(target("box-gpu1"))
+	require.NoError(t, err)
+	assert.Same(t, gpu1, service)
+
+	// Calls naming no node go to the first one
+	service, err = router.service(context.Background())
+	require.NoError(t, err)
+	assert.Same(t, gpu0, service)
+
+	_, err = router.service(target("other"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// SetEngineRoutes sets the routes checked in order before the default engine
+// choice, sending the models matching a route's pattern, in path.Match
+// syntax, to its engine. Routes usually come from the orchestrator's node
+// config profile and are set before the agent serves requests.
+func (s *Service) SetEngineRoutes(routes []*pb.EngineRoute) {
+	s.routes = routes
+}
+
+// routedExecutor returns the executor a route sends the model to, if any
+func (s *Service) routedExecutor(model string) (Executor, bool, error) {
+	for _, route := range s.routes {
+		if ok, _ := path.Match(route.Model, model); !ok {
+			continue
+		}
+		executor, exists := s.executors[route.Engine]
+		if !exists {
+			return nil, true, fmt.Errorf("no %s executor for model %s", route.Engine, model)
+		}
+		return executor, true, nil
+	}
+	return nil, false, nil
+}
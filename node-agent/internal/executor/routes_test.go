@@ -0,0 +1,37 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestService_getExecutorForModel_Routes(t *testing.T) {
+	ollama, vllm := newFakeEngine(1), newFakeEngine(1)
+	service := &Service{executors: map[string]Executor{"ollama": ollama, "vllm": vllm}}
+	service.SetEngineRoutes([]*pb.EngineRoute{
+		{Model: "llama3*", Engine: "vllm"},
+		{Model: "org/*", Engine: "ollama"},
+		{Model: "piper/*", Engine: "piper"},
+	})
+
+	executor, err := service.getExecutorForModel("llama3:70b")
+	require.NoError(t, err)
+	assert.Same(t, vllm, executor)
+
+	executor, err = service.getExecutorForModel("org/model")
+	require.NoError(t, err)
+	assert.Same(t, ollama, executor)
+
+	// Models no route matches get the default choice
+	executor, err = service.getExecutorForModel("mistralai/Mistral-7B")
+	require.NoError(t, err)
+	assert.Same(t, vllm, executor)
+
+	// Routes to an engine the agent doesn't run fail instead of falling back
+	_, err = service.getExecutorForModel("piper/en_US-lessac-medium")
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package executor
+
+// SetInteractiveReserve applies the orchestrator's GPU sharing policy: batch
+// requests leave this fraction of the concurrency limit and of VRAM free for
+// interactive requests, e.g. during office hours. 0 lets batch requests use
+// the whole node.
+func (s *Service) SetInteractiveReserve(reserve float64) {
+	s.mu.Lock()
+	s.reserve = reserve
+	limiter := s.limiter
+	s.mu.Unlock()
+
+	if limiter != nil {
+		limiter.SetInteractiveReserve(reserve)
+	}
+}
+
+// interactiveReserve returns the fraction of capacity batch requests leave free
+func (s *Service) interactiveReserve() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reserve
+}
+
+// isBatch reports whether a request is batch work subject to the interactive
+// reserve: queued jobs, and chat requests below normal priority
+func isBatch(batch bool, priority int32) bool {
+	return batch || priority < 0
+}
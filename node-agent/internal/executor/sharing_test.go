@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+)
+
+func TestLimiter_InteractiveReserve(t *testing.T) {
+	l := NewLimiter(4)
+	l.SetInteractiveReserve(0.5)
+	ctx := context.Background()
+
+	// Batch requests get half the slots
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := l.acquire(ctx, 0, true)
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+	admitted := make(chan struct{})
+	go func() {
+		release, err := l.acquire(ctx, 0, true)
+		if assert.NoError(t, err) {
+			close(admitted)
+			release()
+		}
+	}()
+	require.Eventually(t, func() bool { return l.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	// Interactive requests still get the reserved slots
+	release, err := l.acquire(ctx, -1, false)
+	require.NoError(t, err)
+	defer release()
+
+	// Batch requests use the whole node once the reserve is lifted
+	l.SetInteractiveReserve(0)
+	select {
+	case <-admitted:
+	case <-time.After(time.Second):
+		t.Fatal("batch request not admitted after the reserve was lifted")
+	}
+	for _, release := range releases {
+		release()
+	}
+
+	// Batch requests always keep a slot
+	single := NewLimiter(1)
+	single.SetInteractiveReserve(0.9)
+	release, err = single.acquire(ctx, 0, true)
+	require.NoError(t, err)
+	release()
+}
+
+func TestService_InteractiveReserve(t *testing.T) {
+	service := &Service{
+		sampler: telemetry.NewSampler(staticGPU{Type: "NVIDIA RTX 4090", VRAMTotal: "24.0 GB", VRAMUsed: "12.0 GB"}),
+	}
+	service.SetMemoryGuard(NewMemoryGuard(map[string]float64{"llama3": 256}, 0, 0))
+	service.SetInteractiveReserve(0.5)
+	limiter := NewLimiter(2)
+	service.SetLimiter(limiter)
+	assert.Equal(t, 1, limiter.batchCapacity(), "the reserve applies to limiters set later")
+
+	req := &pb.ChatCompletionRequest{
+		Model:     "llama3",
+		Messages:  []*pb.ChatMessage{{Role: "user", Content: "Hello"}},
+		MaxTokens: 2000,
+	}
+	assert.NoError(t, service.checkMemory(req.Model, chatTokens(req), false))
+	assert.Error(t, service.checkMemory(req.Model, chatTokens(req), true), "batch requests leave half the VRAM free")
+
+	service.SetInteractiveReserve(0)
+	assert.NoError(t, service.checkMemory(req.Model, chatTokens(req), true))
+	assert.Equal(t, 2, limiter.batchCapacity())
+
+	assert.True(t, isBatch(true, 0))
+	assert.True(t, isBatch(false, -1))
+	assert.False(t, isBatch(false, 0))
+}
@@ -0,0 +1,213 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// SpeechExecutor is implemented by executors running text-to-speech models
+type SpeechExecutor interface {
+	// Speech reads req.Input aloud and returns the audio as WAV. The service
+	// converts it to the requested format and speed.
+	Speech(ctx context.Context, model string, req *pb.SpeechRequest) ([]byte, error)
+}
+
+// DefaultSpeechFormat is the audio format of speech requests naming none
+const DefaultSpeechFormat = "mp3"
+
+// speechEncoding is how ffmpeg encodes an audio format
+type speechEncoding struct {
+	args        []string // ffmpeg output options
+	contentType string
+}
+
+// speechEncodings are the audio formats speech can be returned in
+var speechEncodings = map[string]speechEncoding{
+	"wav":  {[]string{"-f", "wav"}, "audio/wav"},
+	"mp3":  {[]string{"-f", "mp3"}, "audio/mpeg"},
+	"flac": {[]string{"-f", "flac"}, "audio/flac"},
+	"opus": {[]string{"-c:a", "libopus", "-f", "ogg"}, "audio/ogg"},
+	"aac":  {[]string{"-c:a", "aac", "-f", "adts"}, "audio/aac"},
+}
+
+// ffmpegPath is the ffmpeg binary converting engine WAV output to other
+// formats and speeds
+var ffmpegPath = "ffmpeg"
+
+// openAIVoices are OpenAI's voice names, which clients written for OpenAI
+// send. They pick a model's default speaker.
+var openAIVoices = map[string]bool{
+	"alloy": true, "ash": true, "ballad": true, "coral": true, "echo": true, "fable": true,
+	"onyx": true, "nova": true, "sage": true, "shimmer": true, "verse": true,
+}
+
+// speechEngines are the executors serving text-to-speech models, which are
+// named after them, e.g. "piper/en_US-lessac-medium"
+var speechEngines = []string{"piper", "coqui"}
+
+// errSpeechOnly is returned by text-to-speech executors for chat and embedding requests
+var errSpeechOnly = errors.New("text-to-speech models only serve speech requests")
+
+// speechEngine returns the engine of a text-to-speech model and the model's
+// name in that engine, and false for other models
+func speechEngine(model string) (engine, name string, ok bool) {
+	engine, name, ok = strings.Cut(model, "/")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	for _, e := range speechEngines {
+		if e == engine {
+			return engine, name, true
+		}
+	}
+	return "", "", false
+}
+
+// speaker returns the engine speaker a request's voice names, or an empty
+// string for the model's default speaker
+func speaker(voice string) string {
+	if openAIVoices[strings.ToLower(voice)] {
+		return ""
+	}
+	return voice
+}
+
+// Speech handles text-to-speech requests by routing to the model's executor
+func (s *Service) Speech(ctx context.Context, req *pb.SpeechRequest) (*pb.SpeechResponse, error) {
+	if req.Model == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
+	}
+	if req.Input == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "input is required")
+	}
+	format := req.ResponseFormat
+	if format == "" {
+		format = DefaultSpeechFormat
+	}
+	if _, ok := speechEncodings[format]; !ok {
+		return nil, errcode.Errorf(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "unsupported response format %q", format)
+	}
+
+	// Check the model is a text-to-speech model before starting it
+	executor, err := s.getExecutorForModel(req.Model)
+	if err != nil {
+		return nil, errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no executor for model %s: %v", req.Model, err)
+	}
+	synthesizer, ok := executor.(SpeechExecutor)
+	if !ok {
+		return nil, errcode.Errorf(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+			"model %s is not a text-to-speech model (use piper/<voice> or coqui/<model>)", req.Model)
+	}
+
+	// Record the job until it ends, so it can be retried if the agent crashes
+	defer s.journalJob(ctx, req.Model)()
+
+	// Shed requests while the GPU runs over its limits
+	release, err := s.acquireThrottle()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	releaseSlot, err := s.acquireLimiter(ctx, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseSlot()
+
+	// Ensure model is running
+	if err := s.ensureModelRunning(ctx, req.Model); err != nil {
+		return nil, errcode.Engine(fmt.Sprintf("failed to start model %s", req.Model), err)
+	}
+
+	// Execute request
+	usage := s.startUsage(req.Model, "speech")
+	wav, err := synthesizer.Speech(ctx, req.Model, req)
+	if err != nil {
+		return nil, errcode.Engine("failed to synthesize speech", err)
+	}
+	audio, contentType, err := encodeSpeech(ctx, wav, format, req.Speed)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, errcode.Errorf(codes.FailedPrecondition, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+				"%s audio at speed %g needs ffmpeg on the node; ask for wav at normal speed", format, req.Speed)
+		}
+		return nil, errcode.Engine("failed to encode speech", err)
+	}
+
+	// Report GPU usage so the orchestrator can attach it to the job
+	if md := usage.finish(ctx); md != nil {
+		grpc.SetTrailer(ctx, md)
+	}
+	return &pb.SpeechResponse{Model: req.Model, Audio: audio, ContentType: contentType}, nil
+}
+
+// encodeSpeech converts WAV audio to a format at a speed, 0 or 1 keeping it
+// as is. WAV at normal speed is returned unchanged; anything else goes
+// through ffmpeg.
+func encodeSpeech(ctx context.Context, wav []byte, format string, speed float32) ([]byte, string, error) {
+	encoding, ok := speechEncodings[format]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported audio format %q", format)
+	}
+	normalSpeed := speed == 0 || speed == 1
+	if format == "wav" && normalSpeed {
+		return wav, encoding.contentType, nil
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-f", "wav", "-i", "pipe:0"}
+	if !normalSpeed {
+		args = append(args, "-filter:a", atempo(speed))
+	}
+	args = append(args, encoding.args...)
+	args = append(args, "pipe:1")
+
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stdin = bytes.NewReader(wav)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), encoding.contentType, nil
+}
+
+// atempo returns the ffmpeg filter changing audio speed without changing its
+// pitch, chaining atempo filters as each only goes from half to twice the speed
+func atempo(speed float32) string {
+	s := float64(speed)
+	var filters []string
+	for ; s > 2; s /= 2 {
+		filters = append(filters, "atempo=2")
+	}
+	for ; s < 0.5; s /= 0.5 {
+		filters = append(filters, "atempo=0.5")
+	}
+	return strings.Join(append(filters, fmt.Sprintf("atempo=%g", s)), ",")
+}
+
+// readAudio reads the audio an engine answered with, failing on error statuses
+func readAudio(resp *http.Response, engine string) ([]byte, error) {
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s returned status %d: %s", engine, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s audio: %w", engine, err)
+	}
+	return audio, nil
+}
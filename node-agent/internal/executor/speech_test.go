@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// fakeSynthesizer is an executor reading input aloud as its bytes
+type fakeSynthesizer struct {
+	*fakeEngine
+}
+
+func (e fakeSynthesizer) Speech(ctx context.Context, model string, req *pb.SpeechRequest) ([]byte, error) {
+	return []byte(req.Input), nil
+}
+
+// enginePort returns the port of a test server standing in for an engine
+func enginePort(t *testing.T, server *httptest.Server) int {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return port
+}
+
+func TestSpeechEngine(t *testing.T) {
+	engine, name, ok := speechEngine("coqui/tts_models/en/vctk/vits")
+	assert.True(t, ok)
+	assert.Equal(t, "coqui", engine)
+	assert.Equal(t, "tts_models/en/vctk/vits", name)
+
+	for _, model := range []string{"llama3", "mistralai/Mistral-7B", "piper/", "piperx/voice"} {
+		_, _, ok := speechEngine(model)
+		assert.False(t, ok, model)
+	}
+}
+
+func TestService_getExecutorForModel_Speech(t *testing.T) {
+	ollama, vllm, piper := newFakeEngine(1), newFakeEngine(1), NewPiperExecutor(nil)
+	service := &Service{executors: map[string]Executor{"ollama": ollama, "vllm": vllm, "piper": piper}}
+
+	executor, err := service.getExecutorForModel("piper/en_US-lessac-medium")
+	require.NoError(t, err)
+	assert.Same(t, piper, executor)
+
+	executor, err = service.getExecutorForModel("mistralai/Mistral-7B")
+	require.NoError(t, err)
+	assert.Same(t, vllm, executor)
+
+	// Speech models don't fall back to the chat engines
+	_, err = service.getExecutorForModel("coqui/tts_models/en/vctk/vits")
+	assert.Error(t, err)
+}
+
+func TestService_Speech(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{
+		executors:     map[string]Executor{"ollama": newFakeEngine(1), "piper": fakeSynthesizer{newFakeEngine(1)}},
+		runningModels: make(map[string]*ModelInstance),
+	}
+
+	resp, err := service.Speech(ctx, &pb.SpeechRequest{Model: "piper/en_US-lessac-medium", Input: "RIFF", ResponseFormat: "wav"})
+	require.NoError(t, err)
+	assert.Equal(t, "piper/en_US-lessac-medium", resp.Model)
+	assert.Equal(t, []byte("RIFF"), resp.Audio)
+	assert.Equal(t, "audio/wav", resp.ContentType)
+
+	for name, req := range map[string]*pb.SpeechRequest{
+		"missing model":      {Input: "hi"},
+		"missing input":      {Model: "piper/en_US-lessac-medium"},
+		"unknown format":     {Model: "piper/en_US-lessac-medium", Input: "hi", ResponseFormat: "ogg"},
+		"not text-to-speech": {Model: "llama3", Input: "hi"},
+	} {
+		_, err := service.Speech(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+	}
+	// Chat models weren't started for speech requests
+	assert.Empty(t, service.executors["ollama"].(*fakeEngine).loaded)
+}
+
+func TestEncodeSpeech(t *testing.T) {
+	ctx := context.Background()
+	wav := silentWAV(8000)
+
+	audio, contentType, err := encodeSpeech(ctx, wav, "wav", 1)
+	require.NoError(t, err)
+	assert.Equal(t, wav, audio)
+	assert.Equal(t, "audio/wav", contentType)
+
+	_, _, err = encodeSpeech(ctx, wav, "ogg", 1)
+	assert.Error(t, err)
+
+	if _, err := exec.LookPath(ffmpegPath); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	audio, contentType, err = encodeSpeech(ctx, wav, "flac", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, "audio/flac", contentType)
+	assert.Equal(t, "fLaC", string(audio[:4]))
+}
+
+// silentWAV returns a second of 16-bit mono silence at the given sample rate
+func silentWAV(rate int) []byte {
+	data := 2 * rate
+	header := []byte("RIFF\x00\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x00\x10\x00data\x00\x00\x00\x00")
+	put := func(at, v int) {
+		header[at], header[at+1], header[at+2], header[at+3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+	}
+	put(4, 36+data)
+	put(24, rate)
+	put(28, 2*rate)
+	put(40, data)
+	return append(header, make([]byte, data)...)
+}
+
+func TestAtempo(t *testing.T) {
+	assert.Equal(t, "atempo=1.5", atempo(1.5))
+	assert.Equal(t, "atempo=2,atempo=2", atempo(4))
+	assert.Equal(t, "atempo=0.5,atempo=0.5", atempo(0.25))
+	assert.Equal(t, "atempo=2,atempo=1.5", atempo(3))
+}
+
+func TestPiperExecutor_Speech(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		got = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Write([]byte("RIFF"))
+	}))
+	defer server.Close()
+
+	executor := NewPiperExecutor(nil)
+	executor.runningPorts.set("piper/en_US-libritts-high", enginePort(t, server))
+
+	audio, err := executor.Speech(context.Background(), "piper/en_US-libritts-high", &pb.SpeechRequest{Input: "hello", Voice: "p225"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+	assert.Equal(t, map[string]interface{}{"text": "hello", "voice": "en_US-libritts-high", "speaker": "p225"}, got)
+
+	// OpenAI voice names pick the default speaker
+	_, err = executor.Speech(context.Background(), "piper/en_US-libritts-high", &pb.SpeechRequest{Input: "hello", Voice: "alloy"})
+	require.NoError(t, err)
+	assert.NotContains(t, got, "speaker")
+
+	_, err = executor.Speech(context.Background(), "piper/other", &pb.SpeechRequest{Input: "hello"})
+	assert.Error(t, err)
+}
+
+func TestCoquiExecutor_Speech(t *testing.T) {
+	var got url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tts", r.URL.Path)
+		got = r.URL.Query()
+		if got.Get("speaker_id") == "nobody" {
+			http.Error(w, "unknown speaker", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("RIFF"))
+	}))
+	defer server.Close()
+
+	executor := NewCoquiExecutor(nil)
+	executor.runningPorts.set("coqui/tts_models/en/vctk/vits", enginePort(t, server))
+
+	audio, err := executor.Speech(context.Background(), "coqui/tts_models/en/vctk/vits", &pb.SpeechRequest{Input: "hello there", Voice: "p225"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+	assert.Equal(t, "hello there", got.Get("text"))
+	assert.Equal(t, "p225", got.Get("speaker_id"))
+
+	_, err = executor.Speech(context.Background(), "coqui/tts_models/en/vctk/vits", &pb.SpeechRequest{Input: "hello", Voice: "nobody"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown speaker")
+}
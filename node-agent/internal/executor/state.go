@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// stateReporterKey carries the function executors report start progress to
+type stateReporterKey struct{}
+
+// withStateReporter returns a context through which an executor starting a
+// model can report its progress, e.g. that it is downloading the model
+func withStateReporter(ctx context.Context, report func(pb.ModelState)) context.Context {
+	return context.WithValue(ctx, stateReporterKey{}, report)
+}
+
+// reportState reports a starting model's progress, if the caller listens
+func reportState(ctx context.Context, state pb.ModelState) {
+	if report, ok := ctx.Value(stateReporterKey{}).(func(pb.ModelState)); ok {
+		report(state)
+	}
+}
+
+// setModelState records a model's lifecycle state, with the error that made
+// it degraded, if any
+func (s *Service) setModelState(model string, state pb.ModelState, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setModelStateLocked(model, state, err)
+}
+
+// setModelStateLocked is setModelState for callers holding mu
+func (s *Service) setModelStateLocked(model string, state pb.ModelState, err error) {
+	if s.states == nil {
+		s.states = make(map[string]*pb.ModelStatus)
+	}
+	// A model staying in its state keeps its timestamp
+	if prev, ok := s.states[model]; ok && prev.State == state && err == nil {
+		return
+	}
+	status := &pb.ModelStatus{Model: model, State: state, SinceUnix: time.Now().Unix()}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	if instance, ok := s.runningModels[model]; ok {
+		status.Engine = instance.Engine
+	}
+	s.states[model] = status
+}
+
+// forgetModelState stops tracking a model that was stopped
+func (s *Service) forgetModelState(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, model)
+}
+
+// GetModelStatus reports the lifecycle state of one or every model the node
+// tracks, e.g. for the orchestrator to check preloaded replicas
+func (s *Service) GetModelStatus(ctx context.Context, req *pb.GetModelStatusRequest) (*pb.GetModelStatusResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := &pb.GetModelStatusResponse{}
+	for model, status := range s.states {
+		if req.Model != "" && model != req.Model {
+			continue
+		}
+		resp.Models = append(resp.Models, &pb.ModelStatus{
+			Model:     status.Model,
+			State:     status.State,
+			Error:     status.Error,
+			SinceUnix: status.SinceUnix,
+			Engine:    status.Engine,
+		})
+	}
+	sort.Slice(resp.Models, func(i, j int) bool { return resp.Models[i].Model < resp.Models[j].Model })
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// downloadingEngine reports a download while starting models, like Ollama
+// pulling a model it doesn't have yet, and records the state the service
+// reports meanwhile
+type downloadingEngine struct {
+	*fakeEngine
+	service *Service
+	seen    pb.ModelState
+}
+
+func (e *downloadingEngine) StartModel(ctx context.Context, model string) error {
+	reportState(ctx, pb.ModelState_MODEL_STATE_DOWNLOADING)
+	if e.service != nil {
+		e.seen = e.service.states[model].State
+	}
+	return e.fakeEngine.StartModel(ctx, model)
+}
+
+func TestService_GetModelStatus(t *testing.T) {
+	ctx := context.Background()
+	engine := &downloadingEngine{fakeEngine: newFakeEngine(1)}
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+	}
+	states := func(model string) map[string]pb.ModelState {
+		resp, err := service.GetModelStatus(ctx, &pb.GetModelStatusRequest{Model: model})
+		require.NoError(t, err)
+		states := make(map[string]pb.ModelState)
+		for _, status := range resp.Models {
+			states[status.Model] = status.State
+		}
+		return states
+	}
+
+	assert.Empty(t, states(""))
+
+	require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+	assert.Equal(t, map[string]pb.ModelState{"llama3": pb.ModelState_MODEL_STATE_READY}, states(""))
+
+	// A model that fails to start is reported degraded, with the error
+	require.Error(t, service.ensureModelRunning(ctx, "phi3"))
+	resp, err := service.GetModelStatus(ctx, &pb.GetModelStatusRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Models, 2)
+	assert.Equal(t, "llama3", resp.Models[0].Model)
+	assert.Equal(t, "phi3", resp.Models[1].Model)
+	assert.Equal(t, pb.ModelState_MODEL_STATE_DEGRADED, resp.Models[1].State)
+	assert.Contains(t, resp.Models[1].Error, "out of memory")
+	assert.NotZero(t, resp.Models[1].SinceUnix)
+
+	assert.Equal(t, map[string]pb.ModelState{"phi3": pb.ModelState_MODEL_STATE_DEGRADED}, states("phi3"))
+
+	// Unloaded models are no longer reported
+	_, err = service.UnloadModel(ctx, &pb.UnloadModelRequest{Model: "llama3"})
+	require.NoError(t, err)
+	assert.NotContains(t, states(""), "llama3")
+}
+
+func TestService_runModel_ReportsDownload(t *testing.T) {
+	engine := &downloadingEngine{fakeEngine: newFakeEngine(1)}
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+	}
+	engine.service = service
+
+	require.NoError(t, service.runModel(context.Background(), "llama3"))
+	assert.Equal(t, pb.ModelState_MODEL_STATE_DOWNLOADING, engine.seen)
+	assert.Equal(t, pb.ModelState_MODEL_STATE_READY, service.states["llama3"].State)
+
+	// Without a listener reporting is a no-op
+	reportState(context.Background(), pb.ModelState_MODEL_STATE_DOWNLOADING)
+}
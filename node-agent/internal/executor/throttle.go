@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	"github.com/Orchion/Orchion/node-agent/internal/errcode"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+const (
+	// ThrottleTempHysteresisC is how far below the temperature limit the GPU
+	// must cool before throttling ends
+	ThrottleTempHysteresisC = 5.0
+
+	// ThrottlePowerHysteresis is the share of the power limit the GPU must
+	// draw less than before throttling ends
+	ThrottlePowerHysteresis = 0.9
+)
+
+// Throttle limits the requests an agent accepts while its GPU runs above a
+// temperature or power limit, e.g. in passively cooled or small form factor
+// builds. While throttled, requests beyond the throttled concurrency are
+// rejected with a retryable NODE_THROTTLED error so the orchestrator sends
+// them elsewhere. Throttling ends once readings fall a margin below the
+// limits, so it doesn't flap around them.
+type Throttle struct {
+	maxTempC      float64 // 0 = no temperature limit
+	maxPowerW     float64 // 0 = no power limit
+	maxConcurrent int     // Requests accepted at once while throttled (0 = new requests paused)
+
+	mu       sync.Mutex
+	inFlight int
+	reason   string // Limit exceeded; empty while not throttled
+	since    time.Time
+	now      func() time.Time
+}
+
+// NewThrottle creates a throttle for the given GPU temperature (°C) and
+// power (W) limits, each disabled if 0, allowing maxConcurrent requests at
+// once while throttled
+func NewThrottle(maxTempC, maxPowerW float64, maxConcurrent int) *Throttle {
+	return &Throttle{
+		maxTempC:      maxTempC,
+		maxPowerW:     maxPowerW,
+		maxConcurrent: maxConcurrent,
+		now:           time.Now,
+	}
+}
+
+// updateLocked starts or ends throttling according to a GPU reading. GPUs
+// without temperature or power readings are never throttled.
+func (t *Throttle) updateLocked(gpu capabilities.GPUInfo) {
+	temp, okTemp := units.ParseNumber(gpu.Temperature)
+	power, okPower := units.ParseNumber(gpu.PowerUsage)
+	hot := t.maxTempC > 0 && okTemp
+	hungry := t.maxPowerW > 0 && okPower
+
+	var reason string
+	switch {
+	case hot && temp >= t.maxTempC:
+		reason = fmt.Sprintf("GPU temperature %.0f°C over %.0f°C limit", temp, t.maxTempC)
+	case hungry && power >= t.maxPowerW:
+		reason = fmt.Sprintf("GPU power %.1f W over %.0f W limit", power, t.maxPowerW)
+	}
+
+	if reason != "" {
+		if t.reason == "" {
+			t.since = t.now()
+			log.Printf("Throttling requests to %d at once: %s", t.maxConcurrent, reason)
+		}
+		t.reason = reason
+		return
+	}
+	if t.reason == "" {
+		return
+	}
+	if (hot && temp > t.maxTempC-ThrottleTempHysteresisC) || (hungry && power > t.maxPowerW*ThrottlePowerHysteresis) {
+		return
+	}
+	log.Printf("GPU back within limits after %s, no longer throttling requests", t.now().Sub(t.since).Round(time.Second))
+	t.reason = ""
+}
+
+// acquire admits a request unless the GPU is throttled and already serving
+// its throttled concurrency. The returned function releases the request.
+func (t *Throttle) acquire(gpu capabilities.GPUInfo) (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.updateLocked(gpu)
+	if t.reason != "" && t.inFlight >= t.maxConcurrent {
+		return nil, errcode.Retryable(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_THROTTLED,
+			fmt.Sprintf("node throttled: %s", t.reason))
+	}
+	t.inFlight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.inFlight--
+			t.mu.Unlock()
+		})
+	}, nil
+}
+
+// state returns the throttling state for a GPU reading, or nil if the GPU
+// isn't throttled
+func (t *Throttle) state(gpu capabilities.GPUInfo) *pb.NodeThrottle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.updateLocked(gpu)
+	if t.reason == "" {
+		return nil
+	}
+	return &pb.NodeThrottle{
+		Reason:        t.reason,
+		MaxConcurrent: int32(t.maxConcurrent),
+		SinceUnix:     t.since.Unix(),
+	}
+}
+
+// SetThrottle enables throttling requests while the GPU exceeds the
+// throttle's temperature or power limits
+func (s *Service) SetThrottle(throttle *Throttle) {
+	s.throttle = throttle
+}
+
+// acquireThrottle admits a request through the throttle, if any. The
+// returned function releases it once the request is done.
+func (s *Service) acquireThrottle() (func(), error) {
+	if s.throttle == nil || s.gpu == nil {
+		return func() {}, nil
+	}
+	release, err := s.throttle.acquire(s.gpu.DetectGPU())
+	if err != nil {
+		log.Printf("Rejecting request: %v", err)
+		return nil, err
+	}
+	return release, nil
+}
+
+// ThrottleState returns the current throttling state to report in
+// heartbeats, or nil while requests aren't throttled. Reading it also ends
+// throttling once the GPU cooled down, even if no requests arrive.
+func (s *Service) ThrottleState() *pb.NodeThrottle {
+	if s.throttle == nil || s.gpu == nil {
+		return nil
+	}
+	return s.throttle.state(s.gpu.DetectGPU())
+}
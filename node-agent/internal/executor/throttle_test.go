@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestThrottle_acquire(t *testing.T) {
+	throttle := NewThrottle(85, 300, 1)
+	cool := capabilities.GPUInfo{Temperature: "60°C", PowerUsage: "150.0 W"}
+	hot := capabilities.GPUInfo{Temperature: "88°C", PowerUsage: "150.0 W"}
+
+	// Not throttled: any number of requests
+	first, err := throttle.acquire(cool)
+	require.NoError(t, err)
+	second, err := throttle.acquire(cool)
+	require.NoError(t, err)
+	second()
+
+	// Throttled to one request at once, which the first already is
+	_, err = throttle.acquire(hot)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*pb.ErrorInfo)
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_THROTTLED, info.Code)
+	assert.True(t, info.Retryable)
+
+	first()
+	release, err := throttle.acquire(hot)
+	require.NoError(t, err)
+	release()
+	release() // Releasing twice doesn't free another slot
+
+	assert.Equal(t, 0, throttle.inFlight)
+}
+
+func TestThrottle_state(t *testing.T) {
+	throttle := NewThrottle(85, 300, 0)
+
+	tests := []struct {
+		name   string
+		gpu    capabilities.GPUInfo
+		reason string
+	}{
+		{"within limits", capabilities.GPUInfo{Temperature: "70°C", PowerUsage: "200.0 W"}, ""},
+		{"over power limit", capabilities.GPUInfo{Temperature: "70°C", PowerUsage: "310.0 W"}, "GPU power 310.0 W over 300 W limit"},
+		{"over temperature limit", capabilities.GPUInfo{Temperature: "86°C", PowerUsage: "310.0 W"}, "GPU temperature 86°C over 85°C limit"},
+		{"stays throttled just below the limits", capabilities.GPUInfo{Temperature: "83°C", PowerUsage: "250.0 W"}, "GPU temperature 86°C over 85°C limit"},
+		{"ends once clearly below the limits", capabilities.GPUInfo{Temperature: "79°C", PowerUsage: "250.0 W"}, ""},
+		{"no readings", capabilities.GPUInfo{Type: "Apple M2"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := throttle.state(tt.gpu)
+			if tt.reason == "" {
+				assert.Nil(t, state)
+				return
+			}
+			require.NotNil(t, state)
+			assert.Equal(t, tt.reason, state.Reason)
+			assert.Equal(t, int32(0), state.MaxConcurrent)
+		})
+	}
+}
+
+func TestService_acquireThrottle(t *testing.T) {
+	service := &Service{gpu: staticGPU{Temperature: "92°C"}}
+
+	release, err := service.acquireThrottle()
+	require.NoError(t, err, "no throttle configured")
+	release()
+	assert.Nil(t, service.ThrottleState())
+
+	service.SetThrottle(NewThrottle(85, 0, 0))
+	_, err = service.acquireThrottle()
+	assert.Error(t, err)
+	assert.Equal(t, "GPU temperature 92°C over 85°C limit", service.ThrottleState().GetReason())
+}
@@ -0,0 +1,246 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxTraceBodyBytes bounds how much of each request/response body is logged
+const maxTraceBodyBytes = 64 << 10 // 64 KiB
+
+// redactedFields are JSON keys whose values carry prompt or completion text
+var redactedFields = map[string]bool{
+	"content":  true,
+	"prompt":   true,
+	"input":    true,
+	"response": true,
+	"text":     true,
+}
+
+// TraceSettings controls engine HTTP trace logging
+type TraceSettings struct {
+	Enabled       bool `json:"enabled"`
+	RedactPrompts bool `json:"redact_prompts"`
+}
+
+// Tracer logs the HTTP traffic between executors and inference engines.
+// It can be switched on and off at runtime to debug a misbehaving model.
+type Tracer struct {
+	enabled atomic.Bool
+	redact  atomic.Bool
+}
+
+// NewTracer creates a disabled tracer that redacts prompt content by default
+func NewTracer() *Tracer {
+	t := &Tracer{}
+	t.redact.Store(true)
+	return t
+}
+
+// Settings returns the current trace settings
+func (t *Tracer) Settings() TraceSettings {
+	return TraceSettings{
+		Enabled:       t.enabled.Load(),
+		RedactPrompts: t.redact.Load(),
+	}
+}
+
+// Apply updates the trace settings
+func (t *Tracer) Apply(settings TraceSettings) {
+	t.enabled.Store(settings.Enabled)
+	t.redact.Store(settings.RedactPrompts)
+}
+
+// Transport wraps an HTTP transport so that requests made through it are traced
+func (t *Tracer) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{base: base, tracer: t}
+}
+
+// ServeHTTP exposes the trace settings on the agent admin endpoint.
+// GET returns the current settings; PUT or POST replaces them.
+func (t *Tracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		settings := t.Settings()
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		t.Apply(settings)
+		log.Printf("Engine HTTP tracing updated: enabled=%t redact_prompts=%t", settings.Enabled, settings.RedactPrompts)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Settings())
+}
+
+// tracingTransport logs requests and responses when the tracer is enabled
+type tracingTransport struct {
+	base   http.RoundTripper
+	tracer *Tracer
+}
+
+// RoundTrip implements http.RoundTripper
+func (tt *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !tt.tracer.enabled.Load() {
+		return tt.base.RoundTrip(req)
+	}
+
+	redact := tt.tracer.redact.Load()
+
+	var reqBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	log.Printf("[trace] engine request %s %s body=%s", req.Method, req.URL, traceBody(reqBody, redact))
+
+	start := time.Now()
+	resp, err := tt.base.RoundTrip(req)
+	if err != nil {
+		log.Printf("[trace] engine request %s %s failed after %s: %v", req.Method, req.URL, time.Since(start), err)
+		return nil, err
+	}
+
+	log.Printf("[trace] engine response %s %s status=%d headers_after=%s", req.Method, req.URL, resp.StatusCode, time.Since(start))
+
+	// The body is logged once it has been fully consumed, since engines stream output
+	resp.Body = &tracedBody{
+		ReadCloser: resp.Body,
+		method:     req.Method,
+		url:        req.URL.String(),
+		start:      start,
+		redact:     redact,
+	}
+	return resp, nil
+}
+
+// tracedBody captures a response body as it is read and logs it on Close
+type tracedBody struct {
+	io.ReadCloser
+	method string
+	url    string
+	start  time.Time
+	redact bool
+
+	buf       bytes.Buffer
+	truncated bool
+	once      sync.Once
+}
+
+// Read implements io.Reader
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if room := maxTraceBodyBytes - b.buf.Len(); room > 0 {
+			if n > room {
+				b.buf.Write(p[:room])
+				b.truncated = true
+			} else {
+				b.buf.Write(p[:n])
+			}
+		} else {
+			b.truncated = true
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *tracedBody) Close() error {
+	b.once.Do(func() {
+		suffix := ""
+		if b.truncated {
+			suffix = " (truncated)"
+		}
+		log.Printf("[trace] engine response body %s %s total=%s body=%s%s",
+			b.method, b.url, time.Since(b.start), traceBody(b.buf.Bytes(), b.redact), suffix)
+	})
+	return b.ReadCloser.Close()
+}
+
+// traceBody renders a body for logging, redacting prompt content if requested.
+// Newline-delimited JSON (as streamed by Ollama) is redacted line by line.
+func traceBody(body []byte, redact bool) string {
+	if len(body) > maxTraceBodyBytes {
+		body = body[:maxTraceBodyBytes]
+	}
+	if !redact || len(body) == 0 {
+		return string(body)
+	}
+
+	lines := bytes.Split(body, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = redactLine(line)
+	}
+	return string(bytes.Join(lines, []byte("\n")))
+}
+
+// redactLine redacts a single JSON document, or an SSE "data:" line carrying one
+func redactLine(line []byte) []byte {
+	prefix := []byte(nil)
+	payload := bytes.TrimSpace(line)
+	if bytes.HasPrefix(payload, []byte("data:")) {
+		prefix = []byte("data: ")
+		payload = bytes.TrimSpace(payload[len("data:"):])
+	}
+	if len(payload) == 0 {
+		return line
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		if prefix != nil && string(payload) == "[DONE]" {
+			return line
+		}
+		// Not JSON we understand; drop it rather than risk leaking prompt text
+		return []byte("[REDACTED]")
+	}
+
+	redacted, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return []byte("[REDACTED]")
+	}
+	return append(prefix, redacted...)
+}
+
+// redactValue replaces prompt-carrying fields in a decoded JSON value
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, field := range val {
+			if redactedFields[k] {
+				val[k] = "[REDACTED]"
+			} else {
+				val[k] = redactValue(field)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
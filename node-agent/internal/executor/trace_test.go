@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog redirects the standard logger for the duration of a test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestTracer_Transport(t *testing.T) {
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(t, string(body), "secret prompt", "engine must receive the original body")
+		w.Write([]byte(`{"message":{"role":"assistant","content":"secret answer"},"done":false}` + "\n"))
+		w.Write([]byte(`{"done":true,"eval_count":12}` + "\n"))
+	}))
+	defer engine.Close()
+
+	tests := []struct {
+		name        string
+		settings    TraceSettings
+		wantTrace   bool
+		wantContent bool
+	}{
+		{"disabled", TraceSettings{Enabled: false, RedactPrompts: true}, false, false},
+		{"redacted", TraceSettings{Enabled: true, RedactPrompts: true}, true, false},
+		{"unredacted", TraceSettings{Enabled: true, RedactPrompts: false}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			tracer := NewTracer()
+			tracer.Apply(tt.settings)
+			client := &http.Client{Transport: tracer.Transport(nil)}
+
+			resp, err := client.Post(engine.URL+"/api/chat", "application/json",
+				strings.NewReader(`{"model":"llama3","messages":[{"role":"user","content":"secret prompt"}]}`))
+			require.NoError(t, err)
+			_, err = io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			out := logs.String()
+			if !tt.wantTrace {
+				assert.Empty(t, out)
+				return
+			}
+
+			assert.Contains(t, out, "engine request POST")
+			assert.Contains(t, out, "status=200")
+			assert.Contains(t, out, `"eval_count":12`)
+			assert.Equal(t, tt.wantContent, strings.Contains(out, "secret prompt"))
+			assert.Equal(t, tt.wantContent, strings.Contains(out, "secret answer"))
+		})
+	}
+}
+
+func TestTracer_ServeHTTP(t *testing.T) {
+	captureLog(t)
+	tracer := NewTracer()
+	assert.Equal(t, TraceSettings{Enabled: false, RedactPrompts: true}, tracer.Settings())
+
+	rec := httptest.NewRecorder()
+	tracer.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/trace", strings.NewReader(`{"enabled":true}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"enabled":true,"redact_prompts":true}`, rec.Body.String())
+	assert.True(t, tracer.Settings().Enabled)
+
+	rec = httptest.NewRecorder()
+	tracer.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/trace", strings.NewReader(`not json`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	tracer.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/trace", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func Test_traceBody(t *testing.T) {
+	assert.Equal(t, `{"model":"m","prompt":"[REDACTED]"}`, traceBody([]byte(`{"model":"m","prompt":"hi"}`), true))
+	assert.Equal(t, `{"model":"m","prompt":"hi"}`, traceBody([]byte(`{"model":"m","prompt":"hi"}`), false))
+	assert.Equal(t, "data: {\"choices\":[{\"delta\":{\"content\":\"[REDACTED]\"}}]}\ndata: [DONE]",
+		traceBody([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\ndata: [DONE]"), true))
+	assert.Equal(t, "[REDACTED]", traceBody([]byte("plain text prompt"), true))
+}
@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+)
+
+// usageRecorder samples the GPU around a single request
+type usageRecorder struct {
+	service *Service
+	model   string
+	kind    string
+	began   time.Time
+	start   *pb.GpuSample
+}
+
+// startUsage takes the GPU sample at the start of a request
+func (s *Service) startUsage(model, kind string) *usageRecorder {
+	u := &usageRecorder{
+		service: s,
+		model:   model,
+		kind:    kind,
+		began:   time.Now(),
+	}
+	if s.sampler != nil {
+		u.start = s.sampler.Sample()
+	}
+	return u
+}
+
+// finish takes the GPU sample at the end of the request, records the request
+// metrics and returns the trailer reporting the samples to the orchestrator.
+// It returns nil if the node has no GPU readings.
+func (u *usageRecorder) finish(ctx context.Context) metadata.MD {
+	usage := &pb.GpuUsage{Start: u.start}
+	if u.service.sampler != nil {
+		usage.End = u.service.sampler.Sample()
+	}
+
+	if u.service.metrics != nil {
+		u.service.metrics.Record(ctx, u.model, u.kind, usage, time.Since(u.began))
+	}
+
+	if usage.Start == nil && usage.End == nil {
+		return nil
+	}
+	value, err := telemetry.MarshalUsage(usage)
+	if err != nil {
+		log.Printf("Failed to encode GPU usage for model %s: %v", u.model, err)
+		return nil
+	}
+	return metadata.Pairs(telemetry.GPUUsageTrailer, value)
+}
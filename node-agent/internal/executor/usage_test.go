@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/node-agent/internal/telemetry"
+)
+
+type staticGPU capabilities.GPUInfo
+
+func (g staticGPU) DetectGPU() capabilities.GPUInfo {
+	return capabilities.GPUInfo(g)
+}
+
+func TestUsageRecorder_Trailer(t *testing.T) {
+	service := &Service{
+		sampler: telemetry.NewSampler(staticGPU{Type: "NVIDIA A100", VRAMTotal: "80.0 GB", VRAMUsed: "40.0 GB", Utilization: "92%"}),
+	}
+
+	md := service.startUsage("llama3", "chat").finish(context.Background())
+	values := md.Get(telemetry.GPUUsageTrailer)
+	require.Len(t, values, 1)
+
+	var usage pb.GpuUsage
+	require.NoError(t, proto.Unmarshal([]byte(values[0]), &usage))
+	assert.Equal(t, 92.0, usage.Start.UtilizationPercent)
+	assert.Equal(t, 40960.0, usage.End.VramUsedMb)
+	assert.Equal(t, 81920.0, usage.End.VramTotalMb)
+}
+
+func TestUsageRecorder_NoGPU(t *testing.T) {
+	service := &Service{
+		sampler: telemetry.NewSampler(staticGPU{Type: "No GPU detected", VRAMTotal: "N/A", VRAMUsed: "N/A"}),
+	}
+
+	assert.Nil(t, service.startUsage("llama3", "chat").finish(context.Background()))
+	assert.Nil(t, (&Service{}).startUsage("llama3", "chat").finish(context.Background()))
+}
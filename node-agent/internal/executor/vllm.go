@@ -1,13 +1,17 @@
 package executor
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Orchion/Orchion/node-agent/internal/containers"
@@ -17,8 +21,15 @@ import (
 // VLLMExecutor manages vLLM containers and handles inference requests
 type VLLMExecutor struct {
 	containerManager containers.Manager
-	basePort         int            // Starting port for vLLM containers
-	runningPorts     map[string]int // model -> port mapping
+	basePort         int         // Starting port for vLLM containers
+	gpus             []string    // GPU device IDs given to containers
+	nameSuffix       string      // Appended to container names, e.g. "-gpu1"
+	runningPorts     *modelPorts // model -> port mapping
+	distributed      map[string]*distributedInstance
+	transport        http.RoundTripper
+	image            string       // Image vLLM containers are started from
+	imageMu          sync.RWMutex // Guards image, which image pins replace at runtime
+	readinessProbes               // Overrides of how vLLM's readiness is probed
 }
 
 // NewVLLMExecutor creates a new vLLM executor
@@ -26,7 +37,10 @@ func NewVLLMExecutor(manager containers.Manager) *VLLMExecutor {
 	return &VLLMExecutor{
 		containerManager: manager,
 		basePort:         8000, // Default vLLM port
-		runningPorts:     make(map[string]int),
+		gpus:             []string{"all"},
+		runningPorts:     newModelPorts(),
+		distributed:      make(map[string]*distributedInstance),
+		image:            containers.DefaultVLLMImage,
 	}
 }
 
@@ -36,9 +50,11 @@ func (e *VLLMExecutor) StartModel(ctx context.Context, model string) error {
 	config := containers.CreateVLLMContainerConfig(&containers.VLLMConfig{
 		Model:              model,
 		Port:               e.basePort,
-		GPUs:               []string{"all"},
+		GPUs:               e.gpus,
 		TensorParallelSize: 1,
 		MaxModelLen:        4096,
+		NameSuffix:         e.nameSuffix,
+		Image:              e.Image(),
 	})
 
 	// Ensure container is running
@@ -47,12 +63,13 @@ func (e *VLLMExecutor) StartModel(ctx context.Context, model string) error {
 	}
 
 	// Wait for vLLM to be ready
-	if err := e.waitForVLLMReady(ctx, config.Port); err != nil {
+	probe := e.probe(model, defaultVLLMProbe)
+	if err := waitForEngineReady(ctx, "vLLM", model, fmt.Sprintf("http://localhost:%d", config.Port), probe); err != nil {
 		return fmt.Errorf("vLLM container failed to become ready: %w", err)
 	}
 
 	// Track the port
-	e.runningPorts[model] = config.Port
+	e.runningPorts.set(model, config.Port)
 
 	log.Printf("vLLM model %s ready on port %d", model, config.Port)
 	return nil
@@ -61,15 +78,16 @@ func (e *VLLMExecutor) StartModel(ctx context.Context, model string) error {
 // StopModel stops the vLLM container for the specified model
 func (e *VLLMExecutor) StopModel(ctx context.Context, model string) error {
 	config := containers.CreateVLLMContainerConfig(&containers.VLLMConfig{
-		Model: model,
-		Port:  e.basePort,
+		Model:      model,
+		Port:       e.basePort,
+		NameSuffix: e.nameSuffix,
 	})
 
 	if err := e.containerManager.StopContainer(ctx, config.Name); err != nil {
 		return fmt.Errorf("failed to stop vLLM container: %w", err)
 	}
 
-	delete(e.runningPorts, model)
+	e.runningPorts.remove(model)
 	log.Printf("Stopped vLLM container for model %s", model)
 	return nil
 }
@@ -77,23 +95,54 @@ func (e *VLLMExecutor) StopModel(ctx context.Context, model string) error {
 // IsModelRunning checks if the vLLM container is running for the specified model
 func (e *VLLMExecutor) IsModelRunning(ctx context.Context, model string) (bool, error) {
 	config := containers.CreateVLLMContainerConfig(&containers.VLLMConfig{
-		Model: model,
-		Port:  e.basePort,
+		Model:      model,
+		Port:       e.basePort,
+		NameSuffix: e.nameSuffix,
 	})
 	return e.containerManager.IsRunning(ctx, config.Name)
 }
 
+// Image returns the image vLLM containers are started from
+func (e *VLLMExecutor) Image() string {
+	e.imageMu.RLock()
+	defer e.imageMu.RUnlock()
+	return e.image
+}
+
+// SetImage sets the image vLLM containers are started from. Running
+// containers keep their image until they restart.
+func (e *VLLMExecutor) SetImage(image string) {
+	e.imageMu.Lock()
+	defer e.imageMu.Unlock()
+	e.image = image
+}
+
+// EngineInfo identifies the vLLM build serving a model
+func (e *VLLMExecutor) EngineInfo(ctx context.Context, model string) *pb.ModelEngine {
+	image := e.Image()
+	info := &pb.ModelEngine{
+		Model:       model,
+		Engine:      "vllm",
+		Image:       image,
+		ImageDigest: imageDigest(ctx, e.containerManager, image),
+	}
+	if port, ok := e.runningPorts.get(model); ok {
+		info.Version = fetchEngineVersion(ctx, e.transport, fmt.Sprintf("http://localhost:%d/version", port))
+	}
+	return info
+}
+
 // ChatCompletion executes a chat completion request using vLLM
-func (e *VLLMExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan *pb.ChatCompletionResponse, error) {
-	port, exists := e.runningPorts[model]
+func (e *VLLMExecutor) ChatCompletion(ctx context.Context, model string, req *pb.ChatCompletionRequest) (<-chan Event, error) {
+	port, exists := e.runningPorts.get(model)
 	if !exists {
 		return nil, fmt.Errorf("model %s is not running", model)
 	}
 
-	responseChan := make(chan *pb.ChatCompletionResponse, 10)
+	events := make(chan Event, 10)
 
 	go func() {
-		defer close(responseChan)
+		defer close(events)
 
 		// Convert messages to OpenAI format
 		messages := make([]map[string]interface{}, len(req.Messages))
@@ -110,16 +159,22 @@ func (e *VLLMExecutor) ChatCompletion(ctx context.Context, model string, req *pb
 			"messages": messages,
 			"stream":   req.Stream,
 		}
-		if req.Temperature > 0 {
-			openaiReq["temperature"] = req.Temperature
+		setVLLMSampling(openaiReq, req)
+		if req.CacheSalt != "" {
+			// Keep prefix cache entries scoped to the caller
+			openaiReq["cache_salt"] = req.CacheSalt
 		}
-		if req.MaxTokens > 0 {
-			openaiReq["max_tokens"] = req.MaxTokens
+		if req.Stream {
+			// Report token usage, including prefix cache hits, after the last chunk
+			openaiReq["stream_options"] = map[string]interface{}{
+				"include_usage": true,
+			}
 		}
+		applyVLLMOptions(openaiReq, req.EngineOptions)
 
 		reqBody, err := json.Marshal(openaiReq)
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to marshal request")
+			fail(ctx, events, fmt.Errorf("failed to marshal request: %w", err))
 			return
 		}
 
@@ -127,39 +182,37 @@ func (e *VLLMExecutor) ChatCompletion(ctx context.Context, model string, req *pb
 		url := fmt.Sprintf("http://localhost:%d/v1/chat/completions", port)
 		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to create request")
+			fail(ctx, events, fmt.Errorf("failed to create request: %w", err))
 			return
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 
-		client := &http.Client{Timeout: 10 * time.Minute}
+		client := &http.Client{Timeout: 10 * time.Minute, Transport: e.transport}
 		resp, err := client.Do(httpReq)
 		if err != nil {
-			responseChan <- e.createErrorResponse(model, "failed to call vLLM")
+			fail(ctx, events, fmt.Errorf("failed to call vLLM: %w", err))
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			responseChan <- e.createErrorResponse(model, fmt.Sprintf("vLLM returned status %d", resp.StatusCode))
+			fail(ctx, events, fmt.Errorf("vLLM returned status %d", resp.StatusCode))
 			return
 		}
 
 		if req.Stream {
-			// Handle streaming response
-			e.handleVLLMStreamingResponse(resp.Body, model, responseChan)
+			vllmStreamEvents(ctx, resp.Body, events)
 		} else {
-			// Handle non-streaming response
-			e.handleVLLMNonStreamingResponse(resp.Body, model, responseChan)
+			vllmEvents(ctx, resp.Body, events)
 		}
 	}()
 
-	return responseChan, nil
+	return events, nil
 }
 
 // Embeddings executes an embeddings request using vLLM
 func (e *VLLMExecutor) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
-	port, exists := e.runningPorts[model]
+	port, exists := e.runningPorts.get(model)
 	if !exists {
 		return nil, fmt.Errorf("model %s is not running", model)
 	}
@@ -182,7 +235,7 @@ func (e *VLLMExecutor) Embeddings(ctx context.Context, model string, req *pb.Emb
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 5 * time.Minute}
+	client := &http.Client{Timeout: 5 * time.Minute, Transport: e.transport}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call vLLM: %w", err)
@@ -225,157 +278,166 @@ func (e *VLLMExecutor) Embeddings(ctx context.Context, model string, req *pb.Emb
 	}, nil
 }
 
-// waitForVLLMReady waits for vLLM to be ready to accept requests
-func (e *VLLMExecutor) waitForVLLMReady(ctx context.Context, port int) error {
-	url := fmt.Sprintf("http://localhost:%d/v1/models", port)
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	// Try for up to 5 minutes (vLLM can take longer to start)
-	for i := 0; i < 300; i++ {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+// setVLLMSampling sets the generation parameters of a request in an
+// OpenAI-compatible request body; unset parameters are left to vLLM's defaults
+func setVLLMSampling(body map[string]interface{}, req *pb.ChatCompletionRequest) {
+	if req.Temperature > 0 {
+		body["temperature"] = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	if len(req.Stop) > 0 {
+		body["stop"] = req.Stop
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if req.TopK > 0 {
+		body["top_k"] = req.TopK
+	}
+	if req.RepetitionPenalty > 0 {
+		body["repetition_penalty"] = req.RepetitionPenalty
+	}
+	if req.Seed != nil {
+		body["seed"] = *req.Seed
+	}
+}
 
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return err
-		}
+// vllmToolCall is a tool call, or a streamed fragment of one, in a vLLM reply
+type vllmToolCall struct {
+	Index    int32  `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
 
-		resp, err := client.Do(req)
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return nil
-			}
-		}
+// event returns the tool call event
+func (c vllmToolCall) event() Event {
+	return Event{Type: EventToolCall, ToolCall: &pb.ToolCall{
+		Index:     c.Index,
+		Id:        c.ID,
+		Name:      c.Function.Name,
+		Arguments: c.Function.Arguments,
+	}}
+}
 
-		time.Sleep(1 * time.Second)
-	}
+// vllmUsage is the token usage reported by vLLM
+type vllmUsage struct {
+	PromptTokens        int32 `json:"prompt_tokens"`
+	CompletionTokens    int32 `json:"completion_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int32 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
 
-	return fmt.Errorf("timeout waiting for vLLM to be ready")
+// event returns the usage event
+func (u *vllmUsage) event() Event {
+	usage := Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return Event{Type: EventUsage, Usage: usage}
 }
 
-// handleVLLMStreamingResponse processes streaming vLLM responses
-func (e *VLLMExecutor) handleVLLMStreamingResponse(body io.Reader, model string, responseChan chan<- *pb.ChatCompletionResponse) {
-	decoder := json.NewDecoder(body)
+// vllmStreamEvents reports the chunks of a streamed vLLM reply as events. The
+// usage comes in its own chunk after the one with the finish reason, so the
+// stream is read to its end before reporting it done.
+func vllmStreamEvents(ctx context.Context, body io.Reader, events chan<- Event) {
+	finishReason := ""
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
 
-	for {
-		var openaiResp struct {
-			ID      string `json:"id"`
-			Object  string `json:"object"`
-			Created int64  `json:"created"`
-			Model   string `json:"model"`
+		var chunk struct {
 			Choices []struct {
-				Index int `json:"index"`
 				Delta struct {
-					Content string `json:"content"`
+					Content   string         `json:"content"`
+					ToolCalls []vllmToolCall `json:"tool_calls"`
 				} `json:"delta"`
 				FinishReason *string `json:"finish_reason"`
 			} `json:"choices"`
+			Usage *vllmUsage `json:"usage"`
 		}
-
-		if err := decoder.Decode(&openaiResp); err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("Error decoding streaming response: %v", err)
-			continue
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			fail(ctx, events, fmt.Errorf("failed to decode vLLM chunk: %w", err))
+			return
 		}
 
-		if len(openaiResp.Choices) == 0 {
+		if chunk.Usage != nil && !emit(ctx, events, chunk.Usage.event()) {
+			return
+		}
+		if len(chunk.Choices) == 0 {
 			continue
 		}
-
-		choice := openaiResp.Choices[0]
-		finishReason := ""
-		if choice.FinishReason != nil {
-			finishReason = *choice.FinishReason
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" && !emit(ctx, events, Event{Type: EventToken, Content: choice.Delta.Content}) {
+			return
 		}
-
-		responseChan <- &pb.ChatCompletionResponse{
-			Id:     openaiResp.ID,
-			Model:  model,
-			Object: "chat.completion.chunk",
-			Choices: []*pb.ChatChoice{
-				{
-					Index: int32(choice.Index),
-					Message: &pb.ChatMessage{
-						Role:    "assistant",
-						Content: choice.Delta.Content,
-					},
-					FinishReason: finishReason,
-				},
-			},
-			Created: openaiResp.Created,
+		for _, call := range choice.Delta.ToolCalls {
+			if !emit(ctx, events, call.event()) {
+				return
+			}
 		}
-
-		// Check if this is the final message
-		if finishReason != "" {
-			break
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		fail(ctx, events, fmt.Errorf("failed to read vLLM stream: %w", err))
+		return
+	}
+	if finishReason == "" {
+		fail(ctx, events, errors.New("vLLM stream ended without a finish reason"))
+		return
+	}
+	emit(ctx, events, Event{Type: EventDone, FinishReason: finishReason})
 }
 
-// handleVLLMNonStreamingResponse processes non-streaming vLLM responses
-func (e *VLLMExecutor) handleVLLMNonStreamingResponse(body io.Reader, model string, responseChan chan<- *pb.ChatCompletionResponse) {
-	var openaiResp struct {
-		ID      string `json:"id"`
-		Object  string `json:"object"`
-		Created int64  `json:"created"`
-		Model   string `json:"model"`
+// vllmEvents reports a non-streamed vLLM reply as events
+func vllmEvents(ctx context.Context, body io.Reader, events chan<- Event) {
+	var reply struct {
 		Choices []struct {
-			Index   int `json:"index"`
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Content   string         `json:"content"`
+				ToolCalls []vllmToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
+		Usage *vllmUsage `json:"usage"`
 	}
-
-	if err := json.NewDecoder(body).Decode(&openaiResp); err != nil {
-		responseChan <- e.createErrorResponse(model, "failed to decode response")
+	if err := json.NewDecoder(body).Decode(&reply); err != nil {
+		fail(ctx, events, fmt.Errorf("failed to decode vLLM response: %w", err))
 		return
 	}
-
-	if len(openaiResp.Choices) == 0 {
-		responseChan <- e.createErrorResponse(model, "no choices in response")
+	if len(reply.Choices) == 0 {
+		fail(ctx, events, errors.New("no choices in vLLM response"))
 		return
 	}
 
-	choice := openaiResp.Choices[0]
-	responseChan <- &pb.ChatCompletionResponse{
-		Id:     openaiResp.ID,
-		Model:  model,
-		Object: "chat.completion",
-		Choices: []*pb.ChatChoice{
-			{
-				Index: int32(choice.Index),
-				Message: &pb.ChatMessage{
-					Role:    choice.Message.Role,
-					Content: choice.Message.Content,
-				},
-				FinishReason: choice.FinishReason,
-			},
-		},
-		Created: openaiResp.Created,
+	choice := reply.Choices[0]
+	reported := []Event{{Type: EventToken, Content: choice.Message.Content}}
+	for i, call := range choice.Message.ToolCalls {
+		call.Index = int32(i)
+		reported = append(reported, call.event())
 	}
-}
-
-// createErrorResponse creates an error response
-func (e *VLLMExecutor) createErrorResponse(model, message string) *pb.ChatCompletionResponse {
-	return &pb.ChatCompletionResponse{
-		Id:      e.generateID(),
-		Model:   model,
-		Object:  "error",
-		Choices: []*pb.ChatChoice{{FinishReason: "error"}},
-		Created: time.Now().Unix(),
+	if reply.Usage != nil {
+		reported = append(reported, reply.Usage.event())
+	}
+	reported = append(reported, Event{Type: EventDone, FinishReason: choice.FinishReason})
+	for _, event := range reported {
+		if !emit(ctx, events, event) {
+			return
+		}
 	}
-}
-
-// generateID generates a unique ID for responses
-func (e *VLLMExecutor) generateID() string {
-	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
 }
@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectEvents runs an event reporter and returns what it reported
+func collectEvents(report func(ctx context.Context, events chan<- Event)) []Event {
+	events := make(chan Event, 100)
+	report(context.Background(), events)
+	close(events)
+
+	var collected []Event
+	for event := range events {
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestVLLMEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		prompt int32
+		cached int32
+	}{
+		{
+			name:   "prefix cache hit",
+			body:   `{"id":"1","choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":120,"completion_tokens":2,"prompt_tokens_details":{"cached_tokens":96}}}`,
+			prompt: 120,
+			cached: 96,
+		},
+		{
+			name:   "no cache details",
+			body:   `{"id":"1","choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":120}}`,
+			prompt: 120,
+		},
+		{
+			name: "no usage",
+			body: `{"id":"1","choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := collectEvents(func(ctx context.Context, events chan<- Event) {
+				vllmEvents(ctx, strings.NewReader(tt.body), events)
+			})
+
+			chat := &chatStream{}
+			var usage Usage
+			for _, event := range events {
+				_, err := chat.add(event)
+				require.NoError(t, err)
+				if event.Type == EventUsage {
+					usage = event.Usage
+				}
+			}
+			assert.Equal(t, "Hi", chat.content.String())
+			assert.Equal(t, tt.prompt, usage.PromptTokens)
+			assert.Equal(t, tt.cached, usage.CachedTokens)
+			assert.Equal(t, Event{Type: EventDone, FinishReason: "stop"}, events[len(events)-1])
+		})
+	}
+
+	t.Run("no choices", func(t *testing.T) {
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			vllmEvents(ctx, strings.NewReader(`{"id":"1","choices":[]}`), events)
+		})
+		require.Len(t, events, 1)
+		assert.Equal(t, EventDone, events[0].Type)
+		assert.Error(t, events[0].Err)
+	})
+}
+
+func TestVLLMStreamEvents(t *testing.T) {
+	t.Run("usage after the finish reason", func(t *testing.T) {
+		body := `data: {"choices":[{"delta":{"role":"assistant","content":""},"finish_reason":null}]}
+
+data: {"choices":[{"delta":{"content":"Hel"},"finish_reason":null}]}
+
+data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"length"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2,"prompt_tokens_details":{"cached_tokens":8}}}
+
+data: [DONE]
+
+`
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			vllmStreamEvents(ctx, strings.NewReader(body), events)
+		})
+		assert.Equal(t, []Event{
+			{Type: EventToken, Content: "Hel"},
+			{Type: EventToken, Content: "lo"},
+			{Type: EventUsage, Usage: Usage{PromptTokens: 12, CompletionTokens: 2, CachedTokens: 8}},
+			{Type: EventDone, FinishReason: "length"},
+		}, events)
+	})
+
+	t.Run("tool call fragments", func(t *testing.T) {
+		body := `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"get_weather","arguments":""}}]},"finish_reason":null}]}
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]},"finish_reason":null}]}
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Oslo\"}"}}]},"finish_reason":"tool_calls"}]}
+data: [DONE]
+`
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			vllmStreamEvents(ctx, strings.NewReader(body), events)
+		})
+		require.Len(t, events, 4)
+		assert.Equal(t, "get_weather", events[0].ToolCall.Name)
+		assert.Equal(t, "call_1", events[0].ToolCall.Id)
+		assert.Equal(t, `"Oslo"}`, events[2].ToolCall.Arguments)
+		assert.Equal(t, Event{Type: EventDone, FinishReason: "tool_calls"}, events[3])
+	})
+
+	t.Run("cut off stream", func(t *testing.T) {
+		events := collectEvents(func(ctx context.Context, events chan<- Event) {
+			vllmStreamEvents(ctx, strings.NewReader(`data: {"choices":[{"delta":{"content":"Hel"},"finish_reason":null}]}`+"\n"), events)
+		})
+		require.Len(t, events, 2)
+		assert.Equal(t, EventDone, events[1].Type)
+		assert.Error(t, events[1].Err)
+	})
+}
@@ -0,0 +1,134 @@
+package executor
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// DefaultWarmInterval is how often warm pool models are checked and kept
+// resident, well within Ollama's default five minute keep-alive
+const DefaultWarmInterval = time.Minute
+
+// warmProbe is the input of the embedding that keeps warm models resident
+const warmProbe = "warm"
+
+// ParseWarmModels parses a comma-separated list of models to keep warm, e.g.
+// "nomic-embed-text,all-minilm"
+func ParseWarmModels(s string) []string {
+	var models []string
+	for _, model := range strings.Split(s, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// warmPool tracks the embedding models a node keeps loaded. Requests for a
+// ready model go straight to its engine, skipping the service lock and the
+// engine check of the model start path.
+type warmPool struct {
+	mu     sync.RWMutex
+	models map[string]bool     // Models in the pool
+	ready  map[string]Executor // Pool models whose last probe succeeded
+}
+
+// StartWarmPool keeps the given embedding models loaded, starting them now
+// and probing them every interval with a one-word embedding, which restarts
+// models that stopped and keeps Ollama from unloading idle ones. Pool models
+// are never evicted or unloaded. Must be called before serving requests.
+func (s *Service) StartWarmPool(ctx context.Context, models []string, interval time.Duration) {
+	s.warm = &warmPool{
+		models: make(map[string]bool, len(models)),
+		ready:  make(map[string]Executor, len(models)),
+	}
+	for _, model := range models {
+		s.warm.models[model] = true
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for _, model := range models {
+				s.warmModel(ctx, model)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// warmModel starts a pool model if needed and probes it, marking it ready
+// for the fast path if the probe succeeds
+func (s *Service) warmModel(ctx context.Context, model string) {
+	if err := s.ensureModelRunning(ctx, model); err != nil {
+		log.Printf("Failed to start warm model %s: %v", model, err)
+		s.warm.cool(model)
+		return
+	}
+	executor, err := s.getExecutorForModel(model)
+	if err != nil {
+		log.Printf("No executor for warm model %s: %v", model, err)
+		return
+	}
+	if _, err := executor.Embeddings(ctx, model, &pb.EmbeddingRequest{Model: model, Input: []string{warmProbe}}); err != nil {
+		log.Printf("Warm model %s failed its probe: %v", model, err)
+		s.warm.cool(model)
+		s.setModelState(model, pb.ModelState_MODEL_STATE_DEGRADED, err)
+		return
+	}
+	s.setModelState(model, pb.ModelState_MODEL_STATE_READY, nil)
+
+	s.warm.mu.Lock()
+	defer s.warm.mu.Unlock()
+	if _, ok := s.warm.ready[model]; !ok {
+		log.Printf("Warm model %s ready", model)
+	}
+	s.warm.ready[model] = executor
+}
+
+// executor returns the executor of a ready pool model
+func (p *warmPool) executor(model string) (Executor, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	executor, ok := p.ready[model]
+	return executor, ok
+}
+
+// cool takes a model off the fast path until its next successful probe
+func (p *warmPool) cool(model string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.ready, model)
+}
+
+// coolAll takes every model off the fast path, e.g. after they were stopped
+func (p *warmPool) coolAll() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ready = make(map[string]Executor)
+}
+
+// pinned reports whether a model is in the pool and must stay loaded
+func (p *warmPool) pinned(model string) bool {
+	return p != nil && p.models[model]
+}
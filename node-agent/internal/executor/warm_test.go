@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// embeddingEngine is a fake engine serving embeddings that counts engine checks
+type embeddingEngine struct {
+	*fakeEngine
+	checks int
+	fail   bool
+}
+
+func (e *embeddingEngine) IsModelRunning(ctx context.Context, model string) (bool, error) {
+	e.checks++
+	return e.fakeEngine.IsModelRunning(ctx, model)
+}
+
+func (e *embeddingEngine) Embeddings(ctx context.Context, model string, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	if e.fail {
+		return nil, errors.New("connection refused")
+	}
+	return &pb.EmbeddingResponse{Model: model, Data: []*pb.Embedding{{Embedding: []float32{0.1, 0.2}}}}, nil
+}
+
+func TestService_WarmPool(t *testing.T) {
+	ctx := context.Background()
+	engine := &embeddingEngine{fakeEngine: newFakeEngine(2)}
+	service := &Service{
+		executors:     map[string]Executor{"ollama": engine},
+		runningModels: make(map[string]*ModelInstance),
+		eviction:      EvictLRU,
+		warm:          &warmPool{models: map[string]bool{"nomic-embed-text": true}, ready: make(map[string]Executor)},
+	}
+
+	service.warmModel(ctx, "nomic-embed-text")
+	require.True(t, engine.loaded["nomic-embed-text"])
+	_, ready := service.warm.executor("nomic-embed-text")
+	require.True(t, ready)
+
+	t.Run("ready models skip the engine check", func(t *testing.T) {
+		checks := engine.checks
+		resp, err := service.Embeddings(ctx, &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hello"}})
+		require.NoError(t, err)
+		assert.Len(t, resp.Data, 1)
+		assert.Equal(t, checks, engine.checks)
+	})
+
+	t.Run("warm models are neither evicted nor unloaded", func(t *testing.T) {
+		require.NoError(t, service.ensureModelRunning(ctx, "llama3"))
+		require.NoError(t, service.ensureModelRunning(ctx, "phi3"))
+		assert.Equal(t, []string{"llama3"}, engine.stopped)
+
+		_, err := service.UnloadModel(ctx, &pb.UnloadModelRequest{Model: "nomic-embed-text"})
+		require.NoError(t, err)
+		assert.True(t, engine.loaded["nomic-embed-text"])
+	})
+
+	t.Run("failed requests leave the fast path until the next probe", func(t *testing.T) {
+		engine.fail = true
+		_, err := service.Embeddings(ctx, &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hello"}})
+		require.Error(t, err)
+		_, ready := service.warm.executor("nomic-embed-text")
+		assert.False(t, ready)
+
+		engine.fail = false
+		service.warmModel(ctx, "nomic-embed-text")
+		_, ready = service.warm.executor("nomic-embed-text")
+		assert.True(t, ready)
+	})
+}
+
+func TestParseWarmModels(t *testing.T) {
+	assert.Equal(t, []string{"nomic-embed-text", "all-minilm"}, ParseWarmModels(" nomic-embed-text, all-minilm ,"))
+	assert.Empty(t, ParseWarmModels(""))
+}
@@ -0,0 +1,77 @@
+package heartbeat
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/Orchion/Orchion/shared/tailnet"
+)
+
+// TailnetAuto is the -tailnet-address setting detecting the agent's tailnet
+// address
+const TailnetAuto = "auto"
+
+// detectTailnet lists this host's tailnet addresses, replaced in tests
+var detectTailnet = tailnet.Detect
+
+// OutboundIP returns the local IP address used to reach the orchestrator,
+// i.e. the address of the interface the OS routes orchestrator traffic over.
+// No packets are sent.
+func OutboundIP(orchestratorAddr string) (string, error) {
+	conn, err := net.Dial("udp", orchestratorAddr)
+	if err != nil {
+		return "", fmt.Errorf("failed to find route to orchestrator %s: %w", orchestratorAddr, err)
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP.IsUnspecified() {
+		return "", fmt.Errorf("no outbound address for orchestrator %s", orchestratorAddr)
+	}
+	return addr.IP.String(), nil
+}
+
+// AgentAddress builds the address the orchestrator reaches the agent at.
+// advertise may be a host, an IP or a host:port; without a port, port is
+// used. An empty advertise falls back to fallbackHost.
+func AgentAddress(advertise, fallbackHost, port string) (string, error) {
+	if advertise == "" {
+		return net.JoinHostPort(fallbackHost, port), nil
+	}
+
+	if host, p, err := net.SplitHostPort(advertise); err == nil {
+		if host == "" || p == "" {
+			return "", fmt.Errorf("invalid advertise address %q (want host or host:port)", advertise)
+		}
+		return advertise, nil
+	}
+	return net.JoinHostPort(advertise, port), nil
+}
+
+// TailnetAddress builds the address the orchestrator reaches the agent at
+// over a Tailscale or WireGuard network. setting is TailnetAuto to use the
+// first tailnet address the agent listens on, given the host it binds to,
+// or a host or host:port as for AgentAddress. An empty setting, or no
+// tailnet to detect, reports no tailnet address.
+func TailnetAddress(setting, bind, port string) (string, error) {
+	switch setting {
+	case "":
+		return "", nil
+	case TailnetAuto:
+	default:
+		return AgentAddress(setting, "", port)
+	}
+
+	found, err := detectTailnet()
+	if err != nil {
+		return "", err
+	}
+	bound, err := netip.ParseAddr(bind)
+	for _, addr := range found {
+		if bind == "" || (err == nil && (bound.IsUnspecified() || bound == addr.IP)) {
+			return net.JoinHostPort(addr.IP.String(), port), nil
+		}
+	}
+	return "", nil
+}
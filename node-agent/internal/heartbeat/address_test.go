@@ -0,0 +1,98 @@
+package heartbeat
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/shared/tailnet"
+)
+
+func TestAgentAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		advertise string
+		want      string
+		wantErr   bool
+	}{
+		{name: "fallback host", advertise: "", want: "gpu-box:50052"},
+		{name: "IP", advertise: "192.168.1.20", want: "192.168.1.20:50052"},
+		{name: "host", advertise: "agent.example.com", want: "agent.example.com:50052"},
+		{name: "host and port", advertise: "203.0.113.7:6000", want: "203.0.113.7:6000"},
+		{name: "IPv6", advertise: "fd00::20", want: "[fd00::20]:50052"},
+		{name: "port only", advertise: ":6000", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AgentAddress(tt.advertise, "gpu-box", "50052")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOutboundIP(t *testing.T) {
+	ip, err := OutboundIP("127.0.0.1:50051")
+	require.NoError(t, err)
+	assert.True(t, net.ParseIP(ip).IsLoopback(), ip)
+
+	_, err = OutboundIP("not an address")
+	assert.Error(t, err)
+}
+
+func TestTailnetAddress(t *testing.T) {
+	original := detectTailnet
+	t.Cleanup(func() { detectTailnet = original })
+	detectTailnet = func() ([]tailnet.Address, error) {
+		return []tailnet.Address{
+			{Interface: "tailscale0", IP: netip.MustParseAddr("100.101.102.103"), Kind: tailnet.KindTailscale},
+			{Interface: "wg0", IP: netip.MustParseAddr("10.8.0.2"), Kind: tailnet.KindWireGuard},
+		}, nil
+	}
+
+	tests := []struct {
+		name    string
+		setting string
+		bind    string
+		want    string
+		wantErr bool
+	}{
+		{name: "disabled", setting: "", want: ""},
+		{name: "detected", setting: TailnetAuto, want: "100.101.102.103:50052"},
+		{name: "detected on all interfaces", setting: TailnetAuto, bind: "0.0.0.0", want: "100.101.102.103:50052"},
+		{name: "detected where bound", setting: TailnetAuto, bind: "10.8.0.2", want: "10.8.0.2:50052"},
+		{name: "bound off the tailnet", setting: TailnetAuto, bind: "192.168.1.20", want: ""},
+		{name: "given", setting: "gpu-box.tail1234.ts.net", want: "gpu-box.tail1234.ts.net:50052"},
+		{name: "given with port", setting: "100.64.0.9:6000", want: "100.64.0.9:6000"},
+		{name: "port only", setting: ":6000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TailnetAddress(tt.setting, tt.bind, "50052")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	detectTailnet = func() ([]tailnet.Address, error) { return nil, nil }
+	got, err := TailnetAddress(TailnetAuto, "", "50052")
+	require.NoError(t, err)
+	assert.Empty(t, got, "not on a tailnet")
+
+	detectTailnet = func() ([]tailnet.Address, error) { return nil, errors.New("permission denied") }
+	_, err = TailnetAddress(TailnetAuto, "", "50052")
+	assert.Error(t, err)
+}
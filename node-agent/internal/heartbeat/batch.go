@@ -0,0 +1,269 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// Labels of logical GPU nodes: the nvidia-smi index of their GPU and, for MIG
+// slices, the MIG profile
+const (
+	GPULabel = "gpu"
+	MIGLabel = "mig"
+)
+
+// Batch heartbeats the logical nodes one agent serves, e.g. one per GPU, with
+// a single BatchHeartbeat call per interval. Capabilities and loaded models
+// ride along on the heartbeats of nodes where they changed, so no separate
+// update calls are made.
+type Batch struct {
+	client      pb.OrchestratorClient
+	nodes       []*batchNode
+	capsUpdater func() []*pb.Capabilities             // Capabilities of each node, in node order
+	models      func(nodeID string) []*pb.ModelEngine // Models loaded for a node, if reported
+	throttle    func(nodeID string) *pb.NodeThrottle  // Throttling state of a node, if reported
+	imagePins   func([]*pb.ImagePin)                  // Applies the orchestrator's image pins, if enabled
+	sharing     func(*pb.GpuSharing)                  // Applies the orchestrator's GPU sharing policy, if enabled
+	interrupted []*pb.InterruptedRequest              // Requests to report as interrupted at the next registration
+	link        *linkMeter                            // Measures the link to the orchestrator during heartbeats
+	thresholds  ChangeThresholds
+	fullRefresh time.Duration
+}
+
+// batchNode is a logical node and the capabilities last acknowledged for it
+type batchNode struct {
+	info         *pb.Node
+	lastCaps     *pb.Capabilities
+	lastModels   []*pb.ModelEngine
+	lastCapsSync time.Time
+	config       *pb.NodeConfig // Config the orchestrator returned with the last registration
+}
+
+// GPUNodes returns one logical node per GPU device, derived from the agent's
+// node. They share its agent address; IDs are suffixed with the device name,
+// e.g. -gpu0 or -gpu0-mig1.
+func GPUNodes(node *pb.Node, devices []capabilities.GPUDevice) []*pb.Node {
+	perDevice := capabilities.DeviceCapabilities(node.Capabilities, devices)
+	nodes := make([]*pb.Node, 0, len(devices))
+	for i, device := range devices {
+		labels := make(map[string]string, len(node.Labels)+2)
+		for k, v := range node.Labels {
+			labels[k] = v
+		}
+		labels[GPULabel] = strconv.Itoa(device.GPU)
+		if device.MIGProfile != "" {
+			labels[MIGLabel] = device.MIGProfile
+		}
+
+		nodes = append(nodes, &pb.Node{
+			Id:              node.Id + "-" + device.Name,
+			Hostname:        node.Hostname,
+			Capabilities:    perDevice[i],
+			LastSeenUnix:    node.LastSeenUnix,
+			AgentAddress:    node.AgentAddress,
+			Labels:          labels,
+			Tunneled:        node.Tunneled,
+			SupportedModels: node.SupportedModels,
+		})
+	}
+	return nodes
+}
+
+// RegisterBatch registers the logical nodes of a multi-node agent and returns
+// a Batch heartbeating them over the client's connection
+func (c *Client) RegisterBatch(ctx context.Context, nodes []*pb.Node) (*Batch, error) {
+	b := &Batch{
+		client:      c.client,
+		thresholds:  c.thresholds,
+		fullRefresh: c.fullRefresh,
+		interrupted: c.interrupted,
+		link:        c.link,
+	}
+	for _, node := range nodes {
+		n := &batchNode{info: node}
+		if err := b.register(ctx, n); err != nil {
+			return nil, err
+		}
+		b.nodes = append(b.nodes, n)
+	}
+	c.interrupted = nil
+	return b, nil
+}
+
+// register registers a node, or registers it again after the orchestrator
+// forgot it
+func (b *Batch) register(ctx context.Context, n *batchNode) error {
+	n.info.LastSeenUnix = time.Now().Unix()
+	req := &pb.RegisterNodeRequest{Node: n.info, InterruptedRequests: b.interrupted}
+	resp, err := b.client.RegisterNode(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to register node %s: %w", n.info.Id, err)
+	}
+	b.interrupted = nil
+	n.config = resp.GetConfig()
+	n.lastCaps = n.info.Capabilities
+	n.lastModels = n.info.Models
+	n.lastCapsSync = time.Now()
+	return nil
+}
+
+// EnableCapabilityUpdates sends each node's capabilities, as returned by
+// updater in node order, with its heartbeat whenever they change
+func (b *Batch) EnableCapabilityUpdates(updater func() []*pb.Capabilities) {
+	b.capsUpdater = updater
+}
+
+// EnableModelReporting sends the models loaded for each node, and the engines
+// serving them, along with its capability updates whenever they change
+func (b *Batch) EnableModelReporting(models func(nodeID string) []*pb.ModelEngine) {
+	b.models = models
+}
+
+// EnableThrottleReporting sends each node's throttling state with its
+// heartbeat
+func (b *Batch) EnableThrottleReporting(throttle func(nodeID string) *pb.NodeThrottle) {
+	b.throttle = throttle
+}
+
+// EnableImagePinning hands the engine images the orchestrator pins
+// fleet-wide to apply after every batched heartbeat
+func (b *Batch) EnableImagePinning(apply func(pins []*pb.ImagePin)) {
+	b.imagePins = apply
+}
+
+// EnableGPUSharing hands the GPU sharing policy in effect, nil if none, to
+// apply after every batched heartbeat
+func (b *Batch) EnableGPUSharing(apply func(sharing *pb.GpuSharing)) {
+	b.sharing = apply
+}
+
+// SetCapabilityThresholds configures how much capabilities must change before
+// an update is sent, and how often a full refresh is sent regardless
+func (b *Batch) SetCapabilityThresholds(thresholds ChangeThresholds, fullRefresh time.Duration) {
+	b.thresholds = thresholds
+	b.fullRefresh = fullRefresh
+}
+
+// Send sends the heartbeats of all nodes in one call, including capabilities
+// and models for nodes where they changed, then registers again any node the
+// orchestrator reports unknown
+func (b *Batch) Send(ctx context.Context) error {
+	var caps []*pb.Capabilities
+	if b.capsUpdater != nil {
+		caps = b.capsUpdater()
+	}
+	models := make([][]*pb.ModelEngine, len(b.nodes))
+	if b.models != nil {
+		for i, n := range b.nodes {
+			models[i] = b.models(n.info.Id)
+		}
+	}
+
+	req := &pb.BatchHeartbeatRequest{}
+	updated := make([]bool, len(b.nodes))
+	for i, n := range b.nodes {
+		hb := &pb.NodeHeartbeat{NodeId: n.info.Id}
+		if b.throttle != nil {
+			hb.Throttle = b.throttle(n.info.Id)
+		}
+		// A GPU that disappeared leaves its node without capabilities
+		if i < len(caps) && n.changed(caps[i], models[i], b.thresholds, b.fullRefresh) {
+			hb.Capabilities = caps[i]
+			hb.Models = models[i]
+			updated[i] = true
+		}
+		req.Heartbeats = append(req.Heartbeats, hb)
+	}
+
+	var resp *pb.BatchHeartbeatResponse
+	err := b.link.measure(func(stats *pb.NetworkStats, probe []byte) (err error) {
+		req.Network, req.BandwidthProbe = stats, probe
+		resp, err = b.client.BatchHeartbeat(ctx, req)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send batch heartbeat: %w", err)
+	}
+	if b.imagePins != nil {
+		b.imagePins(resp.ImagePins)
+	}
+	if b.sharing != nil {
+		b.sharing(resp.Sharing)
+	}
+
+	unknown := make(map[string]bool, len(resp.UnknownNodeIds))
+	for _, id := range resp.UnknownNodeIds {
+		unknown[id] = true
+	}
+	var regErr error
+	now := time.Now()
+	for i, n := range b.nodes {
+		switch {
+		case unknown[n.info.Id]:
+			log.Printf("Node %s not found in registry, attempting re-registration...", n.info.Id)
+			if i < len(caps) {
+				n.info.Capabilities = caps[i]
+			}
+			n.info.Models = models[i]
+			if err := b.register(ctx, n); err != nil {
+				regErr = err
+			}
+		case updated[i]:
+			n.lastCaps = caps[i]
+			n.lastModels = models[i]
+			n.lastCapsSync = now
+		}
+	}
+	return regErr
+}
+
+// changed reports whether a node's capabilities or models must be sent
+func (n *batchNode) changed(caps *pb.Capabilities, models []*pb.ModelEngine, th ChangeThresholds, fullRefresh time.Duration) bool {
+	return n.lastCaps == nil || time.Since(n.lastCapsSync) >= fullRefresh ||
+		capabilitiesChanged(n.lastCaps, caps, th) || modelsChanged(n.lastModels, models)
+}
+
+// StartLoop starts a goroutine that sends the batched heartbeats periodically
+func (b *Batch) StartLoop(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.Send(ctx); err != nil {
+					log.Printf("Batch heartbeat error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Configs returns the config the orchestrator's node profiles set for each
+// node at its last registration, in registration order; nil where no profile
+// matched
+func (b *Batch) Configs() []*pb.NodeConfig {
+	configs := make([]*pb.NodeConfig, len(b.nodes))
+	for i, n := range b.nodes {
+		configs[i] = n.config
+	}
+	return configs
+}
+
+// NodeIDs returns the IDs of the batch's nodes, in registration order
+func (b *Batch) NodeIDs() []string {
+	ids := make([]string, len(b.nodes))
+	for i, n := range b.nodes {
+		ids[i] = n.info.Id
+	}
+	return ids
+}
@@ -0,0 +1,126 @@
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestGPUNodes(t *testing.T) {
+	node := &pb.Node{
+		Id:              "box",
+		Hostname:        "box.lan",
+		AgentAddress:    "10.0.0.5:50052",
+		Labels:          map[string]string{"zone": "home"},
+		SupportedModels: []string{"llama3*"},
+	}
+	devices := []capabilities.GPUDevice{
+		{Name: "gpu0-mig0", GPU: 0, MIGProfile: "1g.5gb", Info: capabilities.GPUInfo{Type: "NVIDIA A100 MIG 1g.5gb"}},
+		{Name: "gpu1", GPU: 1, Info: capabilities.GPUInfo{Type: "NVIDIA T4"}},
+	}
+
+	nodes := GPUNodes(node, devices)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "box-gpu0-mig0", nodes[0].Id)
+	assert.Equal(t, map[string]string{"zone": "home", "gpu": "0", "mig": "1g.5gb"}, nodes[0].Labels)
+	assert.Equal(t, "box-gpu1", nodes[1].Id)
+	assert.Equal(t, "NVIDIA T4", nodes[1].Capabilities.GpuType)
+	assert.Equal(t, map[string]string{"zone": "home", "gpu": "1"}, nodes[1].Labels)
+	assert.Equal(t, "10.0.0.5:50052", nodes[1].AgentAddress)
+	assert.Equal(t, []string{"llama3*"}, nodes[1].SupportedModels)
+	// The agent's own labels are left alone
+	assert.Equal(t, map[string]string{"zone": "home"}, node.Labels)
+}
+
+func TestBatch_Send(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	mockClient.On("RegisterNode", mock.Anything, mock.Anything).Return(&pb.RegisterNodeResponse{}, nil)
+	client := &Client{client: mockClient, thresholds: DefaultChangeThresholds, fullRefresh: time.Hour}
+	client.ReportInterruptedRequests([]*pb.InterruptedRequest{{JobId: "job-1", NodeId: "box-gpu1"}})
+
+	devices := []capabilities.GPUDevice{
+		{Name: "gpu0", Info: capabilities.GPUInfo{VRAMAvailable: "20.0 GB"}},
+		{Name: "gpu1", GPU: 1, Info: capabilities.GPUInfo{VRAMAvailable: "20.0 GB"}},
+	}
+	node := &pb.Node{Id: "box", Hostname: "box", Capabilities: &pb.Capabilities{Cpu: "8 cores"}}
+	batch, err := client.RegisterBatch(context.Background(), GPUNodes(node, devices))
+	require.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "RegisterNode", 2)
+
+	// Interrupted requests are reported once, with the first node
+	first := mockClient.Calls[0].Arguments.Get(1).(*pb.RegisterNodeRequest)
+	require.Len(t, first.InterruptedRequests, 1)
+	assert.Equal(t, "box-gpu1", first.InterruptedRequests[0].NodeId)
+	assert.Empty(t, mockClient.Calls[1].Arguments.Get(1).(*pb.RegisterNodeRequest).InterruptedRequests)
+
+	current := []*pb.Capabilities{
+		{Cpu: "8 cores", GpuVramAvailable: "20.0 GB"},
+		{Cpu: "8 cores", GpuVramAvailable: "4.0 GB"},
+	}
+	batch.EnableCapabilityUpdates(func() []*pb.Capabilities { return current })
+
+	t.Run("sends changed capabilities with the heartbeat", func(t *testing.T) {
+		mockClient.On("BatchHeartbeat", mock.Anything, mock.Anything).Return(&pb.BatchHeartbeatResponse{}, nil).Once()
+		require.NoError(t, batch.Send(context.Background()))
+
+		req := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*pb.BatchHeartbeatRequest)
+		require.Len(t, req.Heartbeats, 2)
+		assert.Equal(t, "box-gpu0", req.Heartbeats[0].NodeId)
+		assert.Nil(t, req.Heartbeats[0].Capabilities)
+		assert.Equal(t, "4.0 GB", req.Heartbeats[1].Capabilities.GpuVramAvailable)
+	})
+
+	t.Run("reports each node's throttling state", func(t *testing.T) {
+		batch.EnableThrottleReporting(func(nodeID string) *pb.NodeThrottle {
+			if nodeID == "box-gpu1" {
+				return &pb.NodeThrottle{Reason: "GPU power 310.0 W over 300 W limit"}
+			}
+			return nil
+		})
+		defer batch.EnableThrottleReporting(nil)
+
+		mockClient.On("BatchHeartbeat", mock.Anything, mock.Anything).Return(&pb.BatchHeartbeatResponse{}, nil).Once()
+		require.NoError(t, batch.Send(context.Background()))
+
+		req := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*pb.BatchHeartbeatRequest)
+		assert.Nil(t, req.Heartbeats[0].Throttle)
+		assert.Equal(t, "GPU power 310.0 W over 300 W limit", req.Heartbeats[1].Throttle.GetReason())
+	})
+
+	t.Run("applies image pins", func(t *testing.T) {
+		var applied []*pb.ImagePin
+		batch.EnableImagePinning(func(pins []*pb.ImagePin) { applied = pins })
+		defer batch.EnableImagePinning(nil)
+
+		pins := []*pb.ImagePin{{Image: "vllm/vllm-openai", Digest: "sha256:aaa"}}
+		mockClient.On("BatchHeartbeat", mock.Anything, mock.Anything).Return(&pb.BatchHeartbeatResponse{ImagePins: pins}, nil).Once()
+		require.NoError(t, batch.Send(context.Background()))
+		assert.Equal(t, pins, applied)
+	})
+
+	t.Run("acknowledged capabilities aren't sent again", func(t *testing.T) {
+		mockClient.On("BatchHeartbeat", mock.Anything, mock.Anything).Return(&pb.BatchHeartbeatResponse{}, nil).Once()
+		require.NoError(t, batch.Send(context.Background()))
+
+		req := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*pb.BatchHeartbeatRequest)
+		assert.Nil(t, req.Heartbeats[1].Capabilities)
+	})
+
+	t.Run("registers unknown nodes again", func(t *testing.T) {
+		mockClient.On("BatchHeartbeat", mock.Anything, mock.Anything).
+			Return(&pb.BatchHeartbeatResponse{UnknownNodeIds: []string{"box-gpu1"}}, nil).Once()
+		require.NoError(t, batch.Send(context.Background()))
+
+		mockClient.AssertNumberOfCalls(t, "RegisterNode", 3)
+		req := mockClient.Calls[len(mockClient.Calls)-1].Arguments.Get(1).(*pb.RegisterNodeRequest)
+		assert.Equal(t, "box-gpu1", req.Node.Id)
+		assert.Equal(t, "4.0 GB", req.Node.Capabilities.GpuVramAvailable)
+	})
+}
@@ -0,0 +1,76 @@
+package heartbeat
+
+import (
+	"math"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// ChangeThresholds controls how much fluctuating capability values must change
+// before an update is sent to the orchestrator
+type ChangeThresholds struct {
+	VRAMMB       float64 // Minimum VRAM change in megabytes
+	TemperatureC float64 // Minimum GPU temperature change in degrees Celsius
+	PowerW       float64 // Minimum power draw change in watts
+}
+
+// DefaultChangeThresholds are used unless overridden with SetCapabilityThresholds
+var DefaultChangeThresholds = ChangeThresholds{
+	VRAMMB:       256,
+	TemperatureC: 5,
+	PowerW:       25,
+}
+
+// capabilitiesChanged reports whether next differs meaningfully from prev.
+// Static values (CPU, memory, OS, GPU model, total VRAM) must match exactly,
+// while fluctuating readings only count once they move beyond the thresholds.
+func capabilitiesChanged(prev, next *pb.Capabilities, th ChangeThresholds) bool {
+	if prev == nil || next == nil {
+		return prev != next
+	}
+
+	if prev.Cpu != next.Cpu ||
+		prev.Memory != next.Memory ||
+		prev.Os != next.Os ||
+		prev.GpuType != next.GpuType ||
+		prev.GpuVramTotal != next.GpuVramTotal {
+		return true
+	}
+
+	return valueChanged(prev.GpuVramAvailable, next.GpuVramAvailable, th.VRAMMB, units.ParseMegabytes) ||
+		valueChanged(prev.GpuVramUsed, next.GpuVramUsed, th.VRAMMB, units.ParseMegabytes) ||
+		valueChanged(prev.GpuTemperature, next.GpuTemperature, th.TemperatureC, units.ParseNumber) ||
+		valueChanged(prev.GpuPowerUsage, next.GpuPowerUsage, th.PowerW, units.ParseNumber) ||
+		valueChanged(prev.PowerUsage, next.PowerUsage, th.PowerW, units.ParseNumber)
+}
+
+// valueChanged compares two readings numerically when both parse, and falls
+// back to an exact string comparison otherwise (e.g. "N/A" or "Unknown")
+func valueChanged(prev, next string, threshold float64, parse func(string) (float64, bool)) bool {
+	if prev == next {
+		return false
+	}
+
+	a, okA := parse(prev)
+	b, okB := parse(next)
+	if !okA || !okB {
+		return true
+	}
+	return math.Abs(a-b) >= threshold
+}
+
+// modelsChanged reports whether the loaded models or their engines differ
+func modelsChanged(prev, next []*pb.ModelEngine) bool {
+	if len(prev) != len(next) {
+		return true
+	}
+	for i := range prev {
+		if !proto.Equal(prev[i], next[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package heartbeat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func baseCapabilities() *pb.Capabilities {
+	return &pb.Capabilities{
+		Cpu:              "16 cores",
+		Memory:           "31.25 GB",
+		Os:               "linux/amd64",
+		GpuType:          "NVIDIA GeForce RTX 4090",
+		GpuVramTotal:     "24.0 GB",
+		GpuVramAvailable: "20.0 GB",
+		GpuVramUsed:      "4.0 GB",
+		GpuTemperature:   "55°C",
+		GpuPowerUsage:    "120.0 W",
+		PowerUsage:       "N/A",
+	}
+}
+
+func TestCapabilitiesChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *pb.Capabilities)
+		want   bool
+	}{
+		{"unchanged", func(c *pb.Capabilities) {}, false},
+		{"small VRAM change", func(c *pb.Capabilities) { c.GpuVramAvailable = "19.9 GB"; c.GpuVramUsed = "4.1 GB" }, false},
+		{"large VRAM change", func(c *pb.Capabilities) { c.GpuVramAvailable = "12.0 GB"; c.GpuVramUsed = "12.0 GB" }, true},
+		{"small temperature change", func(c *pb.Capabilities) { c.GpuTemperature = "57°C" }, false},
+		{"large temperature change", func(c *pb.Capabilities) { c.GpuTemperature = "70°C" }, true},
+		{"small power change", func(c *pb.Capabilities) { c.GpuPowerUsage = "130.0 W" }, false},
+		{"large power change", func(c *pb.Capabilities) { c.GpuPowerUsage = "300.0 W" }, true},
+		{"reading becomes unavailable", func(c *pb.Capabilities) { c.GpuTemperature = "N/A" }, true},
+		{"static field changed", func(c *pb.Capabilities) { c.Memory = "63.50 GB" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := baseCapabilities()
+			tt.modify(next)
+			assert.Equal(t, tt.want, capabilitiesChanged(baseCapabilities(), next, DefaultChangeThresholds))
+		})
+	}
+
+	assert.True(t, capabilitiesChanged(nil, baseCapabilities(), DefaultChangeThresholds))
+	assert.False(t, capabilitiesChanged(nil, nil, DefaultChangeThresholds))
+}
@@ -20,11 +20,28 @@ type Client struct {
 	client      pb.OrchestratorClient
 	address     string
 	nodeID      string
-	nodeInfo    *pb.Node                // Store node info for re-registration
-	updateCaps  bool                    // Whether to update capabilities periodically
-	capsUpdater func() *pb.Capabilities // Function to get updated capabilities
+	nodeInfo    *pb.Node                 // Store node info for re-registration
+	updateCaps  bool                     // Whether to update capabilities periodically
+	capsUpdater func() *pb.Capabilities  // Function to get updated capabilities
+	models      func() []*pb.ModelEngine // Function to get the loaded models, if reported
+	throttle    func() *pb.NodeThrottle  // Function to get the throttling state, if reported
+	imagePins   func([]*pb.ImagePin)     // Function applying the orchestrator's image pins, if enabled
+	sharing     func(*pb.GpuSharing)     // Function applying the orchestrator's GPU sharing policy, if enabled
+	interrupted []*pb.InterruptedRequest // Requests to report as interrupted at the next registration
+	config      *pb.NodeConfig           // Config the orchestrator returned with the last registration
+	link        *linkMeter               // Measures the link to the orchestrator during heartbeats
+
+	// Capability diffing to avoid sending unchanged values
+	lastCaps     *pb.Capabilities  // Capabilities last acknowledged by the orchestrator
+	lastModels   []*pb.ModelEngine // Loaded models last acknowledged by the orchestrator
+	lastCapsSync time.Time         // When capabilities were last sent
+	thresholds   ChangeThresholds
+	fullRefresh  time.Duration // Send capabilities at least this often, even if unchanged
 }
 
+// DefaultFullRefreshInterval is how often capabilities are sent even when nothing changed
+const DefaultFullRefreshInterval = 5 * time.Minute
+
 // NewClient creates a new heartbeat client
 func NewClient(orchestratorAddress string) (*Client, error) {
 	conn, err := grpc.NewClient(orchestratorAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
@@ -33,19 +50,24 @@ func NewClient(orchestratorAddress string) (*Client, error) {
 	}
 
 	return &Client{
-		conn:    conn,
-		client:  pb.NewOrchestratorClient(conn),
-		address: orchestratorAddress,
+		conn:        conn,
+		client:      pb.NewOrchestratorClient(conn),
+		address:     orchestratorAddress,
+		link:        newLinkMeter(),
+		thresholds:  DefaultChangeThresholds,
+		fullRefresh: DefaultFullRefreshInterval,
 	}, nil
 }
 
 // RegisterNode registers a node with the orchestrator
 func (c *Client) RegisterNode(ctx context.Context, node *pb.Node) error {
-	req := &pb.RegisterNodeRequest{Node: node}
-	_, err := c.client.RegisterNode(ctx, req)
+	req := &pb.RegisterNodeRequest{Node: node, InterruptedRequests: c.interrupted}
+	resp, err := c.client.RegisterNode(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to register node: %w", err)
 	}
+	c.interrupted = nil
+	c.config = resp.GetConfig()
 	c.nodeID = node.Id
 	// Store node info for potential re-registration
 	c.nodeInfo = &pb.Node{
@@ -53,16 +75,67 @@ func (c *Client) RegisterNode(ctx context.Context, node *pb.Node) error {
 		Hostname:     node.Hostname,
 		Capabilities: node.Capabilities,
 		LastSeenUnix: node.LastSeenUnix,
+		AgentAddress: node.AgentAddress,
+		Labels:       node.Labels,
+
+		SupportedModels: node.SupportedModels,
 	}
+	c.lastCaps = node.Capabilities
+	c.lastModels = node.Models
+	c.lastCapsSync = time.Now()
 	return nil
 }
 
+// Config returns the config the orchestrator's node profile set for the
+// node at its last registration, or nil if no profile matched
+func (c *Client) Config() *pb.NodeConfig {
+	return c.config
+}
+
 // EnableCapabilityUpdates enables periodic capability updates
 func (c *Client) EnableCapabilityUpdates(updater func() *pb.Capabilities) {
 	c.updateCaps = true
 	c.capsUpdater = updater
 }
 
+// EnableModelReporting sends the loaded models and the engines serving them
+// along with capability updates, whenever they change
+func (c *Client) EnableModelReporting(models func() []*pb.ModelEngine) {
+	c.models = models
+}
+
+// EnableThrottleReporting sends the node's throttling state with every
+// heartbeat
+func (c *Client) EnableThrottleReporting(throttle func() *pb.NodeThrottle) {
+	c.throttle = throttle
+}
+
+// EnableImagePinning hands the engine images the orchestrator pins
+// fleet-wide to apply after every heartbeat
+func (c *Client) EnableImagePinning(apply func(pins []*pb.ImagePin)) {
+	c.imagePins = apply
+}
+
+// EnableGPUSharing hands the GPU sharing policy in effect, nil if none, to
+// apply after every heartbeat
+func (c *Client) EnableGPUSharing(apply func(sharing *pb.GpuSharing)) {
+	c.sharing = apply
+}
+
+// ReportInterruptedRequests reports requests the agent was running when it
+// last stopped unexpectedly with the next registration, so the orchestrator
+// can retry them
+func (c *Client) ReportInterruptedRequests(requests []*pb.InterruptedRequest) {
+	c.interrupted = requests
+}
+
+// SetCapabilityThresholds configures how much capabilities must change before
+// an update is sent, and how often a full refresh is sent regardless
+func (c *Client) SetCapabilityThresholds(thresholds ChangeThresholds, fullRefresh time.Duration) {
+	c.thresholds = thresholds
+	c.fullRefresh = fullRefresh
+}
+
 // SendHeartbeat sends a heartbeat to the orchestrator
 func (c *Client) SendHeartbeat(ctx context.Context) error {
 	if c.nodeID == "" {
@@ -70,15 +143,32 @@ func (c *Client) SendHeartbeat(ctx context.Context) error {
 	}
 
 	req := &pb.HeartbeatRequest{NodeId: c.nodeID}
-	_, err := c.client.Heartbeat(ctx, req)
+	if c.throttle != nil {
+		req.Throttle = c.throttle()
+	}
+	var resp *pb.HeartbeatResponse
+	err := c.link.measure(func(stats *pb.NetworkStats, probe []byte) (err error) {
+		req.Network, req.BandwidthProbe = stats, probe
+		resp, err = c.client.Heartbeat(ctx, req)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send heartbeat: %w", err)
 	}
 
+	if c.imagePins != nil {
+		c.imagePins(resp.ImagePins)
+	}
+	if c.sharing != nil {
+		c.sharing(resp.Sharing)
+	}
 	return nil
 }
 
-// UpdateCapabilities sends updated capabilities to the orchestrator
+// UpdateCapabilities sends updated capabilities, and loaded models if
+// reported, to the orchestrator. Updates are skipped while capabilities stay
+// within the change thresholds and the loaded models are unchanged, except
+// for a periodic full refresh.
 func (c *Client) UpdateCapabilities(ctx context.Context) error {
 	if c.nodeID == "" {
 		return fmt.Errorf("node not registered, cannot update capabilities")
@@ -89,9 +179,19 @@ func (c *Client) UpdateCapabilities(ctx context.Context) error {
 	}
 
 	caps := c.capsUpdater()
+	var models []*pb.ModelEngine
+	if c.models != nil {
+		models = c.models()
+	}
+	if c.lastCaps != nil && time.Since(c.lastCapsSync) < c.fullRefresh &&
+		!capabilitiesChanged(c.lastCaps, caps, c.thresholds) && !modelsChanged(c.lastModels, models) {
+		return nil
+	}
+
 	req := &pb.UpdateNodeRequest{
 		NodeId:       c.nodeID,
 		Capabilities: caps,
+		Models:       models,
 	}
 
 	_, err := c.client.UpdateNode(ctx, req)
@@ -99,6 +199,9 @@ func (c *Client) UpdateCapabilities(ctx context.Context) error {
 		return fmt.Errorf("failed to update capabilities: %w", err)
 	}
 
+	c.lastCaps = caps
+	c.lastModels = models
+	c.lastCapsSync = time.Now()
 	return nil
 }
 
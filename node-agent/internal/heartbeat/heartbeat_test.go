@@ -36,6 +36,14 @@ func (m *MockOrchestratorClient) Heartbeat(ctx context.Context, req *pb.Heartbea
 	return args.Get(0).(*pb.HeartbeatResponse), args.Error(1)
 }
 
+func (m *MockOrchestratorClient) BatchHeartbeat(ctx context.Context, req *pb.BatchHeartbeatRequest, opts ...grpc.CallOption) (*pb.BatchHeartbeatResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.BatchHeartbeatResponse), args.Error(1)
+}
+
 func (m *MockOrchestratorClient) UpdateNode(ctx context.Context, req *pb.UpdateNodeRequest, opts ...grpc.CallOption) (*pb.UpdateNodeResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -52,6 +60,14 @@ func (m *MockOrchestratorClient) ListNodes(ctx context.Context, req *pb.ListNode
 	return args.Get(0).(*pb.ListNodesResponse), args.Error(1)
 }
 
+func (m *MockOrchestratorClient) GetNode(ctx context.Context, req *pb.GetNodeRequest, opts ...grpc.CallOption) (*pb.GetNodeResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.GetNodeResponse), args.Error(1)
+}
+
 func (m *MockOrchestratorClient) SubmitJob(ctx context.Context, req *pb.SubmitJobRequest, opts ...grpc.CallOption) (*pb.SubmitJobResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -68,6 +84,46 @@ func (m *MockOrchestratorClient) GetJobStatus(ctx context.Context, req *pb.GetJo
 	return args.Get(0).(*pb.GetJobStatusResponse), args.Error(1)
 }
 
+func (m *MockOrchestratorClient) GetJobResult(ctx context.Context, req *pb.GetJobResultRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.GetJobResultResponse], error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(grpc.ServerStreamingClient[pb.GetJobResultResponse]), args.Error(1)
+}
+
+func (m *MockOrchestratorClient) SubmitJobGroup(ctx context.Context, req *pb.SubmitJobGroupRequest, opts ...grpc.CallOption) (*pb.SubmitJobGroupResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.SubmitJobGroupResponse), args.Error(1)
+}
+
+func (m *MockOrchestratorClient) GetJobGroup(ctx context.Context, req *pb.GetJobGroupRequest, opts ...grpc.CallOption) (*pb.GetJobGroupResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.GetJobGroupResponse), args.Error(1)
+}
+
+func (m *MockOrchestratorClient) ListJobs(ctx context.Context, req *pb.ListJobsRequest, opts ...grpc.CallOption) (*pb.ListJobsResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.ListJobsResponse), args.Error(1)
+}
+
+func (m *MockOrchestratorClient) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest, opts ...grpc.CallOption) (*pb.SetLogLevelResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*pb.SetLogLevelResponse), args.Error(1)
+}
+
 func TestNewClient(t *testing.T) {
 	// Test with invalid address - may succeed or fail depending on system
 	client, err := NewClient("invalid:99999")
@@ -98,6 +154,51 @@ func TestClient_NodeInfoStorage(t *testing.T) {
 	assert.Equal(t, "test-host", client.nodeInfo.Hostname)
 }
 
+func TestClient_RegisterNode_KeepsAgentAddress(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	mockClient.On("RegisterNode", mock.Anything, mock.Anything).Return(&pb.RegisterNodeResponse{}, nil)
+	client := &Client{client: mockClient}
+
+	err := client.RegisterNode(context.Background(), &pb.Node{
+		Id:           "test-node",
+		Hostname:     "test-host",
+		AgentAddress: "192.168.1.20:50052",
+	})
+	require.NoError(t, err)
+
+	// Re-registration must advertise the same address
+	assert.Equal(t, "192.168.1.20:50052", client.nodeInfo.AgentAddress)
+}
+
+func TestClient_RegisterNode_ReportsInterruptedRequests(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	mockClient.On("RegisterNode", mock.Anything, mock.Anything).Return(&pb.RegisterNodeResponse{}, nil)
+	client := &Client{client: mockClient}
+	client.ReportInterruptedRequests([]*pb.InterruptedRequest{{JobId: "job-1", Model: "llama3"}})
+
+	node := &pb.Node{Id: "test-node", Hostname: "test-host"}
+	require.NoError(t, client.RegisterNode(context.Background(), node))
+	require.NoError(t, client.RegisterNode(context.Background(), node))
+
+	// Only the first registration reports them
+	first := mockClient.Calls[0].Arguments.Get(1).(*pb.RegisterNodeRequest)
+	require.Len(t, first.InterruptedRequests, 1)
+	assert.Equal(t, "job-1", first.InterruptedRequests[0].JobId)
+	second := mockClient.Calls[1].Arguments.Get(1).(*pb.RegisterNodeRequest)
+	assert.Empty(t, second.InterruptedRequests)
+}
+
+func TestClient_RegisterNode_KeepsConfig(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	config := &pb.NodeConfig{Profile: "gpu", MaxConcurrentRequests: 4}
+	mockClient.On("RegisterNode", mock.Anything, mock.Anything).Return(&pb.RegisterNodeResponse{Config: config}, nil)
+	client := &Client{client: mockClient}
+
+	assert.Nil(t, client.Config())
+	require.NoError(t, client.RegisterNode(context.Background(), &pb.Node{Id: "test-node", Hostname: "test-host"}))
+	assert.Same(t, config, client.Config())
+}
+
 func TestClient_EnableCapabilityUpdates(t *testing.T) {
 	client := &Client{}
 
@@ -130,6 +231,44 @@ func TestClient_SendHeartbeat_Unregistered(t *testing.T) {
 	assert.Contains(t, err.Error(), "node not registered")
 }
 
+func TestClient_SendHeartbeat_Throttle(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	throttle := &pb.NodeThrottle{Reason: "GPU temperature 88°C over 85°C limit", MaxConcurrent: 1}
+	mockClient.On("Heartbeat", mock.Anything, &pb.HeartbeatRequest{NodeId: "test-node", Throttle: throttle}).
+		Return(&pb.HeartbeatResponse{}, nil)
+	client := &Client{client: mockClient, nodeID: "test-node"}
+	client.EnableThrottleReporting(func() *pb.NodeThrottle { return throttle })
+
+	require.NoError(t, client.SendHeartbeat(context.Background()))
+	mockClient.AssertExpectations(t)
+}
+
+func TestClient_SendHeartbeat_ImagePins(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	pins := []*pb.ImagePin{{Image: "vllm/vllm-openai", Digest: "sha256:aaa"}}
+	mockClient.On("Heartbeat", mock.Anything, mock.Anything).Return(&pb.HeartbeatResponse{ImagePins: pins}, nil)
+	client := &Client{client: mockClient, nodeID: "test-node"}
+
+	var applied []*pb.ImagePin
+	client.EnableImagePinning(func(p []*pb.ImagePin) { applied = p })
+
+	require.NoError(t, client.SendHeartbeat(context.Background()))
+	assert.Equal(t, pins, applied)
+}
+
+func TestClient_SendHeartbeat_GPUSharing(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	sharing := &pb.GpuSharing{InteractiveReserve: 0.5, Window: "office"}
+	mockClient.On("Heartbeat", mock.Anything, mock.Anything).Return(&pb.HeartbeatResponse{Sharing: sharing}, nil)
+	client := &Client{client: mockClient, nodeID: "test-node"}
+
+	var applied *pb.GpuSharing
+	client.EnableGPUSharing(func(s *pb.GpuSharing) { applied = s })
+
+	require.NoError(t, client.SendHeartbeat(context.Background()))
+	assert.Equal(t, sharing, applied)
+}
+
 func TestClient_UpdateCapabilities_Unregistered(t *testing.T) {
 	client := &Client{
 		nodeID: "", // Not registered
@@ -190,4 +329,73 @@ func TestHeartbeatLoop_ErrorHandling(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, codes.NotFound, st.Code())
 	assert.Contains(t, st.Message(), "node not found")
-}
\ No newline at end of file
+}
+func TestClient_UpdateCapabilities_SkipsUnchanged(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	mockClient.On("UpdateNode", mock.Anything, mock.Anything).Return(&pb.UpdateNodeResponse{}, nil)
+
+	current := &pb.Capabilities{Cpu: "8 cores", GpuVramAvailable: "10.0 GB"}
+	client := &Client{
+		client:       mockClient,
+		nodeID:       "test-node",
+		lastCaps:     &pb.Capabilities{Cpu: "8 cores", GpuVramAvailable: "10.0 GB"},
+		lastCapsSync: time.Now(),
+		thresholds:   DefaultChangeThresholds,
+		fullRefresh:  time.Hour,
+	}
+	client.EnableCapabilityUpdates(func() *pb.Capabilities { return current })
+
+	// Unchanged capabilities are not sent
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNotCalled(t, "UpdateNode", mock.Anything, mock.Anything)
+
+	// A change beyond the threshold is sent
+	current = &pb.Capabilities{Cpu: "8 cores", GpuVramAvailable: "6.0 GB"}
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNumberOfCalls(t, "UpdateNode", 1)
+
+	// A full refresh is sent once the interval has elapsed
+	client.lastCapsSync = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNumberOfCalls(t, "UpdateNode", 2)
+}
+
+func TestClient_UpdateCapabilities_SendsModelChanges(t *testing.T) {
+	mockClient := &MockOrchestratorClient{}
+	mockClient.On("UpdateNode", mock.Anything, mock.Anything).Return(&pb.UpdateNodeResponse{}, nil)
+
+	caps := &pb.Capabilities{Cpu: "8 cores"}
+	client := &Client{
+		client:       mockClient,
+		nodeID:       "test-node",
+		lastCaps:     caps,
+		lastCapsSync: time.Now(),
+		thresholds:   DefaultChangeThresholds,
+		fullRefresh:  time.Hour,
+	}
+	client.EnableCapabilityUpdates(func() *pb.Capabilities { return caps })
+
+	var models []*pb.ModelEngine
+	client.EnableModelReporting(func() []*pb.ModelEngine { return models })
+
+	// Nothing loaded yet, nothing to send
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNotCalled(t, "UpdateNode", mock.Anything, mock.Anything)
+
+	// A newly loaded model is sent even though capabilities are unchanged
+	models = []*pb.ModelEngine{{Model: "llama3", Engine: "ollama", Version: "0.3.12"}}
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNumberOfCalls(t, "UpdateNode", 1)
+	req := mockClient.Calls[0].Arguments.Get(1).(*pb.UpdateNodeRequest)
+	assert.Equal(t, "0.3.12", req.Models[0].Version)
+
+	// The same models aren't sent again
+	models = []*pb.ModelEngine{{Model: "llama3", Engine: "ollama", Version: "0.3.12"}}
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNumberOfCalls(t, "UpdateNode", 1)
+
+	// An engine upgrade is sent
+	models = []*pb.ModelEngine{{Model: "llama3", Engine: "ollama", Version: "0.3.13"}}
+	require.NoError(t, client.UpdateCapabilities(context.Background()))
+	mockClient.AssertNumberOfCalls(t, "UpdateNode", 2)
+}
@@ -0,0 +1,26 @@
+package heartbeat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseLabels parses node labels given as "key=value,key=value", e.g.
+// "zone=eu-west,tier=spot". The orchestrator can filter nodes by them.
+func ParseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, "!=") {
+			return nil, fmt.Errorf("invalid label %q (want key=value)", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
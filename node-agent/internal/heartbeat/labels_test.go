@@ -0,0 +1,23 @@
+package heartbeat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels("zone=eu-west, tier = spot,empty=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"zone": "eu-west", "tier": "spot", "empty": ""}, labels)
+
+	labels, err = ParseLabels("")
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	for _, invalid := range []string{"zone", "=eu", "!zone=eu"} {
+		_, err := ParseLabels(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,68 @@
+package heartbeat
+
+import (
+	"time"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+// Bandwidth probes padding every few heartbeats, from which the orchestrator's
+// scheduler learns how fast each agent's link is
+const (
+	DefaultProbeEvery = 12
+	DefaultProbeBytes = 256 << 10
+)
+
+// linkMeter measures the link to the orchestrator from heartbeat round trips.
+// Plain heartbeats give the round-trip time; every few heartbeats one carries
+// a padding probe, and the extra time it takes gives the upload throughput.
+// Measurements are reported with the following heartbeat.
+type linkMeter struct {
+	every int // Heartbeats per probe
+	bytes int // Probe size
+	now   func() time.Time
+
+	sent  int
+	rtt   time.Duration
+	stats *pb.NetworkStats // Reported with the next heartbeat
+}
+
+func newLinkMeter() *linkMeter {
+	return &linkMeter{every: DefaultProbeEvery, bytes: DefaultProbeBytes, now: time.Now}
+}
+
+// measure sends a heartbeat with the last measurements and a probe when one
+// is due, then measures how long it took. Failed heartbeats measure nothing,
+// and neither does a nil meter.
+func (m *linkMeter) measure(send func(stats *pb.NetworkStats, probe []byte) error) error {
+	if m == nil {
+		return send(nil, nil)
+	}
+
+	var probe []byte
+	// Throughput is derived from the round-trip time, so it needs one first
+	if m.rtt > 0 && m.sent%m.every == m.every-1 {
+		probe = make([]byte, m.bytes)
+	}
+
+	start := m.now()
+	if err := send(m.stats, probe); err != nil {
+		return err
+	}
+	elapsed := m.now().Sub(start)
+	m.sent++
+
+	if probe == nil {
+		m.rtt = elapsed
+		m.stats = &pb.NetworkStats{RttMs: float64(elapsed) / float64(time.Millisecond)}
+		return nil
+	}
+	// A probe going by too fast to time leaves the round trip reported alone
+	if transfer := elapsed - m.rtt; transfer > 0 {
+		m.stats = &pb.NetworkStats{
+			RttMs:      float64(m.rtt) / float64(time.Millisecond),
+			UploadMbps: float64(len(probe)*8) / transfer.Seconds() / 1e6,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package heartbeat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+func TestLinkMeter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := &linkMeter{every: 3, bytes: 1 << 20, now: func() time.Time { return now }}
+
+	// send takes d and records what it was asked to send
+	var stats *pb.NetworkStats
+	var probe []byte
+	send := func(d time.Duration, err error) error {
+		return m.measure(func(s *pb.NetworkStats, p []byte) error {
+			stats, probe = s, p
+			now = now.Add(d)
+			return err
+		})
+	}
+
+	require.NoError(t, send(20*time.Millisecond, nil))
+	assert.Nil(t, stats)
+	assert.Nil(t, probe)
+
+	require.NoError(t, send(10*time.Millisecond, nil))
+	assert.Equal(t, 20.0, stats.RttMs)
+	assert.Nil(t, probe)
+
+	// 1 MiB in 100ms over the round trip
+	require.NoError(t, send(110*time.Millisecond, nil))
+	assert.Equal(t, 10.0, stats.RttMs)
+	assert.Len(t, probe, 1<<20)
+
+	require.NoError(t, send(10*time.Millisecond, nil))
+	assert.Equal(t, 10.0, stats.RttMs)
+	assert.InDelta(t, 83.9, stats.UploadMbps, 0.1)
+	assert.Nil(t, probe)
+
+	// Failed heartbeats measure nothing
+	assert.Error(t, send(time.Second, errors.New("unavailable")))
+	require.NoError(t, send(10*time.Millisecond, nil))
+	assert.Equal(t, 10.0, stats.RttMs)
+	assert.Zero(t, stats.UploadMbps)
+
+	var none *linkMeter
+	assert.NoError(t, none.measure(func(s *pb.NetworkStats, p []byte) error {
+		assert.Nil(t, s)
+		assert.Nil(t, p)
+		return nil
+	}))
+}
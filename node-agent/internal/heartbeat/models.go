@@ -0,0 +1,25 @@
+package heartbeat
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ParseSupportedModels parses comma-separated model name patterns in
+// path.Match syntax, e.g. "nomic-embed-text,phi3*". The orchestrator only
+// schedules matching models on the node; an empty list allows any model.
+func ParseSupportedModels(s string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid model pattern %q: %v", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
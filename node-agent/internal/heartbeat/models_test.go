@@ -0,0 +1,21 @@
+package heartbeat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSupportedModels(t *testing.T) {
+	patterns, err := ParseSupportedModels(" nomic-embed-text, phi3*,,mistralai/* ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nomic-embed-text", "phi3*", "mistralai/*"}, patterns)
+
+	patterns, err = ParseSupportedModels("")
+	require.NoError(t, err)
+	assert.Empty(t, patterns)
+
+	_, err = ParseSupportedModels("llama3[")
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// GPUUsageTrailer is the gRPC trailer carrying a request's serialized pb.GpuUsage
+const GPUUsageTrailer = "orchion-gpu-usage-bin"
+
+// SaturationThreshold is the GPU utilization (percent) at which a request is
+// considered to have started on a saturated GPU
+const SaturationThreshold = 90.0
+
+// DefaultSampleTTL bounds how often request sampling queries the GPU
+const DefaultSampleTTL = time.Second
+
+// Sampler takes GPU utilization and VRAM samples around inference requests
+type Sampler struct {
+	gpu capabilities.GPUDetector
+	now func() time.Time
+}
+
+// NewSampler creates a sampler reading GPU state from the given detector
+func NewSampler(gpu capabilities.GPUDetector) *Sampler {
+	return &Sampler{
+		gpu: gpu,
+		now: time.Now,
+	}
+}
+
+// Sample returns the current GPU state, or nil if the GPU reports no readings
+func (s *Sampler) Sample() *pb.GpuSample {
+	info := s.gpu.DetectGPU()
+
+	sample := &pb.GpuSample{TimestampMs: s.now().UnixMilli()}
+	utilization, okUtil := units.ParseNumber(info.Utilization)
+	used, okUsed := units.ParseMegabytes(info.VRAMUsed)
+	total, okTotal := units.ParseMegabytes(info.VRAMTotal)
+	if !okUtil && !okUsed && !okTotal {
+		return nil
+	}
+
+	sample.UtilizationPercent = utilization
+	sample.VramUsedMb = used
+	sample.VramTotalMb = total
+	return sample
+}
+
+// MarshalUsage serializes GPU usage for the GPUUsageTrailer
+func MarshalUsage(usage *pb.GpuUsage) (string, error) {
+	data, err := proto.Marshal(usage)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Metrics records GPU samples and request durations as OpenTelemetry metrics
+type Metrics struct {
+	utilization metric.Float64Histogram
+	vramUsed    metric.Float64Histogram
+	duration    metric.Float64Histogram
+}
+
+// NewMetrics creates the request metrics on the given meter
+func NewMetrics(meter metric.Meter) (*Metrics, error) {
+	utilization, err := meter.Float64Histogram("orchion.gpu.utilization",
+		metric.WithDescription("GPU utilization sampled at the start and end of inference requests"),
+		metric.WithUnit("%"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create utilization histogram: %w", err)
+	}
+
+	vramUsed, err := meter.Float64Histogram("orchion.gpu.vram.used",
+		metric.WithDescription("GPU memory in use at the start and end of inference requests"),
+		metric.WithUnit("MiBy"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VRAM histogram: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("orchion.inference.duration",
+		metric.WithDescription("Duration of inference requests"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return &Metrics{
+		utilization: utilization,
+		vramUsed:    vramUsed,
+		duration:    duration,
+	}, nil
+}
+
+// Record records a completed request. kind is the request type ("chat" or
+// "embeddings"); the duration is tagged with whether the GPU was already
+// saturated when the request started.
+func (m *Metrics) Record(ctx context.Context, model, kind string, usage *pb.GpuUsage, elapsed time.Duration) {
+	saturated := false
+	for phase, sample := range map[string]*pb.GpuSample{"start": usage.GetStart(), "end": usage.GetEnd()} {
+		if sample == nil {
+			continue
+		}
+		attrs := metric.WithAttributes(
+			attribute.String("model", model),
+			attribute.String("kind", kind),
+			attribute.String("phase", phase),
+		)
+		m.utilization.Record(ctx, sample.UtilizationPercent, attrs)
+		m.vramUsed.Record(ctx, sample.VramUsedMb, attrs)
+		if phase == "start" && sample.UtilizationPercent >= SaturationThreshold {
+			saturated = true
+		}
+	}
+
+	m.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+		attribute.String("model", model),
+		attribute.String("kind", kind),
+		attribute.Bool("gpu.saturated", saturated),
+	))
+}
+
+// Setup installs a global meter provider exporting to an OTLP gRPC collector
+// at endpoint. With no endpoint, metrics go to the no-op global provider.
+// The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, endpoint string, interval time.Duration) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(
+		sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))))
+	otel.SetMeterProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Meter returns the node agent's meter from the global provider
+func Meter() metric.Meter {
+	return otel.Meter("github.com/Orchion/Orchion/node-agent")
+}
@@ -0,0 +1,126 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Orchion/Orchion/node-agent/internal/capabilities"
+	pb "github.com/Orchion/Orchion/node-agent/internal/proto/v1"
+)
+
+type fakeGPU struct {
+	info capabilities.GPUInfo
+}
+
+func (f fakeGPU) DetectGPU() capabilities.GPUInfo {
+	return f.info
+}
+
+func TestSampler_Sample(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+
+	tests := []struct {
+		name string
+		info capabilities.GPUInfo
+		want *pb.GpuSample
+	}{
+		{
+			name: "nvidia",
+			info: capabilities.GPUInfo{
+				Type:        "NVIDIA GeForce RTX 4090",
+				VRAMTotal:   "24.0 GB",
+				VRAMUsed:    "512 MB",
+				Utilization: "87%",
+			},
+			want: &pb.GpuSample{UtilizationPercent: 87, VramUsedMb: 512, VramTotalMb: 24576, TimestampMs: now.UnixMilli()},
+		},
+		{
+			name: "no utilization reading",
+			info: capabilities.GPUInfo{
+				Type:      "AMD Radeon RX 7900 XTX",
+				VRAMTotal: "24.0 GB",
+				VRAMUsed:  "1.0 GB",
+			},
+			want: &pb.GpuSample{VramUsedMb: 1024, VramTotalMb: 24576, TimestampMs: now.UnixMilli()},
+		},
+		{
+			name: "no GPU",
+			info: capabilities.GPUInfo{
+				Type:      "No GPU detected",
+				VRAMTotal: "N/A",
+				VRAMUsed:  "N/A",
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := NewSampler(fakeGPU{info: tt.info})
+			sampler.now = func() time.Time { return now }
+
+			got := sampler.Sample()
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.True(t, proto.Equal(tt.want, got), "got %v", got)
+		})
+	}
+}
+
+func TestMarshalUsage(t *testing.T) {
+	usage := &pb.GpuUsage{
+		Start: &pb.GpuSample{UtilizationPercent: 95},
+		End:   &pb.GpuSample{UtilizationPercent: 40},
+	}
+
+	value, err := MarshalUsage(usage)
+	require.NoError(t, err)
+
+	var decoded pb.GpuUsage
+	require.NoError(t, proto.Unmarshal([]byte(value), &decoded))
+	assert.True(t, proto.Equal(usage, &decoded))
+}
+
+func TestMetrics_Record(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	metrics, err := NewMetrics(provider.Meter("test"))
+	require.NoError(t, err)
+
+	usage := &pb.GpuUsage{
+		Start: &pb.GpuSample{UtilizationPercent: 95, VramUsedMb: 20000},
+		End:   &pb.GpuSample{UtilizationPercent: 60, VramUsedMb: 21000},
+	}
+	metrics.Record(context.Background(), "llama3", "chat", usage, 2*time.Second)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	byName := make(map[string]metricdata.Histogram[float64])
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		byName[m.Name] = m.Data.(metricdata.Histogram[float64])
+	}
+
+	assert.Len(t, byName["orchion.gpu.utilization"].DataPoints, 2)
+	assert.Len(t, byName["orchion.gpu.vram.used"].DataPoints, 2)
+
+	duration := byName["orchion.inference.duration"].DataPoints
+	require.Len(t, duration, 1)
+	assert.Equal(t, 2.0, duration[0].Sum)
+	saturated, ok := duration[0].Attributes.Value(attribute.Key("gpu.saturated"))
+	require.True(t, ok)
+	assert.True(t, saturated.AsBool())
+}
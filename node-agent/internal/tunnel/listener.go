@@ -0,0 +1,176 @@
+// Package tunnel lets an agent behind NAT serve its NodeAgent gRPC service
+// over connections it opens to the orchestrator, instead of waiting to be
+// dialed. The orchestrator runs its gRPC client over the tunnel, so HTTP/2
+// multiplexes all requests over one connection.
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// Handshake with the orchestrator's tunnel server: the agent sends Greeting,
+// its node ID and a newline, then waits for "OK" (the orchestrator took the
+// tunnel) or "ERR <reason>"
+const (
+	Greeting      = "ORCHION-TUNNEL/1 "
+	maxLineLength = 512
+)
+
+// Reconnect backoff after a failed tunnel
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Listener is a net.Listener whose connections are tunnels opened to the
+// orchestrator. It keeps one tunnel at a time: Accept opens the next one once
+// the current one closes, so a gRPC server serving on it reconnects whenever
+// the tunnel drops.
+type Listener struct {
+	addr   string
+	nodeID string
+	logger logging.Logger
+	dial   func(ctx context.Context, addr string) (net.Conn, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	idle   chan struct{} // holds a token while no tunnel is open
+}
+
+// NewListener creates a listener tunneling to the orchestrator's tunnel server
+// at addr on behalf of nodeID
+func NewListener(addr, nodeID string, logger logging.Logger) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	l := &Listener{
+		addr:   addr,
+		nodeID: nodeID,
+		logger: logger,
+		dial: func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		idle:   make(chan struct{}, 1),
+	}
+	l.idle <- struct{}{}
+	return l
+}
+
+// Accept waits for the current tunnel to close, then opens a new one,
+// retrying with backoff until it succeeds or the listener is closed
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case <-l.idle:
+	case <-l.ctx.Done():
+		return nil, net.ErrClosed
+	}
+
+	backoff := minBackoff
+	for {
+		conn, err := l.open()
+		if err == nil {
+			return &tunnelConn{Conn: conn, idle: l.idle}, nil
+		}
+		if l.ctx.Err() != nil {
+			return nil, net.ErrClosed
+		}
+		l.logger.Warn("Failed to open tunnel to orchestrator", map[string]interface{}{
+			"tunnel_address": l.addr,
+			"retry_in":       backoff.String(),
+			"error":          err.Error(),
+		})
+
+		select {
+		case <-time.After(backoff):
+		case <-l.ctx.Done():
+			return nil, net.ErrClosed
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// open dials the orchestrator and waits until it takes the tunnel
+func (l *Listener) open() (net.Conn, error) {
+	conn, err := l.dial(l.ctx, l.addr)
+	if err != nil {
+		return nil, err
+	}
+	// Unblock the handshake read when the listener closes
+	stop := context.AfterFunc(l.ctx, func() { conn.Close() })
+
+	if _, err := fmt.Fprintf(conn, "%s%s\n", Greeting, l.nodeID); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	reply, err := readLine(conn)
+	stop()
+	if err == nil && reply != "OK" {
+		err = fmt.Errorf("orchestrator rejected tunnel: %s", strings.TrimPrefix(reply, "ERR "))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// readLine reads a handshake line a byte at a time, so nothing after it is
+// consumed before gRPC takes over the connection
+func readLine(conn net.Conn) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for len(line) < maxLineLength {
+		if _, err := conn.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, buf[0])
+	}
+	return "", errors.New("handshake line too long")
+}
+
+// Close stops opening tunnels. Tunnels already accepted are closed by the
+// gRPC server.
+func (l *Listener) Close() error {
+	l.cancel()
+	return nil
+}
+
+// Addr returns the orchestrator's tunnel address
+func (l *Listener) Addr() net.Addr {
+	return tunnelAddr(l.addr)
+}
+
+// tunnelAddr is the address a tunnel listener connects to
+type tunnelAddr string
+
+func (a tunnelAddr) Network() string { return "tunnel" }
+func (a tunnelAddr) String() string  { return string(a) }
+
+// tunnelConn lets its listener open the next tunnel once it's closed
+type tunnelConn struct {
+	net.Conn
+	idle chan struct{}
+	once sync.Once
+}
+
+func (c *tunnelConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.idle <- struct{}{} })
+	return err
+}
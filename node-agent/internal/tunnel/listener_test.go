@@ -0,0 +1,103 @@
+package tunnel
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// fakeOrchestrator accepts tunnels and answers each greeting with reply
+func fakeOrchestrator(t *testing.T, reply string) (string, <-chan string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	greetings := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			line, err := readLine(conn)
+			if err != nil {
+				continue
+			}
+			greetings <- line
+			conn.Write([]byte(reply + "\n"))
+		}
+	}()
+	return lis.Addr().String(), greetings
+}
+
+func TestListener_Accept(t *testing.T) {
+	addr, greetings := fakeOrchestrator(t, "OK")
+	l := NewListener(addr, "node-1", logging.Default())
+	defer l.Close()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	assert.Equal(t, Greeting+"node-1", <-greetings)
+
+	// The next tunnel is only opened once the current one closes
+	accepted := make(chan net.Conn)
+	go func() {
+		next, err := l.Accept()
+		if err == nil {
+			accepted <- next
+		}
+	}()
+	select {
+	case <-accepted:
+		t.Fatal("opened a second tunnel while the first was open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, conn.Close())
+	select {
+	case next := <-accepted:
+		next.Close()
+	case <-time.After(2 * time.Second):
+		t.Fatal("no new tunnel after the first closed")
+	}
+	assert.Equal(t, Greeting+"node-1", <-greetings)
+}
+
+func TestListener_Rejected(t *testing.T) {
+	addr, _ := fakeOrchestrator(t, "ERR unknown node")
+	l := NewListener(addr, "node-1", logging.Default())
+	defer l.Close()
+
+	_, err := l.open()
+	assert.ErrorContains(t, err, "orchestrator rejected tunnel: unknown node")
+}
+
+func TestListener_Close(t *testing.T) {
+	// Nothing listens here, so Accept keeps retrying until closed
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	l := NewListener(addr, "node-1", logging.Default())
+	done := make(chan error)
+	go func() {
+		_, err := l.Accept()
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	l.Close()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, net.ErrClosed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept didn't return after Close")
+	}
+}
@@ -3,33 +3,126 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+	"github.com/Orchion/Orchion/orchestrator/internal/acme"
+	"github.com/Orchion/Orchion/orchestrator/internal/api"
+	"github.com/Orchion/Orchion/orchestrator/internal/apiv2"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/deployment"
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
 	"github.com/Orchion/Orchion/orchestrator/internal/gateway"
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
 	"github.com/Orchion/Orchion/orchestrator/internal/llm"
 	logServicePkg "github.com/Orchion/Orchion/orchestrator/internal/logging"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/openapi"
 	"github.com/Orchion/Orchion/orchestrator/internal/orchestrator"
+	"github.com/Orchion/Orchion/orchestrator/internal/preflight"
+	"github.com/Orchion/Orchion/orchestrator/internal/profiles"
 	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/replay"
+	"github.com/Orchion/Orchion/orchestrator/internal/replica"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
 	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/orchestrator/internal/sse"
+	"github.com/Orchion/Orchion/orchestrator/internal/transport"
+	"github.com/Orchion/Orchion/orchestrator/internal/tunnel"
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+	"github.com/Orchion/Orchion/shared/diagnostics"
+	"github.com/Orchion/Orchion/shared/lifecycle"
 	"github.com/Orchion/Orchion/shared/logging"
+	"github.com/Orchion/Orchion/shared/tailnet"
 )
 
 var (
 	port             = flag.String("port", "50051", "gRPC server port")
 	httpPort         = flag.String("http-port", "8080", "HTTP REST API port")
+	grpcBind         = flag.String("grpc-bind-address", "", "Interface address the gRPC and tunnel ports listen on: an IP such as 192.168.1.10, an interface name such as tailscale0, or tailnet for this host's Tailscale or WireGuard address (default: all interfaces)")
+	httpBind         = flag.String("http-bind-address", "", "Interface address the HTTP port listens on: an IP such as 127.0.0.1, an interface name, or tailnet (default: all interfaces)")
+	preferTailnet    = flag.Bool("prefer-tailnet", false, "Dial node agents at the Tailscale or WireGuard address they report, when they report one, instead of their agent address")
+	tlsDomains       = flag.String("tls-domains", "", "Comma-separated domains to serve HTTPS for on the HTTP port, with certificates from Let's Encrypt (leave empty for plain HTTP)")
+	tlsCacheDir      = flag.String("tls-cache-dir", acme.DefaultCacheDir, "Directory keeping ACME certificates and the account key across restarts")
+	tlsEmail         = flag.String("tls-email", "", "Contact e-mail for the ACME account (certificate expiry notices)")
+	acmeDirectory    = flag.String("acme-directory", "", "ACME directory URL, e.g. Let's Encrypt staging for testing (default: Let's Encrypt production)")
+	acmeHTTPPort     = flag.String("acme-http-port", "80", "Port answering ACME HTTP-01 challenges and redirecting to HTTPS when -tls-domains is set (leave empty to rely on TLS-ALPN-01 only)")
+	trustedProxies   = flag.String("trusted-proxies", "", "Comma-separated CIDRs or IPs of reverse proxies whose X-Forwarded-For (or PROXY protocol header) names the real client")
+	proxyProtocol    = flag.Bool("proxy-protocol", false, "Read PROXY protocol v1/v2 headers on HTTP connections from -trusted-proxies")
+	allowedCIDRs     = flag.String("allowed-cidrs", "", "Comma-separated CIDRs or IPs allowed to use the HTTP API and gateway, e.g. 192.168.1.0/24 (default: everyone; list 127.0.0.1,::1 to allow local clients)")
 	heartbeatTimeout = flag.Duration("heartbeat-timeout", 30*time.Second, "Node heartbeat timeout duration")
 	apiKey           = flag.String("api-key", "", "Optional API key for authentication (leave empty to disable)")
+	adminAPIKeys     = flag.String("admin-api-keys", "", "Comma-separated API keys allowed to pin gateway requests to a node with the X-Orchion-Node header")
+	apiKeyRoles      = flag.String("api-key-roles", "", "Comma-separated role:key pairs for the dashboard API and gRPC admin methods, e.g. viewer:abc,operator:def (roles: viewer, operator, admin)")
+	anonymousRead    = flag.Bool("anonymous-read", false, "Allow GET requests to the dashboard API (/api/...) without an API key; changes still need an operator key and /api/admin/ an admin key")
+	sessionTTL       = flag.Duration("session-ttl", auth.DefaultSessionTTL, "How long dashboard session tokens from /api/auth/login last")
+	sessionSecret    = flag.String("session-secret", "", "Secret signing dashboard session tokens; set it to keep sessions across restarts (default: random)")
+	modelCatalog     = flag.String("model-catalog", "", "Optional path to a JSON model catalog (per-model routing weights)")
+	embedPreferCPU   = flag.Bool("embeddings-prefer-cpu", false, "Route embedding requests to CPU-only nodes to keep GPUs free for chat")
+	embedLatencySLO  = flag.Duration("embeddings-latency-slo", time.Second, "Average embedding latency above which a CPU node loses its embedding preference")
+	bandwidthBytes   = flag.Int64("bandwidth-aware-bytes", scheduler.DefaultBandwidthMinBytes, "Payload size from which requests, and all embedding requests, prefer nodes with fast links to the orchestrator as measured during heartbeats (0 = disabled)")
+	localityWeight   = flag.Float64("locality-weight", scheduler.DefaultLocalityWeight, "Score jobs give nodes carrying all of their locality labels, e.g. corpus=nas1, where their data is (0 = ignore locality)")
+	nodeWarmup       = flag.Duration("node-warmup", 0, "How long nodes get no requests after registering, unless they report the requested model loaded or every node is warming up (0 = none)")
+	prefixTTL        = flag.Duration("prefix-affinity-ttl", scheduler.DefaultPrefixAffinityTTL, "How long requests sharing a prompt cache key stay pinned to the same node")
+	historySamples   = flag.Int("node-history-samples", node.DefaultHistoryCapacity, "Hardware samples kept per node for dashboard graphs (one per heartbeat)")
+	nodeEvents       = flag.Int("node-events", node.DefaultEventCapacity, "Events (registrations, lost heartbeats, evictions, ...) kept per node")
+	nodeEventTTL     = flag.Duration("node-event-retention", node.DefaultEventRetention, "How long node events are kept, including those of evicted nodes")
+	tombstoneTTL     = flag.Duration("node-tombstone-retention", node.DefaultTombstoneRetention, "How long nodes removed for missing heartbeats can be restored with their labels and notes (0 = forget them at once)")
+	nodeProfiles     = flag.String("node-profiles", "", "Optional path to a JSON file of node config profiles (labels, supported models, engine routes and limits agents pick up when they register)")
+	imagePins        = flag.String("image-pins", "", "Comma-separated image=digest pairs pinning engine images fleet-wide at startup, e.g. vllm/vllm-openai=sha256:... (change them at runtime with /api/admin/images)")
+	maxInFlight      = flag.Int("gateway-max-inflight", 0, "Maximum concurrent gateway requests; excess requests are queued fairly by API key (0 = unlimited)")
+	maxQueued        = flag.Int("gateway-max-queued", 0, "Maximum gateway requests waiting for -gateway-max-inflight; excess requests get a 503 with Retry-After (0 = unlimited)")
+	strictChunks     = flag.Bool("gateway-strict-chunks", false, "Rewrite streamed chat completion chunks to OpenAI's delta semantics (role only in the first delta, an empty finishing delta), for strict SDK parsers")
+	sseKeepAlive     = flag.Duration("gateway-sse-keepalive", gateway.DefaultSSEKeepAlive, "How long a streamed chat completion may go quiet before an SSE keep-alive comment is sent (0 = disabled)")
+	adminAddr        = flag.String("admin-addr", "127.0.0.1:6060", "Admin-only HTTP address serving pprof profiles (/debug/pprof/) and process metrics (/debug/metrics); keep it off public interfaces (empty to disable)")
+	shutdownTimeout  = flag.Duration("shutdown-timeout", 30*time.Second, "How long a graceful shutdown may take, e.g. for running jobs to finish, before the rest is cut off")
+	sseWriteTimeout  = flag.Duration("sse-write-timeout", sse.DefaultWriteTimeout, "How long an SSE client (chat completions, job output, logs) may take to accept an event before its stream is dropped (0 = no limit)")
+	transformsFile   = flag.String("gateway-transforms", "", "Optional path to a JSON file of gateway request/response transforms")
+	httpRateLimit    = flag.Float64("http-rate-limit", 0, "Maximum HTTP requests per second per API key, or per client address without one (0 = unlimited)")
+	httpRateBurst    = flag.Int("http-rate-burst", 20, "Requests a client may burst above -http-rate-limit")
+	retryRatio       = flag.Float64("gateway-retry-ratio", llm.DefaultRetryRatio, "Share of non-streamed gateway requests that may be retried once on another node when theirs becomes unreachable (0 = never)")
+	resultDir        = flag.String("result-dir", "", "Directory to offload large job results to (leave empty to keep results in memory)")
+	resultS3Bucket   = flag.String("result-s3-bucket", "", "S3 bucket to offload large job results to; credentials come from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY")
+	resultS3Endpoint = flag.String("result-s3-endpoint", "", "S3-compatible endpoint URL (defaults to AWS for the region)")
+	resultS3Region   = flag.String("result-s3-region", "us-east-1", "S3 region")
+	resultOffload    = flag.Int("result-offload-bytes", results.DefaultOffloadThreshold, "Job results larger than this are offloaded to the result store")
+	fileDir          = flag.String("file-dir", "", "Directory to keep files uploaded to /v1/files in (leave empty to disable uploads)")
+	fileS3Bucket     = flag.String("file-s3-bucket", "", "S3 bucket to keep files uploaded to /v1/files in; credentials come from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY")
+	fileS3Endpoint   = flag.String("file-s3-endpoint", "", "S3-compatible endpoint URL for -file-s3-bucket (defaults to AWS for the region)")
+	fileS3Region     = flag.String("file-s3-region", "us-east-1", "S3 region of -file-s3-bucket")
+	fileMaxBytes     = flag.Int64("file-max-bytes", files.DefaultMaxSize, "Largest file /v1/files accepts")
+	recordFile       = flag.String("record-file", "", "Append anonymized gateway requests and responses to this file for replay testing (leave empty to disable)")
+	recordSample     = flag.Float64("record-sample-rate", 1, "Fraction of gateway requests to record when -record-file is set")
+	auditLog         = flag.String("audit-log", "", "Append a JSON line per gateway request (user, API key ID, model, tokens) to this file (leave empty to disable)")
+	userQuotaRPM     = flag.Int("user-quota-rpm", 0, "Maximum gateway requests per minute per end user (OpenAI \"user\" field; 0 = unlimited)")
+	warmInterval     = flag.Duration("warm-replica-interval", replica.DefaultInterval, "How often warm replicas from the model catalog are checked and replaced")
+	modelUsageWindow = flag.Duration("model-usage-window", usage.DefaultModelWindow, "How far back per-model gateway usage is kept for /api/models/usage (0 = not tracked)")
+	userQuotaTokens  = flag.Int64("user-quota-tokens-per-day", 0, "Maximum prompt tokens per UTC day per end user (0 = unlimited)")
+	maxMessageSize   = flag.Int("grpc-max-message-bytes", transport.DefaultMaxMessageSize, "Largest gRPC message sent or received between gateway, orchestrator and node agents")
+	grpcWindowSize   = flag.Int("grpc-initial-window-bytes", 0, "gRPC flow-control window per stream and connection (0 = gRPC's dynamic window)")
+	tunnelPort       = flag.String("tunnel-port", "", "Port on which node agents behind NAT open reverse tunnels (leave empty to disable)")
+	federationFile   = flag.String("federation", "", "Optional path to a JSON file of other Orchion sites gateway requests may be sent to, e.g. when no local node can take them")
+	federationSync   = flag.Duration("federation-sync-interval", federation.DefaultSyncInterval, "How often federated sites are asked for the models they serve")
+	preflightOnly    = flag.Bool("preflight-only", false, "Run the startup checks (flags, config files, ports, writable paths, cached certificates), report every problem and exit")
 )
 
 func main() {
@@ -47,31 +140,218 @@ func main() {
 		"heartbeat_timeout": *heartbeatTimeout,
 	})
 
+	// Listen on the tailnet only when asked to
+	if err := resolveBindAddresses(logger); err != nil {
+		logger.Error("Orchestrator not started: can't resolve bind address", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+
+	// Check everything that can be checked up front and report all problems
+	// at once, rather than starting halfway and failing on the first one
+	if err := preflightChecks().Run(); err != nil {
+		var failed *preflight.Error
+		errors.As(err, &failed)
+		for _, f := range failed.Failures {
+			logger.Error("Preflight check failed", map[string]interface{}{
+				"check": f.Check,
+				"error": f.Err.Error(),
+			})
+		}
+		logger.Error("Orchestrator not started: fix the problems above", map[string]interface{}{
+			"failed_checks": len(failed.Failures),
+		})
+		os.Exit(1)
+	}
+	if *preflightOnly {
+		logger.Info("Preflight checks passed", nil)
+		return
+	}
+
 	// Create node registry
 	registry := node.NewInMemoryRegistry()
+	registry.SetTombstoneRetention(*tombstoneTTL)
 
 	// Create job queue
 	jobQueue := queue.NewJobQueue()
 
+	// Load model catalog
+	models := catalog.New()
+	if *modelCatalog != "" {
+		var err error
+		models, err = catalog.LoadFile(*modelCatalog)
+		if err != nil {
+			logger.Error("Failed to load model catalog", map[string]interface{}{
+				"path":  *modelCatalog,
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		logger.Info("Loaded model catalog", map[string]interface{}{
+			"path":        *modelCatalog,
+			"models":      len(models.Models),
+			"gpu_sharing": len(models.GPUSharing),
+		})
+	}
+
 	// Create scheduler
-	sched := scheduler.NewSimpleScheduler()
+	latencies := scheduler.NewLatencyTracker()
+	prefixes := scheduler.NewPrefixAffinity(*prefixTTL)
+	scorers := []scheduler.Scorer{prefixes}
+	if *embedPreferCPU {
+		scorers = append(scorers, scheduler.NewEmbeddingAffinityScorer(latencies, *embedLatencySLO))
+		logger.Info("Embedding CPU affinity enabled", map[string]interface{}{
+			"latency_slo": *embedLatencySLO,
+		})
+	}
+	// Large payloads avoid nodes behind slow links, e.g. Wi-Fi
+	network := scheduler.NewNetworkTracker()
+	if *bandwidthBytes > 0 {
+		scorers = append(scorers, scheduler.NewBandwidthScorer(network, *bandwidthBytes))
+	}
+	// Jobs prefer the nodes their data is on, as told by their locality labels
+	if *localityWeight > 0 {
+		scorers = append(scorers, scheduler.NewLocalityScorer(*localityWeight))
+	}
+	// Multi-node deployments reserve their nodes and route their model to the head node
+	deployments := deployment.NewManager(registry)
+	// Warm standby replicas from the catalog attract their model's traffic
+	replicas := replica.NewReconciler(registry, models)
+	replicas.SetFilter(deployments)
+	scorers = append(scorers, replicas)
+	// Models may require a minimum engine version and free VRAM from the
+	// catalog, nodes throttled for running hot only get traffic when all nodes
+	// are, and so do nodes warming up after registering
+	filters := []scheduler.Filter{
+		scheduler.NewSupportedModelFilter(), deployments, scheduler.NewEngineVersionFilter(models),
+		scheduler.NewVRAMFilter(models), scheduler.NewThrottleFilter(), scheduler.NewWarmupFilter(*nodeWarmup),
+	}
+	sched := scheduler.NewPipelineScheduler(filters, scorers)
+	// Catalog node weights split traffic between the nodes scoring best
+	sched.SetTieBreaker(scheduler.NewWeightedRandomScorer(models))
 
 	// Create orchestrator service
 	service := orchestrator.NewService(registry, jobQueue, sched)
+	service.SetLogger(logger)
+
+	// Keep recent hardware samples from heartbeats for dashboard graphs
+	history := node.NewHistory(*historySamples)
+	service.SetHistory(history)
+
+	// Keep node events for diagnosing flaky nodes after the fact
+	events := node.NewEventLog(*nodeEvents, *nodeEventTTL)
+	service.SetEvents(events)
+
+	// Pin engine images to a digest fleet-wide, so engines only upgrade when
+	// an admin rolls out a new digest
+	pins, err := images.ParsePins(*imagePins)
+	if err != nil {
+		logger.Error("Invalid -image-pins", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	service.SetImagePins(pins)
+
+	// Hand agents their settings from the orchestrator when they register
+	if *nodeProfiles != "" {
+		p, err := profiles.LoadFile(*nodeProfiles)
+		if err != nil {
+			logger.Error("Failed to load node profiles", map[string]interface{}{
+				"path":  *nodeProfiles,
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		service.SetNodeProfiles(p)
+		logger.Info("Loaded node profiles", map[string]interface{}{
+			"path":     *nodeProfiles,
+			"profiles": len(p.Profiles),
+		})
+	}
+
+	// Warn when a registered agent address can't be reached from here
+	service.SetProber(node.NewProber())
+	service.SetPreferTailnet(*preferTailnet)
+	service.SetNetworkTracker(network)
+
+	// Tell agents how much capacity to keep for interactive requests, as the
+	// catalog's GPU sharing schedule changes over the day
+	service.SetGPUSharing(models)
+
+	// Offload large job results so they don't accumulate in memory
+	resultStore, err := newResultStore()
+	if err != nil {
+		logger.Error("Failed to create result store", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if resultStore != nil {
+		service.SetResultStore(resultStore)
+		logger.Info("Job result offloading enabled", map[string]interface{}{
+			"threshold_bytes": *resultOffload,
+		})
+	}
+
+	// Uploaded files, e.g. job payloads too large to send with each submission
+	fileStore, err := newFileStore()
+	if err != nil {
+		logger.Error("Failed to create file store", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if fileStore != nil {
+		service.SetFileStore(fileStore)
+		logger.Info("File uploads enabled", map[string]interface{}{
+			"max_bytes": *fileMaxBytes,
+		})
+	}
 
 	// Create logging service
 	logService := logServicePkg.NewService()
 
 	// Create LLM service
 	llmService := llm.NewService(registry, sched)
+	grpcTransport := transport.Config{MaxMessageSize: *maxMessageSize, InitialWindowSize: int32(*grpcWindowSize)}
+	llmService.SetDialOptions(grpcTransport.DialOptions()...)
+	llmService.SetPreferTailnet(*preferTailnet)
+	llmService.SetLatencyTracker(latencies)
+	llmService.SetPrefixAffinity(prefixes)
+	if *retryRatio > 0 {
+		llmService.SetRetryBudget(llm.NewRetryBudget(*retryRatio))
+	}
+	deployments.SetDialer(llmService)
+	deployments.SetPreferTailnet(*preferTailnet)
+	replicas.SetDialer(llmService)
+	service.SetNodeDialer(llmService)
+
+	// Agents behind NAT connect in over reverse tunnels instead of being dialed
+	var tunnels *tunnel.Server
+	var tunnelLis net.Listener
+	if *tunnelPort != "" {
+		tunnelLis, err = net.Listen("tcp", net.JoinHostPort(*grpcBind, *tunnelPort))
+		if err != nil {
+			logger.Error("Failed to listen on tunnel port", map[string]interface{}{
+				"port":  *tunnelPort,
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		tunnels = tunnel.NewServer(logger)
+		llmService.SetTunnels(tunnels)
+	}
 
 	// Setup logger with streaming
 	streamer := logServicePkg.NewOrchestratorStreamer(logService)
 	logger.SetStreamer(streamer)
 	defer logger.Close()
+	logging.SetDefault(logger)
 
 	// Setup gRPC server
-	grpcLis, err := net.Listen("tcp", ":"+*port)
+	grpcLis, err := net.Listen("tcp", net.JoinHostPort(*grpcBind, *port))
 	if err != nil {
 		logger.Error("Failed to listen on gRPC port", map[string]interface{}{
 			"port":  *port,
@@ -80,186 +360,667 @@ func main() {
 		os.Exit(1)
 	}
 
-	grpcServer := grpc.NewServer()
+	// Roles of API keys on the dashboard API and gRPC admin methods
+	authz := auth.NewAuthorizer(*apiKey, strings.Split(*adminAPIKeys, ","))
+	authz.SetAnonymousRead(*anonymousRead)
+	roleKeys, err := auth.ParseRoleKeys(*apiKeyRoles)
+	if err != nil {
+		logger.Error("Invalid -api-key-roles", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	for key, role := range roleKeys {
+		authz.SetKey(key, role)
+	}
+	sessions, err := auth.NewSessions([]byte(*sessionSecret), *sessionTTL)
+	if err != nil {
+		logger.Error("Failed to create session signing key", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	authz.SetSessions(sessions)
+	service.SetAuthorizer(authz)
+	if authz.Enabled() {
+		logger.Info("API key roles enabled", map[string]interface{}{
+			"keys":           len(authz.Keys()),
+			"anonymous_read": *anonymousRead,
+		})
+	}
+
+	grpcServer := grpc.NewServer(append(grpcTransport.ServerOptions(),
+		grpc.ChainUnaryInterceptor(authz.UnaryInterceptor(auth.AdminMethods)))...)
 	pb.RegisterOrchestratorServer(grpcServer, service)
 	pb.RegisterOrchionLLMServer(grpcServer, llmService)
 	pb.RegisterLogStreamerServer(grpcServer, logService)
+	pbv2.RegisterOrchestratorServer(grpcServer, apiv2.NewOrchestratorServer(service))
+	pbv2.RegisterOrchionLLMServer(grpcServer, apiv2.NewLLMServer(llmService))
+
+	// Standard health checks and reflection for grpcurl, Kubernetes gRPC
+	// probes and load balancers
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	for name := range grpcServer.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
 
 	// Setup HTTP REST API server
-	mux := http.NewServeMux()
+	router := gateway.NewRouter()
 
 	// Dashboard API
-	mux.HandleFunc("/api/nodes", func(w http.ResponseWriter, r *http.Request) {
-		// Add CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		// Handle preflight requests
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+	router.HandleFunc("/api/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		req, err := api.ParseListNodesRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		ctx := context.Background()
-		resp, err := service.ListNodes(ctx, &pb.ListNodesRequest{})
+		resp, err := service.ListNodes(ctx, req)
+		if status.Code(err) == codes.InvalidArgument {
+			http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		api.SetNextPageToken(w, resp.NextPageToken)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp.Nodes)
-	})
+	}, gateway.CORS("GET, OPTIONS", "Content-Type, Authorization"))
 
-	// Logs streaming endpoint (Server-Sent Events)
-	mux.HandleFunc("/api/logs", func(w http.ResponseWriter, r *http.Request) {
-		// Set SSE headers
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Cache-Control")
-
-		// Handle preflight requests
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// Node details, per-node hardware history and model states
+	nodeMetrics := api.NewNodeMetricsHandler(registry, history)
+	nodeMetrics.SetDialer(llmService)
+	nodeMetrics.SetEvents(events)
+	nodeMetrics.SetNodeService(service)
+	router.Handle("/api/nodes/", nodeMetrics)
+
+	// Runtime log level switch
+	router.Handle("/api/admin/loglevel", logging.NewLevelHandler(logger))
+
+	// Dashboard login: API keys are exchanged for session tokens
+	loginHandler := api.NewLoginHandler(authz)
+	router.Handle("/api/auth/", loginHandler)
+
+	// API key management
+	router.HandleTree("/api/admin/keys", api.NewKeysHandler(authz))
 
+	// Operator notes and annotations on nodes
+	router.Handle("/api/admin/nodes/", api.NewNodeAnnotationsHandler(registry))
+
+	// Restoring nodes removed during an outage
+	removedNodes := api.NewRemovedNodesHandler(registry)
+	removedNodes.SetEvents(events)
+	router.HandleTree("/api/admin/removed-nodes", removedNodes)
+
+	// Fleet-wide engine image pins
+	router.HandleTree("/api/admin/images", api.NewImagePinsHandler(pins))
+
+	// Prompt prefix caching statistics
+	router.HandleFunc("/api/prefix-cache", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Register with the logging service to receive broadcasts
-		// For now, we'll create a simple broadcaster that sends keep-alive messages
-		// In a real implementation, we'd want persistent storage of logs
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prefixes.Stats())
+	}, gateway.CORS("GET, OPTIONS", "Content-Type"))
 
-		// Send a keep-alive message initially
-		fmt.Fprintf(w, "data: {\"type\": \"connected\"}\n\n")
-		if f, ok := w.(http.Flusher); ok {
-			f.Flush()
-		}
+	// Logs streaming endpoint (Server-Sent Events)
+	logsHandler := api.NewLogsHandler(logService)
+	logsHandler.SetWriteTimeout(*sseWriteTimeout)
+	router.HandleFunc("/api/logs", logsHandler.ServeHTTP, gateway.CORS("GET, OPTIONS", "Cache-Control"))
 
-		// Simple keep-alive loop (in production, would stream actual logs)
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-r.Context().Done():
-				return
-			case <-ticker.C:
-				fmt.Fprintf(w, "data: {\"type\": \"keepalive\", \"timestamp\": %d}\n\n", time.Now().Unix())
-				if f, ok := w.(http.Flusher); ok {
-					f.Flush()
-				}
-			}
+	// Job output streaming endpoint (Server-Sent Events)
+	jobsHandler := api.NewJobsHandler(jobQueue)
+	jobsHandler.SetWriteTimeout(*sseWriteTimeout)
+	if resultStore != nil {
+		jobsHandler.SetResultStore(resultStore)
+	}
+	router.HandleTree("/api/jobs", jobsHandler)
+	router.Handle("/api/job-groups/", jobsHandler)
+
+	// Multi-node model deployments
+	router.HandleTree("/api/deployments", api.NewDeploymentsHandler(deployments, models))
+
+	// OpenAI-compatible API Gateway
+	gw := gateway.NewGateway(loopbackAddress(*grpcBind, *port))
+	gw.SetDialOptions(grpcTransport.DialOptions()...)
+	gw.SetSSEKeepAlive(*sseKeepAlive)
+	gw.SetStrictChunks(*strictChunks)
+	gw.SetSSEWriteTimeout(*sseWriteTimeout)
+	if *transformsFile != "" {
+		transforms, err := gateway.LoadTransforms(*transformsFile)
+		if err != nil {
+			logger.Error("Failed to load gateway transforms", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
 		}
+		gw.SetTransforms(transforms)
+		logger.Info("Gateway transforms loaded", map[string]interface{}{
+			"file":       *transformsFile,
+			"transforms": transforms.Len(),
+		})
+	}
+	// The gateway shares the dashboard API's keys and roles, so keys admins
+	// add or remove at /api/admin/keys apply to /v1 as well
+	gw.SetAuthorizer(authz)
+	if *maxInFlight > 0 {
+		fairQueue := gateway.NewFairQueue(*maxInFlight)
+		fairQueue.SetMaxWaiting(*maxQueued)
+		gw.SetFairQueue(fairQueue)
+		logger.Info("Gateway fair queuing enabled", map[string]interface{}{
+			"max_inflight": *maxInFlight,
+			"max_queued":   *maxQueued,
+		})
+	}
+	usageTracker := usage.NewTracker(usage.Quota{
+		RequestsPerMinute: *userQuotaRPM,
+		TokensPerDay:      *userQuotaTokens,
 	})
+	if *auditLog != "" {
+		auditFile, err := os.OpenFile(*auditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			logger.Error("Failed to open audit log", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		defer auditFile.Close()
+		usageTracker.SetAuditLog(auditFile)
+		logger.Info("Gateway audit log enabled", map[string]interface{}{
+			"file": *auditLog,
+		})
+	}
+	if *userQuotaRPM > 0 || *userQuotaTokens > 0 {
+		logger.Info("Per-user quotas enabled", map[string]interface{}{
+			"requests_per_minute": *userQuotaRPM,
+			"tokens_per_day":      *userQuotaTokens,
+		})
+	}
+	usageTracker.SetModelWindow(*modelUsageWindow)
+	gw.SetUsageTracker(usageTracker)
+	router.Handle("/api/usage", api.NewUsageHandler(usageTracker))
+	router.Handle("/api/models/usage", api.NewModelUsageHandler(usageTracker))
+	var record []gateway.Middleware
+	if *recordFile != "" {
+		recorder, err := replay.NewRecorder(*recordFile, *recordSample)
+		if err != nil {
+			logger.Error("Failed to open record file", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		defer recorder.Close()
+		record = append(record, recorder.Wrap)
+		logger.Info("Recording gateway traffic", map[string]interface{}{
+			"file":        *recordFile,
+			"sample_rate": *recordSample,
+		})
+	}
+	// Other Orchion sites take requests when local nodes can't, or first for
+	// the models they're preferred for
+	var sites *federation.Federation
+	if *federationFile != "" {
+		config, err := federation.LoadFile(*federationFile)
+		if err != nil {
+			logger.Error("Failed to load federation config", map[string]interface{}{
+				"path":  *federationFile,
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		sites = federation.New(config)
+		gw.SetFederation(sites)
+		router.Handle("/api/federation", api.NewFederationHandler(sites))
+		logger.Info("Federation enabled", map[string]interface{}{
+			"site":  config.Site,
+			"sites": len(config.Sites),
+		})
+	}
+	router.HandleFunc("/v1/chat/completions", gw.ChatCompletionsHandler, record...)
+	router.HandleFunc("/v1/embeddings", gw.EmbeddingsHandler, record...)
+	router.HandleFunc("/v1/audio/speech", gw.SpeechHandler)
+	router.HandleFunc("/v1/models", gw.ModelsHandler)
+	if fileStore != nil {
+		gw.SetFileStore(fileStore, *fileMaxBytes)
+		router.HandleTree("/v1/files", http.HandlerFunc(gw.FilesHandler))
+	}
 
-	// OpenAI-compatible API Gateway
-	gateway := gateway.NewGateway("localhost:" + *port)
-	if *apiKey != "" {
-		gateway.SetAPIKey(*apiKey)
-		logger.Info("API key authentication enabled", nil)
+	// OpenAPI document of the REST APIs, for client generators and Swagger UI.
+	// It's outside /api/, so it's served without an API key.
+	router.Handle("/openapi.json", openapi.Handler(describeAPI()))
+
+	var proxies *auth.TrustedProxies
+	if *trustedProxies != "" {
+		proxies, err = auth.ParseTrustedProxies(*trustedProxies)
+		if err != nil {
+			logger.Error("Invalid -trusted-proxies", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		logger.Info("Trusting client addresses from reverse proxies", map[string]interface{}{
+			"trusted_proxies": *trustedProxies,
+			"proxy_protocol":  *proxyProtocol,
+		})
+	} else if *proxyProtocol {
+		logger.Error("-proxy-protocol needs -trusted-proxies", nil)
+		os.Exit(1)
+	}
+
+	// Every request passes the middleware in this order
+	if proxies != nil {
+		router.Use(proxies.Middleware)
+	}
+	router.Use(gateway.LogRequests(logger))
+	if *allowedCIDRs != "" {
+		allowList, err := auth.ParseAllowList(*allowedCIDRs)
+		if err != nil {
+			logger.Error("Invalid -allowed-cidrs", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		router.Use(allowList.Middleware)
+		logger.Info("HTTP client allow-list enabled", map[string]interface{}{
+			"allowed_cidrs": *allowedCIDRs,
+		})
+	}
+	if *httpRateLimit > 0 {
+		router.Use(gateway.NewRateLimiter(*httpRateLimit, *httpRateBurst).Middleware)
+		logger.Info("HTTP rate limiting enabled", map[string]interface{}{
+			"requests_per_second": *httpRateLimit,
+			"burst":               *httpRateBurst,
+		})
 	}
-	mux.HandleFunc("/v1/chat/completions", gateway.ChatCompletionsHandler)
-	mux.HandleFunc("/v1/embeddings", gateway.EmbeddingsHandler)
+	router.Use(authz.Middleware)
+	logger.Debug("HTTP routes registered", map[string]interface{}{
+		"routes": strings.Join(router.Routes(), ","),
+	})
 
 	httpServer := &http.Server{
-		Addr:    ":" + *httpPort,
-		Handler: mux,
+		Addr:    net.JoinHostPort(*httpBind, *httpPort),
+		Handler: router,
 	}
 
-	// Start heartbeat monitor goroutine
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	go monitorHeartbeats(ctx, registry, *heartbeatTimeout, logger)
+	// Automatic HTTPS: certificates are requested on the first TLS handshake
+	// for each domain and renewed in the background
+	var acmeServer *http.Server
+	if *tlsDomains != "" {
+		domains, err := acme.ParseDomains(*tlsDomains)
+		if err != nil {
+			logger.Error("Invalid -tls-domains", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		certs := acme.NewManager(acme.Config{
+			Domains:      domains,
+			CacheDir:     *tlsCacheDir,
+			Email:        *tlsEmail,
+			DirectoryURL: *acmeDirectory,
+		})
+		httpServer.TLSConfig = certs.TLSConfig()
+		if *acmeHTTPPort != "" {
+			acmeServer = &http.Server{
+				Addr:    net.JoinHostPort(*httpBind, *acmeHTTPPort),
+				Handler: certs.HTTPHandler(nil),
+			}
+		}
+		logger.Info("Automatic HTTPS enabled", map[string]interface{}{
+			"domains":   strings.Join(domains, ","),
+			"cache_dir": *tlsCacheDir,
+		})
+	}
+
+	// Components start in dependency order and stop in reverse: the gateway
+	// stops taking requests, the job processor drains, then gRPC stops
+	runner := lifecycle.NewRunner(*shutdownTimeout, logger)
+
+	// Profiles and process metrics, on their own admin-only listener since
+	// they're served without authentication. It stops last, so a stuck
+	// shutdown can still be profiled.
+	if *adminAddr != "" {
+		adminMux := http.NewServeMux()
+		diagnostics.Register(adminMux)
+		adminServer := &http.Server{
+			Addr:    *adminAddr,
+			Handler: adminMux,
+		}
+		runner.Add(lifecycle.Component{
+			Name: "admin",
+			Start: func(ctx context.Context) error {
+				logger.Info("Admin endpoint listening", map[string]interface{}{
+					"addr": *adminAddr,
+				})
+				return nil
+			},
+			// The orchestrator runs on without its diagnostics
+			Serve: func() error {
+				if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					logger.Error("Failed to serve admin endpoint", map[string]interface{}{
+						"error": err.Error(),
+					})
+				}
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return adminServer.Close()
+			},
+		})
+	}
+
+	// Evict nodes that stop sending heartbeats
+	monitor := node.NewHeartbeatMonitor(registry, *heartbeatTimeout)
+	monitor.OnCheck(events.Prune)
+	monitor.OnCheck(registry.PruneTombstones)
+	monitor.OnLost(func(nodeID string) {
+		events.Record(nodeID, node.EventHeartbeatLost, fmt.Sprintf("no heartbeat for %s", node.DefaultStaleAfter))
+	})
+	monitor.OnEvict(func(nodeID string) {
+		history.Remove(nodeID)
+		events.Record(nodeID, node.EventEvicted, fmt.Sprintf("no heartbeat for %s", *heartbeatTimeout))
+	})
+	monitor.OnEvict(llmService.ForgetNode)
+	monitor.OnEvict(network.Forget)
+	monitor.OnEvict(latencies.ForgetNode)
+	runner.Add(lifecycle.Component{
+		Name: "node monitor",
+		Start: func(ctx context.Context) error {
+			monitor.Start(logging.NewContext(ctx, logger))
+			// Keep the catalog's warm standby replicas loaded
+			if len(models.WarmReplicas()) > 0 {
+				replicas.Start(logging.NewContext(ctx, logger), *warmInterval)
+			}
+			// Learn which models federated sites serve
+			if sites != nil {
+				sites.Start(ctx, *federationSync)
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			monitor.Stop()
+			return nil
+		},
+	})
+
+	// Accept reverse tunnels from agents behind NAT
+	if tunnels != nil {
+		runner.Add(lifecycle.Component{
+			Name: "tunnels",
+			Start: func(ctx context.Context) error {
+				logger.Info("Node agent tunnels listening", map[string]interface{}{
+					"port": *tunnelPort,
+				})
+				return nil
+			},
+			Serve: func() error { return tunnels.Serve(tunnelLis) },
+			Stop: func(ctx context.Context) error {
+				tunnelLis.Close()
+				tunnels.Close()
+				return nil
+			},
+		})
+	}
+
+	// Agents keep heartbeating over gRPC while queued jobs drain
+	runner.Add(lifecycle.Component{
+		Name: "grpc",
+		Start: func(ctx context.Context) error {
+			logger.Info("gRPC server listening", map[string]interface{}{
+				"port": *port,
+			})
+			return nil
+		},
+		Serve: func() error { return grpcServer.Serve(grpcLis) },
+		Stop: func(ctx context.Context) error {
+			healthServer.Shutdown()
+			return lifecycle.Graceful(ctx, grpcServer.GracefulStop, grpcServer.Stop)
+		},
+	})
 
-	// Start job processor
+	// Job processor
 	processor := orchestrator.NewJobProcessor(jobQueue, sched, registry)
-	processor.Start(ctx)
+	processor.SetLatencyTracker(latencies)
+	processor.SetTunnels(tunnels)
+	processor.SetDialOptions(grpcTransport.DialOptions()...)
+	processor.SetPreferTailnet(*preferTailnet)
+	if resultStore != nil {
+		processor.SetResultStore(resultStore, *resultOffload)
+	}
+	monitor.OnEvict(processor.ForgetNode)
+	runner.Add(lifecycle.Component{
+		Name: "job processor",
+		Start: func(ctx context.Context) error {
+			processor.Start(logging.NewContext(ctx, logger))
+			return nil
+		},
+		Stop: processor.Stop,
+	})
+
+	// HTTP server: REST API, dashboard API and gateway
+	httpLis, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		logger.Error("Failed to listen on HTTP port", map[string]interface{}{
+			"port":  *httpPort,
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if *proxyProtocol {
+		httpLis = auth.NewProxyProtocolListener(httpLis, proxies)
+	}
+	runner.Add(lifecycle.Component{
+		Name: "http",
+		Start: func(ctx context.Context) error {
+			logger.Info("HTTP REST API listening", map[string]interface{}{
+				"port": *httpPort,
+			})
+			return nil
+		},
+		Serve: func() error {
+			if httpServer.TLSConfig != nil {
+				return httpServer.ServeTLS(httpLis, "", "")
+			}
+			return httpServer.Serve(httpLis)
+		},
+		Stop: func(ctx context.Context) error {
+			return stopHTTP(ctx, httpServer)
+		},
+	})
+
+	// Answer ACME HTTP-01 challenges and redirect plain HTTP to HTTPS
+	if acmeServer != nil {
+		runner.Add(lifecycle.Component{
+			Name: "acme",
+			Start: func(ctx context.Context) error {
+				logger.Info("ACME HTTP-01 challenges listening", map[string]interface{}{
+					"port": *acmeHTTPPort,
+				})
+				return nil
+			},
+			Serve: acmeServer.ListenAndServe,
+			Stop: func(ctx context.Context) error {
+				return stopHTTP(ctx, acmeServer)
+			},
+		})
+	}
 
 	// Graceful shutdown handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
 	go func() {
 		sig := <-sigChan
 		logger.Info("Received shutdown signal, shutting down gracefully", map[string]interface{}{
 			"signal": sig.String(),
 		})
-
-		// Shutdown HTTP server
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer shutdownCancel()
-		httpServer.Shutdown(shutdownCtx)
-
-		// Shutdown gRPC server
-		grpcServer.GracefulStop()
-	}()
-
-	// Start HTTP server
-	go func() {
-		logger.Info("HTTP REST API listening", map[string]interface{}{
-			"port": *httpPort,
-		})
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Failed to serve HTTP", map[string]interface{}{
-				"error": err.Error(),
-			})
-			os.Exit(1)
-		}
+		cancel()
 	}()
 
-	// Start gRPC server (blocking)
-	logger.Info("gRPC server listening", map[string]interface{}{
-		"port": *port,
-	})
-	if err := grpcServer.Serve(grpcLis); err != nil {
-		logger.Error("Failed to serve gRPC", map[string]interface{}{
+	if err := runner.Run(ctx); err != nil {
+		logger.Error("Orchestrator stopped with an error", map[string]interface{}{
 			"error": err.Error(),
 		})
 		os.Exit(1)
 	}
 }
 
-// monitorHeartbeats periodically checks for stale nodes and removes them
-func monitorHeartbeats(ctx context.Context, registry node.Registry, timeout time.Duration, logger logging.Logger) {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+// stopHTTP shuts down an HTTP server within half of ctx's time, then closes
+// the connections left, e.g. SSE streams that never go idle, so the
+// components stopping after it still get time
+func stopHTTP(ctx context.Context, server *http.Server) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/2)
+		defer cancel()
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		return server.Close()
+	}
+	return nil
+}
+
+// newResultStore creates the store large job results are offloaded to, or
+// returns nil if offloading isn't configured
+func newResultStore() (results.Store, error) {
+	switch {
+	case *resultS3Bucket != "":
+		return results.NewS3Store(results.S3Config{
+			Endpoint:     *resultS3Endpoint,
+			Region:       *resultS3Region,
+			Bucket:       *resultS3Bucket,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		})
+	case *resultDir != "":
+		return results.NewDiskStore(*resultDir)
+	default:
+		return nil, nil
+	}
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			staleNodes := registry.CheckHeartbeats(timeout)
-			if len(staleNodes) > 0 {
-				logger.Warn("Found stale nodes, removing", map[string]interface{}{
-					"count":   len(staleNodes),
-					"timeout": timeout,
-				})
-				for _, nodeID := range staleNodes {
-					if err := registry.Remove(nodeID); err != nil {
-						logger.Error("Failed to remove stale node", map[string]interface{}{
-							"node_id": nodeID,
-							"error":   err.Error(),
-						})
-					} else {
-						logger.Info("Removed stale node", map[string]interface{}{
-							"node_id": nodeID,
-						})
-					}
-				}
-			}
+// newFileStore creates the store of files uploaded to /v1/files, or returns
+// nil if uploads aren't enabled
+func newFileStore() (*files.Store, error) {
+	var blobs results.Store
+	var err error
+	switch {
+	case *fileS3Bucket != "":
+		blobs, err = results.NewS3Store(results.S3Config{
+			Endpoint:     *fileS3Endpoint,
+			Region:       *fileS3Region,
+			Bucket:       *fileS3Bucket,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		})
+	case *fileDir != "":
+		blobs, err = results.NewDiskStore(*fileDir)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return files.NewStore(blobs), nil
+}
+
+// resolveBindAddresses resolves the bind address flags naming a network
+// interface, or tailnet for this host's tailnet address, to the IP to listen
+// on
+func resolveBindAddresses(logger logging.Logger) error {
+	binds := []struct {
+		name string
+		bind *string
+	}{{"-grpc-bind-address", grpcBind}, {"-http-bind-address", httpBind}}
+	for _, b := range binds {
+		name, bind := b.name, b.bind
+		host, err := tailnet.ResolveHost(*bind)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", name, *bind, err)
+		}
+		if host != *bind {
+			logger.Info("Resolved bind address", map[string]interface{}{
+				"flag":    name,
+				"bind":    *bind,
+				"address": host,
+			})
+			*bind = host
+		}
+	}
+	if *adminAddr != "" {
+		addr, err := tailnet.ResolveAddr(*adminAddr)
+		if err != nil {
+			return fmt.Errorf("-admin-addr %s: %w", *adminAddr, err)
 		}
+		*adminAddr = addr
+	}
+	return nil
+}
+
+// loopbackAddress returns the address the in-process gateway dials to reach
+// the gRPC server listening on bind:port
+func loopbackAddress(bind, port string) string {
+	if ip := net.ParseIP(bind); bind == "" || (ip != nil && ip.IsUnspecified()) {
+		return net.JoinHostPort("localhost", port)
 	}
+	return net.JoinHostPort(bind, port)
+}
+
+// describeAPI builds the OpenAPI document of the gateway and REST APIs
+func describeAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Orchion",
+		Version:     "1",
+		Description: "OpenAI-compatible inference gateway and cluster management API of an Orchion orchestrator",
+	})
+	doc.AddBearerAuth("API key or login session token, required when the orchestrator has API keys configured")
+	api.DescribeAPI(doc)
+	gateway.DescribeAPI(doc)
+
+	// Endpoints served by main
+	doc.Add(http.MethodGet, "/api/prefix-cache", &openapi.Operation{
+		Summary:     "Get prompt prefix caching statistics",
+		OperationID: "getPrefixCacheStats",
+		Tags:        []string{"usage"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Prefix caching statistics since startup", doc.SchemaOf(scheduler.PrefixCacheStats{})),
+		},
+	})
+	level := doc.SchemaOf(struct {
+		Level string `json:"level"`
+	}{})
+	doc.Add(http.MethodGet, "/api/admin/loglevel", &openapi.Operation{
+		Summary:     "Get the log level",
+		OperationID: "getLogLevel",
+		Tags:        []string{"admin"},
+		Responses:   map[string]*openapi.Response{"200": openapi.JSONResponse("The current log level", level)},
+	})
+	doc.Add(http.MethodPut, "/api/admin/loglevel", &openapi.Operation{
+		Summary:     "Set the log level",
+		OperationID: "setLogLevel",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.JSONBody(level),
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("The new log level", level),
+			"400": openapi.TextResponse("Unknown log level"),
+		},
+	})
+	return doc
 }
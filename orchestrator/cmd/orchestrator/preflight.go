@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/acme"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+	"github.com/Orchion/Orchion/orchestrator/internal/gateway"
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
+	"github.com/Orchion/Orchion/orchestrator/internal/preflight"
+	"github.com/Orchion/Orchion/orchestrator/internal/profiles"
+)
+
+// preflightChecks returns the checks run before the orchestrator starts
+// anything: flag values and the files they name, the ports it listens on,
+// the paths it writes to and its cached TLS certificates
+func preflightChecks() *preflight.Checks {
+	checks := &preflight.Checks{}
+
+	// Configuration
+	checks.Add("-heartbeat-timeout", func() error {
+		if *heartbeatTimeout <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	checks.Add("-gateway-retry-ratio", func() error { return preflight.InRange(*retryRatio, 0, 1) })
+	checks.Add("-record-sample-rate", func() error { return preflight.InRange(*recordSample, 0, 1) })
+	checks.Add("-gateway-max-queued", func() error {
+		if *maxQueued < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	checks.Add("-locality-weight", func() error {
+		if *localityWeight < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	})
+	checks.Add("-file-max-bytes", func() error {
+		if *fileMaxBytes <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	checks.Add("-grpc-max-message-bytes", func() error {
+		if *maxMessageSize <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	})
+	if *httpRateLimit > 0 {
+		checks.Add("-http-rate-burst", func() error {
+			if *httpRateBurst < 1 {
+				return errors.New("must be at least 1 with -http-rate-limit")
+			}
+			return nil
+		})
+	}
+	if *resultDir != "" && *resultS3Bucket != "" {
+		checks.Add("-result-dir", func() error {
+			return errors.New("set either -result-dir or -result-s3-bucket, not both")
+		})
+	}
+	if *fileDir != "" && *fileS3Bucket != "" {
+		checks.Add("-file-dir", func() error {
+			return errors.New("set either -file-dir or -file-s3-bucket, not both")
+		})
+	}
+	// Results are stored under their job's ID, which clients choose, so a job
+	// named like a file ID would replace the upload
+	if *fileDir != "" && *resultDir != "" {
+		checks.Add("-file-dir", func() error {
+			if sameDir(*fileDir, *resultDir) {
+				return errors.New("must not be the -result-dir")
+			}
+			return nil
+		})
+	}
+	if *fileS3Bucket != "" && *fileS3Bucket == *resultS3Bucket && *fileS3Endpoint == *resultS3Endpoint {
+		checks.Add("-file-s3-bucket", func() error {
+			return errors.New("must not be the -result-s3-bucket")
+		})
+	}
+	if *modelCatalog != "" {
+		checks.Add("-model-catalog", func() error {
+			_, err := catalog.LoadFile(*modelCatalog)
+			return err
+		})
+	}
+	if *nodeProfiles != "" {
+		checks.Add("-node-profiles", func() error {
+			_, err := profiles.LoadFile(*nodeProfiles)
+			return err
+		})
+	}
+	if *transformsFile != "" {
+		checks.Add("-gateway-transforms", func() error {
+			_, err := gateway.LoadTransforms(*transformsFile)
+			return err
+		})
+	}
+	if *federationFile != "" {
+		checks.Add("-federation", func() error {
+			_, err := federation.LoadFile(*federationFile)
+			return err
+		})
+		checks.Add("-federation-sync-interval", func() error {
+			if *federationSync <= 0 {
+				return errors.New("must be positive")
+			}
+			return nil
+		})
+	}
+	checks.Add("-image-pins", func() error {
+		_, err := images.ParsePins(*imagePins)
+		return err
+	})
+	checks.Add("-api-key-roles", func() error {
+		_, err := auth.ParseRoleKeys(*apiKeyRoles)
+		return err
+	})
+	if *trustedProxies != "" {
+		checks.Add("-trusted-proxies", func() error {
+			_, err := auth.ParseTrustedProxies(*trustedProxies)
+			return err
+		})
+	} else if *proxyProtocol {
+		checks.Add("-proxy-protocol", func() error { return errors.New("needs -trusted-proxies") })
+	}
+	if *allowedCIDRs != "" {
+		checks.Add("-allowed-cidrs", func() error {
+			_, err := auth.ParseAllowList(*allowedCIDRs)
+			return err
+		})
+	}
+
+	// Ports
+	addrs := map[string]string{
+		"-port":      net.JoinHostPort(*grpcBind, *port),
+		"-http-port": net.JoinHostPort(*httpBind, *httpPort),
+	}
+	if *tunnelPort != "" {
+		addrs["-tunnel-port"] = net.JoinHostPort(*grpcBind, *tunnelPort)
+	}
+	if *adminAddr != "" {
+		addrs["-admin-addr"] = *adminAddr
+	}
+	if *tlsDomains != "" && *acmeHTTPPort != "" {
+		addrs["-acme-http-port"] = net.JoinHostPort(*httpBind, *acmeHTTPPort)
+	}
+	checks.Add("ports", func() error { return preflight.DistinctPorts(addrs) })
+	for _, name := range []string{"-port", "-http-port", "-tunnel-port", "-admin-addr", "-acme-http-port"} {
+		if addr, ok := addrs[name]; ok {
+			checks.Add(name, func() error { return preflight.Bindable(addr) })
+		}
+	}
+
+	// Paths written to
+	if *resultDir != "" {
+		checks.Add("-result-dir", func() error { return preflight.WritableDir(*resultDir) })
+	}
+	if *fileDir != "" {
+		checks.Add("-file-dir", func() error { return preflight.WritableDir(*fileDir) })
+	}
+	if *recordFile != "" {
+		checks.Add("-record-file", func() error { return preflight.WritableFile(*recordFile) })
+	}
+	if *auditLog != "" {
+		checks.Add("-audit-log", func() error { return preflight.WritableFile(*auditLog) })
+	}
+
+	// TLS
+	if *tlsDomains != "" {
+		checks.Add("-tls-domains", func() error {
+			domains, err := acme.ParseDomains(*tlsDomains)
+			if err != nil {
+				return err
+			}
+			if err := acme.CheckCache(*tlsCacheDir, domains, time.Now()); err != nil {
+				return fmt.Errorf("-tls-cache-dir %s: %w", *tlsCacheDir, err)
+			}
+			return nil
+		})
+	}
+	return checks
+}
+
+// sameDir reports whether two directory flags name the same directory
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
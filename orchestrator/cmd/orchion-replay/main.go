@@ -0,0 +1,74 @@
+// Command orchion-replay re-sends requests captured with the orchestrator's
+// -record-file flag through a gateway and reports output and latency changes.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/replay"
+)
+
+var (
+	recordFile = flag.String("file", "", "Record file written by the orchestrator's -record-file flag")
+	gatewayURL = flag.String("gateway", "http://localhost:8080", "Base URL of the gateway to replay against")
+	apiKey     = flag.String("api-key", "", "API key for the gateway (leave empty if authentication is disabled)")
+	timeout    = flag.Duration("timeout", 5*time.Minute, "Timeout per replayed request")
+	showAll    = flag.Bool("v", false, "Report every request, not only mismatches")
+)
+
+func main() {
+	flag.Parse()
+	if *recordFile == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*recordFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open record file: %v\n", err)
+		os.Exit(1)
+	}
+	records, err := replay.ReadRecords(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read record file: %v\n", err)
+		os.Exit(1)
+	}
+
+	replayer := &replay.Replayer{
+		BaseURL: *gatewayURL,
+		APIKey:  *apiKey,
+		Client:  &http.Client{Timeout: *timeout},
+	}
+
+	results := make([]replay.Result, 0, len(records))
+	for _, record := range records {
+		result := replayer.Replay(context.Background(), record)
+		results = append(results, result)
+
+		switch {
+		case result.Error != "":
+			fmt.Printf("ERROR %s %s: %s\n", result.ID, result.Endpoint, result.Error)
+		case !result.Match:
+			fmt.Printf("DIFF  %s %s (%v -> %v): %s\n", result.ID, result.Endpoint, result.OldLatency, result.NewLatency.Round(time.Millisecond), result.Diff)
+		case *showAll:
+			fmt.Printf("OK    %s %s (%v -> %v)\n", result.ID, result.Endpoint, result.OldLatency, result.NewLatency.Round(time.Millisecond))
+		}
+	}
+
+	summary := replay.Summarize(results)
+	fmt.Printf("\n%d requests: %d matched, %d output mismatches, %d status mismatches, %d errors\n",
+		summary.Total, summary.Total-summary.OutputMismatches-summary.StatusMismatches-summary.Errors,
+		summary.OutputMismatches, summary.StatusMismatches, summary.Errors)
+	fmt.Printf("latency p50 %v -> %v, p95 %v -> %v\n",
+		summary.OldP50, summary.NewP50.Round(time.Millisecond), summary.OldP95, summary.NewP95.Round(time.Millisecond))
+
+	if summary.OutputMismatches+summary.StatusMismatches+summary.Errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -2,7 +2,6 @@ package integration
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net"
 	"sync"
@@ -13,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
@@ -304,14 +304,15 @@ func TestOrchestrator_JobSubmission(t *testing.T) {
 		Stream: true,
 	}
 
-	// Serialize the chat request (in practice this would be done properly)
-	payload, err := json.Marshal(chatReq)
+	// Submit the chat request as JSON
+	payload, err := protojson.Marshal(chatReq)
 	require.NoError(t, err)
 
 	jobReq := &pb.SubmitJobRequest{
-		JobId:   "test-job-123",
-		JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
-		Payload: payload,
+		JobId:       "test-job-123",
+		JobType:     pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+		Payload:     payload,
+		ContentType: orchestrator.JSONContentType,
 	}
 
 	jobResp, err := ts.service.SubmitJob(ctx, jobReq)
@@ -0,0 +1,166 @@
+// Package acme obtains and renews TLS certificates for the HTTP gateway from
+// Let's Encrypt or another ACME CA, answering HTTP-01 and TLS-ALPN-01
+// challenges itself, so the orchestrator can serve HTTPS without a reverse
+// proxy.
+package acme
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// DefaultCacheDir is where certificates and the ACME account key are kept
+const DefaultCacheDir = "orchion-certs"
+
+// Config configures certificate management
+type Config struct {
+	Domains      []string // Names to get certificates for; no others are served
+	CacheDir     string   // Directory persisting certificates across restarts
+	Email        string   // Optional contact for expiry and problem notices
+	DirectoryURL string   // ACME directory; defaults to Let's Encrypt production
+}
+
+// ParseDomains parses a comma-separated list of domain names. Wildcards and
+// IP addresses are rejected: neither HTTP-01 nor TLS-ALPN-01 can validate them.
+func ParseDomains(spec string) ([]string, error) {
+	var domains []string
+	for _, domain := range strings.Split(spec, ",") {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			continue
+		}
+		if err := validateDomain(domain); err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains in %q", spec)
+	}
+	return domains, nil
+}
+
+// validateDomain checks a name is a fully qualified DNS name
+func validateDomain(domain string) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 || len(domain) > 253 {
+		return fmt.Errorf("invalid domain %q: must be a fully qualified name such as gpu.example.com", domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid domain %q", domain)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("invalid domain %q: wildcards and IP addresses can't be validated", domain)
+			}
+		}
+	}
+	if last := labels[len(labels)-1]; strings.Trim(last, "0123456789") == "" {
+		return fmt.Errorf("invalid domain %q: wildcards and IP addresses can't be validated", domain)
+	}
+	return nil
+}
+
+// NewManager creates a certificate manager for cfg. Serve HTTPS with its
+// TLSConfig, which also answers TLS-ALPN-01 challenges, and serve its
+// HTTPHandler on port 80 for HTTP-01 challenges.
+func NewManager(cfg Config) *autocert.Manager {
+	if cfg.CacheDir == "" {
+		cfg.CacheDir = DefaultCacheDir
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.CacheDir),
+		HostPolicy: autocert.HostWhitelist(cfg.Domains...),
+		Email:      cfg.Email,
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+	return m
+}
+
+// CheckCache checks that certificates can be kept in dir, creating it if
+// needed, and that the certificates already cached for domains are usable.
+// Without a writable cache every restart requests new certificates, which
+// soon runs into the CA's rate limits.
+func CheckCache(dir string, domains []string, now time.Time) error {
+	if dir == "" {
+		dir = DefaultCacheDir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create certificate cache: %w", err)
+	}
+	probe, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("certificate cache is not writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	var errs []error
+	for _, domain := range domains {
+		// autocert caches ECDSA certificates under the domain and RSA ones,
+		// for old clients, under domain+rsa
+		for _, name := range []string{domain, domain + "+rsa"} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err == nil {
+				err = checkCachedCert(data, domain, now)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cached certificate %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkCachedCert checks a cached private key and certificate chain. Expired
+// certificates are fine: they're renewed on the next handshake.
+func checkCachedCert(data []byte, domain string, now time.Time) error {
+	var key bool
+	var leaf *x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch {
+		case strings.Contains(block.Type, "PRIVATE KEY"):
+			key = true
+		case block.Type == "CERTIFICATE" && leaf == nil:
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return err
+			}
+			leaf = cert
+		}
+	}
+	switch {
+	case !key:
+		return errors.New("no private key")
+	case leaf == nil:
+		return errors.New("no certificate")
+	}
+	if err := leaf.VerifyHostname(domain); err != nil {
+		return err
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("not valid until %s; check the system clock", leaf.NotBefore.Format(time.RFC3339))
+	}
+	return nil
+}
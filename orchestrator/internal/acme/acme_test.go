@@ -0,0 +1,101 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDomains(t *testing.T) {
+	domains, err := ParseDomains(" GPU.example.com., api.example.com ,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu.example.com", "api.example.com"}, domains)
+
+	for _, spec := range []string{"", "localhost", "*.example.com", "192.168.1.10", "-bad.example.com", "a..example.com", "exa mple.com"} {
+		_, err := ParseDomains(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNewManager(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(Config{
+		Domains:      []string{"gpu.example.com"},
+		CacheDir:     dir,
+		Email:        "ops@example.com",
+		DirectoryURL: "https://acme-staging-v02.api.letsencrypt.org/directory",
+	})
+
+	assert.NoError(t, m.HostPolicy(context.Background(), "gpu.example.com"))
+	assert.Error(t, m.HostPolicy(context.Background(), "other.example.com"))
+	assert.Equal(t, "https://acme-staging-v02.api.letsencrypt.org/directory", m.Client.DirectoryURL)
+	assert.Contains(t, m.TLSConfig().NextProtos, "acme-tls/1")
+}
+
+// cachedCert returns an autocert cache entry: a key and a self-signed
+// certificate for domain
+func cachedCert(t *testing.T, domain string, notBefore time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{domain},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return append(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+}
+
+func TestCheckCache(t *testing.T) {
+	now := time.Now()
+
+	t.Run("creates missing directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "certs")
+		require.NoError(t, CheckCache(dir, []string{"gpu.example.com"}, now))
+		assert.DirExists(t, dir)
+	})
+
+	t.Run("valid and expired certificates", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gpu.example.com"), cachedCert(t, "gpu.example.com", now.Add(-time.Hour)), 0o600))
+		// Expired certificates are renewed, so they don't fail
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gpu.example.com+rsa"), cachedCert(t, "gpu.example.com", now.Add(-365*24*time.Hour)), 0o600))
+		assert.NoError(t, CheckCache(dir, []string{"gpu.example.com"}, now))
+	})
+
+	t.Run("unusable certificates", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "gpu.example.com"), cachedCert(t, "other.example.com", now.Add(-time.Hour)), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "api.example.com"), []byte("garbage"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.example.com"), cachedCert(t, "new.example.com", now.Add(time.Hour)), 0o600))
+
+		err := CheckCache(dir, []string{"gpu.example.com", "api.example.com", "new.example.com"}, now)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cached certificate gpu.example.com")
+		assert.Contains(t, err.Error(), "cached certificate api.example.com: no private key")
+		assert.Contains(t, err.Error(), "check the system clock")
+	})
+
+	t.Run("not writable", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, nil, 0o600))
+		assert.Error(t, CheckCache(file, nil, now))
+	})
+}
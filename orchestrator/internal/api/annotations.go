@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// Limits on operator annotations, keeping /api/nodes responses small
+const (
+	maxNotesLength      = 4096
+	maxAnnotations      = 64
+	maxAnnotationKey    = 128
+	maxAnnotationLength = 1024
+)
+
+// NodeAnnotationsHandler lets operators attach notes and key/value
+// annotations to nodes
+type NodeAnnotationsHandler struct {
+	registry node.Registry
+}
+
+// NewNodeAnnotationsHandler creates a new node annotations handler
+func NewNodeAnnotationsHandler(registry node.Registry) *NodeAnnotationsHandler {
+	return &NodeAnnotationsHandler{registry: registry}
+}
+
+// nodeAnnotations is the body of annotation requests and responses
+type nodeAnnotations struct {
+	Notes       string            `json:"notes"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ServeHTTP serves GET and PATCH /api/admin/nodes/{id}/annotations. PATCH
+// replaces the notes if "notes" is present and merges "annotations", where a
+// null value removes a key.
+func (h *NodeAnnotationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PATCH, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/nodes/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "annotations" {
+		http.NotFound(w, r)
+		return
+	}
+	nodeID := parts[0]
+
+	n, ok := h.registry.Get(nodeID)
+	if !ok {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+	current := nodeAnnotations{Notes: n.Notes, Annotations: n.Annotations}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeAnnotations(w, current)
+	case http.MethodPatch:
+		h.patch(w, r, nodeID, current)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// annotationsPatch is the body of PATCH requests. Absent notes are kept and
+// null annotation values remove the key.
+type annotationsPatch struct {
+	Notes       *string            `json:"notes,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// patch applies a PATCH body to a node's current annotations
+func (h *NodeAnnotationsHandler) patch(w http.ResponseWriter, r *http.Request, nodeID string, current nodeAnnotations) {
+	var req annotationsPatch
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	updated := nodeAnnotations{Notes: current.Notes, Annotations: make(map[string]string, len(current.Annotations))}
+	for key, value := range current.Annotations {
+		updated.Annotations[key] = value
+	}
+	if req.Notes != nil {
+		updated.Notes = strings.TrimSpace(*req.Notes)
+	}
+	for key, value := range req.Annotations {
+		if value == nil {
+			delete(updated.Annotations, key)
+			continue
+		}
+		updated.Annotations[strings.TrimSpace(key)] = *value
+	}
+
+	if err := validateAnnotations(updated); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.registry.Annotate(nodeID, updated.Notes, updated.Annotations); err != nil {
+		if err == node.ErrNodeNotFound {
+			http.Error(w, "node not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAnnotations(w, updated)
+}
+
+// validateAnnotations enforces the size limits on a node's annotations
+func validateAnnotations(a nodeAnnotations) error {
+	if len(a.Notes) > maxNotesLength {
+		return fmt.Errorf("notes are longer than %d characters", maxNotesLength)
+	}
+	if len(a.Annotations) > maxAnnotations {
+		return fmt.Errorf("a node can have at most %d annotations", maxAnnotations)
+	}
+	for key, value := range a.Annotations {
+		if key == "" || len(key) > maxAnnotationKey {
+			return fmt.Errorf("annotation keys must be 1 to %d characters", maxAnnotationKey)
+		}
+		if len(value) > maxAnnotationLength {
+			return fmt.Errorf("annotation %q is longer than %d characters", key, maxAnnotationLength)
+		}
+	}
+	return nil
+}
+
+func writeAnnotations(w http.ResponseWriter, a nodeAnnotations) {
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+func serveAnnotations(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNodeAnnotationsHandler(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "gpu-1", Hostname: "gpu-box"}))
+	handler := NewNodeAnnotationsHandler(registry)
+	path := "/api/admin/nodes/gpu-1/annotations"
+
+	rec := serveAnnotations(handler, http.MethodPatch, path, `{"notes": "PSU flaky, replace fan", "annotations": {"rack": "b3", "owner": "ml-team"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Notes are kept unless sent; null removes an annotation
+	rec = serveAnnotations(handler, http.MethodPatch, path, `{"annotations": {"owner": null, "ticket": "OPS-42"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serveAnnotations(handler, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body nodeAnnotations
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "PSU flaky, replace fan", body.Notes)
+	assert.Equal(t, map[string]string{"rack": "b3", "ticket": "OPS-42"}, body.Annotations)
+
+	// Shown on the node
+	n, ok := registry.Get("gpu-1")
+	require.True(t, ok)
+	assert.Equal(t, "PSU flaky, replace fan", n.Notes)
+	assert.Equal(t, "b3", n.Annotations["rack"])
+}
+
+func TestNodeAnnotationsHandler_Errors(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "gpu-1", Hostname: "gpu-box"}))
+	handler := NewNodeAnnotationsHandler(registry)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"unknown node", http.MethodGet, "/api/admin/nodes/missing/annotations", "", http.StatusNotFound},
+		{"unknown path", http.MethodGet, "/api/admin/nodes/gpu-1/other", "", http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/api/admin/nodes/gpu-1/annotations", "{}", http.StatusMethodNotAllowed},
+		{"invalid JSON", http.MethodPatch, "/api/admin/nodes/gpu-1/annotations", "{", http.StatusBadRequest},
+		{"empty key", http.MethodPatch, "/api/admin/nodes/gpu-1/annotations", `{"annotations": {" ": "x"}}`, http.StatusBadRequest},
+		{"notes too long", http.MethodPatch, "/api/admin/nodes/gpu-1/annotations", `{"notes": "` + strings.Repeat("x", maxNotesLength+1) + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAnnotations(handler, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/deployment"
+)
+
+// DeploymentsHandler serves the /api/deployments endpoints for multi-node model deployments
+type DeploymentsHandler struct {
+	manager *deployment.Manager
+	catalog *catalog.Catalog
+}
+
+// NewDeploymentsHandler creates a new deployments handler. Models are deployed
+// with the distributed settings from the model catalog.
+func NewDeploymentsHandler(manager *deployment.Manager, models *catalog.Catalog) *DeploymentsHandler {
+	return &DeploymentsHandler{
+		manager: manager,
+		catalog: models,
+	}
+}
+
+// ServeHTTP lists deployments (GET /api/deployments), deploys a model
+// (POST /api/deployments) and removes a deployment (DELETE /api/deployments/{model})
+func (h *DeploymentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Model names may contain slashes (e.g. "meta-llama/Llama-3-70B")
+	model := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/deployments"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && model == "":
+		h.writeJSON(w, http.StatusOK, h.manager.List())
+	case r.Method == http.MethodPost && model == "":
+		h.deploy(w, r)
+	case r.Method == http.MethodDelete && model != "":
+		h.remove(w, r, model)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// deployRequest deploys a model from the catalog across nodes
+type deployRequest struct {
+	Model string `json:"model"`
+}
+
+// deploy launches the distributed deployment configured for a model
+func (h *DeploymentsHandler) deploy(w http.ResponseWriter, r *http.Request) {
+	var req deployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model == "" {
+		http.Error(w, "request body must be JSON with a model", http.StatusBadRequest)
+		return
+	}
+
+	model, ok := h.catalog.Get(req.Model)
+	if !ok || model.Distributed == nil {
+		http.Error(w, fmt.Sprintf("model %s has no distributed configuration in the model catalog", req.Model), http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.manager.Deploy(r.Context(), req.Model, model.Distributed)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, deployment.ErrInsufficientNodes) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, d)
+}
+
+// remove stops a model's deployment
+func (h *DeploymentsHandler) remove(w http.ResponseWriter, r *http.Request, model string) {
+	err := h.manager.Remove(r.Context(), model)
+	switch {
+	case errors.Is(err, deployment.ErrNotDeployed):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeJSON writes v as a JSON response
+func (h *DeploymentsHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/deployment"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+func TestDeploymentsHandler(t *testing.T) {
+	models := catalog.New()
+	models.Models["llama3:70b"] = &catalog.Model{Distributed: &catalog.Distributed{Nodes: 2}}
+	handler := NewDeploymentsHandler(deployment.NewManager(node.NewInMemoryRegistry()), models)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"list", http.MethodGet, "/api/deployments", "", http.StatusOK},
+		{"model without distributed config", http.MethodPost, "/api/deployments", `{"model":"llama3"}`, http.StatusBadRequest},
+		{"missing model", http.MethodPost, "/api/deployments", `{}`, http.StatusBadRequest},
+		{"remove unknown deployment", http.MethodDelete, "/api/deployments/meta-llama/Llama-3-70B", "", http.StatusNotFound},
+		{"delete without model", http.MethodDelete, "/api/deployments", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+)
+
+// FederationHandler serves the other Orchion sites requests may be sent to
+// and the models each last reported
+type FederationHandler struct {
+	federation *federation.Federation
+}
+
+// NewFederationHandler creates a new federation handler
+func NewFederationHandler(f *federation.Federation) *FederationHandler {
+	return &FederationHandler{federation: f}
+}
+
+// federationStatus is the body of federation responses
+type federationStatus struct {
+	Site  string                  `json:"site"`
+	Sites []federation.SiteStatus `json:"sites"`
+}
+
+// ServeHTTP serves GET /api/federation
+func (h *FederationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(federationStatus{
+		Site:  h.federation.Site(),
+		Sites: h.federation.Status(),
+	})
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+)
+
+func TestFederationHandler(t *testing.T) {
+	office := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"object":"list","data":[{"id":"mistral"},{"id":"llama3"}]}`))
+	}))
+	defer office.Close()
+	f := federation.New(&federation.Config{Site: "home", Sites: []*federation.Site{
+		{Name: "office", URL: office.URL, APIKey: "secret", Policy: federation.PolicyOverflow},
+	}})
+	f.Sync(context.Background())
+	handler := NewFederationHandler(f)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/federation", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret", "API keys of sites aren't shown")
+
+	var body struct {
+		Site  string                  `json:"site"`
+		Sites []federation.SiteStatus `json:"sites"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "home", body.Site)
+	require.Len(t, body.Sites, 1)
+	assert.Equal(t, []string{"llama3", "mistral"}, body.Sites[0].Models)
+	assert.Equal(t, federation.PolicyOverflow, body.Sites[0].Policy)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/federation", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
+)
+
+// ImagePinsHandler lets admins pin engine container images to a digest
+// across the fleet. Agents pick pins up with their next heartbeat, pull the
+// pinned image ahead of use and start engines from it.
+type ImagePinsHandler struct {
+	pins *images.Pins
+}
+
+// NewImagePinsHandler creates a new image pins handler
+func NewImagePinsHandler(pins *images.Pins) *ImagePinsHandler {
+	return &ImagePinsHandler{pins: pins}
+}
+
+// imagePin is an image repository and the digest it is pinned to
+type imagePin struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// imagePinList is the body of image pin responses
+type imagePinList struct {
+	Pins []imagePin `json:"pins"`
+}
+
+// imagePinRequest pins an image repository to a digest
+type imagePinRequest struct {
+	Digest string `json:"digest"`
+}
+
+// ServeHTTP lists pins (GET /api/admin/images), pins a repository or rolls
+// it to a new digest (PUT /api/admin/images/{repository} with a "digest") and
+// unpins a repository (DELETE /api/admin/images/{repository}). Repositories
+// keep their slashes, e.g. /api/admin/images/vllm/vllm-openai.
+func (h *ImagePinsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	image := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/images"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && image == "":
+		h.writePins(w)
+	case r.Method == http.MethodPut && image != "":
+		h.pin(w, r, image)
+	case r.Method == http.MethodDelete && image != "":
+		h.unpin(w, image)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// pin pins an image repository to the digest in the body
+func (h *ImagePinsHandler) pin(w http.ResponseWriter, r *http.Request, image string) {
+	var req imagePinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.pins.Set(image, req.Digest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writePins(w)
+}
+
+// unpin removes an image repository's pin
+func (h *ImagePinsHandler) unpin(w http.ResponseWriter, image string) {
+	err := h.pins.Remove(image)
+	switch {
+	case errors.Is(err, images.ErrNotPinned):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writePins writes the current pins
+func (h *ImagePinsHandler) writePins(w http.ResponseWriter) {
+	pins := []imagePin{}
+	for _, p := range h.pins.List() {
+		pins = append(pins, imagePin{Image: p.Image, Digest: p.Digest})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(imagePinList{Pins: pins})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
+)
+
+func TestImagePinsHandler(t *testing.T) {
+	pins := images.NewPins()
+	handler := NewImagePinsHandler(pins)
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("pin an image", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/api/admin/images/vllm/vllm-openai", `{"digest": "`+digest+`"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = serve(http.MethodGet, "/api/admin/images", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			Pins []imagePin `json:"pins"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, []imagePin{{Image: "vllm/vllm-openai", Digest: digest}}, body.Pins)
+	})
+
+	t.Run("unpin an image", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, "/api/admin/images/vllm/vllm-openai", "").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/api/admin/images/vllm/vllm-openai", "").Code)
+		assert.Empty(t, pins.List())
+		assert.JSONEq(t, `{"pins": []}`, serve(http.MethodGet, "/api/admin/images", "").Body.String())
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/admin/images/vllm/vllm-openai", `{"digest": "latest"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/admin/images/vllm/vllm-openai:latest", `{"digest": "`+digest+`"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/api/admin/images/vllm/vllm-openai", `not json`).Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPut, "/api/admin/images", "").Code)
+	})
+}
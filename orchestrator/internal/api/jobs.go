@@ -0,0 +1,508 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+	"github.com/Orchion/Orchion/orchestrator/internal/sse"
+)
+
+// JobsHandler serves the /api/jobs/ dashboard endpoints
+type JobsHandler struct {
+	queue        *queue.JobQueue
+	results      results.Store
+	writeTimeout time.Duration // Zero disables SSE write deadlines
+}
+
+// NewJobsHandler creates a new jobs handler backed by the given queue
+func NewJobsHandler(jobQueue *queue.JobQueue) *JobsHandler {
+	return &JobsHandler{
+		queue:        jobQueue,
+		writeTimeout: sse.DefaultWriteTimeout,
+	}
+}
+
+// SetWriteTimeout sets how long a client following a job's output may take to
+// accept each event before its stream is dropped; zero waits indefinitely
+func (h *JobsHandler) SetWriteTimeout(timeout time.Duration) {
+	h.writeTimeout = timeout
+}
+
+// SetResultStore sets the store holding results the job processor offloaded
+func (h *JobsHandler) SetResultStore(store results.Store) {
+	h.results = store
+}
+
+// ServeHTTP routes /api/jobs, /api/jobs/search, /api/jobs/{id},
+// /api/jobs/{id}/stream, /api/jobs/{id}/result and /api/job-groups/{id}
+// requests
+func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Cache-Control, Last-Event-ID")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if group, ok := strings.CutPrefix(r.URL.Path, "/api/job-groups/"); ok {
+		if group == "" || strings.Contains(group, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		h.getGroup(w, group)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		h.listJobs(w, r)
+		return
+	case len(parts) == 1 && parts[0] == "search":
+		h.searchJobs(w, r)
+		return
+	case len(parts) == 1:
+		h.getJob(w, parts[0])
+		return
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "stream":
+		h.streamJob(w, r, parts[0])
+		return
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "result":
+		h.downloadResult(w, r, parts[0])
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// listJobs returns jobs oldest first, a page at a time if page_size is set
+func (h *JobsHandler) listJobs(w http.ResponseWriter, r *http.Request) {
+	pageSize, pageToken, err := ParsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobs, next, err := h.queue.ListPage(pageSize, pageToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	SetNextPageToken(w, next)
+	writeJobList(w, jobs)
+}
+
+// searchJobs returns the jobs matching the status, node, user, since and q
+// (words of the error message) query parameters, oldest first. Pagination
+// works as in listJobs; with export=true every match is returned as a JSON
+// file download instead.
+func (h *JobsHandler) searchJobs(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSearchFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	export := false
+	if v := r.URL.Query().Get("export"); v != "" {
+		if export, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid export: %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pageSize, pageToken, err := ParsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if export {
+		pageSize, pageToken = 0, ""
+	}
+
+	jobs, next, err := h.queue.Search(filter, pageSize, pageToken)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if export {
+		w.Header().Set("Content-Disposition", `attachment; filename="jobs.json"`)
+	} else {
+		SetNextPageToken(w, next)
+	}
+	writeJobList(w, jobs)
+}
+
+// parseSearchFilter builds a job search filter from query parameters. since
+// is either an RFC 3339 time or a Go duration counted back from now.
+func parseSearchFilter(r *http.Request) (queue.SearchFilter, error) {
+	query := r.URL.Query()
+	filter := queue.SearchFilter{
+		Node: query.Get("node"),
+		User: query.Get("user"),
+		Text: query.Get("q"),
+	}
+
+	if v := query.Get("status"); v != "" {
+		status, err := queue.ParseJobStatus(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid status: %q", v)
+		}
+		filter.Status = &status
+	}
+
+	if v := query.Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			filter.Since = t
+		} else {
+			return filter, fmt.Errorf("invalid since: %q", v)
+		}
+	}
+
+	return filter, nil
+}
+
+// jobSummary summarizes a job in job listings
+type jobSummary struct {
+	JobID        string   `json:"job_id"`
+	JobType      string   `json:"job_type"`
+	Status       string   `json:"status"`
+	AssignedNode string   `json:"assigned_node"`
+	ErrorMessage string   `json:"error_message"`
+	CreatedAtMs  int64    `json:"created_at_ms"`
+	UpdatedAtMs  int64    `json:"updated_at_ms"`
+	ResultSize   int64    `json:"result_size"`
+	User         string   `json:"user"`
+	GroupID      string   `json:"group_id,omitempty"`
+	DependsOn    []string `json:"depends_on,omitempty"`
+}
+
+// writeJobList writes a summary of each job as a JSON array
+func writeJobList(w http.ResponseWriter, jobs []*queue.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeJobs(jobs))
+}
+
+// summarizeJobs summarizes each job
+func summarizeJobs(jobs []*queue.Job) []jobSummary {
+	summaries := make([]jobSummary, 0, len(jobs))
+	for _, job := range jobs {
+		summaries = append(summaries, jobSummary{
+			JobID:        job.ID,
+			JobType:      job.Type.String(),
+			Status:       job.Status.String(),
+			AssignedNode: job.AssignedNode,
+			ErrorMessage: job.ErrorMessage,
+			CreatedAtMs:  job.CreatedAt.UnixMilli(),
+			UpdatedAtMs:  job.UpdatedAt.UnixMilli(),
+			ResultSize:   job.ResultSize,
+			User:         job.User,
+			GroupID:      job.Group,
+			DependsOn:    job.DependsOn,
+		})
+	}
+	return summaries
+}
+
+// jobGroup is the status of a job group
+type jobGroup struct {
+	GroupID string       `json:"group_id"`
+	Status  string       `json:"status"`
+	Jobs    []jobSummary `json:"jobs"`
+}
+
+// getGroup returns the status of a job group and its jobs, in submission order
+func (h *JobsHandler) getGroup(w http.ResponseWriter, groupID string) {
+	jobs, ok := h.queue.Group(groupID)
+	if !ok {
+		http.Error(w, "job group not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobGroup{
+		GroupID: groupID,
+		Status:  queue.GroupStatus(jobs).String(),
+		Jobs:    summarizeJobs(jobs),
+	})
+}
+
+// jobStatus is the status of a job
+type jobStatus struct {
+	JobID           string          `json:"job_id"`
+	Status          string          `json:"status"`
+	AssignedNode    string          `json:"assigned_node"`
+	ErrorMessage    string          `json:"error_message"`
+	QueuePosition   int             `json:"queue_position"`
+	QueueDepth      int             `json:"queue_depth"`
+	EstimatedWaitMs int64           `json:"estimated_wait_ms"`
+	ResultSize      int64           `json:"result_size"`
+	User            string          `json:"user"`
+	GroupID         string          `json:"group_id,omitempty"`
+	DependsOn       []string        `json:"depends_on,omitempty"`
+	GPUUsage        json.RawMessage `json:"gpu_usage,omitempty"` // GpuUsage the node agent reported, if any
+	Attempts        []jobAttempt    `json:"attempts,omitempty"`
+}
+
+// jobAttempt describes a node a job was run on
+type jobAttempt struct {
+	NodeID      string `json:"node_id"`
+	StartedAtMs int64  `json:"started_at_ms"`
+	EndedAtMs   int64  `json:"ended_at_ms,omitempty"` // Absent while running
+	Error       string `json:"error,omitempty"`
+}
+
+// getJob returns the status of a job. While the job is still queued, the
+// response carries its queue position and a Retry-After hint for polling clients.
+func (h *JobsHandler) getJob(w http.ResponseWriter, jobID string) {
+	job, ok := h.queue.Get(jobID)
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	position := h.queue.Position(jobID)
+	wait := h.queue.EstimatedWait(position)
+
+	if position > 0 {
+		// Retry-After is in whole seconds; never suggest polling immediately
+		retryAfter := int((wait + time.Second - 1) / time.Second)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	resp := jobStatus{
+		JobID:           job.ID,
+		Status:          job.Status.String(),
+		AssignedNode:    job.AssignedNode,
+		ErrorMessage:    job.ErrorMessage,
+		QueuePosition:   position,
+		QueueDepth:      h.queue.Count(),
+		EstimatedWaitMs: wait.Milliseconds(),
+		ResultSize:      job.ResultSize,
+		User:            job.User,
+		GroupID:         job.Group,
+		DependsOn:       job.DependsOn,
+		GPUUsage:        gpuUsageJSON(job.GPUUsage),
+		Attempts:        attemptsJSON(h.queue.Attempts(job.ID)),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// attemptsJSON describes the nodes a job was run on, oldest first
+func attemptsJSON(attempts []queue.Attempt) []jobAttempt {
+	if len(attempts) == 0 {
+		return nil
+	}
+	out := make([]jobAttempt, len(attempts))
+	for i, a := range attempts {
+		out[i] = jobAttempt{
+			NodeID:      a.Node,
+			StartedAtMs: a.StartedAt.UnixMilli(),
+			Error:       a.Error,
+		}
+		if !a.EndedAt.IsZero() {
+			out[i].EndedAtMs = a.EndedAt.UnixMilli()
+		}
+	}
+	return out
+}
+
+// gpuUsageJSON converts the GPU usage recorded on a job to JSON, or returns
+// nil if the node agent reported none
+func gpuUsageJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var usage pb.GpuUsage
+	if err := proto.Unmarshal(data, &usage); err != nil {
+		return nil
+	}
+	out, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(&usage)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// downloadResult streams a completed job's serialized result, reading it from
+// the result store if it was offloaded so large results never sit in memory.
+// A single byte range may be requested to resume an interrupted download.
+func (h *JobsHandler) downloadResult(w http.ResponseWriter, r *http.Request, jobID string) {
+	job, ok := h.queue.Get(jobID)
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if job.Status != queue.JobCompleted {
+		http.Error(w, fmt.Sprintf("job is %s", job.Status), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	offset, length, partial, err := results.ParseRange(r.Header.Get("Range"), job.ResultSize)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", job.ResultSize))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if !partial {
+		offset, length = 0, job.ResultSize
+	}
+
+	body, err := results.OpenJobRange(r.Context(), h.results, job, offset, length)
+	if errors.Is(err, results.ErrNotFound) {
+		http.Error(w, "result not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load result: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", results.ContentType(job.Type))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.ID+".pb"))
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	if partial {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, job.ResultSize))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	io.Copy(w, body)
+}
+
+// streamJob replays the buffered output of a job as Server-Sent Events and
+// follows new chunks until the job completes or the client disconnects
+func (h *JobsHandler) streamJob(w http.ResponseWriter, r *http.Request, jobID string) {
+	seq, err := streamStart(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.queue.Get(jobID); !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	events, err := sse.NewWriter(w, h.writeTimeout)
+	if err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	for {
+		snapshot, ok := h.queue.ChunksSince(jobID, seq)
+		if !ok {
+			return
+		}
+
+		if snapshot.Truncated {
+			events.Printf("event: truncated\ndata: {\"first_available\": %d}\n\n", snapshot.First)
+		}
+		if snapshot.Released {
+			h.writeReleased(events, jobID)
+		}
+		for i, chunk := range snapshot.Chunks {
+			events.Printf("id: %d\ndata: %s\n\n", snapshot.First+i, chunk)
+		}
+		seq = snapshot.Next
+
+		if snapshot.Done {
+			h.writeDone(events, jobID)
+			events.Flush()
+			return
+		}
+		// Clients that stop reading are dropped; they resume from Last-Event-ID
+		if events.Flush() != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-snapshot.Wait:
+		}
+	}
+}
+
+// writeDone writes the terminal event carrying the final job status
+func (h *JobsHandler) writeDone(events *sse.Writer, jobID string) {
+	job, ok := h.queue.Get(jobID)
+	if !ok {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"job_id":        job.ID,
+		"status":        job.Status.String(),
+		"error_message": job.ErrorMessage,
+	})
+	events.Printf("event: done\ndata: %s\n\n", data)
+}
+
+// writeReleased points readers of a job finished a while ago, whose output
+// is no longer buffered, at its result
+func (h *JobsHandler) writeReleased(events *sse.Writer, jobID string) {
+	job, ok := h.queue.Get(jobID)
+	if !ok || job.Status != queue.JobCompleted {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"result_url": "/api/jobs/" + job.ID + "/result",
+	})
+	events.Printf("event: released\ndata: %s\n\n", data)
+}
+
+// streamStart determines the first chunk to send, honoring the "from" query
+// parameter and the Last-Event-ID header sent by reconnecting EventSource clients
+func streamStart(r *http.Request) (int, error) {
+	if from := r.URL.Query().Get("from"); from != "" {
+		seq, err := strconv.Atoi(from)
+		if err != nil || seq < 0 {
+			return 0, fmt.Errorf("invalid from parameter: %q", from)
+		}
+		return seq, nil
+	}
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		seq, err := strconv.Atoi(lastID)
+		if err != nil || seq < 0 {
+			return 0, fmt.Errorf("invalid Last-Event-ID header: %q", lastID)
+		}
+		return seq + 1, nil
+	}
+
+	return 0, nil
+}
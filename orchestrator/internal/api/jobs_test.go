@@ -0,0 +1,455 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+)
+
+func TestJobsHandler_StreamCompletedJob(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	jobQueue.AppendChunk("job-1", []byte(`{"content":"Hel"}`))
+	jobQueue.AppendChunk("job-1", []byte(`{"content":"lo"}`))
+	jobQueue.CompleteJob("job-1", nil)
+
+	handler := NewJobsHandler(jobQueue)
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1/stream", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "id: 0\ndata: {\"content\":\"Hel\"}\n\n")
+	assert.Contains(t, body, "id: 1\ndata: {\"content\":\"lo\"}\n\n")
+	assert.Contains(t, body, "event: done\n")
+	assert.Contains(t, body, `"status":"completed"`)
+}
+
+func TestJobsHandler_StreamResume(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	jobQueue.AppendChunk("job-1", []byte("a"))
+	jobQueue.AppendChunk("job-1", []byte("b"))
+	jobQueue.FailJob("job-1", "boom")
+
+	handler := NewJobsHandler(jobQueue)
+
+	t.Run("from query parameter", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1/stream?from=1", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.NotContains(t, rec.Body.String(), "data: a\n")
+		assert.Contains(t, rec.Body.String(), "id: 1\ndata: b\n\n")
+		assert.Contains(t, rec.Body.String(), `"error_message":"boom"`)
+	})
+
+	t.Run("Last-Event-ID header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1/stream", nil)
+		req.Header.Set("Last-Event-ID", "0")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.NotContains(t, rec.Body.String(), "data: a\n")
+		assert.Contains(t, rec.Body.String(), "id: 1\ndata: b\n\n")
+	})
+
+	t.Run("invalid from parameter", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1/stream?from=abc", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestJobsHandler_StreamFollowsRunningJob(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	jobQueue.AppendChunk("job-1", []byte("early"))
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		jobQueue.AppendChunk("job-1", []byte("late"))
+		jobQueue.CompleteJob("job-1", nil)
+	}()
+
+	server := httptest.NewServer(NewJobsHandler(jobQueue))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/jobs/job-1/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := make([]byte, 0, 512)
+	buf := make([]byte, 512)
+	for {
+		n, err := resp.Body.Read(buf)
+		body = append(body, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+
+	assert.Contains(t, string(body), "id: 0\ndata: early\n\n")
+	assert.Contains(t, string(body), "id: 1\ndata: late\n\n")
+	assert.Contains(t, string(body), "event: done\n")
+}
+
+func TestJobsHandler_GetJob(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	jobQueue.Enqueue(&queue.Job{ID: "job-2", Type: queue.JobTypeEmbeddings})
+
+	handler := NewJobsHandler(jobQueue)
+
+	t.Run("queued job", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-2", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "job-2", body["job_id"])
+		assert.Equal(t, "pending", body["status"])
+		assert.Equal(t, float64(2), body["queue_position"])
+		assert.Equal(t, float64(2), body["queue_depth"])
+	})
+
+	t.Run("finished job", func(t *testing.T) {
+		jobQueue.DequeueNonBlocking()
+		jobQueue.CompleteJob("job-1", nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), `"status":"completed"`)
+		assert.NotContains(t, rec.Body.String(), "gpu_usage")
+	})
+
+	t.Run("gpu usage", func(t *testing.T) {
+		usage, err := proto.Marshal(&pb.GpuUsage{
+			Start: &pb.GpuSample{UtilizationPercent: 97, VramUsedMb: 22000, VramTotalMb: 24576},
+			End:   &pb.GpuSample{UtilizationPercent: 35, VramUsedMb: 22100, VramTotalMb: 24576},
+		})
+		require.NoError(t, err)
+		jobQueue.SetGPUUsage("job-1", usage)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			GPUUsage struct {
+				Start struct {
+					UtilizationPercent float64 `json:"utilization_percent"`
+				} `json:"start"`
+				End struct {
+					VramUsedMb float64 `json:"vram_used_mb"`
+				} `json:"end"`
+			} `json:"gpu_usage"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, 97.0, body.GPUUsage.Start.UtilizationPercent)
+		assert.Equal(t, 22100.0, body.GPUUsage.End.VramUsedMb)
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		jobQueue.Enqueue(&queue.Job{ID: "job-2", Type: queue.JobTypeEmbeddings})
+		jobQueue.UpdateStatusAndNode("job-2", queue.JobRunning, "node-a")
+		jobQueue.EndAttempt("job-2", "connection refused")
+		jobQueue.UpdateStatusAndNode("job-2", queue.JobRunning, "node-b")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-2", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			Attempts []map[string]interface{} `json:"attempts"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Attempts, 2)
+		assert.Equal(t, "node-a", body.Attempts[0]["node_id"])
+		assert.Equal(t, "connection refused", body.Attempts[0]["error"])
+		assert.Contains(t, body.Attempts[0], "ended_at_ms")
+		assert.Equal(t, "node-b", body.Attempts[1]["node_id"])
+		assert.NotContains(t, body.Attempts[1], "ended_at_ms")
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestJobsHandler_GetGroup(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	require.NoError(t, jobQueue.EnqueueGroup("group-1", []*queue.Job{
+		{ID: "job-1", Type: queue.JobTypeChatCompletion},
+		{ID: "job-2", Type: queue.JobTypeChatCompletion, DependsOn: []string{"job-1"}},
+	}))
+	handler := NewJobsHandler(jobQueue)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/job-groups/group-1", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body jobGroup
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "group-1", body.GroupID)
+	assert.Equal(t, "pending", body.Status)
+	require.Len(t, body.Jobs, 2)
+	assert.Equal(t, "job-1", body.Jobs[0].JobID)
+	assert.Equal(t, []string{"job-1"}, body.Jobs[1].DependsOn)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/job-groups/missing", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestJobsHandler_Errors(t *testing.T) {
+	handler := NewJobsHandler(queue.NewJobQueue())
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		expected int
+	}{
+		{"unknown job", http.MethodGet, "/api/jobs/missing/stream", http.StatusNotFound},
+		{"unknown job result", http.MethodGet, "/api/jobs/missing/result", http.StatusNotFound},
+		{"invalid page size", http.MethodGet, "/api/jobs?page_size=-1", http.StatusBadRequest},
+		{"invalid page token", http.MethodGet, "/api/jobs?page_token=%25%25", http.StatusBadRequest},
+		{"unknown route", http.MethodGet, "/api/jobs/job-1/other", http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/api/jobs/job-1/stream", http.StatusMethodNotAllowed},
+		{"preflight", http.MethodOptions, "/api/jobs/job-1/stream", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expected, rec.Code)
+		})
+	}
+}
+
+func TestJobsHandler_DownloadResult(t *testing.T) {
+	store, err := results.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+
+	jobQueue := queue.NewJobQueue()
+	jobQueue.Enqueue(&queue.Job{ID: "in-memory", Type: queue.JobTypeEmbeddings})
+	jobQueue.CompleteJob("in-memory", []byte("small result"))
+
+	offloaded := []byte("large offloaded result")
+	require.NoError(t, store.Put(context.Background(), "offloaded", offloaded))
+	jobQueue.Enqueue(&queue.Job{ID: "offloaded", Type: queue.JobTypeEmbeddings})
+	jobQueue.CompleteJobWithRef("offloaded", "offloaded", int64(len(offloaded)))
+
+	jobQueue.Enqueue(&queue.Job{ID: "lost", Type: queue.JobTypeEmbeddings})
+	jobQueue.CompleteJobWithRef("lost", "lost", 10)
+
+	jobQueue.Enqueue(&queue.Job{ID: "pending", Type: queue.JobTypeEmbeddings})
+
+	handler := NewJobsHandler(jobQueue)
+	handler.SetResultStore(store)
+
+	testCases := []struct {
+		name     string
+		jobID    string
+		rangeHdr string
+		expected int
+		body     string
+	}{
+		{"in-memory result", "in-memory", "", http.StatusOK, "small result"},
+		{"offloaded result", "offloaded", "", http.StatusOK, string(offloaded)},
+		{"offloaded result missing", "lost", "", http.StatusNotFound, ""},
+		{"job not completed", "pending", "", http.StatusConflict, ""},
+		{"in-memory range", "in-memory", "bytes=6-", http.StatusPartialContent, "result"},
+		{"offloaded range", "offloaded", "bytes=6-14", http.StatusPartialContent, "offloaded"},
+		{"suffix range", "offloaded", "bytes=-6", http.StatusPartialContent, "result"},
+		{"several ranges", "in-memory", "bytes=0-1,3-4", http.StatusOK, "small result"},
+		{"range past the end", "in-memory", "bytes=100-", http.StatusRequestedRangeNotSatisfiable, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+tc.jobID+"/result", nil)
+			if tc.rangeHdr != "" {
+				req.Header.Set("Range", tc.rangeHdr)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expected, rec.Code)
+			if tc.expected == http.StatusOK || tc.expected == http.StatusPartialContent {
+				assert.Equal(t, `application/x-protobuf; messageType="orchion.v1.EmbeddingResponse"`, rec.Header().Get("Content-Type"))
+				assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+				assert.Equal(t, tc.body, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/offloaded/result", nil)
+	req.Header.Set("Range", "bytes=6-14")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "bytes 6-14/22", rec.Header().Get("Content-Range"))
+	assert.Equal(t, "9", rec.Header().Get("Content-Length"))
+}
+
+func TestJobsHandler_ListJobs(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		jobQueue.Enqueue(&queue.Job{ID: id, Type: queue.JobTypeEmbeddings})
+		time.Sleep(time.Millisecond)
+	}
+	jobQueue.CompleteJob("job-1", []byte("result"))
+	handler := NewJobsHandler(jobQueue)
+
+	list := func(query string) ([]map[string]interface{}, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var jobs []map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jobs))
+		return jobs, rec
+	}
+
+	t.Run("all jobs when unpaginated", func(t *testing.T) {
+		jobs, rec := list("")
+		require.Len(t, jobs, 3)
+		assert.Empty(t, rec.Header().Get(NextPageTokenHeader))
+
+		assert.Equal(t, "job-1", jobs[0]["job_id"])
+		assert.Equal(t, "embeddings", jobs[0]["job_type"])
+		assert.Equal(t, "completed", jobs[0]["status"])
+		assert.Equal(t, float64(len("result")), jobs[0]["result_size"])
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		jobs, rec := list("?page_size=2")
+		require.Len(t, jobs, 2)
+		assert.Equal(t, "job-2", jobs[1]["job_id"])
+		next := rec.Header().Get(NextPageTokenHeader)
+		require.NotEmpty(t, next)
+
+		jobs, rec = list("?page_size=2&page_token=" + next)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "job-3", jobs[0]["job_id"])
+		assert.Empty(t, rec.Header().Get(NextPageTokenHeader))
+	})
+}
+
+func TestJobsHandler_SearchJobs(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		jobQueue.Enqueue(&queue.Job{ID: id, Type: queue.JobTypeChatCompletion})
+		time.Sleep(time.Millisecond)
+	}
+	jobQueue.FailJob("job-1", "CUDA error: out of memory")
+	jobQueue.FailJob("job-2", "model not found")
+	jobQueue.FailJob("job-3", "cuda driver version is insufficient")
+	handler := NewJobsHandler(jobQueue)
+
+	search := func(query string) ([]map[string]interface{}, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/search"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var jobs []map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jobs))
+		return jobs, rec
+	}
+
+	t.Run("status and text", func(t *testing.T) {
+		jobs, _ := search("?status=failed&q=CUDA")
+		require.Len(t, jobs, 2)
+		assert.Equal(t, "job-1", jobs[0]["job_id"])
+		assert.Equal(t, "job-3", jobs[1]["job_id"])
+		assert.Equal(t, "CUDA error: out of memory", jobs[0]["error_message"])
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		jobs, rec := search("?q=cuda&page_size=1")
+		require.Len(t, jobs, 1)
+		next := rec.Header().Get(NextPageTokenHeader)
+		require.NotEmpty(t, next)
+
+		jobs, rec = search("?q=cuda&page_size=1&page_token=" + next)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "job-3", jobs[0]["job_id"])
+		assert.Empty(t, rec.Header().Get(NextPageTokenHeader))
+	})
+
+	t.Run("export", func(t *testing.T) {
+		jobs, rec := search("?status=failed&export=true&page_size=1")
+		assert.Len(t, jobs, 3)
+		assert.Equal(t, `attachment; filename="jobs.json"`, rec.Header().Get("Content-Disposition"))
+		assert.Empty(t, rec.Header().Get(NextPageTokenHeader))
+	})
+
+	t.Run("since", func(t *testing.T) {
+		jobs, _ := search("?since=1h")
+		assert.Len(t, jobs, 3)
+		jobs, _ = search("?since=" + time.Now().Add(time.Hour).Format(time.RFC3339))
+		assert.Empty(t, jobs)
+	})
+
+	for _, query := range []string{"?status=broken", "?since=yesterday", "?export=maybe", "?page_token=%25%25%25"} {
+		t.Run("invalid "+query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/jobs/search"+query, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestJobsHandler_StreamReleasedJob(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	jobQueue.SetStreamRetention(0)
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	jobQueue.AppendChunk("job-1", []byte("a"))
+	jobQueue.CompleteJob("job-1", []byte("result"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/jobs/job-1/stream", nil)
+	rec := httptest.NewRecorder()
+	NewJobsHandler(jobQueue).ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	assert.NotContains(t, body, "data: a\n")
+	assert.Contains(t, body, "event: released\ndata: {\"result_url\":\"/api/jobs/job-1/result\"}\n\n")
+	assert.Contains(t, body, `"status":"completed"`)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+)
+
+// minKeyLength is the shortest API key an admin may choose
+const minKeyLength = 16
+
+// KeysHandler lets admins manage API keys and their roles. Keys added or
+// removed here last until the orchestrator restarts.
+type KeysHandler struct {
+	authz *auth.Authorizer
+}
+
+// NewKeysHandler creates a new API keys handler
+func NewKeysHandler(authz *auth.Authorizer) *KeysHandler {
+	return &KeysHandler{authz: authz}
+}
+
+// ServeHTTP lists keys (GET /api/admin/keys), adds a key or changes its role
+// (POST /api/admin/keys) and removes a key (DELETE /api/admin/keys/{id})
+func (h *KeysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/keys"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		h.writeJSON(w, http.StatusOK, h.authz.Keys())
+	case r.Method == http.MethodPost && id == "":
+		h.addKey(w, r)
+	case r.Method == http.MethodDelete && id != "":
+		h.removeKey(w, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// keyRequest adds a key or changes its role. Without a key, one is generated.
+type keyRequest struct {
+	Key  string `json:"key,omitempty"`
+	Role string `json:"role"`
+}
+
+// newKey is a key just added, the only time the key itself is returned
+type newKey struct {
+	auth.KeyInfo
+	Key string `json:"key"`
+}
+
+// addKey sets the role of the key in the body, generating a key if none is
+// given. The key is only ever returned in this response.
+func (h *KeysHandler) addKey(w http.ResponseWriter, r *http.Request) {
+	var req keyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := strings.TrimSpace(req.Key)
+	switch {
+	case key == "":
+		if key, err = generateKey(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case len(key) < minKeyLength:
+		http.Error(w, fmt.Sprintf("keys must be at least %d characters", minKeyLength), http.StatusBadRequest)
+		return
+	}
+
+	h.authz.SetKey(key, role)
+	h.writeJSON(w, http.StatusCreated, newKey{
+		KeyInfo: auth.KeyInfo{ID: auth.KeyID(key), Role: role.String()},
+		Key:     key,
+	})
+}
+
+// removeKey removes the key with the given ID
+func (h *KeysHandler) removeKey(w http.ResponseWriter, id string) {
+	err := h.authz.RemoveKey(id)
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, auth.ErrLastAdmin):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// generateKey returns a random 256-bit key
+func generateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// writeJSON writes v as a JSON response
+func (h *KeysHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+)
+
+func TestKeysHandler(t *testing.T) {
+	authz := auth.NewAuthorizer("", []string{"root-key"})
+	handler := NewKeysHandler(authz)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("generate a key", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/api/admin/keys", `{"role": "viewer"}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		var created map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+		assert.Len(t, created["key"], 64)
+		assert.Equal(t, auth.KeyID(created["key"]), created["id"])
+		assert.Equal(t, "viewer", created["role"])
+
+		req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+		req.Header.Set("Authorization", "Bearer "+created["key"])
+		assert.Equal(t, auth.RoleViewer, authz.RoleOf(req))
+	})
+
+	t.Run("list keys", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/api/admin/keys", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "root-key")
+
+		var keys []auth.KeyInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("remove a key", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/api/admin/keys", `{"key": "operator-key-0123456", "role": "operator"}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = serve(http.MethodDelete, "/api/admin/keys/"+auth.KeyID("operator-key-0123456"), "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		rec = serve(http.MethodDelete, "/api/admin/keys/"+auth.KeyID("operator-key-0123456"), "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("last admin key", func(t *testing.T) {
+		rec := serve(http.MethodDelete, "/api/admin/keys/"+auth.KeyID("root-key"), "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/admin/keys", `{"role": "owner"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/admin/keys", `{"key": "short", "role": "viewer"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/admin/keys", `not json`).Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPut, "/api/admin/keys", "").Code)
+	})
+}
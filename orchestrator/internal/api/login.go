@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+)
+
+// LoginHandler exchanges API keys for short-lived session tokens, so the
+// dashboard never has to keep the key itself
+type LoginHandler struct {
+	authz *auth.Authorizer
+}
+
+// NewLoginHandler creates a new login handler
+func NewLoginHandler(authz *auth.Authorizer) *LoginHandler {
+	return &LoginHandler{authz: authz}
+}
+
+// ServeHTTP serves POST /api/auth/login and POST /api/auth/logout
+func (h *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth"), "/") {
+	case "login":
+		h.login(w, r)
+	case "logout":
+		h.setCookie(w, r, "", time.Unix(0, 0))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// loginRequest exchanges an API key for a session
+type loginRequest struct {
+	Key string `json:"key"`
+}
+
+// session is a dashboard session a key was exchanged for
+type session struct {
+	Token       string `json:"token"`
+	Role        string `json:"role"`
+	ExpiresAtMs int64  `json:"expires_at_ms"`
+}
+
+// login checks the key in the body and returns a session token, also set as
+// an HttpOnly cookie for same-origin dashboards
+func (h *LoginHandler) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	token, role, expires, err := h.authz.Login(strings.TrimSpace(req.Key))
+	if errors.Is(err, auth.ErrInvalidKey) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setCookie(w, r, token, expires)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session{
+		Token:       token,
+		Role:        role.String(),
+		ExpiresAtMs: expires.UnixMilli(),
+	})
+}
+
+// setCookie sets the session cookie, or clears it if token is empty
+func (h *LoginHandler) setCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/api/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+)
+
+func TestLoginHandler(t *testing.T) {
+	authz := auth.NewAuthorizer("", []string{"root-key"})
+	sessions, err := auth.NewSessions(nil, time.Hour)
+	require.NoError(t, err)
+	authz.SetSessions(sessions)
+	handler := NewLoginHandler(authz)
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("login", func(t *testing.T) {
+		rec := serve("/api/auth/login", `{"key": "root-key"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			Token       string `json:"token"`
+			Role        string `json:"role"`
+			ExpiresAtMs int64  `json:"expires_at_ms"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "admin", resp.Role)
+		assert.NotContains(t, resp.Token, "root-key")
+		assert.Greater(t, resp.ExpiresAtMs, time.Now().UnixMilli())
+
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, auth.SessionCookie, cookies[0].Name)
+		assert.Equal(t, resp.Token, cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/keys", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		assert.Equal(t, auth.RoleAdmin, authz.RoleOf(req))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve("/api/auth/login", `{"key": "guess"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve("/api/auth/login", `not json`).Code)
+	})
+
+	t.Run("logout clears the cookie", func(t *testing.T) {
+		rec := serve("/api/auth/logout", "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		cookies := rec.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Empty(t, cookies[0].Value)
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve("/api/auth/whoami", "").Code)
+	})
+}
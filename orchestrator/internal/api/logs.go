@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	logservice "github.com/Orchion/Orchion/orchestrator/internal/logging"
+	"github.com/Orchion/Orchion/orchestrator/internal/sse"
+)
+
+// LogsKeepAlive is how often the log stream sends a keepalive event
+const LogsKeepAlive = 30 * time.Second
+
+// LogsHandler streams the orchestrator's logs as Server-Sent Events on
+// /api/logs. Each client buffers a bounded number of entries; clients that
+// fall behind lose the oldest ones and are told how many with a "dropped"
+// event.
+type LogsHandler struct {
+	service      *logservice.Service
+	buffer       int
+	writeTimeout time.Duration // Zero disables SSE write deadlines
+	keepAlive    time.Duration
+}
+
+// NewLogsHandler creates a logs handler streaming the service's broadcasts
+func NewLogsHandler(service *logservice.Service) *LogsHandler {
+	return &LogsHandler{
+		service:      service,
+		buffer:       logservice.DefaultClientBuffer,
+		writeTimeout: sse.DefaultWriteTimeout,
+		keepAlive:    LogsKeepAlive,
+	}
+}
+
+// SetWriteTimeout sets how long a client may take to accept each event before
+// its stream is dropped; zero waits indefinitely
+func (h *LogsHandler) SetWriteTimeout(timeout time.Duration) {
+	h.writeTimeout = timeout
+}
+
+// logEvent is an event on the log stream, e.g. {"type": "log", "entry": {...}}
+type logEvent struct {
+	Type      string    `json:"type"`
+	Entry     *logEntry `json:"entry,omitempty"`
+	Count     uint64    `json:"count,omitempty"`     // Entries dropped since the last "dropped" event
+	Timestamp int64     `json:"timestamp,omitempty"` // Unix seconds, on keepalives
+}
+
+// logEntry is a log entry as the dashboard shows it
+type logEntry struct {
+	ID        string            `json:"id"`
+	Timestamp int64             `json:"timestamp"` // Unix milliseconds
+	Level     string            `json:"level"`
+	Source    string            `json:"source"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// levelNames are the level names the dashboard expects
+var levelNames = map[pb.LogLevel]string{
+	pb.LogLevel_LOG_LEVEL_DEBUG: "debug",
+	pb.LogLevel_LOG_LEVEL_INFO:  "info",
+	pb.LogLevel_LOG_LEVEL_WARN:  "warning",
+	pb.LogLevel_LOG_LEVEL_ERROR: "error",
+}
+
+// ServeHTTP streams log entries until the client disconnects or stops reading
+func (h *LogsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	events, err := sse.NewWriter(w, h.writeTimeout)
+	if err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	sub, unsubscribe := h.service.Subscribe(h.buffer)
+	defer unsubscribe()
+
+	write := func(event logEvent) error {
+		data, _ := json.Marshal(event)
+		events.Printf("data: %s\n\n", data)
+		return events.Flush()
+	}
+	if write(logEvent{Type: "connected"}) != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+
+	var reported uint64
+	for {
+		var event logEvent
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			event = logEvent{Type: "keepalive", Timestamp: time.Now().Unix()}
+		case entry := <-sub.Entries():
+			if dropped := sub.Dropped(); dropped > reported {
+				if write(logEvent{Type: "dropped", Count: dropped - reported}) != nil {
+					return
+				}
+				reported = dropped
+			}
+			event = logEvent{Type: "log", Entry: &logEntry{
+				ID:        entry.Id,
+				Timestamp: entry.Timestamp,
+				Level:     levelNames[entry.Level],
+				Source:    entry.Source,
+				Message:   entry.Message,
+				Fields:    entry.Fields,
+			}}
+		}
+		// A client that stopped reading is dropped rather than left to block
+		if write(event) != nil {
+			return
+		}
+	}
+}
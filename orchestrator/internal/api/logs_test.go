@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logservice "github.com/Orchion/Orchion/orchestrator/internal/logging"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+func TestLogsHandler(t *testing.T) {
+	service := logservice.NewService()
+	handler := NewLogsHandler(service)
+	handler.buffer = 2
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := bufio.NewScanner(resp.Body)
+	next := func() logEvent {
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				var event logEvent
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				return event
+			}
+		}
+		t.Fatal("log stream ended")
+		return logEvent{}
+	}
+	require.Equal(t, "connected", next().Type)
+
+	// Entries broadcast faster than the client reads: the oldest are dropped
+	// and the client is told how many
+	for _, msg := range []string{"first", "second", "third"} {
+		service.Broadcast(&logging.LogEntry{ID: msg, Timestamp: time.Now().UnixMilli(), Level: logging.WarnLevel, Message: msg})
+	}
+
+	var got []string
+	dropped := uint64(0)
+	for len(got) == 0 || got[len(got)-1] != "third" {
+		event := next()
+		switch event.Type {
+		case "log":
+			got = append(got, event.Entry.Message)
+			assert.Equal(t, "warning", event.Entry.Level)
+		case "dropped":
+			dropped += event.Count
+		}
+	}
+	// Whether the handler read an entry before the rest arrived varies, but
+	// every entry is either delivered or counted
+	assert.Equal(t, 3, len(got)+int(dropped))
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// DefaultMetricsWindow is the history returned when no window is requested
+const DefaultMetricsWindow = time.Hour
+
+// ModelStatusTimeout bounds asking a node agent for its model states
+const ModelStatusTimeout = 10 * time.Second
+
+// NodeDialer provides NodeAgent clients for nodes
+type NodeDialer interface {
+	NodeClient(n *pb.Node) (pb.NodeAgentClient, error)
+}
+
+// NodeService fetches a node with everything the orchestrator knows about it
+type NodeService interface {
+	GetNode(ctx context.Context, req *pb.GetNodeRequest) (*pb.GetNodeResponse, error)
+}
+
+// NodeMetricsHandler serves node details, per-node hardware history for
+// dashboard graphs, the state of each node's models and node events
+type NodeMetricsHandler struct {
+	registry node.Registry
+	history  *node.History
+	dialer   NodeDialer
+	events   *node.EventLog
+	nodes    NodeService
+}
+
+// modelStatus is a model's state on a node as served to dashboards
+type modelStatus struct {
+	Model     string          `json:"model"`
+	State     string          `json:"state"`
+	Error     string          `json:"error,omitempty"`
+	SinceUnix int64           `json:"since_unix"`
+	Engine    *pb.ModelEngine `json:"engine,omitempty"`
+}
+
+// nodeMetrics is a node's hardware history over a window
+type nodeMetrics struct {
+	NodeID   string        `json:"node_id"`
+	WindowMs int64         `json:"window_ms"`
+	Samples  []node.Sample `json:"samples"`
+}
+
+// nodeModels is the state of each model on a node
+type nodeModels struct {
+	NodeID string        `json:"node_id"`
+	Models []modelStatus `json:"models"`
+}
+
+// nodeDetail is a node with its hardware history, model states and the jobs
+// in flight on it
+type nodeDetail struct {
+	Node        *pb.Node      `json:"node"`
+	History     []node.Sample `json:"history"`
+	Models      []modelStatus `json:"models"`
+	ModelsError string        `json:"models_error,omitempty"`
+	ActiveJobs  []jobSummary  `json:"active_jobs"`
+}
+
+// nodeEvents is a node's events, oldest first
+type nodeEvents struct {
+	NodeID string       `json:"node_id"`
+	Events []node.Event `json:"events"`
+}
+
+// NewNodeMetricsHandler creates a new node metrics handler
+func NewNodeMetricsHandler(registry node.Registry, history *node.History) *NodeMetricsHandler {
+	return &NodeMetricsHandler{
+		registry: registry,
+		history:  history,
+	}
+}
+
+// SetDialer sets how model states are fetched from node agents; without one
+// /api/nodes/{id}/models is not served
+func (h *NodeMetricsHandler) SetDialer(dialer NodeDialer) {
+	h.dialer = dialer
+}
+
+// SetEvents sets the node event log; without one /api/nodes/{id}/events is
+// not served
+func (h *NodeMetricsHandler) SetEvents(events *node.EventLog) {
+	h.events = events
+}
+
+// SetNodeService sets where node details come from; without one
+// /api/nodes/{id} is not served
+func (h *NodeMetricsHandler) SetNodeService(nodes NodeService) {
+	h.nodes = nodes
+}
+
+// ServeHTTP serves GET /api/nodes/{id}?window=1h,
+// GET /api/nodes/{id}/metrics?window=1h, GET /api/nodes/{id}/models and
+// GET /api/nodes/{id}/events
+func (h *NodeMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/nodes/"), "/"), "/")
+	if len(parts) == 1 && parts[0] != "" && h.nodes != nil {
+		h.serveNode(w, r, parts[0])
+		return
+	}
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	nodeID := parts[0]
+	switch {
+	case parts[1] == "models" && h.dialer != nil:
+		h.serveModels(w, r, nodeID)
+		return
+	case parts[1] == "events" && h.events != nil:
+		h.serveEvents(w, r, nodeID)
+		return
+	case parts[1] != "metrics":
+		http.NotFound(w, r)
+		return
+	}
+
+	window, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.registry.Get(nodeID); !ok {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nodeMetrics{
+		NodeID:   nodeID,
+		WindowMs: window.Milliseconds(),
+		Samples:  h.history.Samples(nodeID, window),
+	})
+}
+
+// parseWindow reads the window query parameter, DefaultMetricsWindow if unset
+func parseWindow(r *http.Request) (time.Duration, error) {
+	v := r.URL.Query().Get("window")
+	if v == "" {
+		return DefaultMetricsWindow, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window: %q", v)
+	}
+	return d, nil
+}
+
+// serveNode serves a node with its hardware history over the window, the
+// state of its models and the jobs in flight on it
+func (h *NodeMetricsHandler) serveNode(w http.ResponseWriter, r *http.Request, nodeID string) {
+	window, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.nodes.GetNode(r.Context(), &pb.GetNodeRequest{NodeId: nodeID, HistoryWindowMs: window.Milliseconds()})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound:
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	case codes.InvalidArgument:
+		http.Error(w, status.Convert(err).Message(), http.StatusBadRequest)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	detail := nodeDetail{
+		Node:        resp.Node,
+		History:     make([]node.Sample, 0, len(resp.History)),
+		Models:      make([]modelStatus, 0, len(resp.Models)),
+		ModelsError: resp.ModelsError,
+		ActiveJobs:  make([]jobSummary, 0, len(resp.ActiveJobs)),
+	}
+	for _, s := range resp.History {
+		detail.History = append(detail.History, node.Sample{
+			TimestampMs:  s.TimestampMs,
+			VRAMUsedMB:   s.VramUsedMb,
+			VRAMTotalMB:  s.VramTotalMb,
+			TemperatureC: s.GpuTemperatureC,
+			GPUPowerW:    s.GpuPowerW,
+		})
+	}
+	for _, m := range resp.Models {
+		detail.Models = append(detail.Models, modelStatusJSON(m))
+	}
+	for _, j := range resp.ActiveJobs {
+		detail.ActiveJobs = append(detail.ActiveJobs, jobSummary{
+			JobID:        j.JobId,
+			JobType:      enumName(j.JobType.String(), "JOB_TYPE_"),
+			Status:       enumName(j.Status.String(), "JOB_STATUS_"),
+			AssignedNode: j.AssignedNode,
+			ErrorMessage: j.ErrorMessage,
+			CreatedAtMs:  j.CreatedAtUnixMs,
+			UpdatedAtMs:  j.UpdatedAtUnixMs,
+			ResultSize:   j.ResultSize,
+			User:         j.User,
+			GroupID:      j.GroupId,
+			DependsOn:    j.DependsOn,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// enumName returns a proto enum value's name without its prefix, in lower
+// case, e.g. "ready" for MODEL_STATE_READY
+func enumName(name, prefix string) string {
+	return strings.ToLower(strings.TrimPrefix(name, prefix))
+}
+
+// modelStatusJSON converts a model's state on a node for dashboards
+func modelStatusJSON(m *pb.ModelStatus) modelStatus {
+	return modelStatus{
+		Model:     m.Model,
+		State:     enumName(m.State.String(), "MODEL_STATE_"),
+		Error:     m.Error,
+		SinceUnix: m.SinceUnix,
+		Engine:    m.Engine,
+	}
+}
+
+// serveModels serves the state of each model on a node, as its agent reports
+func (h *NodeMetricsHandler) serveModels(w http.ResponseWriter, r *http.Request, nodeID string) {
+	n, ok := h.registry.Get(nodeID)
+	if !ok {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	client, err := h.dialer.NodeClient(n)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), ModelStatusTimeout)
+	defer cancel()
+	resp, err := client.GetModelStatus(ctx, &pb.GetModelStatusRequest{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get model states: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	models := make([]modelStatus, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, modelStatusJSON(m))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nodeModels{NodeID: nodeID, Models: models})
+}
+
+// serveEvents serves a node's events, oldest first. Events of evicted nodes
+// are served until they expire.
+func (h *NodeMetricsHandler) serveEvents(w http.ResponseWriter, r *http.Request, nodeID string) {
+	events := h.events.Events(nodeID)
+	if _, ok := h.registry.Get(nodeID); !ok && len(events) == 0 {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nodeEvents{NodeID: nodeID, Events: events})
+}
+
+// ParseListNodesRequest builds a ListNodes request from /api/nodes query
+// parameters: page_size, page_token, status, labels (a label selector),
+// gpu=true|false and sort
+func ParseListNodesRequest(r *http.Request) (*pb.ListNodesRequest, error) {
+	pageSize, pageToken, err := ParsePageParams(r)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	req := &pb.ListNodesRequest{
+		PageSize:      int32(pageSize),
+		PageToken:     pageToken,
+		Status:        query.Get("status"),
+		LabelSelector: query.Get("labels"),
+		OrderBy:       query.Get("sort"),
+	}
+
+	if v := query.Get("gpu"); v != "" {
+		gpu, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gpu: %q", v)
+		}
+		req.Gpu = &gpu
+	}
+
+	return req, nil
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+func TestNodeMetricsHandler(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	gpuNode := &pb.Node{Id: "gpu-1", Capabilities: &pb.Capabilities{GpuVramUsed: "2 GB", GpuTemperature: "70°C"}}
+	require.NoError(t, registry.Register(gpuNode))
+
+	history := node.NewHistory(10)
+	history.Record(gpuNode)
+	history.Record(gpuNode)
+
+	handler := NewNodeMetricsHandler(registry, history)
+
+	t.Run("samples", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1/metrics?window=15m", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body struct {
+			NodeID   string        `json:"node_id"`
+			WindowMs int64         `json:"window_ms"`
+			Samples  []node.Sample `json:"samples"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "gpu-1", body.NodeID)
+		assert.Equal(t, int64(15*60*1000), body.WindowMs)
+		require.Len(t, body.Samples, 2)
+		assert.Equal(t, 2048.0, *body.Samples[0].VRAMUsedMB)
+		assert.Equal(t, 70.0, *body.Samples[0].TemperatureC)
+		assert.Nil(t, body.Samples[0].GPUPowerW)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"default window", http.MethodGet, "/api/nodes/gpu-1/metrics", http.StatusOK},
+		{"invalid window", http.MethodGet, "/api/nodes/gpu-1/metrics?window=soon", http.StatusBadRequest},
+		{"negative window", http.MethodGet, "/api/nodes/gpu-1/metrics?window=-1h", http.StatusBadRequest},
+		{"unknown node", http.MethodGet, "/api/nodes/missing/metrics", http.StatusNotFound},
+		{"unknown path", http.MethodGet, "/api/nodes/gpu-1/other", http.StatusNotFound},
+		{"wrong method", http.MethodPost, "/api/nodes/gpu-1/metrics", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestNodeMetricsHandler_Events(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "gpu-1"}))
+	handler := NewNodeMetricsHandler(registry, node.NewHistory(10))
+
+	// Without an event log events aren't served
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1/events", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	events := node.NewEventLog(10, time.Hour)
+	events.Record("gpu-1", node.EventRegistered, "host-1 at 10.0.0.5:50052")
+	events.Record("gone", node.EventEvicted, "no heartbeat for 30s")
+	handler.SetEvents(events)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantEvents []string
+	}{
+		{"registered node", "/api/nodes/gpu-1/events", http.StatusOK, []string{node.EventRegistered}},
+		{"evicted node", "/api/nodes/gone/events", http.StatusOK, []string{node.EventEvicted}},
+		{"unknown node", "/api/nodes/missing/events", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				NodeID string       `json:"node_id"`
+				Events []node.Event `json:"events"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			var types []string
+			for _, e := range body.Events {
+				types = append(types, e.Type)
+			}
+			assert.Equal(t, tt.wantEvents, types)
+		})
+	}
+}
+
+// statusClient reports fixed model states
+type statusClient struct {
+	pb.NodeAgentClient
+	resp *pb.GetModelStatusResponse
+	err  error
+}
+
+func (c *statusClient) GetModelStatus(ctx context.Context, in *pb.GetModelStatusRequest, opts ...grpc.CallOption) (*pb.GetModelStatusResponse, error) {
+	return c.resp, c.err
+}
+
+type staticDialer struct{ client pb.NodeAgentClient }
+
+func (d staticDialer) NodeClient(n *pb.Node) (pb.NodeAgentClient, error) { return d.client, nil }
+
+func TestNodeMetricsHandler_Models(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "gpu-1"}))
+	handler := NewNodeMetricsHandler(registry, node.NewHistory(10))
+
+	// Without a dialer model states aren't served
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1/models", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	client := &statusClient{resp: &pb.GetModelStatusResponse{Models: []*pb.ModelStatus{
+		{Model: "llama3", State: pb.ModelState_MODEL_STATE_READY, SinceUnix: 1700000000},
+		{Model: "phi3", State: pb.ModelState_MODEL_STATE_DEGRADED, Error: "CUDA error: out of memory"},
+	}}}
+	handler.SetDialer(staticDialer{client})
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1/models", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		NodeID string        `json:"node_id"`
+		Models []modelStatus `json:"models"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "gpu-1", body.NodeID)
+	require.Len(t, body.Models, 2)
+	assert.Equal(t, "ready", body.Models[0].State)
+	assert.Equal(t, int64(1700000000), body.Models[0].SinceUnix)
+	assert.Equal(t, "degraded", body.Models[1].State)
+	assert.Equal(t, "CUDA error: out of memory", body.Models[1].Error)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/missing/models", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	client.err = errors.New("connection refused")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1/models", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+// fakeNodeService answers GetNode with a canned response and records requests
+type fakeNodeService struct {
+	req  *pb.GetNodeRequest
+	resp *pb.GetNodeResponse
+	err  error
+}
+
+func (f *fakeNodeService) GetNode(ctx context.Context, req *pb.GetNodeRequest) (*pb.GetNodeResponse, error) {
+	f.req = req
+	return f.resp, f.err
+}
+
+func TestNodeMetricsHandler_Node(t *testing.T) {
+	handler := NewNodeMetricsHandler(node.NewInMemoryRegistry(), node.NewHistory(10))
+
+	// Without a node service details aren't served
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	vram := 2048.0
+	nodes := &fakeNodeService{resp: &pb.GetNodeResponse{
+		Node:        &pb.Node{Id: "gpu-1"},
+		History:     []*pb.NodeSample{{TimestampMs: 1700000000000, VramUsedMb: &vram}},
+		Models:      []*pb.ModelStatus{{Model: "llama3", State: pb.ModelState_MODEL_STATE_READY}},
+		ModelsError: "",
+		ActiveJobs: []*pb.JobSummary{
+			{JobId: "job-1", JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION, Status: pb.JobStatus_JOB_STATUS_RUNNING, AssignedNode: "gpu-1"},
+		},
+	}}
+	handler.SetNodeService(nodes)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1?window=10m", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gpu-1", nodes.req.NodeId)
+	assert.Equal(t, int64(600000), nodes.req.HistoryWindowMs)
+
+	var body nodeDetail
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "gpu-1", body.Node.Id)
+	require.Len(t, body.History, 1)
+	assert.Equal(t, 2048.0, *body.History[0].VRAMUsedMB)
+	require.Len(t, body.Models, 1)
+	assert.Equal(t, "ready", body.Models[0].State)
+	require.Len(t, body.ActiveJobs, 1)
+	assert.Equal(t, "chat_completion", body.ActiveJobs[0].JobType)
+	assert.Equal(t, "running", body.ActiveJobs[0].Status)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/gpu-1?window=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	nodes.err = status.Error(codes.NotFound, "node not found")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/nodes/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestParseListNodesRequest(t *testing.T) {
+	t.Run("all parameters", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/nodes?page_size=20&page_token=abc&status=online&labels=zone%3Deu&gpu=true&sort=-vram_free", nil)
+		req, err := ParseListNodesRequest(r)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(20), req.PageSize)
+		assert.Equal(t, "abc", req.PageToken)
+		assert.Equal(t, "online", req.Status)
+		assert.Equal(t, "zone=eu", req.LabelSelector)
+		require.NotNil(t, req.Gpu)
+		assert.True(t, *req.Gpu)
+		assert.Equal(t, "-vram_free", req.OrderBy)
+	})
+
+	t.Run("no parameters", func(t *testing.T) {
+		req, err := ParseListNodesRequest(httptest.NewRequest(http.MethodGet, "/api/nodes", nil))
+		require.NoError(t, err)
+		assert.Nil(t, req.Gpu)
+		assert.Zero(t, req.PageSize)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"?gpu=maybe", "?page_size=x", "?page_size=-5"} {
+			_, err := ParseListNodesRequest(httptest.NewRequest(http.MethodGet, "/api/nodes"+query, nil))
+			assert.Error(t, err, query)
+		}
+	})
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"net/http"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/deployment"
+	"github.com/Orchion/Orchion/orchestrator/internal/openapi"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+)
+
+// DescribeAPI adds the dashboard and admin REST APIs served by this package's
+// handlers, and /api/nodes, to an OpenAPI document
+func DescribeAPI(doc *openapi.Document) {
+	doc.AddTag("nodes", "Registered nodes, their hardware history, models and events")
+	doc.AddTag("jobs", "Queued and finished jobs")
+	doc.AddTag("admin", "Fleet administration, for admin keys")
+	doc.AddTag("auth", "Dashboard sessions")
+	doc.AddTag("deployments", "Multi-node model deployments")
+	doc.AddTag("usage", "Per-user usage")
+
+	describeNodes(doc)
+	describeJobs(doc)
+	describeAdmin(doc)
+	describeAuth(doc)
+	describeDeployments(doc)
+
+	doc.Add(http.MethodGet, "/api/usage", &openapi.Operation{
+		Summary:     "Get usage per user",
+		OperationID: "listUsage",
+		Tags:        []string{"usage"},
+		Parameters:  []*openapi.Parameter{openapi.QueryParam("user", "string", "Only return this user's usage")},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Usage since the orchestrator started", doc.SchemaOf([]usage.UserUsage{})),
+		},
+	})
+}
+
+// pageParams are the parameters of paginated listings
+func pageParams() []*openapi.Parameter {
+	return []*openapi.Parameter{
+		openapi.QueryParam("page_size", "integer", "Items per page (default: all)"),
+		openapi.QueryParam("page_token", "string", "Token of the page to return, from "+NextPageTokenHeader),
+	}
+}
+
+// pagedResponse returns a listing response advertising the next page
+func pagedResponse(description string, schema *openapi.Schema) *openapi.Response {
+	resp := openapi.JSONResponse(description, schema)
+	resp.Headers = map[string]*openapi.Header{
+		NextPageTokenHeader: {
+			Description: "Token of the next page, absent on the last page",
+			Schema:      &openapi.Schema{Type: "string"},
+		},
+	}
+	return resp
+}
+
+// describeNodes describes the node listing and per-node endpoints
+func describeNodes(doc *openapi.Document) {
+	nodeID := openapi.PathParam("id", "Node ID")
+	notFound := openapi.TextResponse("Node not found")
+
+	doc.Add(http.MethodGet, "/api/nodes", &openapi.Operation{
+		Summary:     "List nodes",
+		OperationID: "listNodes",
+		Tags:        []string{"nodes"},
+		Parameters: append(pageParams(),
+			openapi.QueryParam("status", "string", "online or stale"),
+			openapi.QueryParam("labels", "string", "Label selector, e.g. zone=eu-west,!draining"),
+			openapi.QueryParam("gpu", "boolean", "Only nodes with (true) or without (false) a usable GPU"),
+			openapi.QueryParam("sort", "string", "id, vram_free or last_seen, prefixed with - for descending order"),
+		),
+		Responses: map[string]*openapi.Response{
+			"200": pagedResponse("Nodes", doc.SchemaOf([]*pb.Node{})),
+			"400": openapi.TextResponse("Invalid query"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/nodes/{id}", &openapi.Operation{
+		Summary:     "Get a node with its history, models and in-flight jobs",
+		OperationID: "getNode",
+		Tags:        []string{"nodes"},
+		Parameters:  []*openapi.Parameter{nodeID, openapi.QueryParam("window", "string", "How much hardware history to include, as a Go duration (default: 1h)")},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("The node; models_error says why the agent's model states are missing", doc.SchemaOf(nodeDetail{})),
+			"400": openapi.TextResponse("Invalid window"),
+			"404": notFound,
+		},
+	})
+	doc.Add(http.MethodGet, "/api/nodes/{id}/metrics", &openapi.Operation{
+		Summary:     "Get a node's hardware history",
+		OperationID: "getNodeMetrics",
+		Tags:        []string{"nodes"},
+		Parameters:  []*openapi.Parameter{nodeID, openapi.QueryParam("window", "string", "How far back to go, as a Go duration (default: 1h)")},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Hardware samples, oldest first", doc.SchemaOf(nodeMetrics{})),
+			"400": openapi.TextResponse("Invalid window"),
+			"404": notFound,
+		},
+	})
+	doc.Add(http.MethodGet, "/api/nodes/{id}/models", &openapi.Operation{
+		Summary:     "Get the state of a node's models",
+		OperationID: "getNodeModels",
+		Tags:        []string{"nodes"},
+		Parameters:  []*openapi.Parameter{nodeID},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Model states, as the node's agent reports them", doc.SchemaOf(nodeModels{})),
+			"404": notFound,
+			"502": openapi.TextResponse("The node's agent couldn't be reached"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/nodes/{id}/events", &openapi.Operation{
+		Summary:     "Get a node's events",
+		OperationID: "getNodeEvents",
+		Tags:        []string{"nodes"},
+		Parameters:  []*openapi.Parameter{nodeID},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Events, oldest first", doc.SchemaOf(nodeEvents{})),
+			"404": notFound,
+		},
+	})
+}
+
+// describeJobs describes the job endpoints
+func describeJobs(doc *openapi.Document) {
+	jobID := openapi.PathParam("id", "Job ID")
+	notFound := openapi.TextResponse("Job not found")
+
+	doc.Add(http.MethodGet, "/api/jobs", &openapi.Operation{
+		Summary:     "List jobs",
+		OperationID: "listJobs",
+		Tags:        []string{"jobs"},
+		Parameters:  pageParams(),
+		Responses: map[string]*openapi.Response{
+			"200": pagedResponse("Jobs, oldest first", doc.SchemaOf([]jobSummary{})),
+			"400": openapi.TextResponse("Invalid page parameters"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/jobs/search", &openapi.Operation{
+		Summary:     "Search jobs",
+		OperationID: "searchJobs",
+		Tags:        []string{"jobs"},
+		Parameters: append(pageParams(),
+			openapi.QueryParam("status", "string", "pending, assigned, running, completed or failed"),
+			openapi.QueryParam("node", "string", "Assigned node ID"),
+			openapi.QueryParam("user", "string", "End user"),
+			openapi.QueryParam("since", "string", "RFC 3339 time, or a Go duration counted back from now"),
+			openapi.QueryParam("q", "string", "Words of the error message"),
+			openapi.QueryParam("export", "boolean", "Return every match as a JSON file download"),
+		),
+		Responses: map[string]*openapi.Response{
+			"200": pagedResponse("Matching jobs, oldest first", doc.SchemaOf([]jobSummary{})),
+			"400": openapi.TextResponse("Invalid query"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/jobs/{id}", &openapi.Operation{
+		Summary:     "Get a job's status",
+		OperationID: "getJob",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobID},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Status; queued jobs come with a Retry-After hint", doc.SchemaOf(jobStatus{})),
+			"404": notFound,
+		},
+	})
+	doc.Add(http.MethodGet, "/api/jobs/{id}/stream", &openapi.Operation{
+		Summary:     "Stream a job's output",
+		OperationID: "streamJob",
+		Tags:        []string{"jobs"},
+		Parameters: []*openapi.Parameter{
+			jobID,
+			openapi.QueryParam("from", "integer", "Sequence number of the first chunk to send"),
+			openapi.HeaderParam("Last-Event-ID", "Resume after this event, as reconnecting EventSource clients do"),
+		},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.EventStreamResponse("Output chunks as they are produced, then a done event"),
+			"400": openapi.TextResponse("Invalid resume position"),
+			"404": notFound,
+		},
+	})
+	doc.Add(http.MethodGet, "/api/jobs/{id}/result", &openapi.Operation{
+		Summary:     "Download a completed job's result",
+		OperationID: "getJobResult",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{jobID, openapi.HeaderParam("Range", "A single byte range, e.g. bytes=1048576-")},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "The serialized result; the messageType parameter of its content type names its message",
+				Content:     map[string]openapi.MediaType{results.ProtobufContentType: {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+			},
+			"206": {
+				Description: "The requested range of the result",
+				Content:     map[string]openapi.MediaType{results.ProtobufContentType: {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+			},
+			"404": notFound,
+			"409": openapi.TextResponse("The job hasn't completed"),
+			"416": openapi.TextResponse("Range outside the result"),
+		},
+	})
+	doc.Add(http.MethodGet, "/api/job-groups/{id}", &openapi.Operation{
+		Summary:     "Get a job group's status and jobs",
+		OperationID: "getJobGroup",
+		Tags:        []string{"jobs"},
+		Parameters:  []*openapi.Parameter{openapi.PathParam("id", "Job group ID")},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Group status and its jobs in submission order", doc.SchemaOf(jobGroup{})),
+			"404": notFound,
+		},
+	})
+}
+
+// describeAdmin describes the admin endpoints
+func describeAdmin(doc *openapi.Document) {
+	doc.Add(http.MethodGet, "/api/admin/keys", &openapi.Operation{
+		Summary:     "List API keys",
+		OperationID: "listKeys",
+		Tags:        []string{"admin"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Keys, identified without revealing them", doc.SchemaOf([]auth.KeyInfo{})),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/admin/keys", &openapi.Operation{
+		Summary:     "Add an API key or change its role",
+		OperationID: "addKey",
+		Tags:        []string{"admin"},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(keyRequest{})),
+		Responses: map[string]*openapi.Response{
+			"201": openapi.JSONResponse("The key, only ever returned here", doc.SchemaOf(newKey{})),
+			"400": openapi.TextResponse("Invalid key or role"),
+		},
+	})
+	doc.Add(http.MethodDelete, "/api/admin/keys/{id}", &openapi.Operation{
+		Summary:     "Remove an API key",
+		OperationID: "removeKey",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{openapi.PathParam("id", "Key ID")},
+		Responses: map[string]*openapi.Response{
+			"204": {Description: "Removed"},
+			"404": openapi.TextResponse("Key not found"),
+			"409": openapi.TextResponse("The key is the last admin key"),
+		},
+	})
+
+	nodeID := openapi.PathParam("id", "Node ID")
+	doc.Add(http.MethodGet, "/api/admin/nodes/{id}/annotations", &openapi.Operation{
+		Summary:     "Get a node's notes and annotations",
+		OperationID: "getNodeAnnotations",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{nodeID},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Notes and annotations", doc.SchemaOf(nodeAnnotations{})),
+			"404": openapi.TextResponse("Node not found"),
+		},
+	})
+	doc.Add(http.MethodPatch, "/api/admin/nodes/{id}/annotations", &openapi.Operation{
+		Summary:     "Update a node's notes and annotations",
+		OperationID: "updateNodeAnnotations",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{nodeID},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(annotationsPatch{})),
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Updated notes and annotations", doc.SchemaOf(nodeAnnotations{})),
+			"400": openapi.TextResponse("Invalid annotations"),
+			"404": openapi.TextResponse("Node not found"),
+		},
+	})
+
+	repository := openapi.PathParam("repository", "Image repository without tag, e.g. vllm/vllm-openai")
+	doc.Add(http.MethodGet, "/api/admin/images", &openapi.Operation{
+		Summary:     "List engine image pins",
+		OperationID: "listImagePins",
+		Tags:        []string{"admin"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Pins", doc.SchemaOf(imagePinList{})),
+		},
+	})
+	doc.Add(http.MethodPut, "/api/admin/images/{repository}", &openapi.Operation{
+		Summary:     "Pin an engine image repository to a digest",
+		OperationID: "pinImage",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{repository},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(imagePinRequest{})),
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Pins", doc.SchemaOf(imagePinList{})),
+			"400": openapi.TextResponse("Invalid repository or digest"),
+		},
+	})
+	doc.Add(http.MethodDelete, "/api/admin/images/{repository}", &openapi.Operation{
+		Summary:     "Unpin an engine image repository",
+		OperationID: "unpinImage",
+		Tags:        []string{"admin"},
+		Parameters:  []*openapi.Parameter{repository},
+		Responses: map[string]*openapi.Response{
+			"204": {Description: "Unpinned"},
+			"404": openapi.TextResponse("Repository not pinned"),
+		},
+	})
+}
+
+// describeAuth describes the dashboard session endpoints
+func describeAuth(doc *openapi.Document) {
+	doc.Add(http.MethodPost, "/api/auth/login", &openapi.Operation{
+		Summary:     "Exchange an API key for a dashboard session",
+		Description: "The session token is also set as an HTTP-only cookie.",
+		OperationID: "login",
+		Tags:        []string{"auth"},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(loginRequest{})),
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("The session", doc.SchemaOf(session{})),
+			"401": openapi.TextResponse("Invalid key"),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/auth/logout", &openapi.Operation{
+		Summary:     "End the dashboard session",
+		OperationID: "logout",
+		Tags:        []string{"auth"},
+		Responses: map[string]*openapi.Response{
+			"204": {Description: "The session cookie is cleared"},
+		},
+	})
+}
+
+// describeDeployments describes the deployment endpoints
+func describeDeployments(doc *openapi.Document) {
+	doc.Add(http.MethodGet, "/api/deployments", &openapi.Operation{
+		Summary:     "List multi-node deployments",
+		OperationID: "listDeployments",
+		Tags:        []string{"deployments"},
+		Responses: map[string]*openapi.Response{
+			"200": openapi.JSONResponse("Deployments", doc.SchemaOf([]*deployment.Deployment{})),
+		},
+	})
+	doc.Add(http.MethodPost, "/api/deployments", &openapi.Operation{
+		Summary:     "Deploy a model across nodes",
+		Description: "The model needs a distributed configuration in the model catalog.",
+		OperationID: "deploy",
+		Tags:        []string{"deployments"},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(deployRequest{})),
+		Responses: map[string]*openapi.Response{
+			"201": openapi.JSONResponse("The deployment", doc.SchemaOf(deployment.Deployment{})),
+			"400": openapi.TextResponse("The model can't be deployed across nodes"),
+			"502": openapi.TextResponse("A node failed to start the model"),
+			"503": openapi.TextResponse("Not enough free nodes"),
+		},
+	})
+	doc.Add(http.MethodDelete, "/api/deployments/{model}", &openapi.Operation{
+		Summary:     "Remove a deployment",
+		OperationID: "removeDeployment",
+		Tags:        []string{"deployments"},
+		Parameters:  []*openapi.Parameter{openapi.PathParam("model", "Deployed model")},
+		Responses: map[string]*openapi.Response{
+			"204": {Description: "Removed"},
+			"404": openapi.TextResponse("Model not deployed"),
+			"502": openapi.TextResponse("A node failed to stop the model"),
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/openapi"
+)
+
+func TestDescribeAPI(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "Orchion", Version: "test"})
+	DescribeAPI(doc)
+
+	for _, path := range []string{"/api/nodes", "/api/jobs/{id}", "/api/admin/keys", "/api/auth/login", "/api/deployments/{model}", "/api/usage"} {
+		assert.Contains(t, doc.Paths, path)
+	}
+
+	// Every path parameter is declared, and every reference resolves
+	var refs []string
+	var collect func(s *openapi.Schema)
+	collect = func(s *openapi.Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			refs = append(refs, s.Ref)
+		}
+		collect(s.Items)
+		collect(s.AdditionalProperties)
+		for _, p := range s.Properties {
+			collect(p)
+		}
+		for _, o := range s.OneOf {
+			collect(o)
+		}
+	}
+
+	pathParam := regexp.MustCompile(`\{(\w+)\}`)
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			declared := make(map[string]bool)
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					declared[p.Name] = true
+				}
+			}
+			for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+				assert.True(t, declared[m[1]], "%s %s doesn't declare {%s}", method, path, m[1])
+			}
+
+			require.NotEmpty(t, op.Responses, "%s %s", method, path)
+			if op.RequestBody != nil {
+				for _, mt := range op.RequestBody.Content {
+					collect(mt.Schema)
+				}
+			}
+			for _, resp := range op.Responses {
+				for _, mt := range resp.Content {
+					collect(mt.Schema)
+				}
+			}
+		}
+	}
+	for _, s := range doc.Components.Schemas {
+		collect(s)
+	}
+
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, strings.TrimPrefix(ref, "#/components/schemas/"))
+	}
+}
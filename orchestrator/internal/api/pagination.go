@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/pagination"
+)
+
+// NextPageTokenHeader carries the token for the next page of a paginated
+// listing; it is absent on the last page
+const NextPageTokenHeader = "X-Next-Page-Token"
+
+// ParsePageParams reads the page_size and page_token query parameters. Both
+// are optional; leaving them unset lists everything. Page sizes above
+// pagination.MaxPageSize are capped.
+func ParsePageParams(r *http.Request) (int, string, error) {
+	pageSize := 0
+	if v := r.URL.Query().Get("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, "", fmt.Errorf("invalid page_size: %q", v)
+		}
+		pageSize = n
+		if pageSize > pagination.MaxPageSize {
+			pageSize = pagination.MaxPageSize
+		}
+	}
+	return pageSize, r.URL.Query().Get("page_token"), nil
+}
+
+// SetNextPageToken advertises the next page, if any, to the client. The
+// header is exposed to cross-origin callers such as the dashboard.
+func SetNextPageToken(w http.ResponseWriter, token string) {
+	w.Header().Set("Access-Control-Expose-Headers", NextPageTokenHeader)
+	if token != "" {
+		w.Header().Set(NextPageTokenHeader, token)
+	}
+}
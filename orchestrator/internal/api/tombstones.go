@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// NodeRestorer keeps removed nodes for restoring
+type NodeRestorer interface {
+	Tombstones() []node.Tombstone
+	Restore(nodeID string) (*pb.Node, error)
+}
+
+// RemovedNodesHandler lists nodes recently removed from the registry, e.g.
+// evicted by the heartbeat monitor during a network outage, and restores them
+// with their labels, draining state and operator notes
+type RemovedNodesHandler struct {
+	registry NodeRestorer
+	events   *node.EventLog
+}
+
+// NewRemovedNodesHandler creates a new removed nodes handler
+func NewRemovedNodesHandler(registry NodeRestorer) *RemovedNodesHandler {
+	return &RemovedNodesHandler{registry: registry}
+}
+
+// SetEvents sets where restorations are recorded
+func (h *RemovedNodesHandler) SetEvents(events *node.EventLog) {
+	h.events = events
+}
+
+// removedNodeList is the body of removed node listings
+type removedNodeList struct {
+	Nodes []node.Tombstone `json:"nodes"`
+}
+
+// ServeHTTP lists removed nodes (GET /api/admin/removed-nodes) and restores
+// one (POST /api/admin/removed-nodes/{id}/restore)
+func (h *RemovedNodesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/removed-nodes"), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(removedNodeList{Nodes: h.registry.Tombstones()})
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "restore" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.restore(w, parts[0])
+}
+
+// restore registers a removed node again and returns it
+func (h *RemovedNodesHandler) restore(w http.ResponseWriter, nodeID string) {
+	n, err := h.registry.Restore(nodeID)
+	switch {
+	case errors.Is(err, node.ErrNodeNotFound):
+		http.Error(w, "no removed node with this ID", http.StatusNotFound)
+		return
+	case errors.Is(err, node.ErrNodeRegistered):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if h.events != nil {
+		h.events.Record(nodeID, node.EventRestored, "restored by an operator")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(n)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+func TestRemovedNodesHandler(t *testing.T) {
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "gpu-1", Hostname: "gpu-box", Labels: map[string]string{node.DrainingLabel: ""}}))
+	require.NoError(t, registry.Annotate("gpu-1", "PSU flaky", nil))
+	require.NoError(t, registry.Remove("gpu-1"))
+
+	events := node.NewEventLog(0, 0)
+	handler := NewRemovedNodesHandler(registry)
+	handler.SetEvents(events)
+
+	rec := serveAnnotations(handler, http.MethodGet, "/api/admin/removed-nodes", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list removedNodeList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Nodes, 1)
+	assert.Equal(t, "gpu-1", list.Nodes[0].Node.Id)
+	assert.Equal(t, "PSU flaky", list.Nodes[0].Node.Notes)
+	assert.NotZero(t, list.Nodes[0].RemovedUnix)
+
+	rec = serveAnnotations(handler, http.MethodPost, "/api/admin/removed-nodes/gpu-1/restore", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var restored pb.Node
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &restored))
+	assert.Equal(t, "gpu-box", restored.Hostname)
+	assert.Contains(t, restored.Labels, node.DrainingLabel)
+
+	_, ok := registry.Get("gpu-1")
+	assert.True(t, ok)
+	last, ok := events.Last("gpu-1")
+	require.True(t, ok)
+	assert.Equal(t, node.EventRestored, last.Type)
+	assert.JSONEq(t, `{"nodes": []}`, serveAnnotations(handler, http.MethodGet, "/api/admin/removed-nodes", "").Body.String())
+
+	t.Run("invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serveAnnotations(handler, http.MethodPost, "/api/admin/removed-nodes/unknown/restore", "").Code)
+		assert.Equal(t, http.StatusNotFound, serveAnnotations(handler, http.MethodPost, "/api/admin/removed-nodes/gpu-1", "").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serveAnnotations(handler, http.MethodGet, "/api/admin/removed-nodes/gpu-1/restore", "").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serveAnnotations(handler, http.MethodDelete, "/api/admin/removed-nodes", "").Code)
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+)
+
+// UsageHandler serves per-user gateway usage
+type UsageHandler struct {
+	tracker *usage.Tracker
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(tracker *usage.Tracker) *UsageHandler {
+	return &UsageHandler{tracker: tracker}
+}
+
+// ServeHTTP serves GET /api/usage, optionally narrowed with ?user=
+func (h *UsageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usages := h.tracker.Usage()
+	if query := r.URL.Query(); query.Has("user") {
+		user := query.Get("user")
+		filtered := make([]usage.UserUsage, 0, 1)
+		for _, u := range usages {
+			if u.User == user {
+				filtered = append(filtered, u)
+			}
+		}
+		usages = filtered
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usages)
+}
+
+// ModelUsageHandler serves a leaderboard of gateway usage per model
+type ModelUsageHandler struct {
+	tracker *usage.Tracker
+}
+
+// NewModelUsageHandler creates a new model usage handler
+func NewModelUsageHandler(tracker *usage.Tracker) *ModelUsageHandler {
+	return &ModelUsageHandler{tracker: tracker}
+}
+
+// modelUsageList is the body of model usage responses
+type modelUsageList struct {
+	WindowMs int64              `json:"window_ms"`
+	Models   []usage.ModelUsage `json:"models"`
+}
+
+// ServeHTTP serves GET /api/models/usage?window=1h, most requested model
+// first. The window defaults to, and is capped at, the tracker's model window.
+func (h *ModelUsageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	window := h.tracker.ModelWindow()
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid window: %q", v), http.StatusBadRequest)
+			return
+		}
+		if d < window {
+			window = d
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(modelUsageList{
+		WindowMs: window.Milliseconds(),
+		Models:   h.tracker.ModelUsage(window),
+	})
+}
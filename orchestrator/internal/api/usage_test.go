@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+)
+
+func TestUsageHandler(t *testing.T) {
+	tracker := usage.NewTracker(usage.Quota{})
+	tracker.Record(usage.Record{User: "alice", PromptTokens: 10})
+	tracker.Record(usage.Record{User: "bob", PromptTokens: 5})
+	handler := NewUsageHandler(tracker)
+
+	tests := []struct {
+		name      string
+		url       string
+		wantUsers []string
+	}{
+		{name: "all users", url: "/api/usage", wantUsers: []string{"alice", "bob"}},
+		{name: "one user", url: "/api/usage?user=bob", wantUsers: []string{"bob"}},
+		{name: "unknown user", url: "/api/usage?user=carol", wantUsers: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var usages []usage.UserUsage
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &usages))
+			users := []string{}
+			for _, u := range usages {
+				users = append(users, u.User)
+			}
+			assert.Equal(t, tt.wantUsers, users)
+		})
+	}
+
+	t.Run("rejects other methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/usage", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func TestModelUsageHandler(t *testing.T) {
+	tracker := usage.NewTracker(usage.Quota{})
+	tracker.SetModelWindow(time.Hour)
+	tracker.Record(usage.Record{Model: "llama3", Node: "gpu-1", PromptTokens: 10, LatencyMs: 120})
+	tracker.Record(usage.Record{Model: "llama3", Node: "gpu-1", PromptTokens: 30, LatencyMs: 80})
+	tracker.Record(usage.Record{Model: "nomic-embed", PromptTokens: 5, LatencyMs: 10})
+	handler := NewModelUsageHandler(tracker)
+
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, url, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/api/models/usage")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body modelUsageList
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, time.Hour.Milliseconds(), body.WindowMs)
+	require.Len(t, body.Models, 2)
+	assert.Equal(t, usage.ModelUsage{
+		Model:        "llama3",
+		Requests:     2,
+		PromptTokens: 40,
+		AvgLatencyMs: 100,
+		Nodes:        map[string]int64{"gpu-1": 2},
+	}, body.Models[0])
+
+	// Windows are capped at the tracker's
+	require.NoError(t, json.Unmarshal(serve(http.MethodGet, "/api/models/usage?window=48h").Body.Bytes(), &body))
+	assert.Equal(t, time.Hour.Milliseconds(), body.WindowMs)
+	require.NoError(t, json.Unmarshal(serve(http.MethodGet, "/api/models/usage?window=15m").Body.Bytes(), &body))
+	assert.Equal(t, (15 * time.Minute).Milliseconds(), body.WindowMs)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/models/usage?window=soon").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/api/models/usage").Code)
+}
@@ -0,0 +1,287 @@
+// Package apiv2 serves the orchion.v2 client API on top of the v1 services.
+// v2 differs from v1 only in carrying times as Timestamps and durations as
+// Durations, so each RPC converts its request to v1, calls the v1
+// implementation and converts the response back.
+package apiv2
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+)
+
+// timestampFromUnix converts Unix seconds, leaving zero (unknown) unset
+func timestampFromUnix(sec int64) *timestamppb.Timestamp {
+	if sec == 0 {
+		return nil
+	}
+	return timestamppb.New(time.Unix(sec, 0))
+}
+
+// timestampFromUnixMilli converts Unix milliseconds, leaving zero (unknown) unset
+func timestampFromUnixMilli(ms int64) *timestamppb.Timestamp {
+	if ms == 0 {
+		return nil
+	}
+	return timestamppb.New(time.UnixMilli(ms))
+}
+
+// durationFromMillis converts milliseconds, leaving zero (unknown) unset
+func durationFromMillis(ms int64) *durationpb.Duration {
+	if ms == 0 {
+		return nil
+	}
+	return durationpb.New(time.Duration(ms) * time.Millisecond)
+}
+
+// NodeFromV1 converts a v1 node
+func NodeFromV1(n *pb.Node) *pbv2.Node {
+	if n == nil {
+		return nil
+	}
+	return &pbv2.Node{
+		Id:                n.Id,
+		Hostname:          n.Hostname,
+		Capabilities:      capabilitiesFromV1(n.Capabilities),
+		LastSeenTime:      timestampFromUnix(n.LastSeenUnix),
+		AgentAddress:      n.AgentAddress,
+		Labels:            n.Labels,
+		Models:            modelEnginesFromV1(n.Models),
+		Conflict:          nodeConflictFromV1(n.Conflict),
+		AgentAddressError: n.AgentAddressError,
+		Tunneled:          n.Tunneled,
+		Notes:             n.Notes,
+		Annotations:       n.Annotations,
+		SupportedModels:   n.SupportedModels,
+		Throttle:          nodeThrottleFromV1(n.Throttle),
+		RegisteredTime:    timestampFromUnix(n.RegisteredUnix),
+		TailnetAddress:    n.TailnetAddress,
+	}
+}
+
+// nodeThrottleFromV1 converts a node's throttling state
+func nodeThrottleFromV1(t *pb.NodeThrottle) *pbv2.NodeThrottle {
+	if t == nil {
+		return nil
+	}
+	return &pbv2.NodeThrottle{
+		Reason:        t.Reason,
+		MaxConcurrent: t.MaxConcurrent,
+		SinceTime:     timestampFromUnix(t.SinceUnix),
+	}
+}
+
+// nodeConflictFromV1 converts a node's registration conflict
+func nodeConflictFromV1(c *pb.NodeConflict) *pbv2.NodeConflict {
+	if c == nil {
+		return nil
+	}
+	return &pbv2.NodeConflict{
+		Hostname:        c.Hostname,
+		AgentAddress:    c.AgentAddress,
+		LastAttemptTime: timestampFromUnix(c.LastAttemptUnix),
+		Attempts:        c.Attempts,
+	}
+}
+
+// nodeSampleFromV1 converts a hardware history sample
+func nodeSampleFromV1(s *pb.NodeSample) *pbv2.NodeSample {
+	return &pbv2.NodeSample{
+		Time:            timestampFromUnixMilli(s.TimestampMs),
+		VramUsedMb:      s.VramUsedMb,
+		VramTotalMb:     s.VramTotalMb,
+		GpuTemperatureC: s.GpuTemperatureC,
+		GpuPowerW:       s.GpuPowerW,
+	}
+}
+
+// modelStatusFromV1 converts a model's state on a node
+func modelStatusFromV1(m *pb.ModelStatus) *pbv2.ModelStatus {
+	var engine *pbv2.ModelEngine
+	if m.Engine != nil {
+		engine = modelEnginesFromV1([]*pb.ModelEngine{m.Engine})[0]
+	}
+	return &pbv2.ModelStatus{
+		Model:     m.Model,
+		State:     pbv2.ModelState(m.State),
+		Error:     m.Error,
+		SinceTime: timestampFromUnix(m.SinceUnix),
+		Engine:    engine,
+	}
+}
+
+// modelEnginesFromV1 converts the engines serving a node's models
+func modelEnginesFromV1(models []*pb.ModelEngine) []*pbv2.ModelEngine {
+	if len(models) == 0 {
+		return nil
+	}
+	converted := make([]*pbv2.ModelEngine, len(models))
+	for i, m := range models {
+		converted[i] = &pbv2.ModelEngine{
+			Model:       m.Model,
+			Engine:      m.Engine,
+			Image:       m.Image,
+			ImageDigest: m.ImageDigest,
+			Version:     m.Version,
+		}
+	}
+	return converted
+}
+
+// capabilitiesFromV1 converts v1 capabilities, dropping the deprecated
+// power_usage field
+func capabilitiesFromV1(c *pb.Capabilities) *pbv2.Capabilities {
+	if c == nil {
+		return nil
+	}
+	return &pbv2.Capabilities{
+		Cpu:              c.Cpu,
+		Memory:           c.Memory,
+		Os:               c.Os,
+		GpuType:          c.GpuType,
+		GpuVramTotal:     c.GpuVramTotal,
+		GpuVramAvailable: c.GpuVramAvailable,
+		GpuVramUsed:      c.GpuVramUsed,
+		GpuTemperature:   c.GpuTemperature,
+		GpuPowerUsage:    c.GpuPowerUsage,
+		GpuBackend:       pbv2.GpuBackend(c.GpuBackend),
+		UnifiedMemory:    c.UnifiedMemory,
+	}
+}
+
+// jobAttemptsFromV1 converts the node attempts of a job
+func jobAttemptsFromV1(attempts []*pb.JobAttempt) []*pbv2.JobAttempt {
+	out := make([]*pbv2.JobAttempt, len(attempts))
+	for i, a := range attempts {
+		out[i] = &pbv2.JobAttempt{
+			NodeId:    a.NodeId,
+			StartTime: timestampFromUnixMilli(a.StartedAtMs),
+			EndTime:   timestampFromUnixMilli(a.EndedAtMs),
+			Error:     a.Error,
+		}
+	}
+	return out
+}
+
+// gpuUsageFromV1 converts the GPU usage of a job
+func gpuUsageFromV1(u *pb.GpuUsage) *pbv2.GpuUsage {
+	if u == nil {
+		return nil
+	}
+	return &pbv2.GpuUsage{
+		Start: gpuSampleFromV1(u.Start),
+		End:   gpuSampleFromV1(u.End),
+	}
+}
+
+// gpuSampleFromV1 converts a GPU sample
+func gpuSampleFromV1(s *pb.GpuSample) *pbv2.GpuSample {
+	if s == nil {
+		return nil
+	}
+	return &pbv2.GpuSample{
+		UtilizationPercent: s.UtilizationPercent,
+		VramUsedMb:         s.VramUsedMb,
+		VramTotalMb:        s.VramTotalMb,
+		SampleTime:         timestampFromUnixMilli(s.TimestampMs),
+	}
+}
+
+// JobSummaryFromV1 converts a v1 job summary
+func JobSummaryFromV1(j *pb.JobSummary) *pbv2.JobSummary {
+	if j == nil {
+		return nil
+	}
+	return &pbv2.JobSummary{
+		JobId:        j.JobId,
+		JobType:      pbv2.JobType(j.JobType),
+		Status:       pbv2.JobStatus(j.Status),
+		AssignedNode: j.AssignedNode,
+		ErrorMessage: j.ErrorMessage,
+		CreateTime:   timestampFromUnixMilli(j.CreatedAtUnixMs),
+		UpdateTime:   timestampFromUnixMilli(j.UpdatedAtUnixMs),
+		ResultSize:   j.ResultSize,
+		User:         j.User,
+		GroupId:      j.GroupId,
+		DependsOn:    j.DependsOn,
+	}
+}
+
+// ChatCompletionResponseFromV1 converts a v1 chat completion or chunk
+func ChatCompletionResponseFromV1(r *pb.ChatCompletionResponse) *pbv2.ChatCompletionResponse {
+	if r == nil {
+		return nil
+	}
+	choices := make([]*pbv2.ChatChoice, len(r.Choices))
+	for i, c := range r.Choices {
+		choices[i] = &pbv2.ChatChoice{
+			Index:        c.Index,
+			FinishReason: c.FinishReason,
+		}
+		if c.Message != nil {
+			choices[i].Message = &pbv2.ChatMessage{Role: c.Message.Role, Content: c.Message.Content}
+			for _, call := range c.Message.ToolCalls {
+				choices[i].Message.ToolCalls = append(choices[i].Message.ToolCalls, &pbv2.ToolCall{
+					Index:     call.Index,
+					Id:        call.Id,
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				})
+			}
+		}
+	}
+	return &pbv2.ChatCompletionResponse{
+		Id:                    r.Id,
+		Model:                 r.Model,
+		Choices:               choices,
+		CreateTime:            timestampFromUnix(r.Created),
+		Object:                r.Object,
+		UsagePromptTokens:     r.UsagePromptTokens,
+		UsageCompletionTokens: r.UsageCompletionTokens,
+		UsageCachedTokens:     r.UsageCachedTokens,
+		Status:                r.Status,
+	}
+}
+
+// chatCompletionRequestToV1 converts a v2 chat completion request
+func chatCompletionRequestToV1(r *pbv2.ChatCompletionRequest) *pb.ChatCompletionRequest {
+	messages := make([]*pb.ChatMessage, len(r.Messages))
+	for i, m := range r.Messages {
+		messages[i] = &pb.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+	return &pb.ChatCompletionRequest{
+		Model:             r.Model,
+		Messages:          messages,
+		Temperature:       r.Temperature,
+		Stream:            r.Stream,
+		MaxTokens:         r.MaxTokens,
+		PromptCacheKey:    r.PromptCacheKey,
+		CacheSalt:         r.CacheSalt,
+		User:              r.User,
+		EngineOptions:     r.EngineOptions,
+		Stop:              r.Stop,
+		TopP:              r.TopP,
+		TopK:              r.TopK,
+		RepetitionPenalty: r.RepetitionPenalty,
+		Seed:              r.Seed,
+		Priority:          r.Priority,
+	}
+}
+
+// embeddingResponseFromV1 converts a v1 embedding response
+func embeddingResponseFromV1(r *pb.EmbeddingResponse) *pbv2.EmbeddingResponse {
+	data := make([]*pbv2.Embedding, len(r.Data))
+	for i, e := range r.Data {
+		data[i] = &pbv2.Embedding{Embedding: e.Embedding, Index: e.Index}
+	}
+	return &pbv2.EmbeddingResponse{
+		Model:             r.Model,
+		Data:              data,
+		Object:            r.Object,
+		UsagePromptTokens: r.UsagePromptTokens,
+	}
+}
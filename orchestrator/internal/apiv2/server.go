@@ -0,0 +1,190 @@
+package apiv2
+
+import (
+	"context"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+)
+
+// OrchestratorServer serves orchion.v2.Orchestrator through a v1 server
+type OrchestratorServer struct {
+	pbv2.UnimplementedOrchestratorServer
+	v1 pb.OrchestratorServer
+}
+
+// NewOrchestratorServer creates a v2 server backed by v1
+func NewOrchestratorServer(v1 pb.OrchestratorServer) *OrchestratorServer {
+	return &OrchestratorServer{v1: v1}
+}
+
+// ListNodes implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) ListNodes(ctx context.Context, req *pbv2.ListNodesRequest) (*pbv2.ListNodesResponse, error) {
+	resp, err := s.v1.ListNodes(ctx, &pb.ListNodesRequest{
+		PageSize:      req.PageSize,
+		PageToken:     req.PageToken,
+		Status:        req.Status,
+		LabelSelector: req.LabelSelector,
+		Gpu:           req.Gpu,
+		OrderBy:       req.OrderBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*pbv2.Node, len(resp.Nodes))
+	for i, n := range resp.Nodes {
+		nodes[i] = NodeFromV1(n)
+	}
+	return &pbv2.ListNodesResponse{Nodes: nodes, NextPageToken: resp.NextPageToken}, nil
+}
+
+// GetNode implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) GetNode(ctx context.Context, req *pbv2.GetNodeRequest) (*pbv2.GetNodeResponse, error) {
+	resp, err := s.v1.GetNode(ctx, &pb.GetNodeRequest{
+		NodeId:          req.NodeId,
+		HistoryWindowMs: req.HistoryWindow.AsDuration().Milliseconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &pbv2.GetNodeResponse{Node: NodeFromV1(resp.Node), ModelsError: resp.ModelsError}
+	for _, sample := range resp.History {
+		detail.History = append(detail.History, nodeSampleFromV1(sample))
+	}
+	for _, m := range resp.Models {
+		detail.Models = append(detail.Models, modelStatusFromV1(m))
+	}
+	for _, j := range resp.ActiveJobs {
+		detail.ActiveJobs = append(detail.ActiveJobs, JobSummaryFromV1(j))
+	}
+	return detail, nil
+}
+
+// SubmitJob implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) SubmitJob(ctx context.Context, req *pbv2.SubmitJobRequest) (*pbv2.SubmitJobResponse, error) {
+	resp, err := s.v1.SubmitJob(ctx, submitJobRequestToV1(req))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbv2.SubmitJobResponse{
+		JobId:         resp.JobId,
+		Status:        pbv2.JobStatus(resp.Status),
+		QueuePosition: resp.QueuePosition,
+		QueueDepth:    resp.QueueDepth,
+		EstimatedWait: durationFromMillis(resp.EstimatedWaitMs),
+	}, nil
+}
+
+// GetJobStatus implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) GetJobStatus(ctx context.Context, req *pbv2.GetJobStatusRequest) (*pbv2.GetJobStatusResponse, error) {
+	resp, err := s.v1.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: req.JobId})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbv2.GetJobStatusResponse{
+		JobId:         resp.JobId,
+		Status:        pbv2.JobStatus(resp.Status),
+		AssignedNode:  resp.AssignedNode,
+		ErrorMessage:  resp.ErrorMessage,
+		Result:        resp.Result,
+		QueuePosition: resp.QueuePosition,
+		EstimatedWait: durationFromMillis(resp.EstimatedWaitMs),
+		GpuUsage:      gpuUsageFromV1(resp.GpuUsage),
+		ResultSize:    resp.ResultSize,
+		Attempts:      jobAttemptsFromV1(resp.Attempts),
+	}, nil
+}
+
+// ListJobs implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) ListJobs(ctx context.Context, req *pbv2.ListJobsRequest) (*pbv2.ListJobsResponse, error) {
+	resp, err := s.v1.ListJobs(ctx, &pb.ListJobsRequest{PageSize: req.PageSize, PageToken: req.PageToken})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*pbv2.JobSummary, len(resp.Jobs))
+	for i, j := range resp.Jobs {
+		jobs[i] = JobSummaryFromV1(j)
+	}
+	return &pbv2.ListJobsResponse{Jobs: jobs, NextPageToken: resp.NextPageToken}, nil
+}
+
+// SubmitJobGroup implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) SubmitJobGroup(ctx context.Context, req *pbv2.SubmitJobGroupRequest) (*pbv2.SubmitJobGroupResponse, error) {
+	jobs := make([]*pb.SubmitJobRequest, len(req.Jobs))
+	for i, j := range req.Jobs {
+		jobs[i] = submitJobRequestToV1(j)
+	}
+	resp, err := s.v1.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{GroupId: req.GroupId, Jobs: jobs})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbv2.SubmitJobGroupResponse{GroupId: resp.GroupId, Status: pbv2.JobStatus(resp.Status)}, nil
+}
+
+// GetJobGroup implements pbv2.OrchestratorServer
+func (s *OrchestratorServer) GetJobGroup(ctx context.Context, req *pbv2.GetJobGroupRequest) (*pbv2.GetJobGroupResponse, error) {
+	resp, err := s.v1.GetJobGroup(ctx, &pb.GetJobGroupRequest{GroupId: req.GroupId})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*pbv2.JobSummary, len(resp.Jobs))
+	for i, j := range resp.Jobs {
+		jobs[i] = JobSummaryFromV1(j)
+	}
+	return &pbv2.GetJobGroupResponse{GroupId: resp.GroupId, Status: pbv2.JobStatus(resp.Status), Jobs: jobs}, nil
+}
+
+// submitJobRequestToV1 converts a job submission
+func submitJobRequestToV1(req *pbv2.SubmitJobRequest) *pb.SubmitJobRequest {
+	return &pb.SubmitJobRequest{
+		JobId:       req.JobId,
+		JobType:     pb.JobType(req.JobType),
+		Payload:     req.Payload,
+		DependsOn:   req.DependsOn,
+		ContentType: req.ContentType,
+		Locality:    req.Locality,
+
+		PayloadFileId: req.PayloadFileId,
+	}
+}
+
+// LLMServer serves orchion.v2.OrchionLLM through a v1 server
+type LLMServer struct {
+	pbv2.UnimplementedOrchionLLMServer
+	v1 pb.OrchionLLMServer
+}
+
+// NewLLMServer creates a v2 server backed by v1
+func NewLLMServer(v1 pb.OrchionLLMServer) *LLMServer {
+	return &LLMServer{v1: v1}
+}
+
+// ChatCompletion implements pbv2.OrchionLLMServer
+func (s *LLMServer) ChatCompletion(req *pbv2.ChatCompletionRequest, stream pbv2.OrchionLLM_ChatCompletionServer) error {
+	return s.v1.ChatCompletion(chatCompletionRequestToV1(req), &chatStream{stream})
+}
+
+// Embeddings implements pbv2.OrchionLLMServer
+func (s *LLMServer) Embeddings(ctx context.Context, req *pbv2.EmbeddingRequest) (*pbv2.EmbeddingResponse, error) {
+	resp, err := s.v1.Embeddings(ctx, &pb.EmbeddingRequest{Model: req.Model, Input: req.Input, User: req.User})
+	if err != nil {
+		return nil, err
+	}
+	return embeddingResponseFromV1(resp), nil
+}
+
+// chatStream hands the v1 server a stream that converts what it sends to v2
+type chatStream struct {
+	pbv2.OrchionLLM_ChatCompletionServer
+}
+
+func (s *chatStream) Send(resp *pb.ChatCompletionResponse) error {
+	return s.OrchionLLM_ChatCompletionServer.Send(ChatCompletionResponseFromV1(resp))
+}
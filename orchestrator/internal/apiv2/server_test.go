@@ -0,0 +1,177 @@
+package apiv2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+)
+
+// fakeV1 answers v1 calls with canned responses and records requests
+type fakeV1 struct {
+	pb.UnimplementedOrchestratorServer
+	pb.UnimplementedOrchionLLMServer
+	listNodesReq *pb.ListNodesRequest
+	getNodeReq   *pb.GetNodeRequest
+	chatReq      *pb.ChatCompletionRequest
+}
+
+func (f *fakeV1) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
+	f.listNodesReq = req
+	return &pb.ListNodesResponse{
+		Nodes: []*pb.Node{
+			{Id: "node-1", LastSeenUnix: 1700000000, Labels: map[string]string{"zone": "eu"},
+				Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CUDA, GpuVramUsed: "2 GB"}},
+			{Id: "node-2"},
+		},
+		NextPageToken: "next",
+	}, nil
+}
+
+func (f *fakeV1) GetNode(ctx context.Context, req *pb.GetNodeRequest) (*pb.GetNodeResponse, error) {
+	f.getNodeReq = req
+	vram := 2048.0
+	return &pb.GetNodeResponse{
+		Node:    &pb.Node{Id: req.NodeId},
+		History: []*pb.NodeSample{{TimestampMs: 1700000000123, VramUsedMb: &vram}},
+		Models: []*pb.ModelStatus{
+			{Model: "llama3", State: pb.ModelState_MODEL_STATE_READY, SinceUnix: 1700000000, Engine: &pb.ModelEngine{Engine: "ollama"}},
+		},
+		ActiveJobs: []*pb.JobSummary{{JobId: "job-1", Status: pb.JobStatus_JOB_STATUS_RUNNING}},
+	}, nil
+}
+
+func (f *fakeV1) SubmitJob(ctx context.Context, req *pb.SubmitJobRequest) (*pb.SubmitJobResponse, error) {
+	return &pb.SubmitJobResponse{JobId: req.JobId, Status: pb.JobStatus_JOB_STATUS_PENDING, EstimatedWaitMs: 1500}, nil
+}
+
+func (f *fakeV1) GetJobStatus(ctx context.Context, req *pb.GetJobStatusRequest) (*pb.GetJobStatusResponse, error) {
+	return &pb.GetJobStatusResponse{
+		JobId:    req.JobId,
+		Status:   pb.JobStatus_JOB_STATUS_COMPLETED,
+		GpuUsage: &pb.GpuUsage{Start: &pb.GpuSample{UtilizationPercent: 40, TimestampMs: 1700000000123}},
+	}, nil
+}
+
+func (f *fakeV1) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	return &pb.ListJobsResponse{Jobs: []*pb.JobSummary{
+		{JobId: "job-1", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS, CreatedAtUnixMs: 1700000000500, UpdatedAtUnixMs: 1700000001000},
+	}}, nil
+}
+
+func (f *fakeV1) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.OrchionLLM_ChatCompletionServer) error {
+	f.chatReq = req
+	return stream.Send(&pb.ChatCompletionResponse{
+		Id:      "chat-1",
+		Created: 1700000000,
+		Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", Content: "hi"}}},
+	})
+}
+
+// fakeChatStream collects what a v2 server sends
+type fakeChatStream struct {
+	grpc.ServerStream
+	sent []*pbv2.ChatCompletionResponse
+}
+
+func (s *fakeChatStream) Context() context.Context { return context.Background() }
+
+func (s *fakeChatStream) Send(resp *pbv2.ChatCompletionResponse) error {
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+func TestOrchestratorServer(t *testing.T) {
+	v1 := &fakeV1{}
+	server := NewOrchestratorServer(v1)
+	ctx := context.Background()
+
+	t.Run("ListNodes", func(t *testing.T) {
+		gpu := true
+		resp, err := server.ListNodes(ctx, &pbv2.ListNodesRequest{PageSize: 10, Gpu: &gpu, OrderBy: "-last_seen"})
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(10), v1.listNodesReq.PageSize)
+		assert.True(t, v1.listNodesReq.GetGpu())
+		assert.Equal(t, "-last_seen", v1.listNodesReq.OrderBy)
+
+		require.Len(t, resp.Nodes, 2)
+		assert.Equal(t, "next", resp.NextPageToken)
+		assert.Equal(t, time.Unix(1700000000, 0).UTC(), resp.Nodes[0].LastSeenTime.AsTime())
+		assert.Equal(t, pbv2.GpuBackend_GPU_BACKEND_CUDA, resp.Nodes[0].Capabilities.GpuBackend)
+		assert.Equal(t, "2 GB", resp.Nodes[0].Capabilities.GpuVramUsed)
+		assert.Equal(t, "eu", resp.Nodes[0].Labels["zone"])
+		assert.Nil(t, resp.Nodes[1].LastSeenTime, "unknown times stay unset")
+	})
+
+	t.Run("GetNode", func(t *testing.T) {
+		resp, err := server.GetNode(ctx, &pbv2.GetNodeRequest{NodeId: "node-1", HistoryWindow: durationpb.New(10 * time.Minute)})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(600000), v1.getNodeReq.HistoryWindowMs)
+		assert.Equal(t, "node-1", resp.Node.Id)
+		require.Len(t, resp.History, 1)
+		assert.Equal(t, time.UnixMilli(1700000000123).UTC(), resp.History[0].Time.AsTime())
+		assert.Equal(t, 2048.0, resp.History[0].GetVramUsedMb())
+		assert.Nil(t, resp.History[0].GpuPowerW)
+		require.Len(t, resp.Models, 1)
+		assert.Equal(t, pbv2.ModelState_MODEL_STATE_READY, resp.Models[0].State)
+		assert.Equal(t, "ollama", resp.Models[0].Engine.Engine)
+		require.Len(t, resp.ActiveJobs, 1)
+		assert.Equal(t, pbv2.JobStatus_JOB_STATUS_RUNNING, resp.ActiveJobs[0].Status)
+
+		// No window asks for the default
+		_, err = server.GetNode(ctx, &pbv2.GetNodeRequest{NodeId: "node-1"})
+		require.NoError(t, err)
+		assert.Zero(t, v1.getNodeReq.HistoryWindowMs)
+	})
+
+	t.Run("SubmitJob", func(t *testing.T) {
+		resp, err := server.SubmitJob(ctx, &pbv2.SubmitJobRequest{JobId: "job-1"})
+		require.NoError(t, err)
+		assert.Equal(t, pbv2.JobStatus_JOB_STATUS_PENDING, resp.Status)
+		assert.Equal(t, 1500*time.Millisecond, resp.EstimatedWait.AsDuration())
+	})
+
+	t.Run("GetJobStatus", func(t *testing.T) {
+		resp, err := server.GetJobStatus(ctx, &pbv2.GetJobStatusRequest{JobId: "job-1"})
+		require.NoError(t, err)
+		assert.Equal(t, pbv2.JobStatus_JOB_STATUS_COMPLETED, resp.Status)
+		assert.Nil(t, resp.EstimatedWait)
+		assert.Equal(t, time.UnixMilli(1700000000123).UTC(), resp.GpuUsage.Start.SampleTime.AsTime())
+		assert.Nil(t, resp.GpuUsage.End)
+	})
+
+	t.Run("ListJobs", func(t *testing.T) {
+		resp, err := server.ListJobs(ctx, &pbv2.ListJobsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Jobs, 1)
+		assert.Equal(t, pbv2.JobType_JOB_TYPE_EMBEDDINGS, resp.Jobs[0].JobType)
+		assert.Equal(t, time.UnixMilli(1700000000500).UTC(), resp.Jobs[0].CreateTime.AsTime())
+		assert.Equal(t, time.UnixMilli(1700000001000).UTC(), resp.Jobs[0].UpdateTime.AsTime())
+	})
+}
+
+func TestLLMServerChatCompletion(t *testing.T) {
+	v1 := &fakeV1{}
+	stream := &fakeChatStream{}
+
+	err := NewLLMServer(v1).ChatCompletion(&pbv2.ChatCompletionRequest{
+		Model:    "llama3",
+		Messages: []*pbv2.ChatMessage{{Role: "user", Content: "hello"}},
+	}, stream)
+	require.NoError(t, err)
+
+	assert.Equal(t, "llama3", v1.chatReq.Model)
+	assert.Equal(t, "hello", v1.chatReq.Messages[0].Content)
+	require.Len(t, stream.sent, 1)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), stream.sent[0].CreateTime.AsTime())
+	assert.Equal(t, "hi", stream.sent[0].Choices[0].Message.Content)
+}
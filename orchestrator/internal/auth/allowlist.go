@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// AllowList admits HTTP clients by network address, e.g. to keep a guest
+// network off an orchestrator exposed on a LAN. Loopback clients are only
+// admitted if listed: behind a reverse proxy on the same host that isn't
+// trusted, every client seems to connect from loopback.
+type AllowList struct {
+	prefixes []netip.Prefix
+}
+
+// ParseAllowList parses comma-separated CIDRs or single IP addresses, e.g.
+// "192.168.1.0/24,10.0.0.5"
+func ParseAllowList(spec string) (*AllowList, error) {
+	prefixes, err := parsePrefixes(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &AllowList{prefixes: prefixes}, nil
+}
+
+// parsePrefixes parses comma-separated CIDRs or single IP addresses
+func parsePrefixes(spec string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %v", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", entry, err)
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// containsAddr reports whether any of prefixes contains addr
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a client address is admitted
+func (l *AllowList) Allows(addr netip.Addr) bool {
+	return containsAddr(l.prefixes, addr)
+}
+
+// Middleware rejects requests from clients outside the list with a 403. The
+// client is the connection's peer, or the address a trusted proxy forwarded
+// the request for if TrustedProxies.Middleware runs first.
+func (l *AllowList) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil || !l.Allows(addrPort.Addr()) {
+			http.Error(w, "your address is not allowed to use this orchestrator", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowList(t *testing.T) {
+	l, err := ParseAllowList("192.168.1.0/24, 10.0.0.5,fd00::/8,")
+	require.NoError(t, err)
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"192.168.1.42", true},
+		{"192.168.2.1", false},
+		{"10.0.0.5", true},
+		{"10.0.0.6", false},
+		{"::ffff:192.168.1.42", true},
+		{"fd12::1", true},
+		{"2001:db8::1", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, l.Allows(netip.MustParseAddr(tt.addr)))
+		})
+	}
+
+	local, err := ParseAllowList("127.0.0.1,::1")
+	require.NoError(t, err)
+	assert.True(t, local.Allows(netip.MustParseAddr("127.0.0.1")))
+	assert.True(t, local.Allows(netip.MustParseAddr("::1")))
+
+	for _, spec := range []string{"192.168.1.0/33", "not-an-ip", "10.0.0.0/8,bad"} {
+		_, err := ParseAllowList(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAllowList_Middleware(t *testing.T) {
+	l, err := ParseAllowList("192.168.1.0/24")
+	require.NoError(t, err)
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for remote, want := range map[string]int{
+		"192.168.1.10:51234": http.StatusOK,
+		"[::1]:51234":        http.StatusForbidden,
+		"172.16.0.10:51234":  http.StatusForbidden,
+		"garbage":            http.StatusForbidden,
+	} {
+		t.Run(remote, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			req.RemoteAddr = remote
+			req.Header.Set("X-Forwarded-For", "192.168.1.10")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, want, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Package auth decides which callers may use the orchestrator's HTTP API and
+// gRPC admin methods. Every API key carries a role, and each request needs a
+// role depending on what it does: reading the dashboard API, changing
+// something such as a deployment, or administering the orchestrator.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+)
+
+// Role is what a caller may do, from least to most privileged
+type Role int
+
+const (
+	// RoleAnonymous callers present no valid key. They may read the
+	// dashboard API when anonymous reads are enabled.
+	RoleAnonymous Role = iota
+	// RoleViewer callers may read the dashboard API, e.g. list nodes and jobs
+	RoleViewer
+	// RoleOperator callers may also change the cluster, e.g. deploy models
+	RoleOperator
+	// RoleAdmin callers may also use /api/admin/: manage keys, change the log
+	// level and annotate nodes
+	RoleAdmin
+)
+
+// String returns the string representation of Role
+func (r Role) String() string {
+	switch r {
+	case RoleAnonymous:
+		return "anonymous"
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRole parses the name of a role a key can hold
+func ParseRole(name string) (Role, error) {
+	for r := RoleViewer; r <= RoleAdmin; r++ {
+		if r.String() == name {
+			return r, nil
+		}
+	}
+	return RoleAnonymous, fmt.Errorf("unknown role %q (want viewer, operator or admin)", name)
+}
+
+// ParseRoleKeys parses comma-separated role:key pairs, e.g.
+// "viewer:abc,operator:def"
+func ParseRoleKeys(spec string) (map[string]Role, error) {
+	keys := make(map[string]Role)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid role:key pair %q", pair)
+		}
+		role, err := ParseRole(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		keys[strings.TrimSpace(key)] = role
+	}
+	return keys, nil
+}
+
+// Errors returned by RemoveKey and Login
+var (
+	ErrKeyNotFound = errors.New("API key not found")
+	ErrLastAdmin   = errors.New("can't remove the last admin key")
+	ErrInvalidKey  = errors.New("invalid API key")
+)
+
+// KeyInfo describes a key without revealing it
+type KeyInfo struct {
+	ID   string `json:"id"` // See KeyID
+	Role string `json:"role"`
+}
+
+// KeyID identifies a key in listings and audit records without revealing it
+func KeyID(key string) string {
+	return usage.APIKeyID(key)
+}
+
+// Authorizer assigns roles to requests from their API key. With no keys
+// configured authentication is disabled and every request is allowed.
+type Authorizer struct {
+	mu            sync.RWMutex
+	keys          map[string]Role
+	anonymousRead bool
+	sessions      *Sessions // Optional; nil disables Login
+}
+
+// NewAuthorizer creates an authorizer where apiKey is an operator key and
+// adminKeys are admin keys. Without admin keys apiKey is an admin key
+// instead, so single-key setups can still reach the admin endpoints. Empty
+// keys are ignored.
+func NewAuthorizer(apiKey string, adminKeys []string) *Authorizer {
+	a := &Authorizer{keys: make(map[string]Role)}
+	for _, key := range adminKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			a.keys[key] = RoleAdmin
+		}
+	}
+	if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+		if len(a.keys) == 0 {
+			a.keys[apiKey] = RoleAdmin
+		} else if _, ok := a.keys[apiKey]; !ok {
+			a.keys[apiKey] = RoleOperator
+		}
+	}
+	return a
+}
+
+// SetAnonymousRead lets callers without a key read the dashboard API, e.g. to
+// show /api/nodes on a wall display, while changes still need a key
+func (a *Authorizer) SetAnonymousRead(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.anonymousRead = enabled
+}
+
+// SetSessions lets callers exchange a key for a session token with Login.
+// Session tokens are accepted wherever the key they were opened with is.
+func (a *Authorizer) SetSessions(sessions *Sessions) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessions = sessions
+}
+
+// Login exchanges a key for a session token with the key's role, returning
+// the token, the role and when the session expires
+func (a *Authorizer) Login(key string) (string, Role, time.Time, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	role, ok := a.keys[key]
+	if !ok || key == "" || a.sessions == nil {
+		return "", RoleAnonymous, time.Time{}, ErrInvalidKey
+	}
+	token, expires := a.sessions.Issue(KeyID(key))
+	return token, role, expires, nil
+}
+
+// SetKey adds a key or changes its role
+func (a *Authorizer) SetKey(key string, role Role) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[key] = role
+}
+
+// RemoveKey removes the key with the given ID. The last admin key can't be
+// removed while other keys remain, so the admin endpoints stay reachable.
+func (a *Authorizer) RemoveKey(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	admins := 0
+	for _, role := range a.keys {
+		if role == RoleAdmin {
+			admins++
+		}
+	}
+	for key, role := range a.keys {
+		if KeyID(key) != id {
+			continue
+		}
+		if role == RoleAdmin && admins == 1 && len(a.keys) > 1 {
+			return ErrLastAdmin
+		}
+		delete(a.keys, key)
+		return nil
+	}
+	return ErrKeyNotFound
+}
+
+// Keys lists the configured keys by ID
+func (a *Authorizer) Keys() []KeyInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	keys := make([]KeyInfo, 0, len(a.keys))
+	for key, role := range a.keys {
+		keys = append(keys, KeyInfo{ID: KeyID(key), Role: role.String()})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// Enabled reports whether any key is configured
+func (a *Authorizer) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.keys) > 0
+}
+
+// RoleOf returns the role of the key or session token a request presents in
+// its Authorization header or, failing that, its session cookie
+func (a *Authorizer) RoleOf(r *http.Request) Role {
+	key := RequestKey(r)
+	if key == "" {
+		if cookie, err := r.Cookie(SessionCookie); err == nil {
+			key = cookie.Value
+		}
+	}
+	return a.RoleOfKey(key)
+}
+
+// RoleOfKey returns the role of a key or session token
+func (a *Authorizer) RoleOfKey(key string) Role {
+	if key == "" {
+		return RoleAnonymous
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if role, ok := a.keys[key]; ok {
+		return role
+	}
+	if a.sessions == nil {
+		return RoleAnonymous
+	}
+	id, err := a.sessions.Verify(key)
+	if err != nil {
+		return RoleAnonymous
+	}
+	for k, role := range a.keys {
+		if KeyID(k) == id {
+			return role
+		}
+	}
+	return RoleAnonymous
+}
+
+// KeyIDOf returns the ID of a key, or of the key a session token was opened
+// with, so a caller is known by one ID whichever it presents
+func (a *Authorizer) KeyIDOf(key string) string {
+	if key == "" {
+		return ""
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if _, ok := a.keys[key]; ok || a.sessions == nil {
+		return KeyID(key)
+	}
+	if id, err := a.sessions.Verify(key); err == nil {
+		return id
+	}
+	return KeyID(key)
+}
+
+// Required returns the role a request needs: admin for /api/admin/, operator
+// for anything but reads, and viewer for reads unless anonymous reads are
+// enabled. CORS preflights carry no credentials and are always allowed, as is
+// logging in under /api/auth/.
+func (a *Authorizer) Required(r *http.Request) Role {
+	switch {
+	case r.Method == http.MethodOptions, strings.HasPrefix(r.URL.Path, "/api/auth/"):
+		return RoleAnonymous
+	case strings.HasPrefix(r.URL.Path, "/api/admin/"):
+		return RoleAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		if a.anonymousRead {
+			return RoleAnonymous
+		}
+		return RoleViewer
+	default:
+		return RoleOperator
+	}
+}
+
+// Middleware rejects requests under /api/ whose key doesn't grant the role
+// they need: 401 without a valid key, 403 with a key of too low a role. Other
+// paths are passed through; the OpenAI gateway under /v1/ checks keys itself.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		required := a.Required(r)
+		role := a.RoleOf(r)
+		if role >= required {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if role == RoleAnonymous {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orchion"`)
+			http.Error(w, "a valid API key is required", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, fmt.Sprintf("%s keys can't %s %s", role, r.Method, r.URL.Path), http.StatusForbidden)
+	})
+}
+
+// RequestKey extracts the API key from the Authorization header.
+// Both "Bearer <key>" and "sk-<key>" formats are supported.
+func RequestKey(r *http.Request) string {
+	return parseKey(r.Header.Get("Authorization"))
+}
+
+func parseKey(authHeader string) string {
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if strings.HasPrefix(authHeader, "sk-") {
+		return strings.TrimPrefix(authHeader, "sk-")
+	}
+
+	return authHeader
+}
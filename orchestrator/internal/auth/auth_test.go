@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestKey(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"Bearer secret", "secret"},
+		{"sk-secret", "secret"},
+		{"secret", "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", tt.header)
+			assert.Equal(t, tt.want, RequestKey(req))
+		})
+	}
+}
+
+func TestAuthorizer_RoleOf(t *testing.T) {
+	tests := []struct {
+		name      string
+		adminKeys []string
+		key       string
+		want      Role
+	}{
+		{"no key", []string{"root"}, "", RoleAnonymous},
+		{"unknown key", []string{"root"}, "guess", RoleAnonymous},
+		{"api key", []string{"root"}, "secret", RoleOperator},
+		{"admin key", []string{"root"}, "root", RoleAdmin},
+		{"api key without admin keys", nil, "secret", RoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthorizer("secret", tt.adminKeys)
+			req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			assert.Equal(t, tt.want, a.RoleOf(req))
+		})
+	}
+}
+
+func TestAuthorizer_Middleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		anonymousRead bool
+		method        string
+		path          string
+		key           string
+		want          int
+	}{
+		{"read needs a key", false, http.MethodGet, "/api/nodes", "", http.StatusUnauthorized},
+		{"read with key", false, http.MethodGet, "/api/nodes", "secret", http.StatusOK},
+		{"anonymous read", true, http.MethodGet, "/api/nodes", "", http.StatusOK},
+		{"viewer read", false, http.MethodGet, "/api/nodes", "viewer-key", http.StatusOK},
+		{"viewer write", false, http.MethodPost, "/api/deployments", "viewer-key", http.StatusForbidden},
+		{"anonymous write", true, http.MethodPost, "/api/deployments", "", http.StatusUnauthorized},
+		{"write with key", true, http.MethodPost, "/api/deployments", "secret", http.StatusOK},
+		{"wrong key", true, http.MethodDelete, "/api/deployments/llama3", "guess", http.StatusUnauthorized},
+		{"anonymous admin read", true, http.MethodGet, "/api/admin/loglevel", "", http.StatusUnauthorized},
+		{"admin with api key", true, http.MethodPut, "/api/admin/loglevel", "secret", http.StatusForbidden},
+		{"admin with admin key", false, http.MethodPut, "/api/admin/loglevel", "root", http.StatusOK},
+		{"login without a key", false, http.MethodPost, "/api/auth/login", "", http.StatusOK},
+		{"preflight", false, http.MethodOptions, "/api/deployments", "", http.StatusOK},
+		{"gateway checks its own keys", false, http.MethodPost, "/v1/chat/completions", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthorizer("secret", []string{"root"})
+			a.SetKey("viewer-key", RoleViewer)
+			a.SetAnonymousRead(tt.anonymousRead)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rec := httptest.NewRecorder()
+			a.Middleware(ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("disabled without keys", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/loglevel", nil)
+		rec := httptest.NewRecorder()
+		NewAuthorizer("", nil).Middleware(ok).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestParseRoleKeys(t *testing.T) {
+	keys, err := ParseRoleKeys("viewer:abc, operator:def,admin:g:h,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Role{"abc": RoleViewer, "def": RoleOperator, "g:h": RoleAdmin}, keys)
+
+	for _, spec := range []string{"viewer", "viewer:", "owner:abc", "anonymous:abc"} {
+		_, err := ParseRoleKeys(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestAuthorizer_Keys(t *testing.T) {
+	a := NewAuthorizer("secret", []string{"root"})
+	a.SetKey("viewer-key", RoleViewer)
+
+	assert.ElementsMatch(t, []KeyInfo{
+		{ID: KeyID("secret"), Role: "operator"},
+		{ID: KeyID("root"), Role: "admin"},
+		{ID: KeyID("viewer-key"), Role: "viewer"},
+	}, a.Keys())
+
+	assert.ErrorIs(t, a.RemoveKey(KeyID("root")), ErrLastAdmin)
+	assert.ErrorIs(t, a.RemoveKey("unknown"), ErrKeyNotFound)
+
+	require.NoError(t, a.RemoveKey(KeyID("viewer-key")))
+	req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+	req.Header.Set("Authorization", "Bearer viewer-key")
+	assert.Equal(t, RoleAnonymous, a.RoleOf(req))
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+)
+
+// AdminMethods are the gRPC methods that need an admin key
+var AdminMethods = map[string]Role{
+	pb.Orchestrator_SetLogLevel_FullMethodName: RoleAdmin,
+}
+
+// UnaryInterceptor rejects calls to the given methods unless the key in the
+// "authorization" metadata grants the role they need. Other methods, such as
+// node registration and heartbeats, are passed through.
+func (a *Authorizer) UnaryInterceptor(methods map[string]Role) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		required, ok := methods[info.FullMethod]
+		if !ok || !a.Enabled() {
+			return handler(ctx, req)
+		}
+
+		role := a.RoleOfKey(MetadataKey(ctx))
+		if role >= required {
+			return handler(ctx, req)
+		}
+		if role == RoleAnonymous {
+			return nil, errcode.New(codes.Unauthenticated, pb.ErrorCode_ERROR_CODE_AUTH_FAILED, "a valid API key is required")
+		}
+		return nil, errcode.Errorf(codes.PermissionDenied, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED,
+			"%s keys can't call %s", role, info.FullMethod)
+	}
+}
+
+// MetadataKey extracts the API key from incoming "authorization" metadata
+func MetadataKey(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		return parseKey(values[0])
+	}
+	return ""
+}
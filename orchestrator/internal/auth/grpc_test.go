@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestAuthorizer_UnaryInterceptor(t *testing.T) {
+	a := NewAuthorizer("secret", []string{"root"})
+	interceptor := a.UnaryInterceptor(AdminMethods)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		want   codes.Code
+	}{
+		{"admin method with admin key", pb.Orchestrator_SetLogLevel_FullMethodName, "root", codes.OK},
+		{"admin method with operator key", pb.Orchestrator_SetLogLevel_FullMethodName, "secret", codes.PermissionDenied},
+		{"admin method without key", pb.Orchestrator_SetLogLevel_FullMethodName, "", codes.Unauthenticated},
+		{"other method without key", pb.Orchestrator_Heartbeat_FullMethodName, "", codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.key))
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies are the reverse proxies (nginx, Caddy, a Cloudflare Tunnel
+// connector) whose word on the real client address is taken, through the
+// X-Forwarded-For header or the PROXY protocol
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// ParseTrustedProxies parses comma-separated CIDRs or single IP addresses of
+// trusted proxies
+func ParseTrustedProxies(spec string) (*TrustedProxies, error) {
+	prefixes, err := parsePrefixes(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &TrustedProxies{prefixes: prefixes}, nil
+}
+
+// Trusts reports whether addr is a trusted proxy
+func (p *TrustedProxies) Trusts(addr netip.Addr) bool {
+	return containsAddr(p.prefixes, addr)
+}
+
+// ClientAddr returns the address of the client a request came from. If the
+// peer is a trusted proxy, X-Forwarded-For is walked from the right, skipping
+// further trusted proxies, so clients can't spoof it by sending their own.
+// The zero Addr is returned if RemoteAddr isn't an address.
+func (p *TrustedProxies) ClientAddr(r *http.Request) netip.Addr {
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+	client := peer.Addr().Unmap()
+	if !p.Trusts(client) {
+		return client
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !p.Trusts(client) {
+			break
+		}
+	}
+	return client
+}
+
+// Middleware replaces the RemoteAddr of requests forwarded by trusted proxies
+// with the client's address, so later middleware, rate limiting and audit
+// logs see the client instead of the proxy
+func (p *TrustedProxies) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err == nil && p.Trusts(peer.Addr()) {
+			if client := p.ClientAddr(r); client != peer.Addr().Unmap() {
+				r = r.WithContext(r.Context())
+				r.RemoteAddr = netip.AddrPortFrom(client, 0).String()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP returns the IP address a request came from, without the port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
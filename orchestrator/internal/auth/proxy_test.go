@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedProxies_ClientAddr(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted peer can't spoof", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of proxies", "10.0.0.2:1234", []string{"198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		{"spoofed hop left of the client", "10.0.0.2:1234", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"multiple headers", "10.0.0.2:1234", []string{"198.51.100.1", "10.0.0.3"}, "198.51.100.1"},
+		{"proxy without header", "10.0.0.2:1234", nil, "10.0.0.2"},
+		{"garbage hop", "10.0.0.2:1234", []string{"unknown"}, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, tt.want, proxies.ClientAddr(req).String())
+		})
+	}
+}
+
+func TestTrustedProxies_Middleware(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.2")
+	require.NoError(t, err)
+
+	var seen string
+	handler := proxies.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = ClientIP(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "2001:db8::1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "2001:db8::1", seen)
+	assert.Equal(t, "10.0.0.2:1234", req.RemoteAddr, "the original request is left alone")
+}
+
+// acceptOne sends data on a new connection to l and returns the accepted
+// connection's remote address and first bytes after the header
+func acceptOne(t *testing.T, l net.Listener, data []byte) (string, string) {
+	t.Helper()
+	client, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write(append(data, "GET /"...))
+	require.NoError(t, err)
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 5)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = conn.Read(buf)
+	require.NoError(t, err)
+	return conn.RemoteAddr().String(), string(buf)
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxies, err := ParseTrustedProxies("127.0.0.1")
+	require.NoError(t, err)
+	l := NewProxyProtocolListener(inner, proxies)
+	defer l.Close()
+
+	v2 := func(family byte, addrs []byte) []byte {
+		header := append([]byte{}, proxyV2Signature...)
+		header = append(header, 0x21, family, 0, 0)
+		binary.BigEndian.PutUint16(header[14:], uint16(len(addrs)))
+		return append(header, addrs...)
+	}
+	v4Addrs := []byte{198, 51, 100, 1, 10, 0, 0, 1, 0x30, 0x39, 0x1f, 0x90}
+	v6Addrs := make([]byte, 36)
+	copy(v6Addrs, net.ParseIP("2001:db8::1").To16())
+	binary.BigEndian.PutUint16(v6Addrs[32:], 443)
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 8080\r\n"), "198.51.100.1:12345"},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::1 ::1 443 8080\r\n"), "[2001:db8::1]:443"},
+		{"v2 IPv4", v2(0x11, v4Addrs), "198.51.100.1:12345"},
+		{"v2 IPv6", v2(0x21, v6Addrs), "[2001:db8::1]:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, data := acceptOne(t, l, tt.header)
+			assert.Equal(t, tt.want, remote)
+			assert.Equal(t, "GET /", data)
+		})
+	}
+
+	t.Run("no header", func(t *testing.T) {
+		remote, data := acceptOne(t, l, []byte("GET /index.html HTTP/1.1\r\n"))
+		assert.Contains(t, remote, "127.0.0.1:")
+		assert.Equal(t, "GET /", data)
+	})
+
+	t.Run("v1 UNKNOWN keeps the peer address", func(t *testing.T) {
+		remote, _ := acceptOne(t, l, []byte("PROXY UNKNOWN\r\n"))
+		assert.Contains(t, remote, "127.0.0.1:")
+	})
+}
+
+func TestProxyProtocolListener_UntrustedPeer(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxies, err := ParseTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+	l := NewProxyProtocolListener(inner, proxies)
+	defer l.Close()
+
+	// The header is left in the stream for the HTTP server to reject
+	remote, data := acceptOne(t, l, []byte("PROXY TCP4 198.51.100.1 10.0.0.1 12345 8080\r\n"))
+	assert.Contains(t, remote, "127.0.0.1:")
+	assert.Equal(t, "PROXY", data)
+}
+
+func TestProxyProtocolListener_Close(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxies, err := ParseTrustedProxies("127.0.0.1")
+	require.NoError(t, err)
+	l := NewProxyProtocolListener(inner, proxies)
+
+	done := make(chan error)
+	go func() {
+		_, err := l.Accept()
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	l.Close()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Accept didn't return after Close")
+	}
+}
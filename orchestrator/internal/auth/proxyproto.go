@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyHeaderTimeout bounds how long a trusted proxy may take to send its
+// PROXY protocol header
+const ProxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature starts every PROXY protocol v2 header
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxProxyV1Length is the longest PROXY protocol v1 line, CRLF included
+const maxProxyV1Length = 107
+
+// proxyListener reads PROXY protocol headers on connections from trusted proxies
+type proxyListener struct {
+	net.Listener
+	trusted *TrustedProxies
+
+	conns chan net.Conn
+	errc  chan error
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewProxyProtocolListener wraps a listener so connections from trusted
+// proxies report the client address from their PROXY protocol (v1 or v2)
+// header as RemoteAddr. Headers are read off the accept loop, so a slow proxy
+// doesn't hold up other connections. Connections from other peers, or from
+// trusted proxies that send no header, are passed through unchanged.
+func NewProxyProtocolListener(inner net.Listener, trusted *TrustedProxies) net.Listener {
+	l := &proxyListener{
+		Listener: inner,
+		trusted:  trusted,
+		conns:    make(chan net.Conn),
+		errc:     make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *proxyListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errc <- err:
+			case <-l.done:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.handshake(conn)
+	}
+}
+
+// handshake reads the PROXY header of a trusted proxy's connection and hands
+// the connection to Accept
+func (l *proxyListener) handshake(conn net.Conn) {
+	if peer, ok := conn.RemoteAddr().(*net.TCPAddr); ok && l.trusted.Trusts(peer.AddrPort().Addr()) {
+		conn.SetReadDeadline(time.Now().Add(ProxyHeaderTimeout))
+		wrapped, err := readProxyHeader(conn)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+		conn = wrapped
+	}
+
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// Accept returns the next connection whose header has been read
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errc:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the underlying listener
+func (l *proxyListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// proxyConn is a connection whose remote address came from a PROXY header
+type proxyConn struct {
+	net.Conn
+	r      *bufio.Reader // Holds bytes read past the header
+	remote net.Addr
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+func (c *proxyConn) RemoteAddr() net.Addr       { return c.remote }
+
+// readProxyHeader reads a PROXY protocol header, if there is one, and returns
+// a connection reporting the client address it names
+func readProxyHeader(conn net.Conn) (net.Conn, error) {
+	r := bufio.NewReader(conn)
+	wrapped := &proxyConn{Conn: conn, r: r, remote: conn.RemoteAddr()}
+
+	prefix, err := r.Peek(len(proxyV2Signature))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var remote net.Addr
+	switch {
+	case bytes.Equal(prefix, proxyV2Signature):
+		remote, err = readProxyV2(r)
+	case bytes.HasPrefix(prefix, []byte("PROXY ")):
+		remote, err = readProxyV1(r)
+	default:
+		return wrapped, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if remote != nil {
+		wrapped.remote = remote
+	}
+	return wrapped, nil
+}
+
+// readProxyV1 parses "PROXY TCP4 <src> <dst> <sport> <dport>\r\n". It returns
+// a nil address for "PROXY UNKNOWN", e.g. the proxy's own health checks.
+func readProxyV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= maxProxyV1Length {
+			return nil, errors.New("PROXY v1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed PROXY v1 header %q", strings.TrimSpace(string(line)))
+	}
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed PROXY v1 source address: %v", err)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("malformed PROXY v1 source port: %v", err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr.Unmap(), uint16(port))), nil
+}
+
+// readProxyV2 parses a binary PROXY v2 header. It returns a nil address for
+// LOCAL commands and address families other than TCP over IPv4 or IPv6.
+func readProxyV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", header[12]>>4)
+	}
+	command, family := header[12]&0x0f, header[13]
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	if command != 1 { // LOCAL
+		return nil, nil
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(body) < 12 {
+			return nil, errors.New("short PROXY v2 IPv4 addresses")
+		}
+		addr := netip.AddrFrom4([4]byte(body[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[8:10]))), nil
+	case 0x21: // TCP over IPv6
+		if len(body) < 36 {
+			return nil, errors.New("short PROXY v2 IPv6 addresses")
+		}
+		addr := netip.AddrFrom16([16]byte(body[0:16])).Unmap()
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[32:34]))), nil
+	default:
+		return nil, nil
+	}
+}
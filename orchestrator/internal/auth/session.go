@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTTL is how long a dashboard session lasts before the user has
+// to log in again
+const DefaultSessionTTL = 12 * time.Hour
+
+// SessionCookie is the cookie carrying a session token for same-origin
+// dashboards and EventSource streams, which can't set headers
+const SessionCookie = "orchion_session"
+
+// ErrInvalidSession is returned for session tokens that are malformed, forged
+// or expired
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// sessionHeader is the fixed JOSE header of session tokens
+var sessionHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// sessionClaims are the claims of a session token. The subject is the ID of
+// the key the session was opened with, so removing the key ends the session
+// and changing its role changes the session's role.
+type sessionClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Sessions issues and verifies short-lived session tokens: JWTs signed with
+// HMAC-SHA256, so a dashboard can hold one instead of the API key itself
+type Sessions struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewSessions creates a session issuer signing with secret. With an empty
+// secret a random one is generated, so sessions end when the orchestrator
+// restarts.
+func NewSessions(secret []byte, ttl time.Duration) (*Sessions, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &Sessions{secret: secret, ttl: ttl, now: time.Now}, nil
+}
+
+// Issue returns a token for a session opened with the key with the given ID,
+// and when it expires
+func (s *Sessions) Issue(keyID string) (string, time.Time) {
+	now := s.now()
+	expires := now.Add(s.ttl)
+	claims, _ := json.Marshal(sessionClaims{
+		Subject:   keyID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	unsigned := sessionHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + s.sign(unsigned), expires
+}
+
+// Verify checks a token's signature and expiry and returns the ID of the key
+// its session was opened with
+func (s *Sessions) Verify(token string) (string, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok || header != sessionHeader {
+		return "", ErrInvalidSession
+	}
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(header+"."+payload))) {
+		return "", ErrInvalidSession
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidSession
+	}
+	var claims sessionClaims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Subject == "" {
+		return "", ErrInvalidSession
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return "", ErrInvalidSession
+	}
+	return claims.Subject, nil
+}
+
+func (s *Sessions) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
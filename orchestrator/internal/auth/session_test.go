@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions_IssueVerify(t *testing.T) {
+	sessions, err := NewSessions([]byte("secret"), time.Hour)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	sessions.now = func() time.Time { return now }
+
+	token, expires := sessions.Issue("key-id")
+	assert.Equal(t, now.Add(time.Hour), expires)
+
+	id, err := sessions.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "key-id", id)
+
+	t.Run("forged", func(t *testing.T) {
+		parts := strings.Split(token, ".")
+		forged := parts[0] + "." + parts[1] + "x." + parts[2]
+		_, err := sessions.Verify(forged)
+		assert.ErrorIs(t, err, ErrInvalidSession)
+
+		other, err := NewSessions([]byte("other"), time.Hour)
+		require.NoError(t, err)
+		_, err = other.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidSession)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		_, err := sessions.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidSession)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, token := range []string{"", "abc", "a.b.c", sessionHeader + ".!!." + "x"} {
+			_, err := sessions.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidSession, token)
+		}
+	})
+}
+
+func TestAuthorizer_Login(t *testing.T) {
+	a := NewAuthorizer("secret", []string{"root"})
+	sessions, err := NewSessions(nil, time.Hour)
+	require.NoError(t, err)
+	a.SetSessions(sessions)
+
+	_, _, _, err = a.Login("guess")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	token, role, _, err := a.Login("secret")
+	require.NoError(t, err)
+	assert.Equal(t, RoleOperator, role)
+
+	t.Run("bearer token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		assert.Equal(t, RoleOperator, a.RoleOf(req))
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+		assert.Equal(t, RoleOperator, a.RoleOf(req))
+	})
+
+	t.Run("key ID", func(t *testing.T) {
+		assert.Equal(t, KeyID("secret"), a.KeyIDOf("secret"))
+		assert.Equal(t, KeyID("secret"), a.KeyIDOf(token), "sessions are known by their key")
+		assert.Equal(t, KeyID("unknown"), a.KeyIDOf("unknown"))
+		assert.Empty(t, a.KeyIDOf(""))
+	})
+
+	t.Run("session follows the key's role", func(t *testing.T) {
+		a.SetKey("secret", RoleViewer)
+		req := httptest.NewRequest(http.MethodGet, "/api/nodes", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		assert.Equal(t, RoleViewer, a.RoleOf(req))
+
+		require.NoError(t, a.RemoveKey(KeyID("secret")))
+		assert.Equal(t, RoleAnonymous, a.RoleOf(req))
+	})
+}
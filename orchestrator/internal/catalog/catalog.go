@@ -0,0 +1,171 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Catalog holds operator-provided configuration for the models served by the cluster
+type Catalog struct {
+	Models map[string]*Model `json:"models"`
+
+	// GPUSharing schedules when GPU nodes keep capacity free for interactive
+	// requests
+	GPUSharing []*SharingWindow `json:"gpu_sharing,omitempty"`
+}
+
+// Model describes scheduling configuration for a single model
+type Model struct {
+	// NodeWeights assigns a relative share of the model's traffic to nodes,
+	// keyed by node ID or hostname (e.g. {"gpu-box": 80, "macbook": 20}).
+	// Nodes not listed only receive traffic when no weighted node is available.
+	NodeWeights map[string]float64 `json:"node_weights,omitempty"`
+
+	// Distributed serves the model from a vLLM deployment spanning several GPU
+	// nodes, for models too large for a single node
+	Distributed *Distributed `json:"distributed,omitempty"`
+
+	// WarmReplicas is the minimum number of nodes that keep the model loaded
+	// so traffic never waits for a cold start. The orchestrator preloads the
+	// model on other nodes as replicas leave.
+	WarmReplicas int `json:"warm_replicas,omitempty"`
+
+	// MinEngineVersion is the oldest engine version allowed to serve the
+	// model, keyed by engine (e.g. {"vllm": "0.6.3"}). Nodes reporting an
+	// older engine don't receive the model's traffic.
+	MinEngineVersion map[string]string `json:"min_engine_version,omitempty"`
+
+	// VRAMMB is the free GPU memory in MB a node needs to load the model.
+	// Nodes reporting less don't receive its traffic unless they have it
+	// loaded already.
+	VRAMMB float64 `json:"vram_mb,omitempty"`
+}
+
+// Distributed describes a multi-node deployment of a model
+type Distributed struct {
+	Nodes       int `json:"nodes"`                   // Number of GPU nodes to reserve
+	GPUsPerNode int `json:"gpus_per_node,omitempty"` // GPUs used on each node (default 1)
+}
+
+// New creates an empty catalog
+func New() *Catalog {
+	return &Catalog{
+		Models: make(map[string]*Model),
+	}
+}
+
+// LoadFile reads a catalog from a JSON file
+func LoadFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model catalog: %w", err)
+	}
+
+	c := New()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse model catalog %s: %w", path, err)
+	}
+	if c.Models == nil {
+		c.Models = make(map[string]*Model)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid model catalog %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Validate checks the catalog for invalid values
+func (c *Catalog) Validate() error {
+	for i, w := range c.GPUSharing {
+		if w == nil {
+			return fmt.Errorf("gpu_sharing window %d has no configuration", i)
+		}
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("gpu_sharing window %d: %w", i, err)
+		}
+	}
+	for name, model := range c.Models {
+		if model == nil {
+			return fmt.Errorf("model %q has no configuration", name)
+		}
+		for node, weight := range model.NodeWeights {
+			if weight < 0 {
+				return fmt.Errorf("model %q: weight for node %q must not be negative", name, node)
+			}
+		}
+		if model.WarmReplicas < 0 {
+			return fmt.Errorf("model %q: warm_replicas must not be negative", name)
+		}
+		if model.WarmReplicas > 0 && model.Distributed != nil {
+			return fmt.Errorf("model %q: warm_replicas can't be combined with a distributed deployment", name)
+		}
+		for engine, version := range model.MinEngineVersion {
+			if _, err := ParseVersion(version); err != nil {
+				return fmt.Errorf("model %q: min_engine_version for %q: %w", name, engine, err)
+			}
+		}
+		if model.VRAMMB < 0 {
+			return fmt.Errorf("model %q: vram_mb must not be negative", name)
+		}
+		if d := model.Distributed; d != nil {
+			if d.Nodes < 2 {
+				return fmt.Errorf("model %q: distributed deployments need at least 2 nodes", name)
+			}
+			if d.GPUsPerNode < 0 {
+				return fmt.Errorf("model %q: gpus_per_node must not be negative", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the configuration for a model
+func (c *Catalog) Get(model string) (*Model, bool) {
+	if c == nil {
+		return nil, false
+	}
+	m, ok := c.Models[model]
+	return m, ok
+}
+
+// WarmReplicas returns the models with a warm replica minimum, keyed by model
+func (c *Catalog) WarmReplicas() map[string]int {
+	replicas := make(map[string]int)
+	if c == nil {
+		return replicas
+	}
+	for name, model := range c.Models {
+		if model.WarmReplicas > 0 {
+			replicas[name] = model.WarmReplicas
+		}
+	}
+	return replicas
+}
+
+// MinEngineVersion returns the oldest version of engine allowed to serve model
+func (c *Catalog) MinEngineVersion(model, engine string) (string, bool) {
+	m, ok := c.Get(model)
+	if !ok {
+		return "", false
+	}
+	version, ok := m.MinEngineVersion[engine]
+	return version, ok
+}
+
+// NodeWeight returns the configured weight of a node for a model.
+// The node is matched by ID first, then by hostname. The second return value
+// is false if the model has no weights configured at all.
+func (c *Catalog) NodeWeight(model, nodeID, hostname string) (float64, bool) {
+	m, ok := c.Get(model)
+	if !ok || len(m.NodeWeights) == 0 {
+		return 0, false
+	}
+
+	if weight, ok := m.NodeWeights[nodeID]; ok {
+		return weight, true
+	}
+	return m.NodeWeights[hostname], true
+}
@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCatalog(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("valid catalog", func(t *testing.T) {
+		path := writeCatalog(t, `{"models": {"llama3": {"node_weights": {"gpu-box": 80, "macbook": 20}}}}`)
+
+		c, err := LoadFile(path)
+		require.NoError(t, err)
+
+		model, ok := c.Get("llama3")
+		require.True(t, ok)
+		assert.Equal(t, 80.0, model.NodeWeights["gpu-box"])
+		assert.Equal(t, 20.0, model.NodeWeights["macbook"])
+	})
+
+	t.Run("empty catalog", func(t *testing.T) {
+		c, err := LoadFile(writeCatalog(t, `{}`))
+		require.NoError(t, err)
+		assert.NotNil(t, c.Models)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models":`))
+		assert.Error(t, err)
+	})
+
+	t.Run("negative weight", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models": {"llama3": {"node_weights": {"gpu-box": -1}}}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not be negative")
+	})
+
+	t.Run("distributed model", func(t *testing.T) {
+		c, err := LoadFile(writeCatalog(t, `{"models": {"llama3:70b": {"distributed": {"nodes": 2, "gpus_per_node": 2}}}}`))
+		require.NoError(t, err)
+
+		model, ok := c.Get("llama3:70b")
+		require.True(t, ok)
+		assert.Equal(t, &Distributed{Nodes: 2, GPUsPerNode: 2}, model.Distributed)
+	})
+
+	t.Run("distributed model on a single node", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models": {"llama3:70b": {"distributed": {"nodes": 1}}}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "at least 2 nodes")
+	})
+
+	t.Run("warm replicas", func(t *testing.T) {
+		c, err := LoadFile(writeCatalog(t, `{"models": {"llama3": {"warm_replicas": 2}, "mistral": {}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"llama3": 2}, c.WarmReplicas())
+	})
+
+	t.Run("negative warm replicas", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models": {"llama3": {"warm_replicas": -1}}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "warm_replicas must not be negative")
+	})
+
+	t.Run("warm replicas of a distributed model", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models": {"llama3:70b": {"warm_replicas": 1, "distributed": {"nodes": 2}}}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("minimum engine version", func(t *testing.T) {
+		c, err := LoadFile(writeCatalog(t, `{"models": {"llama3": {"min_engine_version": {"vllm": "0.6.3"}}}}`))
+		require.NoError(t, err)
+
+		version, ok := c.MinEngineVersion("llama3", "vllm")
+		assert.True(t, ok)
+		assert.Equal(t, "0.6.3", version)
+		_, ok = c.MinEngineVersion("llama3", "ollama")
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid minimum engine version", func(t *testing.T) {
+		_, err := LoadFile(writeCatalog(t, `{"models": {"llama3": {"min_engine_version": {"vllm": "latest"}}}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid version")
+	})
+
+	t.Run("VRAM requirement", func(t *testing.T) {
+		c, err := LoadFile(writeCatalog(t, `{"models": {"llama3:70b": {"vram_mb": 42000}}}`))
+		require.NoError(t, err)
+		model, ok := c.Get("llama3:70b")
+		require.True(t, ok)
+		assert.Equal(t, 42000.0, model.VRAMMB)
+
+		_, err = LoadFile(writeCatalog(t, `{"models": {"llama3:70b": {"vram_mb": -1}}}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestCatalog_NodeWeight(t *testing.T) {
+	c := New()
+	c.Models["llama3"] = &Model{NodeWeights: map[string]float64{"node-1": 3, "macbook": 1}}
+	c.Models["mistral"] = &Model{}
+
+	weight, ok := c.NodeWeight("llama3", "node-1", "gpu-box")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, weight)
+
+	// Falls back to hostname
+	weight, ok = c.NodeWeight("llama3", "node-2", "macbook")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, weight)
+
+	// Unlisted node on a weighted model
+	weight, ok = c.NodeWeight("llama3", "node-3", "other")
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, weight)
+
+	// Models without weights
+	_, ok = c.NodeWeight("mistral", "node-1", "gpu-box")
+	assert.False(t, ok)
+	_, ok = c.NodeWeight("unknown", "node-1", "gpu-box")
+	assert.False(t, ok)
+
+	// Nil catalog
+	var nilCatalog *Catalog
+	_, ok = nilCatalog.NodeWeight("llama3", "node-1", "gpu-box")
+	assert.False(t, ok)
+}
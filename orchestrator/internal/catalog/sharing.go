@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SharingWindow reserves part of each GPU node's capacity for interactive
+// requests during configured hours, e.g. office hours, so batch jobs only
+// use the full capacity outside them
+type SharingWindow struct {
+	Name string `json:"name,omitempty"`
+
+	// Days are the weekdays the window starts on ("mon" to "sun"); every day
+	// if empty
+	Days []string `json:"days,omitempty"`
+
+	// Start and End are times of day ("08:00") in the orchestrator's time
+	// zone. A window ending before it starts runs past midnight; one ending
+	// when it starts lasts all day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+
+	// InteractiveReserve is the fraction of concurrency and VRAM batch
+	// requests leave free, from 0 to below 1
+	InteractiveReserve float64 `json:"interactive_reserve"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseTimeOfDay parses "HH:MM" into minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks the window for invalid values
+func (w *SharingWindow) Validate() error {
+	if w.InteractiveReserve < 0 || w.InteractiveReserve >= 1 {
+		return fmt.Errorf("interactive_reserve must be at least 0 and below 1")
+	}
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := parseTimeOfDay(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day %q (want mon to sun)", day)
+		}
+	}
+	return nil
+}
+
+// startsOn reports whether the window starts on a weekday
+func (w *SharingWindow) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t falls in the window
+func (w *SharingWindow) Contains(t time.Time) bool {
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	yesterday := (day + 6) % 7
+	switch {
+	case start == end:
+		return w.startsOn(day)
+	case start < end:
+		return w.startsOn(day) && minute >= start && minute < end
+	default:
+		return (w.startsOn(day) && minute >= start) || (w.startsOn(yesterday) && minute < end)
+	}
+}
+
+// InteractiveReserve returns the largest interactive reserve of the sharing
+// windows containing t, and that window; nil if none does
+func (c *Catalog) InteractiveReserve(t time.Time) (float64, *SharingWindow) {
+	if c == nil {
+		return 0, nil
+	}
+	var reserve float64
+	var window *SharingWindow
+	for _, w := range c.GPUSharing {
+		if w.Contains(t) && (window == nil || w.InteractiveReserve > reserve) {
+			reserve, window = w.InteractiveReserve, w
+		}
+	}
+	return reserve, window
+}
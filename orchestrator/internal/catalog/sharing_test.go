@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// at returns a time on 2024-06-03, a Monday, plus days
+func at(days, hour, minute int) time.Time {
+	return time.Date(2024, 6, 3+days, hour, minute, 0, 0, time.Local)
+}
+
+func TestSharingWindow_Contains(t *testing.T) {
+	office := &SharingWindow{Days: []string{"mon", "Tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:30"}
+	assert.True(t, office.Contains(at(0, 8, 0)))
+	assert.True(t, office.Contains(at(4, 18, 29)))
+	assert.False(t, office.Contains(at(0, 18, 30)))
+	assert.False(t, office.Contains(at(0, 7, 59)))
+	assert.False(t, office.Contains(at(5, 12, 0)), "saturday")
+
+	// Evenings run past midnight into the next day
+	evening := &SharingWindow{Days: []string{"fri"}, Start: "19:00", End: "01:00"}
+	assert.True(t, evening.Contains(at(4, 23, 0)))
+	assert.True(t, evening.Contains(at(5, 0, 30)))
+	assert.False(t, evening.Contains(at(5, 19, 30)))
+	assert.False(t, evening.Contains(at(4, 0, 30)))
+
+	allDay := &SharingWindow{Days: []string{"sun"}, Start: "00:00", End: "00:00"}
+	assert.True(t, allDay.Contains(at(6, 13, 0)))
+	assert.False(t, allDay.Contains(at(0, 13, 0)))
+}
+
+func TestCatalog_InteractiveReserve(t *testing.T) {
+	path := writeCatalog(t, `{"gpu_sharing": [
+		{"name": "daytime", "start": "08:00", "end": "22:00", "interactive_reserve": 0.25},
+		{"name": "office", "days": ["mon", "tue", "wed", "thu", "fri"], "start": "09:00", "end": "17:00", "interactive_reserve": 0.5}
+	]}`)
+	c, err := LoadFile(path)
+	require.NoError(t, err)
+
+	reserve, window := c.InteractiveReserve(at(0, 10, 0))
+	assert.Equal(t, 0.5, reserve)
+	assert.Equal(t, "office", window.Name)
+
+	reserve, window = c.InteractiveReserve(at(5, 10, 0))
+	assert.Equal(t, 0.25, reserve)
+	assert.Equal(t, "daytime", window.Name)
+
+	// Batch jobs get the whole GPU at night
+	reserve, window = c.InteractiveReserve(at(0, 23, 0))
+	assert.Zero(t, reserve)
+	assert.Nil(t, window)
+
+	var none *Catalog
+	_, window = none.InteractiveReserve(at(0, 10, 0))
+	assert.Nil(t, window)
+
+	for name, content := range map[string]string{
+		"reserve too large": `{"gpu_sharing": [{"start": "08:00", "end": "18:00", "interactive_reserve": 1}]}`,
+		"invalid time":      `{"gpu_sharing": [{"start": "8am", "end": "18:00", "interactive_reserve": 0.5}]}`,
+		"invalid day":       `{"gpu_sharing": [{"days": ["monday"], "start": "08:00", "end": "18:00"}]}`,
+		"empty window":      `{"gpu_sharing": [null]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFile(writeCatalog(t, content))
+			assert.Error(t, err)
+		})
+	}
+}
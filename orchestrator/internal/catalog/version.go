@@ -0,0 +1,62 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a dotted numeric engine version, e.g. 0.6.3
+type Version []int
+
+// ParseVersion parses an engine version such as "0.6.3", "v0.3.12" or
+// "0.6.4.post1". Anything after the leading numeric components (pre-release,
+// post-release or build suffixes) is ignored.
+func ParseVersion(s string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	var v Version
+	for _, part := range strings.Split(trimmed, ".") {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, err := strconv.Atoi(part[:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		v = append(v, n)
+		if end < len(part) {
+			break
+		}
+	}
+
+	if len(v) == 0 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 if v is older than, equal to or newer than other.
+// Missing components count as 0, so 0.6 equals 0.6.0.
+func (v Version) Compare(other Version) int {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "0.6.3", want: Version{0, 6, 3}},
+		{input: "v0.3.12", want: Version{0, 3, 12}},
+		{input: "0.6.4.post1", want: Version{0, 6, 4}},
+		{input: "0.6.4.dev12+g1234abc", want: Version{0, 6, 4}},
+		{input: "0.5.0rc1", want: Version{0, 5, 0}},
+		{input: "1", want: Version{1}},
+		{input: "latest", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersion(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "0.6.3", b: "0.6.3", want: 0},
+		{a: "0.6", b: "0.6.0", want: 0},
+		{a: "0.6.2", b: "0.6.3", want: -1},
+		{a: "0.10.0", b: "0.9.9", want: 1},
+		{a: "1.0", b: "0.99.99", want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, err := ParseVersion(tt.a)
+			require.NoError(t, err)
+			b, err := ParseVersion(tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, a.Compare(b))
+		})
+	}
+}
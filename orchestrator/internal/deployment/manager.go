@@ -0,0 +1,314 @@
+package deployment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+)
+
+// RayPort is the port the Ray head of a deployment listens on
+const RayPort = 6379
+
+var (
+	// ErrInsufficientNodes is returned when too few free GPU nodes are registered
+	ErrInsufficientNodes = errors.New("not enough free GPU nodes")
+	// ErrNotDeployed is returned when a model has no distributed deployment
+	ErrNotDeployed = errors.New("model is not deployed")
+	// ErrNoDialer is returned when the manager cannot reach node agents
+	ErrNoDialer = errors.New("no node agent dialer configured")
+)
+
+// Dialer provides NodeAgent clients for nodes
+type Dialer interface {
+	NodeClient(n *pb.Node) (pb.NodeAgentClient, error)
+}
+
+// Deployment is a model served by a vLLM deployment spanning several nodes.
+// The head node serves the model endpoint; workers contribute their GPUs.
+type Deployment struct {
+	ID        string    `json:"id"`
+	Model     string    `json:"model"`
+	Head      string    `json:"head"`
+	Workers   []string  `json:"workers"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Nodes returns the IDs of all nodes in the deployment, head first
+func (d *Deployment) Nodes() []string {
+	return append([]string{d.Head}, d.Workers...)
+}
+
+// Manager reserves GPU nodes for multi-node model deployments and routes the
+// deployed models to their head node. It implements scheduler.Filter so that
+// reserved nodes only receive traffic for the model they serve.
+type Manager struct {
+	registry node.Registry
+	dialer   Dialer
+	tailnet  bool // Nodes reach each other at their tailnet address
+
+	mu          sync.RWMutex
+	deployments map[string]*Deployment // model -> deployment
+	reserved    map[string]string      // node ID -> model
+}
+
+// NewManager creates a new deployment manager
+func NewManager(registry node.Registry) *Manager {
+	return &Manager{
+		registry:    registry,
+		deployments: make(map[string]*Deployment),
+		reserved:    make(map[string]string),
+	}
+}
+
+// SetDialer sets how the manager connects to node agents
+func (m *Manager) SetDialer(dialer Dialer) {
+	m.dialer = dialer
+}
+
+// SetPreferTailnet has workers join the Ray cluster at the head node's
+// tailnet address, if it reported one
+func (m *Manager) SetPreferTailnet(prefer bool) {
+	m.tailnet = prefer
+}
+
+// Deploy reserves nodes for a model and launches a multi-node vLLM deployment
+// on them. If the model is already deployed, the existing deployment is returned.
+func (m *Manager) Deploy(ctx context.Context, model string, cfg *catalog.Distributed) (*Deployment, error) {
+	if m.dialer == nil {
+		return nil, ErrNoDialer
+	}
+
+	m.mu.Lock()
+	if d, ok := m.deployments[model]; ok {
+		m.mu.Unlock()
+		return d, nil
+	}
+
+	for _, reservedFor := range m.reserved {
+		if reservedFor == model {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("deployment of %s is already in progress", model)
+		}
+	}
+
+	nodes := m.freeGPUNodesLocked()
+	if len(nodes) < cfg.Nodes {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: model %s needs %d, found %d", ErrInsufficientNodes, model, cfg.Nodes, len(nodes))
+	}
+	nodes = nodes[:cfg.Nodes]
+
+	d := &Deployment{
+		ID:        newDeploymentID(),
+		Model:     model,
+		Head:      nodes[0].Id,
+		CreatedAt: time.Now(),
+	}
+	for _, n := range nodes[1:] {
+		d.Workers = append(d.Workers, n.Id)
+	}
+
+	// Reserve the nodes before launching so concurrent deployments can't claim them
+	for _, n := range nodes {
+		m.reserved[n.Id] = model
+	}
+	m.mu.Unlock()
+
+	if err := m.launch(ctx, d, nodes, cfg); err != nil {
+		m.release(d)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.deployments[model] = d
+	m.mu.Unlock()
+
+	return d, nil
+}
+
+// launch starts the head first so that workers have a Ray cluster to join.
+// Nodes already started are stopped again if a later node fails.
+func (m *Manager) launch(ctx context.Context, d *Deployment, nodes []*pb.Node, cfg *catalog.Distributed) error {
+	gpusPerNode := cfg.GPUsPerNode
+	if gpusPerNode <= 0 {
+		gpusPerNode = 1
+	}
+
+	headAddress := net.JoinHostPort(nodeHost(nodes[0], m.tailnet), fmt.Sprintf("%d", RayPort))
+
+	var started []*pb.Node
+	for i, n := range nodes {
+		req := &pb.StartDistributedRequest{
+			DeploymentId:         d.ID,
+			Model:                d.Model,
+			Role:                 pb.DistributedRole_DISTRIBUTED_ROLE_WORKER,
+			HeadAddress:          headAddress,
+			TensorParallelSize:   int32(gpusPerNode),
+			PipelineParallelSize: int32(len(nodes)),
+		}
+		if i == 0 {
+			req.Role = pb.DistributedRole_DISTRIBUTED_ROLE_HEAD
+		}
+
+		client, err := m.dialer.NodeClient(n)
+		if err == nil {
+			_, err = client.StartDistributed(ctx, req)
+		}
+		if err != nil {
+			m.stop(context.Background(), d.ID, started)
+			return fmt.Errorf("failed to start deployment of %s on node %s: %w", d.Model, n.Id, err)
+		}
+		started = append(started, n)
+	}
+
+	return nil
+}
+
+// Remove stops a model's deployment and releases its nodes
+func (m *Manager) Remove(ctx context.Context, model string) error {
+	m.mu.Lock()
+	d, ok := m.deployments[model]
+	if ok {
+		delete(m.deployments, model)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrNotDeployed
+	}
+
+	var nodes []*pb.Node
+	for _, id := range d.Nodes() {
+		if n, ok := m.registry.Get(id); ok {
+			nodes = append(nodes, n)
+		}
+	}
+	err := m.stop(ctx, d.ID, nodes)
+	m.release(d)
+	return err
+}
+
+// stop asks the given nodes to tear down their part of a deployment
+func (m *Manager) stop(ctx context.Context, deploymentID string, nodes []*pb.Node) error {
+	if m.dialer == nil {
+		return ErrNoDialer
+	}
+
+	var lastErr error
+	for _, n := range nodes {
+		client, err := m.dialer.NodeClient(n)
+		if err == nil {
+			_, err = client.StopDistributed(ctx, &pb.StopDistributedRequest{DeploymentId: deploymentID})
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("failed to stop deployment %s on node %s: %w", deploymentID, n.Id, err)
+		}
+	}
+	return lastErr
+}
+
+// release frees the nodes reserved for a deployment
+func (m *Manager) release(d *Deployment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range d.Nodes() {
+		if m.reserved[id] == d.Model {
+			delete(m.reserved, id)
+		}
+	}
+}
+
+// Get returns the deployment of a model
+func (m *Manager) Get(model string) (*Deployment, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	d, ok := m.deployments[model]
+	return d, ok
+}
+
+// List returns all deployments ordered by model name
+func (m *Manager) List() []*Deployment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deployments := make([]*Deployment, 0, len(m.deployments))
+	for _, d := range m.deployments {
+		deployments = append(deployments, d)
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Model < deployments[j].Model
+	})
+	return deployments
+}
+
+// Filter routes deployed models to their head node and keeps reserved nodes
+// out of scheduling for every other model
+func (m *Manager) Filter(req *scheduler.Request, nodes []*pb.Node) []*pb.Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if d, ok := m.deployments[req.Model]; ok {
+		for _, n := range nodes {
+			if n.Id == d.Head {
+				return []*pb.Node{n}
+			}
+		}
+		return nil
+	}
+
+	filtered := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if _, reserved := m.reserved[n.Id]; !reserved {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// freeGPUNodesLocked returns unreserved CUDA nodes ordered by ID.
+// Callers must hold m.mu.
+func (m *Manager) freeGPUNodesLocked() []*pb.Node {
+	var nodes []*pb.Node
+	for _, n := range m.registry.List() {
+		if _, reserved := m.reserved[n.Id]; reserved {
+			continue
+		}
+		// vLLM multi-node deployments run on NVIDIA GPUs
+		if n.Capabilities.GetGpuBackend() != pb.GpuBackend_GPU_BACKEND_CUDA {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Id < nodes[j].Id
+	})
+	return nodes
+}
+
+// nodeHost returns the host other nodes use to reach a node
+func nodeHost(n *pb.Node, preferTailnet bool) string {
+	if host, _, err := net.SplitHostPort(node.DialAddress(n, preferTailnet)); err == nil && host != "" {
+		return host
+	}
+	return n.Hostname
+}
+
+// newDeploymentID generates a random deployment ID
+func newDeploymentID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "dep-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,173 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+)
+
+// MockNodeAgentClient records distributed deployment calls made to one node
+type MockNodeAgentClient struct {
+	pb.NodeAgentClient
+	nodeID   string
+	started  []*pb.StartDistributedRequest
+	stopped  []string
+	startErr error
+}
+
+func (c *MockNodeAgentClient) StartDistributed(ctx context.Context, in *pb.StartDistributedRequest, opts ...grpc.CallOption) (*pb.StartDistributedResponse, error) {
+	if c.startErr != nil {
+		return nil, c.startErr
+	}
+	c.started = append(c.started, in)
+	return &pb.StartDistributedResponse{}, nil
+}
+
+func (c *MockNodeAgentClient) StopDistributed(ctx context.Context, in *pb.StopDistributedRequest, opts ...grpc.CallOption) (*pb.StopDistributedResponse, error) {
+	c.stopped = append(c.stopped, in.DeploymentId)
+	return &pb.StopDistributedResponse{}, nil
+}
+
+// MockDialer hands out one mock client per node
+type MockDialer struct {
+	clients map[string]*MockNodeAgentClient
+}
+
+func NewMockDialer() *MockDialer {
+	return &MockDialer{clients: make(map[string]*MockNodeAgentClient)}
+}
+
+func (d *MockDialer) NodeClient(n *pb.Node) (pb.NodeAgentClient, error) {
+	return d.client(n.Id), nil
+}
+
+func (d *MockDialer) client(nodeID string) *MockNodeAgentClient {
+	if _, ok := d.clients[nodeID]; !ok {
+		d.clients[nodeID] = &MockNodeAgentClient{nodeID: nodeID}
+	}
+	return d.clients[nodeID]
+}
+
+func newTestRegistry(t *testing.T, nodes ...*pb.Node) node.Registry {
+	registry := node.NewInMemoryRegistry()
+	for _, n := range nodes {
+		require.NoError(t, registry.Register(n))
+	}
+	return registry
+}
+
+func gpuNode(id string) *pb.Node {
+	return &pb.Node{
+		Id:           id,
+		Hostname:     id,
+		AgentAddress: id + ".lan:50052",
+		Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CUDA},
+	}
+}
+
+func TestManager_Deploy(t *testing.T) {
+	cpu := &pb.Node{Id: "cpu", Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CPU}}
+	registry := newTestRegistry(t, gpuNode("gpu-b"), gpuNode("gpu-a"), gpuNode("gpu-c"), cpu)
+	dialer := NewMockDialer()
+	manager := NewManager(registry)
+	manager.SetDialer(dialer)
+
+	d, err := manager.Deploy(context.Background(), "llama3:70b", &catalog.Distributed{Nodes: 2, GPUsPerNode: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-a", d.Head)
+	assert.Equal(t, []string{"gpu-b"}, d.Workers)
+
+	head := dialer.client("gpu-a").started
+	require.Len(t, head, 1)
+	assert.Equal(t, pb.DistributedRole_DISTRIBUTED_ROLE_HEAD, head[0].Role)
+	assert.Equal(t, int32(2), head[0].TensorParallelSize)
+	assert.Equal(t, int32(2), head[0].PipelineParallelSize)
+
+	worker := dialer.client("gpu-b").started
+	require.Len(t, worker, 1)
+	assert.Equal(t, pb.DistributedRole_DISTRIBUTED_ROLE_WORKER, worker[0].Role)
+	assert.Equal(t, "gpu-a.lan:6379", worker[0].HeadAddress)
+	assert.Equal(t, d.ID, worker[0].DeploymentId)
+
+	// Deploying again returns the existing deployment
+	again, err := manager.Deploy(context.Background(), "llama3:70b", &catalog.Distributed{Nodes: 2})
+	require.NoError(t, err)
+	assert.Same(t, d, again)
+
+	// Only one free GPU node is left
+	_, err = manager.Deploy(context.Background(), "qwen:110b", &catalog.Distributed{Nodes: 2})
+	assert.True(t, errors.Is(err, ErrInsufficientNodes))
+
+	require.NoError(t, manager.Remove(context.Background(), "llama3:70b"))
+	assert.Equal(t, []string{d.ID}, dialer.client("gpu-a").stopped)
+	assert.Equal(t, []string{d.ID}, dialer.client("gpu-b").stopped)
+	assert.Empty(t, manager.List())
+	assert.True(t, errors.Is(manager.Remove(context.Background(), "llama3:70b"), ErrNotDeployed))
+
+	// Released nodes can be reserved again
+	_, err = manager.Deploy(context.Background(), "qwen:110b", &catalog.Distributed{Nodes: 3})
+	assert.NoError(t, err)
+}
+
+func TestManager_DeployOverTailnet(t *testing.T) {
+	head := gpuNode("gpu-a")
+	head.TailnetAddress = "100.101.102.103:50052"
+	registry := newTestRegistry(t, head, gpuNode("gpu-b"))
+	dialer := NewMockDialer()
+	manager := NewManager(registry)
+	manager.SetDialer(dialer)
+	manager.SetPreferTailnet(true)
+
+	_, err := manager.Deploy(context.Background(), "llama3:70b", &catalog.Distributed{Nodes: 2})
+	require.NoError(t, err)
+
+	worker := dialer.client("gpu-b").started
+	require.Len(t, worker, 1)
+	assert.Equal(t, "100.101.102.103:6379", worker[0].HeadAddress)
+}
+
+func TestManager_DeployRollback(t *testing.T) {
+	registry := newTestRegistry(t, gpuNode("gpu-a"), gpuNode("gpu-b"))
+	dialer := NewMockDialer()
+	dialer.client("gpu-b").startErr = errors.New("no GPU memory")
+	manager := NewManager(registry)
+	manager.SetDialer(dialer)
+
+	_, err := manager.Deploy(context.Background(), "llama3:70b", &catalog.Distributed{Nodes: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gpu-b")
+
+	// The head was stopped again and both nodes were released
+	assert.Len(t, dialer.client("gpu-a").stopped, 1)
+	assert.Empty(t, manager.reserved)
+}
+
+func TestManager_Filter(t *testing.T) {
+	registry := newTestRegistry(t, gpuNode("gpu-a"), gpuNode("gpu-b"), gpuNode("gpu-c"))
+	manager := NewManager(registry)
+	manager.SetDialer(NewMockDialer())
+
+	_, err := manager.Deploy(context.Background(), "llama3:70b", &catalog.Distributed{Nodes: 2})
+	require.NoError(t, err)
+
+	ids := func(nodes []*pb.Node) []string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, n.Id)
+		}
+		return out
+	}
+
+	nodes := registry.List()
+	assert.Equal(t, []string{"gpu-a"}, ids(manager.Filter(&scheduler.Request{Model: "llama3:70b"}, nodes)))
+	assert.Equal(t, []string{"gpu-c"}, ids(manager.Filter(&scheduler.Request{Model: "llama3"}, nodes)))
+}
@@ -0,0 +1,95 @@
+// Package errcode attaches Orchion error codes to gRPC status errors so that
+// failure classes survive the hop from node agent to orchestrator to gateway.
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// New creates a gRPC status error carrying the given error code in its
+// details, followed by any further details such as field violations
+func New(c codes.Code, code pb.ErrorCode, msg string, details ...protoadapt.MessageV1) error {
+	st := status.New(c, msg)
+	details = append([]protoadapt.MessageV1{&pb.ErrorInfo{Code: code}}, details...)
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// Errorf is like New but formats the message
+func Errorf(c codes.Code, code pb.ErrorCode, format string, args ...interface{}) error {
+	return New(c, code, fmt.Sprintf(format, args...))
+}
+
+// Has reports whether err is a gRPC status error that already carries an error code
+func Has(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*pb.ErrorInfo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRetryable reports whether a node marked err as safe to retry on another node
+func IsRetryable(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*pb.ErrorInfo); ok {
+			return info.Retryable
+		}
+	}
+	return false
+}
+
+// FromError returns the error code carried by err. Errors without an explicit
+// code are classified from their gRPC status code.
+func FromError(err error) pb.ErrorCode {
+	if err == nil {
+		return pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return pb.ErrorCode_ERROR_CODE_INTERNAL
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*pb.ErrorInfo); ok {
+			return info.Code
+		}
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return pb.ErrorCode_ERROR_CODE_INVALID_REQUEST
+	case codes.Unauthenticated:
+		return pb.ErrorCode_ERROR_CODE_AUTH_FAILED
+	case codes.PermissionDenied:
+		return pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED
+	case codes.Unavailable:
+		return pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE
+	case codes.DeadlineExceeded:
+		return pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT
+	default:
+		return pb.ErrorCode_ERROR_CODE_INTERNAL
+	}
+}
@@ -0,0 +1,74 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestNew(t *testing.T) {
+	err := New(codes.NotFound, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no such model")
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "no such model", st.Message())
+	assert.True(t, Has(err))
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, FromError(err))
+}
+
+func TestErrorf(t *testing.T) {
+	err := Errorf(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "node %s is down", "gpu-1")
+
+	st, _ := status.FromError(err)
+	assert.Equal(t, "node gpu-1 is down", st.Message())
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, FromError(err))
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want pb.ErrorCode
+	}{
+		{"nil", nil, pb.ErrorCode_ERROR_CODE_UNSPECIFIED},
+		{"plain error", errors.New("boom"), pb.ErrorCode_ERROR_CODE_INTERNAL},
+		{"deadline", fmt.Errorf("call: %w", context.DeadlineExceeded), pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad"), pb.ErrorCode_ERROR_CODE_INVALID_REQUEST},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "who"), pb.ErrorCode_ERROR_CODE_AUTH_FAILED},
+		{"permission denied", status.Error(codes.PermissionDenied, "no"), pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED},
+		{"unavailable", status.Error(codes.Unavailable, "down"), pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE},
+		{"deadline status", status.Error(codes.DeadlineExceeded, "slow"), pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT},
+		{"other status", status.Error(codes.Internal, "oops"), pb.ErrorCode_ERROR_CODE_INTERNAL},
+		{"explicit detail wins", New(codes.Internal, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, "oom"), pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FromError(tt.err))
+		})
+	}
+
+	assert.False(t, Has(status.Error(codes.Internal, "oops")))
+	assert.False(t, Has(errors.New("boom")))
+}
+
+func TestIsRetryable(t *testing.T) {
+	retryable := status.New(codes.ResourceExhausted, "not enough free VRAM")
+	retryable, err := retryable.WithDetails(&pb.ErrorInfo{Code: pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, Retryable: true})
+	require.NoError(t, err)
+
+	assert.True(t, IsRetryable(retryable.Err()))
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, FromError(retryable.Err()))
+	assert.False(t, IsRetryable(New(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, "CUDA out of memory")))
+	assert.False(t, IsRetryable(status.Error(codes.Unavailable, "down")))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
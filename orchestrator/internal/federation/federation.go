@@ -0,0 +1,295 @@
+// Package federation lets an orchestrator send gateway requests to other
+// Orchion clusters, e.g. a home cluster borrowing the office's GPUs. Each
+// site's gateway is polled for the models it serves, and requests for those
+// models go to it when the local cluster has no capacity for them, or first
+// if the site is preferred.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSyncInterval is how often sites are polled for their models
+const DefaultSyncInterval = 30 * time.Second
+
+// syncTimeout bounds polling one site
+const syncTimeout = 10 * time.Second
+
+// Policy is when requests go to a site
+type Policy string
+
+const (
+	// PolicyOverflow sends requests to the site only when no local node can
+	// take them
+	PolicyOverflow Policy = "overflow"
+	// PolicyPrefer sends requests to the site first, falling back to local
+	// nodes when the site can't take them
+	PolicyPrefer Policy = "prefer"
+)
+
+// Config is a cluster's federation settings
+type Config struct {
+	// Site names this cluster to the sites it sends requests to
+	Site string `json:"site"`
+
+	// Sites are the other clusters, tried in order
+	Sites []*Site `json:"sites"`
+}
+
+// Site is another Orchion cluster
+type Site struct {
+	Name string `json:"name"`
+
+	// URL is the site's gateway, e.g. https://office.example.com:8080
+	URL string `json:"url"`
+
+	// APIKey authenticates with the site's gateway, if it requires a key
+	APIKey string `json:"api_key,omitempty"`
+
+	// Policy is when requests go to the site; overflow if empty
+	Policy Policy `json:"policy,omitempty"`
+
+	// Models are model patterns in path.Match syntax that may be sent to
+	// the site, e.g. "llama3*". Without patterns, any model the site serves
+	// may.
+	Models []string `json:"models,omitempty"`
+}
+
+// LoadFile reads federation settings from a JSON file
+func LoadFile(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read federation config: %w", err)
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse federation config %s: %w", filename, err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid federation config %s: %w", filename, err)
+	}
+
+	return c, nil
+}
+
+// Validate checks the settings for invalid values
+func (c *Config) Validate() error {
+	if strings.TrimSpace(c.Site) == "" {
+		return fmt.Errorf("site must name this cluster")
+	}
+	names := map[string]bool{c.Site: true}
+	for i, site := range c.Sites {
+		if site == nil || site.Name == "" {
+			return fmt.Errorf("site %d has no name", i)
+		}
+		if names[site.Name] {
+			return fmt.Errorf("site %q is defined twice", site.Name)
+		}
+		names[site.Name] = true
+
+		u, err := url.Parse(site.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("site %q: url must be an http or https URL, got %q", site.Name, site.URL)
+		}
+		switch site.Policy {
+		case "":
+			site.Policy = PolicyOverflow
+		case PolicyOverflow, PolicyPrefer:
+		default:
+			return fmt.Errorf("site %q: unknown policy %q (want overflow or prefer)", site.Name, site.Policy)
+		}
+		for _, pattern := range site.Models {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("site %q: invalid pattern %q", site.Name, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether requests for model may be sent to the site
+func (s *Site) allows(model string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, pattern := range s.Models {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// siteState is what the last poll of a site found
+type siteState struct {
+	models map[string]bool
+	synced time.Time // Last successful poll
+	err    string    // Error of the last poll, if it failed
+}
+
+// SiteStatus is a site's settings and the models it last reported
+type SiteStatus struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Policy     Policy   `json:"policy"`
+	Models     []string `json:"models"`
+	SyncedUnix int64    `json:"synced_unix,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Federation tracks the models other sites serve and forwards requests to
+// them
+type Federation struct {
+	config *Config
+	client *http.Client
+
+	mu    sync.RWMutex
+	state map[string]*siteState
+}
+
+// New creates a federation of this cluster with the configured sites
+func New(config *Config) *Federation {
+	return &Federation{
+		config: config,
+		client: &http.Client{},
+		state:  make(map[string]*siteState, len(config.Sites)),
+	}
+}
+
+// SetClient sets the HTTP client sites are polled and forwarded to with
+func (f *Federation) SetClient(client *http.Client) {
+	f.client = client
+}
+
+// Site returns this cluster's name
+func (f *Federation) Site() string {
+	return f.config.Site
+}
+
+// Start polls the sites for their models every interval until ctx is done
+func (f *Federation) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			f.Sync(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Sync polls every site for its models. A site that can't be polled keeps
+// the models it last reported, so a blip doesn't stop overflow; forwarding
+// to it fails over to the next site anyway.
+func (f *Federation) Sync(ctx context.Context) {
+	for _, site := range f.config.Sites {
+		models, err := f.fetchModels(ctx, site)
+
+		f.mu.Lock()
+		state := f.state[site.Name]
+		if state == nil {
+			state = &siteState{}
+			f.state[site.Name] = state
+		}
+		if err != nil {
+			state.err = err.Error()
+		} else {
+			state.models, state.synced, state.err = models, time.Now(), ""
+		}
+		f.mu.Unlock()
+	}
+}
+
+// fetchModels asks a site's gateway for the models it serves
+func (f *Federation) fetchModels(ctx context.Context, site *Site) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(site.URL, "/")+"/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if site.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+site.APIKey)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing models returned status %d", resp.StatusCode)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid model list: %w", err)
+	}
+	models := make(map[string]bool, len(list.Data))
+	for _, model := range list.Data {
+		models[model.ID] = true
+	}
+	return models, nil
+}
+
+// Sites returns the sites with the given policy that serve model and may be
+// sent requests for it, in the configured order
+func (f *Federation) Sites(model string, policy Policy) []*Site {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var sites []*Site
+	for _, site := range f.config.Sites {
+		if site.Policy != policy || !site.allows(model) {
+			continue
+		}
+		if state := f.state[site.Name]; state != nil && state.models[model] {
+			sites = append(sites, site)
+		}
+	}
+	return sites
+}
+
+// Status returns every site's settings and the models it last reported
+func (f *Federation) Status() []SiteStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	statuses := make([]SiteStatus, 0, len(f.config.Sites))
+	for _, site := range f.config.Sites {
+		status := SiteStatus{Name: site.Name, URL: site.URL, Policy: site.Policy, Models: []string{}}
+		if state := f.state[site.Name]; state != nil {
+			for model := range state.models {
+				status.Models = append(status.Models, model)
+			}
+			sort.Strings(status.Models)
+			if !state.synced.IsZero() {
+				status.SyncedUnix = state.synced.Unix()
+			}
+			status.Error = state.err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
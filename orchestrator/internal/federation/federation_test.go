@@ -0,0 +1,182 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"site": "home",
+		"sites": [
+			{"name": "office", "url": "https://office.example.com:8080", "api_key": "k", "models": ["llama3*"]},
+			{"name": "lab", "url": "http://lab:8080", "policy": "prefer"}
+		]
+	}`), 0o600))
+
+	c, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, c.Sites, 2)
+	assert.Equal(t, PolicyOverflow, c.Sites[0].Policy, "overflow is the default")
+	assert.Equal(t, PolicyPrefer, c.Sites[1].Policy)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	site := func(mutate func(s *Site)) *Config {
+		s := &Site{Name: "office", URL: "https://office.example.com"}
+		mutate(s)
+		return &Config{Site: "home", Sites: []*Site{s}}
+	}
+
+	assert.NoError(t, site(func(s *Site) {}).Validate())
+	assert.Error(t, (&Config{}).Validate(), "this cluster needs a name")
+	assert.Error(t, site(func(s *Site) { s.Name = "" }).Validate())
+	assert.Error(t, site(func(s *Site) { s.Name = "home" }).Validate(), "a site can't share this cluster's name")
+	assert.Error(t, site(func(s *Site) { s.URL = "office.example.com:8080" }).Validate())
+	assert.Error(t, site(func(s *Site) { s.Policy = "always" }).Validate())
+	assert.Error(t, site(func(s *Site) { s.Models = []string{"[llama"} }).Validate())
+
+	twice := &Config{Site: "home", Sites: []*Site{
+		{Name: "office", URL: "https://a.example.com"},
+		{Name: "office", URL: "https://b.example.com"},
+	}}
+	assert.Error(t, twice.Validate())
+}
+
+// modelsServer serves a gateway's model list
+func modelsServer(t *testing.T, models ...string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		data := make([]map[string]string, len(models))
+		for i, model := range models {
+			data[i] = map[string]string{"id": model, "object": "model"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFederation_Sites(t *testing.T) {
+	office := modelsServer(t, "llama3:8b", "mistral")
+	lab := modelsServer(t, "llama3:8b")
+	f := New(&Config{Site: "home", Sites: []*Site{
+		{Name: "office", URL: office.URL, APIKey: "key", Policy: PolicyOverflow, Models: []string{"llama3*"}},
+		{Name: "lab", URL: lab.URL, APIKey: "key", Policy: PolicyOverflow},
+		{Name: "cloud", URL: lab.URL, APIKey: "key", Policy: PolicyPrefer},
+	}})
+	assert.Empty(t, f.Sites("llama3:8b", PolicyOverflow), "nothing is known before syncing")
+
+	f.Sync(context.Background())
+
+	names := func(sites []*Site) []string {
+		var names []string
+		for _, site := range sites {
+			names = append(names, site.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"office", "lab"}, names(f.Sites("llama3:8b", PolicyOverflow)))
+	assert.Equal(t, []string{"cloud"}, names(f.Sites("llama3:8b", PolicyPrefer)))
+	// The office serves mistral, but only llama3 models may be sent there
+	assert.Empty(t, f.Sites("mistral", PolicyOverflow))
+	assert.Empty(t, f.Sites("phi3", PolicyOverflow))
+}
+
+func TestFederation_SyncFailureKeepsModels(t *testing.T) {
+	office := modelsServer(t, "llama3")
+	f := New(&Config{Site: "home", Sites: []*Site{{Name: "office", URL: office.URL, APIKey: "key", Policy: PolicyOverflow}}})
+	f.Sync(context.Background())
+	synced := f.Status()[0].SyncedUnix
+	require.NotZero(t, synced)
+
+	office.Close()
+	f.Sync(context.Background())
+
+	status := f.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, []string{"llama3"}, status[0].Models)
+	assert.Equal(t, synced, status[0].SyncedUnix)
+	assert.NotEmpty(t, status[0].Error)
+	assert.Len(t, f.Sites("llama3", PolicyOverflow), 1)
+}
+
+func TestFederation_Status(t *testing.T) {
+	f := New(&Config{Site: "home", Sites: []*Site{{Name: "office", URL: "http://office", Policy: PolicyPrefer}}})
+	assert.Equal(t, []SiteStatus{{Name: "office", URL: "http://office", Policy: PolicyPrefer, Models: []string{}}}, f.Status())
+	assert.Equal(t, "home", f.Site())
+}
+
+func TestFederation_Forward(t *testing.T) {
+	status := http.StatusOK
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"model":"llama3"}`, string(body))
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer site-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "home", r.Header.Get(ForwardedHeader))
+		assert.Equal(t, "prefix-1", r.Header.Get("X-Prompt-Cache-Key"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Trailer", "X-Orchion-Served-By")
+		w.WriteHeader(status)
+		io.WriteString(w, "data: {}\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: [DONE]\n\n")
+		w.Header().Set("X-Orchion-Served-By", "gpu-1")
+	}))
+	defer site.Close()
+
+	f := New(&Config{Site: "home"})
+	office := &Site{Name: "office", URL: site.URL, APIKey: "site-key"}
+	forward := func() (*httptest.ResponseRecorder, int, error) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer client-key")
+		req.Header.Set("X-Prompt-Cache-Key", "prefix-1")
+		rec := httptest.NewRecorder()
+		code, err := f.Forward(rec, req, office, []byte(`{"model":"llama3"}`))
+		return rec, code, err
+	}
+
+	t.Run("streams the reply", func(t *testing.T) {
+		rec, code, err := forward()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "data: {}\n\ndata: [DONE]\n\n", rec.Body.String())
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "office", rec.Header().Get(SiteHeader))
+		assert.True(t, rec.Flushed)
+		assert.Equal(t, "gpu-1", rec.Result().Trailer.Get("X-Orchion-Served-By"))
+	})
+
+	t.Run("client errors pass through", func(t *testing.T) {
+		status = http.StatusBadRequest
+		rec, code, err := forward()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("a site out of capacity writes nothing", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		rec, _, err := forward()
+		assert.Error(t, err)
+		assert.Zero(t, rec.Body.Len())
+		assert.Empty(t, rec.Header().Get(SiteHeader))
+	})
+}
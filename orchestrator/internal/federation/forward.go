@@ -0,0 +1,84 @@
+package federation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ForwardedHeader names the site a request was forwarded from. Requests
+// carrying it are served locally and never forwarded again, so two sites
+// overflowing to each other can't bounce a request between them.
+const ForwardedHeader = "X-Orchion-Federated-From"
+
+// SiteHeader names the site that served a forwarded request
+const SiteHeader = "X-Orchion-Site"
+
+// forwardedRequestHeaders are the client's headers passed on to the site.
+// Its credentials aren't: the site is called with the site's API key.
+var forwardedRequestHeaders = []string{"Content-Type", "Accept", "X-Prompt-Cache-Key", "X-Orchion-Status-Events"}
+
+// Forward sends a gateway request with the given body to a site and streams
+// the site's reply to w. If the site can't take the request, because it's
+// unreachable, rate limited or out of capacity itself, nothing is written
+// and an error is returned, so the caller can try elsewhere. Otherwise the
+// site's status code is returned, including client errors, which trying
+// elsewhere wouldn't fix.
+func (f *Federation) Forward(w http.ResponseWriter, r *http.Request, site *Site, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, strings.TrimSuffix(site.URL, "/")+r.URL.Path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range forwardedRequestHeaders {
+		if value := r.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if site.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+site.APIKey)
+	}
+	req.Header.Set(ForwardedHeader, f.config.Site)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("site %s: %w", site.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return 0, fmt.Errorf("site %s returned status %d", site.Name, resp.StatusCode)
+	}
+
+	for name, values := range resp.Header {
+		switch name {
+		case "Connection", "Transfer-Encoding", "Content-Length":
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set(SiteHeader, site.Name)
+	w.WriteHeader(resp.StatusCode)
+
+	// Flush as the site does, so streamed replies stay streamed
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				break
+			}
+			rc.Flush()
+		}
+		if err != nil {
+			break
+		}
+	}
+	// Speed reports of streamed replies arrive as trailers
+	for name, values := range resp.Trailer {
+		w.Header()[http.TrailerPrefix+name] = values
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,180 @@
+package files
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+)
+
+// DefaultMaxSize is the largest file accepted by default. Job payloads read
+// from files are forwarded to nodes, so it matches the default gRPC message
+// limit.
+const DefaultMaxSize = 16 << 20 // 16 MiB
+
+// IDPrefix starts every file ID, as in OpenAI's API
+const IDPrefix = "file-"
+
+// idBytes is the number of random bytes in a file ID
+const idBytes = 12
+
+// metadataSuffix is appended to a file's ID to store its description
+const metadataSuffix = ".json"
+
+// ErrNotFound is returned for files that don't exist
+var ErrNotFound = errors.New("file not found")
+
+// File describes an uploaded file in the form of OpenAI's file object
+type File struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"` // "file"
+	Bytes       int64  `json:"bytes"`
+	CreatedAt   int64  `json:"created_at"`
+	Filename    string `json:"filename"`
+	Purpose     string `json:"purpose"`                // Set by the uploader, e.g. "user_data"
+	ContentType string `json:"content_type,omitempty"` // Media type given at upload
+}
+
+// record is how a file's description is stored, along with its owner
+type record struct {
+	File
+	Owner string `json:"owner,omitempty"` // ID of the uploader's API key; empty without keys
+}
+
+// DeletedFile is the response to deleting a file, as in OpenAI's API
+type DeletedFile struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"` // "file"
+	Deleted bool   `json:"deleted"`
+}
+
+// Store keeps uploaded files and their descriptions in a results.Store, so
+// files live in a directory or an S3 bucket like offloaded job results. Each
+// file belongs to the API key that uploaded it, named by its key ID; files of
+// other keys are reported as not found.
+type Store struct {
+	blobs results.Store
+	now   func() time.Time
+}
+
+// NewStore creates a file store writing to blobs
+func NewStore(blobs results.Store) *Store {
+	return &Store{blobs: blobs, now: time.Now}
+}
+
+// Put stores data as a new file of owner and returns its description
+func (s *Store) Put(ctx context.Context, owner, filename, purpose, contentType string, data []byte) (*File, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		ID:          id,
+		Object:      "file",
+		Bytes:       int64(len(data)),
+		CreatedAt:   s.now().Unix(),
+		Filename:    filename,
+		Purpose:     purpose,
+		ContentType: contentType,
+	}
+	metadata, err := json.Marshal(record{File: *f, Owner: owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode file metadata: %w", err)
+	}
+
+	// The content goes first so a described file always has content
+	if err := s.blobs.Put(ctx, id, data); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	if err := s.blobs.Put(ctx, id+metadataSuffix, metadata); err != nil {
+		return nil, fmt.Errorf("failed to store file metadata: %w", err)
+	}
+	return f, nil
+}
+
+// Get returns the description of one of owner's files
+func (s *Store) Get(ctx context.Context, owner, id string) (*File, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+	data, err := results.Get(ctx, s.blobs, id+metadataSuffix)
+	if errors.Is(err, results.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file metadata: %w", err)
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+	if rec.Owner != owner {
+		return nil, ErrNotFound
+	}
+	return &rec.File, nil
+}
+
+// Open streams the content of one of owner's files
+func (s *Store) Open(ctx context.Context, owner, id string) (io.ReadCloser, error) {
+	if _, err := s.Get(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	r, err := s.blobs.Open(ctx, id)
+	if errors.Is(err, results.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+// Read returns the whole content of one of owner's files
+func (s *Store) Read(ctx context.Context, owner, id string) ([]byte, error) {
+	r, err := s.Open(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Delete removes one of owner's files
+func (s *Store) Delete(ctx context.Context, owner, id string) error {
+	if _, err := s.Get(ctx, owner, id); err != nil {
+		return err
+	}
+	// The description goes first so a file is never described without content
+	if err := s.blobs.Delete(ctx, id+metadataSuffix); err != nil && !errors.Is(err, results.ErrNotFound) {
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+	if err := s.blobs.Delete(ctx, id); err != nil && !errors.Is(err, results.ErrNotFound) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// ValidID reports whether id is a file ID as generated by Put, so other
+// names never reach the underlying store
+func ValidID(id string) bool {
+	suffix, ok := strings.CutPrefix(id, IDPrefix)
+	if !ok || len(suffix) != 2*idBytes {
+		return false
+	}
+	_, err := hex.DecodeString(suffix)
+	return err == nil
+}
+
+// newID generates a random file ID
+func newID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate file ID: %w", err)
+	}
+	return IDPrefix + hex.EncodeToString(b), nil
+}
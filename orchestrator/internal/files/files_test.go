@@ -0,0 +1,88 @@
+package files
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	blobs, err := results.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+	store := NewStore(blobs)
+	store.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	t.Run("round trip", func(t *testing.T) {
+		f, err := store.Put(ctx, "alice", "meeting.wav", "user_data", "audio/wav", []byte("RIFF"))
+		require.NoError(t, err)
+		assert.True(t, ValidID(f.ID), f.ID)
+		assert.Equal(t, &File{
+			ID: f.ID, Object: "file", Bytes: 4, CreatedAt: 1700000000,
+			Filename: "meeting.wav", Purpose: "user_data", ContentType: "audio/wav",
+		}, f)
+
+		got, err := store.Get(ctx, "alice", f.ID)
+		require.NoError(t, err)
+		assert.Equal(t, f, got)
+
+		data, err := store.Read(ctx, "alice", f.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("RIFF"), data)
+	})
+
+	t.Run("other owners' files", func(t *testing.T) {
+		f, err := store.Put(ctx, "alice", "a.txt", "user_data", "", []byte("secret"))
+		require.NoError(t, err)
+		for _, owner := range []string{"bob", ""} {
+			_, err = store.Get(ctx, owner, f.ID)
+			assert.ErrorIs(t, err, ErrNotFound, owner)
+			_, err = store.Open(ctx, owner, f.ID)
+			assert.ErrorIs(t, err, ErrNotFound, owner)
+			assert.ErrorIs(t, store.Delete(ctx, owner, f.ID), ErrNotFound, owner)
+		}
+		_, err = store.Get(ctx, "alice", f.ID)
+		assert.NoError(t, err, "still there")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		f, err := store.Put(ctx, "alice", "a.txt", "user_data", "", []byte("data"))
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(ctx, "alice", f.ID))
+
+		_, err = store.Get(ctx, "alice", f.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = blobs.Open(ctx, f.ID)
+		assert.ErrorIs(t, err, results.ErrNotFound, "content removed")
+		assert.ErrorIs(t, store.Delete(ctx, "alice", f.ID), ErrNotFound)
+	})
+
+	t.Run("IDs are unique", func(t *testing.T) {
+		a, err := store.Put(ctx, "", "a.txt", "user_data", "", nil)
+		require.NoError(t, err)
+		b, err := store.Put(ctx, "", "a.txt", "user_data", "", nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, a.ID, b.ID)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := store.Get(ctx, "", "file-000000000000000000000000")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.Open(ctx, "", "file-000000000000000000000000")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("invalid IDs", func(t *testing.T) {
+		require.NoError(t, blobs.Put(ctx, "job-1", []byte("result")))
+		for _, id := range []string{"", "job-1", "file-", "file-../job-1", "file-zz0000000000000000000000"} {
+			assert.False(t, ValidID(id), id)
+			_, err := store.Read(ctx, "", id)
+			assert.ErrorIs(t, err, ErrNotFound, id)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"sort"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// chunkObject is the object type of streamed chat completion chunks
+const chunkObject = "chat.completion.chunk"
+
+// deltaChunker rewrites a stream of chat completion responses into chunks
+// with OpenAI's delta semantics, for strict SDK parsers: a choice's role is
+// only sent in its first delta, later deltas carry just the new content or
+// tool call fragments, finish_reason is null until the last chunk, and that
+// chunk has an empty delta. Engines don't all stream that way; some repeat the
+// whole message with its role in every chunk, or finish in a chunk with
+// content.
+type deltaChunker struct {
+	started  map[int32]bool // Choices whose role was sent
+	finished map[int32]bool // Choices whose finish_reason was sent
+	last     *pb.ChatCompletionResponse
+}
+
+func newDeltaChunker() *deltaChunker {
+	return &deltaChunker{started: make(map[int32]bool), finished: make(map[int32]bool)}
+}
+
+// chunks converts a response into the chunks to send for it, none if it
+// carries nothing new
+func (c *deltaChunker) chunks(resp *pb.ChatCompletionResponse) []map[string]interface{} {
+	c.last = resp
+
+	var deltas, finishes []map[string]interface{}
+	for _, choice := range resp.Choices {
+		if c.finished[choice.Index] {
+			continue
+		}
+
+		delta := map[string]interface{}{}
+		if !c.started[choice.Index] {
+			c.started[choice.Index] = true
+			role := choice.GetMessage().GetRole()
+			if role == "" {
+				role = "assistant"
+			}
+			delta["role"] = role
+		}
+		if content := choice.GetMessage().GetContent(); content != "" {
+			delta["content"] = content
+		}
+		if calls := choice.GetMessage().GetToolCalls(); len(calls) > 0 {
+			delta["tool_calls"] = convertToolCalls(calls, true)
+		}
+		if len(delta) > 0 {
+			deltas = append(deltas, map[string]interface{}{
+				"index":         choice.Index,
+				"delta":         delta,
+				"finish_reason": nil,
+			})
+		}
+
+		if choice.FinishReason != "" {
+			c.finished[choice.Index] = true
+			finishes = append(finishes, finishChoice(choice.Index, choice.FinishReason))
+		}
+	}
+
+	var chunks []map[string]interface{}
+	if len(deltas) > 0 {
+		chunks = append(chunks, c.chunk(resp, deltas))
+	}
+	if len(finishes) > 0 {
+		chunks = append(chunks, c.chunk(resp, finishes))
+	}
+	// Usage goes with the last chunk of the response
+	if usage := convertUsage(resp); usage != nil && len(chunks) > 0 {
+		chunks[len(chunks)-1]["usage"] = usage
+	}
+	return chunks
+}
+
+// close returns the chunk finishing the choices the stream left unfinished,
+// or nil if every choice finished
+func (c *deltaChunker) close() map[string]interface{} {
+	var unfinished []int32
+	for index := range c.started {
+		if !c.finished[index] {
+			unfinished = append(unfinished, index)
+		}
+	}
+	if len(unfinished) == 0 {
+		return nil
+	}
+	sort.Slice(unfinished, func(i, j int) bool { return unfinished[i] < unfinished[j] })
+
+	finishes := make([]map[string]interface{}, len(unfinished))
+	for i, index := range unfinished {
+		c.finished[index] = true
+		finishes[i] = finishChoice(index, "stop")
+	}
+	return c.chunk(c.last, finishes)
+}
+
+// chunk builds a chunk of a response's stream with the given choices
+func (c *deltaChunker) chunk(resp *pb.ChatCompletionResponse, choices []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      resp.Id,
+		"object":  chunkObject,
+		"created": resp.Created,
+		"model":   resp.Model,
+		"choices": choices,
+	}
+}
+
+// finishChoice is a choice's last delta
+func finishChoice(index int32, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"index":         index,
+		"delta":         map[string]interface{}{},
+		"finish_reason": reason,
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// chunkFixture is a stream as the orchestrator sends it and the chunks a
+// strict OpenAI client must receive for it, in testdata/chunks
+type chunkFixture struct {
+	Description string            `json:"description"`
+	Responses   []json.RawMessage `json:"responses"`
+	Chunks      []json.RawMessage `json:"chunks"`
+}
+
+func TestGateway_strictChunksConformance(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "chunks", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	gateway := NewGateway("localhost:8080")
+	gateway.SetStrictChunks(true)
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var fixture chunkFixture
+			require.NoError(t, json.Unmarshal(data, &fixture))
+
+			client := &fakeChatClient{}
+			for _, raw := range fixture.Responses {
+				resp := &pb.ChatCompletionResponse{}
+				require.NoError(t, protojson.Unmarshal(raw, resp))
+				client.responses = append(client.responses, resp)
+			}
+
+			w := httptest.NewRecorder()
+			gateway.streamSSE(context.Background(), w, chatRequest(), client, false)
+
+			events := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+			require.Len(t, events, len(fixture.Chunks)+1, fixture.Description)
+			for i, want := range fixture.Chunks {
+				got := strings.TrimPrefix(events[i], "data: ")
+				assert.JSONEq(t, string(want), got, "chunk %d: %s", i, fixture.Description)
+			}
+			assert.Equal(t, "data: [DONE]", events[len(events)-1])
+		})
+	}
+}
+
+func TestGateway_chunksPassThroughByDefault(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	client := &fakeChatClient{responses: []*pb.ChatCompletionResponse{
+		{Object: chunkObject, Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}}}},
+		{Object: chunkObject, Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", Content: "!"}, FinishReason: "stop"}}},
+	}}
+
+	w := httptest.NewRecorder()
+	gateway.streamSSE(context.Background(), w, chatRequest(), client, false)
+	assert.Equal(t, 2, strings.Count(w.Body.String(), `"role":"assistant"`))
+	assert.True(t, strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n"))
+}
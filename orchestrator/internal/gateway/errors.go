@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+)
+
+// openAIError describes how an Orchion error code is presented to OpenAI clients
+type openAIError struct {
+	status  int
+	errType string
+	code    string
+}
+
+// openAIErrors maps Orchion error codes to OpenAI error types and HTTP statuses
+var openAIErrors = map[pb.ErrorCode]openAIError{
+	pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE:  {http.StatusServiceUnavailable, "server_error", "node_unavailable"},
+	pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND:   {http.StatusNotFound, "invalid_request_error", "model_not_found"},
+	pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT:    {http.StatusGatewayTimeout, "server_error", "engine_timeout"},
+	pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED:    {http.StatusServiceUnavailable, "server_error", "vram_exhausted"},
+	pb.ErrorCode_ERROR_CODE_NODE_THROTTLED:    {http.StatusServiceUnavailable, "server_error", "node_throttled"},
+	pb.ErrorCode_ERROR_CODE_AUTH_FAILED:       {http.StatusUnauthorized, "authentication_error", "invalid_api_key"},
+	pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED: {http.StatusForbidden, "permission_error", "permission_denied"},
+	pb.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED:    {http.StatusTooManyRequests, "rate_limit_error", "quota_exceeded"},
+	pb.ErrorCode_ERROR_CODE_INVALID_REQUEST:   {http.StatusBadRequest, "invalid_request_error", "invalid_request"},
+	pb.ErrorCode_ERROR_CODE_ENGINE_ERROR:      {http.StatusBadGateway, "server_error", "engine_error"},
+	pb.ErrorCode_ERROR_CODE_NODE_ID_CONFLICT:  {http.StatusConflict, "invalid_request_error", "node_id_conflict"},
+	pb.ErrorCode_ERROR_CODE_INTERNAL:          {http.StatusInternalServerError, "server_error", "internal_error"},
+}
+
+// lookupOpenAIError returns the OpenAI presentation of an error code
+func lookupOpenAIError(code pb.ErrorCode) openAIError {
+	if e, ok := openAIErrors[code]; ok {
+		return e
+	}
+	return openAIErrors[pb.ErrorCode_ERROR_CODE_INTERNAL]
+}
+
+// errorBody builds an OpenAI-style error object
+func errorBody(code pb.ErrorCode, message string) map[string]interface{} {
+	e := lookupOpenAIError(code)
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    e.errType,
+			"code":    e.code,
+		},
+	}
+}
+
+// writeError writes an OpenAI-style error response for an Orchion error code
+func (g *Gateway) writeError(w http.ResponseWriter, code pb.ErrorCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(lookupOpenAIError(code).status)
+	json.NewEncoder(w).Encode(errorBody(code, message))
+}
+
+// writeGRPCError writes an OpenAI-style error response for an error returned by the orchestrator
+func (g *Gateway) writeGRPCError(w http.ResponseWriter, err error) {
+	g.writeError(w, errcode.FromError(err), errorMessage(err))
+}
+
+// errorMessage returns the human-readable message of a gRPC error
+func errorMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+	return err.Error()
+}
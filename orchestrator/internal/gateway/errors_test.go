@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+)
+
+func TestGateway_writeGRPCError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+		wantCode   string
+	}{
+		{
+			name:       "model not found",
+			err:        errcode.New(codes.NotFound, pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no executor for model foo"),
+			wantStatus: http.StatusNotFound,
+			wantType:   "invalid_request_error",
+			wantCode:   "model_not_found",
+		},
+		{
+			name:       "permission denied",
+			err:        errcode.New(codes.PermissionDenied, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, "admin key required"),
+			wantStatus: http.StatusForbidden,
+			wantType:   "permission_error",
+			wantCode:   "permission_denied",
+		},
+		{
+			name:       "vram exhausted",
+			err:        errcode.New(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, "CUDA out of memory"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantType:   "server_error",
+			wantCode:   "vram_exhausted",
+		},
+		{
+			name:       "engine timeout",
+			err:        errcode.New(codes.DeadlineExceeded, pb.ErrorCode_ERROR_CODE_ENGINE_TIMEOUT, "engine took too long"),
+			wantStatus: http.StatusGatewayTimeout,
+			wantType:   "server_error",
+			wantCode:   "engine_timeout",
+		},
+		{
+			name:       "status without details",
+			err:        status.Error(codes.InvalidArgument, "model is required"),
+			wantStatus: http.StatusBadRequest,
+			wantType:   "invalid_request_error",
+			wantCode:   "invalid_request",
+		},
+	}
+
+	gateway := NewGateway("localhost:50051")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gateway.writeGRPCError(rec, tt.err)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+					Type    string `json:"type"`
+					Code    string `json:"code"`
+				} `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantType, body.Error.Type)
+			assert.Equal(t, tt.wantCode, body.Error.Code)
+			assert.Equal(t, status.Convert(tt.err).Message(), body.Error.Message)
+		})
+	}
+}
+
+func TestGateway_Unauthorized(t *testing.T) {
+	gateway := NewGateway("localhost:50051")
+	gateway.SetAPIKey("secret")
+
+	rec := httptest.NewRecorder()
+	gateway.EmbeddingsHandler(rec, httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"error":{"message":"Unauthorized","type":"authentication_error","code":"invalid_api_key"}}`, rec.Body.String())
+}
@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Acquire when as many requests as allowed are
+// already waiting
+var ErrQueueFull = errors.New("gateway queue is full")
+
+// releaseWindow is how many recent releases the wait estimate is based on
+const releaseWindow = 50
+
+// FairQueue limits the number of in-flight gateway requests and, once that
+// limit is reached, admits waiting requests using weighted fair queuing by
+// API key so that a single heavy client cannot starve the others.
+type FairQueue struct {
+	mu       sync.Mutex
+	capacity int
+	inFlight int
+
+	weights    map[string]float64 // Per-key weights, defaulting to 1
+	lastFinish map[string]float64 // Virtual finish time of each key's latest request, while ahead of virtual
+	virtual    float64            // Virtual time of the most recently admitted request
+	seq        uint64
+	waiting    []*fairWaiter
+
+	maxWaiting  int             // Waiting requests beyond which Acquire fails; 0 is unlimited
+	lastRelease time.Time       // When a slot was last released
+	intervals   []time.Duration // Recent busy times between releases, for estimating waits
+}
+
+type fairWaiter struct {
+	key    string
+	finish float64
+	cost   float64 // Virtual time charged to the key for the request
+	seq    uint64
+	ready  chan struct{}
+}
+
+// NewFairQueue creates a fair queue admitting at most capacity concurrent requests
+func NewFairQueue(capacity int) *FairQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &FairQueue{
+		capacity:   capacity,
+		weights:    make(map[string]float64),
+		lastFinish: make(map[string]float64),
+	}
+}
+
+// SetWeight sets the share of capacity given to an API key relative to other keys.
+// Non-positive weights reset the key to the default weight of 1.
+func (q *FairQueue) SetWeight(key string, weight float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if weight <= 0 {
+		delete(q.weights, key)
+		return
+	}
+	q.weights[key] = weight
+}
+
+// SetMaxWaiting limits how many requests may wait for a slot; 0 is unlimited
+func (q *FairQueue) SetMaxWaiting(max int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxWaiting = max
+}
+
+// Acquire blocks until a request for the given API key may proceed. The returned
+// function must be called once the request is done to free its slot.
+// Returns the context error if the context is cancelled while waiting, and
+// ErrQueueFull without waiting if too many requests are waiting already.
+func (q *FairQueue) Acquire(ctx context.Context, key string) (func(), error) {
+	q.mu.Lock()
+
+	queued := q.inFlight >= q.capacity || len(q.waiting) > 0
+	if queued && q.maxWaiting > 0 && len(q.waiting) >= q.maxWaiting {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &fairWaiter{key: key, seq: q.seq, ready: make(chan struct{})}
+	w.finish, w.cost = q.finishTagLocked(key)
+	q.seq++
+
+	if !queued {
+		q.admitLocked(w)
+		q.mu.Unlock()
+		return q.releaseFunc(time.Now()), nil
+	}
+
+	q.waiting = append(q.waiting, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return q.releaseFunc(time.Now()), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		removed := q.removeLocked(w)
+		if removed {
+			q.refundLocked(w)
+		}
+		q.mu.Unlock()
+		if !removed {
+			// Admitted concurrently with cancellation; hand the slot back
+			q.release(time.Time{})
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting returns the number of requests waiting for a slot
+func (q *FairQueue) Waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// InFlight returns the number of admitted requests that have not been released
+func (q *FairQueue) InFlight() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.inFlight
+}
+
+// Estimate returns the queue position a request arriving now would get, 0 if
+// it would be admitted at once, and roughly how long it would wait based on
+// how fast slots were released recently while requests were in flight. The
+// wait is 0 until a request has been released.
+func (q *FairQueue) Estimate() (position int, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inFlight < q.capacity && len(q.waiting) == 0 {
+		return 0, 0
+	}
+	position = len(q.waiting) + 1
+	if len(q.intervals) == 0 {
+		return position, 0
+	}
+	var busy time.Duration
+	for _, interval := range q.intervals {
+		busy += interval
+	}
+	perRequest := busy / time.Duration(len(q.intervals))
+	return position, perRequest * time.Duration(position)
+}
+
+// finishTagLocked assigns the virtual finish time of a new request for a key,
+// returning it and the virtual time charged for the request. Callers must hold
+// q.mu.
+func (q *FairQueue) finishTagLocked(key string) (finish, cost float64) {
+	weight, ok := q.weights[key]
+	if !ok {
+		weight = 1
+	}
+
+	start := q.virtual
+	if last := q.lastFinish[key]; last > start {
+		start = last
+	}
+
+	cost = 1 / weight
+	finish = start + cost
+	q.lastFinish[key] = finish
+	return finish, cost
+}
+
+// refundLocked takes back the virtual time charged to a waiter that left
+// before it was admitted, from its key and the key's requests queued after it.
+// Callers must hold q.mu.
+func (q *FairQueue) refundLocked(w *fairWaiter) {
+	for _, waiting := range q.waiting {
+		if waiting.key == w.key && waiting.seq > w.seq {
+			waiting.finish -= w.cost
+		}
+	}
+	q.lastFinish[w.key] -= w.cost
+	q.forgetIdleLocked()
+}
+
+// forgetIdleLocked drops the finish times of keys caught up with the virtual
+// time and with nothing queued, as their next request starts at the virtual
+// time anyway. This keeps one entry per active key rather than per key or
+// client address ever seen. Callers must hold q.mu.
+func (q *FairQueue) forgetIdleLocked() {
+	queued := make(map[string]bool, len(q.waiting))
+	for _, w := range q.waiting {
+		queued[w.key] = true
+	}
+	for key, finish := range q.lastFinish {
+		if finish <= q.virtual && !queued[key] {
+			delete(q.lastFinish, key)
+		}
+	}
+}
+
+// admitLocked lets a request proceed. Callers must hold q.mu.
+func (q *FairQueue) admitLocked(w *fairWaiter) {
+	q.inFlight++
+	if w.finish > q.virtual {
+		q.virtual = w.finish
+		q.forgetIdleLocked()
+	}
+	close(w.ready)
+}
+
+// removeLocked drops a waiter that gave up. Returns false if it was already admitted.
+// Callers must hold q.mu.
+func (q *FairQueue) removeLocked(w *fairWaiter) bool {
+	for i, waiting := range q.waiting {
+		if waiting == w {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// releaseFunc returns an idempotent function releasing the slot of a request
+// admitted at the given time
+func (q *FairQueue) releaseFunc(admitted time.Time) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { q.release(admitted) })
+	}
+}
+
+// release frees the slot of a request admitted at the given time, zero if it
+// didn't run, and admits the waiting request with the smallest finish tag
+func (q *FairQueue) release(admitted time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight--
+	if !admitted.IsZero() {
+		q.recordReleaseLocked(admitted, time.Now())
+	}
+	for q.inFlight < q.capacity && len(q.waiting) > 0 {
+		next := 0
+		for i, w := range q.waiting {
+			best := q.waiting[next]
+			if w.finish < best.finish || (w.finish == best.finish && w.seq < best.seq) {
+				next = i
+			}
+		}
+
+		w := q.waiting[next]
+		q.waiting = append(q.waiting[:next], q.waiting[next+1:]...)
+		q.admitLocked(w)
+	}
+}
+
+// recordReleaseLocked records the time since the previous release, counted
+// from when the released request was admitted if that was later, so idle
+// periods without requests don't inflate the wait estimate. Callers must hold
+// q.mu.
+func (q *FairQueue) recordReleaseLocked(admitted, now time.Time) {
+	since := q.lastRelease
+	if admitted.After(since) {
+		since = admitted
+	}
+	q.lastRelease = now
+	q.intervals = append(q.intervals, now.Sub(since))
+	if len(q.intervals) > releaseWindow {
+		q.intervals = q.intervals[len(q.intervals)-releaseWindow:]
+	}
+}
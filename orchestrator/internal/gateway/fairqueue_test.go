@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueWaiter enqueues a request for key and reports its key on order once admitted
+func queueWaiter(t *testing.T, q *FairQueue, key string, order chan<- string) {
+	t.Helper()
+
+	waiting := q.Waiting()
+	go func() {
+		release, err := q.Acquire(context.Background(), key)
+		if err != nil {
+			return
+		}
+		order <- key
+		release()
+	}()
+
+	require.Eventually(t, func() bool { return q.Waiting() == waiting+1 }, time.Second, time.Millisecond)
+}
+
+func TestFairQueue_AdmitsUpToCapacity(t *testing.T) {
+	q := NewFairQueue(2)
+
+	release1, err := q.Acquire(context.Background(), "a")
+	require.NoError(t, err)
+	release2, err := q.Acquire(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, 2, q.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Acquire(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, q.Waiting())
+
+	release1()
+	release1() // Releasing twice is a no-op
+	assert.Equal(t, 1, q.InFlight())
+	release2()
+	assert.Equal(t, 0, q.InFlight())
+}
+
+func TestFairQueue_InterleavesKeys(t *testing.T) {
+	q := NewFairQueue(1)
+	release, err := q.Acquire(context.Background(), "heavy")
+	require.NoError(t, err)
+
+	// A heavy client queues many requests before a light client shows up
+	order := make(chan string, 6)
+	for i := 0; i < 4; i++ {
+		queueWaiter(t, q, "heavy", order)
+	}
+	queueWaiter(t, q, "light", order)
+
+	release()
+
+	got := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		got = append(got, <-order)
+	}
+	assert.Equal(t, "light", got[1], "light client should not wait behind the heavy client's backlog: %v", got)
+}
+
+func TestFairQueue_Weights(t *testing.T) {
+	q := NewFairQueue(1)
+	q.SetWeight("premium", 3)
+
+	release, err := q.Acquire(context.Background(), "blocker")
+	require.NoError(t, err)
+
+	order := make(chan string, 8)
+	for i := 0; i < 4; i++ {
+		queueWaiter(t, q, "premium", order)
+		queueWaiter(t, q, "basic", order)
+	}
+
+	release()
+
+	got := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		got = append(got, <-order)
+	}
+	for i := 0; i < 4; i++ {
+		<-order
+	}
+
+	premium := 0
+	for _, key := range got {
+		if key == "premium" {
+			premium++
+		}
+	}
+	assert.Equal(t, 3, premium, "premium should get 3 of the first 4 slots: %v", got)
+}
+
+func TestFairQueue_ForgetsIdleKeys(t *testing.T) {
+	q := NewFairQueue(1)
+
+	// Every client address gets a finish time, until it has caught up
+	for _, key := range []string{"ip:10.0.0.1", "ip:10.0.0.2", "ip:10.0.0.3"} {
+		release, err := q.Acquire(context.Background(), key)
+		require.NoError(t, err)
+		release()
+	}
+	assert.Empty(t, q.lastFinish)
+
+	t.Run("cancelled waiters are refunded", func(t *testing.T) {
+		release, err := q.Acquire(context.Background(), "busy")
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		for i := 0; i < 3; i++ {
+			_, err = q.Acquire(ctx, "impatient")
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		assert.NotContains(t, q.lastFinish, "impatient")
+	})
+}
+
+func TestFairQueue_RefundKeepsOrder(t *testing.T) {
+	q := NewFairQueue(1)
+	release, err := q.Acquire(context.Background(), "blocker")
+	require.NoError(t, err)
+
+	// A client whose queued request gives up isn't charged for it
+	ctx, cancel := context.WithCancel(context.Background())
+	gaveUp := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(ctx, "a")
+		gaveUp <- err
+	}()
+	require.Eventually(t, func() bool { return q.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	order := make(chan string, 3)
+	queueWaiter(t, q, "a", order)
+	queueWaiter(t, q, "b", order)
+	cancel()
+	require.ErrorIs(t, <-gaveUp, context.Canceled)
+
+	release()
+	assert.Equal(t, "a", <-order, "a's remaining request should go first")
+	assert.Equal(t, "b", <-order)
+}
+
+func TestFairQueue_Estimate(t *testing.T) {
+	q := NewFairQueue(1)
+	position, wait := q.Estimate()
+	assert.Zero(t, position)
+	assert.Zero(t, wait)
+
+	release, err := q.Acquire(context.Background(), "a")
+	require.NoError(t, err)
+	defer release()
+	position, wait = q.Estimate()
+	assert.Equal(t, 1, position)
+	assert.Zero(t, wait, "no estimate before slots were released")
+
+	q.intervals = []time.Duration{time.Second, 3 * time.Second}
+	queueWaiter(t, q, "b", make(chan string, 1))
+	position, wait = q.Estimate()
+	assert.Equal(t, 2, position)
+	assert.Equal(t, 4*time.Second, wait)
+
+	q.SetMaxWaiting(1)
+	_, err = q.Acquire(context.Background(), "c")
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	t.Run("idle periods don't count", func(t *testing.T) {
+		q := NewFairQueue(2)
+		base := time.Now()
+		q.recordReleaseLocked(base.Add(-2*time.Second), base)
+		// Both slots busy, the second request released a second later
+		q.recordReleaseLocked(base.Add(-time.Second), base.Add(time.Second))
+		// Nothing in flight for an hour, then a request taking 3 seconds
+		q.recordReleaseLocked(base.Add(time.Hour), base.Add(time.Hour+3*time.Second))
+		assert.Equal(t, []time.Duration{2 * time.Second, time.Second, 3 * time.Second}, q.intervals)
+	})
+}
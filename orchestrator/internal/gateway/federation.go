@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// SetFederation sends requests to other Orchion clusters: to sites preferred
+// for the model before local nodes, and to overflow sites when no local node
+// can take the request
+func (g *Gateway) SetFederation(f *federation.Federation) {
+	g.federation = f
+}
+
+// overflowCodes are the errors meaning the local cluster has no capacity
+// for a request, which another site may have
+var overflowCodes = map[pb.ErrorCode]bool{
+	pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE: true,
+	pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND:  true,
+	pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED:   true,
+	pb.ErrorCode_ERROR_CODE_NODE_THROTTLED:   true,
+}
+
+// federable reports whether a request may be sent to another site: not if
+// it was forwarded here from one, or pinned to a local node
+func (g *Gateway) federable(r *http.Request) bool {
+	return g.federation != nil && r.Header.Get(federation.ForwardedHeader) == "" && r.Header.Get(TargetNodeHeader) == ""
+}
+
+// federate sends a request to the first site with the given policy that
+// takes it. If one does, the reply has been written and ok is true; the
+// returned generation names the site and code reports a failed request.
+func (g *Gateway) federate(w http.ResponseWriter, r *http.Request, policy federation.Policy, model string, req map[string]interface{}) (gen llm.Generation, code pb.ErrorCode, ok bool) {
+	if !g.federable(r) {
+		return gen, code, false
+	}
+	sites := g.federation.Sites(model, policy)
+	if len(sites) == 0 {
+		return gen, code, false
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return gen, code, false
+	}
+	for _, site := range sites {
+		status, err := g.federation.Forward(w, r, site, body)
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("Federated site did not take request", map[string]interface{}{
+				"site":  site.Name,
+				"model": model,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if status >= http.StatusBadRequest {
+			code = pb.ErrorCode_ERROR_CODE_ENGINE_ERROR
+		}
+		return llm.Generation{Node: "site:" + site.Name}, code, true
+	}
+	return gen, code, false
+}
+
+// overflows reports whether a local failure may be retried on another site
+func overflows(err error) bool {
+	return overflowCodes[errcode.FromError(err)]
+}
+
+// peekedStream replays the first message of a chat completion stream, read
+// to decide whether to overflow the request to another site
+type peekedStream struct {
+	pb.OrchionLLM_ChatCompletionClient
+	first  *pb.ChatCompletionResponse
+	err    error
+	peeked bool
+}
+
+func (s *peekedStream) Recv() (*pb.ChatCompletionResponse, error) {
+	if s.peeked {
+		s.peeked = false
+		return s.first, s.err
+	}
+	return s.OrchionLLM_ChatCompletionClient.Recv()
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+)
+
+// chatServer answers chat completions with a fixed reply, or fails them with
+// err
+type chatServer struct {
+	pb.UnimplementedOrchionLLMServer
+	err   error
+	calls *int32
+}
+
+func (s chatServer) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.OrchionLLM_ChatCompletionServer) error {
+	atomic.AddInt32(s.calls, 1)
+	if s.err != nil {
+		return s.err
+	}
+	return stream.Send(&pb.ChatCompletionResponse{
+		Id:    "local",
+		Model: req.Model,
+		Choices: []*pb.ChatChoice{{
+			Message:      &pb.ChatMessage{Role: "assistant", Content: "from home"},
+			FinishReason: "stop",
+		}},
+	})
+}
+
+// startSite serves a federated site's gateway offering model, counting the
+// chat completions it serves
+func startSite(t *testing.T, model string, calls *int32) *httptest.Server {
+	t.Helper()
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]string{{"id": model}}})
+		case "/v1/chat/completions", "/v1/embeddings":
+			atomic.AddInt32(calls, 1)
+			assert.Equal(t, "home", r.Header.Get(federation.ForwardedHeader))
+			assert.Equal(t, "Bearer office-key", r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"remote"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+// federatedGateway returns a gateway on an orchestrator serving chat with
+// srv, federated with a site of the given policy
+func federatedGateway(t *testing.T, srv chatServer, siteURL string, policy federation.Policy) *Gateway {
+	t.Helper()
+	f := federation.New(&federation.Config{
+		Site:  "home",
+		Sites: []*federation.Site{{Name: "office", URL: siteURL, APIKey: "office-key", Policy: policy}},
+	})
+	f.Sync(context.Background())
+
+	gateway := NewGateway(startLLMServer(t, srv))
+	gateway.SetFederation(f)
+	return gateway
+}
+
+func federatedChat(gateway *Gateway, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"llama3","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer client-key")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	gateway.ChatCompletionsHandler(rec, req)
+	return rec
+}
+
+func TestGateway_federationOverflow(t *testing.T) {
+	full := errcode.New(codes.NotFound, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "no node available for model llama3")
+
+	t.Run("overflows when no local node can take the request", func(t *testing.T) {
+		var localCalls, siteCalls int32
+		site := startSite(t, "llama3", &siteCalls)
+		gateway := federatedGateway(t, chatServer{err: full, calls: &localCalls}, site.URL, federation.PolicyOverflow)
+		tracker := usage.NewTracker(usage.Quota{})
+		gateway.SetUsageTracker(tracker)
+
+		rec := federatedChat(gateway, nil)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, `{"id":"remote"}`, rec.Body.String())
+		assert.Equal(t, "office", rec.Header().Get(federation.SiteHeader))
+		assert.EqualValues(t, 1, localCalls)
+		assert.EqualValues(t, 1, siteCalls)
+
+		models := tracker.ModelUsage(0)
+		require.Len(t, models, 1)
+		assert.Equal(t, map[string]int64{"site:office": 1}, models[0].Nodes)
+		assert.Zero(t, models[0].Errors)
+	})
+
+	t.Run("serves locally when a node can", func(t *testing.T) {
+		var localCalls, siteCalls int32
+		site := startSite(t, "llama3", &siteCalls)
+		gateway := federatedGateway(t, chatServer{calls: &localCalls}, site.URL, federation.PolicyOverflow)
+
+		rec := federatedChat(gateway, nil)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "from home")
+		assert.Zero(t, siteCalls)
+	})
+
+	t.Run("requests from another site are never forwarded", func(t *testing.T) {
+		var localCalls, siteCalls int32
+		site := startSite(t, "llama3", &siteCalls)
+		gateway := federatedGateway(t, chatServer{err: full, calls: &localCalls}, site.URL, federation.PolicyOverflow)
+
+		rec := federatedChat(gateway, http.Header{federation.ForwardedHeader: {"office"}})
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Zero(t, siteCalls)
+	})
+
+	t.Run("other failures are not overflowed", func(t *testing.T) {
+		var localCalls, siteCalls int32
+		site := startSite(t, "llama3", &siteCalls)
+		invalid := errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "bad request")
+		gateway := federatedGateway(t, chatServer{err: invalid, calls: &localCalls}, site.URL, federation.PolicyOverflow)
+
+		rec := federatedChat(gateway, nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Zero(t, siteCalls)
+	})
+
+	t.Run("sites without the model are skipped", func(t *testing.T) {
+		var localCalls, siteCalls int32
+		site := startSite(t, "mistral", &siteCalls)
+		gateway := federatedGateway(t, chatServer{err: full, calls: &localCalls}, site.URL, federation.PolicyOverflow)
+
+		rec := federatedChat(gateway, nil)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Zero(t, siteCalls)
+	})
+}
+
+func TestGateway_federationPrefer(t *testing.T) {
+	var localCalls, siteCalls int32
+	site := startSite(t, "llama3", &siteCalls)
+	gateway := federatedGateway(t, chatServer{calls: &localCalls}, site.URL, federation.PolicyPrefer)
+
+	rec := federatedChat(gateway, nil)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "office", rec.Header().Get(federation.SiteHeader))
+	assert.Zero(t, localCalls)
+
+	// A preferred site that can't take the request falls back to local nodes
+	site.Close()
+	rec = federatedChat(gateway, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "from home")
+	assert.EqualValues(t, 1, localCalls)
+}
+
+func TestGateway_federationEmbeddings(t *testing.T) {
+	var siteCalls int32
+	site := startSite(t, "nomic-embed-text", &siteCalls)
+	f := federation.New(&federation.Config{
+		Site:  "home",
+		Sites: []*federation.Site{{Name: "office", URL: site.URL, APIKey: "office-key", Policy: federation.PolicyOverflow}},
+	})
+	f.Sync(context.Background())
+	// Nothing listens here, so local requests fail with node_unavailable
+	gateway := NewGateway("127.0.0.1:1")
+	gateway.SetFederation(f)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"nomic-embed-text","input":"hi"}`))
+	rec := httptest.NewRecorder()
+	gateway.EmbeddingsHandler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body, _ := io.ReadAll(rec.Body)
+	assert.Equal(t, `{"id":"remote"}`, string(body))
+	assert.EqualValues(t, 1, siteCalls)
+}
@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+)
+
+// multipartMemory is how much of an upload is parsed in memory; the rest is
+// buffered in a temporary file until it's stored
+const multipartMemory = 1 << 20
+
+// SetFileStore serves /v1/files from store, accepting files up to maxSize
+// bytes
+func (g *Gateway) SetFileStore(store *files.Store, maxSize int64) {
+	g.files = store
+	g.maxFileSize = maxSize
+}
+
+// FilesHandler handles /v1/files. POST uploads a file as multipart/form-data
+// with "file" and "purpose" fields, like OpenAI's API; GET /v1/files/{id}
+// returns its description, GET /v1/files/{id}/content its content and
+// DELETE /v1/files/{id} removes it. Files are only visible to the API key
+// that uploaded them. Jobs name an uploaded file as their payload with
+// payload_file_id.
+func (g *Gateway) FilesHandler(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !g.authorize(w, r) {
+		return
+	}
+	if g.files == nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "File uploads are not enabled")
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/files"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		g.uploadFile(w, r)
+	case rest != "" && r.Method == http.MethodGet:
+		id, content := strings.CutSuffix(rest, "/content")
+		if strings.Contains(id, "/") {
+			writeFileNotFound(w, rest)
+			return
+		}
+		if content {
+			g.serveFileContent(w, r, id)
+		} else {
+			g.serveFile(w, r, id)
+		}
+	case rest != "" && r.Method == http.MethodDelete:
+		if strings.Contains(rest, "/") {
+			writeFileNotFound(w, rest)
+			return
+		}
+		g.deleteFile(w, r, rest)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// uploadFile stores the file of a multipart upload
+func (g *Gateway) uploadFile(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the form around the file
+	r.Body = http.MaxBytesReader(w, r.Body, g.maxFileSize+multipartMemory)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("File exceeds %d bytes", g.maxFileSize))
+			return
+		}
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid multipart form: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	purpose := r.FormValue("purpose")
+	if purpose == "" {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "purpose is required")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "file is required")
+		return
+	}
+	defer file.Close()
+	if header.Size > g.maxFileSize {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("File exceeds %d bytes", g.maxFileSize))
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Failed to read file: %v", err))
+		return
+	}
+	f, err := g.files.Put(r.Context(), g.fileOwner(r), header.Filename, purpose, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f)
+}
+
+// serveFile writes a file's description
+func (g *Gateway) serveFile(w http.ResponseWriter, r *http.Request, id string) {
+	f, err := g.files.Get(r.Context(), g.fileOwner(r), id)
+	if errors.Is(err, files.ErrNotFound) {
+		writeFileNotFound(w, id)
+		return
+	}
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f)
+}
+
+// serveFileContent streams a file's content in the media type it was
+// uploaded with
+func (g *Gateway) serveFileContent(w http.ResponseWriter, r *http.Request, id string) {
+	f, err := g.files.Get(r.Context(), g.fileOwner(r), id)
+	if errors.Is(err, files.ErrNotFound) {
+		writeFileNotFound(w, id)
+		return
+	}
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, err.Error())
+		return
+	}
+	content, err := g.files.Open(r.Context(), g.fileOwner(r), id)
+	if errors.Is(err, files.ErrNotFound) {
+		writeFileNotFound(w, id)
+		return
+	}
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, err.Error())
+		return
+	}
+	defer content.Close()
+
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(f.Bytes, 10))
+	io.Copy(w, content)
+}
+
+// deleteFile removes a file
+func (g *Gateway) deleteFile(w http.ResponseWriter, r *http.Request, id string) {
+	err := g.files.Delete(r.Context(), g.fileOwner(r), id)
+	if errors.Is(err, files.ErrNotFound) {
+		writeFileNotFound(w, id)
+		return
+	}
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(files.DeletedFile{ID: id, Object: "file", Deleted: true})
+}
+
+// fileOwner returns the owner of the files a request can see, the ID of its
+// API key, or of the key its session token was opened with
+func (g *Gateway) fileOwner(r *http.Request) string {
+	if g.authz != nil {
+		return g.authz.KeyIDOf(auth.RequestKey(r))
+	}
+	return auth.KeyID(auth.RequestKey(r))
+}
+
+// writeFileNotFound writes OpenAI's 404 response for an unknown file
+func writeFileNotFound(w http.ResponseWriter, id string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(errorBody(pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("No such file: %s", id)))
+}
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+)
+
+// uploadRequest builds a multipart upload of data to /v1/files
+func uploadRequest(t *testing.T, purpose, filename, contentType string, data []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if purpose != "" {
+		require.NoError(t, form.WriteField("purpose", purpose))
+	}
+	if filename != "" {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		require.NoError(t, err)
+		part.Write(data)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestGateway_FilesHandler(t *testing.T) {
+	blobs, err := results.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+	gateway := NewGateway("localhost:8080")
+	gateway.SetFileStore(files.NewStore(blobs), 1024)
+
+	w := httptest.NewRecorder()
+	gateway.FilesHandler(w, uploadRequest(t, "user_data", "batch.json", "application/json", []byte(`{"model":"llama3"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var uploaded files.File
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&uploaded))
+	assert.Equal(t, "file", uploaded.Object)
+	assert.Equal(t, "batch.json", uploaded.Filename)
+	assert.Equal(t, "user_data", uploaded.Purpose)
+	assert.Equal(t, int64(18), uploaded.Bytes)
+
+	t.Run("description", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/files/"+uploaded.ID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var got files.File
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal(t, uploaded, got)
+	})
+
+	t.Run("content", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/files/"+uploaded.ID+"/content", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `{"model":"llama3"}`, w.Body.String())
+	})
+
+	t.Run("unknown file", func(t *testing.T) {
+		for _, path := range []string{"/v1/files/file-000000000000000000000000", "/v1/files/nope/content", "/v1/files/a/b/content"} {
+			w := httptest.NewRecorder()
+			gateway.FilesHandler(w, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+	})
+
+	t.Run("other keys' files", func(t *testing.T) {
+		for _, path := range []string{"/v1/files/" + uploaded.ID, "/v1/files/" + uploaded.ID + "/content"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer other-key")
+			w := httptest.NewRecorder()
+			gateway.FilesHandler(w, req)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, "/v1/files/"+uploaded.ID, nil)
+		req.Header.Set("Authorization", "Bearer other-key")
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		upload := uploadRequest(t, "user_data", "a.wav", "audio/wav", []byte("RIFF"))
+		upload.Header.Set("Authorization", "Bearer alice-key")
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, upload)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var f files.File
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&f))
+
+		del := httptest.NewRequest(http.MethodDelete, "/v1/files/"+f.ID, nil)
+		del.Header.Set("Authorization", "Bearer alice-key")
+		w = httptest.NewRecorder()
+		gateway.FilesHandler(w, del)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var deleted files.DeletedFile
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&deleted))
+		assert.Equal(t, files.DeletedFile{ID: f.ID, Object: "file", Deleted: true}, deleted)
+
+		get := httptest.NewRequest(http.MethodGet, "/v1/files/"+f.ID, nil)
+		get.Header.Set("Authorization", "Bearer alice-key")
+		w = httptest.NewRecorder()
+		gateway.FilesHandler(w, get)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("sessions own files as their key", func(t *testing.T) {
+		authz := auth.NewAuthorizer("alice-key", nil)
+		sessions, err := auth.NewSessions(nil, time.Hour)
+		require.NoError(t, err)
+		authz.SetSessions(sessions)
+		gateway.SetAuthorizer(authz)
+		defer gateway.SetAuthorizer(nil)
+		token, _, _, err := authz.Login("alice-key")
+		require.NoError(t, err)
+
+		upload := uploadRequest(t, "user_data", "a.wav", "audio/wav", []byte("RIFF"))
+		upload.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, upload)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var f files.File
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&f))
+
+		del := httptest.NewRequest(http.MethodDelete, "/v1/files/"+f.ID, nil)
+		del.Header.Set("Authorization", "Bearer alice-key")
+		w = httptest.NewRecorder()
+		gateway.FilesHandler(w, del)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("invalid uploads", func(t *testing.T) {
+		for name, req := range map[string]*http.Request{
+			"no purpose": uploadRequest(t, "", "a.wav", "audio/wav", []byte("RIFF")),
+			"no file":    uploadRequest(t, "user_data", "", "", nil),
+			"too large":  uploadRequest(t, "user_data", "a.wav", "audio/wav", make([]byte, 2048)),
+			"not a form": httptest.NewRequest(http.MethodPost, "/v1/files", bytes.NewReader([]byte("RIFF"))),
+		} {
+			w := httptest.NewRecorder()
+			gateway.FilesHandler(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		}
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		gateway.SetAPIKey("secret")
+		defer gateway.SetAPIKey("")
+		w := httptest.NewRecorder()
+		gateway.FilesHandler(w, httptest.NewRequest(http.MethodGet, "/v1/files/"+uploaded.ID, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		NewGateway("localhost:8080").FilesHandler(w, uploadRequest(t, "user_data", "a.wav", "audio/wav", []byte("RIFF")))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -2,28 +2,108 @@ package gateway
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/federation"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
+	"github.com/Orchion/Orchion/orchestrator/internal/sse"
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
+// TargetNodeHeader names a node to send the request to, bypassing the
+// scheduler. Only admin API keys may set it; it's meant for debugging a
+// specific node.
+const TargetNodeHeader = "X-Orchion-Node"
+
+// StatusEventsHeader asks for "status" events in a chat completion stream
+// while the model is still loading. Without it the stream only carries SSE
+// comments then, which every client ignores.
+const StatusEventsHeader = "X-Orchion-Status-Events"
+
+// PriorityHeader sets a chat request's priority on saturated nodes: "high",
+// "normal", "low" or an integer, higher first. Any key may lower its
+// requests' priority, e.g. for batch workloads; only admin API keys may raise
+// it.
+const PriorityHeader = "X-Orchion-Priority"
+
+// QueueWaitHeader carries the estimated wait, in whole seconds, of a request
+// that was queued because the gateway was at -gateway-max-inflight. Requests
+// turned away because the queue is full get the same estimate in Retry-After.
+const QueueWaitHeader = "X-Orchion-Queue-Wait"
+
+// Named request priorities
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+// Chat completion response headers reporting which node generated the reply
+// and how fast: milliseconds to the first token, model loading included, and
+// tokens per second after it (streamed replies only). Streamed replies send
+// them as HTTP trailers.
+const (
+	ServedByHeader = "X-Orchion-Served-By"
+	TTFTHeader     = "X-Orchion-TTFT"
+	TPSHeader      = "X-Orchion-TPS"
+)
+
+// DefaultSSEKeepAlive is how long a chat completion stream may go quiet
+// before the gateway sends an SSE comment, so proxies and clients with idle
+// timeouts don't drop slow generations
+const DefaultSSEKeepAlive = 15 * time.Second
+
+// StreamReadAhead is how many chat completion chunks the gateway buffers for
+// a client that reads slower than the model generates
+const StreamReadAhead = 32
+
 // Gateway handles HTTP requests and converts them to gRPC
 type Gateway struct {
 	orchestratorAddr string
-	apiKey           string // Optional API key for authentication
+	apiKey           string          // Optional API key for authentication
+	adminKeys        map[string]bool // API keys allowed to target nodes explicitly
+	queue            *FairQueue      // Optional admission queue, nil when unlimited
+	usage            *usage.Tracker  // Optional per-user usage tracking and quotas
+	dialOpts         []grpc.DialOption
+	keepAlive        time.Duration // Zero disables SSE keep-alive comments
+	writeTimeout     time.Duration // Zero disables SSE write deadlines
+	transforms       *Transforms   // Optional request and response transforms
+	strictChunks     bool          // Rewrite streamed chunks to OpenAI's delta semantics
+
+	federation *federation.Federation // Optional other clusters requests may go to
+
+	// Optional roles of API keys; takes over from the API and admin keys
+	authz *auth.Authorizer
+
+	// Optional store of files uploaded to /v1/files, and the largest upload
+	files       *files.Store
+	maxFileSize int64
 }
 
 // NewGateway creates a new gateway
 func NewGateway(orchestratorAddr string) *Gateway {
 	return &Gateway{
 		orchestratorAddr: orchestratorAddr,
+		keepAlive:        DefaultSSEKeepAlive,
+		writeTimeout:     sse.DefaultWriteTimeout,
 	}
 }
 
@@ -32,29 +112,219 @@ func (g *Gateway) SetAPIKey(apiKey string) {
 	g.apiKey = apiKey
 }
 
+// SetAdminKeys sets the API keys allowed to pin requests to a node with the
+// X-Orchion-Node header. Admin keys are also accepted wherever the regular
+// API key is.
+func (g *Gateway) SetAdminKeys(keys []string) {
+	g.adminKeys = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key != "" {
+			g.adminKeys[key] = true
+		}
+	}
+}
+
+// SetAuthorizer checks requests against the keys and roles of authz, which
+// admins manage at /api/admin/keys, in place of the API and admin keys:
+// operator keys may call the API, and admin keys may also pin requests to a
+// node and raise their priority
+func (g *Gateway) SetAuthorizer(authz *auth.Authorizer) {
+	g.authz = authz
+}
+
+// SetFairQueue limits concurrent requests, queuing the excess fairly by API key
+func (g *Gateway) SetFairQueue(queue *FairQueue) {
+	g.queue = queue
+}
+
+// SetUsageTracker records every request's usage and enforces the tracker's
+// per-user quotas
+func (g *Gateway) SetUsageTracker(tracker *usage.Tracker) {
+	g.usage = tracker
+}
+
+// SetDialOptions adds options, such as message size limits, to connections
+// to the orchestrator
+func (g *Gateway) SetDialOptions(opts ...grpc.DialOption) {
+	g.dialOpts = opts
+}
+
+// SetSSEKeepAlive sets how long a chat completion stream may go quiet before
+// an SSE comment is sent; zero disables keep-alive comments
+func (g *Gateway) SetSSEKeepAlive(interval time.Duration) {
+	g.keepAlive = interval
+}
+
+// SetStrictChunks rewrites streamed chat completion chunks to follow
+// OpenAI's delta semantics exactly, for SDKs that reject the role repeated in
+// every delta or content in the finishing chunk. Off by default, chunks pass
+// through as the engine sent them.
+func (g *Gateway) SetStrictChunks(strict bool) {
+	g.strictChunks = strict
+}
+
+// SetSSEWriteTimeout sets how long a streaming client may take to accept
+// each event before its stream is dropped; zero waits indefinitely
+func (g *Gateway) SetSSEWriteTimeout(timeout time.Duration) {
+	g.writeTimeout = timeout
+}
+
+// SetTransforms sets the transforms applied to chat completion and embeddings
+// requests and responses
+func (g *Gateway) SetTransforms(transforms *Transforms) {
+	g.transforms = transforms
+}
+
+// dial connects to the orchestrator
+func (g *Gateway) dial() (*grpc.ClientConn, error) {
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, g.dialOpts...)
+	return grpc.NewClient(g.orchestratorAddr, opts...)
+}
+
+// checkQuota reports whether the request's user may make another request
+func (g *Gateway) checkQuota(user string) error {
+	if g.usage == nil {
+		return nil
+	}
+	return g.usage.Allow(user)
+}
+
+// recordUsage adds a finished request to the usage tracker, if one is configured
+func (g *Gateway) recordUsage(r *http.Request, start time.Time, model, user string, promptTokens int32, gen llm.Generation, code pb.ErrorCode) {
+	if g.usage == nil {
+		return
+	}
+
+	rec := usage.Record{
+		Time:         start,
+		User:         user,
+		APIKeyID:     usage.APIKeyID(auth.RequestKey(r)),
+		ClientIP:     auth.ClientIP(r),
+		Endpoint:     r.URL.Path,
+		Model:        model,
+		PromptTokens: int64(promptTokens),
+		LatencyMs:    time.Since(start).Milliseconds(),
+
+		Node:            gen.Node,
+		TTFTMs:          gen.TTFT.Milliseconds(),
+		TokensPerSecond: gen.TokensPerSecond,
+	}
+	if code != pb.ErrorCode_ERROR_CODE_UNSPECIFIED {
+		rec.Error = lookupOpenAIError(code).code
+	}
+	g.usage.Record(rec)
+}
+
 // authenticate checks if the request is authenticated (if API key is set)
 func (g *Gateway) authenticate(r *http.Request) bool {
-	if g.apiKey == "" {
-		return true // No authentication required
+	enabled := g.apiKey != ""
+	if g.authz != nil {
+		enabled = g.authz.Enabled()
 	}
+	return !enabled || g.role(r) >= auth.RoleOperator
+}
 
-	// Check Authorization header: "Bearer <key>" or "sk-<key>"
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
+// authorize checks that the request may call the API, writing a 401 without
+// a valid key and a 403 for keys that may only read the dashboard API
+func (g *Gateway) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if g.authenticate(r) {
+		return true
+	}
+	if role := g.role(r); role != auth.RoleAnonymous {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, role.String()+" keys can't call the API")
 		return false
 	}
+	g.writeError(w, pb.ErrorCode_ERROR_CODE_AUTH_FAILED, "Unauthorized")
+	return false
+}
+
+// role returns the role of the request's API key, from the "Bearer <key>" or
+// "sk-<key>" Authorization header
+func (g *Gateway) role(r *http.Request) auth.Role {
+	key := auth.RequestKey(r)
+	switch {
+	case key == "":
+		return auth.RoleAnonymous
+	case g.authz != nil:
+		return g.authz.RoleOfKey(key)
+	case g.adminKeys[key]:
+		return auth.RoleAdmin
+	case key == g.apiKey:
+		return auth.RoleOperator
+	}
+	return auth.RoleAnonymous
+}
 
-	// Support both "Bearer <key>" and "sk-<key>" formats
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		key := strings.TrimPrefix(authHeader, "Bearer ")
-		return key == g.apiKey
+// targetContext returns the context for the orchestrator call, carrying the
+// node named in the X-Orchion-Node header. ok is false if the header is set
+// but the request's API key isn't an admin key.
+func (g *Gateway) targetContext(r *http.Request) (ctx context.Context, ok bool) {
+	nodeID := strings.TrimSpace(r.Header.Get(TargetNodeHeader))
+	if nodeID == "" {
+		return r.Context(), true
 	}
-	if strings.HasPrefix(authHeader, "sk-") {
-		key := strings.TrimPrefix(authHeader, "sk-")
-		return key == g.apiKey
+	if g.role(r) < auth.RoleAdmin {
+		return nil, false
 	}
+	return metadata.AppendToOutgoingContext(r.Context(), llm.TargetNodeMetadata, nodeID), true
+}
 
-	return authHeader == g.apiKey
+// parsePriority parses an X-Orchion-Priority header value; an empty value is
+// normal priority
+func parsePriority(v string) (int32, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	priority, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q (want high, normal, low or an integer)", PriorityHeader, v)
+	}
+	return int32(priority), nil
+}
+
+// admit waits for a slot in the fair queue, if one is configured. Requests
+// without an API key are queued by client address. The returned function
+// releases the slot; ok is false, with the response written, if the queue is
+// full or the client went away while waiting.
+func (g *Gateway) admit(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if g.queue == nil {
+		return func() {}, true
+	}
+
+	key := auth.RequestKey(r)
+	if key == "" {
+		key = "ip:" + auth.ClientIP(r)
+	}
+	position, wait := g.queue.Estimate()
+	release, err := g.queue.Acquire(r.Context(), key)
+	if errors.Is(err, ErrQueueFull) {
+		w.Header().Set("Retry-After", waitSeconds(wait))
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "Too many requests queued, retry later")
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Request cancelled while queued", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	if position > 0 {
+		w.Header().Set(QueueWaitHeader, waitSeconds(wait))
+	}
+	return release, true
+}
+
+// waitSeconds formats a wait in whole seconds, rounded up and at least 1 so
+// clients never retry immediately
+func waitSeconds(wait time.Duration) string {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
 }
 
 // ChatCompletionsHandler handles /v1/chat/completions
@@ -62,7 +332,8 @@ func (g *Gateway) ChatCompletionsHandler(w http.ResponseWriter, r *http.Request)
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Prompt-Cache-Key, X-Orchion-Node, "+StatusEventsHeader+", "+PriorityHeader)
+	w.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{ServedByHeader, TTFTHeader, TPSHeader, QueueWaitHeader}, ", "))
 
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -75,45 +346,120 @@ func (g *Gateway) ChatCompletionsHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check authentication if API key is set
-	if !g.authenticate(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if !g.authorize(w, r) {
+		return
+	}
+
+	ctx, ok := g.targetContext(r)
+	if !ok {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, TargetNodeHeader+" requires an admin API key")
 		return
 	}
 
 	// Parse OpenAI request
 	var openaiReq map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&openaiReq); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if err := g.transforms.Request(r, openaiReq); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
 
 	// Convert to gRPC request
 	grpcReq, err := g.convertChatCompletionRequest(openaiReq)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	// Clients may declare a stable prompt prefix in the header instead of the body
+	if grpcReq.PromptCacheKey == "" {
+		grpcReq.PromptCacheKey = r.Header.Get("X-Prompt-Cache-Key")
+	}
+	if grpcReq.PromptCacheKey != "" {
+		grpcReq.CacheSalt = cacheSalt(auth.RequestKey(r))
+	}
+
+	grpcReq.Priority, err = parsePriority(r.Header.Get(PriorityHeader))
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, err.Error())
+		return
+	}
+	if grpcReq.Priority > PriorityNormal && g.role(r) < auth.RoleAdmin {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, "raising "+PriorityHeader+" requires an admin API key")
+		return
+	}
+
+	if err := g.checkQuota(grpcReq.User); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED, err.Error())
+		return
+	}
+
+	// Wait for our turn when the cluster is saturated
+	release, ok := g.admit(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	start := time.Now()
+	var promptTokens int32
+	var gen llm.Generation
+	code := pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+	defer func() {
+		g.recordUsage(r, start, grpcReq.Model, grpcReq.User, promptTokens, gen, code)
+	}()
+
+	// Sites preferred for the model take its requests before local nodes
+	if site, siteCode, ok := g.federate(w, r, federation.PolicyPrefer, grpcReq.Model, openaiReq); ok {
+		gen, code = site, siteCode
 		return
 	}
 
 	// Connect to orchestrator
-	conn, err := grpc.NewClient(g.orchestratorAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := g.dial()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to connect to orchestrator: %v", err), http.StatusInternalServerError)
+		code = pb.ErrorCode_ERROR_CODE_INTERNAL
+		g.writeError(w, code, fmt.Sprintf("Failed to connect to orchestrator: %v", err))
 		return
 	}
 	defer conn.Close()
 
+	// Cancelling the call's context ends the upstream stream, freeing the
+	// node as soon as the client goes away
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	client := pb.NewOrchionLLMClient(conn)
-	stream, err := client.ChatCompletion(r.Context(), grpcReq)
+	stream, err := client.ChatCompletion(ctx, grpcReq)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to call orchestrator: %v", err), http.StatusInternalServerError)
+		code = errcode.FromError(err)
+		g.writeGRPCError(w, err)
 		return
 	}
 
+	// Scheduling failures arrive as the stream's first message, so peek at
+	// it when another site could take the request instead. Only then: until
+	// it arrives, nothing is sent to the client, keep-alives included.
+	if g.federable(r) && len(g.federation.Sites(grpcReq.Model, federation.PolicyOverflow)) > 0 {
+		first, err := stream.Recv()
+		if err != nil && overflows(err) {
+			if site, siteCode, ok := g.federate(w, r, federation.PolicyOverflow, grpcReq.Model, openaiReq); ok {
+				gen, code = site, siteCode
+				return
+			}
+		}
+		stream = &peekedStream{OrchionLLM_ChatCompletionClient: stream, first: first, err: err, peeked: true}
+	}
+
 	// Stream responses
 	if grpcReq.Stream {
-		g.streamSSE(w, stream)
+		statusEvents, _ := strconv.ParseBool(r.Header.Get(StatusEventsHeader))
+		promptTokens, gen, code = g.streamSSE(ctx, w, r, stream, statusEvents)
 	} else {
-		g.sendNonStreamingResponse(w, stream)
+		promptTokens, gen, code = g.sendNonStreamingResponse(w, r, stream)
 	}
 }
 
@@ -122,7 +468,7 @@ func (g *Gateway) EmbeddingsHandler(w http.ResponseWriter, r *http.Request) {
 	// CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Orchion-Node")
 
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -135,42 +481,89 @@ func (g *Gateway) EmbeddingsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check authentication if API key is set
-	if !g.authenticate(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if !g.authorize(w, r) {
+		return
+	}
+
+	ctx, ok := g.targetContext(r)
+	if !ok {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, TargetNodeHeader+" requires an admin API key")
 		return
 	}
 
 	// Parse OpenAI request
 	var openaiReq map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&openaiReq); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if err := g.transforms.Request(r, openaiReq); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid request: %v", err))
 		return
 	}
 
 	// Convert to gRPC request
 	grpcReq, err := g.convertEmbeddingRequest(openaiReq)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	if err := g.checkQuota(grpcReq.User); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED, err.Error())
+		return
+	}
+
+	// Wait for our turn when the cluster is saturated
+	release, ok := g.admit(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	start := time.Now()
+	var promptTokens int32
+	code := pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+	var gen llm.Generation
+	defer func() {
+		g.recordUsage(r, start, grpcReq.Model, grpcReq.User, promptTokens, gen, code)
+	}()
+
+	// Sites preferred for the model take its requests before local nodes
+	if site, siteCode, ok := g.federate(w, r, federation.PolicyPrefer, grpcReq.Model, openaiReq); ok {
+		gen, code = site, siteCode
 		return
 	}
 
 	// Connect to orchestrator
-	conn, err := grpc.NewClient(g.orchestratorAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := g.dial()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to connect to orchestrator: %v", err), http.StatusInternalServerError)
+		code = pb.ErrorCode_ERROR_CODE_INTERNAL
+		g.writeError(w, code, fmt.Sprintf("Failed to connect to orchestrator: %v", err))
 		return
 	}
 	defer conn.Close()
 
 	client := pb.NewOrchionLLMClient(conn)
-	resp, err := client.Embeddings(r.Context(), grpcReq)
+	resp, err := llm.EmbedInChunks(ctx, grpcReq, func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+		return client.Embeddings(ctx, req)
+	})
+	if err != nil && overflows(err) {
+		if site, siteCode, ok := g.federate(w, r, federation.PolicyOverflow, grpcReq.Model, openaiReq); ok {
+			gen, code = site, siteCode
+			return
+		}
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to call orchestrator: %v", err), http.StatusInternalServerError)
+		code = errcode.FromError(err)
+		g.writeGRPCError(w, err)
 		return
 	}
+	promptTokens = resp.UsagePromptTokens
 
 	// Convert to OpenAI format
 	openaiResp := g.convertEmbeddingResponse(resp)
+	g.transforms.Response(r, openaiResp)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(openaiResp)
@@ -219,6 +612,45 @@ func (g *Gateway) convertChatCompletionRequest(req map[string]interface{}) (*pb.
 		grpcReq.MaxTokens = int32(maxTokens)
 	}
 
+	// Prompt cache key
+	if key, ok := req["prompt_cache_key"].(string); ok {
+		grpcReq.PromptCacheKey = key
+	}
+
+	// Sampling parameters
+	stop, err := stopSequences(req)
+	if err != nil {
+		return nil, err
+	}
+	grpcReq.Stop = stop
+	if topP, ok := req["top_p"].(float64); ok {
+		grpcReq.TopP = float32(topP)
+	}
+	if topK, ok := req["top_k"].(float64); ok {
+		grpcReq.TopK = int32(topK)
+	}
+	if penalty, ok := req["repetition_penalty"].(float64); ok {
+		grpcReq.RepetitionPenalty = float32(penalty)
+	}
+	if seed, ok := req["seed"].(float64); ok {
+		s := int64(seed)
+		grpcReq.Seed = &s
+	}
+
+	// End-user identifier
+	user, err := requestUser(req)
+	if err != nil {
+		return nil, err
+	}
+	grpcReq.User = user
+
+	// Engine-specific options
+	options, err := engineOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	grpcReq.EngineOptions = options
+
 	return grpcReq, nil
 }
 
@@ -245,60 +677,308 @@ func (g *Gateway) convertEmbeddingRequest(req map[string]interface{}) (*pb.Embed
 		return nil, fmt.Errorf("input is required")
 	}
 
+	// End-user identifier
+	user, err := requestUser(req)
+	if err != nil {
+		return nil, err
+	}
+	grpcReq.User = user
+
 	return grpcReq, nil
 }
 
-// streamSSE streams Server-Sent Events
-func (g *Gateway) streamSSE(w http.ResponseWriter, stream pb.OrchionLLM_ChatCompletionClient) {
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+// requestUser returns the OpenAI "user" field of a request, if present
+func requestUser(req map[string]interface{}) (string, error) {
+	v, ok := req["user"]
+	if !ok || v == nil {
+		return "", nil
+	}
+	user, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("user must be a string")
+	}
+	return strings.TrimSpace(user), nil
+}
 
-	flusher, ok := w.(http.Flusher)
+// stopSequences returns the OpenAI "stop" field of a request, a string or an
+// array of strings, if present
+func stopSequences(req map[string]interface{}) ([]string, error) {
+	switch v := req["stop"].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		stop := make([]string, len(v))
+		for i, s := range v {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("stop must be a string or an array of strings")
+			}
+			stop[i] = str
+		}
+		return stop, nil
+	default:
+		return nil, fmt.Errorf("stop must be a string or an array of strings")
+	}
+}
+
+// engineOptions returns the engine-specific options under the "orchion"
+// namespace of a request, e.g. {"orchion": {"num_ctx": 8192}}, with each
+// value encoded as JSON
+func engineOptions(req map[string]interface{}) (map[string]string, error) {
+	v, ok := req["orchion"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	namespace, ok := v.(map[string]interface{})
 	if !ok {
+		return nil, fmt.Errorf("orchion must be an object")
+	}
+	options := make(map[string]string, len(namespace))
+	for key, value := range namespace {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid orchion option %q: %v", key, err)
+		}
+		options[key] = string(encoded)
+	}
+	return options, nil
+}
+
+// streamSSE streams Server-Sent Events. While the node loads the model it
+// sends ": warming-up" comments, and "status" events if statusEvents is set,
+// so clients and proxies don't time out waiting for the first token; slow
+// generations get ": keep-alive" comments whenever the stream goes quiet. It
+// returns as soon as ctx is done, e.g. when the client disconnects, or once
+// the client takes longer than the write timeout to accept an event. It
+// returns the prompt tokens reported by the engine, the node's speed and the
+// error code the stream ended with, if any.
+func (g *Gateway) streamSSE(ctx context.Context, w http.ResponseWriter, r *http.Request, stream pb.OrchionLLM_ChatCompletionClient, statusEvents bool) (int32, llm.Generation, pb.ErrorCode) {
+	events, err := sse.NewWriter(w, g.writeTimeout)
+	if err != nil {
 		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
-		return
+		return 0, llm.Generation{}, pb.ErrorCode_ERROR_CODE_INTERNAL
+	}
+	w.Header().Set("Trailer", strings.Join([]string{ServedByHeader, TTFTHeader, TPSHeader, QueueWaitHeader}, ", "))
+
+	done := make(chan struct{})
+	defer close(done)
+	results := receive(stream, done)
+
+	var keepAlive <-chan time.Time
+	if g.keepAlive > 0 {
+		ticker := time.NewTicker(g.keepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+	lastWrite := time.Now()
+
+	var promptTokens int32
+	// slowClient gives up on a client that stopped accepting events; the
+	// caller cancels the upstream call as for a disconnect
+	slowClient := func() (int32, llm.Generation, pb.ErrorCode) {
+		if ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("Dropped SSE client that stopped reading", map[string]interface{}{
+				"error": events.Err().Error(),
+			})
+		}
+		return promptTokens, llm.Generation{}, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+	}
+
+	var chunker *deltaChunker
+	if g.strictChunks {
+		chunker = newDeltaChunker()
+	}
+	// writeChunk sends a chunk, reporting whether the client accepted it
+	writeChunk := func(chunk map[string]interface{}) bool {
+		g.transforms.Response(r, chunk)
+		data, _ := json.Marshal(chunk)
+		events.Printf("data: %s\n\n", data)
+		return events.Flush() == nil
+	}
+	// closeStream finishes choices left open and ends the stream
+	closeStream := func() {
+		if chunker != nil {
+			if chunk := chunker.close(); chunk != nil && !writeChunk(chunk) {
+				return
+			}
+		}
+		events.Printf("data: [DONE]\n\n")
+		events.Flush()
 	}
 
 	for {
-		resp, err := stream.Recv()
+		var resp *pb.ChatCompletionResponse
+		var err error
+		select {
+		case <-ctx.Done():
+			// Nobody is reading anymore; the caller cancels the upstream call
+			return promptTokens, llm.Generation{}, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+		case <-keepAlive:
+			if time.Since(lastWrite) >= g.keepAlive {
+				events.Printf(": keep-alive\n\n")
+				if events.Flush() != nil {
+					return slowClient()
+				}
+				lastWrite = time.Now()
+			}
+			continue
+		case msg := <-results:
+			resp, err = msg.resp, msg.err
+		}
+		lastWrite = time.Now()
+
+		if err != nil && ctx.Err() != nil {
+			// The call failed because the client went away
+			return promptTokens, llm.Generation{}, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+		}
 		if err != nil {
 			if err == io.EOF || err == context.Canceled {
-				fmt.Fprintf(w, "data: [DONE]\n\n")
-				flusher.Flush()
-				return
+				closeStream()
+				var gen llm.Generation
+				if err == io.EOF {
+					gen = llm.GenerationFromMetadata(stream.Trailer())
+					setGenerationHeaders(w.Header(), gen)
+				}
+				return promptTokens, gen, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
 			}
-			fmt.Fprintf(w, "data: {\"error\":\"%v\"}\n\n", err)
-			flusher.Flush()
-			return
+			code := errcode.FromError(err)
+			data, _ := json.Marshal(errorBody(code, errorMessage(err)))
+			events.Printf("data: %s\n\n", data)
+			events.Flush()
+			return promptTokens, llm.Generation{}, code
+		}
+		if resp.Status != "" {
+			events.Printf(": warming-up\n\n")
+			if statusEvents {
+				data, _ := json.Marshal(map[string]interface{}{"status": resp.Status, "model": resp.Model})
+				events.Printf("event: status\ndata: %s\n\n", data)
+			}
+			if events.Flush() != nil {
+				return slowClient()
+			}
+			continue
+		}
+		if resp.UsagePromptTokens > promptTokens {
+			promptTokens = resp.UsagePromptTokens
 		}
 
 		// Convert to OpenAI SSE format
-		openaiResp := g.convertChatCompletionResponse(resp)
-		data, _ := json.Marshal(openaiResp)
-		fmt.Fprintf(w, "data: %s\n\n", data)
-		flusher.Flush()
+		var chunks []map[string]interface{}
+		if chunker != nil {
+			chunks = chunker.chunks(resp)
+		} else {
+			chunks = []map[string]interface{}{g.convertChatCompletionResponse(resp)}
+		}
+		for _, chunk := range chunks {
+			if !writeChunk(chunk) {
+				return slowClient()
+			}
+		}
 
 		// Check if finished
 		if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != "" {
-			fmt.Fprintf(w, "data: [DONE]\n\n")
-			flusher.Flush()
-			return
+			closeStream()
+			gen := finishReceived(ctx, stream, results)
+			setGenerationHeaders(w.Header(), gen)
+			return promptTokens, gen, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+		}
+	}
+}
+
+// received is a message or error read from a chat completion stream
+type received struct {
+	resp *pb.ChatCompletionResponse
+	err  error
+}
+
+// receive reads a stream in the background, so the reader can watch for
+// disconnects and keep-alives while waiting. It reads up to StreamReadAhead
+// messages ahead of the reader; past that it stops reading, so gRPC flow
+// control holds back the node rather than the gateway buffering a slow
+// client's reply. It stops after the first error or once done is closed.
+func receive(stream pb.OrchionLLM_ChatCompletionClient, done <-chan struct{}) <-chan received {
+	results := make(chan received, StreamReadAhead)
+	go func() {
+		for {
+			resp, err := stream.Recv()
+			select {
+			case results <- received{resp: resp, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// finishReceived is finishGeneration for a stream read by receive
+func finishReceived(ctx context.Context, stream pb.OrchionLLM_ChatCompletionClient, results <-chan received) llm.Generation {
+	for {
+		select {
+		case <-ctx.Done():
+			return llm.Generation{}
+		case msg := <-results:
+			if msg.err == nil {
+				continue
+			}
+			if msg.err != io.EOF {
+				return llm.Generation{}
+			}
+			return llm.GenerationFromMetadata(stream.Trailer())
 		}
 	}
 }
 
-// sendNonStreamingResponse sends a single response
-func (g *Gateway) sendNonStreamingResponse(w http.ResponseWriter, stream pb.OrchionLLM_ChatCompletionClient) {
+// sendNonStreamingResponse sends a single response. It returns the prompt
+// tokens reported by the engine, the node's speed and the error code of a
+// failed request.
+func (g *Gateway) sendNonStreamingResponse(w http.ResponseWriter, r *http.Request, stream pb.OrchionLLM_ChatCompletionClient) (int32, llm.Generation, pb.ErrorCode) {
 	resp, err := stream.Recv()
+	for err == nil && resp.Status != "" {
+		resp, err = stream.Recv()
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to receive response: %v", err), http.StatusInternalServerError)
-		return
+		g.writeGRPCError(w, err)
+		return 0, llm.Generation{}, errcode.FromError(err)
 	}
 
+	gen := finishGeneration(stream)
+	setGenerationHeaders(w.Header(), gen)
 	openaiResp := g.convertChatCompletionResponse(resp)
+	g.transforms.Response(r, openaiResp)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(openaiResp)
+	return resp.UsagePromptTokens, gen, pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+}
+
+// finishGeneration reads a stream whose reply is complete to its end and
+// returns the node's speed from the trailer
+func finishGeneration(stream pb.OrchionLLM_ChatCompletionClient) llm.Generation {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err != io.EOF {
+				return llm.Generation{}
+			}
+			return llm.GenerationFromMetadata(stream.Trailer())
+		}
+	}
+}
+
+// setGenerationHeaders reports the node's speed in response headers
+func setGenerationHeaders(h http.Header, gen llm.Generation) {
+	if gen.Node == "" {
+		return
+	}
+	h.Set(ServedByHeader, gen.Node)
+	h.Set(TTFTHeader, strconv.FormatInt(gen.TTFT.Milliseconds(), 10))
+	if gen.TokensPerSecond > 0 {
+		h.Set(TPSHeader, strconv.FormatFloat(gen.TokensPerSecond, 'f', 1, 64))
+	}
 }
 
 // convertChatCompletionResponse converts gRPC response to OpenAI format
@@ -309,18 +989,19 @@ func (g *Gateway) convertChatCompletionResponse(resp *pb.ChatCompletionResponse)
 			"index": choice.Index,
 		}
 
+		message := map[string]interface{}{
+			"role":    choice.GetMessage().GetRole(),
+			"content": choice.GetMessage().GetContent(),
+		}
+		if calls := choice.GetMessage().GetToolCalls(); len(calls) > 0 {
+			message["tool_calls"] = convertToolCalls(calls, resp.Object == "chat.completion.chunk")
+		}
 		if resp.Object == "chat.completion.chunk" {
 			// Streaming format
-			choiceMap["delta"] = map[string]interface{}{
-				"role":    choice.Message.Role,
-				"content": choice.Message.Content,
-			}
+			choiceMap["delta"] = message
 		} else {
 			// Non-streaming format
-			choiceMap["message"] = map[string]interface{}{
-				"role":    choice.Message.Role,
-				"content": choice.Message.Content,
-			}
+			choiceMap["message"] = message
 		}
 
 		if choice.FinishReason != "" {
@@ -330,13 +1011,68 @@ func (g *Gateway) convertChatCompletionResponse(resp *pb.ChatCompletionResponse)
 		choices[i] = choiceMap
 	}
 
-	return map[string]interface{}{
+	openaiResp := map[string]interface{}{
 		"id":      resp.Id,
 		"object":  resp.Object,
 		"created": resp.Created,
 		"model":   resp.Model,
 		"choices": choices,
 	}
+
+	if usage := convertUsage(resp); usage != nil {
+		openaiResp["usage"] = usage
+	}
+
+	return openaiResp
+}
+
+// convertUsage converts a response's token usage to OpenAI format, or returns
+// nil if the response doesn't report any
+func convertUsage(resp *pb.ChatCompletionResponse) map[string]interface{} {
+	if resp.UsagePromptTokens == 0 && resp.UsageCompletionTokens == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"prompt_tokens":     resp.UsagePromptTokens,
+		"completion_tokens": resp.UsageCompletionTokens,
+		"total_tokens":      resp.UsagePromptTokens + resp.UsageCompletionTokens,
+		"prompt_tokens_details": map[string]interface{}{
+			"cached_tokens": resp.UsageCachedTokens,
+		},
+	}
+}
+
+// convertToolCalls converts tool calls to OpenAI format. Chunks carry
+// fragments, which OpenAI identifies by index; the ID and function name come
+// with a call's first fragment only.
+func convertToolCalls(calls []*pb.ToolCall, chunk bool) []map[string]interface{} {
+	converted := make([]map[string]interface{}, len(calls))
+	for i, call := range calls {
+		function := map[string]interface{}{"arguments": call.Arguments}
+		c := map[string]interface{}{"function": function}
+		if chunk {
+			c["index"] = call.Index
+		}
+		if call.Id != "" {
+			c["id"] = call.Id
+			c["type"] = "function"
+		}
+		if call.Name != "" {
+			function["name"] = call.Name
+		}
+		converted[i] = c
+	}
+	return converted
+}
+
+// cacheSalt derives the engine prefix cache salt for an API key, so cached
+// prompts are only reused by callers using the same key
+func cacheSalt(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:16])
 }
 
 // convertEmbeddingResponse converts gRPC response to OpenAI format
@@ -1,13 +1,26 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
+	"github.com/Orchion/Orchion/orchestrator/internal/usage"
 )
 
 func TestNewGateway(t *testing.T) {
@@ -60,6 +73,186 @@ func TestGateway_authenticate(t *testing.T) {
 	assert.True(t, gateway.authenticate(req))
 }
 
+func TestGateway_adminKeysAuthenticate(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetAPIKey("user-key")
+	gateway.SetAdminKeys([]string{"admin-key", ""})
+
+	for key, expected := range map[string]bool{"user-key": true, "admin-key": true, "other": false} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		assert.Equal(t, expected, gateway.authenticate(req), key)
+	}
+}
+
+func TestGateway_authorizer(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	authz := auth.NewAuthorizer("operator-key", []string{"admin-key"})
+	authz.SetKey("viewer-key", auth.RoleViewer)
+	gateway.SetAuthorizer(authz)
+
+	request := func(key string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set(TargetNodeHeader, "node-1")
+		return req
+	}
+	for key, want := range map[string]int{"operator-key": http.StatusOK, "admin-key": http.StatusOK, "viewer-key": http.StatusForbidden, "other": http.StatusUnauthorized} {
+		w := httptest.NewRecorder()
+		if gateway.authorize(w, request(key)) {
+			w.WriteHeader(http.StatusOK)
+		}
+		assert.Equal(t, want, w.Code, key)
+	}
+
+	_, ok := gateway.targetContext(request("operator-key"))
+	assert.False(t, ok, "only admin keys may target nodes")
+	_, ok = gateway.targetContext(request("admin-key"))
+	assert.True(t, ok)
+
+	// Keys managed at /api/admin/keys apply right away
+	authz.SetKey("new-key-0123456789", auth.RoleOperator)
+	assert.True(t, gateway.authenticate(request("new-key-0123456789")))
+	require.NoError(t, authz.RemoveKey(auth.KeyID("operator-key")))
+	assert.False(t, gateway.authenticate(request("operator-key")))
+}
+
+func TestGateway_targetContext(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetAPIKey("user-key")
+	gateway.SetAdminKeys([]string{"admin-key"})
+
+	newRequest := func(key, nodeID string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		if nodeID != "" {
+			req.Header.Set(TargetNodeHeader, nodeID)
+		}
+		return req
+	}
+
+	t.Run("no target", func(t *testing.T) {
+		ctx, ok := gateway.targetContext(newRequest("user-key", ""))
+		require.True(t, ok)
+		_, hasMD := metadata.FromOutgoingContext(ctx)
+		assert.False(t, hasMD)
+	})
+
+	t.Run("admin key targets node", func(t *testing.T) {
+		ctx, ok := gateway.targetContext(newRequest("admin-key", "node-x"))
+		require.True(t, ok)
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Equal(t, []string{"node-x"}, md.Get(llm.TargetNodeMetadata))
+	})
+
+	t.Run("regular key may not target", func(t *testing.T) {
+		_, ok := gateway.targetContext(newRequest("user-key", "node-x"))
+		assert.False(t, ok)
+	})
+}
+
+func TestGateway_targetNodeForbidden(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetAPIKey("user-key")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"m","input":"hi"}`))
+	req.Header.Set("Authorization", "Bearer user-key")
+	req.Header.Set(TargetNodeHeader, "node-x")
+	rec := httptest.NewRecorder()
+	gateway.EmbeddingsHandler(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "permission_denied", body["error"]["code"])
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int32
+		ok   bool
+	}{
+		{"", PriorityNormal, true},
+		{"normal", PriorityNormal, true},
+		{"High", PriorityHigh, true},
+		{" low ", PriorityLow, true},
+		{"5", 5, true},
+		{"-3", -3, true},
+		{"urgent", 0, false},
+		{"99999999999", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parsePriority(tt.in)
+			if !tt.ok {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGateway_priorityHeader(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetAPIKey("user-key")
+	gateway.SetAdminKeys([]string{"admin-key"})
+
+	tests := []struct {
+		name     string
+		key      string
+		priority string
+		code     int
+	}{
+		{"regular key may not raise", "user-key", "high", http.StatusForbidden},
+		{"invalid priority", "admin-key", "urgent", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			req.Header.Set(PriorityHeader, tt.priority)
+			rec := httptest.NewRecorder()
+			gateway.ChatCompletionsHandler(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestGateway_usageAndQuota(t *testing.T) {
+	// Nothing listens here, so admitted requests fail with node_unavailable
+	gateway := NewGateway("127.0.0.1:1")
+	tracker := usage.NewTracker(usage.Quota{RequestsPerMinute: 1})
+	gateway.SetUsageTracker(tracker)
+	gateway.SetAPIKey("secret")
+
+	send := func(user string) *httptest.ResponseRecorder {
+		body := `{"model":"m","input":"hi","user":"` + user + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		gateway.EmbeddingsHandler(rec, req)
+		return rec
+	}
+
+	assert.NotEqual(t, http.StatusTooManyRequests, send("alice").Code)
+
+	rec := send("alice")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "quota_exceeded", body["error"]["code"])
+
+	assert.NotEqual(t, http.StatusTooManyRequests, send("bob").Code)
+
+	usages := tracker.Usage()
+	require.Len(t, usages, 2)
+	assert.Equal(t, usage.UserUsage{User: "alice", Requests: 1, Errors: 1, Rejected: 1}, usages[0])
+	assert.Equal(t, "bob", usages[1].User)
+}
+
 func TestGateway_convertChatCompletionRequest(t *testing.T) {
 	gateway := NewGateway("localhost:8080")
 
@@ -72,6 +265,7 @@ func TestGateway_convertChatCompletionRequest(t *testing.T) {
 		"temperature": 0.7,
 		"stream":     true,
 		"max_tokens": 100.0,
+		"prompt_cache_key": "conversation-42",
 	}
 
 	grpcReq, err := gateway.convertChatCompletionRequest(reqData)
@@ -83,6 +277,58 @@ func TestGateway_convertChatCompletionRequest(t *testing.T) {
 	assert.Equal(t, float32(0.7), grpcReq.Temperature)
 	assert.True(t, grpcReq.Stream)
 	assert.Equal(t, int32(100), grpcReq.MaxTokens)
+	assert.Equal(t, "conversation-42", grpcReq.PromptCacheKey)
+	assert.Empty(t, grpcReq.User)
+
+	// Test end-user identifier
+	reqData["user"] = " user-123 "
+	grpcReq, err = gateway.convertChatCompletionRequest(reqData)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", grpcReq.User)
+
+	reqData["user"] = 123.0
+	_, err = gateway.convertChatCompletionRequest(reqData)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user must be a string")
+
+	// Test sampling parameters
+	delete(reqData, "user")
+	assert.Empty(t, grpcReq.Stop)
+	assert.Nil(t, grpcReq.Seed)
+	reqData["stop"] = []interface{}{"\n\n", "User:"}
+	reqData["top_p"] = 0.9
+	reqData["top_k"] = 40.0
+	reqData["repetition_penalty"] = 1.1
+	reqData["seed"] = 0.0
+	grpcReq, err = gateway.convertChatCompletionRequest(reqData)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"\n\n", "User:"}, grpcReq.Stop)
+	assert.Equal(t, float32(0.9), grpcReq.TopP)
+	assert.Equal(t, int32(40), grpcReq.TopK)
+	assert.Equal(t, float32(1.1), grpcReq.RepetitionPenalty)
+	require.NotNil(t, grpcReq.Seed)
+	assert.Equal(t, int64(0), *grpcReq.Seed)
+
+	reqData["stop"] = "END"
+	grpcReq, err = gateway.convertChatCompletionRequest(reqData)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"END"}, grpcReq.Stop)
+
+	reqData["stop"] = 42.0
+	_, err = gateway.convertChatCompletionRequest(reqData)
+	assert.Error(t, err)
+	delete(reqData, "stop")
+
+	// Test engine options
+	reqData["orchion"] = map[string]interface{}{"num_ctx": 8192.0, "keep_alive": "10m", "stop": []interface{}{"User:"}}
+	grpcReq, err = gateway.convertChatCompletionRequest(reqData)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"num_ctx": "8192", "keep_alive": `"10m"`, "stop": `["User:"]`}, grpcReq.EngineOptions)
+
+	reqData["orchion"] = "fast"
+	_, err = gateway.convertChatCompletionRequest(reqData)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "orchion must be an object")
 
 	// Test missing model
 	badReq := map[string]interface{}{
@@ -138,6 +384,12 @@ func TestGateway_convertEmbeddingRequest(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string{"Hello", "world"}, grpcReq2.Input)
 
+	// Test end-user identifier
+	reqData2["user"] = "user-123"
+	grpcReq2, err = gateway.convertEmbeddingRequest(reqData2)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", grpcReq2.User)
+
 	// Test missing model
 	badReq := map[string]interface{}{
 		"input": "Hello world",
@@ -248,4 +500,285 @@ func TestGateway_convertEmbeddingResponse(t *testing.T) {
 
 // Note: These tests would require more complex mocking of gRPC clients
 // For now, we'll test the basic structure and conversion functions
-// Full HTTP handler tests would require integration with a test gRPC server
\ No newline at end of file
+// Full HTTP handler tests would require integration with a test gRPC server
+func Test_cacheSalt(t *testing.T) {
+	assert.Empty(t, cacheSalt(""))
+	assert.Len(t, cacheSalt("key-a"), 32)
+	assert.Equal(t, cacheSalt("key-a"), cacheSalt("key-a"))
+	assert.NotEqual(t, cacheSalt("key-a"), cacheSalt("key-b"))
+}
+
+func TestGateway_convertChatCompletionResponseUsage(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+
+	resp := gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Object:                "chat.completion",
+		UsagePromptTokens:     120,
+		UsageCompletionTokens: 30,
+		UsageCachedTokens:     96,
+	})
+	assert.Equal(t, map[string]interface{}{
+		"prompt_tokens":         int32(120),
+		"completion_tokens":     int32(30),
+		"total_tokens":          int32(150),
+		"prompt_tokens_details": map[string]interface{}{"cached_tokens": int32(96)},
+	}, resp["usage"])
+
+	// Usage is omitted when the engine didn't report it
+	resp = gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{Object: "chat.completion"})
+	assert.NotContains(t, resp, "usage")
+}
+
+func TestGateway_convertChatCompletionResponseToolCalls(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+
+	resp := gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Object: "chat.completion",
+		Choices: []*pb.ChatChoice{{
+			Message: &pb.ChatMessage{Role: "assistant", ToolCalls: []*pb.ToolCall{
+				{Id: "call_1", Name: "get_weather", Arguments: `{"city":"Oslo"}`},
+			}},
+			FinishReason: "tool_calls",
+		}},
+	})
+	choice := resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, []map[string]interface{}{{
+		"id":       "call_1",
+		"type":     "function",
+		"function": map[string]interface{}{"name": "get_weather", "arguments": `{"city":"Oslo"}`},
+	}}, choice["message"].(map[string]interface{})["tool_calls"])
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+
+	// Later fragments of a streamed call carry only its index and arguments
+	resp = gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Object: "chat.completion.chunk",
+		Choices: []*pb.ChatChoice{{
+			Message: &pb.ChatMessage{Role: "assistant", ToolCalls: []*pb.ToolCall{{Index: 1, Arguments: `"Oslo"}`}}},
+		}},
+	})
+	choice = resp["choices"].([]map[string]interface{})[0]
+	assert.Equal(t, []map[string]interface{}{{
+		"index":    int32(1),
+		"function": map[string]interface{}{"arguments": `"Oslo"}`},
+	}}, choice["delta"].(map[string]interface{})["tool_calls"])
+
+	// Choices without a message don't break the conversion
+	resp = gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Object:  "chat.completion.chunk",
+		Choices: []*pb.ChatChoice{{FinishReason: "stop"}},
+	})
+	assert.Len(t, resp["choices"], 1)
+}
+
+// fakeChatClient replays chat responses and a trailer as if streamed by the
+// orchestrator
+type fakeChatClient struct {
+	grpc.ClientStream
+	responses []*pb.ChatCompletionResponse
+	trailer   metadata.MD
+}
+
+func (c *fakeChatClient) Trailer() metadata.MD {
+	return c.trailer
+}
+
+func (c *fakeChatClient) Recv() (*pb.ChatCompletionResponse, error) {
+	if len(c.responses) == 0 {
+		return nil, io.EOF
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func TestGateway_modelLoadingStatus(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	responses := func() []*pb.ChatCompletionResponse {
+		return []*pb.ChatCompletionResponse{
+			{Model: "llama3", Status: llm.ModelLoadingStatus},
+			{Model: "llama3", Status: llm.ModelLoadingStatus},
+			{Model: "llama3", Object: "chat.completion", Choices: []*pb.ChatChoice{
+				{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}, FinishReason: "stop"},
+			}},
+		}
+	}
+
+	t.Run("streams keep-alive comments", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.streamSSE(context.Background(), w, chatRequest(), &fakeChatClient{responses: responses()}, false)
+		body := w.Body.String()
+		assert.Equal(t, 2, strings.Count(body, ": warming-up\n\n"))
+		assert.NotContains(t, body, "event: status")
+		assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+	})
+
+	t.Run("streams status events on request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.streamSSE(context.Background(), w, chatRequest(), &fakeChatClient{responses: responses()}, true)
+		assert.Equal(t, 2, strings.Count(w.Body.String(), "event: status\ndata: {\"model\":\"llama3\",\"status\":\"loading\"}\n\n"))
+	})
+
+	t.Run("non-streaming responses skip status", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.sendNonStreamingResponse(w, chatRequest(), &fakeChatClient{responses: responses()})
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "chat.completion", resp["object"])
+	})
+}
+
+// chatRequest returns a chat completion request for stream tests
+func chatRequest() *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+}
+
+// slowChatClient hands out responses as the test sends them, like a node
+// generating slowly
+type slowChatClient struct {
+	grpc.ClientStream
+	ctx       context.Context
+	responses chan *pb.ChatCompletionResponse
+}
+
+func (c *slowChatClient) Trailer() metadata.MD {
+	return nil
+}
+
+func (c *slowChatClient) Recv() (*pb.ChatCompletionResponse, error) {
+	select {
+	case resp, ok := <-c.responses:
+		if !ok {
+			return nil, io.EOF
+		}
+		return resp, nil
+	case <-c.ctx.Done():
+		return nil, status.Error(codes.Canceled, "context canceled")
+	}
+}
+
+func TestGateway_streamKeepAlive(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetSSEKeepAlive(10 * time.Millisecond)
+
+	stream := &slowChatClient{ctx: context.Background(), responses: make(chan *pb.ChatCompletionResponse)}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		stream.responses <- &pb.ChatCompletionResponse{Object: "chat.completion", Choices: []*pb.ChatChoice{
+			{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}, FinishReason: "stop"},
+		}}
+		close(stream.responses)
+	}()
+
+	w := httptest.NewRecorder()
+	_, _, code := gateway.streamSSE(context.Background(), w, chatRequest(), stream, false)
+	assert.Equal(t, pb.ErrorCode_ERROR_CODE_UNSPECIFIED, code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, ": keep-alive\n\n")
+	assert.True(t, strings.Index(body, ": keep-alive") < strings.Index(body, "data: "))
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
+
+func TestGateway_streamClientDisconnect(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	gateway.SetSSEKeepAlive(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &slowChatClient{ctx: ctx, responses: make(chan *pb.ChatCompletionResponse)}
+	go func() {
+		stream.responses <- &pb.ChatCompletionResponse{Object: "chat.completion", Choices: []*pb.ChatChoice{
+			{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}},
+		}}
+		// The client goes away mid-generation
+		cancel()
+	}()
+
+	done := make(chan struct{})
+	w := httptest.NewRecorder()
+	go func() {
+		gateway.streamSSE(ctx, w, chatRequest(), stream, false)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream should end when the client disconnects")
+	}
+	body := w.Body.String()
+	assert.NotContains(t, body, "[DONE]")
+	assert.NotContains(t, body, "error")
+}
+
+func TestGateway_generationHeaders(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	trailer := llm.Generation{Node: "node-1", TTFT: 850 * time.Millisecond, TokensPerSecond: 31.25}.Metadata()
+	reply := func(finish string) []*pb.ChatCompletionResponse {
+		return []*pb.ChatCompletionResponse{{Object: "chat.completion", Choices: []*pb.ChatChoice{
+			{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}, FinishReason: finish},
+		}}}
+	}
+
+	t.Run("non-streaming responses carry headers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, gen, _ := gateway.sendNonStreamingResponse(w, chatRequest(), &fakeChatClient{responses: reply("stop"), trailer: trailer})
+		assert.Equal(t, "node-1", gen.Node)
+		assert.Equal(t, "node-1", w.Header().Get(ServedByHeader))
+		assert.Equal(t, "850", w.Header().Get(TTFTHeader))
+		assert.Equal(t, "31.2", w.Header().Get(TPSHeader))
+	})
+
+	t.Run("streams carry trailers", func(t *testing.T) {
+		for _, finish := range []string{"stop", ""} {
+			w := httptest.NewRecorder()
+			_, gen, _ := gateway.streamSSE(context.Background(), w, chatRequest(), &fakeChatClient{responses: reply(finish), trailer: trailer}, false)
+			assert.Equal(t, 850*time.Millisecond, gen.TTFT)
+
+			result := w.Result()
+			assert.Contains(t, result.Header.Get("Trailer"), TTFTHeader)
+			assert.Equal(t, "850", result.Trailer.Get(TTFTHeader))
+		}
+	})
+
+	t.Run("no headers without measurements", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.sendNonStreamingResponse(w, chatRequest(), &fakeChatClient{responses: reply("stop")})
+		assert.Empty(t, w.Header().Get(TTFTHeader))
+	})
+}
+
+func TestGateway_admitQueueHeaders(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+	queue := NewFairQueue(1)
+	queue.SetMaxWaiting(1)
+	gateway.SetFairQueue(queue)
+
+	// Slots were released every 2 seconds lately
+	queue.intervals = []time.Duration{2 * time.Second}
+
+	release, ok := gateway.admit(httptest.NewRecorder(), chatRequest())
+	require.True(t, ok)
+
+	queued := httptest.NewRecorder()
+	admitted := make(chan bool)
+	go func() {
+		release, ok := gateway.admit(queued, chatRequest())
+		if ok {
+			release()
+		}
+		admitted <- ok
+	}()
+	require.Eventually(t, func() bool { return queue.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	t.Run("rejected once the queue is full", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, ok := gateway.admit(w, chatRequest())
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "4", w.Header().Get("Retry-After"))
+	})
+
+	release()
+	require.True(t, <-admitted)
+	assert.Equal(t, "2", queued.Header().Get(QueueWaitHeader))
+}
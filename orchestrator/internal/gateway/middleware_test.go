@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+func TestCORS(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), CORS("GET, OPTIONS", "Content-Type"))
+
+	w := serve(h, http.MethodOptions, "/api/nodes")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = serve(h, http.MethodGet, "/api/nodes")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestLogRequests(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.NewLogger(logging.Config{Level: logging.DebugLevel})
+	logger.SetOutput(&out)
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Streamed responses keep streaming
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		w.WriteHeader(http.StatusAccepted)
+	}), LogRequests(logger))
+
+	assert.Equal(t, http.StatusAccepted, serve(h, http.MethodGet, "/api/logs").Code)
+	assert.Contains(t, out.String(), `"path":"/api/logs"`)
+	assert.Contains(t, out.String(), `"status":202`)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(1, 2)
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A burst, then one request per second
+	assert.Equal(t, http.StatusOK, request("key-a"))
+	assert.Equal(t, http.StatusOK, request("key-a"))
+	assert.Equal(t, http.StatusTooManyRequests, request("key-a"))
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, request("key-a"))
+	assert.Equal(t, http.StatusTooManyRequests, request("key-a"))
+
+	// Other clients have their own budget
+	assert.Equal(t, http.StatusOK, request("key-b"))
+	assert.Equal(t, http.StatusOK, request(""))
+
+	// Idle clients are dropped once their budget is full again
+	now = now.Add(time.Hour)
+	assert.True(t, limiter.Allow("key-c"))
+	assert.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// ModelsHandler handles /v1/models, listing the models loaded on online nodes
+// along with the engine builds serving them
+func (g *Gateway) ModelsHandler(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !g.authorize(w, r) {
+		return
+	}
+
+	conn, err := g.dial()
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INTERNAL, fmt.Sprintf("Failed to connect to orchestrator: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewOrchestratorClient(conn).ListNodes(r.Context(), &pb.ListNodesRequest{Status: "online"})
+	if err != nil {
+		g.writeGRPCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.convertModelList(resp.Nodes))
+}
+
+// convertModelList converts the models loaded on nodes to an OpenAI model
+// list. Each model carries an "engines" entry per node serving it.
+func (g *Gateway) convertModelList(nodes []*pb.Node) map[string]interface{} {
+	engines := make(map[string][]map[string]interface{})
+	for _, n := range nodes {
+		for _, m := range n.Models {
+			engines[m.Model] = append(engines[m.Model], map[string]interface{}{
+				"node_id":      n.Id,
+				"engine":       m.Engine,
+				"image":        m.Image,
+				"image_digest": m.ImageDigest,
+				"version":      m.Version,
+			})
+		}
+	}
+
+	ids := make([]string, 0, len(engines))
+	for id := range engines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		data[i] = map[string]interface{}{
+			"id":       id,
+			"object":   "model",
+			"created":  0,
+			"owned_by": "orchion",
+			"engines":  engines[id],
+		}
+	}
+
+	return map[string]interface{}{
+		"object": "list",
+		"data":   data,
+	}
+}
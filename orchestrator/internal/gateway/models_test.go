@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestGateway_convertModelList(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+
+	nodes := []*pb.Node{
+		{Id: "node-1", Models: []*pb.ModelEngine{
+			{Model: "llama3", Engine: "ollama", Image: "ollama/ollama:latest", ImageDigest: "sha256:aaa", Version: "0.3.12"},
+		}},
+		{Id: "node-2", Models: []*pb.ModelEngine{
+			{Model: "mistralai/Mistral-7B-v0.1", Engine: "vllm", Image: "vllm/vllm-openai:latest", ImageDigest: "sha256:bbb", Version: "0.6.3"},
+			{Model: "llama3", Engine: "ollama", Image: "ollama/ollama:latest", ImageDigest: "sha256:ccc", Version: "0.3.10"},
+		}},
+		{Id: "node-3"},
+	}
+
+	resp := gateway.convertModelList(nodes)
+	assert.Equal(t, "list", resp["object"])
+
+	data, ok := resp["data"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, data, 2)
+
+	assert.Equal(t, "llama3", data[0]["id"])
+	assert.Equal(t, "model", data[0]["object"])
+	engines, ok := data[0]["engines"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, engines, 2)
+	assert.Equal(t, "node-1", engines[0]["node_id"])
+	assert.Equal(t, "0.3.12", engines[0]["version"])
+	assert.Equal(t, "sha256:ccc", engines[1]["image_digest"])
+
+	assert.Equal(t, "mistralai/Mistral-7B-v0.1", data[1]["id"])
+}
+
+func TestGateway_convertModelListEmpty(t *testing.T) {
+	resp := NewGateway("localhost:8080").convertModelList(nil)
+
+	data, ok := resp["data"].([]map[string]interface{})
+	require.True(t, ok)
+	assert.Empty(t, data)
+}
@@ -0,0 +1,316 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/openapi"
+)
+
+// The types below describe the OpenAI-compatible bodies the gateway reads and
+// writes, for the OpenAPI document. Tests check them against the converted
+// responses.
+
+// stringOrStrings is a field accepting a string or an array of strings
+type stringOrStrings []string
+
+// OpenAPISchema implements openapi.Describer
+func (stringOrStrings) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{OneOf: []*openapi.Schema{{Type: "string"}, openapi.ArrayOf(&openapi.Schema{Type: "string"})}}
+}
+
+// chatCompletionRequest is the body of /v1/chat/completions
+type chatCompletionRequest struct {
+	Model             string                 `json:"model"`
+	Messages          []chatMessage          `json:"messages"`
+	Stream            bool                   `json:"stream,omitempty"`
+	Temperature       float64                `json:"temperature,omitempty"`
+	MaxTokens         int32                  `json:"max_tokens,omitempty"`
+	Stop              stringOrStrings        `json:"stop,omitempty"`
+	TopP              float64                `json:"top_p,omitempty"`
+	TopK              int32                  `json:"top_k,omitempty"`
+	RepetitionPenalty float64                `json:"repetition_penalty,omitempty"`
+	Seed              int64                  `json:"seed,omitempty"`
+	PromptCacheKey    string                 `json:"prompt_cache_key,omitempty"` // Stable prompt prefix identifier for prefix-cache routing
+	User              string                 `json:"user,omitempty"`             // End user, for usage tracking and quotas
+	Orchion           map[string]interface{} `json:"orchion,omitempty"`          // Engine-specific options, e.g. {"num_ctx": 8192}
+}
+
+// chatMessage is a message of a chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatCompletion is a chat completion, or a chunk of one when streamed
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"` // chat.completion or chat.completion.chunk
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+// chatChoice is a choice of a chat completion. Chunks carry a delta instead
+// of a message.
+type chatChoice struct {
+	Index        int32         `json:"index"`
+	Message      *replyMessage `json:"message,omitempty"`
+	Delta        *replyMessage `json:"delta,omitempty"`
+	FinishReason string        `json:"finish_reason,omitempty"`
+}
+
+// replyMessage is the message of a choice
+type replyMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+// toolCall is a tool call of a reply. Chunks carry fragments, identified by
+// index; the ID, type and function name come with a call's first fragment.
+type toolCall struct {
+	Index    int32            `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function toolCallFunction `json:"function"`
+}
+
+// toolCallFunction is the function a tool call invokes
+type toolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// chatUsage is the token usage of a chat completion
+type chatUsage struct {
+	PromptTokens        int32 `json:"prompt_tokens"`
+	CompletionTokens    int32 `json:"completion_tokens"`
+	TotalTokens         int32 `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int32 `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// embeddingRequest is the body of /v1/embeddings
+type embeddingRequest struct {
+	Model string          `json:"model"`
+	Input stringOrStrings `json:"input"`
+	User  string          `json:"user,omitempty"`
+}
+
+// embeddingList is the response of /v1/embeddings
+type embeddingList struct {
+	Object string      `json:"object"`
+	Data   []embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  struct {
+		PromptTokens int32 `json:"prompt_tokens"`
+		TotalTokens  int32 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// embedding is the embedding of one input
+type embedding struct {
+	Object    string    `json:"object"`
+	Embedding []float64 `json:"embedding"`
+	Index     int32     `json:"index"`
+}
+
+// modelList is the response of /v1/models
+type modelList struct {
+	Object string  `json:"object"`
+	Data   []model `json:"data"`
+}
+
+// model is a model loaded on online nodes
+type model struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	OwnedBy string        `json:"owned_by"`
+	Engines []modelEngine `json:"engines"`
+}
+
+// modelEngine is the engine build serving a model on a node
+type modelEngine struct {
+	NodeID      string `json:"node_id"`
+	Engine      string `json:"engine"`
+	Image       string `json:"image"`
+	ImageDigest string `json:"image_digest"`
+	Version     string `json:"version"`
+}
+
+// speechRequest is the body of /v1/audio/speech
+type speechRequest struct {
+	Model          string       `json:"model"`
+	Input          string       `json:"input"`
+	Voice          string       `json:"voice,omitempty"` // Speaker of multi-speaker models; OpenAI voice names pick the default speaker
+	ResponseFormat speechFormat `json:"response_format,omitempty"`
+	Speed          float64      `json:"speed,omitempty"` // 0.25 to 4
+	User           string       `json:"user,omitempty"`
+}
+
+// speechFormat is the audio format of a speech response
+type speechFormat string
+
+// OpenAPISchema implements openapi.Describer
+func (speechFormat) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Enum: SpeechFormats, Description: "Audio format, " + DefaultSpeechFormat + " by default"}
+}
+
+// errorResponse is an OpenAI-style error
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+// DescribeAPI adds the OpenAI-compatible API to an OpenAPI document
+func DescribeAPI(doc *openapi.Document) {
+	doc.AddTag("openai", "OpenAI-compatible inference API")
+	errors := errorResponses(doc)
+	targetNode := openapi.HeaderParam(TargetNodeHeader, "Run the request on this node (admin keys only)")
+
+	chatResponses := withResponse(errors, "200", &openapi.Response{
+		Description: "The completion, or with stream set, Server-Sent Events carrying chunks of it and a final [DONE]. " +
+			"While the model loads, \": warming-up\" comments are sent, and status events if " + StatusEventsHeader + " is set.",
+		Headers: generationHeaders(),
+		Content: map[string]openapi.MediaType{
+			"application/json":  {Schema: doc.SchemaOf(chatCompletion{})},
+			"text/event-stream": {Schema: &openapi.Schema{Type: "string"}},
+		},
+	})
+	doc.Add(http.MethodPost, "/v1/chat/completions", &openapi.Operation{
+		Summary:     "Create a chat completion",
+		OperationID: "createChatCompletion",
+		Tags:        []string{"openai"},
+		Parameters: []*openapi.Parameter{
+			targetNode,
+			openapi.HeaderParam(PriorityHeader, fmt.Sprintf("Admission priority, %d to %d; above %d needs an admin key", PriorityLow, PriorityHigh, PriorityNormal)),
+			openapi.HeaderParam(StatusEventsHeader, "Send status events while the model loads"),
+			openapi.HeaderParam("X-Prompt-Cache-Key", "Stable prompt prefix identifier, if not given in the body"),
+		},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(chatCompletionRequest{})),
+		Responses:   chatResponses,
+	})
+
+	doc.Add(http.MethodPost, "/v1/embeddings", &openapi.Operation{
+		Summary:     "Create embeddings",
+		OperationID: "createEmbeddings",
+		Tags:        []string{"openai"},
+		Parameters:  []*openapi.Parameter{targetNode},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(embeddingRequest{})),
+		Responses:   withResponse(errors, "200", openapi.JSONResponse("An embedding per input, in input order", doc.SchemaOf(embeddingList{}))),
+	})
+
+	audio := make(map[string]openapi.MediaType, len(SpeechFormats))
+	for _, format := range SpeechFormats {
+		audio[speechContentTypes[format]] = openapi.MediaType{Schema: &openapi.Schema{Type: "string", Format: "binary"}}
+	}
+	doc.Add(http.MethodPost, "/v1/audio/speech", &openapi.Operation{
+		Summary:     "Read text aloud",
+		Description: fmt.Sprintf("Synthesizes speech from up to %d characters with a text-to-speech model, e.g. piper/en_US-lessac-medium.", MaxSpeechInput),
+		OperationID: "createSpeech",
+		Tags:        []string{"openai"},
+		Parameters:  []*openapi.Parameter{targetNode},
+		RequestBody: openapi.JSONBody(doc.SchemaOf(speechRequest{})),
+		Responses:   withResponse(errors, "200", &openapi.Response{Description: "The audio, in the requested format", Content: audio}),
+	})
+
+	upload := &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"file":    {Type: "string", Format: "binary"},
+			"purpose": {Type: "string", Description: "What the file is for, e.g. user_data"},
+		},
+		Required: []string{"file", "purpose"},
+	}
+	file := doc.SchemaOf(files.File{})
+	fileID := openapi.PathParam("id", "File ID")
+	doc.Add(http.MethodPost, "/v1/files", &openapi.Operation{
+		Summary:     "Upload a file",
+		Description: "Stores a file, e.g. a job payload, which jobs then name with payload_file_id rather than carrying it.",
+		OperationID: "createFile",
+		Tags:        []string{"openai"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{"multipart/form-data": {Schema: upload}}},
+		Responses:   withResponse(errors, "200", openapi.JSONResponse("The stored file", file)),
+	})
+	doc.Add(http.MethodGet, "/v1/files/{id}", &openapi.Operation{
+		Summary:     "Get a file's description",
+		OperationID: "retrieveFile",
+		Tags:        []string{"openai"},
+		Parameters:  []*openapi.Parameter{fileID},
+		Responses:   withResponse(errors, "200", openapi.JSONResponse("The file", file)),
+	})
+	doc.Add(http.MethodDelete, "/v1/files/{id}", &openapi.Operation{
+		Summary:     "Delete a file",
+		OperationID: "deleteFile",
+		Tags:        []string{"openai"},
+		Parameters:  []*openapi.Parameter{fileID},
+		Responses:   withResponse(errors, "200", openapi.JSONResponse("The deleted file", doc.SchemaOf(files.DeletedFile{}))),
+	})
+	doc.Add(http.MethodGet, "/v1/files/{id}/content", &openapi.Operation{
+		Summary:     "Download a file",
+		OperationID: "downloadFile",
+		Tags:        []string{"openai"},
+		Parameters:  []*openapi.Parameter{fileID},
+		Responses: withResponse(errors, "200", &openapi.Response{
+			Description: "The content, in the media type it was uploaded with",
+			Content:     map[string]openapi.MediaType{"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+		}),
+	})
+
+	doc.Add(http.MethodGet, "/v1/models", &openapi.Operation{
+		Summary:     "List the models loaded on online nodes",
+		OperationID: "listModels",
+		Tags:        []string{"openai"},
+		Responses:   withResponse(errors, "200", openapi.JSONResponse("Models and the engine builds serving them", doc.SchemaOf(modelList{}))),
+	})
+}
+
+// errorResponses describes the error responses of the OpenAI-compatible API,
+// by HTTP status, from the error codes the gateway maps to it
+func errorResponses(doc *openapi.Document) map[string]*openapi.Response {
+	codes := make(map[int][]string)
+	for _, e := range openAIErrors {
+		codes[e.status] = append(codes[e.status], e.code)
+	}
+
+	responses := make(map[string]*openapi.Response, len(codes))
+	for status, c := range codes {
+		sort.Strings(c)
+		responses[strconv.Itoa(status)] = openapi.JSONResponse(
+			fmt.Sprintf("%s (%s)", http.StatusText(status), strings.Join(c, ", ")),
+			doc.SchemaOf(errorResponse{}),
+		)
+	}
+	return responses
+}
+
+// withResponse returns a copy of responses with another one added
+func withResponse(responses map[string]*openapi.Response, status string, resp *openapi.Response) map[string]*openapi.Response {
+	out := make(map[string]*openapi.Response, len(responses)+1)
+	for s, r := range responses {
+		out[s] = r
+	}
+	out[status] = resp
+	return out
+}
+
+// generationHeaders describes the headers reporting the node's speed, sent
+// as trailers when streaming
+func generationHeaders() map[string]*openapi.Header {
+	return map[string]*openapi.Header{
+		ServedByHeader: {Description: "Node that served the request", Schema: &openapi.Schema{Type: "string"}},
+		TTFTHeader:     {Description: "Time to first token in milliseconds", Schema: &openapi.Schema{Type: "integer"}},
+		TPSHeader:      {Description: "Generated tokens per second", Schema: &openapi.Schema{Type: "number"}},
+	}
+}
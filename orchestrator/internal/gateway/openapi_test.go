@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/openapi"
+)
+
+// decodeStrict encodes v and decodes it into out, failing on fields out
+// doesn't declare, so the documented types can't drift from the responses
+func decodeStrict(t *testing.T, v interface{}, out interface{}) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(out), string(data))
+}
+
+func TestDescribedTypesMatchResponses(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+
+	t.Run("chat completion", func(t *testing.T) {
+		resp := &pb.ChatCompletionResponse{
+			Id: "chatcmpl-1", Object: "chat.completion", Created: 1700000000, Model: "llama3",
+			Choices: []*pb.ChatChoice{{
+				Message: &pb.ChatMessage{Role: "assistant", Content: "Hi", ToolCalls: []*pb.ToolCall{
+					{Id: "call_1", Name: "get_weather", Arguments: `{"city":"Oslo"}`},
+				}},
+				FinishReason: "tool_calls",
+			}},
+			UsagePromptTokens: 10, UsageCompletionTokens: 5, UsageCachedTokens: 4,
+		}
+		var out chatCompletion
+		decodeStrict(t, gateway.convertChatCompletionResponse(resp), &out)
+		require.Len(t, out.Choices, 1)
+		require.NotNil(t, out.Choices[0].Message)
+		assert.Equal(t, "get_weather", out.Choices[0].Message.ToolCalls[0].Function.Name)
+		assert.Equal(t, int32(4), out.Usage.PromptTokensDetails.CachedTokens)
+	})
+
+	t.Run("chat completion chunk", func(t *testing.T) {
+		resp := &pb.ChatCompletionResponse{
+			Id: "chatcmpl-1", Object: "chat.completion.chunk", Model: "llama3",
+			Choices: []*pb.ChatChoice{{
+				Message: &pb.ChatMessage{Content: "lo", ToolCalls: []*pb.ToolCall{{Index: 1, Arguments: `"}`}}},
+			}},
+		}
+		var out chatCompletion
+		decodeStrict(t, gateway.convertChatCompletionResponse(resp), &out)
+		require.NotNil(t, out.Choices[0].Delta)
+		assert.Equal(t, int32(1), out.Choices[0].Delta.ToolCalls[0].Index)
+	})
+
+	t.Run("embeddings", func(t *testing.T) {
+		resp := &pb.EmbeddingResponse{
+			Object: "list", Model: "nomic-embed-text", UsagePromptTokens: 3,
+			Data: []*pb.Embedding{{Index: 0, Embedding: []float32{0.5, -0.25}}},
+		}
+		var out embeddingList
+		decodeStrict(t, gateway.convertEmbeddingResponse(resp), &out)
+		assert.Equal(t, []float64{0.5, -0.25}, out.Data[0].Embedding)
+	})
+
+	t.Run("models", func(t *testing.T) {
+		nodes := []*pb.Node{{Id: "node-1", Models: []*pb.ModelEngine{
+			{Model: "llama3", Engine: "ollama", Image: "ollama/ollama:latest", ImageDigest: "sha256:aaa", Version: "0.3.12"},
+		}}}
+		var out modelList
+		decodeStrict(t, gateway.convertModelList(nodes), &out)
+		assert.Equal(t, "node-1", out.Data[0].Engines[0].NodeID)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var out errorResponse
+		decodeStrict(t, errorBody(pb.ErrorCode_ERROR_CODE_MODEL_NOT_FOUND, "no such model"), &out)
+		assert.Equal(t, "model_not_found", out.Error.Code)
+	})
+}
+
+func TestDescribeAPI(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "Orchion", Version: "test"})
+	DescribeAPI(doc)
+
+	chat := doc.Paths["/v1/chat/completions"]["post"]
+	require.NotNil(t, chat)
+	assert.Contains(t, chat.Responses["200"].Content, "text/event-stream")
+	assert.Contains(t, chat.Responses["200"].Headers, TTFTHeader)
+	assert.Equal(t, "Not Found (model_not_found)", chat.Responses["404"].Description)
+	assert.Contains(t, doc.Paths["/v1/embeddings"], "post")
+	assert.Contains(t, doc.Paths["/v1/models"], "get")
+	assert.Contains(t, doc.Paths["/v1/audio/speech"]["post"].Responses["200"].Content, "audio/mpeg")
+	assert.Contains(t, doc.Paths["/v1/files"]["post"].RequestBody.Content, "multipart/form-data")
+	assert.Contains(t, doc.Paths["/v1/files/{id}/content"], "get")
+	assert.Contains(t, doc.Paths["/v1/files/{id}"], "delete")
+
+	req := doc.Components.Schemas["ChatCompletionRequest"]
+	require.NotNil(t, req)
+	assert.Equal(t, []string{"model", "messages"}, req.Required)
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Middleware wraps a handler, e.g. to authenticate or log requests
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h in middleware, the first running first
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Router is the orchestrator's HTTP server: it routes requests to the
+// registered handlers through the middleware every request passes. Routes
+// and middleware may be added while it serves.
+type Router struct {
+	mux *http.ServeMux
+
+	mu         sync.Mutex
+	middleware []Middleware
+	patterns   []string
+	handler    atomic.Pointer[http.Handler] // mux wrapped in middleware
+}
+
+// NewRouter creates a router without routes or middleware
+func NewRouter() *Router {
+	r := &Router{mux: http.NewServeMux()}
+	var h http.Handler = r.mux
+	r.handler.Store(&h)
+	return r
+}
+
+// Use adds middleware run on every request, after the middleware added
+// before it
+func (r *Router) Use(middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, middleware...)
+	h := Chain(r.mux, r.middleware...)
+	r.handler.Store(&h)
+}
+
+// Handle routes a ServeMux pattern to h, wrapped in middleware of its own
+func (r *Router) Handle(pattern string, h http.Handler, middleware ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mux.Handle(pattern, Chain(h, middleware...))
+	r.patterns = append(r.patterns, pattern)
+}
+
+// HandleFunc routes a ServeMux pattern to a handler function
+func (r *Router) HandleFunc(pattern string, fn http.HandlerFunc, middleware ...Middleware) {
+	r.Handle(pattern, fn, middleware...)
+}
+
+// HandleTree routes a path and every path below it to h, e.g. /api/jobs and
+// /api/jobs/{id}
+func (r *Router) HandleTree(path string, h http.Handler, middleware ...Middleware) {
+	path = strings.TrimSuffix(path, "/")
+	h = Chain(h, middleware...)
+	r.Handle(path, h)
+	r.Handle(path+"/", h)
+}
+
+// Routes returns the registered patterns, sorted
+func (r *Router) Routes() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	patterns := append([]string{}, r.patterns...)
+	sort.Strings(patterns)
+	return patterns
+}
+
+// ServeHTTP passes a request through the middleware to its route
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	(*r.handler.Load()).ServeHTTP(w, req)
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tag returns middleware appending name to the X-Trace response header
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+
+	router.Use(tag("outer"), tag("inner"))
+	router.HandleFunc("/api/usage", ok, tag("route"))
+	router.HandleTree("/api/jobs/", ok)
+
+	w := serve(router, http.MethodGet, "/api/usage")
+	assert.Equal(t, "/api/usage", w.Body.String())
+	assert.Equal(t, []string{"outer", "inner", "route"}, w.Header().Values("X-Trace"))
+
+	assert.Equal(t, "/api/jobs", serve(router, http.MethodGet, "/api/jobs").Body.String())
+	assert.Equal(t, "/api/jobs/job-1", serve(router, http.MethodGet, "/api/jobs/job-1").Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodGet, "/api/other").Code)
+
+	// Routes and middleware can be added while serving
+	router.Use(tag("late"))
+	router.HandleFunc("/v1/models", ok)
+	w = serve(router, http.MethodGet, "/v1/models")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"outer", "inner", "late"}, w.Header().Values("X-Trace"))
+
+	assert.Equal(t, []string{"/api/jobs", "/api/jobs/", "/api/usage", "/v1/models"}, router.Routes())
+}
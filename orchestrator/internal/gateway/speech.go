@@ -0,0 +1,187 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
+)
+
+// SpeechFormats are the audio formats /v1/audio/speech can answer in, as
+// OpenAI names them
+var SpeechFormats = []string{"mp3", "wav", "flac", "opus", "aac"}
+
+// speechContentTypes are the MIME types of SpeechFormats
+var speechContentTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"wav":  "audio/wav",
+	"flac": "audio/flac",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+}
+
+// DefaultSpeechFormat is the audio format of speech requests naming none, as
+// with OpenAI
+const DefaultSpeechFormat = "mp3"
+
+// MaxSpeechInput is the most characters a speech request may read aloud
+const MaxSpeechInput = 4096
+
+// SpeechHandler handles /v1/audio/speech, answering with the audio itself
+func (g *Gateway) SpeechHandler(w http.ResponseWriter, r *http.Request) {
+	// CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Orchion-Node")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Check authentication if API key is set
+	if !g.authorize(w, r) {
+		return
+	}
+
+	ctx, ok := g.targetContext(r)
+	if !ok {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_PERMISSION_DENIED, TargetNodeHeader+" requires an admin API key")
+		return
+	}
+
+	// Parse OpenAI request
+	var openaiReq map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&openaiReq); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	// Convert to gRPC request
+	grpcReq, err := g.convertSpeechRequest(openaiReq)
+	if err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+
+	if err := g.checkQuota(grpcReq.User); err != nil {
+		g.writeError(w, pb.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED, err.Error())
+		return
+	}
+
+	// Wait for our turn when the cluster is saturated
+	release, ok := g.admit(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	start := time.Now()
+	code := pb.ErrorCode_ERROR_CODE_UNSPECIFIED
+	defer func() {
+		g.recordUsage(r, start, grpcReq.Model, grpcReq.User, 0, llm.Generation{}, code)
+	}()
+
+	// Connect to orchestrator
+	conn, err := g.dial()
+	if err != nil {
+		code = pb.ErrorCode_ERROR_CODE_INTERNAL
+		g.writeError(w, code, fmt.Sprintf("Failed to connect to orchestrator: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	resp, err := pb.NewOrchionLLMClient(conn).Speech(ctx, grpcReq)
+	if err != nil {
+		code = errcode.FromError(err)
+		g.writeGRPCError(w, err)
+		return
+	}
+
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = speechContentTypes[grpcReq.ResponseFormat]
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.Audio)))
+	w.Write(resp.Audio)
+}
+
+// convertSpeechRequest converts OpenAI request to gRPC
+func (g *Gateway) convertSpeechRequest(req map[string]interface{}) (*pb.SpeechRequest, error) {
+	grpcReq := &pb.SpeechRequest{ResponseFormat: DefaultSpeechFormat}
+
+	// Model
+	if model, ok := req["model"].(string); ok && model != "" {
+		grpcReq.Model = model
+	} else {
+		return nil, fmt.Errorf("model is required")
+	}
+
+	// Input
+	if input, ok := req["input"].(string); ok && input != "" {
+		grpcReq.Input = input
+	} else {
+		return nil, fmt.Errorf("input is required")
+	}
+	if n := utf8.RuneCountInString(grpcReq.Input); n > MaxSpeechInput {
+		return nil, fmt.Errorf("input has %d characters, more than the %d allowed", n, MaxSpeechInput)
+	}
+
+	// Voice
+	if v, ok := req["voice"]; ok && v != nil {
+		voice, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("voice must be a string")
+		}
+		grpcReq.Voice = voice
+	}
+
+	// Response format
+	if v, ok := req["response_format"]; ok && v != nil {
+		format, ok := v.(string)
+		if !ok || !validSpeechFormat(format) {
+			return nil, fmt.Errorf("response_format must be one of %v", SpeechFormats)
+		}
+		grpcReq.ResponseFormat = format
+	}
+
+	// Speed
+	if v, ok := req["speed"]; ok && v != nil {
+		speed, ok := v.(float64)
+		if !ok || speed < 0.25 || speed > 4 {
+			return nil, fmt.Errorf("speed must be a number from 0.25 to 4")
+		}
+		grpcReq.Speed = float32(speed)
+	}
+
+	// End-user identifier
+	user, err := requestUser(req)
+	if err != nil {
+		return nil, err
+	}
+	grpcReq.User = user
+
+	return grpcReq, nil
+}
+
+// validSpeechFormat reports whether format is one of SpeechFormats
+func validSpeechFormat(format string) bool {
+	for _, f := range SpeechFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestGateway_convertSpeechRequest(t *testing.T) {
+	gateway := NewGateway("localhost:8080")
+
+	req, err := gateway.convertSpeechRequest(map[string]interface{}{
+		"model": "piper/en_US-lessac-medium",
+		"input": "Dinner is ready",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "piper/en_US-lessac-medium", req.Model)
+	assert.Equal(t, "Dinner is ready", req.Input)
+	assert.Equal(t, DefaultSpeechFormat, req.ResponseFormat)
+	assert.Zero(t, req.Speed)
+
+	req, err = gateway.convertSpeechRequest(map[string]interface{}{
+		"model":           "coqui/tts_models/en/vctk/vits",
+		"input":           "Dinner is ready",
+		"voice":           "p225",
+		"response_format": "wav",
+		"speed":           1.5,
+		"user":            "kitchen",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "p225", req.Voice)
+	assert.Equal(t, "wav", req.ResponseFormat)
+	assert.Equal(t, float32(1.5), req.Speed)
+	assert.Equal(t, "kitchen", req.User)
+
+	for name, bad := range map[string]map[string]interface{}{
+		"missing model":  {"input": "hi"},
+		"missing input":  {"model": "m"},
+		"empty input":    {"model": "m", "input": ""},
+		"long input":     {"model": "m", "input": strings.Repeat("a", MaxSpeechInput+1)},
+		"unknown format": {"model": "m", "input": "hi", "response_format": "ogg"},
+		"slow speed":     {"model": "m", "input": "hi", "speed": 0.1},
+		"voice type":     {"model": "m", "input": "hi", "voice": 3.0},
+	} {
+		_, err := gateway.convertSpeechRequest(bad)
+		assert.Error(t, err, name)
+	}
+}
+
+// speechServer answers speech requests with the input as audio
+type speechServer struct {
+	pb.UnimplementedOrchionLLMServer
+	contentType string
+}
+
+func (s speechServer) Speech(ctx context.Context, req *pb.SpeechRequest) (*pb.SpeechResponse, error) {
+	return &pb.SpeechResponse{Model: req.Model, Audio: []byte(req.Input), ContentType: s.contentType}, nil
+}
+
+// startLLMServer serves an OrchionLLM implementation on a local port and
+// returns its address
+func startLLMServer(t *testing.T, srv pb.OrchionLLMServer) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterOrchionLLMServer(server, srv)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestGateway_SpeechHandler(t *testing.T) {
+	t.Run("answers with the audio", func(t *testing.T) {
+		gateway := NewGateway(startLLMServer(t, speechServer{contentType: "audio/wav"}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"model":"piper/en_US-lessac-medium","input":"hello","response_format":"wav"}`))
+		rec := httptest.NewRecorder()
+		gateway.SpeechHandler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "audio/wav", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "5", rec.Header().Get("Content-Length"))
+		assert.Equal(t, "hello", rec.Body.String())
+	})
+
+	t.Run("content type follows the format if the node names none", func(t *testing.T) {
+		gateway := NewGateway(startLLMServer(t, speechServer{}))
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"model":"piper/en_US-lessac-medium","input":"hello"}`))
+		rec := httptest.NewRecorder()
+		gateway.SpeechHandler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "audio/mpeg", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		gateway := NewGateway("127.0.0.1:1")
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(`{"model":"m","input":"hi","response_format":"ogg"}`))
+		rec := httptest.NewRecorder()
+		gateway.SpeechHandler(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid_request")
+	})
+}
@@ -0,0 +1,185 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+)
+
+// Transform mutates gateway requests and responses in their OpenAI JSON form,
+// e.g. to template prompts, scrub personal data or post-process output.
+// Streamed chat completions pass every chunk through TransformResponse.
+type Transform interface {
+	// TransformRequest changes a request body before it's handled; an error
+	// rejects the request with a 400
+	TransformRequest(r *http.Request, body map[string]interface{}) error
+	// TransformResponse changes a response body before it's sent
+	TransformResponse(r *http.Request, body map[string]interface{})
+}
+
+// TransformFactory creates a transform from its JSON config
+type TransformFactory func(config json.RawMessage) (Transform, error)
+
+var (
+	transformTypesMu sync.RWMutex
+	transformTypes   = make(map[string]TransformFactory)
+)
+
+// RegisterTransform makes a transform type available to transform configs,
+// e.g. from an init function of a package linked into a custom build. It
+// panics if the type is already registered.
+func RegisterTransform(typ string, factory TransformFactory) {
+	transformTypesMu.Lock()
+	defer transformTypesMu.Unlock()
+
+	if _, exists := transformTypes[typ]; exists {
+		panic("gateway: transform type " + typ + " registered twice")
+	}
+	transformTypes[typ] = factory
+}
+
+// TransformTypes returns the registered transform types, sorted
+func TransformTypes() []string {
+	transformTypesMu.RLock()
+	defer transformTypesMu.RUnlock()
+
+	types := make([]string, 0, len(transformTypes))
+	for typ := range transformTypes {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// TransformConfig configures one transform and the requests it applies to
+type TransformConfig struct {
+	Type string `json:"type"`
+
+	// Routes are the gateway paths the transform applies to, e.g.
+	// "/v1/chat/completions"; all of them if empty
+	Routes []string `json:"routes,omitempty"`
+
+	// APIKeys restricts the transform to requests made with these keys
+	APIKeys []string `json:"api_keys,omitempty"`
+
+	// Config is passed to the transform type's factory
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// Transforms are the transforms the gateway applies, in order
+type Transforms struct {
+	transforms []*configuredTransform
+}
+
+// configuredTransform is a transform with the requests it applies to
+type configuredTransform struct {
+	Transform
+	routes  map[string]bool
+	apiKeys map[string]bool
+}
+
+// applies reports whether the transform applies to a request
+func (t *configuredTransform) applies(r *http.Request) bool {
+	if len(t.routes) > 0 && !t.routes[r.URL.Path] {
+		return false
+	}
+	return len(t.apiKeys) == 0 || t.apiKeys[auth.RequestKey(r)]
+}
+
+// NewTransforms creates the transforms configured by configs
+func NewTransforms(configs []TransformConfig) (*Transforms, error) {
+	transformTypesMu.RLock()
+	defer transformTypesMu.RUnlock()
+
+	t := &Transforms{}
+	for i, config := range configs {
+		factory, ok := transformTypes[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("transform %d: unknown type %q", i, config.Type)
+		}
+		transform, err := factory(config.Config)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d (%s): %w", i, config.Type, err)
+		}
+
+		configured := &configuredTransform{Transform: transform}
+		if len(config.Routes) > 0 {
+			configured.routes = make(map[string]bool, len(config.Routes))
+			for _, route := range config.Routes {
+				configured.routes[route] = true
+			}
+		}
+		if len(config.APIKeys) > 0 {
+			configured.apiKeys = make(map[string]bool, len(config.APIKeys))
+			for _, key := range config.APIKeys {
+				configured.apiKeys[key] = true
+			}
+		}
+		t.transforms = append(t.transforms, configured)
+	}
+	return t, nil
+}
+
+// LoadTransforms reads transform configs from a JSON file of the form
+// {"transforms": [...]}
+func LoadTransforms(filename string) (*Transforms, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transforms: %w", err)
+	}
+
+	var file struct {
+		Transforms []TransformConfig `json:"transforms"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse transforms %s: %w", filename, err)
+	}
+
+	t, err := NewTransforms(file.Transforms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transforms %s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// Len returns the number of transforms
+func (t *Transforms) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.transforms)
+}
+
+// Request applies the transforms matching a request to its body, stopping at
+// the first error
+func (t *Transforms) Request(r *http.Request, body map[string]interface{}) error {
+	if t == nil {
+		return nil
+	}
+	for _, transform := range t.transforms {
+		if !transform.applies(r) {
+			continue
+		}
+		if err := transform.TransformRequest(r, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Response applies the transforms matching a request to its response body
+func (t *Transforms) Response(r *http.Request, body map[string]interface{}) {
+	if t == nil {
+		return
+	}
+	for _, transform := range t.transforms {
+		if transform.applies(r) {
+			transform.TransformResponse(r, body)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// decode parses a JSON request or response body
+func decode(t *testing.T, body string) map[string]interface{} {
+	var v map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &v))
+	return v
+}
+
+func requestWithKey(path, key string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, nil)
+	if key != "" {
+		r.Header.Set("Authorization", "Bearer "+key)
+	}
+	return r
+}
+
+func TestNewTransforms(t *testing.T) {
+	assert.Contains(t, TransformTypes(), TransformScrubPII)
+
+	for name, config := range map[string]TransformConfig{
+		"unknown type":        {Type: "translate"},
+		"template no content": {Type: TransformPromptTemplate, Config: json.RawMessage(`{"user": "Be brief"}`)},
+		"empty template":      {Type: TransformPromptTemplate},
+		"unknown field":       {Type: TransformScrubPII, Config: json.RawMessage(`{"output": true}`)},
+		"invalid pattern":     {Type: TransformReplace, Config: json.RawMessage(`{"rules": [{"pattern": "("}]}`)},
+		"no rules":            {Type: TransformReplace},
+		"no compat options":   {Type: TransformOpenAICompat},
+		"unknown field case":  {Type: TransformOpenAICompat, Config: json.RawMessage(`{"field_case": "kebab"}`)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTransforms([]TransformConfig{config})
+			assert.Error(t, err)
+		})
+	}
+
+	assert.Panics(t, func() { RegisterTransform(TransformReplace, newReplace) })
+}
+
+func TestLoadTransforms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transforms.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"transforms": [
+		{"type": "scrub_pii", "routes": ["/v1/embeddings"]},
+		{"type": "prompt_template", "api_keys": ["team-a"], "config": {"system": "Answer in French."}}
+	]}`), 0o644))
+
+	transforms, err := LoadTransforms(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, transforms.Len())
+
+	// Routes and keys pick the transforms applied
+	body := decode(t, `{"input": "mail bob@example.com", "messages": [{"role": "user", "content": "Hi"}]}`)
+	require.NoError(t, transforms.Request(requestWithKey("/v1/chat/completions", "team-b"), body))
+	assert.Equal(t, "mail bob@example.com", body["input"])
+	assert.Len(t, body["messages"], 1)
+
+	require.NoError(t, transforms.Request(requestWithKey("/v1/embeddings", "team-a"), body))
+	assert.Equal(t, "mail [email]", body["input"])
+	assert.Len(t, body["messages"], 2)
+
+	_, err = LoadTransforms(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	var none *Transforms
+	assert.NoError(t, none.Request(requestWithKey("/v1/embeddings", ""), body))
+	assert.Equal(t, 0, none.Len())
+}
+
+func TestPromptTemplate(t *testing.T) {
+	transforms, err := NewTransforms([]TransformConfig{{
+		Type:   TransformPromptTemplate,
+		Config: json.RawMessage(`{"system": "You are terse.", "user": "Question: {{content}}"}`),
+	}})
+	require.NoError(t, err)
+
+	body := decode(t, `{"messages": [
+		{"role": "user", "content": "What is Go?"},
+		{"role": "assistant", "content": "A language."},
+		{"role": "user", "content": [{"type": "text", "text": "And Rust?"}, {"type": "image_url", "image_url": {"url": "x"}}]}
+	]}`)
+	require.NoError(t, transforms.Request(chatRequest(), body))
+
+	messages := body["messages"].([]interface{})
+	require.Len(t, messages, 4)
+	assert.Equal(t, map[string]interface{}{"role": "system", "content": "You are terse."}, messages[0])
+	assert.Equal(t, "Question: What is Go?", messages[1].(map[string]interface{})["content"])
+	assert.Equal(t, "A language.", messages[2].(map[string]interface{})["content"])
+	parts := messages[3].(map[string]interface{})["content"].([]interface{})
+	assert.Equal(t, "Question: And Rust?", parts[0].(map[string]interface{})["text"])
+}
+
+func TestScrubPII(t *testing.T) {
+	transforms, err := NewTransforms([]TransformConfig{{
+		Type:   TransformScrubPII,
+		Config: json.RawMessage(`{"responses": true}`),
+	}})
+	require.NoError(t, err)
+
+	body := decode(t, `{"messages": [{"role": "user", "content": "Call +1 555 123 4567"}]}`)
+	require.NoError(t, transforms.Request(chatRequest(), body))
+	assert.Equal(t, "Call [number]", body["messages"].([]interface{})[0].(map[string]interface{})["content"])
+
+	resp := NewGateway("localhost:8080").convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Object:  "chat.completion",
+		Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", Content: "Write to ann@example.com"}}},
+	})
+	transforms.Response(chatRequest(), resp)
+	assert.Equal(t, "Write to [email]", resp["choices"].([]map[string]interface{})[0]["message"].(map[string]interface{})["content"])
+}
+
+func TestReplace_Stream(t *testing.T) {
+	transforms, err := NewTransforms([]TransformConfig{{
+		Type:   TransformReplace,
+		Routes: []string{"/v1/chat/completions"},
+		Config: json.RawMessage(`{"rules": [{"pattern": "(?i)acme", "replacement": "[redacted]"}]}`),
+	}})
+	require.NoError(t, err)
+
+	gateway := NewGateway("localhost:8080")
+	gateway.SetTransforms(transforms)
+	w := httptest.NewRecorder()
+	gateway.streamSSE(context.Background(), w, chatRequest(), &fakeChatClient{responses: []*pb.ChatCompletionResponse{
+		{Object: "chat.completion.chunk", Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Content: "ACME builds"}}}},
+		{Object: "chat.completion.chunk", Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Content: " rockets"}, FinishReason: "stop"}}},
+	}}, false)
+
+	assert.Contains(t, w.Body.String(), `"content":"[redacted] builds"`)
+	assert.NotContains(t, w.Body.String(), "ACME")
+}
+
+func TestOpenAICompat(t *testing.T) {
+	transforms, err := NewTransforms([]TransformConfig{{
+		Type:   TransformOpenAICompat,
+		Config: json.RawMessage(`{"system_fingerprint": "fp_orchion", "service_tier": "default", "id_prefixes": true}`),
+	}})
+	require.NoError(t, err)
+	gateway := NewGateway("localhost:8080")
+
+	resp := gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+		Id: "7f3a", Object: "chat.completion", Model: "llama3",
+		Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", ToolCalls: []*pb.ToolCall{
+			{Id: "0", Name: "get_weather", Arguments: "{}"},
+			{Id: "call_1", Name: "get_time", Arguments: "{}"},
+		}}}},
+	})
+	transforms.Response(chatRequest(), resp)
+	assert.Equal(t, "chatcmpl-7f3a", resp["id"])
+	assert.Equal(t, "fp_orchion", resp["system_fingerprint"])
+	assert.Equal(t, "default", resp["service_tier"])
+	calls := resp["choices"].([]map[string]interface{})[0]["message"].(map[string]interface{})["tool_calls"].([]map[string]interface{})
+	assert.Equal(t, "call_0", calls[0]["id"])
+	assert.Equal(t, "call_1", calls[1]["id"], "already prefixed")
+
+	t.Run("chunks", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.SetTransforms(transforms)
+		gateway.streamSSE(context.Background(), w, chatRequest(), &fakeChatClient{responses: []*pb.ChatCompletionResponse{
+			{Id: "chatcmpl-1", Object: "chat.completion.chunk", Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Content: "Hi"}, FinishReason: "stop"}}},
+		}}, false)
+		assert.Contains(t, w.Body.String(), `"id":"chatcmpl-1"`)
+		assert.Contains(t, w.Body.String(), `"system_fingerprint":"fp_orchion"`)
+	})
+
+	t.Run("embeddings are left alone", func(t *testing.T) {
+		resp := gateway.convertEmbeddingResponse(&pb.EmbeddingResponse{Object: "list", Model: "nomic-embed-text"})
+		transforms.Response(requestWithKey("/v1/embeddings", ""), resp)
+		assert.NotContains(t, resp, "system_fingerprint")
+	})
+
+	t.Run("camelCase fields", func(t *testing.T) {
+		transforms, err := NewTransforms([]TransformConfig{{
+			Type:   TransformOpenAICompat,
+			Config: json.RawMessage(`{"field_case": "camel"}`),
+		}})
+		require.NoError(t, err)
+
+		resp := gateway.convertChatCompletionResponse(&pb.ChatCompletionResponse{
+			Object: "chat.completion", UsagePromptTokens: 10, UsageCompletionTokens: 2,
+			Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Role: "assistant", Content: "Hi"}, FinishReason: "stop"}},
+		})
+		transforms.Response(chatRequest(), resp)
+		data, err := json.Marshal(resp)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"finishReason":"stop"`)
+		assert.Contains(t, string(data), `"promptTokensDetails":{"cachedTokens":0}`)
+		assert.NotContains(t, string(data), "_")
+	})
+}
@@ -0,0 +1,337 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/replay"
+)
+
+// Built-in transform types
+const (
+	TransformPromptTemplate = "prompt_template"
+	TransformScrubPII       = "scrub_pii"
+	TransformReplace        = "replace"
+	TransformOpenAICompat   = "openai_compat"
+)
+
+// Field name cases openai_compat sends responses in
+const (
+	FieldCaseSnake = "snake"
+	FieldCaseCamel = "camel"
+)
+
+// TemplateContent is replaced with a message's text in prompt templates
+const TemplateContent = "{{content}}"
+
+func init() {
+	RegisterTransform(TransformPromptTemplate, newPromptTemplate)
+	RegisterTransform(TransformScrubPII, newScrubPII)
+	RegisterTransform(TransformReplace, newReplace)
+	RegisterTransform(TransformOpenAICompat, newOpenAICompat)
+}
+
+// decodeConfig decodes a transform's JSON config, rejecting unknown fields
+func decodeConfig(config json.RawMessage, v interface{}) error {
+	if len(config) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(string(config)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return nil
+}
+
+// promptTemplate adds a system prompt to chat requests and wraps user
+// messages in a template
+type promptTemplate struct {
+	System string `json:"system,omitempty"`
+	User   string `json:"user,omitempty"` // Must contain TemplateContent
+}
+
+func newPromptTemplate(config json.RawMessage) (Transform, error) {
+	t := &promptTemplate{}
+	if err := decodeConfig(config, t); err != nil {
+		return nil, err
+	}
+	if t.System == "" && t.User == "" {
+		return nil, errors.New("a system prompt or user template is required")
+	}
+	if t.User != "" && !strings.Contains(t.User, TemplateContent) {
+		return nil, fmt.Errorf("the user template must contain %s", TemplateContent)
+	}
+	return t, nil
+}
+
+func (t *promptTemplate) TransformRequest(r *http.Request, body map[string]interface{}) error {
+	messages, ok := body["messages"].([]interface{})
+	if !ok {
+		return nil
+	}
+	if t.User != "" {
+		mapRequestText(body, func(role, text string) string {
+			if role != "user" {
+				return text
+			}
+			return strings.ReplaceAll(t.User, TemplateContent, text)
+		})
+	}
+	if t.System != "" {
+		system := map[string]interface{}{"role": "system", "content": t.System}
+		body["messages"] = append([]interface{}{system}, messages...)
+	}
+	return nil
+}
+
+func (t *promptTemplate) TransformResponse(r *http.Request, body map[string]interface{}) {}
+
+// scrubPII masks e-mail addresses and phone numbers in prompts and inputs,
+// and optionally in output, so they never reach the nodes
+type scrubPII struct {
+	Responses bool `json:"responses,omitempty"`
+}
+
+func newScrubPII(config json.RawMessage) (Transform, error) {
+	t := &scrubPII{}
+	if err := decodeConfig(config, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *scrubPII) TransformRequest(r *http.Request, body map[string]interface{}) error {
+	mapRequestText(body, func(role, text string) string {
+		return replay.ScrubText(text)
+	})
+	return nil
+}
+
+func (t *scrubPII) TransformResponse(r *http.Request, body map[string]interface{}) {
+	if t.Responses {
+		mapResponseText(body, replay.ScrubText)
+	}
+}
+
+// replace rewrites output with regular expressions. Streamed output is
+// rewritten chunk by chunk, so patterns shouldn't span more than a few
+// characters.
+type replace struct {
+	Rules []replaceRule `json:"rules"`
+}
+
+// replaceRule replaces the matches of a regular expression, expanding $1
+// style references in the replacement
+type replaceRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+	re          *regexp.Regexp
+}
+
+func newReplace(config json.RawMessage) (Transform, error) {
+	t := &replace{}
+	if err := decodeConfig(config, t); err != nil {
+		return nil, err
+	}
+	if len(t.Rules) == 0 {
+		return nil, errors.New("at least one rule is required")
+	}
+	for i := range t.Rules {
+		re, err := regexp.Compile(t.Rules[i].Pattern)
+		if err != nil || t.Rules[i].Pattern == "" {
+			return nil, fmt.Errorf("invalid pattern %q", t.Rules[i].Pattern)
+		}
+		t.Rules[i].re = re
+	}
+	return t, nil
+}
+
+func (t *replace) TransformRequest(r *http.Request, body map[string]interface{}) error {
+	return nil
+}
+
+func (t *replace) TransformResponse(r *http.Request, body map[string]interface{}) {
+	mapResponseText(body, func(text string) string {
+		for _, rule := range t.Rules {
+			text = rule.re.ReplaceAllString(text, rule.Replacement)
+		}
+		return text
+	})
+}
+
+// openAICompat adds the chat completion fields some OpenAI SDKs and proxies,
+// such as LiteLLM, validate responses against, and optionally renames
+// response fields to camelCase for clients expecting it
+type openAICompat struct {
+	SystemFingerprint string `json:"system_fingerprint,omitempty"` // e.g. "fp_orchion"
+	ServiceTier       string `json:"service_tier,omitempty"`       // e.g. "default"
+	IDPrefixes        bool   `json:"id_prefixes,omitempty"`        // Start completion IDs with chatcmpl- and tool call IDs with call_
+	FieldCase         string `json:"field_case,omitempty"`         // FieldCaseSnake (default) or FieldCaseCamel
+}
+
+func newOpenAICompat(config json.RawMessage) (Transform, error) {
+	t := &openAICompat{}
+	if err := decodeConfig(config, t); err != nil {
+		return nil, err
+	}
+	switch t.FieldCase {
+	case "", FieldCaseSnake, FieldCaseCamel:
+	default:
+		return nil, fmt.Errorf("field_case must be %q or %q", FieldCaseSnake, FieldCaseCamel)
+	}
+	if *t == (openAICompat{}) {
+		return nil, errors.New("at least one option is required")
+	}
+	return t, nil
+}
+
+func (t *openAICompat) TransformRequest(r *http.Request, body map[string]interface{}) error {
+	return nil
+}
+
+func (t *openAICompat) TransformResponse(r *http.Request, body map[string]interface{}) {
+	if object, _ := body["object"].(string); strings.HasPrefix(object, "chat.completion") {
+		if t.SystemFingerprint != "" {
+			body["system_fingerprint"] = t.SystemFingerprint
+		}
+		if t.ServiceTier != "" {
+			body["service_tier"] = t.ServiceTier
+		}
+		if t.IDPrefixes {
+			prefixID(body, "chatcmpl-")
+			for _, message := range responseMessages(body) {
+				for _, call := range objects(message["tool_calls"]) {
+					prefixID(call, "call_")
+				}
+			}
+		}
+	}
+	if t.FieldCase == FieldCaseCamel {
+		camelKeys(body)
+	}
+}
+
+// prefixID makes an object's non-empty ID start with prefix
+func prefixID(object map[string]interface{}, prefix string) {
+	if id, ok := object["id"].(string); ok && id != "" && !strings.HasPrefix(id, prefix) {
+		object["id"] = prefix + id
+	}
+}
+
+// camelKeys renames the snake_case keys of an object and the objects nested
+// in it to camelCase, e.g. finish_reason to finishReason
+func camelKeys(object map[string]interface{}) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		value := object[key]
+		switch v := value.(type) {
+		case map[string]interface{}:
+			camelKeys(v)
+		case []map[string]interface{}, []interface{}:
+			for _, nested := range objects(v) {
+				camelKeys(nested)
+			}
+		}
+		if camel := camelCase(key); camel != key {
+			delete(object, key)
+			object[camel] = value
+		}
+	}
+}
+
+// camelCase converts a snake_case name to camelCase
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// mapRequestText replaces the text of a chat request's messages, including
+// text parts of multimodal content, or of an embeddings request's input
+func mapRequestText(body map[string]interface{}, fn func(role, text string) string) {
+	switch input := body["input"].(type) {
+	case string:
+		body["input"] = fn("", input)
+	case []interface{}:
+		for i, item := range input {
+			if text, ok := item.(string); ok {
+				input[i] = fn("", text)
+			}
+		}
+	}
+
+	messages, _ := body["messages"].([]interface{})
+	for _, m := range messages {
+		message, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		role, _ := message["role"].(string)
+		switch content := message["content"].(type) {
+		case string:
+			message["content"] = fn(role, content)
+		case []interface{}:
+			for _, p := range content {
+				if part, ok := p.(map[string]interface{}); ok && part["type"] == "text" {
+					if text, ok := part["text"].(string); ok {
+						part["text"] = fn(role, text)
+					}
+				}
+			}
+		}
+	}
+}
+
+// mapResponseText replaces the text of a chat response's choices, or chunk's
+// deltas
+func mapResponseText(body map[string]interface{}, fn func(text string) string) {
+	for _, message := range responseMessages(body) {
+		if text, ok := message["content"].(string); ok && text != "" {
+			message["content"] = fn(text)
+		}
+	}
+}
+
+// responseMessages returns the messages of a chat response's choices, or
+// chunk's deltas
+func responseMessages(body map[string]interface{}) []map[string]interface{} {
+	var messages []map[string]interface{}
+	for _, choice := range objects(body["choices"]) {
+		for _, key := range []string{"message", "delta"} {
+			if message, ok := choice[key].(map[string]interface{}); ok {
+				messages = append(messages, message)
+			}
+		}
+	}
+	return messages
+}
+
+// objects returns the objects of a JSON array, as built by the gateway or
+// decoded from a body
+func objects(v interface{}) []map[string]interface{} {
+	switch a := v.(type) {
+	case []map[string]interface{}:
+		return a
+	case []interface{}:
+		var out []map[string]interface{}
+		for _, item := range a {
+			if object, ok := item.(map[string]interface{}); ok {
+				out = append(out, object)
+			}
+		}
+		return out
+	}
+	return nil
+}
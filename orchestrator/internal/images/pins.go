@@ -0,0 +1,110 @@
+// Package images keeps the engine container images pinned across the fleet.
+// Node agents learn the pins from heartbeat responses and run engines from a
+// pinned repository at the pinned digest, so engines only change version when
+// an operator rolls out a new digest, not whenever a tag like "latest" moves.
+package images
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// ErrNotPinned is returned when removing the pin of an image that has none
+var ErrNotPinned = errors.New("image is not pinned")
+
+// digestPattern matches OCI content digests such as "sha256:<64 hex digits>"
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[+._-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+
+// Pins maps image repositories to the digest the fleet runs them at
+type Pins struct {
+	mu   sync.RWMutex
+	pins map[string]string
+}
+
+// NewPins creates an empty set of pins
+func NewPins() *Pins {
+	return &Pins{pins: make(map[string]string)}
+}
+
+// ParsePins parses comma-separated image=digest pairs, e.g.
+// "vllm/vllm-openai=sha256:...,ollama/ollama=sha256:..."
+func ParsePins(s string) (*Pins, error) {
+	pins := NewPins()
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		image, digest, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid image pin %q (want image=digest)", pair)
+		}
+		if err := pins.Set(image, digest); err != nil {
+			return nil, err
+		}
+	}
+	return pins, nil
+}
+
+// Set pins an image repository to a digest, replacing its previous pin
+func (p *Pins) Set(image, digest string) error {
+	image, digest = strings.TrimSpace(image), strings.TrimSpace(digest)
+	if err := validateRepository(image); err != nil {
+		return err
+	}
+	if !digestPattern.MatchString(digest) {
+		return fmt.Errorf("invalid digest %q (want e.g. sha256:<64 hex digits>)", digest)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pins[image] = digest
+	return nil
+}
+
+// Remove unpins an image repository, so agents go back to the image they
+// were configured with
+func (p *Pins) Remove(image string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pins[image]; !ok {
+		return ErrNotPinned
+	}
+	delete(p.pins, image)
+	return nil
+}
+
+// List returns the pins sorted by image
+func (p *Pins) List() []*pb.ImagePin {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	pins := make([]*pb.ImagePin, 0, len(p.pins))
+	for image, digest := range p.pins {
+		pins = append(pins, &pb.ImagePin{Image: image, Digest: digest})
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Image < pins[j].Image })
+	return pins
+}
+
+// validateRepository checks that an image is a bare repository, e.g.
+// "vllm/vllm-openai" or "registry:5000/team/ollama", since the pin's digest
+// replaces any tag
+func validateRepository(image string) error {
+	switch {
+	case image == "" || strings.ContainsAny(image, " \t\n"):
+		return fmt.Errorf("invalid image %q", image)
+	case strings.Contains(image, "@"):
+		return fmt.Errorf("image %q already has a digest (want a repository such as vllm/vllm-openai)", image)
+	case strings.Contains(image[strings.LastIndex(image, "/")+1:], ":"):
+		return fmt.Errorf("image %q has a tag (want a repository such as vllm/vllm-openai)", image)
+	}
+	return nil
+}
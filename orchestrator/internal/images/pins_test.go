@@ -0,0 +1,73 @@
+package images
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+var (
+	digestA = "sha256:" + strings.Repeat("a", 64)
+	digestB = "sha256:" + strings.Repeat("b", 64)
+)
+
+func TestPins(t *testing.T) {
+	pins := NewPins()
+	require.NoError(t, pins.Set("vllm/vllm-openai", digestA))
+	require.NoError(t, pins.Set("registry:5000/team/ollama", digestA))
+
+	// Rolling out a new digest replaces the pin
+	require.NoError(t, pins.Set("vllm/vllm-openai", digestB))
+
+	assert.Equal(t, []*pb.ImagePin{
+		{Image: "registry:5000/team/ollama", Digest: digestA},
+		{Image: "vllm/vllm-openai", Digest: digestB},
+	}, pins.List())
+
+	require.NoError(t, pins.Remove("registry:5000/team/ollama"))
+	assert.ErrorIs(t, pins.Remove("registry:5000/team/ollama"), ErrNotPinned)
+	assert.Len(t, pins.List(), 1)
+}
+
+func TestPins_SetErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		image  string
+		digest string
+	}{
+		{"empty image", "", digestA},
+		{"tagged image", "vllm/vllm-openai:latest", digestA},
+		{"image with digest", "vllm/vllm-openai@" + digestA, digestA},
+		{"missing digest", "vllm/vllm-openai", ""},
+		{"tag instead of digest", "vllm/vllm-openai", "v0.6.3"},
+		{"short digest", "vllm/vllm-openai", "sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, NewPins().Set(tt.image, tt.digest))
+		})
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	pins, err := ParsePins("vllm/vllm-openai=" + digestA + ", ollama/ollama=" + digestB)
+	require.NoError(t, err)
+	assert.Equal(t, []*pb.ImagePin{
+		{Image: "ollama/ollama", Digest: digestB},
+		{Image: "vllm/vllm-openai", Digest: digestA},
+	}, pins.List())
+
+	pins, err = ParsePins("")
+	require.NoError(t, err)
+	assert.Empty(t, pins.List())
+
+	_, err = ParsePins("vllm/vllm-openai")
+	assert.Error(t, err)
+	_, err = ParsePins("vllm/vllm-openai:latest=" + digestA)
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// EmbedFunc sends one embedding request
+type EmbedFunc func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error)
+
+// EmbedInChunks sends req with embed. If the request or its response exceeds
+// a gRPC message size limit, it splits the inputs in half and embeds each half
+// the same way, so large batches succeed regardless of the limits on the way.
+func EmbedInChunks(ctx context.Context, req *pb.EmbeddingRequest, embed EmbedFunc) (*pb.EmbeddingResponse, error) {
+	resp, err := embed(ctx, req)
+	if err == nil || len(req.Input) < 2 || !IsMessageTooLarge(err) {
+		return resp, err
+	}
+
+	mid := len(req.Input) / 2
+	logging.FromContext(ctx).Info("Embedding batch exceeds the gRPC message size limit, splitting it", map[string]interface{}{
+		"inputs": len(req.Input),
+		"error":  err.Error(),
+	})
+
+	first := proto.Clone(req).(*pb.EmbeddingRequest)
+	first.Input = req.Input[:mid]
+	head, err := EmbedInChunks(ctx, first, embed)
+	if err != nil {
+		return nil, err
+	}
+
+	second := proto.Clone(req).(*pb.EmbeddingRequest)
+	second.Input = req.Input[mid:]
+	tail, err := EmbedInChunks(ctx, second, embed)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeEmbeddings(head, tail, int32(mid)), nil
+}
+
+// mergeEmbeddings joins the responses for two halves of a batch, shifting the
+// second half's indices past the first half's inputs
+func mergeEmbeddings(head, tail *pb.EmbeddingResponse, offset int32) *pb.EmbeddingResponse {
+	data := make([]*pb.Embedding, 0, len(head.Data)+len(tail.Data))
+	data = append(data, head.Data...)
+	for _, emb := range tail.Data {
+		data = append(data, &pb.Embedding{
+			Index:     emb.Index + offset,
+			Embedding: emb.Embedding,
+		})
+	}
+
+	return &pb.EmbeddingResponse{
+		Model:             head.Model,
+		Data:              data,
+		Object:            head.Object,
+		UsagePromptTokens: head.UsagePromptTokens + tail.UsagePromptTokens,
+	}
+}
+
+// IsMessageTooLarge reports whether err is gRPC rejecting a message over its
+// send or receive size limit
+func IsMessageTooLarge(err error) bool {
+	st, ok := status.FromError(err)
+	return ok && st.Code() == codes.ResourceExhausted && strings.Contains(st.Message(), "larger than max")
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// limitedEmbed embeds inputs as their length, failing like gRPC's message
+// size limit for batches larger than maxInputs
+func limitedEmbed(maxInputs int, calls *[]int) EmbedFunc {
+	return func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+		*calls = append(*calls, len(req.Input))
+		if len(req.Input) > maxInputs {
+			return nil, status.Error(codes.ResourceExhausted, "grpc: received message larger than max (5000000 vs. 4194304)")
+		}
+		resp := &pb.EmbeddingResponse{Model: req.Model, Object: "list", UsagePromptTokens: int32(len(req.Input))}
+		for i, input := range req.Input {
+			resp.Data = append(resp.Data, &pb.Embedding{Index: int32(i), Embedding: []float32{float32(len(input))}})
+		}
+		return resp, nil
+	}
+}
+
+func TestEmbedInChunks(t *testing.T) {
+	req := &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"a", "bb", "ccc", "dddd", "eeeee"}}
+
+	tests := []struct {
+		name      string
+		maxInputs int
+		calls     []int
+	}{
+		{"fits", 5, []int{5}},
+		{"split once", 3, []int{5, 2, 3}},
+		{"split recursively", 1, []int{5, 2, 1, 1, 3, 1, 2, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []int
+			resp, err := EmbedInChunks(context.Background(), req, limitedEmbed(tt.maxInputs, &calls))
+			require.NoError(t, err)
+			assert.Equal(t, tt.calls, calls)
+
+			assert.Equal(t, "nomic-embed-text", resp.Model)
+			assert.Equal(t, int32(5), resp.UsagePromptTokens)
+			require.Len(t, resp.Data, 5)
+			for i, emb := range resp.Data {
+				assert.Equal(t, int32(i), emb.Index)
+				assert.Equal(t, []float32{float32(i + 1)}, emb.Embedding)
+			}
+		})
+	}
+}
+
+func TestEmbedInChunks_OtherErrors(t *testing.T) {
+	var calls int
+	_, err := EmbedInChunks(context.Background(), &pb.EmbeddingRequest{Input: []string{"a", "b"}},
+		func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+			calls++
+			return nil, status.Error(codes.ResourceExhausted, "node is out of VRAM")
+		})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestEmbedInChunks_SingleInputTooLarge(t *testing.T) {
+	var calls []int
+	_, err := EmbedInChunks(context.Background(), &pb.EmbeddingRequest{Input: []string{"a"}}, limitedEmbed(0, &calls))
+	assert.True(t, IsMessageTooLarge(err))
+	assert.Equal(t, []int{1}, calls)
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// gRPC trailer keys with which ChatCompletion reports how fast the node
+// generated the reply
+const (
+	ServedByTrailer = "x-orchion-served-by"
+	TTFTTrailer     = "x-orchion-ttft-ms"
+	TPSTrailer      = "x-orchion-tps"
+)
+
+// Generation measures how fast a node produced a chat completion
+type Generation struct {
+	Node            string
+	TTFT            time.Duration // From forwarding the request to the first output, model loading included
+	TokensPerSecond float64       // Streamed chunks per second after the first; 0 unless streamed
+}
+
+// generationMeter measures a chat completion as its responses arrive
+type generationMeter struct {
+	node   string
+	start  time.Time
+	first  time.Time
+	last   time.Time
+	chunks int
+}
+
+func newGenerationMeter(node string) *generationMeter {
+	return &generationMeter{node: node, start: time.Now()}
+}
+
+// observe records a response from the node. Responses without output, such as
+// loading reports, don't count.
+func (m *generationMeter) observe(resp *pb.ChatCompletionResponse) {
+	if resp.Status != "" || len(resp.Choices) == 0 {
+		return
+	}
+	now := time.Now()
+	if m.chunks == 0 {
+		m.first = now
+	}
+	m.last = now
+	m.chunks++
+}
+
+// generation returns the measurements, or false if no output arrived
+func (m *generationMeter) generation() (Generation, bool) {
+	if m.chunks == 0 {
+		return Generation{}, false
+	}
+	gen := Generation{Node: m.node, TTFT: m.first.Sub(m.start)}
+	// Engines stream about one token per chunk
+	if elapsed := m.last.Sub(m.first); m.chunks > 1 && elapsed > 0 {
+		gen.TokensPerSecond = float64(m.chunks-1) / elapsed.Seconds()
+	}
+	return gen, true
+}
+
+// Metadata encodes the measurements as gRPC trailer metadata
+func (g Generation) Metadata() metadata.MD {
+	md := metadata.Pairs(
+		ServedByTrailer, g.Node,
+		TTFTTrailer, strconv.FormatInt(g.TTFT.Milliseconds(), 10),
+	)
+	if g.TokensPerSecond > 0 {
+		md.Set(TPSTrailer, strconv.FormatFloat(g.TokensPerSecond, 'f', 1, 64))
+	}
+	return md
+}
+
+// GenerationFromMetadata decodes measurements from gRPC trailer metadata.
+// Missing or malformed values are left zero.
+func GenerationFromMetadata(md metadata.MD) Generation {
+	var gen Generation
+	if v := md.Get(ServedByTrailer); len(v) > 0 {
+		gen.Node = v[0]
+	}
+	if v := md.Get(TTFTTrailer); len(v) > 0 {
+		if ms, err := strconv.ParseInt(v[0], 10, 64); err == nil {
+			gen.TTFT = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := md.Get(TPSTrailer); len(v) > 0 {
+		if tps, err := strconv.ParseFloat(v[0], 64); err == nil {
+			gen.TokensPerSecond = tps
+		}
+	}
+	return gen
+}
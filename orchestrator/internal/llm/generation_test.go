@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestGenerationMeter(t *testing.T) {
+	chunk := &pb.ChatCompletionResponse{Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Content: "a"}}}}
+	start := time.Now()
+	meter := &generationMeter{node: "node-1", start: start}
+
+	_, ok := meter.generation()
+	assert.False(t, ok, "no output yet")
+
+	// Loading reports don't count as output
+	meter.observe(&pb.ChatCompletionResponse{Status: ModelLoadingStatus})
+	_, ok = meter.generation()
+	assert.False(t, ok)
+
+	for i := 0; i < 11; i++ {
+		meter.observe(chunk)
+	}
+	// Pin the timings: first token after 2s, then 10 more over 500ms
+	meter.first = start.Add(2 * time.Second)
+	meter.last = meter.first.Add(500 * time.Millisecond)
+
+	gen, ok := meter.generation()
+	require.True(t, ok)
+	assert.Equal(t, Generation{Node: "node-1", TTFT: 2 * time.Second, TokensPerSecond: 20}, gen)
+}
+
+func TestGenerationMetadata(t *testing.T) {
+	gen := Generation{Node: "node-1", TTFT: 1500 * time.Millisecond, TokensPerSecond: 42.5}
+	md := gen.Metadata()
+	assert.Equal(t, []string{"1500"}, md.Get(TTFTTrailer))
+	assert.Equal(t, []string{"42.5"}, md.Get(TPSTrailer))
+	assert.Equal(t, gen, GenerationFromMetadata(md))
+
+	// Non-streamed replies have no rate
+	md = Generation{Node: "node-1", TTFT: time.Second}.Metadata()
+	assert.Empty(t, md.Get(TPSTrailer))
+
+	assert.Equal(t, Generation{}, GenerationFromMetadata(metadata.Pairs(TTFTTrailer, "soon")))
+	assert.Equal(t, Generation{}, GenerationFromMetadata(nil))
+}
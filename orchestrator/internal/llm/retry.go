@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"sync"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+)
+
+// DefaultRetryRatio is the share of requests that may fail over to another node
+const DefaultRetryRatio = 0.1
+
+// retryBudgetBurst is how many failovers the budget holds, available from the
+// start so the first node flap after a quiet period is covered
+const retryBudgetBurst = 10
+
+// RetryBudget limits how many requests fail over to another node after their
+// node went away, so an outage across the cluster doesn't multiply the load.
+// Every request earns a fraction of a retry; every failover spends one.
+type RetryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+// NewRetryBudget creates a budget allowing ratio failovers per request, e.g.
+// 0.1 for one in ten
+func NewRetryBudget(ratio float64) *RetryBudget {
+	return &RetryBudget{ratio: ratio, tokens: retryBudgetBurst}
+}
+
+// deposit credits the budget for a request
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > retryBudgetBurst {
+		b.tokens = retryBudgetBurst
+	}
+}
+
+// withdraw spends a retry, reporting false if the budget is exhausted
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// failover reports whether a request whose node failed before answering may
+// be retried on another node. Each request fails over at most once, and only
+// when its node became unreachable; other errors would likely repeat.
+func (s *Service) failover(err error, failedOver *bool) bool {
+	if s.retries == nil || *failedOver || errcode.FromError(err) != pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE {
+		return false
+	}
+	if !s.retries.withdraw() {
+		return false
+	}
+	*failedOver = true
+	return true
+}
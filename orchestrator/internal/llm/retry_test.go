@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(0.5)
+	for i := 0; i < retryBudgetBurst; i++ {
+		require.True(t, budget.withdraw(), "the burst is available from the start")
+	}
+	assert.False(t, budget.withdraw())
+
+	// Two requests earn one retry
+	budget.deposit()
+	assert.False(t, budget.withdraw())
+	budget.deposit()
+	assert.True(t, budget.withdraw())
+
+	// Quiet periods don't save up more than the burst
+	for i := 0; i < 100; i++ {
+		budget.deposit()
+	}
+	assert.InDelta(t, float64(retryBudgetBurst), budget.tokens, 0.001)
+}
+
+func TestService_failover(t *testing.T) {
+	nodeDown := status.Error(codes.Unavailable, "connection refused")
+	nodes := []*pb.Node{{Id: "node-1"}, {Id: "node-2"}, {Id: "node-3"}}
+	newService := func(budget *RetryBudget, clients ...*fakeNodeClient) *Service {
+		service := NewService(&MockRegistry{}, &excludingScheduler{nodes: nodes})
+		service.SetRetryBudget(budget)
+		for i, client := range clients {
+			service.nodeClients[nodes[i].Id] = client
+		}
+		return service
+	}
+	embed := func(service *Service) error {
+		_, err := service.Embeddings(context.Background(), &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+		return err
+	}
+	chatReq := func(stream bool) *pb.ChatCompletionRequest {
+		return &pb.ChatCompletionRequest{Model: "llama3", Stream: stream, Messages: []*pb.ChatMessage{{Role: "user", Content: "Hi"}}}
+	}
+
+	t.Run("embeddings fail over once", func(t *testing.T) {
+		down, alsoDown, spare := &fakeNodeClient{err: nodeDown}, &fakeNodeClient{err: nodeDown}, &fakeNodeClient{}
+		err := embed(newService(NewRetryBudget(DefaultRetryRatio), down, alsoDown, spare))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, errcode.FromError(err))
+		assert.Equal(t, 1, alsoDown.calls)
+		assert.Equal(t, 0, spare.calls)
+
+		down, spare = &fakeNodeClient{err: nodeDown}, &fakeNodeClient{}
+		require.NoError(t, embed(newService(NewRetryBudget(DefaultRetryRatio), down, spare)))
+		assert.Equal(t, 1, spare.calls)
+	})
+
+	t.Run("no failover without a budget", func(t *testing.T) {
+		down, spare := &fakeNodeClient{err: nodeDown}, &fakeNodeClient{}
+		assert.Error(t, embed(newService(nil, down, spare)))
+		assert.Equal(t, 0, spare.calls)
+
+		exhausted := NewRetryBudget(0)
+		for exhausted.withdraw() {
+		}
+		down, spare = &fakeNodeClient{err: nodeDown}, &fakeNodeClient{}
+		assert.Error(t, embed(newService(exhausted, down, spare)))
+		assert.Equal(t, 0, spare.calls)
+	})
+
+	t.Run("engine errors don't fail over", func(t *testing.T) {
+		broken, spare := &fakeNodeClient{err: errcode.New(codes.Internal, pb.ErrorCode_ERROR_CODE_ENGINE_ERROR, "boom")}, &fakeNodeClient{}
+		assert.Error(t, embed(newService(NewRetryBudget(DefaultRetryRatio), broken, spare)))
+		assert.Equal(t, 0, spare.calls)
+	})
+
+	t.Run("only non-streamed chat fails over", func(t *testing.T) {
+		reply := &pb.ChatCompletionResponse{Id: "chat-1"}
+		down, spare := &fakeNodeClient{err: nodeDown}, &fakeNodeClient{resp: reply}
+		stream := &fakeChatServerStream{}
+		require.NoError(t, newService(NewRetryBudget(DefaultRetryRatio), down, spare).ChatCompletion(chatReq(false), stream))
+		assert.Equal(t, []*pb.ChatCompletionResponse{reply}, stream.sent)
+
+		down, spare = &fakeNodeClient{err: nodeDown}, &fakeNodeClient{resp: reply}
+		err := newService(NewRetryBudget(DefaultRetryRatio), down, spare).ChatCompletion(chatReq(true), &fakeChatServerStream{})
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, errcode.FromError(err))
+		assert.Equal(t, 0, spare.calls)
+	})
+}
@@ -2,26 +2,49 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
 	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/orchestrator/internal/tunnel"
 )
 
+// TargetNodeMetadata is the gRPC metadata key with which the gateway pins a
+// request to a named node, bypassing the scheduler. The gateway only sets it
+// for admin API keys.
+const TargetNodeMetadata = "x-orchion-node"
+
+// ModelLoadingStatus is the status of chat responses a node sends while it
+// starts the model. They carry no choices.
+const ModelLoadingStatus = "loading"
+
 // Service implements the OrchionLLM gRPC service
 type Service struct {
 	pb.UnimplementedOrchionLLMServer
 	registry  node.Registry
 	scheduler scheduler.Scheduler
+	latencies *scheduler.LatencyTracker
+	prefixes  *scheduler.PrefixAffinity
+	retries   *RetryBudget // Optional; nil disables failover after node failures
+	tunnels   *tunnel.Server
+	dialOpts  []grpc.DialOption
+	tailnet   bool // Dial agents at their tailnet address
 	// nodeClients maintains gRPC connections to node agents
 	nodeClients map[string]pb.NodeAgentClient
+	nodeConns   map[string]*grpc.ClientConn
 	mu          sync.RWMutex
 }
 
@@ -31,49 +54,148 @@ func NewService(registry node.Registry, sched scheduler.Scheduler) *Service {
 		registry:    registry,
 		scheduler:   sched,
 		nodeClients: make(map[string]pb.NodeAgentClient),
+		nodeConns:   make(map[string]*grpc.ClientConn),
 	}
 }
 
+// SetLatencyTracker sets the tracker that records per-node request latency
+func (s *Service) SetLatencyTracker(tracker *scheduler.LatencyTracker) {
+	s.latencies = tracker
+}
+
+// SetRetryBudget lets requests whose node became unreachable before answering
+// fail over once to another node, within the budget. Streamed chat
+// completions never fail over.
+func (s *Service) SetRetryBudget(budget *RetryBudget) {
+	s.retries = budget
+}
+
+// SetPrefixAffinity sets the tracker that pins prompt cache keys to nodes
+func (s *Service) SetPrefixAffinity(prefixes *scheduler.PrefixAffinity) {
+	s.prefixes = prefixes
+}
+
+// SetTunnels sets the server through which agents behind NAT connect in
+func (s *Service) SetTunnels(tunnels *tunnel.Server) {
+	s.tunnels = tunnels
+}
+
+// SetDialOptions adds options, such as message size limits, to connections
+// to node agents
+func (s *Service) SetDialOptions(opts ...grpc.DialOption) {
+	s.dialOpts = opts
+}
+
+// SetPreferTailnet dials agents that reported a tailnet address at it
+// instead of their agent address
+func (s *Service) SetPreferTailnet(prefer bool) {
+	s.tailnet = prefer
+}
+
 // ChatCompletion handles chat completion requests
 func (s *Service) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.OrchionLLM_ChatCompletionServer) error {
 	if req.Model == "" {
-		return status.Error(codes.InvalidArgument, "model is required")
+		return errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
 	}
 
 	if len(req.Messages) == 0 {
-		return status.Error(codes.InvalidArgument, "messages are required")
+		return errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "messages are required")
 	}
 
-	// Select a node for this model
-	selectedNode, err := s.scheduler.SelectNode(req.Model, s.registry)
-	if err != nil {
-		return status.Error(codes.NotFound, fmt.Sprintf("no node available for model %s: %v", req.Model, err))
+	// Keep the latest usage the engine reported for prefix cache statistics
+	var promptTokens, cachedTokens int32
+	defer func() {
+		if s.prefixes != nil && promptTokens > 0 {
+			s.prefixes.ObserveUsage(promptTokens, cachedTokens)
+		}
+	}()
+
+	if s.retries != nil {
+		s.retries.deposit()
+	}
+
+	schedReq := &scheduler.Request{Model: req.Model, Kind: scheduler.KindChatCompletion, CacheKey: req.PromptCacheKey}
+	var lastErr error
+	failedOver := false
+	for attempt := 1; ; attempt++ {
+		// Select a node for this model
+		selectedNode, targeted, err := s.selectNode(stream.Context(), schedReq)
+		if err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+		// Requests pinned for debugging shouldn't move a cache key's affinity
+		if s.prefixes != nil && !targeted {
+			s.prefixes.Record(req.PromptCacheKey, selectedNode.Id)
+		}
+
+		meter := newGenerationMeter(selectedNode.Id)
+		sent, err := s.forwardChat(selectedNode, req, stream, meter, &promptTokens, &cachedTokens)
+		if err == nil {
+			// Tell the gateway how fast the node was
+			if gen, ok := meter.generation(); ok {
+				stream.SetTrailer(gen.Metadata())
+			}
+			return nil
+		}
+		// Nodes reject requests they can't fit before streaming anything, and
+		// flapping nodes may drop a reply that isn't streamed; try another
+		if sent || targeted || attempt >= scheduler.MaxNodeAttempts {
+			return err
+		}
+		if !errcode.IsRetryable(err) && (req.Stream || !s.failover(err, &failedOver)) {
+			return err
+		}
+		schedReq.Exclude = append(schedReq.Exclude, selectedNode.Id)
+		lastErr = err
 	}
+}
 
+// forwardChat streams a chat completion from a node to the gateway, timing
+// its responses with meter. sent reports whether any output reached the
+// gateway before an error.
+func (s *Service) forwardChat(n *pb.Node, req *pb.ChatCompletionRequest, stream pb.OrchionLLM_ChatCompletionServer, meter *generationMeter, promptTokens, cachedTokens *int32) (sent bool, err error) {
 	// Get or create gRPC client for this node
-	client, err := s.getNodeClient(selectedNode.Id, selectedNode)
+	client, err := s.getNodeClient(n.Id, n)
 	if err != nil {
-		return status.Error(codes.Internal, fmt.Sprintf("failed to connect to node: %v", err))
+		return false, errcode.Errorf(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "failed to connect to node: %v", err)
 	}
 
-	// Forward request to node agent
-	nodeStream, err := client.ChatCompletion(context.Background(), req)
+	// Forward request to node agent. The node stops generating as soon as the
+	// gateway goes away or we stop reading.
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	nodeStream, err := client.ChatCompletion(ctx, req)
 	if err != nil {
-		return status.Error(codes.Internal, fmt.Sprintf("failed to call node agent: %v", err))
+		return false, nodeError("failed to call node agent", err)
 	}
 
 	// Stream responses back to gateway
 	for {
 		resp, err := nodeStream.Recv()
 		if err != nil {
-			if err == context.Canceled || err == context.DeadlineExceeded {
-				return nil
+			// Don't retry a request nobody is waiting for
+			if ctxErr := stream.Context().Err(); ctxErr != nil {
+				return sent, status.FromContextError(ctxErr).Err()
 			}
-			return status.Error(codes.Internal, fmt.Sprintf("error receiving from node: %v", err))
+			if err == io.EOF || err == context.Canceled || err == context.DeadlineExceeded {
+				return sent, nil
+			}
+			return sent, nodeError("error receiving from node", err)
+		}
+		meter.observe(resp)
+		if resp.UsagePromptTokens > 0 {
+			*promptTokens, *cachedTokens = resp.UsagePromptTokens, resp.UsageCachedTokens
 		}
 
 		if err := stream.Send(resp); err != nil {
-			return err
+			return true, err
+		}
+		// Loading reports carry no output, so another node may still take over
+		if resp.Status == "" {
+			sent = true
 		}
 	}
 }
@@ -81,31 +203,177 @@ func (s *Service) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.Orchio
 // Embeddings handles embedding requests
 func (s *Service) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
 	if req.Model == "" {
-		return nil, status.Error(codes.InvalidArgument, "model is required")
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
 	}
 
 	if len(req.Input) == 0 {
-		return nil, status.Error(codes.InvalidArgument, "input is required")
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "input is required")
 	}
 
-	// Select a node for this model
-	selectedNode, err := s.scheduler.SelectNode(req.Model, s.registry)
-	if err != nil {
-		return nil, status.Error(codes.NotFound, fmt.Sprintf("no node available for model %s: %v", req.Model, err))
+	if s.retries != nil {
+		s.retries.deposit()
 	}
 
-	// Get or create gRPC client for this node
-	client, err := s.getNodeClient(selectedNode.Id, selectedNode)
+	// Inputs go to the node and a vector per input comes back
+	schedReq := &scheduler.Request{
+		Model:        req.Model,
+		Kind:         scheduler.KindEmbeddings,
+		PayloadBytes: int64(proto.Size(req) + len(req.Input)*scheduler.EmbeddingVectorBytes),
+	}
+	var lastErr error
+	failedOver := false
+	for attempt := 1; ; attempt++ {
+		// Select a node for this model
+		selectedNode, targeted, err := s.selectNode(ctx, schedReq)
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		// Get or create gRPC client for this node
+		client, err := s.getNodeClient(selectedNode.Id, selectedNode)
+		if err != nil {
+			return nil, errcode.Errorf(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "failed to connect to node: %v", err)
+		}
+
+		// Forward request to node agent
+		start := time.Now()
+		resp, err := EmbedInChunks(ctx, req, func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+			return client.Embeddings(ctx, req)
+		})
+		if err != nil {
+			err = nodeError("failed to call node agent", err)
+			// Nodes reject requests they can't fit before running them, and
+			// flapping nodes drop them; try another
+			if targeted || attempt >= scheduler.MaxNodeAttempts || !(errcode.IsRetryable(err) || s.failover(err, &failedOver)) {
+				return nil, err
+			}
+			schedReq.Exclude = append(schedReq.Exclude, selectedNode.Id)
+			lastErr = err
+			continue
+		}
+		if s.latencies != nil {
+			s.latencies.Observe(selectedNode.Id, scheduler.KindEmbeddings, time.Since(start))
+		}
+		return resp, nil
+	}
+}
+
+// Speech handles text-to-speech requests
+func (s *Service) Speech(ctx context.Context, req *pb.SpeechRequest) (*pb.SpeechResponse, error) {
+	if req.Model == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "model is required")
+	}
+
+	if req.Input == "" {
+		return nil, errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, "input is required")
+	}
+
+	if s.retries != nil {
+		s.retries.deposit()
+	}
+
+	schedReq := &scheduler.Request{Model: req.Model, Kind: scheduler.KindSpeech}
+	var lastErr error
+	failedOver := false
+	for attempt := 1; ; attempt++ {
+		// Select a node for this model
+		selectedNode, targeted, err := s.selectNode(ctx, schedReq)
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		// Get or create gRPC client for this node
+		client, err := s.getNodeClient(selectedNode.Id, selectedNode)
+		if err != nil {
+			return nil, errcode.Errorf(codes.Unavailable, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "failed to connect to node: %v", err)
+		}
+
+		// Forward request to node agent
+		start := time.Now()
+		resp, err := client.Speech(ctx, req)
+		if err != nil {
+			err = nodeError("failed to call node agent", err)
+			if targeted || attempt >= scheduler.MaxNodeAttempts || !(errcode.IsRetryable(err) || s.failover(err, &failedOver)) {
+				return nil, err
+			}
+			schedReq.Exclude = append(schedReq.Exclude, selectedNode.Id)
+			lastErr = err
+			continue
+		}
+		if s.latencies != nil {
+			s.latencies.Observe(selectedNode.Id, scheduler.KindSpeech, time.Since(start))
+		}
+		return resp, nil
+	}
+}
+
+// selectNode picks the node to serve a request: the node named in the
+// TargetNodeMetadata if the gateway pinned one (targeted is then true),
+// otherwise the scheduler's choice
+func (s *Service) selectNode(ctx context.Context, req *scheduler.Request) (*pb.Node, bool, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(TargetNodeMetadata); len(values) > 0 && values[0] != "" {
+			n, ok := s.registry.Get(values[0])
+			if !ok {
+				return nil, true, errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "node %s is not registered", values[0])
+			}
+			return n, true, nil
+		}
+	}
+
+	n, err := s.scheduler.SelectNode(req, s.registry)
+	var vramErr *scheduler.InsufficientVRAMError
+	if errors.As(err, &vramErr) {
+		return nil, false, errcode.Errorf(codes.ResourceExhausted, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, "no node available for model %s: %v", req.Model, err)
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to connect to node: %v", err))
+		return nil, false, errcode.Errorf(codes.NotFound, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, "no node available for model %s: %v", req.Model, err)
 	}
+	return n, false, nil
+}
 
-	// Forward request to node agent
-	return client.Embeddings(ctx, req)
+// nodeError wraps an error returned by a node agent, preserving the error code
+// the agent attached so clients see the original failure class
+func nodeError(msg string, err error) error {
+	if errcode.Has(err) {
+		return err
+	}
+
+	code := errcode.FromError(err)
+	if code == pb.ErrorCode_ERROR_CODE_INTERNAL {
+		code = pb.ErrorCode_ERROR_CODE_ENGINE_ERROR
+	}
+	st, _ := status.FromError(err)
+	return errcode.Errorf(st.Code(), code, "%s: %v", msg, err)
+}
+
+// NodeClient returns the NodeAgent client for a node, connecting if needed
+func (s *Service) NodeClient(n *pb.Node) (pb.NodeAgentClient, error) {
+	return s.getNodeClient(n.Id, n)
+}
+
+// ForgetNode closes the connection to a node's agent, e.g. once the node is
+// evicted. The next call to the node connects again.
+func (s *Service) ForgetNode(nodeID string) {
+	s.mu.Lock()
+	conn := s.nodeConns[nodeID]
+	delete(s.nodeClients, nodeID)
+	delete(s.nodeConns, nodeID)
+	s.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
 }
 
 // getNodeClient gets or creates a gRPC client for a node
-func (s *Service) getNodeClient(nodeID string, node *pb.Node) (pb.NodeAgentClient, error) {
+func (s *Service) getNodeClient(nodeID string, n *pb.Node) (pb.NodeAgentClient, error) {
 	s.mu.RLock()
 	if client, exists := s.nodeClients[nodeID]; exists {
 		s.mu.RUnlock()
@@ -121,21 +389,29 @@ func (s *Service) getNodeClient(nodeID string, node *pb.Node) (pb.NodeAgentClien
 		return client, nil
 	}
 
-	// Determine node agent address
-	addr := node.AgentAddress
-	if addr == "" {
-		// Default to hostname:50052 if not specified
-		addr = fmt.Sprintf("%s:50052", node.Hostname)
+	addr := node.DialAddress(n, s.tailnet)
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, s.dialOpts...)
+	// Agents serving a node per GPU route calls by the target node
+	opts = append(opts, node.TargetDialOptions(nodeID)...)
+
+	// Agents behind NAT connect in; run the client over their tunnel
+	if n.Tunneled {
+		if s.tunnels == nil {
+			return nil, fmt.Errorf("node %s connects over a reverse tunnel but tunnels are disabled", nodeID)
+		}
+		addr = tunnel.Target(nodeID)
+		opts = append(opts, grpc.WithContextDialer(s.tunnels.Dial))
 	}
 
 	// Connect to node agent
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to node %s at %s: %w", nodeID, addr, err)
 	}
 
 	client := pb.NewNodeAgentClient(conn)
 	s.nodeClients[nodeID] = client
+	s.nodeConns[nodeID] = conn
 
 	return client, nil
 }
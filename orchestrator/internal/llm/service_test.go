@@ -2,17 +2,23 @@ package llm
 
 import (
 	"context"
+	"io"
 	"testing"
+	"sync"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
 )
 
 
@@ -31,11 +37,21 @@ func (m *MockRegistry) UpdateCapabilities(nodeID string, capabilities *pb.Capabi
 	return args.Error(0)
 }
 
+func (m *MockRegistry) UpdateModels(nodeID string, models []*pb.ModelEngine) error {
+	args := m.Called(nodeID, models)
+	return args.Error(0)
+}
+
 func (m *MockRegistry) UpdateHeartbeat(nodeID string) error {
 	args := m.Called(nodeID)
 	return args.Error(0)
 }
 
+func (m *MockRegistry) UpdateThrottle(nodeID string, throttle *pb.NodeThrottle) error {
+	args := m.Called(nodeID, throttle)
+	return args.Error(0)
+}
+
 func (m *MockRegistry) List() []*pb.Node {
 	args := m.Called()
 	return args.Get(0).([]*pb.Node)
@@ -60,13 +76,18 @@ func (m *MockRegistry) CheckHeartbeats(timeout time.Duration) []string {
 	return args.Get(0).([]string)
 }
 
+func (m *MockRegistry) Annotate(nodeID string, notes string, annotations map[string]string) error {
+	args := m.Called(nodeID, notes, annotations)
+	return args.Error(0)
+}
+
 // MockScheduler is a mock implementation of the Scheduler interface
 type MockScheduler struct {
 	mock.Mock
 }
 
-func (m *MockScheduler) SelectNode(model string, registry node.Registry) (*pb.Node, error) {
-	args := m.Called(model, registry)
+func (m *MockScheduler) SelectNode(req *scheduler.Request, registry node.Registry) (*pb.Node, error) {
+	args := m.Called(req, registry)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -199,3 +220,250 @@ func TestService_getNodeClient_ErrorHandling(t *testing.T) {
 	}
 }
 
+
+func TestService_selectNode(t *testing.T) {
+	schedReq := &scheduler.Request{Model: "llama3", Kind: scheduler.KindChatCompletion}
+	target := func(nodeID string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(TargetNodeMetadata, nodeID))
+	}
+
+	t.Run("scheduler picks the node by default", func(t *testing.T) {
+		mockScheduler := &MockScheduler{}
+		mockScheduler.On("SelectNode", schedReq, mock.Anything).Return(&pb.Node{Id: "scheduled"}, nil)
+		service := NewService(&MockRegistry{}, mockScheduler)
+
+		n, targeted, err := service.selectNode(context.Background(), schedReq)
+		require.NoError(t, err)
+		assert.Equal(t, "scheduled", n.Id)
+		assert.False(t, targeted)
+	})
+
+	t.Run("target node bypasses the scheduler", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		mockRegistry.On("Get", "node-x").Return(&pb.Node{Id: "node-x"}, true)
+		mockScheduler := &MockScheduler{}
+		service := NewService(mockRegistry, mockScheduler)
+
+		n, targeted, err := service.selectNode(target("node-x"), schedReq)
+		require.NoError(t, err)
+		assert.Equal(t, "node-x", n.Id)
+		assert.True(t, targeted)
+		mockScheduler.AssertNotCalled(t, "SelectNode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown target node", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		mockRegistry.On("Get", "missing").Return(nil, false)
+		service := NewService(mockRegistry, &MockScheduler{})
+
+		_, _, err := service.selectNode(target("missing"), schedReq)
+		require.Error(t, err)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_UNAVAILABLE, errcode.FromError(err))
+		assert.Contains(t, err.Error(), "missing")
+	})
+
+	t.Run("no node with enough VRAM", func(t *testing.T) {
+		mockScheduler := &MockScheduler{}
+		mockScheduler.On("SelectNode", schedReq, mock.Anything).Return(nil,
+			&scheduler.InsufficientVRAMError{Model: "llama3", RequiredMB: 42000, Node: "laptop", FreeMB: 6144})
+		service := NewService(&MockRegistry{}, mockScheduler)
+
+		_, _, err := service.selectNode(context.Background(), schedReq)
+		require.Error(t, err)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, errcode.FromError(err))
+		assert.Contains(t, err.Error(), "6144 MB on node laptop")
+	})
+}
+
+// fakeNodeClient is a node agent client answering every call with err, or
+// with resp if err is nil
+type fakeNodeClient struct {
+	pb.NodeAgentClient
+	err   error
+	resp  *pb.ChatCompletionResponse
+	block bool            // Chat streams wait for their context instead of ending
+	ctx   context.Context // Context of the last chat call
+	calls int
+}
+
+func (c *fakeNodeClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pb.EmbeddingResponse{Model: req.Model}, nil
+}
+
+func (c *fakeNodeClient) ChatCompletion(ctx context.Context, req *pb.ChatCompletionRequest, opts ...grpc.CallOption) (pb.NodeAgent_ChatCompletionClient, error) {
+	c.calls++
+	c.ctx = ctx
+	stream := &fakeChatClientStream{err: c.err, resp: c.resp}
+	if c.block {
+		stream.ctx = ctx
+	}
+	return stream, nil
+}
+
+// fakeChatClientStream returns resp (if any), then err or io.EOF. With ctx
+// set, it waits for ctx to be done instead.
+type fakeChatClientStream struct {
+	grpc.ClientStream
+	err  error
+	resp *pb.ChatCompletionResponse
+	ctx  context.Context
+}
+
+func (s *fakeChatClientStream) Recv() (*pb.ChatCompletionResponse, error) {
+	if s.resp != nil {
+		resp := s.resp
+		s.resp = nil
+		return resp, nil
+	}
+	if s.ctx != nil {
+		<-s.ctx.Done()
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return nil, io.EOF
+}
+
+// fakeChatServerStream collects the responses and trailer sent to the gateway
+type fakeChatServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context // Defaults to context.Background()
+	mu      sync.Mutex
+	sent    []*pb.ChatCompletionResponse
+	trailer metadata.MD
+}
+
+func (s *fakeChatServerStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *fakeChatServerStream) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return context.Background()
+}
+
+func (s *fakeChatServerStream) Send(resp *pb.ChatCompletionResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+func (s *fakeChatServerStream) sentResponses() []*pb.ChatCompletionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.ChatCompletionResponse(nil), s.sent...)
+}
+
+// excludingScheduler picks the first registered node the request doesn't exclude
+type excludingScheduler struct {
+	nodes []*pb.Node
+}
+
+func (s *excludingScheduler) SelectNode(req *scheduler.Request, registry node.Registry) (*pb.Node, error) {
+	for _, n := range s.nodes {
+		excluded := false
+		for _, id := range req.Exclude {
+			excluded = excluded || id == n.Id
+		}
+		if !excluded {
+			return n, nil
+		}
+	}
+	return nil, scheduler.ErrNoNodesAvailable
+}
+
+func TestService_retriesRejectedRequests(t *testing.T) {
+	vramRejected := func() error {
+		st := status.New(codes.ResourceExhausted, "insufficient VRAM for model llama3")
+		st, _ = st.WithDetails(&pb.ErrorInfo{Code: pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, Retryable: true})
+		return st.Err()
+	}
+	nodes := []*pb.Node{{Id: "node-1"}, {Id: "node-2"}}
+	newService := func(clients ...*fakeNodeClient) *Service {
+		service := NewService(&MockRegistry{}, &excludingScheduler{nodes: nodes})
+		for i, client := range clients {
+			service.nodeClients[nodes[i].Id] = client
+		}
+		return service
+	}
+	chatReq := &pb.ChatCompletionRequest{Model: "llama3", Messages: []*pb.ChatMessage{{Role: "user", Content: "Hi"}}}
+	reply := &pb.ChatCompletionResponse{Id: "chat-1"}
+
+	t.Run("embeddings move to another node", func(t *testing.T) {
+		full, spare := &fakeNodeClient{err: vramRejected()}, &fakeNodeClient{}
+		resp, err := newService(full, spare).Embeddings(context.Background(), &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+		require.NoError(t, err)
+		assert.Equal(t, "nomic-embed-text", resp.Model)
+		assert.Equal(t, 1, full.calls)
+		assert.Equal(t, 1, spare.calls)
+	})
+
+	t.Run("chat moves to another node", func(t *testing.T) {
+		full, spare := &fakeNodeClient{err: vramRejected()}, &fakeNodeClient{resp: reply}
+		stream := &fakeChatServerStream{}
+		require.NoError(t, newService(full, spare).ChatCompletion(chatReq, stream))
+		assert.Equal(t, []*pb.ChatCompletionResponse{reply}, stream.sent)
+		assert.Equal(t, 1, spare.calls)
+	})
+
+	t.Run("chat reports the node's speed", func(t *testing.T) {
+		answer := &pb.ChatCompletionResponse{Id: "chat-2", Choices: []*pb.ChatChoice{{Message: &pb.ChatMessage{Content: "Hello"}}}}
+		stream := &fakeChatServerStream{}
+		require.NoError(t, newService(&fakeNodeClient{resp: answer}).ChatCompletion(chatReq, stream))
+		assert.Equal(t, "node-1", GenerationFromMetadata(stream.trailer).Node)
+		assert.Len(t, stream.trailer.Get(TTFTTrailer), 1)
+		assert.Empty(t, stream.trailer.Get(TPSTrailer), "a single response has no rate")
+	})
+
+	t.Run("chat is cancelled on the node when the client goes away", func(t *testing.T) {
+		generating, spare := &fakeNodeClient{resp: reply, block: true}, &fakeNodeClient{resp: reply}
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &fakeChatServerStream{ctx: ctx}
+
+		done := make(chan error, 1)
+		go func() { done <- newService(generating, spare).ChatCompletion(chatReq, stream) }()
+		require.Eventually(t, func() bool { return len(stream.sentResponses()) == 1 }, time.Second, time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.Equal(t, codes.Canceled, status.Code(err))
+		case <-time.After(time.Second):
+			t.Fatal("chat kept streaming after the client went away")
+		}
+		assert.ErrorIs(t, generating.ctx.Err(), context.Canceled, "the node's stream is cancelled")
+		assert.Equal(t, 0, spare.calls)
+	})
+
+	t.Run("chat failing mid-stream is not retried", func(t *testing.T) {
+		partial, spare := &fakeNodeClient{resp: reply, err: vramRejected()}, &fakeNodeClient{resp: reply}
+		err := newService(partial, spare).ChatCompletion(chatReq, &fakeChatServerStream{})
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, errcode.FromError(err))
+		assert.Equal(t, 0, spare.calls)
+	})
+
+	t.Run("errors that aren't retryable are returned", func(t *testing.T) {
+		broken, spare := &fakeNodeClient{err: errcode.New(codes.Internal, pb.ErrorCode_ERROR_CODE_ENGINE_ERROR, "boom")}, &fakeNodeClient{}
+		_, err := newService(broken, spare).Embeddings(context.Background(), &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_ENGINE_ERROR, errcode.FromError(err))
+		assert.Equal(t, 0, spare.calls)
+	})
+
+	t.Run("every node rejecting returns the node's error", func(t *testing.T) {
+		first, second := &fakeNodeClient{err: vramRejected()}, &fakeNodeClient{err: vramRejected()}
+		_, err := newService(first, second).Embeddings(context.Background(), &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, errcode.FromError(err))
+		assert.Equal(t, 1, second.calls)
+	})
+}
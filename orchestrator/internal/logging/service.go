@@ -3,45 +3,106 @@ package logging
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
 	"github.com/Orchion/Orchion/shared/logging"
 )
 
+// DefaultClientBuffer is how many log entries are buffered for each client;
+// once a client falls that far behind, its oldest entries are dropped
+const DefaultClientBuffer = 256
+
 // Service implements the LogStreamer gRPC service
 type Service struct {
 	pb.UnimplementedLogStreamerServer
 	mu      sync.RWMutex
-	clients map[string]pb.LogStreamer_StreamLogsServer
+	clients map[string]*Subscription
 }
 
 // NewService creates a new logging service
 func NewService() *Service {
 	return &Service{
-		clients: make(map[string]pb.LogStreamer_StreamLogsServer),
+		clients: make(map[string]*Subscription),
 	}
 }
 
-// StreamLogs handles streaming log entries to connected clients
-func (s *Service) StreamLogs(req *pb.StreamLogsRequest, stream pb.LogStreamer_StreamLogsServer) error {
-	// Generate a client ID
+// Subscription buffers the log entries broadcast to one client. Slow clients
+// lose their oldest entries rather than holding back logging or growing the
+// buffer.
+type Subscription struct {
+	entries chan *pb.LogEntry
+	mu      sync.Mutex // Serializes pushes, so dropping and adding stay paired
+	dropped atomic.Uint64
+}
+
+// Entries delivers the buffered log entries, oldest first
+func (s *Subscription) Entries() <-chan *pb.LogEntry {
+	return s.entries
+}
+
+// Dropped returns how many entries were dropped so far because the client
+// fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// push buffers an entry, dropping the oldest one if the buffer is full
+func (s *Subscription) push(entry *pb.LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		select {
+		case s.entries <- entry:
+			return
+		default:
+		}
+		select {
+		case <-s.entries:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+// Subscribe registers a client for broadcast log entries, buffering up to
+// buffer of them; call the returned function once the client is gone
+func (s *Service) Subscribe(buffer int) (*Subscription, func()) {
+	if buffer <= 0 {
+		buffer = DefaultClientBuffer
+	}
+	sub := &Subscription{entries: make(chan *pb.LogEntry, buffer)}
 	clientID := generateClientID()
 
 	s.mu.Lock()
-	s.clients[clientID] = stream
+	s.clients[clientID] = sub
 	s.mu.Unlock()
 
-	// Clean up when client disconnects
-	defer func() {
+	return sub, func() {
 		s.mu.Lock()
 		delete(s.clients, clientID)
 		s.mu.Unlock()
-	}()
+	}
+}
 
-	// Keep the stream open
-	<-stream.Context().Done()
-	return stream.Context().Err()
+// StreamLogs handles streaming log entries to connected clients
+func (s *Service) StreamLogs(req *pb.StreamLogsRequest, stream pb.LogStreamer_StreamLogsServer) error {
+	sub, unsubscribe := s.Subscribe(DefaultClientBuffer)
+	defer unsubscribe()
+
+	// Sends block while the client is slow, so entries pile up in the
+	// subscription, which drops the oldest
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case entry := <-sub.Entries():
+			if err := stream.Send(&pb.StreamLogsResponse{Entry: entry}); err != nil {
+				return err
+			}
+		}
+	}
 }
 
 // Broadcast sends a log entry to all connected clients
@@ -58,12 +119,8 @@ func (s *Service) Broadcast(entry *logging.LogEntry) {
 		Fields:    entry.Fields,
 	}
 
-	for clientID, stream := range s.clients {
-		go func(clientID string, stream pb.LogStreamer_StreamLogsServer) {
-			if err := stream.Send(&pb.StreamLogsResponse{Entry: pbEntry}); err != nil {
-				// Client disconnected, will be cleaned up on next send
-			}
-		}(clientID, stream)
+	for _, sub := range s.clients {
+		sub.push(pbEntry)
 	}
 }
 
@@ -83,7 +140,10 @@ func (s *Service) convertLevel(level logging.Level) pb.LogLevel {
 	}
 }
 
+// clientSeq tells apart clients subscribing within the same nanosecond
+var clientSeq atomic.Uint64
+
 // generateClientID generates a unique client ID
 func generateClientID() string {
-	return fmt.Sprintf("client-%d", time.Now().UnixNano())
+	return fmt.Sprintf("client-%d-%d", time.Now().UnixNano(), clientSeq.Add(1))
 }
@@ -132,4 +132,22 @@ func TestService_ConcurrentBroadcast(t *testing.T) {
 	assert.NotNil(t, service)
 	assert.NotNil(t, service.clients)
 	assert.Len(t, service.clients, 0) // No clients connected
-}
\ No newline at end of file
+}
+func TestService_SubscribeDropsOldest(t *testing.T) {
+	service := NewService()
+	sub, unsubscribe := service.Subscribe(2)
+
+	for i := 1; i <= 5; i++ {
+		service.Broadcast(&logging.LogEntry{ID: fmt.Sprintf("entry-%d", i), Level: logging.InfoLevel})
+	}
+
+	// The slow client keeps the newest entries
+	assert.Equal(t, uint64(3), sub.Dropped())
+	assert.Equal(t, "entry-4", (<-sub.Entries()).Id)
+	assert.Equal(t, "entry-5", (<-sub.Entries()).Id)
+
+	unsubscribe()
+	assert.Len(t, service.clients, 0)
+	service.Broadcast(&logging.LogEntry{ID: "entry-6"})
+	assert.Len(t, sub.Entries(), 0)
+}
@@ -0,0 +1,20 @@
+package node
+
+import (
+	"fmt"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// DialAddress returns the address the orchestrator connects to a node's
+// agent at: its tailnet address if it reported one and preferTailnet is
+// set, else its agent address, else its hostname on the default agent port
+func DialAddress(n *pb.Node, preferTailnet bool) string {
+	if preferTailnet && n.TailnetAddress != "" {
+		return n.TailnetAddress
+	}
+	if n.AgentAddress != "" {
+		return n.AgentAddress
+	}
+	return fmt.Sprintf("%s:50052", n.Hostname)
+}
@@ -0,0 +1,19 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestDialAddress(t *testing.T) {
+	onTailnet := &pb.Node{Hostname: "gpu-1", AgentAddress: "192.168.1.20:50052", TailnetAddress: "100.101.102.103:50052"}
+	offTailnet := &pb.Node{Hostname: "gpu-2", AgentAddress: "192.168.1.21:50052"}
+
+	assert.Equal(t, "100.101.102.103:50052", DialAddress(onTailnet, true))
+	assert.Equal(t, "192.168.1.20:50052", DialAddress(onTailnet, false))
+	assert.Equal(t, "192.168.1.21:50052", DialAddress(offTailnet, true))
+	assert.Equal(t, "gpu-3:50052", DialAddress(&pb.Node{Hostname: "gpu-3"}, false))
+}
@@ -0,0 +1,173 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// DefaultEventCapacity bounds the events kept per node
+const DefaultEventCapacity = 100
+
+// DefaultEventRetention is how long node events are kept, including those of
+// nodes that were evicted
+const DefaultEventRetention = 24 * time.Hour
+
+// DrainingLabel marks a node operators are taking out of rotation; label
+// selectors exclude it with "!draining"
+const DrainingLabel = "draining"
+
+// Node event types
+const (
+	EventRegistered          = "registered"
+	EventHeartbeatLost       = "heartbeat_lost"
+	EventHeartbeatRestored   = "heartbeat_restored"
+	EventEvicted             = "evicted"
+	EventRestored            = "restored"
+	EventDrained             = "drained"
+	EventCapabilitiesChanged = "capabilities_changed"
+)
+
+// Event is something that happened to a node
+type Event struct {
+	TimestampMs int64  `json:"timestamp_ms"`
+	Type        string `json:"type"`
+	Message     string `json:"message,omitempty"`
+}
+
+// EventLog keeps a bounded history of events per node, so connectivity
+// problems can be diagnosed after the fact. Events outlive the node's
+// registration until they expire.
+type EventLog struct {
+	capacity  int
+	retention time.Duration
+	now       func() time.Time
+
+	mu     sync.RWMutex
+	events map[string][]Event
+}
+
+// NewEventLog creates a log keeping up to capacity events per node for the
+// retention period
+func NewEventLog(capacity int, retention time.Duration) *EventLog {
+	if capacity <= 0 {
+		capacity = DefaultEventCapacity
+	}
+	if retention <= 0 {
+		retention = DefaultEventRetention
+	}
+	return &EventLog{
+		capacity:  capacity,
+		retention: retention,
+		now:       time.Now,
+		events:    make(map[string][]Event),
+	}
+}
+
+// Record adds an event to a node's history, dropping its oldest event once
+// the history is full
+func (l *EventLog) Record(nodeID, eventType, message string) {
+	event := Event{TimestampMs: l.now().UnixMilli(), Type: eventType, Message: message}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := l.unexpiredLocked(nodeID)
+	if len(events) >= l.capacity {
+		events = events[len(events)-l.capacity+1:]
+	}
+	l.events[nodeID] = append(events, event)
+}
+
+// Last returns a node's most recent event
+func (l *EventLog) Last(nodeID string) (Event, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	events := l.events[nodeID]
+	if len(events) == 0 {
+		return Event{}, false
+	}
+	return events[len(events)-1], true
+}
+
+// Events returns a node's unexpired events, oldest first
+func (l *EventLog) Events(nodeID string) []Event {
+	since := l.now().Add(-l.retention).UnixMilli()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	events := make([]Event, 0, len(l.events[nodeID]))
+	for _, e := range l.events[nodeID] {
+		if e.TimestampMs >= since {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Prune drops expired events, and forgets nodes left without any
+func (l *EventLog) Prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for nodeID := range l.events {
+		l.unexpiredLocked(nodeID)
+	}
+}
+
+// unexpiredLocked drops a node's expired events and returns the rest
+func (l *EventLog) unexpiredLocked(nodeID string) []Event {
+	events := l.events[nodeID]
+	since := l.now().Add(-l.retention).UnixMilli()
+	i := 0
+	for i < len(events) && events[i].TimestampMs < since {
+		i++
+	}
+	if i == len(events) {
+		delete(l.events, nodeID)
+		return nil
+	}
+	if i > 0 {
+		events = append([]Event(nil), events[i:]...)
+		l.events[nodeID] = events
+	}
+	return events
+}
+
+// CapabilityChanges describes the changes between two capability reports,
+// e.g. "gpu_vram_total: 24.0 GB -> 48.0 GB". Fluctuating readings such as
+// free VRAM or temperature are left to the hardware history.
+func CapabilityChanges(prev, next *pb.Capabilities) []string {
+	fields := []struct {
+		name       string
+		prev, next string
+	}{
+		{"cpu", prev.GetCpu(), next.GetCpu()},
+		{"memory", prev.GetMemory(), next.GetMemory()},
+		{"os", prev.GetOs(), next.GetOs()},
+		{"gpu_type", prev.GetGpuType(), next.GetGpuType()},
+		{"gpu_vram_total", prev.GetGpuVramTotal(), next.GetGpuVramTotal()},
+		{"gpu_backend", prev.GetGpuBackend().String(), next.GetGpuBackend().String()},
+		{"unified_memory", fmt.Sprint(prev.GetUnifiedMemory()), fmt.Sprint(next.GetUnifiedMemory())},
+	}
+
+	var changes []string
+	for _, f := range fields {
+		if f.prev != f.next {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", f.name, orUnknown(f.prev), orUnknown(f.next)))
+		}
+	}
+	return changes
+}
+
+// orUnknown returns s, or "unknown" for readings a node didn't report
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestEventLog_Record(t *testing.T) {
+	l := NewEventLog(3, time.Hour)
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+
+	l.Record("node-1", EventRegistered, "host-1 at 10.0.0.5:50052")
+	l.Record("node-1", EventHeartbeatLost, "")
+	l.Record("node-2", EventRegistered, "")
+
+	events := l.Events("node-1")
+	require.Len(t, events, 2)
+	assert.Equal(t, Event{TimestampMs: now.UnixMilli(), Type: EventRegistered, Message: "host-1 at 10.0.0.5:50052"}, events[0])
+	assert.Equal(t, EventHeartbeatLost, events[1].Type)
+
+	last, ok := l.Last("node-1")
+	require.True(t, ok)
+	assert.Equal(t, EventHeartbeatLost, last.Type)
+	_, ok = l.Last("missing")
+	assert.False(t, ok)
+	assert.Empty(t, l.Events("missing"))
+
+	// A full history drops its oldest events
+	l.Record("node-1", EventHeartbeatRestored, "")
+	l.Record("node-1", EventEvicted, "")
+	events = l.Events("node-1")
+	require.Len(t, events, 3)
+	assert.Equal(t, EventHeartbeatLost, events[0].Type)
+	assert.Equal(t, EventEvicted, events[2].Type)
+}
+
+func TestEventLog_Retention(t *testing.T) {
+	l := NewEventLog(10, time.Hour)
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+
+	l.Record("node-1", EventRegistered, "")
+	l.Record("node-2", EventRegistered, "")
+	now = now.Add(45 * time.Minute)
+	l.Record("node-1", EventEvicted, "")
+
+	now = now.Add(30 * time.Minute)
+	events := l.Events("node-1")
+	require.Len(t, events, 1)
+	assert.Equal(t, EventEvicted, events[0].Type)
+	assert.Empty(t, l.Events("node-2"))
+
+	l.Prune()
+	assert.NotContains(t, l.events, "node-2", "nodes without events are forgotten")
+	assert.Len(t, l.events["node-1"], 1)
+}
+
+func TestCapabilityChanges(t *testing.T) {
+	prev := &pb.Capabilities{Cpu: "8 cores", GpuVramTotal: "24.0 GB", GpuVramAvailable: "20.0 GB", GpuTemperature: "60°C"}
+	next := &pb.Capabilities{Cpu: "8 cores", GpuVramTotal: "48.0 GB", GpuVramAvailable: "10.0 GB", GpuTemperature: "80°C", GpuType: "NVIDIA A6000"}
+
+	assert.Equal(t, []string{
+		"gpu_type: unknown -> NVIDIA A6000",
+		"gpu_vram_total: 24.0 GB -> 48.0 GB",
+	}, CapabilityChanges(prev, next))
+	assert.Empty(t, CapabilityChanges(prev, prev))
+}
@@ -0,0 +1,190 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// DefaultCheckInterval is how often the heartbeat monitor checks nodes
+const DefaultCheckInterval = 10 * time.Second
+
+// HeartbeatMonitor periodically checks when nodes last sent a heartbeat. It
+// tells subscribers when a node goes stale and evicts nodes that stay silent
+// past the timeout.
+type HeartbeatMonitor struct {
+	registry Registry
+	timeout  time.Duration
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	lost    map[string]bool // Nodes subscribers were told went stale
+	onLost  []func(nodeID string)
+	onEvict []func(nodeID string)
+	onCheck []func()
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewHeartbeatMonitor creates a monitor evicting nodes without a heartbeat
+// for longer than timeout
+func NewHeartbeatMonitor(registry Registry, timeout time.Duration) *HeartbeatMonitor {
+	return &HeartbeatMonitor{
+		registry: registry,
+		timeout:  timeout,
+		interval: DefaultCheckInterval,
+		now:      time.Now,
+		lost:     make(map[string]bool),
+	}
+}
+
+// OnLost subscribes to nodes going stale, called once each time a node goes
+// DefaultStaleAfter without a heartbeat
+func (m *HeartbeatMonitor) OnLost(fn func(nodeID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onLost = append(m.onLost, fn)
+}
+
+// OnEvict subscribes to nodes being removed from the registry
+func (m *HeartbeatMonitor) OnEvict(fn func(nodeID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvict = append(m.onEvict, fn)
+}
+
+// OnCheck subscribes to every check, for housekeeping that runs on the
+// monitor's schedule
+func (m *HeartbeatMonitor) OnCheck(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onCheck = append(m.onCheck, fn)
+}
+
+// Start checks nodes in the background until Stop is called or ctx is done
+func (m *HeartbeatMonitor) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go m.run(ctx, m.done)
+}
+
+// Stop stops the monitor and waits for a check in progress to finish
+func (m *HeartbeatMonitor) Stop() {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel, m.done = nil, nil
+	m.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// run checks nodes on every tick
+func (m *HeartbeatMonitor) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check runs one pass over the registry, notifying subscribers of nodes that
+// went stale and evicting those past the timeout
+func (m *HeartbeatMonitor) Check(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	now := m.now()
+
+	m.mu.Lock()
+	onLost := append([]func(string){}, m.onLost...)
+	onEvict := append([]func(string){}, m.onEvict...)
+	onCheck := append([]func(){}, m.onCheck...)
+	m.mu.Unlock()
+
+	for _, fn := range onCheck {
+		fn()
+	}
+
+	nodes := m.registry.List()
+	var lost, evict []string
+
+	m.mu.Lock()
+	seen := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		seen[n.Id] = true
+		silent := now.Sub(time.Unix(n.LastSeenUnix, 0))
+		if silent <= DefaultStaleAfter && silent <= m.timeout {
+			delete(m.lost, n.Id)
+			continue
+		}
+
+		if !m.lost[n.Id] {
+			m.lost[n.Id] = true
+			lost = append(lost, n.Id)
+		}
+		if silent > m.timeout {
+			evict = append(evict, n.Id)
+		}
+	}
+	// Forget nodes removed by other means, so they're reported again if
+	// they come back and go stale
+	for nodeID := range m.lost {
+		if !seen[nodeID] {
+			delete(m.lost, nodeID)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, nodeID := range lost {
+		for _, fn := range onLost {
+			fn(nodeID)
+		}
+	}
+
+	if len(evict) == 0 {
+		return
+	}
+	logger.Warn("Found stale nodes, removing", map[string]interface{}{
+		"count":   len(evict),
+		"timeout": m.timeout,
+	})
+	for _, nodeID := range evict {
+		if err := m.registry.Remove(nodeID); err != nil {
+			logger.Error("Failed to remove stale node", map[string]interface{}{
+				"node_id": nodeID,
+				"error":   err.Error(),
+			})
+			continue
+		}
+
+		m.mu.Lock()
+		delete(m.lost, nodeID)
+		m.mu.Unlock()
+
+		for _, fn := range onEvict {
+			fn(nodeID)
+		}
+		logger.Info("Removed stale node", map[string]interface{}{
+			"node_id": nodeID,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestHeartbeatMonitor_Check(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-2", Hostname: "host-2"}))
+
+	start := time.Unix(1700000000, 0)
+	heartbeat := func(nodeID string, at time.Time) {
+		registry.nodes[nodeID].LastSeenUnix = at.Unix()
+	}
+	heartbeat("node-1", start)
+	heartbeat("node-2", start)
+
+	monitor := NewHeartbeatMonitor(registry, time.Minute)
+	now := start
+	monitor.now = func() time.Time { return now }
+
+	var lost, evicted []string
+	checks := 0
+	monitor.OnLost(func(nodeID string) { lost = append(lost, nodeID) })
+	monitor.OnEvict(func(nodeID string) { evicted = append(evicted, nodeID) })
+	monitor.OnCheck(func() { checks++ })
+
+	monitor.Check(context.Background())
+	assert.Empty(t, lost)
+	assert.Empty(t, evicted)
+
+	// Both nodes go stale; each is reported once
+	now = start.Add(30 * time.Second)
+	monitor.Check(context.Background())
+	monitor.Check(context.Background())
+	assert.ElementsMatch(t, []string{"node-1", "node-2"}, lost)
+	assert.Empty(t, evicted)
+
+	// node-2 sends a heartbeat, node-1 stays silent past the timeout
+	now = start.Add(61 * time.Second)
+	heartbeat("node-2", now)
+	monitor.Check(context.Background())
+	assert.Equal(t, []string{"node-1"}, evicted)
+	_, ok := registry.Get("node-1")
+	assert.False(t, ok)
+	_, ok = registry.Get("node-2")
+	assert.True(t, ok)
+
+	// node-2 going stale again is reported again
+	lost = nil
+	now = start.Add(80 * time.Second)
+	monitor.Check(context.Background())
+	assert.Equal(t, []string{"node-2"}, lost)
+
+	assert.Equal(t, 5, checks)
+}
+
+func TestHeartbeatMonitor_StartStop(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+
+	monitor := NewHeartbeatMonitor(registry, time.Minute)
+	monitor.interval = time.Millisecond
+	monitor.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	evicted := make(chan string, 1)
+	monitor.OnEvict(func(nodeID string) { evicted <- nodeID })
+
+	monitor.Start(context.Background())
+	select {
+	case nodeID := <-evicted:
+		assert.Equal(t, "node-1", nodeID)
+	case <-time.After(time.Second):
+		t.Fatal("monitor should have evicted the node")
+	}
+
+	monitor.Stop()
+	// Stopping twice is harmless
+	monitor.Stop()
+}
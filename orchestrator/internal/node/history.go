@@ -0,0 +1,132 @@
+package node
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// DefaultHistoryCapacity keeps an hour of samples at the default 5s heartbeat interval
+const DefaultHistoryCapacity = 720
+
+// Sample is a node's hardware readings at one point in time. Readings the node
+// doesn't report are nil.
+type Sample struct {
+	TimestampMs  int64    `json:"timestamp_ms"`
+	VRAMUsedMB   *float64 `json:"vram_used_mb,omitempty"`
+	VRAMTotalMB  *float64 `json:"vram_total_mb,omitempty"`
+	TemperatureC *float64 `json:"gpu_temperature_c,omitempty"`
+	GPUPowerW    *float64 `json:"gpu_power_w,omitempty"`
+}
+
+// History keeps a fixed number of recent hardware samples per node, recorded
+// from heartbeats, for dashboard graphs
+type History struct {
+	capacity int
+	now      func() time.Time
+
+	mu     sync.RWMutex
+	series map[string]*sampleRing
+}
+
+// NewHistory creates a history keeping up to capacity samples per node
+func NewHistory(capacity int) *History {
+	if capacity <= 0 {
+		capacity = DefaultHistoryCapacity
+	}
+	return &History{
+		capacity: capacity,
+		now:      time.Now,
+		series:   make(map[string]*sampleRing),
+	}
+}
+
+// Record adds a sample of the node's current capabilities
+func (h *History) Record(n *pb.Node) {
+	sample := newSample(n.Capabilities, h.now())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.series[n.Id]
+	if !ok {
+		ring = &sampleRing{samples: make([]Sample, h.capacity)}
+		h.series[n.Id] = ring
+	}
+	ring.add(sample)
+}
+
+// Samples returns a node's samples from the last window, oldest first
+func (h *History) Samples(nodeID string, window time.Duration) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ring, ok := h.series[nodeID]
+	if !ok {
+		return []Sample{}
+	}
+
+	since := h.now().Add(-window).UnixMilli()
+	samples := make([]Sample, 0, ring.count)
+	for _, s := range ring.ordered() {
+		if s.TimestampMs >= since {
+			samples = append(samples, s)
+		}
+	}
+	return samples
+}
+
+// Remove drops a node's samples
+func (h *History) Remove(nodeID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.series, nodeID)
+}
+
+// sampleRing is a fixed-size ring buffer of samples
+type sampleRing struct {
+	samples []Sample
+	next    int
+	count   int
+}
+
+// add appends a sample, overwriting the oldest once full
+func (r *sampleRing) add(s Sample) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.count < len(r.samples) {
+		r.count++
+	}
+}
+
+// ordered returns the samples oldest first
+func (r *sampleRing) ordered() []Sample {
+	start := (r.next - r.count + len(r.samples)) % len(r.samples)
+	ordered := make([]Sample, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		ordered = append(ordered, r.samples[(start+i)%len(r.samples)])
+	}
+	return ordered
+}
+
+// newSample parses capability readings such as "7.5 GB", "65°C" or "120.5 W"
+func newSample(caps *pb.Capabilities, t time.Time) Sample {
+	return Sample{
+		TimestampMs:  t.UnixMilli(),
+		VRAMUsedMB:   reading(units.ParseMegabytes(caps.GetGpuVramUsed())),
+		VRAMTotalMB:  reading(units.ParseMegabytes(caps.GetGpuVramTotal())),
+		TemperatureC: reading(units.ParseNumber(caps.GetGpuTemperature())),
+		GPUPowerW:    reading(units.ParseNumber(caps.GetGpuPowerUsage())),
+	}
+}
+
+// reading returns a parsed reading, or nil for readings such as "N/A" or
+// "Unknown" that don't parse
+func reading(value float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &value
+}
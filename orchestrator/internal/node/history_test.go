@@ -0,0 +1,86 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func testNode(vramUsed string) *pb.Node {
+	return &pb.Node{
+		Id: "node-1",
+		Capabilities: &pb.Capabilities{
+			GpuVramUsed:    vramUsed,
+			GpuVramTotal:   "24.0 GB",
+			GpuTemperature: "65°C",
+			GpuPowerUsage:  "120.5 W",
+		},
+	}
+}
+
+func TestHistory_Record(t *testing.T) {
+	h := NewHistory(10)
+	now := time.Unix(1700000000, 0)
+	h.now = func() time.Time { return now }
+
+	h.Record(testNode("512 MB"))
+
+	samples := h.Samples("node-1", time.Hour)
+	require.Len(t, samples, 1)
+	s := samples[0]
+	assert.Equal(t, now.UnixMilli(), s.TimestampMs)
+	assert.Equal(t, 512.0, *s.VRAMUsedMB)
+	assert.Equal(t, 24576.0, *s.VRAMTotalMB)
+	assert.Equal(t, 65.0, *s.TemperatureC)
+	assert.Equal(t, 120.5, *s.GPUPowerW)
+}
+
+func TestHistory_UnavailableReadings(t *testing.T) {
+	h := NewHistory(10)
+	h.Record(&pb.Node{Id: "cpu-node", Capabilities: &pb.Capabilities{
+		GpuVramUsed:    "N/A",
+		GpuTemperature: "Unknown",
+	}})
+	h.Record(&pb.Node{Id: "bare-node"})
+
+	for _, id := range []string{"cpu-node", "bare-node"} {
+		samples := h.Samples(id, time.Hour)
+		require.Len(t, samples, 1)
+		assert.Nil(t, samples[0].VRAMUsedMB)
+		assert.Nil(t, samples[0].TemperatureC)
+	}
+}
+
+func TestHistory_RingBufferAndWindow(t *testing.T) {
+	h := NewHistory(3)
+	start := time.Unix(1700000000, 0)
+	now := start
+	h.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		now = start.Add(time.Duration(i) * time.Minute)
+		h.Record(testNode("1 GB"))
+	}
+
+	// Only the newest three samples are kept, oldest first
+	samples := h.Samples("node-1", time.Hour)
+	require.Len(t, samples, 3)
+	assert.Equal(t, start.Add(2*time.Minute).UnixMilli(), samples[0].TimestampMs)
+	assert.Equal(t, start.Add(4*time.Minute).UnixMilli(), samples[2].TimestampMs)
+
+	// The window trims older samples
+	assert.Len(t, h.Samples("node-1", 90*time.Second), 2)
+}
+
+func TestHistory_Remove(t *testing.T) {
+	h := NewHistory(10)
+	h.Record(testNode("1 GB"))
+	h.Remove("node-1")
+
+	assert.Empty(t, h.Samples("node-1", time.Hour))
+	assert.NotNil(t, h.Samples("unknown", time.Hour))
+}
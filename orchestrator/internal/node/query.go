@@ -0,0 +1,210 @@
+package node
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// DefaultStaleAfter is how long a node may go without a heartbeat before it
+// is reported stale (three missed heartbeats at the default 5s interval).
+// Stale nodes stay listed until the heartbeat monitor removes them.
+const DefaultStaleAfter = 15 * time.Second
+
+// Node statuses reported to the dashboard
+const (
+	StatusOnline = "online"
+	StatusStale  = "stale"
+)
+
+// Status reports whether a node has sent a heartbeat recently
+func Status(n *pb.Node, now time.Time) string {
+	if now.Sub(time.Unix(n.LastSeenUnix, 0)) > DefaultStaleAfter {
+		return StatusStale
+	}
+	return StatusOnline
+}
+
+// HasGPU reports whether a node advertises a usable GPU
+func HasGPU(n *pb.Node) bool {
+	caps := n.GetCapabilities()
+	if caps == nil {
+		return false
+	}
+
+	switch caps.GpuBackend {
+	case pb.GpuBackend_GPU_BACKEND_UNSPECIFIED:
+		// Agents predating typed backends only report the GPU name
+		gpu := strings.TrimSpace(caps.GpuType)
+		return gpu != "" && gpu != "No GPU detected"
+	case pb.GpuBackend_GPU_BACKEND_CPU:
+		return false
+	default:
+		return true
+	}
+}
+
+// labelRequirement is one term of a label selector
+type labelRequirement struct {
+	key   string
+	value string
+	op    string // "=", "!=", "exists" or "!exists"
+}
+
+// Selector matches node labels, e.g. "zone=eu-west,tier!=spot,gpu-pool,!draining"
+type Selector []labelRequirement
+
+// ParseSelector parses a comma-separated label selector. Each term is
+// key=value, key!=value, key (label present) or !key (label absent).
+func ParseSelector(s string) (Selector, error) {
+	var selector Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var req labelRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			req = labelRequirement{key: parts[0], value: parts[1], op: "!="}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			req = labelRequirement{key: parts[0], value: parts[1], op: "="}
+		case strings.HasPrefix(term, "!"):
+			req = labelRequirement{key: term[1:], op: "!exists"}
+		default:
+			req = labelRequirement{key: term, op: "exists"}
+		}
+
+		req.key = strings.TrimSpace(req.key)
+		req.value = strings.TrimSpace(req.value)
+		if req.key == "" {
+			return nil, fmt.Errorf("invalid label selector term %q", term)
+		}
+		selector = append(selector, req)
+	}
+	return selector, nil
+}
+
+// Matches reports whether labels satisfy every term of the selector
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.key]
+		switch req.op {
+		case "=":
+			if !ok || value != req.value {
+				return false
+			}
+		case "!=":
+			if ok && value == req.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Query filters and orders nodes for listing
+type Query struct {
+	Status   string // StatusOnline or StatusStale; empty matches any
+	Selector Selector
+	GPU      *bool // Match nodes with (true) or without (false) a usable GPU
+	OrderBy  string
+	Now      time.Time
+}
+
+// NewQuery builds a query from a ListNodes request
+func NewQuery(req *pb.ListNodesRequest, now time.Time) (*Query, error) {
+	switch req.Status {
+	case "", StatusOnline, StatusStale:
+	default:
+		return nil, fmt.Errorf("invalid status %q (want %q or %q)", req.Status, StatusOnline, StatusStale)
+	}
+
+	selector, err := ParseSelector(req.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := SortKey(req.OrderBy); err != nil {
+		return nil, err
+	}
+
+	return &Query{
+		Status:   req.Status,
+		Selector: selector,
+		GPU:      req.Gpu,
+		OrderBy:  req.OrderBy,
+		Now:      now,
+	}, nil
+}
+
+// Filter returns the nodes matching the query, in their original order
+func (q *Query) Filter(nodes []*pb.Node) []*pb.Node {
+	matched := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if q.Status != "" && Status(n, q.Now) != q.Status {
+			continue
+		}
+		if q.GPU != nil && HasGPU(n) != *q.GPU {
+			continue
+		}
+		if !q.Selector.Matches(n.Labels) {
+			continue
+		}
+		matched = append(matched, n)
+	}
+	return matched
+}
+
+// SortKey returns a function mapping nodes to strings that sort in the
+// requested order, ties broken by node ID. orderBy is "id" (or empty),
+// "vram_free" or "last_seen", optionally prefixed with "-" for descending.
+func SortKey(orderBy string) (func(*pb.Node) string, error) {
+	descending := strings.HasPrefix(orderBy, "-")
+	field := strings.TrimPrefix(orderBy, "-")
+
+	switch field {
+	case "", "id":
+		if descending {
+			return nil, fmt.Errorf("descending order is not supported for %q", field)
+		}
+		return (*pb.Node).GetId, nil
+	case "vram_free":
+		return func(n *pb.Node) string {
+			// Nodes not reporting free VRAM sort as having none
+			free := 0.0
+			if v, ok := units.ParseMegabytes(n.GetCapabilities().GetGpuVramAvailable()); ok && v > 0 {
+				free = v
+			}
+			if descending {
+				free = math.MaxInt32 - free
+			}
+			return fmt.Sprintf("%020.3f/%s", free, n.Id)
+		}, nil
+	case "last_seen":
+		return func(n *pb.Node) string {
+			seen := n.LastSeenUnix
+			if descending {
+				seen = math.MaxInt64 - seen
+			}
+			return fmt.Sprintf("%020d/%s", seen, n.Id)
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid order_by %q (want id, vram_free or last_seen)", orderBy)
+	}
+}
@@ -0,0 +1,149 @@
+package node
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestHasGPU(t *testing.T) {
+	assert.True(t, HasGPU(&pb.Node{Capabilities: &pb.Capabilities{GpuType: "AMD Radeon RX 7900 XT"}}))
+	assert.False(t, HasGPU(&pb.Node{Capabilities: &pb.Capabilities{GpuType: "No GPU detected"}}))
+	assert.False(t, HasGPU(&pb.Node{Capabilities: &pb.Capabilities{}}))
+	assert.False(t, HasGPU(&pb.Node{}))
+
+	// Typed backends take precedence over the GPU name
+	assert.True(t, HasGPU(&pb.Node{Capabilities: &pb.Capabilities{GpuType: "Apple M2 Ultra", GpuBackend: pb.GpuBackend_GPU_BACKEND_METAL}}))
+	assert.False(t, HasGPU(&pb.Node{Capabilities: &pb.Capabilities{GpuType: "Intel UHD Graphics 630", GpuBackend: pb.GpuBackend_GPU_BACKEND_CPU}}))
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Unix(1000, 0)
+	assert.Equal(t, StatusOnline, Status(&pb.Node{LastSeenUnix: 995}, now))
+	assert.Equal(t, StatusStale, Status(&pb.Node{LastSeenUnix: 900}, now))
+}
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"zone": "eu-west", "tier": "spot", "gpu-pool": ""}
+
+	testCases := []struct {
+		selector string
+		expected bool
+	}{
+		{"", true},
+		{"zone=eu-west", true},
+		{"zone=us-east", false},
+		{"zone=eu-west,tier!=spot", false},
+		{"zone = eu-west , tier!=reserved", true},
+		{"gpu-pool", true},
+		{"draining", false},
+		{"!draining", true},
+		{"!zone", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			selector, err := ParseSelector(tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, selector.Matches(labels))
+		})
+	}
+
+	for _, invalid := range []string{"=value", "!", "!=x"} {
+		_, err := ParseSelector(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestQuery_Filter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	nodes := []*pb.Node{
+		{Id: "gpu-eu", LastSeenUnix: 999, Labels: map[string]string{"zone": "eu"},
+			Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CUDA}},
+		{Id: "cpu-eu", LastSeenUnix: 999, Labels: map[string]string{"zone": "eu"},
+			Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CPU}},
+		{Id: "gpu-us-stale", LastSeenUnix: 900, Labels: map[string]string{"zone": "us"},
+			Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_ROCM}},
+	}
+	gpu, cpu := true, false
+
+	testCases := []struct {
+		name     string
+		req      *pb.ListNodesRequest
+		expected []string
+	}{
+		{"no filters", &pb.ListNodesRequest{}, []string{"gpu-eu", "cpu-eu", "gpu-us-stale"}},
+		{"online", &pb.ListNodesRequest{Status: StatusOnline}, []string{"gpu-eu", "cpu-eu"}},
+		{"stale", &pb.ListNodesRequest{Status: StatusStale}, []string{"gpu-us-stale"}},
+		{"gpu", &pb.ListNodesRequest{Gpu: &gpu}, []string{"gpu-eu", "gpu-us-stale"}},
+		{"cpu only", &pb.ListNodesRequest{Gpu: &cpu}, []string{"cpu-eu"}},
+		{"labels", &pb.ListNodesRequest{LabelSelector: "zone=eu"}, []string{"gpu-eu", "cpu-eu"}},
+		{"combined", &pb.ListNodesRequest{Status: StatusOnline, Gpu: &gpu, LabelSelector: "zone=eu"}, []string{"gpu-eu"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := NewQuery(tc.req, now)
+			require.NoError(t, err)
+
+			var ids []string
+			for _, n := range query.Filter(nodes) {
+				ids = append(ids, n.Id)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestNewQuery_Invalid(t *testing.T) {
+	for _, req := range []*pb.ListNodesRequest{
+		{Status: "offline"},
+		{LabelSelector: "=x"},
+		{OrderBy: "hostname"},
+		{OrderBy: "-id"},
+	} {
+		_, err := NewQuery(req, time.Now())
+		assert.Error(t, err, req.String())
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	nodes := []*pb.Node{
+		{Id: "a", LastSeenUnix: 300, Capabilities: &pb.Capabilities{GpuVramAvailable: "2 GB"}},
+		{Id: "b", LastSeenUnix: 100, Capabilities: &pb.Capabilities{GpuVramAvailable: "16 GB"}},
+		{Id: "c", LastSeenUnix: 200, Capabilities: &pb.Capabilities{GpuVramAvailable: "N/A"}},
+	}
+
+	testCases := []struct {
+		orderBy  string
+		expected []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"id", []string{"a", "b", "c"}},
+		{"vram_free", []string{"c", "a", "b"}},
+		{"-vram_free", []string{"b", "a", "c"}},
+		{"last_seen", []string{"b", "c", "a"}},
+		{"-last_seen", []string{"a", "c", "b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.orderBy, func(t *testing.T) {
+			key, err := SortKey(tc.orderBy)
+			require.NoError(t, err)
+
+			sorted := append([]*pb.Node(nil), nodes...)
+			sort.Slice(sorted, func(i, j int) bool { return key(sorted[i]) < key(sorted[j]) })
+
+			var ids []string
+			for _, n := range sorted {
+				ids = append(ids, n.Id)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// Reachability probe defaults. Agents register before their gRPC server
+// starts listening, so the probe retries for about half a minute.
+const (
+	DefaultProbeAttempts = 10
+	DefaultProbeInterval = 3 * time.Second
+	DefaultProbeTimeout  = 2 * time.Second
+)
+
+// Prober checks that the orchestrator can connect to the agent address a
+// node registered with, and remembers the nodes it couldn't reach
+type Prober struct {
+	attempts int
+	interval time.Duration
+	dial     func(ctx context.Context, address string) error
+
+	mu          sync.RWMutex
+	unreachable map[string]string // node ID -> last dial error
+}
+
+// NewProber creates a prober using the default attempts, interval and timeout
+func NewProber() *Prober {
+	return &Prober{
+		attempts:    DefaultProbeAttempts,
+		interval:    DefaultProbeInterval,
+		dial:        dialTCP(DefaultProbeTimeout),
+		unreachable: make(map[string]string),
+	}
+}
+
+// dialTCP returns a dial function that opens and immediately closes a TCP
+// connection
+func dialTCP(timeout time.Duration) func(ctx context.Context, address string) error {
+	return func(ctx context.Context, address string) error {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// Probe dials the node's agent address in the background, logging a warning
+// if it stays unreachable after every attempt. The probe outlives ctx's
+// cancellation but keeps its logger.
+func (p *Prober) Probe(ctx context.Context, nodeID, address string) {
+	if address == "" {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go p.probe(ctx, nodeID, address)
+}
+
+// probe runs the dial attempts for one registration
+func (p *Prober) probe(ctx context.Context, nodeID, address string) {
+	var err error
+	for attempt := 1; attempt <= p.attempts; attempt++ {
+		if err = p.dial(ctx, address); err == nil {
+			p.mu.Lock()
+			delete(p.unreachable, nodeID)
+			p.mu.Unlock()
+			return
+		}
+		if attempt < p.attempts {
+			time.Sleep(p.interval)
+		}
+	}
+
+	p.mu.Lock()
+	p.unreachable[nodeID] = err.Error()
+	p.mu.Unlock()
+
+	logging.FromContext(ctx).Warn("Orchestrator cannot reach the node's agent address; set -advertise-address on the agent", map[string]interface{}{
+		"node_id":       nodeID,
+		"agent_address": address,
+		"attempts":      p.attempts,
+		"error":         err.Error(),
+	})
+}
+
+// Unreachable returns why the node's agent address couldn't be reached, or
+// "" if it was reachable or hasn't been probed
+func (p *Prober) Unreachable(nodeID string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.unreachable[nodeID]
+}
@@ -0,0 +1,75 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProber(attempts int) *Prober {
+	p := NewProber()
+	p.attempts = attempts
+	p.interval = 10 * time.Millisecond
+	p.dial = dialTCP(time.Second)
+	return p
+}
+
+func TestProber_Reachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	p := testProber(1)
+	p.unreachable["node-1"] = "connection refused"
+
+	p.probe(context.Background(), "node-1", listener.Addr().String())
+	assert.Empty(t, p.Unreachable("node-1"))
+}
+
+func TestProber_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	p := testProber(2)
+	p.probe(context.Background(), "node-1", address)
+	assert.NotEmpty(t, p.Unreachable("node-1"))
+	assert.Empty(t, p.Unreachable("node-2"))
+}
+
+func TestProber_RetriesUntilListening(t *testing.T) {
+	var calls int
+	p := testProber(3)
+	p.dial = func(ctx context.Context, address string) error {
+		calls++
+		if calls < 3 {
+			return assert.AnError
+		}
+		return nil
+	}
+
+	p.probe(context.Background(), "node-1", "10.0.0.5:50052")
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, p.Unreachable("node-1"))
+}
+
+func TestProber_Async(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	p := testProber(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Probe(ctx, "node-1", address)
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return p.Unreachable("node-1") != ""
+	}, 2*time.Second, 10*time.Millisecond)
+}
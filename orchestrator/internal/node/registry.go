@@ -1,57 +1,148 @@
 package node
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
 )
 
+// DefaultTombstoneRetention is how long removed nodes are kept for restoring
+const DefaultTombstoneRetention = time.Hour
+
 // Registry manages registered nodes and their state
 type Registry interface {
 	Register(node *pb.Node) error
 	UpdateCapabilities(nodeID string, capabilities *pb.Capabilities) error
+	UpdateModels(nodeID string, models []*pb.ModelEngine) error
 	UpdateHeartbeat(nodeID string) error
+	UpdateThrottle(nodeID string, throttle *pb.NodeThrottle) error
 	List() []*pb.Node
 	Get(nodeID string) (*pb.Node, bool)
 	Remove(nodeID string) error
 	CheckHeartbeats(timeout time.Duration) []string // Returns IDs of stale nodes
+	Annotate(nodeID string, notes string, annotations map[string]string) error
 }
 
-// InMemoryRegistry is an in-memory implementation of Registry
+// InMemoryRegistry is an in-memory implementation of Registry. Registered
+// nodes are never modified in place: updates store a modified copy, so List
+// and Get only hold the lock to collect pointers and copy the nodes after
+// releasing it, and dashboard traffic doesn't hold up heartbeats.
 type InMemoryRegistry struct {
 	mu    sync.RWMutex
 	nodes map[string]*pb.Node
+	// Operator notes outlive registrations, so they're kept apart from the
+	// nodes agents send and survive a node going stale and coming back
+	annotations map[string]annotation
+	// Removed nodes are kept for a while, so a node evicted during a brief
+	// outage can be restored with its labels and notes
+	tombstones         map[string]Tombstone
+	tombstoneRetention time.Duration
+}
+
+// Tombstone is a node removed from the registry, as it was registered
+type Tombstone struct {
+	Node        *pb.Node `json:"node"`
+	RemovedUnix int64    `json:"removed_unix"`
+}
+
+// annotation is what operators attached to a node
+type annotation struct {
+	notes       string
+	annotations map[string]string
 }
 
 // NewInMemoryRegistry creates a new in-memory node registry
 func NewInMemoryRegistry() *InMemoryRegistry {
 	return &InMemoryRegistry{
-		nodes: make(map[string]*pb.Node),
+		nodes:              make(map[string]*pb.Node),
+		annotations:        make(map[string]annotation),
+		tombstones:         make(map[string]Tombstone),
+		tombstoneRetention: DefaultTombstoneRetention,
 	}
 }
 
-// Register adds or updates a node in the registry
+// SetTombstoneRetention sets how long removed nodes can be restored; zero
+// forgets them on removal
+func (r *InMemoryRegistry) SetTombstoneRetention(retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tombstoneRetention = retention
+}
+
+// Register adds or updates a node in the registry, recording when it
+// registered. A node registering again while it has a tombstone replaces it.
+// A registration for an ID held by an online node with a
+// different hostname or agent address is rejected with ErrNodeIDConflict and
+// recorded on the existing node; once the existing node goes stale, the ID
+// can be taken over.
 func (r *InMemoryRegistry) Register(node *pb.Node) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	now := time.Now()
+	if existing, exists := r.nodes[node.Id]; exists && Status(existing, now) == StatusOnline && conflicts(existing, node) {
+		attempts := int32(1)
+		if existing.Conflict != nil {
+			attempts = existing.Conflict.Attempts + 1
+		}
+		conflicted := cloneNode(existing)
+		conflicted.Conflict = &pb.NodeConflict{
+			Hostname:        node.Hostname,
+			AgentAddress:    node.AgentAddress,
+			LastAttemptUnix: now.Unix(),
+			Attempts:        attempts,
+		}
+		r.nodes[node.Id] = conflicted
+		return ErrNodeIDConflict
+	}
+
 	if node.LastSeenUnix == 0 {
-		node.LastSeenUnix = time.Now().Unix()
+		node.LastSeenUnix = now.Unix()
 	}
+	node.RegisteredUnix = now.Unix()
 
-	r.nodes[node.Id] = node
+	r.nodes[node.Id] = cloneNode(node)
+	delete(r.tombstones, node.Id)
 	return nil
 }
 
+// conflicts reports whether a registration comes from a different agent than
+// the one holding the node ID. Agent addresses are only compared when both
+// are known.
+func conflicts(existing, node *pb.Node) bool {
+	if existing.Hostname != node.Hostname {
+		return true
+	}
+	return existing.AgentAddress != "" && node.AgentAddress != "" && existing.AgentAddress != node.AgentAddress
+}
+
 // UpdateCapabilities updates the capabilities for a node
 func (r *InMemoryRegistry) UpdateCapabilities(nodeID string, capabilities *pb.Capabilities) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if node, exists := r.nodes[nodeID]; exists {
-		node.Capabilities = capabilities
-		node.LastSeenUnix = time.Now().Unix()
+		updated := cloneNode(node)
+		updated.Capabilities = capabilities
+		updated.LastSeenUnix = time.Now().Unix()
+		r.nodes[nodeID] = updated
+		return nil
+	}
+
+	return ErrNodeNotFound
+}
+
+// UpdateModels replaces the models a node reports as loaded
+func (r *InMemoryRegistry) UpdateModels(nodeID string, models []*pb.ModelEngine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if node, exists := r.nodes[nodeID]; exists {
+		updated := cloneNode(node)
+		updated.Models = models
+		r.nodes[nodeID] = updated
 		return nil
 	}
 
@@ -64,28 +155,47 @@ func (r *InMemoryRegistry) UpdateHeartbeat(nodeID string) error {
 	defer r.mu.Unlock()
 
 	if node, exists := r.nodes[nodeID]; exists {
-		node.LastSeenUnix = time.Now().Unix()
+		updated := cloneNode(node)
+		updated.LastSeenUnix = time.Now().Unix()
+		r.nodes[nodeID] = updated
 		return nil
 	}
 
 	return ErrNodeNotFound
 }
 
+// UpdateThrottle sets a node's throttling state, or clears it if throttle is nil
+func (r *InMemoryRegistry) UpdateThrottle(nodeID string, throttle *pb.NodeThrottle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node, exists := r.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+	// Most heartbeats come from nodes that aren't throttled
+	if node.Throttle == nil && throttle == nil {
+		return nil
+	}
+	updated := cloneNode(node)
+	updated.Throttle = throttle
+	r.nodes[nodeID] = updated
+	return nil
+}
+
 // List returns all registered nodes
 func (r *InMemoryRegistry) List() []*pb.Node {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	nodes := make([]*pb.Node, 0, len(r.nodes))
+	snapshot := make([]annotatedNode, 0, len(r.nodes))
 	for _, node := range r.nodes {
+		snapshot = append(snapshot, annotatedNode{node: node, annotation: r.annotations[node.Id]})
+	}
+	r.mu.RUnlock()
+
+	nodes := make([]*pb.Node, len(snapshot))
+	for i, n := range snapshot {
 		// Return a copy to avoid race conditions
-		nodes = append(nodes, &pb.Node{
-			Id:           node.Id,
-			Hostname:     node.Hostname,
-			Capabilities: node.Capabilities,
-			LastSeenUnix: node.LastSeenUnix,
-			AgentAddress: node.AgentAddress,
-		})
+		nodes[i] = n.copy()
 	}
 	return nodes
 }
@@ -93,25 +203,60 @@ func (r *InMemoryRegistry) List() []*pb.Node {
 // Get retrieves a node by ID
 func (r *InMemoryRegistry) Get(nodeID string) (*pb.Node, bool) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	node, exists := r.nodes[nodeID]
+	a := r.annotations[nodeID]
+	r.mu.RUnlock()
+
 	if !exists {
 		return nil, false
 	}
 
 	// Return a copy
+	return annotatedNode{node: node, annotation: a}.copy(), true
+}
+
+// annotatedNode is a registered node and its operator annotations, as read
+// under the lock
+type annotatedNode struct {
+	node       *pb.Node
+	annotation annotation
+}
+
+// copy returns a copy of the node with its operator annotations
+func (n annotatedNode) copy() *pb.Node {
+	c := cloneNode(n.node)
+	c.Notes = n.annotation.notes
+	c.Annotations = n.annotation.annotations
+	return c
+}
+
+// cloneNode returns a shallow copy of a node. Nested messages, maps and
+// slices are shared; they are replaced, never modified, on update.
+func cloneNode(node *pb.Node) *pb.Node {
 	return &pb.Node{
-		Id:           node.Id,
-		Hostname:     node.Hostname,
-		Capabilities: node.Capabilities,
-		LastSeenUnix: node.LastSeenUnix,
-		AgentAddress: node.AgentAddress,
-	}, true
+		Id:                node.Id,
+		Hostname:          node.Hostname,
+		Capabilities:      node.Capabilities,
+		LastSeenUnix:      node.LastSeenUnix,
+		AgentAddress:      node.AgentAddress,
+		Labels:            node.Labels,
+		Models:            node.Models,
+		Conflict:          node.Conflict,
+		AgentAddressError: node.AgentAddressError,
+		Tunneled:          node.Tunneled,
+		Notes:             node.Notes,
+		Annotations:       node.Annotations,
+		SupportedModels:   node.SupportedModels,
+		Throttle:          node.Throttle,
+		RegisteredUnix:    node.RegisteredUnix,
+		TailnetAddress:    node.TailnetAddress,
+	}
 }
 
-// Remove removes a node from the registry
-func (r *InMemoryRegistry) Remove(nodeID string) error {
+// Annotate replaces the operator notes and annotations of a registered node.
+// They are kept across re-registrations, and when the node is removed until
+// its tombstone expires, or dropped with it when tombstones are disabled.
+func (r *InMemoryRegistry) Annotate(nodeID string, notes string, annotations map[string]string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -119,10 +264,100 @@ func (r *InMemoryRegistry) Remove(nodeID string) error {
 		return ErrNodeNotFound
 	}
 
+	if notes == "" && len(annotations) == 0 {
+		delete(r.annotations, nodeID)
+		return nil
+	}
+	r.annotations[nodeID] = annotation{notes: notes, annotations: annotations}
+	return nil
+}
+
+// Remove removes a node from the registry, keeping a tombstone of it for the
+// tombstone retention period. Without tombstones its annotations go with it.
+func (r *InMemoryRegistry) Remove(nodeID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	node, exists := r.nodes[nodeID]
+	if !exists {
+		return ErrNodeNotFound
+	}
+
 	delete(r.nodes, nodeID)
+	if r.tombstoneRetention > 0 {
+		r.tombstones[nodeID] = Tombstone{Node: node, RemovedUnix: time.Now().Unix()}
+	} else {
+		delete(r.annotations, nodeID)
+	}
 	return nil
 }
 
+// Tombstones returns the removed nodes that can still be restored, most
+// recently removed first
+func (r *InMemoryRegistry) Tombstones() []Tombstone {
+	now := time.Now()
+	r.mu.RLock()
+	tombstones := make([]Tombstone, 0, len(r.tombstones))
+	for nodeID, t := range r.tombstones {
+		if r.expired(t, now) {
+			continue
+		}
+		n := annotatedNode{node: t.Node, annotation: r.annotations[nodeID]}
+		tombstones = append(tombstones, Tombstone{Node: n.copy(), RemovedUnix: t.RemovedUnix})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(tombstones, func(i, j int) bool {
+		if tombstones[i].RemovedUnix != tombstones[j].RemovedUnix {
+			return tombstones[i].RemovedUnix > tombstones[j].RemovedUnix
+		}
+		return tombstones[i].Node.Id < tombstones[j].Node.Id
+	})
+	return tombstones
+}
+
+// Restore registers a removed node again as it was, with its labels and
+// notes, and returns it. The node counts as just seen, so its agent gets a
+// full heartbeat timeout to come back before it is removed again.
+func (r *InMemoryRegistry) Restore(nodeID string) (*pb.Node, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.nodes[nodeID]; exists {
+		return nil, ErrNodeRegistered
+	}
+	t, ok := r.tombstones[nodeID]
+	if !ok || r.expired(t, time.Now()) {
+		return nil, ErrNodeNotFound
+	}
+
+	restored := cloneNode(t.Node)
+	restored.LastSeenUnix = time.Now().Unix()
+	r.nodes[nodeID] = restored
+	delete(r.tombstones, nodeID)
+	return annotatedNode{node: restored, annotation: r.annotations[nodeID]}.copy(), nil
+}
+
+// PruneTombstones forgets removed nodes past the retention period, along
+// with their annotations
+func (r *InMemoryRegistry) PruneTombstones() {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for nodeID, t := range r.tombstones {
+		if r.expired(t, now) {
+			delete(r.tombstones, nodeID)
+			delete(r.annotations, nodeID)
+		}
+	}
+}
+
+// expired reports whether a tombstone is past the retention period
+func (r *InMemoryRegistry) expired(t Tombstone, now time.Time) bool {
+	return now.Sub(time.Unix(t.RemovedUnix, 0)) > r.tombstoneRetention
+}
+
 // CheckHeartbeats returns IDs of nodes that haven't sent a heartbeat within the timeout
 func (r *InMemoryRegistry) CheckHeartbeats(timeout time.Duration) []string {
 	r.mu.RLock()
@@ -143,6 +378,12 @@ func (r *InMemoryRegistry) CheckHeartbeats(timeout time.Duration) []string {
 
 var ErrNodeNotFound = &RegistryError{Message: "node not found"}
 
+// ErrNodeRegistered is returned when restoring a node that is registered
+var ErrNodeRegistered = &RegistryError{Message: "node is registered"}
+
+// ErrNodeIDConflict is returned when another online agent already holds a node ID
+var ErrNodeIDConflict = &RegistryError{Message: "node ID is registered by another agent"}
+
 type RegistryError struct {
 	Message string
 }
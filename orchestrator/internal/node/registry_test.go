@@ -42,6 +42,7 @@ func TestInMemoryRegistry_Register(t *testing.T) {
 		assert.Equal(t, "test-node-1", retrieved.Id)
 		assert.Equal(t, "test-host", retrieved.Hostname)
 		assert.NotZero(t, retrieved.LastSeenUnix) // Should be set automatically
+		assert.NotZero(t, retrieved.RegisteredUnix)
 	})
 
 	t.Run("registration with existing LastSeenUnix", func(t *testing.T) {
@@ -72,10 +73,10 @@ func TestInMemoryRegistry_Register(t *testing.T) {
 		err := registry.Register(node)
 		require.NoError(t, err)
 
-		// Update with new data
+		// Update with new data from the same agent
 		updatedNode := &pb.Node{
 			Id:       "update-test",
-			Hostname: "new-host",
+			Hostname: "old-host",
 			Capabilities: &pb.Capabilities{
 				Cpu:    "4 cores",
 				Memory: "16GB",
@@ -87,12 +88,69 @@ func TestInMemoryRegistry_Register(t *testing.T) {
 		// Verify update
 		retrieved, exists := registry.Get("update-test")
 		assert.True(t, exists)
-		assert.Equal(t, "new-host", retrieved.Hostname)
+		assert.Equal(t, "old-host", retrieved.Hostname)
 		assert.Equal(t, "4 cores", retrieved.Capabilities.Cpu)
 		assert.Equal(t, "16GB", retrieved.Capabilities.Memory)
 	})
 }
 
+func TestInMemoryRegistry_RegisterConflict(t *testing.T) {
+	original := func() *pb.Node {
+		return &pb.Node{Id: "clone", Hostname: "vm-1", AgentAddress: "10.0.0.1:50052"}
+	}
+
+	tests := []struct {
+		name    string
+		second  *pb.Node
+		wantErr bool
+	}{
+		{name: "same agent re-registers", second: original()},
+		{name: "same host, address unknown", second: &pb.Node{Id: "clone", Hostname: "vm-1"}},
+		{name: "different hostname", second: &pb.Node{Id: "clone", Hostname: "vm-2", AgentAddress: "10.0.0.1:50052"}, wantErr: true},
+		{name: "different agent address", second: &pb.Node{Id: "clone", Hostname: "vm-1", AgentAddress: "10.0.0.2:50052"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewInMemoryRegistry()
+			require.NoError(t, registry.Register(original()))
+
+			err := registry.Register(tt.second)
+			retrieved, _ := registry.Get("clone")
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Nil(t, retrieved.Conflict)
+				return
+			}
+
+			assert.Equal(t, ErrNodeIDConflict, err)
+			// The original registration is kept and the conflict recorded on it
+			assert.Equal(t, "vm-1", retrieved.Hostname)
+			assert.Equal(t, "10.0.0.1:50052", retrieved.AgentAddress)
+			require.NotNil(t, retrieved.Conflict)
+			assert.Equal(t, tt.second.Hostname, retrieved.Conflict.Hostname)
+			assert.Equal(t, tt.second.AgentAddress, retrieved.Conflict.AgentAddress)
+			assert.Equal(t, int32(1), retrieved.Conflict.Attempts)
+
+			assert.Equal(t, ErrNodeIDConflict, registry.Register(tt.second))
+			retrieved, _ = registry.Get("clone")
+			assert.Equal(t, int32(2), retrieved.Conflict.Attempts)
+		})
+	}
+
+	t.Run("stale node can be taken over", func(t *testing.T) {
+		registry := NewInMemoryRegistry()
+		stale := original()
+		stale.LastSeenUnix = time.Now().Add(-time.Hour).Unix()
+		require.NoError(t, registry.Register(stale))
+
+		require.NoError(t, registry.Register(&pb.Node{Id: "clone", Hostname: "vm-2"}))
+		retrieved, _ := registry.Get("clone")
+		assert.Equal(t, "vm-2", retrieved.Hostname)
+		assert.Nil(t, retrieved.Conflict)
+	})
+}
+
 func TestInMemoryRegistry_UpdateCapabilities(t *testing.T) {
 	registry := NewInMemoryRegistry()
 
@@ -143,6 +201,33 @@ func TestInMemoryRegistry_UpdateCapabilities(t *testing.T) {
 	})
 }
 
+func TestInMemoryRegistry_UpdateModels(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "models-test"}))
+
+	t.Run("replaces loaded models", func(t *testing.T) {
+		err := registry.UpdateModels("models-test", []*pb.ModelEngine{
+			{Model: "llama3", Engine: "ollama", Image: "ollama/ollama:latest", ImageDigest: "sha256:abc", Version: "0.3.12"},
+		})
+		require.NoError(t, err)
+
+		retrieved, exists := registry.Get("models-test")
+		require.True(t, exists)
+		require.Len(t, retrieved.Models, 1)
+		assert.Equal(t, "0.3.12", retrieved.Models[0].Version)
+		assert.Equal(t, "sha256:abc", retrieved.Models[0].ImageDigest)
+
+		require.NoError(t, registry.UpdateModels("models-test", nil))
+		retrieved, _ = registry.Get("models-test")
+		assert.Empty(t, retrieved.Models)
+	})
+
+	t.Run("update non-existent node", func(t *testing.T) {
+		err := registry.UpdateModels("non-existent", nil)
+		assert.Equal(t, ErrNodeNotFound, err)
+	})
+}
+
 func TestInMemoryRegistry_UpdateHeartbeat(t *testing.T) {
 	registry := NewInMemoryRegistry()
 
@@ -174,6 +259,22 @@ func TestInMemoryRegistry_UpdateHeartbeat(t *testing.T) {
 	})
 }
 
+func TestInMemoryRegistry_UpdateThrottle(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host"}))
+
+	throttle := &pb.NodeThrottle{Reason: "GPU temperature 88°C over 85°C limit", SinceUnix: 1000}
+	require.NoError(t, registry.UpdateThrottle("node-1", throttle))
+	retrieved, _ := registry.Get("node-1")
+	assert.Equal(t, throttle, retrieved.Throttle)
+
+	require.NoError(t, registry.UpdateThrottle("node-1", nil))
+	retrieved, _ = registry.Get("node-1")
+	assert.Nil(t, retrieved.Throttle)
+
+	assert.Equal(t, ErrNodeNotFound, registry.UpdateThrottle("non-existent", nil))
+}
+
 func TestInMemoryRegistry_List(t *testing.T) {
 	registry := NewInMemoryRegistry()
 
@@ -240,6 +341,14 @@ func TestInMemoryRegistry_Get(t *testing.T) {
 		assert.Equal(t, "test-host", original.Hostname)
 	})
 
+	t.Run("get keeps supported models", func(t *testing.T) {
+		registry.Register(&pb.Node{Id: "models-test", Hostname: "test-host", SupportedModels: []string{"phi3*"}})
+
+		retrieved, exists := registry.Get("models-test")
+		assert.True(t, exists)
+		assert.Equal(t, []string{"phi3*"}, retrieved.SupportedModels)
+	})
+
 	t.Run("get non-existent node", func(t *testing.T) {
 		retrieved, exists := registry.Get("non-existent")
 		assert.False(t, exists)
@@ -390,4 +499,193 @@ func TestRegistryError_Error(t *testing.T) {
 func TestErrNodeNotFound(t *testing.T) {
 	assert.NotNil(t, ErrNodeNotFound)
 	assert.Equal(t, "node not found", ErrNodeNotFound.Error())
-}
\ No newline at end of file
+}
+func TestInMemoryRegistry_Annotate(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	assert.Equal(t, ErrNodeNotFound, registry.Annotate("node-1", "notes", nil))
+
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+	require.NoError(t, registry.Annotate("node-1", "PSU flaky", map[string]string{"rack": "b3"}))
+
+	// Annotations survive the agent re-registering and the node being removed
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+	require.NoError(t, registry.Remove("node-1"))
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+
+	retrieved, ok := registry.Get("node-1")
+	require.True(t, ok)
+	assert.Equal(t, "PSU flaky", retrieved.Notes)
+	assert.Equal(t, map[string]string{"rack": "b3"}, retrieved.Annotations)
+	assert.Equal(t, "PSU flaky", registry.List()[0].Notes)
+
+	require.NoError(t, registry.Annotate("node-1", "", nil))
+	retrieved, _ = registry.Get("node-1")
+	assert.Empty(t, retrieved.Notes)
+	assert.Nil(t, retrieved.Annotations)
+}
+
+func TestInMemoryRegistry_ListSnapshot(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1", LastSeenUnix: 100}))
+
+	nodes := registry.List()
+	require.Len(t, nodes, 1)
+
+	// Updates replace the stored node instead of changing the listed copy
+	require.NoError(t, registry.UpdateHeartbeat("node-1"))
+	require.NoError(t, registry.UpdateModels("node-1", []*pb.ModelEngine{{Model: "llama3"}}))
+	assert.Equal(t, int64(100), nodes[0].LastSeenUnix)
+	assert.Empty(t, nodes[0].Models)
+
+	node, exists := registry.Get("node-1")
+	require.True(t, exists)
+	assert.Greater(t, node.LastSeenUnix, int64(100))
+	assert.Len(t, node.Models, 1)
+}
+
+// registerBenchmarkNodes registers n nodes with capabilities and labels
+func registerBenchmarkNodes(registry *InMemoryRegistry, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("node-%d", i)
+		registry.Register(&pb.Node{
+			Id:           ids[i],
+			Hostname:     fmt.Sprintf("host-%d", i),
+			Capabilities: &pb.Capabilities{Cpu: "16 cores", Memory: "64GB"},
+			Labels:       map[string]string{"zone": "eu-west"},
+		})
+	}
+	return ids
+}
+
+func BenchmarkInMemoryRegistry_List(b *testing.B) {
+	registry := NewInMemoryRegistry()
+	registerBenchmarkNodes(registry, 1000)
+
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_ = registry.List()
+		}
+	})
+}
+
+func BenchmarkInMemoryRegistry_ListDuringHeartbeats(b *testing.B) {
+	registry := NewInMemoryRegistry()
+	ids := registerBenchmarkNodes(registry, 1000)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				registry.UpdateHeartbeat(ids[i%len(ids)])
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_ = registry.List()
+		}
+	})
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkInMemoryRegistry_UpdateHeartbeatDuringLists(b *testing.B) {
+	registry := NewInMemoryRegistry()
+	ids := registerBenchmarkNodes(registry, 1000)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_ = registry.List()
+				}
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		registry.UpdateHeartbeat(ids[i%len(ids)])
+	}
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+}
+
+func TestInMemoryRegistry_Tombstones(t *testing.T) {
+	registry := NewInMemoryRegistry()
+	labels := map[string]string{"zone": "eu", DrainingLabel: ""}
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1", Labels: labels, LastSeenUnix: 100}))
+	require.NoError(t, registry.Annotate("node-1", "PSU flaky", nil))
+	require.NoError(t, registry.Remove("node-1"))
+
+	tombstones := registry.Tombstones()
+	require.Len(t, tombstones, 1)
+	assert.Equal(t, labels, tombstones[0].Node.Labels)
+	assert.Equal(t, "PSU flaky", tombstones[0].Node.Notes)
+
+	restored, err := registry.Restore("node-1")
+	require.NoError(t, err)
+	assert.Equal(t, labels, restored.Labels)
+	assert.Equal(t, "PSU flaky", restored.Notes)
+	assert.Greater(t, restored.LastSeenUnix, int64(100), "restored nodes get a fresh heartbeat timeout")
+	assert.Empty(t, registry.Tombstones())
+
+	_, err = registry.Restore("node-1")
+	assert.Equal(t, ErrNodeRegistered, err)
+	_, err = registry.Restore("unknown")
+	assert.Equal(t, ErrNodeNotFound, err)
+
+	t.Run("re-registering replaces the tombstone", func(t *testing.T) {
+		require.NoError(t, registry.Remove("node-1"))
+		require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+		assert.Empty(t, registry.Tombstones())
+	})
+
+	t.Run("expired tombstones are pruned with their annotations", func(t *testing.T) {
+		require.NoError(t, registry.Remove("node-1"))
+		tombstone := registry.tombstones["node-1"]
+		tombstone.RemovedUnix -= int64(DefaultTombstoneRetention.Seconds()) + 1
+		registry.tombstones["node-1"] = tombstone
+
+		assert.Empty(t, registry.Tombstones())
+		_, err := registry.Restore("node-1")
+		assert.Equal(t, ErrNodeNotFound, err)
+
+		registry.PruneTombstones()
+		assert.Empty(t, registry.tombstones)
+		require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+		retrieved, _ := registry.Get("node-1")
+		assert.Empty(t, retrieved.Notes)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		registry.SetTombstoneRetention(0)
+		require.NoError(t, registry.Annotate("node-1", "PSU flaky", nil))
+		require.NoError(t, registry.Remove("node-1"))
+		assert.Empty(t, registry.Tombstones())
+		assert.Empty(t, registry.annotations, "annotations go with the node")
+
+		require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+		retrieved, _ := registry.Get("node-1")
+		assert.Empty(t, retrieved.Notes)
+	})
+}
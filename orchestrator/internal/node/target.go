@@ -0,0 +1,26 @@
+package node
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TargetMetadata is the gRPC metadata key naming the node a NodeAgent call is
+// for. Agents serving a logical node per GPU use it to run the call on the
+// engines of that node's GPU.
+const TargetMetadata = "orchion-target-node"
+
+// TargetDialOptions return dial options naming the node in the metadata of
+// every call made over a connection to its agent
+func TargetDialOptions(nodeID string) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, TargetMetadata, nodeID), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, TargetMetadata, nodeID), desc, cc, method, opts...)
+		}),
+	}
+}
@@ -0,0 +1,65 @@
+package node
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// targetEchoAgent replies with the target node named in the call's metadata
+type targetEchoAgent struct {
+	pb.UnimplementedNodeAgentServer
+}
+
+func target(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(TargetMetadata); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (targetEchoAgent) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	return &pb.EmbeddingResponse{Model: target(ctx)}, nil
+}
+
+func (targetEchoAgent) ChatCompletion(req *pb.ChatCompletionRequest, stream pb.NodeAgent_ChatCompletionServer) error {
+	return stream.Send(&pb.ChatCompletionResponse{Model: target(stream.Context())})
+}
+
+func TestTargetDialOptions(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	pb.RegisterNodeAgentServer(srv, targetEchoAgent{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, TargetDialOptions("box-gpu1")...)
+	conn, err := grpc.NewClient(lis.Addr().String(), opts...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewNodeAgentClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Embeddings(ctx, &pb.EmbeddingRequest{Model: "m"})
+	require.NoError(t, err)
+	assert.Equal(t, "box-gpu1", resp.Model)
+
+	stream, err := client.ChatCompletion(ctx, &pb.ChatCompletionRequest{Model: "m"})
+	require.NoError(t, err)
+	chunk, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "box-gpu1", chunk.Model)
+}
@@ -0,0 +1,196 @@
+package node
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// Plausible ranges for capability readings; readings outside them are clamped
+const (
+	maxCPUCores     = 4096
+	maxTemperatureC = 150
+	maxHostnameLen  = 253
+)
+
+// FieldError is a problem with one field of a node an agent sent
+type FieldError struct {
+	Field       string // e.g. "capabilities.memory"
+	Description string
+}
+
+// ValidationError lists every invalid field of a node, so a misconfigured
+// agent learns about all of them at once
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Description
+	}
+	return "invalid node: " + strings.Join(parts, "; ")
+}
+
+// validator collects field errors
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Description: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// Normalize validates a registering node and normalizes it in place: names
+// and addresses are trimmed and capability readings are parsed, rewritten in
+// canonical units and clamped to plausible ranges. It returns a
+// *ValidationError listing every field that can't be made sense of.
+func Normalize(n *pb.Node) error {
+	v := &validator{}
+
+	n.Hostname = strings.TrimSpace(n.Hostname)
+	switch {
+	case n.Hostname == "":
+		v.add("hostname", "is required")
+	case len(n.Hostname) > maxHostnameLen:
+		v.add("hostname", "is longer than %d characters", maxHostnameLen)
+	}
+
+	n.AgentAddress = strings.TrimSpace(n.AgentAddress)
+	if n.AgentAddress != "" {
+		if err := validateHostPort(n.AgentAddress); err != nil {
+			v.add("agent_address", "%v", err)
+		}
+	}
+	n.TailnetAddress = strings.TrimSpace(n.TailnetAddress)
+	if n.TailnetAddress != "" {
+		if err := validateHostPort(n.TailnetAddress); err != nil {
+			v.add("tailnet_address", "%v", err)
+		}
+	}
+
+	for key := range n.Labels {
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "=,!") {
+			v.add("labels", "key %q must be non-empty and not contain '=', ',' or '!'", key)
+		}
+	}
+
+	if n.Capabilities != nil {
+		normalizeCapabilities(v, n.Capabilities)
+	}
+	return v.err()
+}
+
+// NormalizeCapabilities validates and normalizes capabilities sent in an
+// update, as Normalize does on registration
+func NormalizeCapabilities(caps *pb.Capabilities) error {
+	v := &validator{}
+	normalizeCapabilities(v, caps)
+	return v.err()
+}
+
+// validateHostPort checks an address has a host and a port in range
+func validateHostPort(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("must be host:port: %v", err)
+	}
+	if host == "" {
+		return fmt.Errorf("has no host")
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("port %q is not between 1 and 65535", port)
+	}
+	return nil
+}
+
+func normalizeCapabilities(v *validator, caps *pb.Capabilities) {
+	caps.Cpu = normalizeCores(v, "capabilities.cpu", caps.Cpu)
+	caps.Memory, _ = normalizeSize(v, "capabilities.memory", caps.Memory)
+	caps.Os = strings.TrimSpace(caps.Os)
+	caps.GpuType = strings.TrimSpace(caps.GpuType)
+
+	var total *float64
+	caps.GpuVramTotal, total = normalizeSize(v, "capabilities.gpu_vram_total", caps.GpuVramTotal)
+	caps.GpuVramAvailable = clampToTotal(v, "capabilities.gpu_vram_available", caps.GpuVramAvailable, total)
+	caps.GpuVramUsed = clampToTotal(v, "capabilities.gpu_vram_used", caps.GpuVramUsed, total)
+
+	caps.GpuTemperature = normalizeReading(v, "capabilities.gpu_temperature", caps.GpuTemperature, maxTemperatureC, "%.0f°C")
+	caps.GpuPowerUsage = normalizeReading(v, "capabilities.gpu_power_usage", caps.GpuPowerUsage, math.Inf(1), "%.1f W")
+	caps.PowerUsage = normalizeReading(v, "capabilities.power_usage", caps.PowerUsage, math.Inf(1), "%.1f W")
+
+	if _, ok := pb.GpuBackend_name[int32(caps.GpuBackend)]; !ok {
+		v.add("capabilities.gpu_backend", "unknown backend %d", caps.GpuBackend)
+	}
+}
+
+// normalizeCores parses a CPU reading such as "8 cores" into "8 cores"
+func normalizeCores(v *validator, field, s string) string {
+	s = strings.TrimSpace(s)
+	if units.Unknown(s) {
+		return ""
+	}
+	cores, ok := units.ParseCores(s)
+	if !ok {
+		v.add(field, "%q is not a number of cores such as \"8 cores\"", s)
+		return s
+	}
+	if cores < 1 {
+		v.add(field, "%q must be at least one core", s)
+		return s
+	}
+	return units.FormatCores(min(cores, maxCPUCores))
+}
+
+// normalizeSize parses a size such as "7.5 GB" or "512 MB" into GB. It also
+// returns the size in megabytes, or nil if unknown or invalid.
+func normalizeSize(v *validator, field, s string) (string, *float64) {
+	s = strings.TrimSpace(s)
+	if units.Unknown(s) {
+		return "", nil
+	}
+	mb, ok := units.ParseMegabytes(s)
+	if !ok {
+		v.add(field, "%q is not a size such as \"16 GB\"", s)
+		return s, nil
+	}
+	mb = math.Max(mb, 0)
+	return units.FormatMegabytes(mb), &mb
+}
+
+// clampToTotal normalizes a VRAM size, capping it at the total VRAM if known
+func clampToTotal(v *validator, field, s string, total *float64) string {
+	normalized, mb := normalizeSize(v, field, s)
+	if mb == nil || total == nil || *mb <= *total {
+		return normalized
+	}
+	return units.FormatMegabytes(*total)
+}
+
+// normalizeReading parses a reading such as "65°C" or "120.5 W", clamps it to
+// [0, max] and formats it with format
+func normalizeReading(v *validator, field, s string, max float64, format string) string {
+	s = strings.TrimSpace(s)
+	if units.Unknown(s) {
+		return ""
+	}
+	value, ok := units.ParseNumber(s)
+	if !ok {
+		v.add(field, "%q is not a number", s)
+		return s
+	}
+	return fmt.Sprintf(format, math.Max(0, math.Min(value, max)))
+}
@@ -0,0 +1,106 @@
+package node
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestNormalize(t *testing.T) {
+	n := &pb.Node{
+		Id:           "node-1",
+		Hostname:     "  gpu-box  ",
+		AgentAddress: " 10.0.0.5:50052 ",
+		Labels:       map[string]string{"zone": "eu-west"},
+		Capabilities: &pb.Capabilities{
+			Cpu:              "16 cores",
+			Memory:           "31.25 GB (approximate)",
+			Os:               " linux/amd64 ",
+			GpuType:          "NVIDIA GeForce RTX 4090",
+			GpuVramTotal:     "24576 MB",
+			GpuVramAvailable: "30 GB",
+			GpuVramUsed:      "-1 GB",
+			GpuTemperature:   "900°C",
+			GpuPowerUsage:    "N/A",
+			GpuBackend:       pb.GpuBackend_GPU_BACKEND_CUDA,
+		},
+	}
+
+	require.NoError(t, Normalize(n))
+	assert.Equal(t, "gpu-box", n.Hostname)
+	assert.Equal(t, "10.0.0.5:50052", n.AgentAddress)
+
+	caps := n.Capabilities
+	assert.Equal(t, "16 cores", caps.Cpu)
+	assert.Equal(t, "31.2 GB", caps.Memory)
+	assert.Equal(t, "linux/amd64", caps.Os)
+	assert.Equal(t, "24.0 GB", caps.GpuVramTotal)
+	assert.Equal(t, "24.0 GB", caps.GpuVramAvailable, "clamped to total")
+	assert.Equal(t, "0.0 GB", caps.GpuVramUsed, "clamped to zero")
+	assert.Equal(t, "150°C", caps.GpuTemperature)
+	assert.Equal(t, "", caps.GpuPowerUsage, "unknown readings are cleared")
+}
+
+func TestNormalize_LocaleVariants(t *testing.T) {
+	n := &pb.Node{
+		Hostname: "h",
+		Capabilities: &pb.Capabilities{
+			Cpu:              "16 Kerne",
+			Memory:           "31,25 Go",
+			GpuVramTotal:     "24 GiB",
+			GpuVramAvailable: "20,5\u00a0GB",
+			GpuTemperature:   "65,4°C",
+		},
+	}
+
+	require.NoError(t, Normalize(n))
+	caps := n.Capabilities
+	assert.Equal(t, "16 cores", caps.Cpu)
+	assert.Equal(t, "31.2 GB", caps.Memory)
+	assert.Equal(t, "24.0 GB", caps.GpuVramTotal)
+	assert.Equal(t, "20.5 GB", caps.GpuVramAvailable)
+	assert.Equal(t, "65°C", caps.GpuTemperature)
+}
+
+func TestNormalize_FieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  *pb.Node
+		field string
+	}{
+		{"empty hostname", &pb.Node{Hostname: "   "}, "hostname"},
+		{"address without port", &pb.Node{Hostname: "h", AgentAddress: "10.0.0.5"}, "agent_address"},
+		{"address port out of range", &pb.Node{Hostname: "h", AgentAddress: "10.0.0.5:70000"}, "agent_address"},
+		{"tailnet address without port", &pb.Node{Hostname: "h", TailnetAddress: "100.64.0.5"}, "tailnet_address"},
+		{"label key with separator", &pb.Node{Hostname: "h", Labels: map[string]string{"a=b": "c"}}, "labels"},
+		{"cpu not a count", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{Cpu: "fast"}}, "capabilities.cpu"},
+		{"zero cores", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{Cpu: "0 cores"}}, "capabilities.cpu"},
+		{"memory not a size", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{Memory: "plenty"}}, "capabilities.memory"},
+		{"memory in an unknown unit", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{Memory: "16 parsecs"}}, "capabilities.memory"},
+		{"temperature not a number", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{GpuTemperature: "hot"}}, "capabilities.gpu_temperature"},
+		{"unknown backend", &pb.Node{Hostname: "h", Capabilities: &pb.Capabilities{GpuBackend: 42}}, "capabilities.gpu_backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Normalize(tt.node)
+			var invalid *ValidationError
+			require.True(t, errors.As(err, &invalid), "got %v", err)
+			require.Len(t, invalid.Fields, 1)
+			assert.Equal(t, tt.field, invalid.Fields[0].Field)
+		})
+	}
+}
+
+func TestNormalize_ReportsEveryField(t *testing.T) {
+	err := Normalize(&pb.Node{Capabilities: &pb.Capabilities{Cpu: "none yet", Memory: "?"}})
+	var invalid *ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Len(t, invalid.Fields, 3)
+	assert.Contains(t, err.Error(), "hostname: is required")
+	assert.Contains(t, err.Error(), "capabilities.memory")
+}
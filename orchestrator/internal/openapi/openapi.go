@@ -0,0 +1,228 @@
+// Package openapi builds an OpenAPI 3.1 document describing the
+// orchestrator's REST APIs from the Go types their handlers read and write,
+// and serves it so users can generate clients or browse the API in Swagger UI.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Version is the OpenAPI version of the documents built here
+const Version = "3.1.0"
+
+// Document is an OpenAPI document. Operations are added with Add, with
+// schemas for Go types built by SchemaOf.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+
+	types map[reflect.Type]string // Component name of each Go type with a schema
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations, e.g. in Swagger UI
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on a path, keyed by lowercase HTTP method
+type PathItem map[string]*Operation
+
+// Operation is an API operation on a path
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body an operation accepts
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType is the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON Schema. The zero Schema accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Components holds the schemas operations refer to and the security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating requests
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// New creates an empty document
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+		types:      make(map[reflect.Type]string),
+	}
+}
+
+// Add adds an operation on a path, e.g. Add(http.MethodGet, "/api/jobs/{id}", op)
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// AddTag adds a tag operations can be grouped by
+func (d *Document) AddTag(name, description string) {
+	d.Tags = append(d.Tags, Tag{Name: name, Description: description})
+}
+
+// AddBearerAuth declares that requests may authenticate with an API key in
+// an "Authorization: Bearer" header. Authentication stays optional in the
+// document, as it depends on how the orchestrator is configured.
+func (d *Document) AddBearerAuth(description string) {
+	if d.Components.SecuritySchemes == nil {
+		d.Components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	d.Components.SecuritySchemes["bearerAuth"] = &SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: description,
+	}
+	d.Security = []map[string][]string{{"bearerAuth": {}}, {}}
+}
+
+// JSONBody returns a required JSON request body
+func JSONBody(schema *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// JSONResponse returns a JSON response
+func JSONResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// TextResponse returns a plain text response, e.g. an error written with
+// http.Error
+func TextResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+	}
+}
+
+// EventStreamResponse returns a Server-Sent Events response
+func EventStreamResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}},
+	}
+}
+
+// PathParam returns a required string path parameter
+func PathParam(name, description string) *Parameter {
+	return &Parameter{Name: name, In: "path", Description: description, Required: true, Schema: &Schema{Type: "string"}}
+}
+
+// QueryParam returns an optional query parameter of a JSON Schema type
+func QueryParam(name, typ, description string) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+// HeaderParam returns an optional string request header
+func HeaderParam(name, description string) *Parameter {
+	return &Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// Handler serves the document as JSON on GET requests
+func Handler(doc *Document) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Add CORS headers, so Swagger UI can load the document from anywhere
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		// Handle preflight requests
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
@@ -0,0 +1,107 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type item struct {
+	base
+	Name     string            `json:"name"`
+	Count    int64             `json:"count,omitempty"`
+	Score    *float64          `json:"score,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Created  time.Time         `json:"created"`
+	Payload  []byte            `json:"payload,omitempty"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Children []*item           `json:"children"`
+	Size     int               `json:"size,string"`
+	Stop     stringOrStrings   `json:"stop,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+// stringOrStrings describes its schema
+type stringOrStrings []string
+
+func (stringOrStrings) OpenAPISchema() *Schema {
+	return &Schema{OneOf: []*Schema{{Type: "string"}, ArrayOf(&Schema{Type: "string"})}}
+}
+
+// Event collides with node.Event
+type Event struct {
+	Kind string `json:"kind"`
+}
+
+func TestDocument_SchemaOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Item"}, doc.SchemaOf(&item{}))
+	assert.Equal(t, ArrayOf(&Schema{Ref: "#/components/schemas/Item"}), doc.SchemaOf([]item{}))
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer", Format: "int32"}}, doc.SchemaOf(map[string]int32{}))
+
+	s := doc.Components.Schemas["Item"]
+	require.NotNil(t, s)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"id", "name", "created", "children", "size"}, s.Required)
+	assert.Equal(t, map[string]*Schema{
+		"id":       {Type: "string"},
+		"name":     {Type: "string"},
+		"count":    {Type: "integer", Format: "int64"},
+		"score":    {Type: "number", Format: "double"},
+		"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"created":  {Type: "string", Format: "date-time"},
+		"payload":  {Type: "string", Format: "byte"},
+		"raw":      {},
+		"children": ArrayOf(&Schema{Ref: "#/components/schemas/Item"}),
+		"size":     {Type: "string"},
+		"stop":     {OneOf: []*Schema{{Type: "string"}, ArrayOf(&Schema{Type: "string"})}},
+	}, s.Properties)
+
+	// Types of different packages sharing a name get distinct schemas
+	assert.Equal(t, "#/components/schemas/Event", doc.SchemaOf(node.Event{}).Ref)
+	assert.Equal(t, "#/components/schemas/OpenapiEvent", doc.SchemaOf(Event{}).Ref)
+	assert.Equal(t, "#/components/schemas/Event", doc.SchemaOf(node.Event{}).Ref)
+}
+
+func TestHandler(t *testing.T) {
+	doc := New(Info{Title: "Orchion", Version: "1"})
+	doc.AddBearerAuth("API key")
+	doc.Add(http.MethodGet, "/api/items/{id}", &Operation{
+		Summary:    "Get an item",
+		Parameters: []*Parameter{PathParam("id", "Item ID")},
+		Responses: map[string]*Response{
+			"200": JSONResponse("The item", doc.SchemaOf(item{})),
+			"404": TextResponse("No such item"),
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	Handler(doc).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	var served map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, "3.1.0", served["openapi"])
+	op := served["paths"].(map[string]interface{})["/api/items/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "Get an item", op["summary"])
+	assert.Contains(t, served["components"].(map[string]interface{})["schemas"], "Item")
+
+	rec = httptest.NewRecorder()
+	Handler(doc).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/openapi.json", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	describerType  = reflect.TypeOf((*Describer)(nil)).Elem()
+)
+
+// Describer is implemented by types whose JSON encoding can't be derived
+// from their Go type, e.g. fields accepting a string or an array of strings
+type Describer interface {
+	OpenAPISchema() *Schema
+}
+
+// SchemaOf returns the schema of the JSON encoding/json produces for v's
+// type. Named struct types become component schemas the returned schema
+// refers to. Fields without omitempty are required, since they are always
+// encoded.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+// ArrayOf returns the schema of an array of items
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// schema returns the schema of a Go type
+func (d *Document) schema(t reflect.Type) *Schema {
+	if t.Implements(describerType) {
+		return reflect.Zero(t).Interface().(Describer).OpenAPISchema()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return d.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return ArrayOf(d.schema(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.ref(t)
+	default:
+		// Interfaces and anything else encoding/json can't describe statically
+		return &Schema{}
+	}
+}
+
+// ref returns a reference to the component schema of a named struct type,
+// adding it first if needed
+func (d *Document) ref(t reflect.Type) *Schema {
+	name, ok := d.types[t]
+	if !ok {
+		name = d.componentName(t)
+		d.types[t] = name
+		// Claim the name before building the schema, for recursive types
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName names a type's component schema after the type, prefixed
+// with its package's name if another package's type took the name already
+func (d *Document) componentName(t reflect.Type) string {
+	name := exported(t.Name())
+	if _, taken := d.Components.Schemas[name]; taken {
+		name = exported(path.Base(t.PkgPath())) + name
+	}
+	return name
+}
+
+// structSchema returns the object schema of a struct type's JSON encoding
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(s, t)
+	return s
+}
+
+// addFields adds the properties of a struct type's fields to s, including
+// those of embedded structs as encoding/json does
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			d.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		field := d.schema(f.Type)
+		if hasOption(opts, "string") && field.Type != "" {
+			field = &Schema{Type: "string"}
+		}
+		s.Properties[name] = field
+		if !hasOption(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// hasOption reports whether a json tag's options include opt
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// exported returns name with its first letter upper case
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
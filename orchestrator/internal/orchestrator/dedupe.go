@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// dedupeInputs returns the distinct inputs in order of first appearance and,
+// for each original input, the position of its distinct input
+func dedupeInputs(inputs []string) (unique []string, positions []int) {
+	seen := make(map[string]int, len(inputs))
+	positions = make([]int, len(inputs))
+	for i, input := range inputs {
+		pos, ok := seen[input]
+		if !ok {
+			pos = len(unique)
+			seen[input] = pos
+			unique = append(unique, input)
+		}
+		positions[i] = pos
+	}
+	return unique, positions
+}
+
+// expandEmbeddings turns a response for deduplicated inputs back into one
+// embedding per original input, with indices matching the original request.
+// Token usage is left as reported, since it reflects the compute spent.
+func expandEmbeddings(resp *pb.EmbeddingResponse, positions []int) (*pb.EmbeddingResponse, error) {
+	byIndex := make(map[int32]*pb.Embedding, len(resp.Data))
+	for _, emb := range resp.Data {
+		byIndex[emb.Index] = emb
+	}
+
+	data := make([]*pb.Embedding, len(positions))
+	for i, pos := range positions {
+		emb, ok := byIndex[int32(pos)]
+		if !ok {
+			return nil, fmt.Errorf("node returned no embedding for input %d", pos)
+		}
+		data[i] = &pb.Embedding{
+			Index:     int32(i),
+			Embedding: emb.Embedding,
+		}
+	}
+
+	return &pb.EmbeddingResponse{
+		Model:             resp.Model,
+		Data:              data,
+		Object:            resp.Object,
+		UsagePromptTokens: resp.UsagePromptTokens,
+	}, nil
+}
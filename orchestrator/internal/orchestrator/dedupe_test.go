@@ -0,0 +1,131 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+)
+
+func TestDedupeInputs(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputs        []string
+		wantUnique    []string
+		wantPositions []int
+	}{
+		{
+			name:          "no duplicates",
+			inputs:        []string{"a", "b", "c"},
+			wantUnique:    []string{"a", "b", "c"},
+			wantPositions: []int{0, 1, 2},
+		},
+		{
+			name:          "repeated chunks",
+			inputs:        []string{"header", "body", "header", "footer", "body"},
+			wantUnique:    []string{"header", "body", "footer"},
+			wantPositions: []int{0, 1, 0, 2, 1},
+		},
+		{
+			name:          "all identical",
+			inputs:        []string{"x", "x", "x"},
+			wantUnique:    []string{"x"},
+			wantPositions: []int{0, 0, 0},
+		},
+		{
+			name:          "empty",
+			inputs:        nil,
+			wantPositions: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unique, positions := dedupeInputs(tt.inputs)
+			assert.Equal(t, tt.wantUnique, unique)
+			assert.Equal(t, tt.wantPositions, positions)
+		})
+	}
+}
+
+func TestExpandEmbeddings(t *testing.T) {
+	resp := &pb.EmbeddingResponse{
+		Model:  "nomic-embed-text",
+		Object: "list",
+		// Out of order on purpose: engines identify inputs by index
+		Data: []*pb.Embedding{
+			{Index: 1, Embedding: []float32{0.2}},
+			{Index: 0, Embedding: []float32{0.1}},
+		},
+		UsagePromptTokens: 4,
+	}
+
+	t.Run("re-expands to the original inputs", func(t *testing.T) {
+		expanded, err := expandEmbeddings(resp, []int{0, 1, 0})
+		require.NoError(t, err)
+
+		require.Len(t, expanded.Data, 3)
+		for i, want := range [][]float32{{0.1}, {0.2}, {0.1}} {
+			assert.Equal(t, int32(i), expanded.Data[i].Index)
+			assert.Equal(t, want, expanded.Data[i].Embedding)
+		}
+		assert.Equal(t, "nomic-embed-text", expanded.Model)
+		assert.Equal(t, int32(4), expanded.UsagePromptTokens)
+	})
+
+	t.Run("missing embedding", func(t *testing.T) {
+		_, err := expandEmbeddings(resp, []int{0, 2})
+		assert.Error(t, err)
+	})
+}
+
+// echoEmbeddingClient records the inputs it receives and embeds each as its length
+type echoEmbeddingClient struct {
+	pb.NodeAgentClient
+	received []string
+}
+
+func (c *echoEmbeddingClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	c.received = req.Input
+	resp := &pb.EmbeddingResponse{Model: req.Model, Object: "list"}
+	for i, input := range req.Input {
+		resp.Data = append(resp.Data, &pb.Embedding{Index: int32(i), Embedding: []float32{float32(len(input))}})
+	}
+	return resp, nil
+}
+
+func TestJobProcessor_DedupesEmbeddingInputs(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	sched := &MockScheduler{}
+	sched.On("SelectNode", mock.Anything, mock.Anything).Return(&pb.Node{Id: "node-1"}, nil)
+
+	client := &echoEmbeddingClient{}
+	processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+	processor.nodeClients["node-1"] = client
+
+	payload, err := proto.Marshal(&pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"a", "bb", "a", "a"}})
+	require.NoError(t, err)
+	job := &queue.Job{ID: "job-1", Type: queue.JobTypeEmbeddings, Payload: payload}
+	jobQueue.Enqueue(job)
+	processor.processJob(context.Background(), job)
+
+	assert.Equal(t, []string{"a", "bb"}, client.received)
+
+	done, _ := jobQueue.Get("job-1")
+	require.Equal(t, queue.JobCompleted, done.Status)
+
+	var resp pb.EmbeddingResponse
+	require.NoError(t, proto.Unmarshal(done.Result, &resp))
+	require.Len(t, resp.Data, 4)
+	for i, want := range []float32{1, 2, 1, 1} {
+		assert.Equal(t, int32(i), resp.Data[i].Index)
+		assert.Equal(t, []float32{want}, resp.Data[i].Embedding)
+	}
+}
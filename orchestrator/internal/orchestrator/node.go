@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// DefaultHistoryWindow is the hardware history GetNode returns when no window
+// is requested
+const DefaultHistoryWindow = time.Hour
+
+// ModelStatusTimeout bounds asking a node agent for its model states
+const ModelStatusTimeout = 10 * time.Second
+
+// NodeDialer provides NodeAgent clients for nodes
+type NodeDialer interface {
+	NodeClient(n *pb.Node) (pb.NodeAgentClient, error)
+}
+
+// SetNodeDialer sets how GetNode asks node agents for their model states;
+// without one GetNode returns no models
+func (s *Service) SetNodeDialer(dialer NodeDialer) {
+	s.dialer = dialer
+}
+
+// GetNode returns a node along with its hardware history, the state of its
+// models as its agent reports them and the jobs in flight on it. An agent
+// that can't be reached doesn't fail the call; models_error says why.
+func (s *Service) GetNode(ctx context.Context, req *pb.GetNodeRequest) (*pb.GetNodeResponse, error) {
+	if req.NodeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "node_id is required")
+	}
+	if req.HistoryWindowMs < 0 {
+		return nil, status.Error(codes.InvalidArgument, "history_window_ms must not be negative")
+	}
+
+	n, ok := s.registry.Get(req.NodeId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "node not found")
+	}
+	if s.prober != nil {
+		n.AgentAddressError = s.prober.Unreachable(n.Id)
+	}
+	resp := &pb.GetNodeResponse{Node: n}
+
+	if s.history != nil {
+		window := DefaultHistoryWindow
+		if req.HistoryWindowMs > 0 {
+			window = time.Duration(req.HistoryWindowMs) * time.Millisecond
+		}
+		for _, sample := range s.history.Samples(n.Id, window) {
+			resp.History = append(resp.History, nodeSample(sample))
+		}
+	}
+
+	if s.queue != nil {
+		for _, job := range s.queue.InFlight(n.Id) {
+			resp.ActiveJobs = append(resp.ActiveJobs, jobSummary(job))
+		}
+	}
+
+	if s.dialer != nil {
+		models, err := s.modelStatus(ctx, n)
+		if err != nil {
+			resp.ModelsError = err.Error()
+		}
+		resp.Models = models
+	}
+
+	return resp, nil
+}
+
+// modelStatus asks a node's agent for the state of its models
+func (s *Service) modelStatus(ctx context.Context, n *pb.Node) ([]*pb.ModelStatus, error) {
+	client, err := s.dialer.NodeClient(n)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, ModelStatusTimeout)
+	defer cancel()
+	resp, err := client.GetModelStatus(ctx, &pb.GetModelStatusRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model states: %v", status.Convert(err).Message())
+	}
+	return resp.Models, nil
+}
+
+// nodeSample converts a hardware history sample
+func nodeSample(sample node.Sample) *pb.NodeSample {
+	return &pb.NodeSample{
+		TimestampMs:     sample.TimestampMs,
+		VramUsedMb:      sample.VRAMUsedMB,
+		VramTotalMb:     sample.VRAMTotalMB,
+		GpuTemperatureC: sample.TemperatureC,
+		GpuPowerW:       sample.GPUPowerW,
+	}
+}
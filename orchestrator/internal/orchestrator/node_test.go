@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+)
+
+// modelStatusClient answers GetModelStatus with a canned response
+type modelStatusClient struct {
+	pb.NodeAgentClient
+	resp *pb.GetModelStatusResponse
+	err  error
+}
+
+func (c *modelStatusClient) GetModelStatus(ctx context.Context, in *pb.GetModelStatusRequest, opts ...grpc.CallOption) (*pb.GetModelStatusResponse, error) {
+	return c.resp, c.err
+}
+
+type staticDialer struct{ client pb.NodeAgentClient }
+
+func (d staticDialer) NodeClient(n *pb.Node) (pb.NodeAgentClient, error) { return d.client, nil }
+
+func TestService_GetNode(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	jobQueue := queue.NewJobQueue()
+	service := NewService(registry, jobQueue, &MockScheduler{})
+
+	gpu := &pb.Node{Id: "gpu-1", Hostname: "gpu-box", Capabilities: &pb.Capabilities{GpuVramUsed: "2 GB", GpuVramTotal: "8 GB"}}
+	require.NoError(t, registry.Register(gpu))
+	history := node.NewHistory(10)
+	history.Record(gpu)
+	service.SetHistory(history)
+
+	// One job running on the node, one elsewhere and one still queued
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		jobQueue.Enqueue(&queue.Job{ID: id, Type: queue.JobTypeChatCompletion})
+	}
+	jobQueue.DequeueNonBlocking()
+	jobQueue.UpdateStatusAndNode("job-1", queue.JobRunning, "gpu-1")
+	jobQueue.DequeueNonBlocking()
+	jobQueue.UpdateStatusAndNode("job-2", queue.JobRunning, "gpu-2")
+
+	client := &modelStatusClient{resp: &pb.GetModelStatusResponse{Models: []*pb.ModelStatus{
+		{Model: "llama3", State: pb.ModelState_MODEL_STATE_READY},
+	}}}
+	service.SetNodeDialer(staticDialer{client})
+
+	resp, err := service.GetNode(ctx, &pb.GetNodeRequest{NodeId: "gpu-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-box", resp.Node.Hostname)
+	require.Len(t, resp.History, 1)
+	assert.Equal(t, 2048.0, resp.History[0].GetVramUsedMb())
+	require.Len(t, resp.ActiveJobs, 1)
+	assert.Equal(t, "job-1", resp.ActiveJobs[0].JobId)
+	require.Len(t, resp.Models, 1)
+	assert.Equal(t, "llama3", resp.Models[0].Model)
+	assert.Empty(t, resp.ModelsError)
+
+	t.Run("unreachable agent", func(t *testing.T) {
+		client.err = errors.New("connection refused")
+		defer func() { client.err = nil }()
+
+		resp, err := service.GetNode(ctx, &pb.GetNodeRequest{NodeId: "gpu-1"})
+		require.NoError(t, err)
+		assert.Empty(t, resp.Models)
+		assert.Contains(t, resp.ModelsError, "connection refused")
+		assert.Len(t, resp.ActiveJobs, 1)
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		_, err := service.GetNode(ctx, &pb.GetNodeRequest{NodeId: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, err := service.GetNode(ctx, &pb.GetNodeRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = service.GetNode(ctx, &pb.GetNodeRequest{NodeId: "gpu-1", HistoryWindowMs: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
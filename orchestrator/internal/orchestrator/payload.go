@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+)
+
+// JSONContentType is the content type of job payloads in the protobuf JSON
+// mapping, e.g. {"model": "llama3", "messages": [...]}
+const JSONContentType = "application/json"
+
+// payloadContentType normalizes a submission's content_type: empty means
+// protobuf, and parameters such as charset are dropped
+func payloadContentType(contentType string) (string, error) {
+	if contentType == "" {
+		return results.ProtobufContentType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content_type %q: %v", contentType, err)
+	}
+	switch mediaType {
+	case results.ProtobufContentType, "application/protobuf":
+		return results.ProtobufContentType, nil
+	case JSONContentType:
+		return JSONContentType, nil
+	default:
+		return "", fmt.Errorf("unsupported content_type %q (want %s or %s)", contentType, results.ProtobufContentType, JSONContentType)
+	}
+}
+
+// looksLikeJSON reports whether a payload starts like a JSON object. No valid
+// protobuf message starts with '{', which would open a group for field 15.
+func looksLikeJSON(payload []byte) bool {
+	trimmed := bytes.TrimLeft(payload, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// decodePayload decodes a job payload of the given content type into m,
+// naming the likely cause when the payload doesn't match its content type
+func decodePayload(contentType string, payload []byte, m proto.Message) error {
+	name := m.ProtoReflect().Descriptor().Name()
+	if contentType == JSONContentType {
+		if len(payload) > 0 && !looksLikeJSON(payload) {
+			return fmt.Errorf("payload is not a JSON object; omit content_type for protobuf payloads")
+		}
+		if err := protojson.Unmarshal(payload, m); err != nil {
+			return fmt.Errorf("invalid JSON %s: %v", name, err)
+		}
+		return nil
+	}
+
+	if looksLikeJSON(payload) {
+		return fmt.Errorf("payload looks like JSON; set content_type to %s", JSONContentType)
+	}
+	if err := proto.Unmarshal(payload, m); err != nil {
+		return fmt.Errorf("invalid protobuf %s: %v", name, err)
+	}
+	return nil
+}
+
+// resolvePayload returns req with its payload read from the uploaded file it
+// names, if any. Only files uploaded with the caller's API key can be named.
+// Files uploaded as JSON are decoded as JSON unless the submission sets a
+// content type.
+func (s *Service) resolvePayload(ctx context.Context, req *pb.SubmitJobRequest) (*pb.SubmitJobRequest, error) {
+	if req.PayloadFileId == "" {
+		return req, nil
+	}
+	if len(req.Payload) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "set either payload or payload_file_id, not both")
+	}
+	if s.files == nil {
+		return nil, status.Error(codes.FailedPrecondition, "file uploads are not enabled")
+	}
+
+	owner := s.callerKeyID(ctx)
+	f, err := s.files.Get(ctx, owner, req.PayloadFileId)
+	if errors.Is(err, files.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "payload file %s not found", req.PayloadFileId)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	payload, err := s.files.Read(ctx, owner, f.ID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read payload file %s: %v", f.ID, err)
+	}
+
+	resolved := proto.Clone(req).(*pb.SubmitJobRequest)
+	resolved.Payload = payload
+	if resolved.ContentType == "" {
+		if mediaType, _, err := mime.ParseMediaType(f.ContentType); err == nil && mediaType == JSONContentType {
+			resolved.ContentType = JSONContentType
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,134 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/pipeline"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// executePipeline runs a pipeline job on the orchestrator, dispatching each
+// inference step to a node selected for that step's model
+func (p *JobProcessor) executePipeline(ctx context.Context, job *queue.Job) {
+	var req pb.PipelineRequest
+	if err := decodePayload(job.ContentType, job.Payload, &req); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode pipeline request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to decode request: %v", err))
+		return
+	}
+
+	// Each completed step is streamed so subscribers can follow progress
+	runner := pipeline.NewRunner(&pipelineEngine{processor: p, locality: job.Locality})
+	resp, err := runner.Run(ctx, &req, func(step *pb.PipelineStepResult) {
+		if chunk, err := protojson.Marshal(step); err == nil {
+			p.queue.AppendChunk(job.ID, chunk)
+		}
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Pipeline job failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, err.Error())
+		return
+	}
+
+	result, err := proto.Marshal(resp)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to marshal response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to marshal response: %v", err))
+		return
+	}
+
+	p.completeJob(ctx, job.ID, result)
+	logging.FromContext(ctx).Info("Completed pipeline job", map[string]interface{}{
+		"steps": len(resp.Steps),
+	})
+}
+
+// pipelineEngine runs pipeline inference steps on scheduled nodes
+type pipelineEngine struct {
+	processor *JobProcessor
+	locality  map[string]string // The job's locality labels, for every step
+}
+
+// ChatCompletion runs a chat step and returns the assistant's reply
+func (e *pipelineEngine) ChatCompletion(ctx context.Context, req *pb.ChatCompletionRequest) (string, error) {
+	client, _, err := e.processor.selectNodeClient(&scheduler.Request{Model: req.Model, Kind: scheduler.KindChatCompletion, Locality: e.locality})
+	if err != nil {
+		return "", err
+	}
+
+	req.Batch = true
+	stream, err := client.ChatCompletion(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute: %w", err)
+	}
+
+	// Streamed responses carry deltas; otherwise the last response holds the full reply
+	var content strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error receiving response: %w", err)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+			continue
+		}
+		if !req.Stream {
+			content.Reset()
+		}
+		content.WriteString(resp.Choices[0].Message.Content)
+	}
+
+	return content.String(), nil
+}
+
+// Embeddings runs an embedding step
+func (e *pipelineEngine) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	client, selectedNode, err := e.processor.selectNodeClient(&scheduler.Request{Model: req.Model, Kind: scheduler.KindEmbeddings, Locality: e.locality})
+	if err != nil {
+		return nil, err
+	}
+
+	req.Batch = true
+	start := time.Now()
+	resp, err := client.Embeddings(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute: %w", err)
+	}
+	if e.processor.latencies != nil {
+		e.processor.latencies.Observe(selectedNode.Id, scheduler.KindEmbeddings, time.Since(start))
+	}
+	return resp, nil
+}
+
+// selectNodeClient selects a node for a request and returns a client for it
+func (p *JobProcessor) selectNodeClient(req *scheduler.Request) (pb.NodeAgentClient, *pb.Node, error) {
+	selectedNode, err := p.scheduler.SelectNode(req, p.registry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select node: %w", err)
+	}
+
+	client, err := p.getNodeClient(selectedNode.Id, selectedNode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to node: %w", err)
+	}
+	return client, selectedNode, nil
+}
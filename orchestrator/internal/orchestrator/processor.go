@@ -10,21 +10,50 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
 	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
 	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/orchestrator/internal/tunnel"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
+// GPUUsageTrailer is the gRPC trailer in which node agents report the GPU
+// usage (a serialized pb.GpuUsage) sampled around a request
+const GPUUsageTrailer = "orchion-gpu-usage-bin"
+
+// JobIDMetadata is the gRPC metadata key naming the job a NodeAgent call
+// runs. Agents journal it while the call runs and report it on restart if
+// they crashed meanwhile, so the job can be retried.
+const JobIDMetadata = "orchion-job-id"
+
 // JobProcessor processes jobs from the queue and assigns them to nodes
 type JobProcessor struct {
 	queue       *queue.JobQueue
 	scheduler   scheduler.Scheduler
 	registry    node.Registry
+	latencies   *scheduler.LatencyTracker
+	tunnels     *tunnel.Server
+	dialOpts    []grpc.DialOption
+	tailnet     bool // Dial agents at their tailnet address
 	nodeClients map[string]pb.NodeAgentClient
+	nodeConns   map[string]*grpc.ClientConn
 	mu          sync.RWMutex
+
+	// Results larger than resultThreshold are offloaded to resultStore
+	resultStore     results.Store
+	resultThreshold int
+
+	stopLoop context.CancelFunc // Stops dequeuing, set by Start
+	loopDone chan struct{}      // Closed once the loop stopped dequeuing
+	inFlight sync.WaitGroup     // Jobs being processed
 }
 
 // NewJobProcessor creates a new job processor
@@ -34,89 +63,218 @@ func NewJobProcessor(queue *queue.JobQueue, sched scheduler.Scheduler, registry
 		scheduler:   sched,
 		registry:    registry,
 		nodeClients: make(map[string]pb.NodeAgentClient),
+		nodeConns:   make(map[string]*grpc.ClientConn),
 	}
 }
 
-// Start begins processing jobs in a goroutine
+// SetLatencyTracker sets the tracker that records per-node request latency
+func (p *JobProcessor) SetLatencyTracker(tracker *scheduler.LatencyTracker) {
+	p.latencies = tracker
+}
+
+// SetTunnels sets the server through which agents behind NAT connect in
+func (p *JobProcessor) SetTunnels(tunnels *tunnel.Server) {
+	p.tunnels = tunnels
+}
+
+// SetDialOptions adds options, such as message size limits, to connections
+// to node agents
+func (p *JobProcessor) SetDialOptions(opts ...grpc.DialOption) {
+	p.dialOpts = opts
+}
+
+// SetPreferTailnet dials agents that reported a tailnet address at it
+// instead of their agent address
+func (p *JobProcessor) SetPreferTailnet(prefer bool) {
+	p.tailnet = prefer
+}
+
+// SetResultStore offloads results larger than threshold bytes to store instead
+// of keeping them in memory on the job
+func (p *JobProcessor) SetResultStore(store results.Store, threshold int) {
+	p.resultStore = store
+	p.resultThreshold = threshold
+}
+
+// Start begins processing jobs in a goroutine. Jobs run on ctx, so
+// cancelling it aborts them; Stop lets them finish instead.
 func (p *JobProcessor) Start(ctx context.Context) {
-	go p.processLoop(ctx)
+	loopCtx, stopLoop := context.WithCancel(ctx)
+	p.stopLoop = stopLoop
+	p.loopDone = make(chan struct{})
+	go p.processLoop(ctx, loopCtx)
 }
 
-// processLoop continuously processes jobs from the queue
-func (p *JobProcessor) processLoop(ctx context.Context) {
-	log.Printf("Job processor started")
-	defer log.Printf("Job processor stopped")
+// Stop stops taking jobs from the queue and waits for the jobs being
+// processed to finish, giving up once ctx is done. Jobs still pending stay
+// queued.
+func (p *JobProcessor) Stop(ctx context.Context) error {
+	if p.stopLoop == nil {
+		return nil
+	}
+	p.stopLoop()
+
+	drained := make(chan struct{})
+	go func() {
+		<-p.loopDone
+		p.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running: %w", ctx.Err())
+	}
+}
+
+// processLoop continuously processes jobs from the queue until loopCtx is
+// done, running them on ctx
+func (p *JobProcessor) processLoop(ctx, loopCtx context.Context) {
+	defer close(p.loopDone)
+	logger := logging.FromContext(ctx)
+	logger.Info("Job processor started", nil)
+	defer logger.Info("Job processor stopped", nil)
 
 	for {
-		select {
-		case <-ctx.Done():
+		job, err := p.queue.Dequeue(loopCtx)
+		if err != nil {
 			return
-		default:
-			// Try to dequeue with a short timeout to allow context checking
-			job := p.queue.DequeueWithTimeout(100 * time.Millisecond)
-			if job != nil {
-				// Process job in a separate goroutine to allow concurrent processing
-				go p.processJob(ctx, job)
-			}
 		}
+		// Process job in a separate goroutine to allow concurrent processing
+		p.inFlight.Add(1)
+		go func() {
+			defer p.inFlight.Done()
+			p.processJob(ctx, job)
+		}()
 	}
 }
 
 // processJob assigns a job to a node and dispatches it
 func (p *JobProcessor) processJob(ctx context.Context, job *queue.Job) {
-	log.Printf("Processing job %s (type: %d)", job.ID, job.Type)
+	// Every log line emitted while processing the job carries its ID
+	fields := map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": int(job.Type),
+	}
+	if job.User != "" {
+		fields["user"] = job.User
+	}
+	ctx = logging.ContextWithFields(ctx, fields)
+	logging.FromContext(ctx).Info("Processing job", nil)
 
 	// Update status to assigned
 	p.queue.UpdateStatus(job.ID, queue.JobAssigned)
 
-	// Select a node using the scheduler
-	selectedNode, err := p.scheduler.SelectNode("", p.registry)
-	if err != nil {
-		log.Printf("Failed to select node for job %s: %v", job.ID, err)
-		p.queue.FailJob(job.ID, fmt.Sprintf("failed to select node: %v", err))
+	// Pipelines run on the orchestrator and schedule each step separately
+	if job.Type == queue.JobTypePipeline {
+		p.queue.UpdateStatus(job.ID, queue.JobRunning)
+		p.executePipeline(ctx, job)
 		return
 	}
 
+	schedReq := &scheduler.Request{Model: job.Model, Kind: requestKind(job.Type), PayloadBytes: int64(len(job.Payload)), Locality: job.Locality}
+	var rejection error
+	for attempt := 1; ; attempt++ {
+		// Select a node using the scheduler
+		selectedNode, err := p.scheduler.SelectNode(schedReq, p.registry)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to select node", map[string]interface{}{
+				"error": err.Error(),
+			})
+			if rejection != nil {
+				// Every remaining node was tried; report why the last one refused
+				p.queue.FailJob(job.ID, fmt.Sprintf("failed to execute: %v", rejection))
+				return
+			}
+			p.queue.FailJob(job.ID, fmt.Sprintf("failed to select node: %v", err))
+			return
+		}
+
+		rejection = p.runOnNode(ctx, job, selectedNode)
+		if rejection == nil {
+			return
+		}
+		p.queue.EndAttempt(job.ID, rejection.Error())
+		if attempt >= scheduler.MaxNodeAttempts {
+			p.queue.FailJob(job.ID, fmt.Sprintf("failed to execute: %v", rejection))
+			return
+		}
+		// The node turned the job down before running it; try another
+		logging.FromContext(ctx).Warn("Node rejected job, retrying on another node", map[string]interface{}{
+			"node_id": selectedNode.Id,
+			"error":   rejection.Error(),
+		})
+		schedReq.Exclude = append(schedReq.Exclude, selectedNode.Id)
+	}
+}
+
+// runOnNode dispatches a job to a node. It returns the node's error if the
+// node rejected the job with a retryable error before running it; the job is
+// then left for another node. Any other outcome is recorded on the job.
+func (p *JobProcessor) runOnNode(ctx context.Context, job *queue.Job, selectedNode *pb.Node) error {
 	// Update job with assigned node
 	p.queue.UpdateStatusAndNode(job.ID, queue.JobRunning, selectedNode.Id)
-	log.Printf("Assigned job %s to node %s (%s)", job.ID, selectedNode.Id, selectedNode.AgentAddress)
+	ctx = logging.ContextWithFields(ctx, map[string]interface{}{
+		"node_id": selectedNode.Id,
+	})
+	logging.FromContext(ctx).Info("Assigned job to node", map[string]interface{}{
+		"agent_address": selectedNode.AgentAddress,
+	})
 
 	// Get or create gRPC client for this node
 	client, err := p.getNodeClient(selectedNode.Id, selectedNode)
 	if err != nil {
-		log.Printf("Failed to connect to node %s for job %s: %v", selectedNode.Id, job.ID, err)
+		logging.FromContext(ctx).Error("Failed to connect to node", map[string]interface{}{
+			"error": err.Error(),
+		})
 		p.queue.FailJob(job.ID, fmt.Sprintf("failed to connect to node: %v", err))
-		return
+		return nil
 	}
 
-	// Dispatch job based on type
+	// Dispatch job based on type, naming it for the agent's journal
+	ctx = metadata.AppendToOutgoingContext(ctx, JobIDMetadata, job.ID)
 	switch job.Type {
 	case queue.JobTypeChatCompletion:
-		p.executeChatCompletion(ctx, job, client)
+		return p.executeChatCompletion(ctx, job, client)
 	case queue.JobTypeEmbeddings:
-		p.executeEmbeddings(ctx, job, client)
+		return p.executeEmbeddings(ctx, job, client, selectedNode.Id)
+	case queue.JobTypeSpeech:
+		return p.executeSpeech(ctx, job, client, selectedNode.Id)
 	default:
-		log.Printf("Unknown job type %d for job %s", job.Type, job.ID)
+		logging.FromContext(ctx).Error("Unknown job type", nil)
 		p.queue.FailJob(job.ID, fmt.Sprintf("unknown job type: %d", job.Type))
+		return nil
 	}
 }
 
-// executeChatCompletion executes a chat completion job on a node
-func (p *JobProcessor) executeChatCompletion(ctx context.Context, job *queue.Job, client pb.NodeAgentClient) {
+// executeChatCompletion executes a chat completion job on a node. It returns
+// the node's error if the node rejected the job with a retryable error.
+func (p *JobProcessor) executeChatCompletion(ctx context.Context, job *queue.Job, client pb.NodeAgentClient) error {
 	// Deserialize the request from payload
 	var req pb.ChatCompletionRequest
-	if err := proto.Unmarshal(job.Payload, &req); err != nil {
-		log.Printf("Failed to unmarshal chat completion request for job %s: %v", job.ID, err)
-		p.queue.FailJob(job.ID, fmt.Sprintf("failed to unmarshal request: %v", err))
-		return
+	if err := decodePayload(job.ContentType, job.Payload, &req); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode chat completion request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to decode request: %v", err))
+		return nil
 	}
 
-	// Call the node agent
+	ctx = logging.ContextWithFields(ctx, map[string]interface{}{
+		"model": req.Model,
+	})
+	logger := logging.FromContext(ctx)
+
+	// Call the node agent; queued jobs yield to interactive requests
+	req.Batch = true
 	stream, err := client.ChatCompletion(ctx, &req)
 	if err != nil {
-		log.Printf("Failed to execute chat completion for job %s: %v", job.ID, err)
+		logger.Error("Failed to execute chat completion", map[string]interface{}{
+			"error": err.Error(),
+		})
 		p.queue.FailJob(job.ID, fmt.Sprintf("failed to execute: %v", err))
-		return
+		return nil
 	}
 
 	// Collect all responses (for async jobs, we store the final result)
@@ -128,61 +286,239 @@ func (p *JobProcessor) executeChatCompletion(ctx context.Context, job *queue.Job
 			if err == io.EOF {
 				break
 			}
-			log.Printf("Error receiving chat completion response for job %s: %v", job.ID, err)
+			if lastResponse == nil && errcode.IsRetryable(err) {
+				return err
+			}
+			logger.Error("Error receiving chat completion response", map[string]interface{}{
+				"error": err.Error(),
+			})
 			p.queue.FailJob(job.ID, fmt.Sprintf("error receiving response: %v", err))
-			return
+			return nil
+		}
+		// Jobs have no client waiting on the stream to keep alive
+		if resp.Status == llm.ModelLoadingStatus {
+			continue
 		}
 		lastResponse = resp
+
+		// Buffer the chunk so stream subscribers can catch up mid-generation
+		if chunk, err := protojson.Marshal(resp); err == nil {
+			p.queue.AppendChunk(job.ID, chunk)
+		}
 	}
 
+	p.recordGPUUsage(job.ID, stream.Trailer())
+
 	// Serialize the final response
 	if lastResponse != nil {
 		result, err := proto.Marshal(lastResponse)
 		if err != nil {
-			log.Printf("Failed to marshal response for job %s: %v", job.ID, err)
+			logger.Error("Failed to marshal response", map[string]interface{}{
+				"error": err.Error(),
+			})
 			p.queue.FailJob(job.ID, fmt.Sprintf("failed to marshal response: %v", err))
-			return
+			return nil
 		}
-		p.queue.CompleteJob(job.ID, result)
-		log.Printf("Completed chat completion job %s", job.ID)
+		p.completeJob(ctx, job.ID, result)
+		logger.Info("Completed chat completion job", nil)
 	} else {
-		p.queue.CompleteJob(job.ID, nil)
-		log.Printf("Completed chat completion job %s (no response)", job.ID)
+		p.completeJob(ctx, job.ID, nil)
+		logger.Info("Completed chat completion job (no response)", nil)
 	}
+	return nil
 }
 
-// executeEmbeddings executes an embeddings job on a node
-func (p *JobProcessor) executeEmbeddings(ctx context.Context, job *queue.Job, client pb.NodeAgentClient) {
+// executeEmbeddings executes an embeddings job on a node. It returns the
+// node's error if the node rejected the job with a retryable error.
+func (p *JobProcessor) executeEmbeddings(ctx context.Context, job *queue.Job, client pb.NodeAgentClient, nodeID string) error {
 	// Deserialize the request from payload
 	var req pb.EmbeddingRequest
-	if err := proto.Unmarshal(job.Payload, &req); err != nil {
-		log.Printf("Failed to unmarshal embedding request for job %s: %v", job.ID, err)
-		p.queue.FailJob(job.ID, fmt.Sprintf("failed to unmarshal request: %v", err))
-		return
+	if err := decodePayload(job.ContentType, job.Payload, &req); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode embedding request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to decode request: %v", err))
+		return nil
+	}
+
+	ctx = logging.ContextWithFields(ctx, map[string]interface{}{
+		"model": req.Model,
+	})
+	logger := logging.FromContext(ctx)
+
+	// Queued jobs yield to interactive requests
+	req.Batch = true
+
+	// Embed each distinct input once; ingestion batches often repeat chunks
+	unique, positions := dedupeInputs(req.Input)
+	dispatched := &req
+	if len(unique) < len(req.Input) {
+		dispatched = proto.Clone(&req).(*pb.EmbeddingRequest)
+		dispatched.Input = unique
+		logger.Info("Deduplicated embedding inputs", map[string]interface{}{
+			"inputs":   len(req.Input),
+			"distinct": len(unique),
+		})
 	}
 
 	// Call the node agent
-	resp, err := client.Embeddings(ctx, &req)
+	start := time.Now()
+	resp, err := llm.EmbedInChunks(ctx, dispatched, func(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+		var trailer metadata.MD
+		resp, err := client.Embeddings(ctx, req, grpc.Trailer(&trailer))
+		p.recordGPUUsage(job.ID, trailer)
+		return resp, err
+	})
 	if err != nil {
-		log.Printf("Failed to execute embeddings for job %s: %v", job.ID, err)
+		if errcode.IsRetryable(err) {
+			return err
+		}
+		logger.Error("Failed to execute embeddings", map[string]interface{}{
+			"error": err.Error(),
+		})
 		p.queue.FailJob(job.ID, fmt.Sprintf("failed to execute: %v", err))
-		return
+		return nil
+	}
+	if p.latencies != nil {
+		p.latencies.Observe(nodeID, scheduler.KindEmbeddings, time.Since(start))
+	}
+
+	if dispatched != &req {
+		if resp, err = expandEmbeddings(resp, positions); err != nil {
+			logger.Error("Failed to expand deduplicated embeddings", map[string]interface{}{
+				"error": err.Error(),
+			})
+			p.queue.FailJob(job.ID, fmt.Sprintf("failed to expand embeddings: %v", err))
+			return nil
+		}
+	}
+
+	// Serialize the response
+	result, err := proto.Marshal(resp)
+	if err != nil {
+		logger.Error("Failed to marshal response", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to marshal response: %v", err))
+		return nil
+	}
+
+	p.completeJob(ctx, job.ID, result)
+	logger.Info("Completed embeddings job", nil)
+	return nil
+}
+
+// executeSpeech executes a text-to-speech job on a node. It returns the
+// node's error if the node rejected the job with a retryable error.
+func (p *JobProcessor) executeSpeech(ctx context.Context, job *queue.Job, client pb.NodeAgentClient, nodeID string) error {
+	// Deserialize the request from payload
+	var req pb.SpeechRequest
+	if err := decodePayload(job.ContentType, job.Payload, &req); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode speech request", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to decode request: %v", err))
+		return nil
+	}
+
+	ctx = logging.ContextWithFields(ctx, map[string]interface{}{
+		"model": req.Model,
+	})
+	logger := logging.FromContext(ctx)
+
+	// Call the node agent
+	start := time.Now()
+	var trailer metadata.MD
+	resp, err := client.Speech(ctx, &req, grpc.Trailer(&trailer))
+	p.recordGPUUsage(job.ID, trailer)
+	if err != nil {
+		if errcode.IsRetryable(err) {
+			return err
+		}
+		logger.Error("Failed to execute speech", map[string]interface{}{
+			"error": err.Error(),
+		})
+		p.queue.FailJob(job.ID, fmt.Sprintf("failed to execute: %v", err))
+		return nil
+	}
+	if p.latencies != nil {
+		p.latencies.Observe(nodeID, scheduler.KindSpeech, time.Since(start))
 	}
 
 	// Serialize the response
 	result, err := proto.Marshal(resp)
 	if err != nil {
-		log.Printf("Failed to marshal response for job %s: %v", job.ID, err)
+		logger.Error("Failed to marshal response", map[string]interface{}{
+			"error": err.Error(),
+		})
 		p.queue.FailJob(job.ID, fmt.Sprintf("failed to marshal response: %v", err))
+		return nil
+	}
+
+	p.completeJob(ctx, job.ID, result)
+	logger.Info("Completed speech job", nil)
+	return nil
+}
+
+// completeJob marks a job completed, offloading large results to the result
+// store. If the store fails the result is kept in memory so it isn't lost.
+func (p *JobProcessor) completeJob(ctx context.Context, jobID string, result []byte) {
+	if p.resultStore == nil || len(result) <= p.resultThreshold {
+		p.queue.CompleteJob(jobID, result)
 		return
 	}
 
-	p.queue.CompleteJob(job.ID, result)
-	log.Printf("Completed embeddings job %s", job.ID)
+	if err := p.resultStore.Put(ctx, jobID, result); err != nil {
+		logging.FromContext(ctx).Warn("Failed to offload result, keeping it in memory", map[string]interface{}{
+			"error":        err.Error(),
+			"result_bytes": len(result),
+		})
+		p.queue.CompleteJob(jobID, result)
+		return
+	}
+	p.queue.CompleteJobWithRef(jobID, jobID, int64(len(result)))
+}
+
+// recordGPUUsage stores the GPU usage a node agent reported in the call trailer
+// on the job, so slow jobs can be correlated with GPU saturation
+func (p *JobProcessor) recordGPUUsage(jobID string, trailer metadata.MD) {
+	values := trailer.Get(GPUUsageTrailer)
+	if len(values) == 0 {
+		return
+	}
+	p.queue.SetGPUUsage(jobID, []byte(values[0]))
+}
+
+// requestKind maps a job type to the scheduler request kind
+func requestKind(t queue.JobType) scheduler.RequestKind {
+	switch t {
+	case queue.JobTypeChatCompletion:
+		return scheduler.KindChatCompletion
+	case queue.JobTypeEmbeddings:
+		return scheduler.KindEmbeddings
+	case queue.JobTypeSpeech:
+		return scheduler.KindSpeech
+	default:
+		return scheduler.KindUnspecified
+	}
+}
+
+// ForgetNode closes the connection to a node's agent, e.g. once the node is
+// evicted. The next call to the node connects again.
+func (p *JobProcessor) ForgetNode(nodeID string) {
+	p.mu.Lock()
+	conn := p.nodeConns[nodeID]
+	delete(p.nodeClients, nodeID)
+	delete(p.nodeConns, nodeID)
+	p.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
 }
 
 // getNodeClient gets or creates a gRPC client for a node
-func (p *JobProcessor) getNodeClient(nodeID string, node *pb.Node) (pb.NodeAgentClient, error) {
+func (p *JobProcessor) getNodeClient(nodeID string, n *pb.Node) (pb.NodeAgentClient, error) {
 	p.mu.RLock()
 	if client, exists := p.nodeClients[nodeID]; exists {
 		p.mu.RUnlock()
@@ -198,21 +534,29 @@ func (p *JobProcessor) getNodeClient(nodeID string, node *pb.Node) (pb.NodeAgent
 		return client, nil
 	}
 
-	// Determine node agent address
-	addr := node.AgentAddress
-	if addr == "" {
-		// Default to hostname:50052 if not specified
-		addr = fmt.Sprintf("%s:50052", node.Hostname)
+	addr := node.DialAddress(n, p.tailnet)
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, p.dialOpts...)
+	// Agents serving a node per GPU route calls by the target node
+	opts = append(opts, node.TargetDialOptions(nodeID)...)
+
+	// Agents behind NAT connect in; run the client over their tunnel
+	if n.Tunneled {
+		if p.tunnels == nil {
+			return nil, fmt.Errorf("node %s connects over a reverse tunnel but tunnels are disabled", nodeID)
+		}
+		addr = tunnel.Target(nodeID)
+		opts = append(opts, grpc.WithContextDialer(p.tunnels.Dial))
 	}
 
 	// Connect to node agent
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to node %s at %s: %w", nodeID, addr, err)
 	}
 
 	client := pb.NewNodeAgentClient(conn)
 	p.nodeClients[nodeID] = client
+	p.nodeConns[nodeID] = conn
 
 	log.Printf("Connected to node agent %s at %s", nodeID, addr)
 	return client, nil
@@ -0,0 +1,340 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// failingNodeClient fails every embeddings call
+type failingNodeClient struct {
+	pb.NodeAgentClient
+}
+
+func (failingNodeClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	return nil, assert.AnError
+}
+
+func TestJobProcessor_LogsCarryJobFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.NewLogger(logging.Config{Level: logging.InfoLevel, Source: "test"})
+	logger.SetOutput(&buf)
+
+	jobQueue := queue.NewJobQueue()
+	sched := &MockScheduler{}
+	sched.On("SelectNode", mock.Anything, mock.Anything).Return(&pb.Node{Id: "node-1"}, nil)
+
+	processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+	processor.nodeClients["node-1"] = failingNodeClient{}
+
+	payload, err := proto.Marshal(&pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+	require.NoError(t, err)
+	job := &queue.Job{ID: "job-1", Type: queue.JobTypeEmbeddings, Payload: payload}
+	jobQueue.Enqueue(job)
+
+	processor.processJob(logging.NewContext(context.Background(), logger), job)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "job-1", entry["job_id"], line)
+	}
+
+	// The failure is logged once the node and model are known
+	var last map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, "Failed to execute embeddings", last["msg"])
+	assert.Equal(t, "node-1", last["node_id"])
+	assert.Equal(t, "nomic-embed-text", last["model"])
+
+	failed, _ := jobQueue.Get("job-1")
+	assert.Equal(t, queue.JobFailed, failed.Status)
+}
+
+// failingResultStore rejects every result
+type failingResultStore struct{}
+
+func (failingResultStore) Put(ctx context.Context, key string, data []byte) error {
+	return assert.AnError
+}
+
+func (failingResultStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, results.ErrNotFound
+}
+
+func (failingResultStore) Delete(ctx context.Context, key string) error {
+	return results.ErrNotFound
+}
+
+func TestJobProcessor_CompleteJobOffloadsLargeResults(t *testing.T) {
+	ctx := context.Background()
+	store, err := results.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		store      results.Store
+		result     []byte
+		offloaded  bool
+		wantMemory []byte
+	}{
+		{"small result stays in memory", store, []byte("small"), false, []byte("small")},
+		{"large result is offloaded", store, bytes.Repeat([]byte("x"), 64), true, nil},
+		{"store failure keeps result in memory", failingResultStore{}, bytes.Repeat([]byte("y"), 64), false, bytes.Repeat([]byte("y"), 64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobQueue := queue.NewJobQueue()
+			processor := NewJobProcessor(jobQueue, &MockScheduler{}, &MockRegistry{})
+			processor.SetResultStore(tt.store, 16)
+
+			jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeEmbeddings})
+			processor.completeJob(ctx, "job-1", tt.result)
+
+			job, _ := jobQueue.Get("job-1")
+			assert.Equal(t, queue.JobCompleted, job.Status)
+			assert.Equal(t, tt.wantMemory, job.Result)
+			assert.Equal(t, int64(len(tt.result)), job.ResultSize)
+
+			if tt.offloaded {
+				assert.Equal(t, "job-1", job.ResultRef)
+				stored, err := results.Get(ctx, store, job.ResultRef)
+				require.NoError(t, err)
+				assert.Equal(t, tt.result, stored)
+			} else {
+				assert.Empty(t, job.ResultRef)
+			}
+		})
+	}
+}
+
+// rejectingNodeClient turns embeddings down as not fitting in free VRAM
+type rejectingNodeClient struct {
+	pb.NodeAgentClient
+}
+
+func (rejectingNodeClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	st, _ := status.New(codes.ResourceExhausted, "insufficient VRAM").
+		WithDetails(&pb.ErrorInfo{Code: pb.ErrorCode_ERROR_CODE_VRAM_EXHAUSTED, Retryable: true})
+	return nil, st.Err()
+}
+
+// embeddingNodeClient answers embeddings with an empty response
+type embeddingNodeClient struct {
+	pb.NodeAgentClient
+}
+
+func (embeddingNodeClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	return &pb.EmbeddingResponse{Model: req.Model}, nil
+}
+
+func TestJobProcessor_RetriesRejectedJobs(t *testing.T) {
+	excludes := func(ids ...string) interface{} {
+		return mock.MatchedBy(func(req *scheduler.Request) bool {
+			return len(req.Exclude) == len(ids) && (len(ids) == 0 || assert.ObjectsAreEqual(ids, req.Exclude))
+		})
+	}
+	payload, err := proto.Marshal(&pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+	require.NoError(t, err)
+
+	t.Run("another node runs the job", func(t *testing.T) {
+		jobQueue := queue.NewJobQueue()
+		sched := &MockScheduler{}
+		sched.On("SelectNode", excludes(), mock.Anything).Return(&pb.Node{Id: "full"}, nil)
+		sched.On("SelectNode", excludes("full"), mock.Anything).Return(&pb.Node{Id: "spare"}, nil)
+
+		processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+		processor.nodeClients["full"] = rejectingNodeClient{}
+		processor.nodeClients["spare"] = embeddingNodeClient{}
+
+		job := &queue.Job{ID: "job-1", Type: queue.JobTypeEmbeddings, Payload: payload, Model: "nomic-embed-text"}
+		jobQueue.Enqueue(job)
+		processor.processJob(context.Background(), job)
+
+		// The scheduler learns which model the job needs
+		for _, call := range sched.Calls {
+			assert.Equal(t, "nomic-embed-text", call.Arguments.Get(0).(*scheduler.Request).Model)
+		}
+
+		done, _ := jobQueue.Get("job-1")
+		assert.Equal(t, queue.JobCompleted, done.Status)
+		assert.Equal(t, "spare", done.AssignedNode)
+
+		attempts := jobQueue.Attempts("job-1")
+		require.Len(t, attempts, 2)
+		assert.Equal(t, "full", attempts[0].Node)
+		assert.Contains(t, attempts[0].Error, "insufficient VRAM")
+		assert.Equal(t, "spare", attempts[1].Node)
+		assert.Empty(t, attempts[1].Error)
+	})
+
+	t.Run("job fails with the rejection once no node is left", func(t *testing.T) {
+		jobQueue := queue.NewJobQueue()
+		sched := &MockScheduler{}
+		sched.On("SelectNode", excludes(), mock.Anything).Return(&pb.Node{Id: "full"}, nil)
+		sched.On("SelectNode", excludes("full"), mock.Anything).Return(nil, scheduler.ErrNoNodesAvailable)
+
+		processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+		processor.nodeClients["full"] = rejectingNodeClient{}
+
+		job := &queue.Job{ID: "job-1", Type: queue.JobTypeEmbeddings, Payload: payload}
+		jobQueue.Enqueue(job)
+		processor.processJob(context.Background(), job)
+
+		failed, _ := jobQueue.Get("job-1")
+		assert.Equal(t, queue.JobFailed, failed.Status)
+		assert.Contains(t, failed.ErrorMessage, "insufficient VRAM")
+	})
+}
+
+// speechNodeClient reads the input aloud as WAV audio
+type speechNodeClient struct {
+	pb.NodeAgentClient
+}
+
+func (speechNodeClient) Speech(ctx context.Context, req *pb.SpeechRequest, opts ...grpc.CallOption) (*pb.SpeechResponse, error) {
+	return &pb.SpeechResponse{Model: req.Model, Audio: []byte("RIFF" + req.Input), ContentType: "audio/wav"}, nil
+}
+
+func TestJobProcessor_SpeechJob(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	sched := &MockScheduler{}
+	sched.On("SelectNode", mock.MatchedBy(func(req *scheduler.Request) bool {
+		return req.Kind == scheduler.KindSpeech && req.Model == "piper/en_US-lessac-medium"
+	}), mock.Anything).Return(&pb.Node{Id: "node-1"}, nil)
+
+	processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+	processor.nodeClients["node-1"] = speechNodeClient{}
+
+	payload, err := proto.Marshal(&pb.SpeechRequest{Model: "piper/en_US-lessac-medium", Input: "hello", ResponseFormat: "wav"})
+	require.NoError(t, err)
+	job := &queue.Job{ID: "job-1", Type: queue.JobTypeSpeech, Payload: payload, Model: "piper/en_US-lessac-medium"}
+	jobQueue.Enqueue(job)
+	processor.processJob(context.Background(), job)
+
+	done, _ := jobQueue.Get("job-1")
+	require.Equal(t, queue.JobCompleted, done.Status, done.ErrorMessage)
+
+	var resp pb.SpeechResponse
+	require.NoError(t, proto.Unmarshal(done.Result, &resp))
+	assert.Equal(t, []byte("RIFFhello"), resp.Audio)
+	assert.Equal(t, "audio/wav", resp.ContentType)
+}
+
+func TestJobProcessor_PayloadContentType(t *testing.T) {
+	run := func(job *queue.Job) *queue.Job {
+		jobQueue := queue.NewJobQueue()
+		sched := &MockScheduler{}
+		sched.On("SelectNode", mock.Anything, mock.Anything).Return(&pb.Node{Id: "node-1"}, nil)
+
+		processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+		processor.nodeClients["node-1"] = speechNodeClient{}
+
+		jobQueue.Enqueue(job)
+		processor.processJob(context.Background(), job)
+		done, _ := jobQueue.Get(job.ID)
+		return done
+	}
+
+	t.Run("JSON payload", func(t *testing.T) {
+		done := run(&queue.Job{
+			ID:          "job-1",
+			Type:        queue.JobTypeSpeech,
+			Payload:     []byte(`{"model": "piper/en_US-lessac-medium", "input": "hello"}`),
+			ContentType: JSONContentType,
+		})
+		require.Equal(t, queue.JobCompleted, done.Status, done.ErrorMessage)
+
+		var resp pb.SpeechResponse
+		require.NoError(t, proto.Unmarshal(done.Result, &resp))
+		assert.Equal(t, "RIFFhello", string(resp.Audio))
+	})
+
+	t.Run("JSON payload without content type", func(t *testing.T) {
+		done := run(&queue.Job{
+			ID:      "job-1",
+			Type:    queue.JobTypeSpeech,
+			Payload: []byte(`{"model": "piper/en_US-lessac-medium", "input": "hello"}`),
+		})
+		assert.Equal(t, queue.JobFailed, done.Status)
+		assert.Contains(t, done.ErrorMessage, "set content_type to application/json")
+	})
+
+	t.Run("protobuf payload declared as JSON", func(t *testing.T) {
+		payload, err := proto.Marshal(&pb.SpeechRequest{Model: "piper/en_US-lessac-medium", Input: "hello"})
+		require.NoError(t, err)
+		done := run(&queue.Job{ID: "job-1", Type: queue.JobTypeSpeech, Payload: payload, ContentType: JSONContentType})
+		assert.Equal(t, queue.JobFailed, done.Status)
+		assert.Contains(t, done.ErrorMessage, "payload is not a JSON object")
+	})
+}
+
+// blockingNodeClient answers embeddings once released
+type blockingNodeClient struct {
+	pb.NodeAgentClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c blockingNodeClient) Embeddings(ctx context.Context, req *pb.EmbeddingRequest, opts ...grpc.CallOption) (*pb.EmbeddingResponse, error) {
+	close(c.started)
+	<-c.release
+	return &pb.EmbeddingResponse{Model: req.Model}, nil
+}
+
+func TestJobProcessor_StopDrainsJobs(t *testing.T) {
+	jobQueue := queue.NewJobQueue()
+	sched := &MockScheduler{}
+	sched.On("SelectNode", mock.Anything, mock.Anything).Return(&pb.Node{Id: "node-1"}, nil)
+
+	processor := NewJobProcessor(jobQueue, sched, &MockRegistry{})
+	client := blockingNodeClient{started: make(chan struct{}), release: make(chan struct{})}
+	processor.nodeClients["node-1"] = client
+
+	payload, err := proto.Marshal(&pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+	require.NoError(t, err)
+	jobQueue.Enqueue(&queue.Job{ID: "running", Type: queue.JobTypeEmbeddings, Payload: payload})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	processor.Start(ctx)
+	<-client.started
+
+	// The running job holds up the drain until it finishes
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelTimeout()
+	assert.ErrorIs(t, processor.Stop(timeout), context.DeadlineExceeded)
+
+	close(client.release)
+	require.NoError(t, processor.Stop(context.Background()))
+	done, _ := jobQueue.Get("running")
+	assert.Equal(t, queue.JobCompleted, done.Status)
+
+	// Jobs submitted after the processor stopped stay queued
+	jobQueue.Enqueue(&queue.Job{ID: "pending", Type: queue.JobTypeEmbeddings, Payload: payload})
+	assert.Never(t, func() bool {
+		pending, _ := jobQueue.Get("pending")
+		return pending.Status != queue.JobPending
+	}, 50*time.Millisecond, 5*time.Millisecond)
+}
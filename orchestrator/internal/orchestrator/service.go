@@ -2,14 +2,31 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/pagination"
+	"github.com/Orchion/Orchion/orchestrator/internal/pipeline"
+	"github.com/Orchion/Orchion/orchestrator/internal/profiles"
 	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
 	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
 // Service implements the Orchion gRPC service
@@ -18,6 +35,20 @@ type Service struct {
 	registry  node.Registry
 	queue     *queue.JobQueue
 	scheduler scheduler.Scheduler
+	logger    logging.Logger
+	history   *node.History
+	events    *node.EventLog
+	results   results.Store
+	files     *files.Store
+	authz     *auth.Authorizer
+	prober    *node.Prober
+	tailnet   bool // Probe agents at their tailnet address
+	pins      *images.Pins
+	profiles  *profiles.Profiles
+	network   *scheduler.NetworkTracker
+	sharing   *catalog.Catalog
+	now       func() time.Time
+	dialer    NodeDialer
 }
 
 // NewService creates a new orchestrator service
@@ -26,6 +57,7 @@ func NewService(registry node.Registry, jobQueue *queue.JobQueue, sched schedule
 		registry:  registry,
 		queue:     jobQueue,
 		scheduler: sched,
+		now:       time.Now,
 	}
 }
 
@@ -44,11 +76,98 @@ func (s *Service) RegisterNode(ctx context.Context, req *pb.RegisterNodeRequest)
 		return nil, status.Error(codes.InvalidArgument, "node.id is required")
 	}
 
+	// Profile settings replace the agent's own, so they're validated too
+	var config *pb.NodeConfig
+	if profile := s.profiles.Match(req.Node); profile != nil {
+		config = profile.Apply(req.Node)
+	}
+
+	// Catch misconfigured agents when they join rather than when scheduling
+	if err := node.Normalize(req.Node); err != nil {
+		return nil, invalidNode(err)
+	}
+
+	prev := s.eventNode(req.Node.Id)
 	if err := s.registry.Register(req.Node); err != nil {
+		if err == node.ErrNodeIDConflict {
+			return nil, s.nodeIDConflict(ctx, req.Node)
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	s.recordHistory(req.Node.Id)
+	s.recordRegistration(prev, req.Node)
+	s.requeueInterrupted(ctx, req.Node.Id, req.InterruptedRequests)
+	// Tunneled agents can't be dialed by design
+	if s.prober != nil && !req.Node.Tunneled {
+		s.prober.Probe(ctx, req.Node.Id, node.DialAddress(req.Node, s.tailnet))
+	}
+
+	return &pb.RegisterNodeResponse{Config: config}, nil
+}
+
+// requeueInterrupted retries the jobs an agent reports its crash interrupted,
+// unless they were retried already or have used up their attempts, so a job
+// crashing agents isn't retried forever
+func (s *Service) requeueInterrupted(ctx context.Context, nodeID string, interrupted []*pb.InterruptedRequest) {
+	if s.queue == nil {
+		return
+	}
+	for _, req := range interrupted {
+		ranOn := req.NodeId
+		if ranOn == "" {
+			ranOn = nodeID
+		}
+		fields := map[string]interface{}{
+			"job_id":  req.JobId,
+			"node_id": ranOn,
+			"model":   req.Model,
+		}
+		if len(s.queue.Attempts(req.JobId)) >= scheduler.MaxNodeAttempts {
+			logging.FromContext(ctx).Warn("Not retrying job interrupted by an agent crash, out of attempts", fields)
+			continue
+		}
+		if s.queue.Requeue(req.JobId, ranOn, "interrupted by an agent crash") {
+			logging.FromContext(ctx).Info("Retrying job interrupted by an agent crash", fields)
+		}
+	}
+}
+
+// invalidNode returns the error for a node that failed validation, listing
+// each invalid field as a BadRequest field violation
+func invalidNode(err error) error {
+	var invalid *node.ValidationError
+	if !errors.As(err, &invalid) {
+		return errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, err.Error())
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, f := range invalid.Fields {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Description,
+		})
+	}
+	return errcode.New(codes.InvalidArgument, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, err.Error(), badRequest)
+}
+
+// nodeIDConflict logs a registration rejected because another online agent
+// holds the node ID and returns the error for the registering agent
+func (s *Service) nodeIDConflict(ctx context.Context, n *pb.Node) error {
+	holder := &pb.Node{}
+	if existing, ok := s.registry.Get(n.Id); ok {
+		holder = existing
+	}
 
-	return &pb.RegisterNodeResponse{}, nil
+	logging.FromContext(ctx).Warn("Rejected registration with a node ID held by another agent", map[string]interface{}{
+		"node_id":                  n.Id,
+		"hostname":                 n.Hostname,
+		"agent_address":            n.AgentAddress,
+		"registered_hostname":      holder.Hostname,
+		"registered_agent_address": holder.AgentAddress,
+	})
+	return errcode.Errorf(codes.AlreadyExists, pb.ErrorCode_ERROR_CODE_NODE_ID_CONFLICT,
+		"node ID %s is already registered by %s (%s); give each agent a unique -node-id",
+		n.Id, holder.Hostname, holder.AgentAddress)
 }
 
 // Heartbeat updates the heartbeat timestamp for a node
@@ -57,17 +176,24 @@ func (s *Service) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.
 		return nil, status.Error(codes.InvalidArgument, "node_id is required")
 	}
 
-	if err := s.registry.UpdateHeartbeat(req.NodeId); err != nil {
+	err := s.registry.UpdateHeartbeat(req.NodeId)
+	if err == nil {
+		err = s.registry.UpdateThrottle(req.NodeId, req.Throttle)
+	}
+	if err != nil {
 		if err == node.ErrNodeNotFound {
 			return nil, status.Error(codes.NotFound, "node not found")
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	s.recordHistory(req.NodeId)
+	s.recordHeartbeat(req.NodeId)
+	s.recordNetwork(req.NodeId, req.Network)
 
-	return &pb.HeartbeatResponse{}, nil
+	return &pb.HeartbeatResponse{ImagePins: s.imagePins(), Sharing: s.gpuSharing()}, nil
 }
 
-// UpdateNode updates a node's capabilities
+// UpdateNode updates a node's capabilities and loaded models
 func (s *Service) UpdateNode(ctx context.Context, req *pb.UpdateNodeRequest) (*pb.UpdateNodeResponse, error) {
 	if req.NodeId == "" {
 		return nil, status.Error(codes.InvalidArgument, "node_id is required")
@@ -76,51 +202,412 @@ func (s *Service) UpdateNode(ctx context.Context, req *pb.UpdateNodeRequest) (*p
 	if req.Capabilities == nil {
 		return nil, status.Error(codes.InvalidArgument, "capabilities is required")
 	}
+	if err := node.NormalizeCapabilities(req.Capabilities); err != nil {
+		return nil, invalidNode(err)
+	}
 
+	prev := s.eventNode(req.NodeId)
 	if err := s.registry.UpdateCapabilities(req.NodeId, req.Capabilities); err != nil {
 		if err == node.ErrNodeNotFound {
 			return nil, status.Error(codes.NotFound, "node not found")
 		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	s.recordCapabilityChanges(req.NodeId, prev, req.Capabilities)
+	if err := s.registry.UpdateModels(req.NodeId, req.Models); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 
 	return &pb.UpdateNodeResponse{}, nil
 }
 
-// ListNodes returns all registered nodes
+// MaxBatchHeartbeats bounds the logical nodes one BatchHeartbeat call covers
+const MaxBatchHeartbeats = 256
+
+// BatchHeartbeat records the heartbeats, and updates where included, of the
+// logical nodes an agent serves. Nodes the registry doesn't know are reported
+// back for the agent to register again instead of failing the whole batch.
+func (s *Service) BatchHeartbeat(ctx context.Context, req *pb.BatchHeartbeatRequest) (*pb.BatchHeartbeatResponse, error) {
+	if len(req.Heartbeats) > MaxBatchHeartbeats {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d heartbeats per batch", MaxBatchHeartbeats)
+	}
+	for _, hb := range req.Heartbeats {
+		if hb.NodeId == "" {
+			return nil, status.Error(codes.InvalidArgument, "node_id is required")
+		}
+		if hb.Capabilities != nil {
+			if err := node.NormalizeCapabilities(hb.Capabilities); err != nil {
+				return nil, invalidNode(err)
+			}
+		}
+	}
+
+	resp := &pb.BatchHeartbeatResponse{ImagePins: s.imagePins(), Sharing: s.gpuSharing()}
+	for _, hb := range req.Heartbeats {
+		err := s.registry.UpdateHeartbeat(hb.NodeId)
+		if err == nil {
+			s.recordHeartbeat(hb.NodeId)
+			// The agent's logical nodes share its link
+			s.recordNetwork(hb.NodeId, req.Network)
+			err = s.registry.UpdateThrottle(hb.NodeId, hb.Throttle)
+		}
+		if err == nil && hb.Capabilities != nil {
+			prev := s.eventNode(hb.NodeId)
+			err = s.registry.UpdateCapabilities(hb.NodeId, hb.Capabilities)
+			if err == nil {
+				s.recordCapabilityChanges(hb.NodeId, prev, hb.Capabilities)
+				err = s.registry.UpdateModels(hb.NodeId, hb.Models)
+			}
+		}
+		if err == node.ErrNodeNotFound {
+			resp.UnknownNodeIds = append(resp.UnknownNodeIds, hb.NodeId)
+			continue
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		s.recordHistory(hb.NodeId)
+	}
+
+	return resp, nil
+}
+
+// SetHistory sets where node hardware samples are recorded on each heartbeat
+func (s *Service) SetHistory(history *node.History) {
+	s.history = history
+}
+
+// recordHistory samples a node's current capabilities into the history
+func (s *Service) recordHistory(nodeID string) {
+	if s.history == nil {
+		return
+	}
+	if n, ok := s.registry.Get(nodeID); ok {
+		s.history.Record(n)
+	}
+}
+
+// SetEvents sets where node events such as registrations and capability
+// changes are recorded
+func (s *Service) SetEvents(events *node.EventLog) {
+	s.events = events
+}
+
+// eventNode returns a node as registered before an update, to record what the
+// update changed, or nil if events aren't recorded or the node isn't known
+func (s *Service) eventNode(nodeID string) *pb.Node {
+	if s.events == nil {
+		return nil
+	}
+	n, _ := s.registry.Get(nodeID)
+	return n
+}
+
+// recordRegistration records a node registering, and it being drained if the
+// registration added the draining label. prev is the node as registered
+// before, if it was.
+func (s *Service) recordRegistration(prev, n *pb.Node) {
+	if s.events == nil {
+		return
+	}
+	s.events.Record(n.Id, node.EventRegistered, fmt.Sprintf("%s at %s", n.Hostname, n.AgentAddress))
+	if _, draining := n.Labels[node.DrainingLabel]; draining {
+		if _, wasDraining := prev.GetLabels()[node.DrainingLabel]; !wasDraining {
+			s.events.Record(n.Id, node.EventDrained, "registered with the draining label")
+		}
+	}
+	if prev != nil {
+		s.recordCapabilityChanges(n.Id, prev, n.Capabilities)
+	}
+}
+
+// recordHeartbeat records a heartbeat arriving after the node's heartbeat
+// was reported lost
+func (s *Service) recordHeartbeat(nodeID string) {
+	if s.events == nil {
+		return
+	}
+	if last, ok := s.events.Last(nodeID); ok && last.Type == node.EventHeartbeatLost {
+		lost := time.Since(time.UnixMilli(last.TimestampMs)).Round(time.Second)
+		s.events.Record(nodeID, node.EventHeartbeatRestored, fmt.Sprintf("heartbeat resumed after %s", lost))
+	}
+}
+
+// recordCapabilityChanges records changes to a node's capabilities. prev is
+// the node before the update, nil if unknown.
+func (s *Service) recordCapabilityChanges(nodeID string, prev *pb.Node, caps *pb.Capabilities) {
+	if s.events == nil || prev == nil {
+		return
+	}
+	if changes := node.CapabilityChanges(prev.Capabilities, caps); len(changes) > 0 {
+		s.events.Record(nodeID, node.EventCapabilitiesChanged, strings.Join(changes, ", "))
+	}
+}
+
+// SetImagePins sets the engine images pinned fleet-wide, which heartbeat
+// responses tell agents about
+func (s *Service) SetImagePins(pins *images.Pins) {
+	s.pins = pins
+}
+
+// SetNodeProfiles sets the config profiles registering nodes are matched
+// against
+func (s *Service) SetNodeProfiles(p *profiles.Profiles) {
+	s.profiles = p
+}
+
+// imagePins returns the engine images pinned fleet-wide, if any
+func (s *Service) imagePins() []*pb.ImagePin {
+	if s.pins == nil {
+		return nil
+	}
+	return s.pins.List()
+}
+
+// SetNetworkTracker sets where the link stats agents report with their
+// heartbeats are recorded, for bandwidth-aware placement
+func (s *Service) SetNetworkTracker(network *scheduler.NetworkTracker) {
+	s.network = network
+}
+
+// recordNetwork records a node's reported link stats, if tracked
+func (s *Service) recordNetwork(nodeID string, stats *pb.NetworkStats) {
+	if s.network != nil {
+		s.network.Observe(nodeID, stats)
+	}
+}
+
+// SetGPUSharing sets the catalog whose GPU sharing schedule heartbeat
+// responses tell agents the interactive reserve from
+func (s *Service) SetGPUSharing(models *catalog.Catalog) {
+	s.sharing = models
+}
+
+// gpuSharing returns the GPU sharing policy in effect, if any
+func (s *Service) gpuSharing() *pb.GpuSharing {
+	reserve, window := s.sharing.InteractiveReserve(s.now())
+	if window == nil || reserve <= 0 {
+		return nil
+	}
+	return &pb.GpuSharing{InteractiveReserve: reserve, Window: window.Name}
+}
+
+// SetProber enables checking that registering nodes' agent addresses are
+// reachable from the orchestrator
+func (s *Service) SetProber(prober *node.Prober) {
+	s.prober = prober
+}
+
+// SetPreferTailnet probes agents that reported a tailnet address at it, as
+// they're dialed there
+func (s *Service) SetPreferTailnet(prefer bool) {
+	s.tailnet = prefer
+}
+
+// ListNodes returns registered nodes matching the request's filters, sorted
+// and a page at a time if requested
 func (s *Service) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
-	nodes := s.registry.List()
-	return &pb.ListNodesResponse{Nodes: nodes}, nil
+	query, err := node.NewQuery(req, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	nodes := query.Filter(s.registry.List())
+	if s.prober != nil {
+		for _, n := range nodes {
+			n.AgentAddressError = s.prober.Unreachable(n.Id)
+		}
+	}
+	if req.PageSize == 0 && req.PageToken == "" && req.OrderBy == "" {
+		return &pb.ListNodesResponse{Nodes: nodes}, nil
+	}
+
+	key, _ := node.SortKey(req.OrderBy)
+	page, next, err := pagination.Page(nodes, key, int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.ListNodesResponse{Nodes: page, NextPageToken: next}, nil
 }
 
 func (s *Service) SubmitJob(ctx context.Context, req *pb.SubmitJobRequest) (*pb.SubmitJobResponse, error) {
-	if req.JobId == "" {
-		return nil, status.Error(codes.InvalidArgument, "job_id is required")
+	req, err := s.resolvePayload(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	job, err := newJob(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, dep := range req.DependsOn {
+		if _, ok := s.queue.Get(dep); !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown dependency %s", dep)
+		}
+	}
+
+	if err := s.queue.Enqueue(job); err != nil {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	position := s.queue.Position(job.ID)
+
+	return &pb.SubmitJobResponse{
+		JobId:           job.ID,
+		Status:          protoJobStatus(job.Status), // Failed already if a dependency failed
+		QueuePosition:   int32(position),
+		QueueDepth:      int32(s.queue.Count()),
+		EstimatedWaitMs: s.queue.EstimatedWait(position).Milliseconds(),
+	}, nil
+}
+
+// SubmitJobGroup queues a group of jobs, which run once the jobs they depend
+// on completed
+func (s *Service) SubmitJobGroup(ctx context.Context, req *pb.SubmitJobGroupRequest) (*pb.SubmitJobGroupResponse, error) {
+	if req.GroupId == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+	if err := queue.ValidateID(req.GroupId); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid group_id: %v", err)
+	}
+	if len(req.Jobs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "jobs are required")
+	}
+
+	jobs := make([]*queue.Job, len(req.Jobs))
+	for i, jobReq := range req.Jobs {
+		jobReq, err := s.resolvePayload(ctx, jobReq)
+		if err != nil {
+			return nil, err
+		}
+		job, err := newJob(jobReq)
+		if err != nil {
+			return nil, err
+		}
+		jobs[i] = job
+	}
+
+	if err := s.queue.EnqueueGroup(req.GroupId, jobs); err != nil {
+		if errors.Is(err, queue.ErrGroupExists) || errors.Is(err, queue.ErrJobExists) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.SubmitJobGroupResponse{
+		GroupId: req.GroupId,
+		Status:  protoJobStatus(queue.GroupStatus(jobs)),
+	}, nil
+}
+
+// GetJobGroup returns the aggregated status of a job group and its jobs
+func (s *Service) GetJobGroup(ctx context.Context, req *pb.GetJobGroupRequest) (*pb.GetJobGroupResponse, error) {
+	if req.GroupId == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+
+	jobs, ok := s.queue.Group(req.GroupId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "job group not found")
+	}
+
+	summaries := make([]*pb.JobSummary, len(jobs))
+	for i, job := range jobs {
+		summaries[i] = jobSummary(job)
+	}
+	return &pb.GetJobGroupResponse{
+		GroupId: req.GroupId,
+		Status:  protoJobStatus(queue.GroupStatus(jobs)),
+		Jobs:    summaries,
+	}, nil
+}
+
+// checkPayload decodes a submission's payload into m for its metadata. JSON
+// payloads must decode, as must anything that looks like JSON; other protobuf
+// payloads failing to decode are left for the processor to report, as before
+// content types existed.
+func checkPayload(contentType string, payload []byte, m proto.Message) error {
+	err := decodePayload(contentType, payload, m)
+	if err != nil && (contentType == JSONContentType || looksLikeJSON(payload)) {
+		return status.Errorf(codes.InvalidArgument, "invalid payload: %v", err)
+	}
+	if err != nil {
+		proto.Reset(m)
+	}
+	return nil
+}
+
+// newJob creates the queued job a submission describes, generating its ID if
+// the client left it empty
+func newJob(req *pb.SubmitJobRequest) (*queue.Job, error) {
+	id := req.JobId
+	if id == "" {
+		var err error
+		if id, err = queue.NewID(); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	} else if err := queue.ValidateID(id); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid job_id: %v", err)
+	}
+
+	contentType, err := payloadContentType(req.ContentType)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
 	// Convert proto job type to internal job type
 	var jobType queue.JobType
+	var user, model string
 	switch req.JobType {
 	case pb.JobType_JOB_TYPE_CHAT_COMPLETION:
 		jobType = queue.JobTypeChatCompletion
+		var chatReq pb.ChatCompletionRequest
+		if err := checkPayload(contentType, req.Payload, &chatReq); err != nil {
+			return nil, err
+		}
+		user, model = chatReq.User, chatReq.Model
 	case pb.JobType_JOB_TYPE_EMBEDDINGS:
 		jobType = queue.JobTypeEmbeddings
+		var embedReq pb.EmbeddingRequest
+		if err := checkPayload(contentType, req.Payload, &embedReq); err != nil {
+			return nil, err
+		}
+		user, model = embedReq.User, embedReq.Model
+	case pb.JobType_JOB_TYPE_SPEECH:
+		jobType = queue.JobTypeSpeech
+		var speechReq pb.SpeechRequest
+		if err := checkPayload(contentType, req.Payload, &speechReq); err != nil {
+			return nil, err
+		}
+		user, model = speechReq.User, speechReq.Model
+	case pb.JobType_JOB_TYPE_PIPELINE:
+		jobType = queue.JobTypePipeline
+		// Reject broken pipelines up front rather than failing them in the queue
+		var pipelineReq pb.PipelineRequest
+		if err := decodePayload(contentType, req.Payload, &pipelineReq); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid pipeline payload: %v", err)
+		}
+		if err := pipeline.Validate(&pipelineReq); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid pipeline: %v", err)
+		}
 	default:
 		return nil, status.Error(codes.InvalidArgument, "job_type is required")
 	}
-
-	job := &queue.Job{
-		ID:      req.JobId,
-		Type:    jobType,
-		Payload: req.Payload,
-		Status:  queue.JobPending,
+	for key := range req.Locality {
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "=,!") {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid locality: label key %q must be non-empty and not contain '=', ',' or '!'", key)
+		}
 	}
 
-	s.queue.Enqueue(job)
-
-	return &pb.SubmitJobResponse{
-		JobId:  job.ID,
-		Status: pb.JobStatus_JOB_STATUS_PENDING,
+	return &queue.Job{
+		ID:          id,
+		Type:        jobType,
+		Payload:     req.Payload,
+		ContentType: contentType,
+		Status:      queue.JobPending,
+		User:        user,
+		Model:       model,
+		DependsOn:   req.DependsOn,
+		Locality:    req.Locality,
 	}, nil
 }
 
@@ -136,27 +623,234 @@ func (s *Service) GetJobStatus(ctx context.Context, req *pb.GetJobStatusRequest)
 	}
 
 	// Convert internal status to proto status
-	var protoStatus pb.JobStatus
-	switch job.Status {
+	var gpuUsage *pb.GpuUsage
+	if len(job.GPUUsage) > 0 {
+		gpuUsage = &pb.GpuUsage{}
+		if err := proto.Unmarshal(job.GPUUsage, gpuUsage); err != nil {
+			gpuUsage = nil
+		}
+	}
+
+	result, err := s.loadResult(ctx, job)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	position := s.queue.Position(job.ID)
+
+	return &pb.GetJobStatusResponse{
+		JobId:           job.ID,
+		Status:          protoJobStatus(job.Status),
+		AssignedNode:    job.AssignedNode,
+		ErrorMessage:    job.ErrorMessage,
+		Result:          result,
+		QueuePosition:   int32(position),
+		EstimatedWaitMs: s.queue.EstimatedWait(position).Milliseconds(),
+		GpuUsage:        gpuUsage,
+		ResultSize:      job.ResultSize,
+		Attempts:        protoJobAttempts(s.queue.Attempts(job.ID)),
+	}, nil
+}
+
+// ResultChunkSize is the size of the chunks GetJobResult streams results in
+const ResultChunkSize = 64 << 10
+
+// GetJobResult streams a completed job's result in chunks, reading it from
+// the result store if it was offloaded, so multi-MB results neither sit in
+// memory nor hit the message size limit
+func (s *Service) GetJobResult(req *pb.GetJobResultRequest, stream pb.Orchestrator_GetJobResultServer) error {
+	if req.JobId == "" {
+		return status.Error(codes.InvalidArgument, "job_id is required")
+	}
+
+	job, found := s.queue.Get(req.JobId)
+	if !found {
+		return status.Error(codes.NotFound, "job not found")
+	}
+	if job.Status != queue.JobCompleted {
+		return status.Errorf(codes.FailedPrecondition, "job is %s", job.Status)
+	}
+
+	ctx := stream.Context()
+	r, err := results.OpenJobRange(ctx, s.results, job, req.Offset, req.Length)
+	switch {
+	case errors.Is(err, results.ErrInvalidRange):
+		return status.Errorf(codes.OutOfRange, "range outside the %d byte result", job.ResultSize)
+	case errors.Is(err, results.ErrNotFound):
+		return status.Error(codes.NotFound, "result not found")
+	case err != nil:
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer r.Close()
+
+	// The first chunk describes the result, even if it's empty
+	resp := &pb.GetJobResultResponse{
+		Offset:      req.Offset,
+		TotalSize:   job.ResultSize,
+		ContentType: results.ContentType(job.Type),
+		MessageType: results.MessageType(job.Type),
+	}
+	buf := make([]byte, ResultChunkSize)
+	for first := true; ; first = false {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || first {
+			resp.Data = buf[:n]
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+			resp = &pb.GetJobResultResponse{Offset: resp.Offset + int64(n)}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read result: %v", err)
+		}
+	}
+}
+
+// protoJobAttempts converts the node attempts of a job
+func protoJobAttempts(attempts []queue.Attempt) []*pb.JobAttempt {
+	out := make([]*pb.JobAttempt, len(attempts))
+	for i, a := range attempts {
+		out[i] = &pb.JobAttempt{
+			NodeId:      a.Node,
+			StartedAtMs: a.StartedAt.UnixMilli(),
+			Error:       a.Error,
+		}
+		if !a.EndedAt.IsZero() {
+			out[i].EndedAtMs = a.EndedAt.UnixMilli()
+		}
+	}
+	return out
+}
+
+// ListJobs returns jobs oldest first, a page at a time if requested
+func (s *Service) ListJobs(ctx context.Context, req *pb.ListJobsRequest) (*pb.ListJobsResponse, error) {
+	jobs, next, err := s.queue.ListPage(int(req.PageSize), req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	summaries := make([]*pb.JobSummary, 0, len(jobs))
+	for _, job := range jobs {
+		summaries = append(summaries, jobSummary(job))
+	}
+
+	return &pb.ListJobsResponse{Jobs: summaries, NextPageToken: next}, nil
+}
+
+// jobSummary describes a job without its payload or result
+func jobSummary(job *queue.Job) *pb.JobSummary {
+	return &pb.JobSummary{
+		JobId:           job.ID,
+		JobType:         protoJobType(job.Type),
+		Status:          protoJobStatus(job.Status),
+		AssignedNode:    job.AssignedNode,
+		ErrorMessage:    job.ErrorMessage,
+		CreatedAtUnixMs: job.CreatedAt.UnixMilli(),
+		UpdatedAtUnixMs: job.UpdatedAt.UnixMilli(),
+		ResultSize:      job.ResultSize,
+		User:            job.User,
+		GroupId:         job.Group,
+		DependsOn:       job.DependsOn,
+	}
+}
+
+// protoJobStatus converts an internal job status to its proto equivalent
+func protoJobStatus(s queue.JobStatus) pb.JobStatus {
+	switch s {
 	case queue.JobPending:
-		protoStatus = pb.JobStatus_JOB_STATUS_PENDING
+		return pb.JobStatus_JOB_STATUS_PENDING
 	case queue.JobAssigned:
-		protoStatus = pb.JobStatus_JOB_STATUS_ASSIGNED
+		return pb.JobStatus_JOB_STATUS_ASSIGNED
 	case queue.JobRunning:
-		protoStatus = pb.JobStatus_JOB_STATUS_RUNNING
+		return pb.JobStatus_JOB_STATUS_RUNNING
 	case queue.JobCompleted:
-		protoStatus = pb.JobStatus_JOB_STATUS_COMPLETED
+		return pb.JobStatus_JOB_STATUS_COMPLETED
 	case queue.JobFailed:
-		protoStatus = pb.JobStatus_JOB_STATUS_FAILED
+		return pb.JobStatus_JOB_STATUS_FAILED
 	default:
-		protoStatus = pb.JobStatus_JOB_STATUS_UNSPECIFIED
+		return pb.JobStatus_JOB_STATUS_UNSPECIFIED
 	}
+}
 
-	return &pb.GetJobStatusResponse{
-		JobId:        job.ID,
-		Status:       protoStatus,
-		AssignedNode: job.AssignedNode,
-		ErrorMessage: job.ErrorMessage,
-		Result:       job.Result,
-	}, nil
+// protoJobType converts an internal job type to its proto equivalent
+func protoJobType(t queue.JobType) pb.JobType {
+	switch t {
+	case queue.JobTypeChatCompletion:
+		return pb.JobType_JOB_TYPE_CHAT_COMPLETION
+	case queue.JobTypeEmbeddings:
+		return pb.JobType_JOB_TYPE_EMBEDDINGS
+	case queue.JobTypePipeline:
+		return pb.JobType_JOB_TYPE_PIPELINE
+	case queue.JobTypeSpeech:
+		return pb.JobType_JOB_TYPE_SPEECH
+	default:
+		return pb.JobType_JOB_TYPE_UNSPECIFIED
+	}
+}
+
+// SetResultStore sets the store holding results the job processor offloaded
+func (s *Service) SetResultStore(store results.Store) {
+	s.results = store
+}
+
+// SetFileStore lets jobs name a file uploaded to /v1/files as their payload
+func (s *Service) SetFileStore(store *files.Store) {
+	s.files = store
+}
+
+// SetAuthorizer resolves the session tokens callers present to the key they
+// were opened with, so jobs can name files uploaded with either
+func (s *Service) SetAuthorizer(authz *auth.Authorizer) {
+	s.authz = authz
+}
+
+// callerKeyID returns the ID of the API key a call presents, see
+// Authorizer.KeyIDOf
+func (s *Service) callerKeyID(ctx context.Context) string {
+	if s.authz != nil {
+		return s.authz.KeyIDOf(auth.MetadataKey(ctx))
+	}
+	return auth.KeyID(auth.MetadataKey(ctx))
+}
+
+// loadResult returns a job's result, reading it back from the result store if
+// it was offloaded
+func (s *Service) loadResult(ctx context.Context, job *queue.Job) ([]byte, error) {
+	if job.ResultRef == "" {
+		return job.Result, nil
+	}
+	if s.results == nil {
+		return nil, fmt.Errorf("result for job %s was offloaded but no result store is configured", job.ID)
+	}
+
+	result, err := results.Get(ctx, s.results, job.ResultRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load result for job %s: %w", job.ID, err)
+	}
+	return result, nil
+}
+
+// SetLogger sets the logger whose level SetLogLevel adjusts
+func (s *Service) SetLogger(logger logging.Logger) {
+	s.logger = logger
+}
+
+// SetLogLevel changes the orchestrator's log level without a restart
+func (s *Service) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	if s.logger == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no logger configured")
+	}
+
+	if req.Level != "" {
+		level, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		s.logger.SetLevel(level)
+	}
+
+	return &pb.SetLogLevelResponse{Level: s.logger.GetLevel().String()}, nil
 }
@@ -2,18 +2,32 @@ package orchestrator
 
 import (
 	"context"
+	"strings"
 	"time"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/auth"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/errcode"
+	"github.com/Orchion/Orchion/orchestrator/internal/files"
+	"github.com/Orchion/Orchion/orchestrator/internal/images"
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/profiles"
 	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+	"github.com/Orchion/Orchion/orchestrator/internal/results"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/shared/logging"
 )
 
 // MockRegistry is a mock implementation of node.Registry
@@ -31,11 +45,21 @@ func (m *MockRegistry) UpdateCapabilities(nodeID string, capabilities *pb.Capabi
 	return args.Error(0)
 }
 
+func (m *MockRegistry) UpdateModels(nodeID string, models []*pb.ModelEngine) error {
+	args := m.Called(nodeID, models)
+	return args.Error(0)
+}
+
 func (m *MockRegistry) UpdateHeartbeat(nodeID string) error {
 	args := m.Called(nodeID)
 	return args.Error(0)
 }
 
+func (m *MockRegistry) UpdateThrottle(nodeID string, throttle *pb.NodeThrottle) error {
+	args := m.Called(nodeID, throttle)
+	return args.Error(0)
+}
+
 func (m *MockRegistry) List() []*pb.Node {
 	args := m.Called()
 	return args.Get(0).([]*pb.Node)
@@ -56,13 +80,18 @@ func (m *MockRegistry) CheckHeartbeats(timeout time.Duration) []string {
 	return args.Get(0).([]string)
 }
 
+func (m *MockRegistry) Annotate(nodeID string, notes string, annotations map[string]string) error {
+	args := m.Called(nodeID, notes, annotations)
+	return args.Error(0)
+}
+
 // MockScheduler is a mock implementation of scheduler.Scheduler
 type MockScheduler struct {
 	mock.Mock
 }
 
-func (m *MockScheduler) SelectNode(model string, registry node.Registry) (*pb.Node, error) {
-	args := m.Called(model, registry)
+func (m *MockScheduler) SelectNode(req *scheduler.Request, registry node.Registry) (*pb.Node, error) {
+	args := m.Called(req, registry)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -118,6 +147,73 @@ func TestService_RegisterNode(t *testing.T) {
 		mockRegistry.AssertExpectations(t)
 	})
 
+	t.Run("applies the matching node profile", func(t *testing.T) {
+		registry := node.NewInMemoryRegistry()
+		service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+		service.SetNodeProfiles(&profiles.Profiles{Profiles: []*profiles.Profile{
+			{Name: "gpu", Nodes: []string{"gpu-*"}, Labels: map[string]string{"tier": "gpu"}, MaxConcurrentRequests: 4},
+		}})
+
+		resp, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: &pb.Node{
+			Id:       "gpu-1",
+			Hostname: "gpu-box",
+			Labels:   map[string]string{"zone": "eu-west"},
+		}})
+		require.NoError(t, err)
+		require.NotNil(t, resp.Config)
+		assert.Equal(t, "gpu", resp.Config.Profile)
+		assert.Equal(t, int32(4), resp.Config.MaxConcurrentRequests)
+
+		registered, ok := registry.Get("gpu-1")
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{"zone": "eu-west", "tier": "gpu"}, registered.Labels)
+
+		// Nodes no profile matches get no config
+		resp, err = service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: &pb.Node{Id: "laptop", Hostname: "laptop"}})
+		require.NoError(t, err)
+		assert.Nil(t, resp.Config)
+	})
+
+	t.Run("retries interrupted requests", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		jobQueue := queue.NewJobQueue()
+		service := NewService(mockRegistry, jobQueue, &MockScheduler{})
+
+		// Jobs that failed when the agent crashed, one out of attempts
+		for _, id := range []string{"job-1", "job-2", "job-3"} {
+			jobQueue.Enqueue(&queue.Job{ID: id, Type: queue.JobTypeChatCompletion})
+			jobQueue.DequeueNonBlocking()
+		}
+		jobQueue.UpdateStatusAndNode("job-1", queue.JobRunning, "test-node")
+		jobQueue.FailJob("job-1", "stream error")
+		jobQueue.UpdateStatusAndNode("job-2", queue.JobRunning, "test-node-gpu1")
+		jobQueue.FailJob("job-2", "stream error")
+		for i := 0; i < scheduler.MaxNodeAttempts; i++ {
+			jobQueue.UpdateStatusAndNode("job-3", queue.JobRunning, "test-node")
+		}
+		jobQueue.FailJob("job-3", "stream error")
+
+		node := &pb.Node{Id: "test-node", Hostname: "test-host"}
+		mockRegistry.On("Register", node).Return(nil)
+
+		_, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{
+			Node: node,
+			InterruptedRequests: []*pb.InterruptedRequest{
+				{JobId: "job-1"},
+				{JobId: "job-2", NodeId: "test-node-gpu1"},
+				{JobId: "job-3"},
+				{JobId: "unknown-job"},
+			},
+		})
+		require.NoError(t, err)
+
+		for id, want := range map[string]queue.JobStatus{"job-1": queue.JobPending, "job-2": queue.JobPending, "job-3": queue.JobFailed} {
+			job, _ := jobQueue.Get(id)
+			assert.Equal(t, want, job.Status, id)
+		}
+		assert.Equal(t, 2, jobQueue.Count())
+	})
+
 	t.Run("nil node", func(t *testing.T) {
 		mockRegistry := &MockRegistry{}
 		mockQueue := queue.NewJobQueue()
@@ -157,6 +253,33 @@ func TestService_RegisterNode(t *testing.T) {
 		assert.Contains(t, st.Message(), "node.id is required")
 	})
 
+	t.Run("invalid node", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		service := NewService(mockRegistry, queue.NewJobQueue(), &MockScheduler{})
+
+		resp, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: &pb.Node{
+			Id:           "test-node",
+			Hostname:     " ",
+			Capabilities: &pb.Capabilities{Memory: "lots"},
+		}})
+
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_INVALID_REQUEST, errcode.FromError(err))
+
+		var fields []string
+		for _, detail := range status.Convert(err).Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				for _, v := range badRequest.FieldViolations {
+					fields = append(fields, v.Field)
+				}
+			}
+		}
+		assert.Equal(t, []string{"hostname", "capabilities.memory"}, fields)
+		mockRegistry.AssertNotCalled(t, "Register", mock.Anything)
+	})
+
 	t.Run("registry error", func(t *testing.T) {
 		mockRegistry := &MockRegistry{}
 		mockQueue := queue.NewJobQueue()
@@ -180,6 +303,23 @@ func TestService_RegisterNode(t *testing.T) {
 		assert.Equal(t, codes.Internal, st.Code())
 		mockRegistry.AssertExpectations(t)
 	})
+	t.Run("node ID held by another agent", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		service := NewService(mockRegistry, queue.NewJobQueue(), &MockScheduler{})
+
+		clone := &pb.Node{Id: "test-node", Hostname: "vm-2", AgentAddress: "10.0.0.2:50052"}
+		mockRegistry.On("Register", clone).Return(node.ErrNodeIDConflict)
+		mockRegistry.On("Get", "test-node").Return(&pb.Node{Id: "test-node", Hostname: "vm-1", AgentAddress: "10.0.0.1:50052"}, true)
+
+		resp, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: clone})
+
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Equal(t, pb.ErrorCode_ERROR_CODE_NODE_ID_CONFLICT, errcode.FromError(err))
+		assert.Contains(t, status.Convert(err).Message(), "vm-1")
+		mockRegistry.AssertExpectations(t)
+	})
 }
 
 func TestService_Heartbeat(t *testing.T) {
@@ -192,9 +332,11 @@ func TestService_Heartbeat(t *testing.T) {
 
 		service := NewService(mockRegistry, mockQueue, mockScheduler)
 
+		throttle := &pb.NodeThrottle{Reason: "GPU temperature 88°C over 85°C limit"}
 		mockRegistry.On("UpdateHeartbeat", "test-node").Return(nil)
+		mockRegistry.On("UpdateThrottle", "test-node", throttle).Return(nil)
 
-		resp, err := service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "test-node"})
+		resp, err := service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "test-node", Throttle: throttle})
 
 		require.NoError(t, err)
 		assert.NotNil(t, resp)
@@ -258,6 +400,65 @@ func TestService_Heartbeat(t *testing.T) {
 	})
 }
 
+func TestService_BatchHeartbeat(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("heartbeats and updates nodes", func(t *testing.T) {
+		registry := node.NewInMemoryRegistry()
+		require.NoError(t, registry.Register(&pb.Node{Id: "gpu0", Hostname: "host", LastSeenUnix: 1}))
+		require.NoError(t, registry.Register(&pb.Node{Id: "gpu1", Hostname: "host", LastSeenUnix: 1}))
+		service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+
+		resp, err := service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{Heartbeats: []*pb.NodeHeartbeat{
+			{NodeId: "gpu0"},
+			{
+				NodeId:       "gpu1",
+				Capabilities: &pb.Capabilities{Cpu: "8 cores", GpuVramTotal: "24.0 GB"},
+				Models:       []*pb.ModelEngine{{Model: "llama3", Engine: "ollama"}},
+				Throttle:     &pb.NodeThrottle{Reason: "GPU power 310.0 W over 300 W limit"},
+			},
+			{NodeId: "gpu2"},
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpu2"}, resp.UnknownNodeIds)
+
+		gpu0, _ := registry.Get("gpu0")
+		assert.Greater(t, gpu0.LastSeenUnix, int64(1))
+		assert.Nil(t, gpu0.Capabilities)
+		assert.Nil(t, gpu0.Throttle)
+
+		gpu1, _ := registry.Get("gpu1")
+		assert.Greater(t, gpu1.LastSeenUnix, int64(1))
+		assert.Equal(t, "24.0 GB", gpu1.Capabilities.GpuVramTotal)
+		assert.Len(t, gpu1.Models, 1)
+		assert.Equal(t, "GPU power 310.0 W over 300 W limit", gpu1.Throttle.GetReason())
+	})
+
+	t.Run("invalid heartbeats", func(t *testing.T) {
+		service := NewService(node.NewInMemoryRegistry(), queue.NewJobQueue(), &MockScheduler{})
+
+		tests := []struct {
+			name       string
+			heartbeats []*pb.NodeHeartbeat
+		}{
+			{"empty node ID", []*pb.NodeHeartbeat{{NodeId: ""}}},
+			{"too many", make([]*pb.NodeHeartbeat, MaxBatchHeartbeats+1)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for i := range tt.heartbeats {
+					if tt.heartbeats[i] == nil {
+						tt.heartbeats[i] = &pb.NodeHeartbeat{NodeId: "node"}
+					}
+				}
+				_, err := service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{Heartbeats: tt.heartbeats})
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		}
+	})
+}
+
 func TestService_UpdateNode(t *testing.T) {
 	ctx := context.Background()
 
@@ -273,11 +474,14 @@ func TestService_UpdateNode(t *testing.T) {
 			Memory: "16GB",
 		}
 
+		models := []*pb.ModelEngine{{Model: "llama3", Engine: "ollama", Version: "0.3.12"}}
 		mockRegistry.On("UpdateCapabilities", "test-node", capabilities).Return(nil)
+		mockRegistry.On("UpdateModels", "test-node", models).Return(nil)
 
 		resp, err := service.UpdateNode(ctx, &pb.UpdateNodeRequest{
 			NodeId:       "test-node",
 			Capabilities: capabilities,
+			Models:       models,
 		})
 
 		require.NoError(t, err)
@@ -393,6 +597,107 @@ func TestService_ListNodes(t *testing.T) {
 	})
 }
 
+func TestService_ListNodesPagination(t *testing.T) {
+	ctx := context.Background()
+
+	mockRegistry := &MockRegistry{}
+	mockRegistry.On("List").Return([]*pb.Node{
+		{Id: "node-3"}, {Id: "node-1"}, {Id: "node-2"},
+	})
+	service := NewService(mockRegistry, queue.NewJobQueue(), &MockScheduler{})
+
+	resp, err := service.ListNodes(ctx, &pb.ListNodesRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Nodes, 2)
+	assert.Equal(t, "node-1", resp.Nodes[0].Id)
+	assert.Equal(t, "node-2", resp.Nodes[1].Id)
+	require.NotEmpty(t, resp.NextPageToken)
+
+	resp, err = service.ListNodes(ctx, &pb.ListNodesRequest{PageSize: 2, PageToken: resp.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, resp.Nodes, 1)
+	assert.Equal(t, "node-3", resp.Nodes[0].Id)
+	assert.Empty(t, resp.NextPageToken)
+
+	_, err = service.ListNodes(ctx, &pb.ListNodesRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = service.ListNodes(ctx, &pb.ListNodesRequest{PageToken: "%%%"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_ListNodesFilters(t *testing.T) {
+	ctx := context.Background()
+
+	mockRegistry := &MockRegistry{}
+	mockRegistry.On("List").Return([]*pb.Node{
+		{Id: "node-1", Labels: map[string]string{"zone": "eu"}, Capabilities: &pb.Capabilities{GpuVramAvailable: "4 GB"}},
+		{Id: "node-2", Labels: map[string]string{"zone": "us"}, Capabilities: &pb.Capabilities{GpuVramAvailable: "20 GB"}},
+		{Id: "node-3", Labels: map[string]string{"zone": "eu"}, Capabilities: &pb.Capabilities{GpuVramAvailable: "12 GB"}},
+	})
+	service := NewService(mockRegistry, queue.NewJobQueue(), &MockScheduler{})
+
+	resp, err := service.ListNodes(ctx, &pb.ListNodesRequest{LabelSelector: "zone=eu", OrderBy: "-vram_free"})
+	require.NoError(t, err)
+	require.Len(t, resp.Nodes, 2)
+	assert.Equal(t, "node-3", resp.Nodes[0].Id)
+	assert.Equal(t, "node-1", resp.Nodes[1].Id)
+
+	// Filters apply before pagination
+	resp, err = service.ListNodes(ctx, &pb.ListNodesRequest{LabelSelector: "zone=eu", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.Nodes, 1)
+	assert.Equal(t, "node-1", resp.Nodes[0].Id)
+	assert.NotEmpty(t, resp.NextPageToken)
+
+	_, err = service.ListNodes(ctx, &pb.ListNodesRequest{Status: "offline"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestService_ListJobs(t *testing.T) {
+	ctx := context.Background()
+	jobQueue := queue.NewJobQueue()
+	service := NewService(&MockRegistry{}, jobQueue, &MockScheduler{})
+
+	jobQueue.Enqueue(&queue.Job{ID: "job-1", Type: queue.JobTypeChatCompletion})
+	time.Sleep(time.Millisecond)
+	jobQueue.Enqueue(&queue.Job{ID: "job-2", Type: queue.JobTypeEmbeddings})
+	jobQueue.CompleteJob("job-2", []byte("result"))
+
+	t.Run("all jobs when unpaginated", func(t *testing.T) {
+		resp, err := service.ListJobs(ctx, &pb.ListJobsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Jobs, 2)
+		assert.Empty(t, resp.NextPageToken)
+
+		assert.Equal(t, "job-1", resp.Jobs[0].JobId)
+		assert.Equal(t, pb.JobType_JOB_TYPE_CHAT_COMPLETION, resp.Jobs[0].JobType)
+		assert.Equal(t, pb.JobStatus_JOB_STATUS_PENDING, resp.Jobs[0].Status)
+
+		assert.Equal(t, "job-2", resp.Jobs[1].JobId)
+		assert.Equal(t, pb.JobStatus_JOB_STATUS_COMPLETED, resp.Jobs[1].Status)
+		assert.Equal(t, int64(len("result")), resp.Jobs[1].ResultSize)
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		resp, err := service.ListJobs(ctx, &pb.ListJobsRequest{PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, resp.Jobs, 1)
+		assert.Equal(t, "job-1", resp.Jobs[0].JobId)
+
+		resp, err = service.ListJobs(ctx, &pb.ListJobsRequest{PageSize: 1, PageToken: resp.NextPageToken})
+		require.NoError(t, err)
+		require.Len(t, resp.Jobs, 1)
+		assert.Equal(t, "job-2", resp.Jobs[0].JobId)
+		assert.Empty(t, resp.NextPageToken)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := service.ListJobs(ctx, &pb.ListJobsRequest{PageToken: "%%%"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestService_SubmitJob(t *testing.T) {
 	ctx := context.Background()
 
@@ -425,6 +730,102 @@ func TestService_SubmitJob(t *testing.T) {
 		assert.Equal(t, payload, job.Payload)
 	})
 
+	t.Run("records the request's user and model", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+
+		payload, err := proto.Marshal(&pb.EmbeddingRequest{Model: "m", Input: []string{"hi"}, User: "user-42"})
+		require.NoError(t, err)
+		_, err = service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:   "job-user",
+			JobType: pb.JobType_JOB_TYPE_EMBEDDINGS,
+			Payload: payload,
+		})
+		require.NoError(t, err)
+
+		job, found := mockQueue.Get("job-user")
+		require.True(t, found)
+		assert.Equal(t, "user-42", job.User)
+		assert.Equal(t, "m", job.Model)
+
+		resp, err := service.ListJobs(ctx, &pb.ListJobsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Jobs, 1)
+		assert.Equal(t, "user-42", resp.Jobs[0].User)
+	})
+
+	t.Run("records the job's locality", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+
+		_, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:    "job-local",
+			JobType:  pb.JobType_JOB_TYPE_EMBEDDINGS,
+			Locality: map[string]string{"corpus": "nas1"},
+		})
+		require.NoError(t, err)
+		job, found := mockQueue.Get("job-local")
+		require.True(t, found)
+		assert.Equal(t, map[string]string{"corpus": "nas1"}, job.Locality)
+
+		_, err = service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobType:  pb.JobType_JOB_TYPE_EMBEDDINGS,
+			Locality: map[string]string{"corpus=nas1": ""},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("payload from an uploaded file", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+		req := &pb.SubmitJobRequest{JobId: "job-file", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS, PayloadFileId: "file-000000000000000000000000"}
+
+		_, err := service.SubmitJob(ctx, req)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err), "uploads not enabled")
+
+		blobs, err := results.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+		store := files.NewStore(blobs)
+		service.SetFileStore(store)
+		_, err = service.SubmitJob(ctx, req)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		payload := []byte(`{"model": "nomic-embed-text", "input": ["a", "b"]}`)
+		f, err := store.Put(ctx, auth.KeyID("alice-key"), "inputs.json", "user_data", "application/json", payload)
+		require.NoError(t, err)
+		req.PayloadFileId = f.ID
+		_, err = service.SubmitJob(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer bob-key")), req)
+		assert.Equal(t, codes.NotFound, status.Code(err), "other keys' files")
+		_, err = service.SubmitJob(ctx, req)
+		assert.Equal(t, codes.NotFound, status.Code(err), "anonymous")
+
+		_, err = service.SubmitJob(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer alice-key")), req)
+		require.NoError(t, err)
+
+		// A session opened with the key names the key's files too
+		authz := auth.NewAuthorizer("alice-key", nil)
+		sessions, err := auth.NewSessions(nil, time.Hour)
+		require.NoError(t, err)
+		authz.SetSessions(sessions)
+		service.SetAuthorizer(authz)
+		token, _, _, err := authz.Login("alice-key")
+		require.NoError(t, err)
+		session := proto.Clone(req).(*pb.SubmitJobRequest)
+		session.JobId = "job-file-session"
+		_, err = service.SubmitJob(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token)), session)
+		require.NoError(t, err)
+		job, found := mockQueue.Get("job-file")
+		require.True(t, found)
+		assert.Equal(t, payload, job.Payload)
+		assert.Equal(t, JSONContentType, job.ContentType)
+		assert.Equal(t, "nomic-embed-text", job.Model)
+		assert.Empty(t, req.Payload, "the submission is left alone")
+
+		req.JobId, req.Payload = "job-both", payload
+		_, err = service.SubmitJob(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer alice-key")), req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
 	t.Run("successful embeddings job submission", func(t *testing.T) {
 		mockRegistry := &MockRegistry{}
 		mockQueue := queue.NewJobQueue()
@@ -454,6 +855,69 @@ func TestService_SubmitJob(t *testing.T) {
 		assert.Equal(t, payload, job.Payload)
 	})
 
+	t.Run("pipeline job submission", func(t *testing.T) {
+		service := NewService(&MockRegistry{}, queue.NewJobQueue(), &MockScheduler{})
+
+		payload, err := proto.Marshal(&pb.PipelineRequest{
+			Steps: []*pb.PipelineStep{
+				{Name: "answer", Step: &pb.PipelineStep_Chat{Chat: &pb.ChatCompletionRequest{Model: "llama3"}}},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:   "pipeline-job",
+			JobType: pb.JobType_JOB_TYPE_PIPELINE,
+			Payload: payload,
+		})
+		require.NoError(t, err)
+
+		job, found := service.queue.Get("pipeline-job")
+		require.True(t, found)
+		assert.Equal(t, queue.JobTypePipeline, job.Type)
+	})
+
+	t.Run("invalid pipeline is rejected", func(t *testing.T) {
+		service := NewService(&MockRegistry{}, queue.NewJobQueue(), &MockScheduler{})
+
+		payload, err := proto.Marshal(&pb.PipelineRequest{})
+		require.NoError(t, err)
+
+		_, err = service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:   "pipeline-job",
+			JobType: pb.JobType_JOB_TYPE_PIPELINE,
+			Payload: payload,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "pipeline has no steps")
+	})
+
+	t.Run("reports queue position and depth", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		mockQueue := queue.NewJobQueue()
+		mockScheduler := &MockScheduler{}
+
+		service := NewService(mockRegistry, mockQueue, mockScheduler)
+
+		for _, id := range []string{"job-1", "job-2"} {
+			_, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+				JobId:   id,
+				JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+			})
+			require.NoError(t, err)
+		}
+
+		resp, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:   "job-3",
+			JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), resp.QueuePosition)
+		assert.Equal(t, int32(3), resp.QueueDepth)
+		assert.Equal(t, int64(0), resp.EstimatedWaitMs) // No throughput history yet
+	})
+
 	t.Run("empty job ID", func(t *testing.T) {
 		mockRegistry := &MockRegistry{}
 		mockQueue := queue.NewJobQueue()
@@ -466,12 +930,78 @@ func TestService_SubmitJob(t *testing.T) {
 			JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
 		})
 
-		require.Error(t, err)
-		assert.Nil(t, resp)
-		st, ok := status.FromError(err)
-		assert.True(t, ok)
-		assert.Equal(t, codes.InvalidArgument, st.Code())
-		assert.Contains(t, st.Message(), "job_id is required")
+		// The server generates a UUID
+		require.NoError(t, err)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, resp.JobId)
+		_, found := mockQueue.Get(resp.JobId)
+		assert.True(t, found)
+	})
+
+	t.Run("invalid job ID", func(t *testing.T) {
+		service := NewService(&MockRegistry{}, queue.NewJobQueue(), &MockScheduler{})
+
+		for _, id := range []string{"job\nlevel=error msg=forged", "job 1", strings.Repeat("a", queue.MaxIDLength+1)} {
+			_, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+				JobId:   id,
+				JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), id)
+		}
+
+		_, err := service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{
+			GroupId: "group/../1",
+			Jobs:    []*pb.SubmitJobRequest{{JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION}},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("payload content types", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+		chat := []byte(`{"model": "llama3", "messages": [{"role": "user", "content": "hi"}], "user": "alice"}`)
+
+		resp, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobType:     pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+			Payload:     chat,
+			ContentType: "application/json; charset=utf-8",
+		})
+		require.NoError(t, err)
+		job, found := mockQueue.Get(resp.JobId)
+		require.True(t, found)
+		assert.Equal(t, "application/json", job.ContentType)
+		assert.Equal(t, "llama3", job.Model)
+		assert.Equal(t, "alice", job.User)
+
+		for name, req := range map[string]*pb.SubmitJobRequest{
+			"JSON without content type": {Payload: chat},
+			"invalid JSON":              {Payload: []byte(`{"model": 1}`), ContentType: "application/json"},
+			"protobuf declared as JSON": {Payload: []byte("\x0a\x06llama3"), ContentType: "application/json"},
+			"unsupported content type":  {Payload: chat, ContentType: "text/yaml"},
+		} {
+			req.JobType = pb.JobType_JOB_TYPE_CHAT_COMPLETION
+			_, err := service.SubmitJob(ctx, req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+		}
+	})
+
+	t.Run("taken job ID", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+		_, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{JobId: "dup", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS, Payload: []byte("first")})
+		require.NoError(t, err)
+
+		_, err = service.SubmitJob(ctx, &pb.SubmitJobRequest{JobId: "dup", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS, Payload: []byte("second")})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		_, err = service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{
+			GroupId: "group-dup",
+			Jobs:    []*pb.SubmitJobRequest{{JobId: "dup", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS}},
+		})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		job, found := mockQueue.Get("dup")
+		require.True(t, found)
+		assert.Equal(t, []byte("first"), job.Payload)
+		assert.Equal(t, 1, mockQueue.Count())
 	})
 
 	t.Run("invalid job type", func(t *testing.T) {
@@ -495,6 +1025,68 @@ func TestService_SubmitJob(t *testing.T) {
 	})
 }
 
+func TestService_SubmitJobGroup(t *testing.T) {
+	ctx := context.Background()
+	jobQueue := queue.NewJobQueue()
+	service := NewService(&MockRegistry{}, jobQueue, &MockScheduler{})
+
+	resp, err := service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{
+		GroupId: "group-1",
+		Jobs: []*pb.SubmitJobRequest{
+			{JobId: "extract", JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION, Payload: []byte("a")},
+			{JobId: "summarize", JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION, Payload: []byte("b"), DependsOn: []string{"extract"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "group-1", resp.GroupId)
+	assert.Equal(t, pb.JobStatus_JOB_STATUS_PENDING, resp.Status)
+
+	// Only the job without dependencies is queued
+	assert.Equal(t, 1, jobQueue.Count())
+
+	group, err := service.GetJobGroup(ctx, &pb.GetJobGroupRequest{GroupId: "group-1"})
+	require.NoError(t, err)
+	require.Len(t, group.Jobs, 2)
+	assert.Equal(t, "extract", group.Jobs[0].JobId)
+	assert.Equal(t, "group-1", group.Jobs[1].GroupId)
+	assert.Equal(t, []string{"extract"}, group.Jobs[1].DependsOn)
+
+	t.Run("existing group", func(t *testing.T) {
+		_, err := service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{
+			GroupId: "group-1",
+			Jobs:    []*pb.SubmitJobRequest{{JobId: "other", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS}},
+		})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("dependency listed later", func(t *testing.T) {
+		_, err := service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{
+			GroupId: "group-2",
+			Jobs: []*pb.SubmitJobRequest{
+				{JobId: "b", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS, DependsOn: []string{"a"}},
+				{JobId: "a", JobType: pb.JobType_JOB_TYPE_EMBEDDINGS},
+			},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = service.GetJobGroup(ctx, &pb.GetJobGroupRequest{GroupId: "group-2"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("no jobs", func(t *testing.T) {
+		_, err := service.SubmitJobGroup(ctx, &pb.SubmitJobGroupRequest{GroupId: "group-3"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		_, err := service.SubmitJob(ctx, &pb.SubmitJobRequest{
+			JobId:     "lonely",
+			JobType:   pb.JobType_JOB_TYPE_EMBEDDINGS,
+			DependsOn: []string{"missing"},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestService_GetJobStatus(t *testing.T) {
 	ctx := context.Background()
 
@@ -550,6 +1142,86 @@ func TestService_GetJobStatus(t *testing.T) {
 		assert.Equal(t, "Model not available", resp.ErrorMessage)
 	})
 
+	t.Run("job with attempts", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+
+		mockQueue.Enqueue(&queue.Job{ID: "retried-job"})
+		mockQueue.UpdateStatusAndNode("retried-job", queue.JobRunning, "node-a")
+		mockQueue.EndAttempt("retried-job", "insufficient VRAM")
+		mockQueue.UpdateStatusAndNode("retried-job", queue.JobRunning, "node-b")
+
+		resp, err := service.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: "retried-job"})
+		require.NoError(t, err)
+		require.Len(t, resp.Attempts, 2)
+		assert.Equal(t, "node-a", resp.Attempts[0].NodeId)
+		assert.Equal(t, "insufficient VRAM", resp.Attempts[0].Error)
+		assert.NotZero(t, resp.Attempts[0].EndedAtMs)
+		assert.Equal(t, "node-b", resp.Attempts[1].NodeId)
+		assert.Zero(t, resp.Attempts[1].EndedAtMs, "still running")
+	})
+
+	t.Run("job with GPU usage", func(t *testing.T) {
+		mockRegistry := &MockRegistry{}
+		mockQueue := queue.NewJobQueue()
+		mockScheduler := &MockScheduler{}
+
+		service := NewService(mockRegistry, mockQueue, mockScheduler)
+
+		usage := &pb.GpuUsage{
+			Start: &pb.GpuSample{UtilizationPercent: 98, VramUsedMb: 23000},
+			End:   &pb.GpuSample{UtilizationPercent: 41, VramUsedMb: 23100},
+		}
+		data, err := proto.Marshal(usage)
+		require.NoError(t, err)
+
+		mockQueue.Enqueue(&queue.Job{ID: "gpu-job", Status: queue.JobCompleted})
+		mockQueue.SetGPUUsage("gpu-job", data)
+
+		resp, err := service.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: "gpu-job"})
+
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(usage, resp.GpuUsage))
+	})
+
+	t.Run("offloaded result", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+
+		store, err := results.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+		service.SetResultStore(store)
+
+		result := []byte("large embedding result")
+		require.NoError(t, store.Put(ctx, "offloaded-job", result))
+		mockQueue.Enqueue(&queue.Job{ID: "offloaded-job", Type: queue.JobTypeEmbeddings})
+		mockQueue.CompleteJobWithRef("offloaded-job", "offloaded-job", int64(len(result)))
+
+		resp, err := service.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: "offloaded-job"})
+
+		require.NoError(t, err)
+		assert.Equal(t, result, resp.Result)
+		assert.Equal(t, int64(len(result)), resp.ResultSize)
+	})
+
+	t.Run("offloaded result missing from store", func(t *testing.T) {
+		mockQueue := queue.NewJobQueue()
+		service := NewService(&MockRegistry{}, mockQueue, &MockScheduler{})
+
+		store, err := results.NewDiskStore(t.TempDir())
+		require.NoError(t, err)
+		service.SetResultStore(store)
+
+		mockQueue.Enqueue(&queue.Job{ID: "lost-job", Type: queue.JobTypeEmbeddings})
+		mockQueue.CompleteJobWithRef("lost-job", "lost-job", 10)
+
+		resp, err := service.GetJobStatus(ctx, &pb.GetJobStatusRequest{JobId: "lost-job"})
+
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+
 	t.Run("empty job ID", func(t *testing.T) {
 		mockRegistry := &MockRegistry{}
 		mockQueue := queue.NewJobQueue()
@@ -619,4 +1291,281 @@ func TestService_GetJobStatus(t *testing.T) {
 			})
 		}
 	})
-}
\ No newline at end of file
+}
+// fakeResultStream collects the chunks GetJobResult sends
+type fakeResultStream struct {
+	grpc.ServerStream
+	chunks []*pb.GetJobResultResponse
+}
+
+func (s *fakeResultStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeResultStream) Send(resp *pb.GetJobResultResponse) error {
+	s.chunks = append(s.chunks, proto.Clone(resp).(*pb.GetJobResultResponse))
+	return nil
+}
+
+// data joins the chunks' data
+func (s *fakeResultStream) data() []byte {
+	var data []byte
+	for _, chunk := range s.chunks {
+		data = append(data, chunk.Data...)
+	}
+	return data
+}
+
+func TestService_GetJobResult(t *testing.T) {
+	ctx := context.Background()
+	jobQueue := queue.NewJobQueue()
+	service := NewService(&MockRegistry{}, jobQueue, &MockScheduler{})
+	store, err := results.NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+	service.SetResultStore(store)
+
+	result := []byte(strings.Repeat("embedding", ResultChunkSize/4))
+	require.NoError(t, store.Put(ctx, "offloaded", result))
+	jobQueue.Enqueue(&queue.Job{ID: "offloaded", Type: queue.JobTypeEmbeddings})
+	jobQueue.CompleteJobWithRef("offloaded", "offloaded", int64(len(result)))
+
+	jobQueue.Enqueue(&queue.Job{ID: "empty", Type: queue.JobTypeChatCompletion})
+	jobQueue.CompleteJob("empty", nil)
+	jobQueue.Enqueue(&queue.Job{ID: "pending", Type: queue.JobTypeChatCompletion})
+
+	t.Run("whole result", func(t *testing.T) {
+		stream := &fakeResultStream{}
+		require.NoError(t, service.GetJobResult(&pb.GetJobResultRequest{JobId: "offloaded"}, stream))
+
+		require.Len(t, stream.chunks, 3)
+		assert.Equal(t, result, stream.data())
+		first := stream.chunks[0]
+		assert.Equal(t, int64(len(result)), first.TotalSize)
+		assert.Equal(t, "orchion.v1.EmbeddingResponse", first.MessageType)
+		assert.Equal(t, `application/x-protobuf; messageType="orchion.v1.EmbeddingResponse"`, first.ContentType)
+		assert.Equal(t, int64(ResultChunkSize), stream.chunks[1].Offset)
+		assert.Empty(t, stream.chunks[1].MessageType)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		stream := &fakeResultStream{}
+		require.NoError(t, service.GetJobResult(&pb.GetJobResultRequest{JobId: "offloaded", Offset: 9, Length: 18}, stream))
+		require.Len(t, stream.chunks, 1)
+		assert.Equal(t, "embeddingembedding", string(stream.data()))
+		assert.Equal(t, int64(9), stream.chunks[0].Offset)
+	})
+
+	t.Run("empty result", func(t *testing.T) {
+		stream := &fakeResultStream{}
+		require.NoError(t, service.GetJobResult(&pb.GetJobResultRequest{JobId: "empty"}, stream))
+		require.Len(t, stream.chunks, 1)
+		assert.Empty(t, stream.chunks[0].Data)
+		assert.Equal(t, "orchion.v1.ChatCompletionResponse", stream.chunks[0].MessageType)
+	})
+
+	for name, tc := range map[string]struct {
+		req  *pb.GetJobResultRequest
+		code codes.Code
+	}{
+		"empty job ID":      {&pb.GetJobResultRequest{}, codes.InvalidArgument},
+		"unknown job":       {&pb.GetJobResultRequest{JobId: "missing"}, codes.NotFound},
+		"job not completed": {&pb.GetJobResultRequest{JobId: "pending"}, codes.FailedPrecondition},
+		"offset past end":   {&pb.GetJobResultRequest{JobId: "offloaded", Offset: int64(len(result))}, codes.OutOfRange},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := service.GetJobResult(tc.req, &fakeResultStream{})
+			assert.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestService_SetLogLevel(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(&MockRegistry{}, queue.NewJobQueue(), &MockScheduler{})
+
+	t.Run("no logger", func(t *testing.T) {
+		_, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	logger := logging.NewLogger(logging.Config{Level: logging.InfoLevel, Source: "test"})
+	service.SetLogger(logger)
+
+	t.Run("set level", func(t *testing.T) {
+		resp, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"})
+		require.NoError(t, err)
+		assert.Equal(t, "debug", resp.Level)
+		assert.Equal(t, logging.DebugLevel, logger.GetLevel())
+	})
+
+	t.Run("read level", func(t *testing.T) {
+		resp, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, "debug", resp.Level)
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		_, err := service.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "chatty"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, logging.DebugLevel, logger.GetLevel())
+	})
+}
+
+func TestService_HeartbeatRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	history := node.NewHistory(10)
+
+	service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+	service.SetHistory(history)
+
+	_, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: &pb.Node{
+		Id:           "node-1",
+		Hostname:     "host-1",
+		Capabilities: &pb.Capabilities{GpuVramUsed: "1 GB"},
+	}})
+	require.NoError(t, err)
+	_, err = service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+
+	samples := history.Samples("node-1", time.Hour)
+	require.Len(t, samples, 2)
+	assert.Equal(t, 1024.0, *samples[1].VRAMUsedMB)
+}
+
+func TestService_HeartbeatRecordsNetwork(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1-gpu0", Hostname: "host-2"}))
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1-gpu1", Hostname: "host-2"}))
+	network := scheduler.NewNetworkTracker()
+
+	service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+	service.SetNetworkTracker(network)
+
+	_, err := service.Heartbeat(ctx, &pb.HeartbeatRequest{
+		NodeId:         "node-1",
+		Network:        &pb.NetworkStats{RttMs: 12, UploadMbps: 80},
+		BandwidthProbe: make([]byte, 1024),
+	})
+	require.NoError(t, err)
+	link, ok := network.Link("node-1")
+	require.True(t, ok)
+	assert.Equal(t, 12*time.Millisecond, link.RTT)
+	assert.Equal(t, 80.0, link.UploadMbps)
+
+	// Logical nodes share their agent's link
+	_, err = service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{
+		Heartbeats: []*pb.NodeHeartbeat{{NodeId: "node-1-gpu0"}, {NodeId: "node-1-gpu1"}},
+		Network:    &pb.NetworkStats{RttMs: 3},
+	})
+	require.NoError(t, err)
+	for _, id := range []string{"node-1-gpu0", "node-1-gpu1"} {
+		link, ok := network.Link(id)
+		require.True(t, ok, id)
+		assert.Equal(t, 3*time.Millisecond, link.RTT)
+	}
+}
+
+func TestService_RecordsNodeEvents(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	events := node.NewEventLog(10, time.Hour)
+
+	service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+	service.SetEvents(events)
+
+	n := &pb.Node{Id: "node-1", Hostname: "host-1", AgentAddress: "10.0.0.5:50052", Capabilities: &pb.Capabilities{Cpu: "8 cores"}}
+	_, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: n})
+	require.NoError(t, err)
+
+	// Heartbeats only record that they resumed after being lost
+	_, err = service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+	events.Record("node-1", node.EventHeartbeatLost, "")
+	_, err = service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{Heartbeats: []*pb.NodeHeartbeat{{
+		NodeId:       "node-1",
+		Capabilities: &pb.Capabilities{Cpu: "16 cores"},
+	}}})
+	require.NoError(t, err)
+	_, err = service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+
+	drained := &pb.Node{Id: "node-1", Hostname: "host-1", AgentAddress: "10.0.0.5:50052", Labels: map[string]string{node.DrainingLabel: ""}, Capabilities: &pb.Capabilities{Cpu: "16 cores"}}
+	_, err = service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: drained})
+	require.NoError(t, err)
+
+	var types []string
+	for _, e := range events.Events("node-1") {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{
+		node.EventRegistered,
+		node.EventHeartbeatLost,
+		node.EventHeartbeatRestored,
+		node.EventCapabilitiesChanged,
+		node.EventRegistered,
+		node.EventDrained,
+	}, types)
+	assert.Equal(t, "host-1 at 10.0.0.5:50052", events.Events("node-1")[0].Message)
+	assert.Equal(t, "cpu: 8 cores -> 16 cores", events.Events("node-1")[3].Message)
+}
+
+func TestService_HeartbeatReturnsImagePins(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	pins := images.NewPins()
+	digest := "sha256:" + strings.Repeat("a", 64)
+	require.NoError(t, pins.Set("vllm/vllm-openai", digest))
+
+	service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+	service.SetImagePins(pins)
+
+	_, err := service.RegisterNode(ctx, &pb.RegisterNodeRequest{Node: &pb.Node{Id: "node-1", Hostname: "host-1"}})
+	require.NoError(t, err)
+
+	want := &pb.ImagePin{Image: "vllm/vllm-openai", Digest: digest}
+	resp, err := service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+	require.Len(t, resp.ImagePins, 1)
+	assert.True(t, proto.Equal(want, resp.ImagePins[0]))
+
+	batch, err := service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{Heartbeats: []*pb.NodeHeartbeat{{NodeId: "node-1"}}})
+	require.NoError(t, err)
+	require.Len(t, batch.ImagePins, 1)
+	assert.True(t, proto.Equal(want, batch.ImagePins[0]))
+
+	// Unpinning is seen by agents on their next heartbeat
+	require.NoError(t, pins.Remove("vllm/vllm-openai"))
+	resp, err = service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ImagePins)
+}
+
+func TestService_HeartbeatReturnsGPUSharing(t *testing.T) {
+	ctx := context.Background()
+	registry := node.NewInMemoryRegistry()
+	require.NoError(t, registry.Register(&pb.Node{Id: "node-1", Hostname: "host-1"}))
+
+	models := catalog.New()
+	models.GPUSharing = []*catalog.SharingWindow{{Name: "daytime", Start: "08:00", End: "22:00", InteractiveReserve: 0.5}}
+	service := NewService(registry, queue.NewJobQueue(), &MockScheduler{})
+	service.SetGPUSharing(models)
+
+	service.now = func() time.Time { return time.Date(2024, 6, 3, 12, 0, 0, 0, time.Local) }
+	want := &pb.GpuSharing{InteractiveReserve: 0.5, Window: "daytime"}
+	resp, err := service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(want, resp.Sharing))
+
+	batch, err := service.BatchHeartbeat(ctx, &pb.BatchHeartbeatRequest{Heartbeats: []*pb.NodeHeartbeat{{NodeId: "node-1"}}})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(want, batch.Sharing))
+
+	// Nothing is reserved outside the schedule
+	service.now = func() time.Time { return time.Date(2024, 6, 3, 23, 0, 0, 0, time.Local) }
+	resp, err = service.Heartbeat(ctx, &pb.HeartbeatRequest{NodeId: "node-1"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Sharing)
+}
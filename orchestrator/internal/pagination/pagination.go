@@ -0,0 +1,65 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+)
+
+// MaxPageSize caps the page size clients may request
+const MaxPageSize = 1000
+
+// ErrInvalidToken is returned for page tokens that weren't issued by Page
+var ErrInvalidToken = errors.New("invalid page token")
+
+// Page returns the page of items following token, sorted by key. Tokens hold
+// the key of the last item returned, so items added or removed between calls
+// don't shift later pages. A pageSize of 0 returns every remaining item, which
+// keeps unpaginated callers working. The returned token is empty on the last
+// page.
+func Page[T any](items []T, key func(T) string, pageSize int, token string) ([]T, string, error) {
+	if pageSize < 0 {
+		return nil, "", errors.New("page size must not be negative")
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	after, err := decodeToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sorted := make([]T, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool { return key(sorted[i]) < key(sorted[j]) })
+
+	start := 0
+	if token != "" {
+		start = sort.Search(len(sorted), func(i int) bool { return key(sorted[i]) > after })
+	}
+	remaining := sorted[start:]
+
+	if pageSize == 0 || len(remaining) <= pageSize {
+		return remaining, "", nil
+	}
+	page := remaining[:pageSize]
+	return page, encodeToken(key(page[len(page)-1])), nil
+}
+
+// encodeToken makes a key opaque to clients
+func encodeToken(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeToken recovers the key a token was issued for
+func decodeToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidToken
+	}
+	return string(key), nil
+}
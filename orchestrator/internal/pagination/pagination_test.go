@@ -0,0 +1,67 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func identity(s string) string { return s }
+
+func TestPage(t *testing.T) {
+	items := []string{"d", "b", "e", "a", "c"}
+
+	t.Run("page size 0 returns everything sorted", func(t *testing.T) {
+		page, next, err := Page(items, identity, 0, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, page)
+		assert.Empty(t, next)
+	})
+
+	t.Run("walks all pages", func(t *testing.T) {
+		var pages [][]string
+		token := ""
+		for {
+			page, next, err := Page(items, identity, 2, token)
+			require.NoError(t, err)
+			pages = append(pages, page)
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+	})
+
+	t.Run("exact final page has no token", func(t *testing.T) {
+		page, next, err := Page(items, identity, 5, "")
+		require.NoError(t, err)
+		assert.Len(t, page, 5)
+		assert.Empty(t, next)
+	})
+
+	t.Run("removed item doesn't shift later pages", func(t *testing.T) {
+		_, next, err := Page(items, identity, 2, "")
+		require.NoError(t, err)
+
+		page, _, err := Page([]string{"a", "c", "d", "e"}, identity, 2, next)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c", "d"}, page)
+	})
+
+	t.Run("does not reorder the input", func(t *testing.T) {
+		input := []string{"b", "a"}
+		_, _, err := Page(input, identity, 1, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "a"}, input)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, _, err := Page(items, identity, -1, "")
+		assert.Error(t, err)
+
+		_, _, err = Page(items, identity, 2, "not base64!")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
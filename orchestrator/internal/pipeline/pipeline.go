@@ -0,0 +1,238 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// DefaultWebhookTimeout bounds webhook steps that don't set their own timeout
+const DefaultWebhookTimeout = 30 * time.Second
+
+// maxWebhookResponseBytes bounds the webhook response kept as step output
+const maxWebhookResponseBytes = 1 << 20 // 1 MiB
+
+// placeholderRe matches {{name}} references to variables and step outputs
+var placeholderRe = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// Engine executes the inference steps of a pipeline
+type Engine interface {
+	ChatCompletion(ctx context.Context, req *pb.ChatCompletionRequest) (string, error)
+	Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error)
+}
+
+// Runner executes pipeline jobs step by step, passing each step's output on to
+// the steps after it
+type Runner struct {
+	engine Engine
+	client *http.Client
+}
+
+// NewRunner creates a pipeline runner that sends inference steps to the given engine
+func NewRunner(engine Engine) *Runner {
+	return &Runner{
+		engine: engine,
+		client: &http.Client{},
+	}
+}
+
+// Validate checks a pipeline definition before it is queued
+func Validate(req *pb.PipelineRequest) error {
+	if len(req.Steps) == 0 {
+		return fmt.Errorf("pipeline has no steps")
+	}
+
+	seen := make(map[string]bool)
+	for i, step := range req.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("step %d has no name", i)
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("duplicate step name %q", step.Name)
+		}
+		if _, ok := req.Variables[step.Name]; ok {
+			return fmt.Errorf("step name %q shadows a variable", step.Name)
+		}
+		seen[step.Name] = true
+
+		switch s := step.Step.(type) {
+		case *pb.PipelineStep_Embed:
+			if s.Embed.GetModel() == "" {
+				return fmt.Errorf("step %q: model is required", step.Name)
+			}
+		case *pb.PipelineStep_Chat:
+			if s.Chat.GetModel() == "" {
+				return fmt.Errorf("step %q: model is required", step.Name)
+			}
+		case *pb.PipelineStep_Webhook:
+			if s.Webhook.GetUrl() == "" {
+				return fmt.Errorf("step %q: url is required", step.Name)
+			}
+		default:
+			return fmt.Errorf("step %q has no action", step.Name)
+		}
+	}
+	return nil
+}
+
+// state holds the values steps can reference. Raw values are JSON documents
+// (such as embeddings) that webhooks receive unquoted.
+type state struct {
+	values map[string]string
+	raw    map[string]bool
+}
+
+// Run executes the pipeline. onStep is called after each step completes, so
+// callers can report progress.
+func (r *Runner) Run(ctx context.Context, req *pb.PipelineRequest, onStep func(*pb.PipelineStepResult)) (*pb.PipelineResponse, error) {
+	if err := Validate(req); err != nil {
+		return nil, err
+	}
+
+	st := &state{
+		values: make(map[string]string, len(req.Variables)+len(req.Steps)),
+		raw:    make(map[string]bool),
+	}
+	for k, v := range req.Variables {
+		st.values[k] = v
+	}
+
+	resp := &pb.PipelineResponse{}
+	for _, step := range req.Steps {
+		output, raw, err := r.runStep(ctx, step, st)
+		if err != nil {
+			return nil, fmt.Errorf("step %q failed: %w", step.Name, err)
+		}
+
+		st.values[step.Name] = output
+		st.raw[step.Name] = raw
+
+		result := &pb.PipelineStepResult{Name: step.Name, Output: output}
+		resp.Steps = append(resp.Steps, result)
+		resp.Output = output
+		if onStep != nil {
+			onStep(result)
+		}
+	}
+
+	return resp, nil
+}
+
+// runStep executes a single step and returns its output
+func (r *Runner) runStep(ctx context.Context, step *pb.PipelineStep, st *state) (string, bool, error) {
+	switch s := step.Step.(type) {
+	case *pb.PipelineStep_Embed:
+		embed := proto.Clone(s.Embed).(*pb.EmbeddingRequest)
+		for i, input := range embed.Input {
+			embed.Input[i] = st.expand(input)
+		}
+
+		resp, err := r.engine.Embeddings(ctx, embed)
+		if err != nil {
+			return "", false, err
+		}
+
+		vectors := make([][]float32, len(resp.Data))
+		for i, data := range resp.Data {
+			vectors[i] = data.Embedding
+		}
+		output, err := json.Marshal(vectors)
+		if err != nil {
+			return "", false, err
+		}
+		return string(output), true, nil
+
+	case *pb.PipelineStep_Chat:
+		chat := proto.Clone(s.Chat).(*pb.ChatCompletionRequest)
+		for _, msg := range chat.Messages {
+			msg.Content = st.expand(msg.Content)
+		}
+
+		output, err := r.engine.ChatCompletion(ctx, chat)
+		return output, false, err
+
+	case *pb.PipelineStep_Webhook:
+		output, err := r.callWebhook(ctx, s.Webhook, st)
+		return output, false, err
+	}
+
+	return "", false, fmt.Errorf("step has no action")
+}
+
+// callWebhook POSTs the pipeline state to a webhook and returns the response body
+func (r *Runner) callWebhook(ctx context.Context, hook *pb.WebhookStep, st *state) (string, error) {
+	timeout := DefaultWebhookTimeout
+	if hook.TimeoutMs > 0 {
+		timeout = time.Duration(hook.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"variables": st.jsonValues(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal webhook request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, st.expand(hook.Url), bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range hook.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	return string(data), nil
+}
+
+// expand replaces {{name}} placeholders with the referenced values.
+// Unknown placeholders are left untouched.
+func (st *state) expand(s string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(match string) string {
+		name := placeholderRe.FindStringSubmatch(match)[1]
+		if v, ok := st.values[name]; ok {
+			return v
+		}
+		return match
+	})
+}
+
+// jsonValues returns the state as JSON values for webhook requests
+func (st *state) jsonValues() map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage, len(st.values))
+	for k, v := range st.values {
+		if st.raw[k] {
+			values[k] = json.RawMessage(v)
+			continue
+		}
+		quoted, _ := json.Marshal(v)
+		values[k] = quoted
+	}
+	return values
+}
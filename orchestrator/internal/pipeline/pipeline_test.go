@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// MockEngine answers inference steps with canned results and records the requests
+type MockEngine struct {
+	chats   []*pb.ChatCompletionRequest
+	embeds  []*pb.EmbeddingRequest
+	chatErr error
+}
+
+func (e *MockEngine) ChatCompletion(ctx context.Context, req *pb.ChatCompletionRequest) (string, error) {
+	e.chats = append(e.chats, req)
+	if e.chatErr != nil {
+		return "", e.chatErr
+	}
+	return "Paris", nil
+}
+
+func (e *MockEngine) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	e.embeds = append(e.embeds, req)
+	return &pb.EmbeddingResponse{
+		Data: []*pb.Embedding{{Embedding: []float32{0.5, 1}}},
+	}, nil
+}
+
+func ragPipeline(url string) *pb.PipelineRequest {
+	return &pb.PipelineRequest{
+		Variables: map[string]string{"query": "What is the capital of France?"},
+		Steps: []*pb.PipelineStep{
+			{Name: "embed", Step: &pb.PipelineStep_Embed{Embed: &pb.EmbeddingRequest{
+				Model: "nomic-embed-text",
+				Input: []string{"{{query}}"},
+			}}},
+			{Name: "retrieve", Step: &pb.PipelineStep_Webhook{Webhook: &pb.WebhookStep{
+				Url:     url,
+				Headers: map[string]string{"Authorization": "Bearer secret"},
+			}}},
+			{Name: "answer", Step: &pb.PipelineStep_Chat{Chat: &pb.ChatCompletionRequest{
+				Model: "llama3",
+				Messages: []*pb.ChatMessage{
+					{Role: "system", Content: "Context: {{retrieve}}"},
+					{Role: "user", Content: "{{ query }}"},
+				},
+			}}},
+		},
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	var webhookBody map[string]map[string]json.RawMessage
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&webhookBody)
+		w.Write([]byte("France's capital is Paris."))
+	}))
+	defer server.Close()
+
+	engine := &MockEngine{}
+	var progress []string
+	req := ragPipeline(server.URL)
+
+	resp, err := NewRunner(engine).Run(context.Background(), req, func(step *pb.PipelineStepResult) {
+		progress = append(progress, step.Name)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"embed", "retrieve", "answer"}, progress)
+	assert.Equal(t, "Paris", resp.Output)
+	require.Len(t, resp.Steps, 3)
+	assert.Equal(t, "[[0.5,1]]", resp.Steps[0].Output)
+
+	// Variables and step outputs are substituted into later steps
+	assert.Equal(t, []string{"What is the capital of France?"}, engine.embeds[0].Input)
+	assert.Equal(t, "Context: France's capital is Paris.", engine.chats[0].Messages[0].Content)
+	assert.Equal(t, "What is the capital of France?", engine.chats[0].Messages[1].Content)
+
+	// The original request is left untouched
+	assert.Equal(t, "{{query}}", req.Steps[0].GetEmbed().Input[0])
+
+	// The webhook receives embeddings as JSON arrays and text as strings
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.JSONEq(t, `[[0.5,1]]`, string(webhookBody["variables"]["embed"]))
+	assert.JSONEq(t, `"What is the capital of France?"`, string(webhookBody["variables"]["query"]))
+}
+
+func TestRunner_RunFailures(t *testing.T) {
+	t.Run("webhook error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "index not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		engine := &MockEngine{}
+		_, err := NewRunner(engine).Run(context.Background(), ragPipeline(server.URL), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `step "retrieve" failed`)
+		assert.Contains(t, err.Error(), "index not found")
+		assert.Empty(t, engine.chats)
+	})
+
+	t.Run("engine error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		engine := &MockEngine{chatErr: errors.New("no nodes available")}
+		_, err := NewRunner(engine).Run(context.Background(), ragPipeline(server.URL), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `step "answer" failed: no nodes available`)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	chat := &pb.PipelineStep_Chat{Chat: &pb.ChatCompletionRequest{Model: "llama3"}}
+
+	tests := []struct {
+		name string
+		req  *pb.PipelineRequest
+		err  string
+	}{
+		{"valid", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Name: "a", Step: chat}}}, ""},
+		{"no steps", &pb.PipelineRequest{}, "no steps"},
+		{"unnamed step", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Step: chat}}}, "has no name"},
+		{"duplicate name", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Name: "a", Step: chat}, {Name: "a", Step: chat}}}, "duplicate step name"},
+		{"shadowed variable", &pb.PipelineRequest{
+			Variables: map[string]string{"a": "x"},
+			Steps:     []*pb.PipelineStep{{Name: "a", Step: chat}},
+		}, "shadows a variable"},
+		{"missing action", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Name: "a"}}}, "has no action"},
+		{"missing model", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Name: "a", Step: &pb.PipelineStep_Embed{Embed: &pb.EmbeddingRequest{}}}}}, "model is required"},
+		{"missing url", &pb.PipelineRequest{Steps: []*pb.PipelineStep{{Name: "a", Step: &pb.PipelineStep_Webhook{Webhook: &pb.WebhookStep{}}}}}, "url is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.req)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package preflight runs the orchestrator's startup checks: configuration,
+// ports, writable paths and certificates are all checked before anything
+// starts, and every problem is reported at once, instead of the orchestrator
+// starting halfway and failing on the first problem or the first request.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Checks is a list of startup checks
+type Checks struct {
+	checks []check
+}
+
+// check is a named startup check
+type check struct {
+	name string
+	run  func() error
+}
+
+// Add adds a check; run returns nil if the check passes
+func (c *Checks) Add(name string, run func() error) {
+	c.checks = append(c.checks, check{name: name, run: run})
+}
+
+// Run runs every check, returning an *Error listing those that failed
+func (c *Checks) Run() error {
+	var failures []Failure
+	for _, check := range c.checks {
+		if err := check.run(); err != nil {
+			failures = append(failures, Failure{Check: check.name, Err: err})
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &Error{Failures: failures}
+}
+
+// Failure is a failed check
+type Failure struct {
+	Check string
+	Err   error
+}
+
+// Error lists the checks that failed
+type Error struct {
+	Failures []Failure
+}
+
+func (e *Error) Error() string {
+	problems := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		problems[i] = f.Check + ": " + f.Err.Error()
+	}
+	noun := "checks"
+	if len(e.Failures) == 1 {
+		noun = "check"
+	}
+	return fmt.Sprintf("%d preflight %s failed: %s", len(e.Failures), noun, strings.Join(problems, "; "))
+}
+
+// Unwrap returns the errors of the failed checks
+func (e *Error) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// Bindable checks that addr can be listened on
+func Bindable(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return lis.Close()
+}
+
+// DistinctPorts checks that no two of addrs, given by name, listen on the
+// same port of overlapping interfaces. Listening on each in turn can't catch
+// that, since each check's listener is closed before the next.
+func DistinctPorts(addrs map[string]string) error {
+	type listener struct{ name, host, port string }
+	var seen []listener
+	var errs []error
+	for _, name := range sortedKeys(addrs) {
+		host, port, err := net.SplitHostPort(addrs[name])
+		if err != nil {
+			continue // Bindable reports it
+		}
+		for _, other := range seen {
+			if other.port == port && overlaps(other.host, host) {
+				errs = append(errs, fmt.Errorf("%s and %s both listen on port %s", other.name, name, port))
+			}
+		}
+		seen = append(seen, listener{name: name, host: host, port: port})
+	}
+	return errors.Join(errs...)
+}
+
+// overlaps reports whether listeners on hosts a and b conflict: the same
+// host, or either on every interface
+func overlaps(a, b string) bool {
+	unspecified := func(host string) bool {
+		ip := net.ParseIP(host)
+		return host == "" || (ip != nil && ip.IsUnspecified())
+	}
+	return a == b || unspecified(a) || unspecified(b)
+}
+
+// WritableDir checks that files can be created in dir, creating it if needed
+func WritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// WritableFile checks that path can be appended to, creating it if needed,
+// as the orchestrator does with its log files
+func WritableFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// InRange checks that a numeric setting is within [min, max]
+func InRange(value, min, max float64) error {
+	if value < min || value > max {
+		return fmt.Errorf("%g is outside [%g, %g]", value, min, max)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, for stable reports
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
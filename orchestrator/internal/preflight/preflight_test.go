@@ -0,0 +1,94 @@
+package preflight
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecks_ReportsEveryFailure(t *testing.T) {
+	errBad := errors.New("bad value")
+	checks := &Checks{}
+	checks.Add("-ok", func() error { return nil })
+	checks.Add("-first", func() error { return errBad })
+	checks.Add("-second", func() error { return errors.New("missing file") })
+
+	err := checks.Run()
+	require.Error(t, err)
+
+	var failed *Error
+	require.True(t, errors.As(err, &failed))
+	require.Len(t, failed.Failures, 2)
+	assert.Equal(t, "-first", failed.Failures[0].Check)
+	assert.Equal(t, "-second", failed.Failures[1].Check)
+	assert.ErrorIs(t, err, errBad)
+	assert.Equal(t, "2 preflight checks failed: -first: bad value; -second: missing file", err.Error())
+}
+
+func TestChecks_Pass(t *testing.T) {
+	checks := &Checks{}
+	checks.Add("-ok", func() error { return nil })
+	assert.NoError(t, checks.Run())
+	assert.NoError(t, (&Checks{}).Run())
+}
+
+func TestBindable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	assert.Error(t, Bindable(lis.Addr().String()))
+	assert.NoError(t, Bindable("127.0.0.1:0"))
+	assert.Error(t, Bindable(":99999"))
+}
+
+func TestDistinctPorts(t *testing.T) {
+	assert.NoError(t, DistinctPorts(map[string]string{
+		"-port":       ":50051",
+		"-http-port":  ":8080",
+		"-admin-addr": "127.0.0.1:6060",
+	}))
+	// Different interfaces may share a port
+	assert.NoError(t, DistinctPorts(map[string]string{
+		"-http-port":  "192.168.1.10:8080",
+		"-admin-addr": "127.0.0.1:8080",
+	}))
+
+	err := DistinctPorts(map[string]string{
+		"-http-port":   ":8080",
+		"-admin-addr":  "127.0.0.1:8080",
+		"-port":        "0.0.0.0:50051",
+		"-tunnel-port": ":50051",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "-admin-addr and -http-port both listen on port 8080")
+	assert.Contains(t, err.Error(), "-port and -tunnel-port both listen on port 50051")
+}
+
+func TestWritablePaths(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, WritableDir(filepath.Join(dir, "results")))
+	assert.DirExists(t, filepath.Join(dir, "results"))
+	entries, err := os.ReadDir(filepath.Join(dir, "results"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the probe file is removed")
+
+	assert.NoError(t, WritableFile(filepath.Join(dir, "audit.log")))
+	assert.FileExists(t, filepath.Join(dir, "audit.log"))
+
+	assert.Error(t, WritableFile(filepath.Join(dir, "missing", "audit.log")))
+	assert.Error(t, WritableDir(filepath.Join(dir, "audit.log")))
+}
+
+func TestInRange(t *testing.T) {
+	assert.NoError(t, InRange(0.5, 0, 1))
+	assert.NoError(t, InRange(1, 0, 1))
+	assert.EqualError(t, InRange(1.5, 0, 1), "1.5 is outside [0, 1]")
+	assert.Error(t, InRange(-0.1, 0, 1))
+}
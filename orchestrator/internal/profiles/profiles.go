@@ -0,0 +1,175 @@
+// Package profiles holds server-side node config profiles. Agents pick up the
+// settings of the profile matching them when they register, so executor
+// routing, limits and labels can change fleet-wide from one file instead of
+// on every machine.
+package profiles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// engines are the engines node agents run
+var engines = map[string]bool{"ollama": true, "vllm": true, "piper": true, "coqui": true}
+
+// Profiles is an ordered list of node config profiles
+type Profiles struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// Profile is a named set of settings applied to the nodes it matches
+type Profile struct {
+	Name string `json:"name"`
+
+	// Nodes are node ID or hostname patterns in path.Match syntax, e.g.
+	// "gpu-*". A profile without patterns matches any node.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// MatchLabels restricts the profile to nodes registering with all of
+	// these labels
+	MatchLabels map[string]string `json:"match_labels,omitempty"`
+
+	// Labels are merged into the node's labels, replacing those with the
+	// same key
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// SupportedModels replaces the model patterns the node serves
+	SupportedModels []string `json:"supported_models,omitempty"`
+
+	// EngineRoutes send models to an engine, checked in order before the
+	// agent's default choice, e.g. {"model": "llama3*", "engine": "vllm"}
+	EngineRoutes []EngineRoute `json:"engine_routes,omitempty"`
+
+	// MaxConcurrentRequests replaces the agent's -max-concurrent-requests
+	MaxConcurrentRequests int32 `json:"max_concurrent_requests,omitempty"`
+}
+
+// EngineRoute sends the models matching a pattern to an engine
+type EngineRoute struct {
+	Model  string `json:"model"`
+	Engine string `json:"engine"`
+}
+
+// LoadFile reads profiles from a JSON file
+func LoadFile(filename string) (*Profiles, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node profiles: %w", err)
+	}
+
+	p := &Profiles{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse node profiles %s: %w", filename, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid node profiles %s: %w", filename, err)
+	}
+
+	return p, nil
+}
+
+// Validate checks the profiles for invalid values
+func (p *Profiles) Validate() error {
+	names := make(map[string]bool, len(p.Profiles))
+	for i, profile := range p.Profiles {
+		if profile == nil || profile.Name == "" {
+			return fmt.Errorf("profile %d has no name", i)
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("profile %q is defined twice", profile.Name)
+		}
+		names[profile.Name] = true
+
+		patterns := append(append([]string{}, profile.Nodes...), profile.SupportedModels...)
+		for _, route := range profile.EngineRoutes {
+			if !engines[route.Engine] {
+				return fmt.Errorf("profile %q: unknown engine %q", profile.Name, route.Engine)
+			}
+			patterns = append(patterns, route.Model)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("profile %q: invalid pattern %q", profile.Name, pattern)
+			}
+		}
+
+		for key := range profile.Labels {
+			if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "=,!") {
+				return fmt.Errorf("profile %q: label key %q must be non-empty and not contain '=', ',' or '!'", profile.Name, key)
+			}
+		}
+		if profile.MaxConcurrentRequests < 0 {
+			return fmt.Errorf("profile %q: max_concurrent_requests must not be negative", profile.Name)
+		}
+	}
+	return nil
+}
+
+// Match returns the first profile matching a node, or nil if none does
+func (p *Profiles) Match(n *pb.Node) *Profile {
+	if p == nil {
+		return nil
+	}
+	for _, profile := range p.Profiles {
+		if profile.matches(n) {
+			return profile
+		}
+	}
+	return nil
+}
+
+// matches reports whether the profile applies to a node
+func (p *Profile) matches(n *pb.Node) bool {
+	for key, value := range p.MatchLabels {
+		if v, ok := n.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	if len(p.Nodes) == 0 {
+		return true
+	}
+	for _, pattern := range p.Nodes {
+		if ok, _ := path.Match(pattern, n.Id); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, n.Hostname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply merges the profile's labels and supported models into a registering
+// node and returns the config the node's agent applies
+func (p *Profile) Apply(n *pb.Node) *pb.NodeConfig {
+	if len(p.Labels) > 0 {
+		labels := make(map[string]string, len(n.Labels)+len(p.Labels))
+		for key, value := range n.Labels {
+			labels[key] = value
+		}
+		for key, value := range p.Labels {
+			labels[key] = value
+		}
+		n.Labels = labels
+	}
+	if len(p.SupportedModels) > 0 {
+		n.SupportedModels = p.SupportedModels
+	}
+
+	config := &pb.NodeConfig{
+		Profile:               p.Name,
+		Labels:                p.Labels,
+		SupportedModels:       p.SupportedModels,
+		MaxConcurrentRequests: p.MaxConcurrentRequests,
+	}
+	for _, route := range p.EngineRoutes {
+		config.EngineRoutes = append(config.EngineRoutes, &pb.EngineRoute{Model: route.Model, Engine: route.Engine})
+	}
+	return config
+}
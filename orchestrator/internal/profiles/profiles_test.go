@@ -0,0 +1,99 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func writeProfiles(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("valid profiles", func(t *testing.T) {
+		p, err := LoadFile(writeProfiles(t, `{"profiles": [
+			{"name": "gpu", "nodes": ["gpu-*"], "labels": {"tier": "gpu"},
+			 "engine_routes": [{"model": "llama3*", "engine": "vllm"}], "max_concurrent_requests": 4}
+		]}`))
+		require.NoError(t, err)
+		require.Len(t, p.Profiles, 1)
+		assert.Equal(t, "gpu", p.Profiles[0].Name)
+		assert.Equal(t, []EngineRoute{{Model: "llama3*", Engine: "vllm"}}, p.Profiles[0].EngineRoutes)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+
+	for name, content := range map[string]string{
+		"invalid JSON":     `{"profiles": [`,
+		"no name":          `{"profiles": [{"nodes": ["a"]}]}`,
+		"duplicate name":   `{"profiles": [{"name": "a"}, {"name": "a"}]}`,
+		"unknown engine":   `{"profiles": [{"name": "a", "engine_routes": [{"model": "x", "engine": "tgi"}]}]}`,
+		"invalid pattern":  `{"profiles": [{"name": "a", "nodes": ["gpu-["]}]}`,
+		"invalid label":    `{"profiles": [{"name": "a", "labels": {"a=b": "c"}}]}`,
+		"negative limit":   `{"profiles": [{"name": "a", "max_concurrent_requests": -1}]}`,
+		"empty model rule": `{"profiles": [{"name": "a", "supported_models": [""]}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFile(writeProfiles(t, content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProfiles_Match(t *testing.T) {
+	p := &Profiles{Profiles: []*Profile{
+		{Name: "spot", MatchLabels: map[string]string{"tier": "spot"}},
+		{Name: "gpu", Nodes: []string{"gpu-*"}},
+		{Name: "default"},
+	}}
+
+	assert.Equal(t, "spot", p.Match(&pb.Node{Id: "gpu-1", Labels: map[string]string{"tier": "spot"}}).Name)
+	assert.Equal(t, "gpu", p.Match(&pb.Node{Id: "gpu-1"}).Name)
+	assert.Equal(t, "gpu", p.Match(&pb.Node{Id: "a1b2", Hostname: "gpu-box"}).Name)
+	assert.Equal(t, "default", p.Match(&pb.Node{Id: "laptop"}).Name)
+
+	assert.Nil(t, (&Profiles{}).Match(&pb.Node{Id: "laptop"}))
+	var none *Profiles
+	assert.Nil(t, none.Match(&pb.Node{Id: "laptop"}))
+}
+
+func TestProfile_Apply(t *testing.T) {
+	profile := &Profile{
+		Name:                  "gpu",
+		Labels:                map[string]string{"tier": "gpu", "zone": "eu-west"},
+		SupportedModels:       []string{"llama3*"},
+		EngineRoutes:          []EngineRoute{{Model: "llama3*", Engine: "vllm"}},
+		MaxConcurrentRequests: 4,
+	}
+	agentLabels := map[string]string{"tier": "spot", "rack": "r1"}
+	n := &pb.Node{Id: "gpu-1", Labels: agentLabels, SupportedModels: []string{"*"}}
+
+	config := profile.Apply(n)
+
+	assert.Equal(t, map[string]string{"tier": "gpu", "zone": "eu-west", "rack": "r1"}, n.Labels)
+	assert.Equal(t, []string{"llama3*"}, n.SupportedModels)
+	// The agent's labels aren't modified in place
+	assert.Equal(t, "spot", agentLabels["tier"])
+
+	assert.Equal(t, "gpu", config.Profile)
+	assert.Equal(t, int32(4), config.MaxConcurrentRequests)
+	require.Len(t, config.EngineRoutes, 1)
+	assert.Equal(t, "vllm", config.EngineRoutes[0].Engine)
+
+	// Profiles without labels or models leave the node's own
+	n = &pb.Node{Id: "gpu-2", Labels: agentLabels, SupportedModels: []string{"*"}}
+	(&Profile{Name: "limits", MaxConcurrentRequests: 2}).Apply(n)
+	assert.Equal(t, agentLabels, n.Labels)
+	assert.Equal(t, []string{"*"}, n.SupportedModels)
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// MaxIDLength bounds client-supplied job and group IDs
+const MaxIDLength = 128
+
+// ValidateID checks a client-supplied job or group ID: 1 to MaxIDLength
+// letters, digits, '.', '_', ':' or '-'. Anything else, e.g. newlines or
+// control characters, could forge log lines or be abused as map keys.
+func ValidateID(id string) error {
+	if id == "" {
+		return fmt.Errorf("ID is empty")
+	}
+	if len(id) > MaxIDLength {
+		return fmt.Errorf("ID is longer than %d characters", MaxIDLength)
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == ':', c == '-':
+		default:
+			return fmt.Errorf("ID contains %q; only letters, digits, '.', '_', ':' and '-' are allowed", c)
+		}
+	}
+	return nil
+}
+
+// NewID generates a random (version 4) UUID for a job submitted without an ID
+func NewID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateID(t *testing.T) {
+	for _, id := range []string{"job-1", "batch_2024.01:item-7", strings.Repeat("a", MaxIDLength)} {
+		assert.NoError(t, ValidateID(id), id)
+	}
+	for _, id := range []string{"", "job 1", "job\nlevel=error", "job/1", "jöb", strings.Repeat("a", MaxIDLength+1)} {
+		assert.Error(t, ValidateID(id), id)
+	}
+}
+
+func TestNewID(t *testing.T) {
+	id, err := NewID()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.NoError(t, ValidateID(id))
+
+	other, err := NewID()
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+}
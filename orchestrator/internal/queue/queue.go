@@ -1,8 +1,15 @@
 package queue
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/pagination"
 )
 
 // JobStatus represents the status of a job
@@ -34,6 +41,16 @@ func (s JobStatus) String() string {
 	}
 }
 
+// ParseJobStatus parses a status name as returned by JobStatus.String
+func ParseJobStatus(name string) (JobStatus, error) {
+	for s := JobPending; s <= JobFailed; s++ {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown job status %q", name)
+}
+
 // JobType represents the type of job
 type JobType int
 
@@ -41,93 +58,318 @@ const (
 	JobTypeUnspecified JobType = iota
 	JobTypeChatCompletion
 	JobTypeEmbeddings
+	JobTypePipeline
+	JobTypeSpeech
 )
 
+// String returns the string representation of JobType
+func (t JobType) String() string {
+	switch t {
+	case JobTypeChatCompletion:
+		return "chat_completion"
+	case JobTypeEmbeddings:
+		return "embeddings"
+	case JobTypePipeline:
+		return "pipeline"
+	case JobTypeSpeech:
+		return "speech"
+	default:
+		return "unspecified"
+	}
+}
+
+// throughputWindow is the number of recent job completions used to estimate wait times
+const throughputWindow = 32
+
 // Job represents a job in the queue
 type Job struct {
 	ID           string
 	Type         JobType
-	Payload      []byte // Serialized request (ChatCompletionRequest or EmbeddingRequest)
+	Payload      []byte // Serialized request (ChatCompletionRequest, EmbeddingRequest or PipelineRequest)
+	ContentType  string // Payload encoding, "application/x-protobuf" or "application/json"; protobuf if empty
 	Status       JobStatus
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	AssignedNode string
-	Result       []byte // Serialized response when completed
-	ErrorMessage string // Error message if failed
+	Result       []byte    // Serialized response when completed
+	ResultRef    string    // Result store key when the result was offloaded instead of kept in Result
+	ResultSize   int64     // Size of the result in bytes, whether held in memory or offloaded
+	ErrorMessage string    // Error message if failed
+	GPUUsage     []byte    // Serialized GpuUsage sampled by the node agent around the request
+	User         string    // End-user identifier from the request (OpenAI "user"), if any
+	Model        string    // Model the request names, for scheduling (empty for pipelines)
+	Attempts     []Attempt // Nodes the job was run on, oldest first; read with JobQueue.Attempts
+	Group        string    // Group the job was submitted in, if any
+	DependsOn    []string  // Jobs that must complete before the job runs
+
+	// Locality are the node labels marking where the job's data is; nodes
+	// carrying them are preferred for running it
+	Locality map[string]string
+
+	// failedDependency is the dependency whose failure failed the job, so the
+	// job is retried along with it
+	failedDependency string
+}
+
+// MaxAttempts bounds the attempts kept per job; older ones are dropped
+const MaxAttempts = 16
+
+// Attempt is one try at running a job on a node
+type Attempt struct {
+	Node      string
+	StartedAt time.Time
+	EndedAt   time.Time // Zero while running
+	Error     string    // Why the attempt failed; empty if it succeeded or is running
 }
 
 // JobQueue is a concurrency-safe in-memory job queue
 type JobQueue struct {
 	mu    sync.Mutex
-	cond  *sync.Cond
 	jobs  []*Job
 	index map[string]*Job
+
+	// Closed and replaced whenever a job becomes ready, waking every
+	// blocked Dequeue
+	wake chan struct{}
+
+	// Buffered streaming output for running jobs, and recently finished
+	// ones until the stream retention has passed
+	streams         map[string]*streamBuffer
+	maxStreamBytes  int
+	streamRetention time.Duration
+
+	// When a job last finished, and the busy time between recent completions,
+	// used to estimate processing rate
+	lastCompletion time.Time
+	intervals      []time.Duration
+
+	// Pending jobs held until their dependencies complete
+	waiting map[string]*Job
+	// Jobs of each group, in submission order
+	groups map[string][]*Job
 }
 
+// ErrGroupExists is returned when submitting a job group whose ID is taken
+var ErrGroupExists = errors.New("job group already exists")
+
+// ErrJobExists is returned when submitting a job whose ID is taken
+var ErrJobExists = errors.New("job already exists")
+
 // NewJobQueue creates a new job queue
 func NewJobQueue() *JobQueue {
-	jq := &JobQueue{
-		jobs:  make([]*Job, 0),
-		index: make(map[string]*Job),
+	return &JobQueue{
+		jobs:            make([]*Job, 0),
+		index:           make(map[string]*Job),
+		streams:         make(map[string]*streamBuffer),
+		maxStreamBytes:  DefaultMaxStreamBytes,
+		streamRetention: DefaultStreamRetention,
+		waiting:         make(map[string]*Job),
+		groups:          make(map[string][]*Job),
+		wake:            make(chan struct{}),
 	}
-	jq.cond = sync.NewCond(&jq.mu)
-	return jq
 }
 
-// Enqueue adds a job to the queue
-func (q *JobQueue) Enqueue(job *Job) {
+// signalLocked wakes every Dequeue waiting for a job
+func (q *JobQueue) signalLocked() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// Enqueue adds a job to the queue. A job depending on others is held until
+// they complete, and fails once one of them fails. Nothing is added if the
+// job's ID is taken.
+func (q *JobQueue) Enqueue(job *Job) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	job.CreatedAt = time.Now()
-	job.UpdatedAt = time.Now()
-	if job.Status == 0 {
-		job.Status = JobPending
+	if _, taken := q.index[job.ID]; taken {
+		return fmt.Errorf("job %s: %w", job.ID, ErrJobExists)
 	}
-
-	q.jobs = append(q.jobs, job)
-	q.index[job.ID] = job
-	q.cond.Signal()
+	q.enqueueLocked(job)
+	return nil
 }
 
-// Dequeue removes and returns the next job from the queue
-// This blocks until a job is available
-func (q *JobQueue) Dequeue() *Job {
+// EnqueueGroup adds a group of jobs, which may depend on jobs listed before
+// them or already in the queue. Nothing is added if the group exists, a job
+// ID is taken or a dependency is unknown.
+func (q *JobQueue) EnqueueGroup(group string, jobs []*Job) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	for len(q.jobs) == 0 {
-		q.cond.Wait()
+	if _, exists := q.groups[group]; exists {
+		return ErrGroupExists
+	}
+	listed := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if _, taken := q.index[job.ID]; taken || listed[job.ID] {
+			return fmt.Errorf("job %s: %w", job.ID, ErrJobExists)
+		}
+		for _, dep := range job.DependsOn {
+			if _, ok := q.index[dep]; !ok && !listed[dep] {
+				return fmt.Errorf("job %s depends on unknown job %s", job.ID, dep)
+			}
+		}
+		listed[job.ID] = true
 	}
 
-	job := q.jobs[0]
-	q.jobs = q.jobs[1:]
-	return job
+	for _, job := range jobs {
+		job.Group = group
+		q.enqueueLocked(job)
+	}
+	return nil
 }
 
-// DequeueWithTimeout attempts to dequeue a job with a timeout
-// Returns nil if timeout expires before a job is available
-func (q *JobQueue) DequeueWithTimeout(timeout time.Duration) *Job {
-	// Start a timer that will broadcast to wake us up
-	timer := time.AfterFunc(timeout, func() {
-		q.cond.Broadcast()
+// enqueueLocked adds a job, queueing it once its dependencies completed.
+// Callers must hold q.mu.
+func (q *JobQueue) enqueueLocked(job *Job) {
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	q.index[job.ID] = job
+	if job.Group != "" {
+		q.groups[job.Group] = append(q.groups[job.Group], job)
+	}
+	q.scheduleLocked(job)
+}
+
+// scheduleLocked queues a pending job whose dependencies all completed, holds
+// it while some are outstanding and fails it if one failed or doesn't exist.
+// Callers must hold q.mu.
+func (q *JobQueue) scheduleLocked(job *Job) {
+	ready := true
+	for _, id := range job.DependsOn {
+		dep, ok := q.index[id]
+		if !ok || dep.Status == JobFailed {
+			q.failDependentLocked(job, id)
+			return
+		}
+		if dep.Status != JobCompleted {
+			ready = false
+		}
+	}
+	if !ready {
+		q.waiting[job.ID] = job
+		return
+	}
+
+	delete(q.waiting, job.ID)
+	q.jobs = append(q.jobs, job)
+	q.signalLocked()
+}
+
+// failDependentLocked fails a job because its dependency failed, and the jobs
+// depending on it in turn. Callers must hold q.mu.
+func (q *JobQueue) failDependentLocked(job *Job, dependency string) {
+	delete(q.waiting, job.ID)
+	job.Status = JobFailed
+	job.ErrorMessage = fmt.Sprintf("dependency %s failed", dependency)
+	job.failedDependency = dependency
+	job.UpdatedAt = time.Now()
+	q.finishStreamLocked(job.ID)
+	q.releaseDependentsLocked(job.ID)
+}
+
+// releaseDependentsLocked schedules the held jobs depending on a job that
+// finished, oldest first. Callers must hold q.mu.
+func (q *JobQueue) releaseDependentsLocked(id string) {
+	var dependents []*Job
+	for _, job := range q.waiting {
+		for _, dep := range job.DependsOn {
+			if dep == id {
+				dependents = append(dependents, job)
+				break
+			}
+		}
+	}
+	sort.Slice(dependents, func(i, j int) bool {
+		return jobSortKey(dependents[i]) < jobSortKey(dependents[j])
 	})
-	defer timer.Stop()
+	for _, job := range dependents {
+		q.scheduleLocked(job)
+	}
+}
 
+// reviveDependentsLocked holds the jobs that failed because a job failed
+// again, now that the job is retried. Callers must hold q.mu.
+func (q *JobQueue) reviveDependentsLocked(id string) {
+	for _, job := range q.index {
+		if job.Status != JobFailed || job.failedDependency != id {
+			continue
+		}
+		job.Status = JobPending
+		job.ErrorMessage = ""
+		job.failedDependency = ""
+		job.UpdatedAt = time.Now()
+		delete(q.streams, job.ID)
+		q.waiting[job.ID] = job
+		q.reviveDependentsLocked(job.ID)
+	}
+}
+
+// Group returns the jobs of a group in submission order, and false if the
+// group doesn't exist
+func (q *JobQueue) Group(id string) ([]*Job, bool) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	jobs, ok := q.groups[id]
+	return append([]*Job(nil), jobs...), ok
+}
 
-	deadline := time.Now().Add(timeout)
+// GroupStatus aggregates the statuses of a group's jobs: failed once a job
+// failed, completed once all completed, running once any started and pending
+// otherwise
+func GroupStatus(jobs []*Job) JobStatus {
+	completed, started := 0, false
+	for _, job := range jobs {
+		switch job.Status {
+		case JobFailed:
+			return JobFailed
+		case JobCompleted:
+			completed++
+			started = true
+		case JobAssigned, JobRunning:
+			started = true
+		}
+	}
+	switch {
+	case completed == len(jobs):
+		return JobCompleted
+	case started:
+		return JobRunning
+	default:
+		return JobPending
+	}
+}
+
+// Dequeue removes and returns the next job from the queue, blocking until
+// a job is available or ctx is done, in which case it returns ctx.Err()
+func (q *JobQueue) Dequeue(ctx context.Context) (*Job, error) {
+	for {
+		q.mu.Lock()
+		if len(q.jobs) > 0 {
+			job := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			q.mu.Unlock()
+			return job, nil
+		}
+		wake := q.wake
+		q.mu.Unlock()
 
-	for len(q.jobs) == 0 {
-		if time.Now().After(deadline) {
-			return nil
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
 		}
-		q.cond.Wait()
 	}
+}
 
-	job := q.jobs[0]
-	q.jobs = q.jobs[1:]
+// DequeueWithTimeout attempts to dequeue a job with a timeout
+// Returns nil if timeout expires before a job is available
+func (q *JobQueue) DequeueWithTimeout(timeout time.Duration) *Job {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	job, _ := q.Dequeue(ctx)
 	return job
 }
 
@@ -164,7 +406,8 @@ func (q *JobQueue) UpdateStatus(id string, status JobStatus) {
 	}
 }
 
-// UpdateStatusAndNode updates both the status and assigned node of a job
+// UpdateStatusAndNode updates both the status and assigned node of a job,
+// starting a new attempt on the node
 func (q *JobQueue) UpdateStatusAndNode(id string, status JobStatus, nodeID string) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
@@ -172,9 +415,43 @@ func (q *JobQueue) UpdateStatusAndNode(id string, status JobStatus, nodeID strin
 		job.Status = status
 		job.AssignedNode = nodeID
 		job.UpdatedAt = time.Now()
+		endAttemptLocked(job, "interrupted by a new attempt")
+		job.Attempts = append(job.Attempts, Attempt{Node: nodeID, StartedAt: job.UpdatedAt})
+		if len(job.Attempts) > MaxAttempts {
+			job.Attempts = append([]Attempt(nil), job.Attempts[len(job.Attempts)-MaxAttempts:]...)
+		}
+	}
+}
+
+// EndAttempt records why the job's current attempt failed when the job is
+// retried on another node. Completing or failing the job ends the attempt too.
+func (q *JobQueue) EndAttempt(id string, errorMsg string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.index[id]; ok {
+		endAttemptLocked(job, errorMsg)
 	}
 }
 
+// endAttemptLocked ends the job's running attempt, if any
+func endAttemptLocked(job *Job, errorMsg string) {
+	if n := len(job.Attempts); n > 0 && job.Attempts[n-1].EndedAt.IsZero() {
+		job.Attempts[n-1].EndedAt = time.Now()
+		job.Attempts[n-1].Error = errorMsg
+	}
+}
+
+// Attempts returns a copy of the job's attempts, oldest first
+func (q *JobQueue) Attempts(id string) []Attempt {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.index[id]
+	if !ok {
+		return nil
+	}
+	return append([]Attempt(nil), job.Attempts...)
+}
+
 // CompleteJob marks a job as completed with a result
 func (q *JobQueue) CompleteJob(id string, result []byte) {
 	q.mu.Lock()
@@ -182,7 +459,39 @@ func (q *JobQueue) CompleteJob(id string, result []byte) {
 	if job, ok := q.index[id]; ok {
 		job.Status = JobCompleted
 		job.Result = result
+		job.ResultSize = int64(len(result))
+		job.UpdatedAt = time.Now()
+		endAttemptLocked(job, "")
+		q.recordCompletionLocked(job)
+		q.finishStreamLocked(id)
+		q.releaseDependentsLocked(id)
+	}
+}
+
+// CompleteJobWithRef marks a job as completed with a result held in a result
+// store under ref rather than in memory
+func (q *JobQueue) CompleteJobWithRef(id string, ref string, size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.index[id]; ok {
+		job.Status = JobCompleted
+		job.Result = nil
+		job.ResultRef = ref
+		job.ResultSize = size
 		job.UpdatedAt = time.Now()
+		endAttemptLocked(job, "")
+		q.recordCompletionLocked(job)
+		q.finishStreamLocked(id)
+		q.releaseDependentsLocked(id)
+	}
+}
+
+// SetGPUUsage records the GPU usage a node agent reported for a job
+func (q *JobQueue) SetGPUUsage(id string, usage []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job, ok := q.index[id]; ok {
+		job.GPUUsage = usage
 	}
 }
 
@@ -194,9 +503,43 @@ func (q *JobQueue) FailJob(id string, errorMsg string) {
 		job.Status = JobFailed
 		job.ErrorMessage = errorMsg
 		job.UpdatedAt = time.Now()
+		endAttemptLocked(job, errorMsg)
+		q.recordCompletionLocked(job)
+		q.finishStreamLocked(id)
+		q.releaseDependentsLocked(id)
 	}
 }
 
+// Requeue puts a job that failed on nodeID back in the queue, e.g. because
+// the node's agent reported crashing while running it, recording reason as
+// why its attempt there ended. Output streamed by the failed attempt is
+// dropped, and jobs that failed because the job did wait for it again. It
+// returns false, leaving the job alone, if the job doesn't exist or didn't
+// fail on nodeID, e.g. because it was already retried elsewhere.
+func (q *JobQueue) Requeue(id string, nodeID string, reason string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.index[id]
+	if !ok || job.Status != JobFailed || job.AssignedNode != nodeID {
+		return false
+	}
+
+	if n := len(job.Attempts); n > 0 && job.Attempts[n-1].Node == nodeID {
+		job.Attempts[n-1].Error = reason
+	}
+	job.Status = JobPending
+	job.ErrorMessage = ""
+	job.UpdatedAt = time.Now()
+	q.notifyStreamLocked(id)
+	delete(q.streams, id)
+	q.reviveDependentsLocked(id)
+
+	q.jobs = append(q.jobs, job)
+	q.signalLocked()
+	return true
+}
+
 // List returns all jobs in the queue
 func (q *JobQueue) List() []*Job {
 	q.mu.Lock()
@@ -209,6 +552,81 @@ func (q *JobQueue) List() []*Job {
 	return jobs
 }
 
+// ListPage returns a page of jobs, oldest first. See pagination.Page for the
+// meaning of pageSize and token.
+func (q *JobQueue) ListPage(pageSize int, token string) ([]*Job, string, error) {
+	return pagination.Page(q.List(), jobSortKey, pageSize, token)
+}
+
+// SearchFilter selects jobs in Search. Zero fields match every job.
+type SearchFilter struct {
+	Status *JobStatus
+	Node   string    // Assigned node ID
+	User   string    // End-user identifier
+	Since  time.Time // Jobs created at or after this time
+	// Text matches jobs whose error message contains every word of it,
+	// ignoring case
+	Text string
+}
+
+// Matches reports whether a job satisfies the filter
+func (f SearchFilter) Matches(job *Job) bool {
+	if f.Status != nil && job.Status != *f.Status {
+		return false
+	}
+	if f.Node != "" && job.AssignedNode != f.Node {
+		return false
+	}
+	if f.User != "" && job.User != f.User {
+		return false
+	}
+	if !f.Since.IsZero() && job.CreatedAt.Before(f.Since) {
+		return false
+	}
+	message := strings.ToLower(job.ErrorMessage)
+	for _, word := range strings.Fields(strings.ToLower(f.Text)) {
+		if !strings.Contains(message, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Search returns a page of the jobs matching filter, oldest first. See
+// pagination.Page for the meaning of pageSize and token.
+func (q *JobQueue) Search(filter SearchFilter, pageSize int, token string) ([]*Job, string, error) {
+	q.mu.Lock()
+	jobs := make([]*Job, 0)
+	for _, job := range q.index {
+		if filter.Matches(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	q.mu.Unlock()
+
+	return pagination.Page(jobs, jobSortKey, pageSize, token)
+}
+
+// InFlight returns the jobs assigned to or running on a node, oldest first
+func (q *JobQueue) InFlight(nodeID string) []*Job {
+	q.mu.Lock()
+	jobs := make([]*Job, 0)
+	for _, job := range q.index {
+		if job.AssignedNode == nodeID && (job.Status == JobAssigned || job.Status == JobRunning) {
+			jobs = append(jobs, job)
+		}
+	}
+	q.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobSortKey(jobs[i]) < jobSortKey(jobs[j]) })
+	return jobs
+}
+
+// jobSortKey orders jobs by creation time, breaking ties by ID
+func jobSortKey(job *Job) string {
+	return fmt.Sprintf("%020d/%s", job.CreatedAt.UnixNano(), job.ID)
+}
+
 // Count returns the number of jobs in the queue
 func (q *JobQueue) Count() int {
 	q.mu.Lock()
@@ -229,3 +647,57 @@ func (q *JobQueue) CountByStatus(status JobStatus) int {
 	}
 	return count
 }
+
+// Position returns the 1-based position of a job among pending jobs,
+// or 0 if the job is not waiting in the queue
+func (q *JobQueue) Position(id string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.jobs {
+		if job.ID == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// EstimatedWait returns a rough estimate of how long a job at the given queue
+// position will wait, based on how fast jobs recently completed while some
+// were running. Returns 0 if there is not enough history to estimate.
+func (q *JobQueue) EstimatedWait(position int) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if position <= 0 || len(q.intervals) == 0 {
+		return 0
+	}
+
+	var busy time.Duration
+	for _, interval := range q.intervals {
+		busy += interval
+	}
+	perJob := busy / time.Duration(len(q.intervals))
+	return perJob * time.Duration(position)
+}
+
+// recordCompletionLocked records a finished job for throughput estimation:
+// the time since the previous completion, counted from when the job was
+// dispatched if that was later, so idle periods without jobs don't inflate
+// the estimate. Jobs never dispatched only count after another completion.
+// Callers must hold q.mu.
+func (q *JobQueue) recordCompletionLocked(job *Job) {
+	since := q.lastCompletion
+	if n := len(job.Attempts); n > 0 && job.Attempts[n-1].StartedAt.After(since) {
+		since = job.Attempts[n-1].StartedAt
+	}
+	q.lastCompletion = job.UpdatedAt
+	if since.IsZero() {
+		return
+	}
+
+	q.intervals = append(q.intervals, job.UpdatedAt.Sub(since))
+	if len(q.intervals) > throughputWindow {
+		q.intervals = q.intervals[len(q.intervals)-throughputWindow:]
+	}
+}
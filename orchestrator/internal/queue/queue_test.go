@@ -1,12 +1,15 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJobStatus_String(t *testing.T) {
@@ -29,6 +32,24 @@ func TestJobStatus_String(t *testing.T) {
 	}
 }
 
+func TestJobType_String(t *testing.T) {
+	testCases := []struct {
+		jobType  JobType
+		expected string
+	}{
+		{JobTypeChatCompletion, "chat_completion"},
+		{JobTypeEmbeddings, "embeddings"},
+		{JobTypePipeline, "pipeline"},
+		{JobTypeUnspecified, "unspecified"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.jobType.String())
+		})
+	}
+}
+
 func TestNewJobQueue(t *testing.T) {
 	queue := NewJobQueue()
 	assert.NotNil(t, queue)
@@ -36,7 +57,7 @@ func TestNewJobQueue(t *testing.T) {
 	assert.NotNil(t, queue.index)
 	assert.Equal(t, 0, len(queue.jobs))
 	assert.Equal(t, 0, len(queue.index))
-	assert.NotNil(t, queue.cond)
+	assert.NotNil(t, queue.wake)
 }
 
 func TestJobQueue_Enqueue(t *testing.T) {
@@ -105,6 +126,18 @@ func TestJobQueue_Enqueue(t *testing.T) {
 		assert.Equal(t, "multi-2", queue.jobs[1].ID)
 		assert.Equal(t, "multi-3", queue.jobs[2].ID)
 	})
+
+	t.Run("taken ID", func(t *testing.T) {
+		queue := NewJobQueue()
+		first := &Job{ID: "dup", Payload: []byte("first")}
+		require.NoError(t, queue.Enqueue(first))
+
+		err := queue.Enqueue(&Job{ID: "dup", Payload: []byte("second")})
+		assert.ErrorIs(t, err, ErrJobExists)
+		retrieved, _ := queue.Get("dup")
+		assert.Same(t, first, retrieved)
+		assert.Equal(t, 1, queue.Count())
+	})
 }
 
 func TestJobQueue_Dequeue(t *testing.T) {
@@ -118,7 +151,8 @@ func TestJobQueue_Dequeue(t *testing.T) {
 		queue.Enqueue(job2)
 
 		// Dequeue first job
-		dequeued := queue.Dequeue()
+		dequeued, err := queue.Dequeue(context.Background())
+		require.NoError(t, err)
 		assert.NotNil(t, dequeued)
 		assert.Equal(t, "job-1", dequeued.ID)
 		assert.Equal(t, JobTypeChatCompletion, dequeued.Type)
@@ -137,7 +171,8 @@ func TestJobQueue_Dequeue(t *testing.T) {
 		job := &Job{ID: "only-job", Type: JobTypeChatCompletion}
 		queue.Enqueue(job)
 
-		dequeued := queue.Dequeue()
+		dequeued, err := queue.Dequeue(context.Background())
+		require.NoError(t, err)
 		assert.NotNil(t, dequeued)
 		assert.Equal(t, "only-job", dequeued.ID)
 
@@ -145,6 +180,52 @@ func TestJobQueue_Dequeue(t *testing.T) {
 		assert.Equal(t, 0, len(queue.jobs))
 		assert.Equal(t, 1, len(queue.index)) // Current implementation keeps jobs in index
 	})
+
+	t.Run("dequeue wakes on enqueue", func(t *testing.T) {
+		queue := NewJobQueue()
+
+		done := make(chan *Job)
+		go func() {
+			job, _ := queue.Dequeue(context.Background())
+			done <- job
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		queue.Enqueue(&Job{ID: "late-job", Type: JobTypeChatCompletion})
+
+		select {
+		case job := <-done:
+			require.NotNil(t, job)
+			assert.Equal(t, "late-job", job.ID)
+		case <-time.After(time.Second):
+			t.Fatal("Dequeue should have woken on enqueue")
+		}
+	})
+
+	t.Run("dequeue returns when context is cancelled", func(t *testing.T) {
+		queue := NewJobQueue()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			_, err := queue.Dequeue(ctx)
+			done <- err
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.True(t, errors.Is(err, context.Canceled))
+		case <-time.After(time.Second):
+			t.Fatal("Dequeue should have returned on cancellation")
+		}
+
+		// A job enqueued afterwards is still there for the next caller
+		queue.Enqueue(&Job{ID: "kept"})
+		assert.Equal(t, 1, queue.Count())
+	})
 }
 
 func TestJobQueue_DequeueWithTimeout(t *testing.T) {
@@ -281,6 +362,50 @@ func TestJobQueue_UpdateStatusAndNode(t *testing.T) {
 	assert.True(t, retrieved.UpdatedAt.After(originalTime))
 }
 
+func TestJobQueue_Attempts(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "retried-job", Type: JobTypeEmbeddings})
+	assert.Empty(t, queue.Attempts("retried-job"))
+	assert.Nil(t, queue.Attempts("missing"))
+
+	queue.UpdateStatusAndNode("retried-job", JobRunning, "node-a")
+	attempts := queue.Attempts("retried-job")
+	require.Len(t, attempts, 1)
+	assert.Equal(t, "node-a", attempts[0].Node)
+	assert.True(t, attempts[0].EndedAt.IsZero(), "still running")
+
+	queue.EndAttempt("retried-job", "connection refused")
+	queue.UpdateStatusAndNode("retried-job", JobRunning, "node-b")
+	queue.FailJob("retried-job", "insufficient VRAM")
+
+	attempts = queue.Attempts("retried-job")
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "connection refused", attempts[0].Error)
+	assert.Equal(t, "node-b", attempts[1].Node)
+	assert.Equal(t, "insufficient VRAM", attempts[1].Error)
+	assert.False(t, attempts[1].EndedAt.Before(attempts[1].StartedAt))
+
+	// Completing ends the attempt without an error
+	queue.Enqueue(&Job{ID: "done-job", Type: JobTypeEmbeddings})
+	queue.UpdateStatusAndNode("done-job", JobRunning, "node-c")
+	queue.CompleteJob("done-job", []byte("ok"))
+	attempts = queue.Attempts("done-job")
+	require.Len(t, attempts, 1)
+	assert.False(t, attempts[0].EndedAt.IsZero())
+	assert.Empty(t, attempts[0].Error)
+
+	// Only the most recent attempts are kept
+	queue.Enqueue(&Job{ID: "flapping-job", Type: JobTypeEmbeddings})
+	for i := 0; i < MaxAttempts+4; i++ {
+		queue.UpdateStatusAndNode("flapping-job", JobRunning, fmt.Sprintf("node-%d", i))
+		queue.EndAttempt("flapping-job", "connection refused")
+	}
+	attempts = queue.Attempts("flapping-job")
+	require.Len(t, attempts, MaxAttempts)
+	assert.Equal(t, "node-4", attempts[0].Node)
+	assert.Equal(t, fmt.Sprintf("node-%d", MaxAttempts+3), attempts[MaxAttempts-1].Node)
+}
+
 func TestJobQueue_CompleteJob(t *testing.T) {
 	queue := NewJobQueue()
 
@@ -298,9 +423,36 @@ func TestJobQueue_CompleteJob(t *testing.T) {
 	assert.True(t, exists)
 	assert.Equal(t, JobCompleted, retrieved.Status)
 	assert.Equal(t, result, retrieved.Result)
+	assert.Equal(t, int64(len(result)), retrieved.ResultSize)
 	assert.True(t, retrieved.UpdatedAt.After(originalTime))
 }
 
+func TestJobQueue_CompleteJobWithRef(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "offloaded-job", Type: JobTypeEmbeddings})
+
+	queue.CompleteJobWithRef("offloaded-job", "offloaded-job", 4<<20)
+
+	retrieved, exists := queue.Get("offloaded-job")
+	assert.True(t, exists)
+	assert.Equal(t, JobCompleted, retrieved.Status)
+	assert.Nil(t, retrieved.Result)
+	assert.Equal(t, "offloaded-job", retrieved.ResultRef)
+	assert.Equal(t, int64(4<<20), retrieved.ResultSize)
+}
+
+func TestJobQueue_SetGPUUsage(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "gpu-job", Type: JobTypeEmbeddings})
+
+	queue.SetGPUUsage("gpu-job", []byte("usage"))
+	queue.SetGPUUsage("missing", []byte("usage"))
+
+	retrieved, exists := queue.Get("gpu-job")
+	assert.True(t, exists)
+	assert.Equal(t, []byte("usage"), retrieved.GPUUsage)
+}
+
 func TestJobQueue_FailJob(t *testing.T) {
 	queue := NewJobQueue()
 
@@ -321,6 +473,128 @@ func TestJobQueue_FailJob(t *testing.T) {
 	assert.True(t, retrieved.UpdatedAt.After(originalTime))
 }
 
+func TestJobQueue_Requeue(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "crashed-job", Type: JobTypeChatCompletion})
+	require.NotNil(t, queue.DequeueNonBlocking())
+	queue.UpdateStatusAndNode("crashed-job", JobRunning, "node-a")
+	queue.AppendChunk("crashed-job", []byte("partial"))
+
+	// Only jobs that failed on the node are requeued
+	assert.False(t, queue.Requeue("crashed-job", "node-a", "agent crashed"))
+	queue.FailJob("crashed-job", "stream error")
+	assert.False(t, queue.Requeue("crashed-job", "node-b", "agent crashed"))
+	assert.False(t, queue.Requeue("missing", "node-a", "agent crashed"))
+
+	assert.True(t, queue.Requeue("crashed-job", "node-a", "agent crashed"))
+	job, ok := queue.Get("crashed-job")
+	require.True(t, ok)
+	assert.Equal(t, JobPending, job.Status)
+	assert.Empty(t, job.ErrorMessage)
+	assert.Equal(t, "agent crashed", queue.Attempts("crashed-job")[0].Error)
+
+	snapshot, _ := queue.ChunksSince("crashed-job", 0)
+	assert.Empty(t, snapshot.Chunks)
+	assert.False(t, snapshot.Done)
+	assert.Same(t, job, queue.DequeueNonBlocking())
+
+	// Once requeued, it isn't requeued again
+	assert.False(t, queue.Requeue("crashed-job", "node-a", "agent crashed"))
+}
+
+func TestJobQueue_Dependencies(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "embed", Type: JobTypeEmbeddings})
+	queue.Enqueue(&Job{ID: "summarize", Type: JobTypeChatCompletion, DependsOn: []string{"embed"}})
+	queue.Enqueue(&Job{ID: "report", Type: JobTypeChatCompletion, DependsOn: []string{"embed", "summarize"}})
+
+	// Jobs wait outside the queue for their dependencies
+	assert.Equal(t, 1, queue.Count())
+	assert.Equal(t, 0, queue.Position("summarize"))
+	require.Equal(t, "embed", queue.DequeueNonBlocking().ID)
+	assert.Nil(t, queue.DequeueNonBlocking())
+
+	queue.CompleteJob("embed", []byte("ok"))
+	require.Equal(t, "summarize", queue.DequeueNonBlocking().ID)
+	assert.Nil(t, queue.DequeueNonBlocking())
+
+	queue.CompleteJob("summarize", []byte("ok"))
+	require.Equal(t, "report", queue.DequeueNonBlocking().ID)
+
+	// Jobs depending on completed jobs are queued right away
+	queue.Enqueue(&Job{ID: "late", DependsOn: []string{"embed"}})
+	assert.Equal(t, 1, queue.Position("late"))
+}
+
+func TestJobQueue_DependencyFailure(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "a"})
+	queue.Enqueue(&Job{ID: "b", DependsOn: []string{"a"}})
+	queue.Enqueue(&Job{ID: "c", DependsOn: []string{"b"}})
+	queue.Enqueue(&Job{ID: "orphan", DependsOn: []string{"missing"}})
+	require.Equal(t, "a", queue.DequeueNonBlocking().ID)
+	queue.UpdateStatusAndNode("a", JobRunning, "node-a")
+
+	// Failures cascade to every job depending on the failed one
+	queue.FailJob("a", "CUDA error: out of memory")
+	for _, id := range []string{"b", "c"} {
+		job, _ := queue.Get(id)
+		assert.Equal(t, JobFailed, job.Status, id)
+	}
+	b, _ := queue.Get("b")
+	assert.Equal(t, "dependency a failed", b.ErrorMessage)
+	orphan, _ := queue.Get("orphan")
+	assert.Equal(t, JobFailed, orphan.Status)
+
+	// Retrying the failed job retries its dependents with it
+	require.True(t, queue.Requeue("a", "node-a", "agent crashed"))
+	c, _ := queue.Get("c")
+	assert.Equal(t, JobPending, c.Status)
+	require.Equal(t, "a", queue.DequeueNonBlocking().ID)
+	queue.CompleteJob("a", nil)
+	assert.Equal(t, "b", queue.DequeueNonBlocking().ID)
+}
+
+func TestJobQueue_EnqueueGroup(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "existing"})
+
+	err := queue.EnqueueGroup("etl", []*Job{
+		{ID: "extract", DependsOn: []string{"existing"}},
+		{ID: "load", DependsOn: []string{"extract"}},
+	})
+	require.NoError(t, err)
+	jobs, ok := queue.Group("etl")
+	require.True(t, ok)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "etl", jobs[1].Group)
+
+	assert.ErrorIs(t, queue.EnqueueGroup("etl", nil), ErrGroupExists)
+	// Jobs may only depend on jobs listed before them, so groups can't have cycles
+	assert.Error(t, queue.EnqueueGroup("cycle", []*Job{{ID: "x", DependsOn: []string{"y"}}, {ID: "y", DependsOn: []string{"x"}}}))
+	assert.ErrorIs(t, queue.EnqueueGroup("taken", []*Job{{ID: "load"}}), ErrJobExists)
+	_, ok = queue.Group("cycle")
+	assert.False(t, ok)
+	_, ok = queue.Get("x")
+	assert.False(t, ok)
+}
+
+func TestGroupStatus(t *testing.T) {
+	jobs := func(statuses ...JobStatus) []*Job {
+		out := make([]*Job, len(statuses))
+		for i, s := range statuses {
+			out[i] = &Job{Status: s}
+		}
+		return out
+	}
+
+	assert.Equal(t, JobPending, GroupStatus(jobs(JobPending, JobPending)))
+	assert.Equal(t, JobRunning, GroupStatus(jobs(JobCompleted, JobPending)))
+	assert.Equal(t, JobRunning, GroupStatus(jobs(JobAssigned, JobPending)))
+	assert.Equal(t, JobCompleted, GroupStatus(jobs(JobCompleted, JobCompleted)))
+	assert.Equal(t, JobFailed, GroupStatus(jobs(JobCompleted, JobFailed, JobRunning)))
+}
+
 func TestJobQueue_List(t *testing.T) {
 	queue := NewJobQueue()
 
@@ -352,6 +626,98 @@ func TestJobQueue_List(t *testing.T) {
 	assert.Equal(t, JobTypeEmbeddings, jobMap["list-2"].Type)
 }
 
+func TestJobQueue_ListPage(t *testing.T) {
+	queue := NewJobQueue()
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		queue.Enqueue(&Job{ID: id, Type: JobTypeEmbeddings})
+		time.Sleep(time.Millisecond)
+	}
+
+	page, next, err := queue.ListPage(2, "")
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "job-1", page[0].ID)
+	assert.Equal(t, "job-2", page[1].ID)
+	require.NotEmpty(t, next)
+
+	page, next, err = queue.ListPage(2, next)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "job-3", page[0].ID)
+	assert.Empty(t, next)
+
+	_, _, err = queue.ListPage(2, "%%%")
+	assert.Error(t, err)
+}
+
+func TestParseJobStatus(t *testing.T) {
+	for _, status := range []JobStatus{JobPending, JobAssigned, JobRunning, JobCompleted, JobFailed} {
+		parsed, err := ParseJobStatus(status.String())
+		require.NoError(t, err)
+		assert.Equal(t, status, parsed)
+	}
+
+	_, err := ParseJobStatus("unknown")
+	assert.Error(t, err)
+}
+
+func TestJobQueue_Search(t *testing.T) {
+	queue := NewJobQueue()
+	for _, id := range []string{"job-1", "job-2", "job-3", "job-4"} {
+		queue.Enqueue(&Job{ID: id, Type: JobTypeChatCompletion, User: "alice"})
+		time.Sleep(time.Millisecond)
+	}
+	queue.UpdateStatusAndNode("job-1", JobRunning, "node-1")
+	queue.FailJob("job-1", "CUDA error: out of memory")
+	queue.UpdateStatusAndNode("job-2", JobRunning, "node-2")
+	queue.FailJob("job-2", "model not found")
+	queue.UpdateStatusAndNode("job-3", JobRunning, "node-2")
+	queue.FailJob("job-3", "cuda driver version is insufficient")
+
+	failed := JobFailed
+	ids := func(filter SearchFilter) []string {
+		jobs, _, err := queue.Search(filter, 0, "")
+		require.NoError(t, err)
+		ids := make([]string, len(jobs))
+		for i, job := range jobs {
+			ids[i] = job.ID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{"no filter", SearchFilter{}, []string{"job-1", "job-2", "job-3", "job-4"}},
+		{"status", SearchFilter{Status: &failed}, []string{"job-1", "job-2", "job-3"}},
+		{"text ignores case", SearchFilter{Status: &failed, Text: "CUDA"}, []string{"job-1", "job-3"}},
+		{"every word must match", SearchFilter{Text: "cuda memory"}, []string{"job-1"}},
+		{"node", SearchFilter{Node: "node-2"}, []string{"job-2", "job-3"}},
+		{"user", SearchFilter{User: "bob"}, []string{}},
+		{"since", SearchFilter{Since: time.Now().Add(time.Hour)}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ids(tt.filter))
+		})
+	}
+
+	t.Run("paginated", func(t *testing.T) {
+		page, next, err := queue.Search(SearchFilter{Status: &failed}, 2, "")
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		require.NotEmpty(t, next)
+
+		page, next, err = queue.Search(SearchFilter{Status: &failed}, 2, next)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, "job-3", page[0].ID)
+		assert.Empty(t, next)
+	})
+}
+
 func TestJobQueue_Count(t *testing.T) {
 	queue := NewJobQueue()
 
@@ -363,7 +729,8 @@ func TestJobQueue_Count(t *testing.T) {
 	queue.Enqueue(&Job{ID: "count-2"})
 	assert.Equal(t, 2, queue.Count())
 
-	queue.Dequeue()
+	_, err := queue.Dequeue(context.Background())
+	require.NoError(t, err)
 	assert.Equal(t, 1, queue.Count())
 }
 
@@ -390,6 +757,56 @@ func TestJobQueue_CountByStatus(t *testing.T) {
 	assert.Equal(t, 0, queue.CountByStatus(JobAssigned))
 }
 
+func TestJobQueue_Position(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "job-1", Type: JobTypeChatCompletion})
+	queue.Enqueue(&Job{ID: "job-2", Type: JobTypeChatCompletion})
+
+	assert.Equal(t, 1, queue.Position("job-1"))
+	assert.Equal(t, 2, queue.Position("job-2"))
+	assert.Equal(t, 0, queue.Position("missing"))
+
+	queue.DequeueNonBlocking()
+	assert.Equal(t, 0, queue.Position("job-1"))
+	assert.Equal(t, 1, queue.Position("job-2"))
+}
+
+func TestJobQueue_EstimatedWait(t *testing.T) {
+	queue := NewJobQueue()
+
+	// No history yet
+	assert.Equal(t, time.Duration(0), queue.EstimatedWait(3))
+
+	base := time.Now()
+	finished := func(started, ended time.Time) *Job {
+		job := &Job{UpdatedAt: ended}
+		if !started.IsZero() {
+			job.Attempts = []Attempt{{Node: "node-1", StartedAt: started}}
+		}
+		return job
+	}
+	queue.recordCompletionLocked(finished(base, base.Add(2*time.Second)))
+	queue.recordCompletionLocked(finished(base.Add(time.Second), base.Add(4*time.Second)))
+
+	// Two seconds per job on average
+	assert.Equal(t, 6*time.Second, queue.EstimatedWait(3))
+	assert.Equal(t, time.Duration(0), queue.EstimatedWait(0))
+
+	// An idle hour before the next job was dispatched doesn't count
+	queue.recordCompletionLocked(finished(base.Add(time.Hour), base.Add(time.Hour+2*time.Second)))
+	assert.Equal(t, 6*time.Second, queue.EstimatedWait(3))
+
+	// Jobs failed before being dispatched count from the previous completion
+	queue.recordCompletionLocked(finished(time.Time{}, base.Add(time.Hour+4*time.Second)))
+	assert.Equal(t, 6*time.Second, queue.EstimatedWait(3))
+
+	// History is bounded to the throughput window
+	for i := 0; i < throughputWindow*2; i++ {
+		queue.recordCompletionLocked(finished(time.Time{}, base.Add(2*time.Hour+time.Duration(i)*time.Second)))
+	}
+	assert.Len(t, queue.intervals, throughputWindow)
+}
+
 func TestJobQueue_Concurrency(t *testing.T) {
 	queue := NewJobQueue()
 	const numGoroutines = 10
@@ -489,4 +906,23 @@ func BenchmarkJobQueue_Get(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		queue.Get("bench-job")
 	}
-}
\ No newline at end of file
+}
+func TestJobQueue_InFlight(t *testing.T) {
+	q := NewJobQueue()
+	for _, id := range []string{"job-1", "job-2", "job-3", "job-4"} {
+		q.Enqueue(&Job{ID: id, Type: JobTypeEmbeddings})
+	}
+	for i := 0; i < 3; i++ {
+		q.DequeueNonBlocking()
+	}
+	q.UpdateStatusAndNode("job-1", JobRunning, "node-1")
+	q.UpdateStatusAndNode("job-2", JobAssigned, "node-1")
+	q.UpdateStatusAndNode("job-3", JobRunning, "node-1")
+	q.CompleteJob("job-3", nil)
+
+	jobs := q.InFlight("node-1")
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "job-1", jobs[0].ID)
+	assert.Equal(t, "job-2", jobs[1].ID)
+	assert.Empty(t, q.InFlight("node-2"))
+}
@@ -0,0 +1,153 @@
+package queue
+
+import "time"
+
+// DefaultMaxStreamBytes bounds the streamed output buffered per job
+const DefaultMaxStreamBytes = 1 << 20 // 1 MiB
+
+// DefaultStreamRetention is how long a finished job's streamed output stays
+// buffered for readers still catching up on it
+const DefaultStreamRetention = 30 * time.Second
+
+// streamBuffer holds the most recent chunks produced by a running job so that
+// clients connecting mid-generation can catch up on already-produced output
+type streamBuffer struct {
+	chunks [][]byte
+	first  int // Sequence number of chunks[0]
+	size   int // Total bytes held in chunks
+	notify chan struct{}
+}
+
+// StreamSnapshot is a view of a job's buffered output from a given sequence number
+type StreamSnapshot struct {
+	Chunks    [][]byte        // Chunks available from the requested position
+	First     int             // Sequence number of Chunks[0]
+	Next      int             // Sequence number to request on the next call
+	Truncated bool            // True if older chunks were dropped to bound memory
+	Done      bool            // True once the job has completed or failed
+	Released  bool            // True if the job finished and its buffer was released; its output is in the job's result
+	Wait      <-chan struct{} // Closed when new chunks arrive or the job finishes
+}
+
+// SetMaxStreamBytes sets the per-job limit on buffered stream output
+func (q *JobQueue) SetMaxStreamBytes(max int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxStreamBytes = max
+}
+
+// SetStreamRetention sets how long a finished job's streamed output stays
+// buffered; 0 releases it as soon as the job finishes
+func (q *JobQueue) SetStreamRetention(retention time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.streamRetention = retention
+}
+
+// AppendChunk records a chunk of streamed output for a running job.
+// The oldest chunks are dropped once the buffer exceeds the configured limit.
+func (q *JobQueue) AppendChunk(id string, chunk []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.index[id]; !ok {
+		return
+	}
+
+	buf := q.streamLocked(id)
+	buf.chunks = append(buf.chunks, chunk)
+	buf.size += len(chunk)
+
+	// Always keep the latest chunk, even if it alone exceeds the limit
+	for buf.size > q.maxStreamBytes && len(buf.chunks) > 1 {
+		buf.size -= len(buf.chunks[0])
+		buf.chunks[0] = nil
+		buf.chunks = buf.chunks[1:]
+		buf.first++
+	}
+
+	q.notifyStreamLocked(id)
+}
+
+// ChunksSince returns the buffered chunks of a job starting at sequence number seq.
+// The returned bool is false if the job does not exist.
+func (q *JobQueue) ChunksSince(id string, seq int) (StreamSnapshot, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.index[id]
+	if !ok {
+		return StreamSnapshot{}, false
+	}
+
+	done := job.Status == JobCompleted || job.Status == JobFailed
+	buf, ok := q.streams[id]
+	if !ok && done {
+		return StreamSnapshot{Next: seq, Done: true, Released: true}, true
+	}
+	if !ok {
+		buf = q.streamLocked(id)
+	}
+	end := buf.first + len(buf.chunks)
+
+	snapshot := StreamSnapshot{
+		Next: end,
+		Done: done,
+		Wait: buf.notify,
+	}
+
+	if seq < buf.first {
+		snapshot.Truncated = true
+		seq = buf.first
+	}
+	if seq < end {
+		snapshot.Chunks = append([][]byte(nil), buf.chunks[seq-buf.first:]...)
+		snapshot.First = seq
+	}
+
+	return snapshot, true
+}
+
+// streamLocked returns the stream buffer for a job, creating it if needed.
+// Callers must hold q.mu.
+func (q *JobQueue) streamLocked(id string) *streamBuffer {
+	buf, ok := q.streams[id]
+	if !ok {
+		buf = &streamBuffer{notify: make(chan struct{})}
+		q.streams[id] = buf
+	}
+	return buf
+}
+
+// notifyStreamLocked wakes up all readers waiting on a job's stream.
+// Callers must hold q.mu.
+func (q *JobQueue) notifyStreamLocked(id string) {
+	if buf, ok := q.streams[id]; ok {
+		close(buf.notify)
+		buf.notify = make(chan struct{})
+	}
+}
+
+// finishStreamLocked wakes up all readers of a finished job's stream and
+// releases its buffer once the stream retention has passed. Callers must hold
+// q.mu.
+func (q *JobQueue) finishStreamLocked(id string) {
+	q.notifyStreamLocked(id)
+	buf, ok := q.streams[id]
+	if !ok {
+		return
+	}
+	if q.streamRetention <= 0 {
+		delete(q.streams, id)
+		return
+	}
+
+	time.AfterFunc(q.streamRetention, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		// A requeued job streams into a new buffer
+		if q.streams[id] == buf {
+			delete(q.streams, id)
+		}
+	})
+}
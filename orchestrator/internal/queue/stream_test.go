@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue_AppendChunk(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "job-1", Type: JobTypeChatCompletion})
+
+	queue.AppendChunk("job-1", []byte("a"))
+	queue.AppendChunk("job-1", []byte("b"))
+	queue.AppendChunk("unknown", []byte("ignored"))
+
+	snapshot, ok := queue.ChunksSince("job-1", 0)
+	require.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, snapshot.Chunks)
+	assert.Equal(t, 0, snapshot.First)
+	assert.Equal(t, 2, snapshot.Next)
+	assert.False(t, snapshot.Done)
+	assert.False(t, snapshot.Truncated)
+
+	snapshot, ok = queue.ChunksSince("job-1", 1)
+	require.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("b")}, snapshot.Chunks)
+	assert.Equal(t, 1, snapshot.First)
+
+	snapshot, ok = queue.ChunksSince("job-1", 2)
+	require.True(t, ok)
+	assert.Empty(t, snapshot.Chunks)
+	assert.Equal(t, 2, snapshot.Next)
+
+	_, ok = queue.ChunksSince("unknown", 0)
+	assert.False(t, ok)
+}
+
+func TestJobQueue_AppendChunk_BoundedMemory(t *testing.T) {
+	queue := NewJobQueue()
+	queue.SetMaxStreamBytes(4)
+	queue.Enqueue(&Job{ID: "job-1", Type: JobTypeChatCompletion})
+
+	queue.AppendChunk("job-1", []byte("aa"))
+	queue.AppendChunk("job-1", []byte("bb"))
+	queue.AppendChunk("job-1", []byte("cc"))
+
+	snapshot, ok := queue.ChunksSince("job-1", 0)
+	require.True(t, ok)
+	assert.True(t, snapshot.Truncated)
+	assert.Equal(t, 1, snapshot.First)
+	assert.Equal(t, [][]byte{[]byte("bb"), []byte("cc")}, snapshot.Chunks)
+
+	// A single oversized chunk is still kept
+	queue.AppendChunk("job-1", []byte("dddddd"))
+	snapshot, _ = queue.ChunksSince("job-1", 0)
+	assert.Equal(t, [][]byte{[]byte("dddddd")}, snapshot.Chunks)
+	assert.Equal(t, 3, snapshot.First)
+}
+
+func TestJobQueue_ChunksSince_Wait(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Enqueue(&Job{ID: "job-1", Type: JobTypeChatCompletion})
+
+	snapshot, ok := queue.ChunksSince("job-1", 0)
+	require.True(t, ok)
+
+	go queue.AppendChunk("job-1", []byte("a"))
+
+	select {
+	case <-snapshot.Wait:
+	case <-time.After(time.Second):
+		t.Fatal("expected wait channel to be closed by AppendChunk")
+	}
+
+	snapshot, _ = queue.ChunksSince("job-1", snapshot.Next)
+	go queue.CompleteJob("job-1", nil)
+
+	select {
+	case <-snapshot.Wait:
+	case <-time.After(time.Second):
+		t.Fatal("expected wait channel to be closed by CompleteJob")
+	}
+
+	snapshot, _ = queue.ChunksSince("job-1", 0)
+	assert.True(t, snapshot.Done)
+	assert.Len(t, snapshot.Chunks, 1)
+}
+
+func TestJobQueue_ReleasesFinishedStreams(t *testing.T) {
+	queue := NewJobQueue()
+	queue.SetStreamRetention(0)
+	for _, id := range []string{"completed", "offloaded", "failed"} {
+		queue.Enqueue(&Job{ID: id, Type: JobTypeChatCompletion})
+		queue.AppendChunk(id, []byte("a"))
+	}
+	require.Len(t, queue.streams, 3)
+
+	queue.CompleteJob("completed", []byte("result"))
+	queue.CompleteJobWithRef("offloaded", "offloaded", 6)
+	queue.FailJob("failed", "boom")
+	assert.Empty(t, queue.streams)
+
+	// Late readers are told to read the result instead, without buffering again
+	snapshot, ok := queue.ChunksSince("completed", 0)
+	require.True(t, ok)
+	assert.True(t, snapshot.Done)
+	assert.True(t, snapshot.Released)
+	assert.Empty(t, snapshot.Chunks)
+	assert.Empty(t, queue.streams)
+
+	t.Run("after the retention", func(t *testing.T) {
+		queue.SetStreamRetention(20 * time.Millisecond)
+		queue.Enqueue(&Job{ID: "retained", Type: JobTypeChatCompletion})
+		queue.AppendChunk("retained", []byte("a"))
+		queue.CompleteJob("retained", nil)
+
+		// Readers catching up still get the output until the retention passes
+		snapshot, _ := queue.ChunksSince("retained", 0)
+		assert.Equal(t, [][]byte{[]byte("a")}, snapshot.Chunks)
+		assert.False(t, snapshot.Released)
+
+		require.Eventually(t, func() bool {
+			queue.mu.Lock()
+			defer queue.mu.Unlock()
+			return len(queue.streams) == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
@@ -0,0 +1,245 @@
+// Package replay records gateway traffic and replays it against a gateway to
+// compare outputs and latency, e.g. before and after an engine upgrade or a
+// scheduler change.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxRecordedBody is the largest request or response body that is recorded.
+// Larger exchanges are passed through but not recorded.
+const MaxRecordedBody = 1 << 20 // 1 MiB
+
+// Record is one gateway request and the response it got
+type Record struct {
+	ID        string          `json:"id"`
+	Time      time.Time       `json:"time"`
+	Endpoint  string          `json:"endpoint"`
+	Request   json.RawMessage `json:"request"`
+	Status    int             `json:"status"`
+	Response  string          `json:"response"` // JSON body, or the raw event stream for streamed chats
+	LatencyMs int64           `json:"latency_ms"`
+}
+
+// Recorder captures anonymized gateway requests and responses as JSON lines.
+// Request headers, including API keys, are never recorded.
+type Recorder struct {
+	sampleRate float64
+	random     func() float64
+
+	mu  sync.Mutex
+	out io.WriteCloser
+	seq int
+}
+
+// NewRecorder appends records to the file at path, recording the given
+// fraction of requests (1 records everything)
+func NewRecorder(path string, sampleRate float64) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %w", err)
+	}
+	return newRecorder(f, sampleRate), nil
+}
+
+// newRecorder creates a recorder writing to out
+func newRecorder(out io.WriteCloser, sampleRate float64) *Recorder {
+	return &Recorder{
+		sampleRate: sampleRate,
+		random:     rand.Float64,
+		out:        out,
+	}
+}
+
+// Wrap records the requests next handles
+func (rec *Recorder) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || rec.random() >= rec.sampleRate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxRecordedBody+1))
+		if err != nil {
+			http.Error(w, "Failed to read request", http.StatusBadRequest)
+			return
+		}
+		// Hand the handler the full body, including anything past the limit
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(capture, r)
+		latency := time.Since(start)
+
+		if len(body) > MaxRecordedBody || capture.overflow {
+			return
+		}
+		rec.write(r.URL.Path, start, latency, body, capture)
+	})
+}
+
+// write anonymizes and appends a record. Requests that aren't JSON are skipped.
+func (rec *Recorder) write(endpoint string, start time.Time, latency time.Duration, body []byte, capture *captureWriter) {
+	request, err := anonymizeJSON(body)
+	if err != nil {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.seq++
+	line, err := json.Marshal(Record{
+		ID:        fmt.Sprintf("%s-%06d", start.UTC().Format("20060102T150405"), rec.seq),
+		Time:      start.UTC(),
+		Endpoint:  endpoint,
+		Request:   request,
+		Status:    capture.status,
+		Response:  anonymizeResponse(capture.body.String()),
+		LatencyMs: latency.Milliseconds(),
+	})
+	if err != nil {
+		return
+	}
+	rec.out.Write(append(line, '\n'))
+}
+
+// Close closes the record file
+func (rec *Recorder) Close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.out.Close()
+}
+
+// ReadRecords reads records written by a Recorder
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*MaxRecordedBody)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// captureWriter passes a response through while keeping a copy of it
+type captureWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.body.Len()+len(p) > MaxRecordedBody {
+		c.overflow = true
+	} else {
+		c.body.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush keeps streamed responses streaming
+func (c *captureWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// FlushError flushes like Flush, reporting failed writes through
+// http.ResponseController
+func (c *captureWriter) FlushError() error {
+	return http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (c *captureWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+)
+
+// ScrubText masks e-mail addresses and phone numbers
+func ScrubText(s string) string {
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	return phonePattern.ReplaceAllString(s, "[number]")
+}
+
+// anonymizeJSON drops caller identifiers (the OpenAI "user" field) and scrubs
+// personal data from every string in a JSON document
+func anonymizeJSON(data []byte) (json.RawMessage, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(anonymizeValue(doc))
+}
+
+// anonymizeValue anonymizes a decoded JSON value
+func anonymizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		delete(v, "user")
+		for k, child := range v {
+			v[k] = anonymizeValue(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = anonymizeValue(child)
+		}
+		return v
+	case string:
+		return ScrubText(v)
+	default:
+		return v
+	}
+}
+
+// anonymizeResponse anonymizes a JSON response body, or each data event of a
+// Server-Sent Events stream
+func anonymizeResponse(body string) string {
+	if out, err := anonymizeJSON([]byte(body)); err == nil {
+		return string(out)
+	}
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if out, err := anonymizeJSON([]byte(data)); err == nil {
+			lines[i] = "data: " + string(out)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
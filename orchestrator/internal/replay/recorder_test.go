@@ -0,0 +1,106 @@
+package replay
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct{ bytes.Buffer }
+
+func (*nopCloser) Close() error { return nil }
+
+func TestRecorder(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"choices":[{"message":{"content":"mail bob@example.com"}}],"echo":` + string(body) + `}`))
+	})
+
+	t.Run("records anonymized exchange", func(t *testing.T) {
+		out := &nopCloser{}
+		rec := newRecorder(out, 1)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"m","user":"u-42","messages":[{"role":"user","content":"call +1 555 123 4567"}]}`))
+		req.Header.Set("Authorization", "Bearer secret-key")
+		w := httptest.NewRecorder()
+		rec.Wrap(echo).ServeHTTP(w, req)
+
+		// The handler still sees the original request
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), "u-42")
+
+		records, err := ReadRecords(&out.Buffer)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		r := records[0]
+		assert.Equal(t, "/v1/chat/completions", r.Endpoint)
+		assert.Equal(t, http.StatusCreated, r.Status)
+		assert.NotEmpty(t, r.ID)
+		assert.JSONEq(t, `{"model":"m","messages":[{"role":"user","content":"call [number]"}]}`, string(r.Request))
+		assert.NotContains(t, r.Response, "bob@example.com")
+		assert.NotContains(t, r.Response, "u-42")
+		assert.NotContains(t, out.String(), "secret-key")
+	})
+
+	t.Run("skips unsampled requests", func(t *testing.T) {
+		out := &nopCloser{}
+		rec := newRecorder(out, 0.5)
+		rec.random = func() float64 { return 0.7 }
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"input":"x"}`))
+		rec.Wrap(echo).ServeHTTP(httptest.NewRecorder(), req)
+		assert.Zero(t, out.Len())
+	})
+
+	t.Run("skips oversized requests", func(t *testing.T) {
+		out := &nopCloser{}
+		rec := newRecorder(out, 1)
+
+		big := `{"input":"` + strings.Repeat("a", MaxRecordedBody) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(big))
+		w := httptest.NewRecorder()
+		rec.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Len(t, body, len(big))
+		})).ServeHTTP(w, req)
+		assert.Zero(t, out.Len())
+	})
+}
+
+func TestNewRecorderAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	for i := 0; i < 2; i++ {
+		rec, err := NewRecorder(path, 1)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"input":"x"}`))
+		rec.Wrap(handler).ServeHTTP(httptest.NewRecorder(), req)
+		require.NoError(t, rec.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	records, err := ReadRecords(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestAnonymizeResponseStream(t *testing.T) {
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"me@corp.io\"}}]}\n\ndata: [DONE]\n\n"
+	got := anonymizeResponse(stream)
+	assert.Equal(t, "data: {\"choices\":[{\"delta\":{\"content\":\"[email]\"}}]}\n\ndata: [DONE]\n\n", got)
+}
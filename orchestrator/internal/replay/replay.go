@@ -0,0 +1,274 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EmbeddingMatchThreshold is the cosine similarity at or above which replayed
+// embeddings count as matching the recorded ones
+const EmbeddingMatchThreshold = 0.999
+
+// Replayer re-sends recorded requests to a gateway
+type Replayer struct {
+	BaseURL string // e.g. "http://localhost:8080"
+	APIKey  string // Optional
+	Client  *http.Client
+}
+
+// Result compares a replayed request with its recording
+type Result struct {
+	ID         string        `json:"id"`
+	Endpoint   string        `json:"endpoint"`
+	OldStatus  int           `json:"old_status"`
+	NewStatus  int           `json:"new_status"`
+	OldLatency time.Duration `json:"old_latency"`
+	NewLatency time.Duration `json:"new_latency"`
+	Match      bool          `json:"match"`
+	Similarity float64       `json:"similarity,omitempty"` // Lowest embedding cosine similarity
+	Diff       string        `json:"diff,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Replay re-sends a recorded request and compares the response
+func (rp *Replayer) Replay(ctx context.Context, record Record) Result {
+	result := Result{
+		ID:         record.ID,
+		Endpoint:   record.Endpoint,
+		OldStatus:  record.Status,
+		OldLatency: time.Duration(record.LatencyMs) * time.Millisecond,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(rp.BaseURL, "/")+record.Endpoint, bytes.NewReader(record.Request))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if rp.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+rp.APIKey)
+	}
+
+	client := rp.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	result.NewLatency = time.Since(start)
+	result.NewStatus = resp.StatusCode
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Match, result.Similarity, result.Diff = Compare(record.Endpoint, record.Response, string(body))
+	if result.OldStatus != result.NewStatus {
+		result.Match = false
+		result.Diff = fmt.Sprintf("status %d, was %d", result.NewStatus, result.OldStatus)
+	}
+	return result
+}
+
+// Compare compares a recorded response with a replayed one: the generated
+// text for chat completions and the vectors for embeddings. similarity is only
+// set for embeddings.
+func Compare(endpoint, oldBody, newBody string) (match bool, similarity float64, diff string) {
+	if strings.HasSuffix(endpoint, "/embeddings") {
+		return compareEmbeddings(oldBody, newBody)
+	}
+
+	oldText, newText := chatText(oldBody), chatText(newBody)
+	if oldText == newText {
+		return true, 0, ""
+	}
+	return false, 0, textDiff(oldText, newText)
+}
+
+// chatText extracts the generated text of a chat completion response or stream
+func chatText(body string) string {
+	type choice struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	}
+	var chunk struct {
+		Choices []choice `json:"choices"`
+	}
+
+	var text strings.Builder
+	appendChunk := func(data string) {
+		chunk.Choices = nil
+		if json.Unmarshal([]byte(data), &chunk) != nil {
+			return
+		}
+		for _, c := range chunk.Choices {
+			text.WriteString(c.Message.Content)
+			text.WriteString(c.Delta.Content)
+		}
+	}
+
+	if !strings.Contains(body, "data: ") {
+		appendChunk(body)
+		return text.String()
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok && data != "[DONE]" {
+			appendChunk(data)
+		}
+	}
+	return text.String()
+}
+
+// textDiff describes where two texts first differ
+func textDiff(oldText, newText string) string {
+	i := 0
+	for i < len(oldText) && i < len(newText) && oldText[i] == newText[i] {
+		i++
+	}
+	return fmt.Sprintf("output differs at byte %d: was %q, now %q", i, excerpt(oldText, i), excerpt(newText, i))
+}
+
+// excerpt returns up to 40 bytes of s starting at i
+func excerpt(s string, i int) string {
+	end := i + 40
+	if end > len(s) {
+		end = len(s)
+	}
+	return s[i:end]
+}
+
+// compareEmbeddings compares two embedding responses by cosine similarity
+func compareEmbeddings(oldBody, newBody string) (bool, float64, string) {
+	oldVecs, err := embeddings(oldBody)
+	if err != nil {
+		return false, 0, fmt.Sprintf("recorded response: %v", err)
+	}
+	newVecs, err := embeddings(newBody)
+	if err != nil {
+		return false, 0, fmt.Sprintf("replayed response: %v", err)
+	}
+	if len(oldVecs) != len(newVecs) {
+		return false, 0, fmt.Sprintf("%d embeddings, was %d", len(newVecs), len(oldVecs))
+	}
+
+	lowest := 1.0
+	for i := range oldVecs {
+		if len(oldVecs[i]) != len(newVecs[i]) {
+			return false, 0, fmt.Sprintf("embedding %d has %d dimensions, was %d", i, len(newVecs[i]), len(oldVecs[i]))
+		}
+		if s := cosineSimilarity(oldVecs[i], newVecs[i]); s < lowest {
+			lowest = s
+		}
+	}
+
+	if lowest < EmbeddingMatchThreshold {
+		return false, lowest, fmt.Sprintf("cosine similarity %.4f", lowest)
+	}
+	return true, lowest, ""
+}
+
+// embeddings extracts the vectors of an embeddings response
+func embeddings(body string) ([][]float64, error) {
+	var resp struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return nil, err
+	}
+
+	vecs := make([][]float64, len(resp.Data))
+	for i, d := range resp.Data {
+		vecs[i] = d.Embedding
+	}
+	return vecs, nil
+}
+
+// cosineSimilarity returns the cosine similarity of two equal-length vectors
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		if normA == normB {
+			return 1
+		}
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Summary aggregates replay results
+type Summary struct {
+	Total            int           `json:"total"`
+	Errors           int           `json:"errors"`
+	StatusMismatches int           `json:"status_mismatches"`
+	OutputMismatches int           `json:"output_mismatches"`
+	OldP50           time.Duration `json:"old_p50"`
+	NewP50           time.Duration `json:"new_p50"`
+	OldP95           time.Duration `json:"old_p95"`
+	NewP95           time.Duration `json:"new_p95"`
+}
+
+// Summarize aggregates results. Requests that failed to replay are left out
+// of the latency percentiles.
+func Summarize(results []Result) Summary {
+	summary := Summary{Total: len(results)}
+	var oldLatencies, newLatencies []time.Duration
+
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			summary.Errors++
+			continue
+		case r.OldStatus != r.NewStatus:
+			summary.StatusMismatches++
+		case !r.Match:
+			summary.OutputMismatches++
+		}
+		oldLatencies = append(oldLatencies, r.OldLatency)
+		newLatencies = append(newLatencies, r.NewLatency)
+	}
+
+	summary.OldP50, summary.OldP95 = percentile(oldLatencies, 50), percentile(oldLatencies, 95)
+	summary.NewP50, summary.NewP95 = percentile(newLatencies, 50), percentile(newLatencies, 95)
+	return summary
+}
+
+// percentile returns the nearest-rank percentile p of latencies
+func percentile(latencies []time.Duration, p int) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		old      string
+		new      string
+		match    bool
+	}{
+		{
+			name:     "same chat text",
+			endpoint: "/v1/chat/completions",
+			old:      `{"id":"a","choices":[{"message":{"content":"hello"}}]}`,
+			new:      `{"id":"b","choices":[{"message":{"content":"hello"}}]}`,
+			match:    true,
+		},
+		{
+			name:     "different chat text",
+			endpoint: "/v1/chat/completions",
+			old:      `{"choices":[{"message":{"content":"hello"}}]}`,
+			new:      `{"choices":[{"message":{"content":"help"}}]}`,
+		},
+		{
+			name:     "stream matches differently chunked stream",
+			endpoint: "/v1/chat/completions",
+			old:      "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\ndata: [DONE]\n\n",
+			new:      "data: {\"choices\":[{\"delta\":{\"content\":\"hello\"}}]}\n\ndata: [DONE]\n\n",
+			match:    true,
+		},
+		{
+			name:     "close embeddings",
+			endpoint: "/v1/embeddings",
+			old:      `{"data":[{"embedding":[1,2,3]}]}`,
+			new:      `{"data":[{"embedding":[1,2,3.0001]}]}`,
+			match:    true,
+		},
+		{
+			name:     "diverging embeddings",
+			endpoint: "/v1/embeddings",
+			old:      `{"data":[{"embedding":[1,0,0]}]}`,
+			new:      `{"data":[{"embedding":[0,1,0]}]}`,
+		},
+		{
+			name:     "embedding dimensions changed",
+			endpoint: "/v1/embeddings",
+			old:      `{"data":[{"embedding":[1,0]}]}`,
+			new:      `{"data":[{"embedding":[1,0,0]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, _, diff := Compare(tt.endpoint, tt.old, tt.new)
+			assert.Equal(t, tt.match, match)
+			if tt.match {
+				assert.Empty(t, diff)
+			} else {
+				assert.NotEmpty(t, diff)
+			}
+		})
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	var gotAuth string
+	var gotBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"choices":[{"message":{"content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	replayer := &Replayer{BaseURL: server.URL + "/", APIKey: "key"}
+
+	t.Run("matching response", func(t *testing.T) {
+		result := replayer.Replay(context.Background(), Record{
+			ID:        "r1",
+			Endpoint:  "/v1/chat/completions",
+			Request:   json.RawMessage(`{"model":"m"}`),
+			Status:    http.StatusOK,
+			Response:  `{"choices":[{"message":{"content":"hi"}}]}`,
+			LatencyMs: 250,
+		})
+		assert.Empty(t, result.Error)
+		assert.True(t, result.Match)
+		assert.Equal(t, 250*time.Millisecond, result.OldLatency)
+		assert.Equal(t, "Bearer key", gotAuth)
+		assert.Equal(t, "m", gotBody["model"])
+	})
+
+	t.Run("status change", func(t *testing.T) {
+		result := replayer.Replay(context.Background(), Record{
+			Endpoint: "/v1/chat/completions",
+			Request:  json.RawMessage(`{}`),
+			Status:   http.StatusServiceUnavailable,
+		})
+		assert.False(t, result.Match)
+		assert.Contains(t, result.Diff, "status 200, was 503")
+	})
+
+	t.Run("unreachable gateway", func(t *testing.T) {
+		result := (&Replayer{BaseURL: "http://127.0.0.1:1"}).Replay(context.Background(), Record{
+			Endpoint: "/v1/embeddings",
+			Request:  json.RawMessage(`{}`),
+		})
+		assert.NotEmpty(t, result.Error)
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		{OldStatus: 200, NewStatus: 200, Match: true, OldLatency: 100 * time.Millisecond, NewLatency: 80 * time.Millisecond},
+		{OldStatus: 200, NewStatus: 200, Match: false, OldLatency: 200 * time.Millisecond, NewLatency: 90 * time.Millisecond},
+		{OldStatus: 200, NewStatus: 500, OldLatency: 300 * time.Millisecond, NewLatency: 10 * time.Millisecond},
+		{Error: "connection refused"},
+	}
+
+	summary := Summarize(results)
+	assert.Equal(t, 4, summary.Total)
+	assert.Equal(t, 1, summary.Errors)
+	assert.Equal(t, 1, summary.StatusMismatches)
+	assert.Equal(t, 1, summary.OutputMismatches)
+	assert.Equal(t, 200*time.Millisecond, summary.OldP50)
+	assert.Equal(t, 300*time.Millisecond, summary.OldP95)
+	assert.Equal(t, 80*time.Millisecond, summary.NewP50)
+	require.Equal(t, 90*time.Millisecond, summary.NewP95)
+}
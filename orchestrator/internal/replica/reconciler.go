@@ -0,0 +1,356 @@
+// Package replica keeps warm standby replicas of models loaded on nodes, as
+// configured by warm_replicas in the model catalog, so traffic for those
+// models never waits for a cold start.
+package replica
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// DefaultInterval is how often replicas are reconciled
+const DefaultInterval = 30 * time.Second
+
+// LoadTimeout bounds a single LoadModel call, which may include pulling the model
+const LoadTimeout = 10 * time.Minute
+
+// StatusTimeout bounds a single GetModelStatus call
+const StatusTimeout = 10 * time.Second
+
+// Dialer provides NodeAgent clients for nodes
+type Dialer interface {
+	NodeClient(n *pb.Node) (pb.NodeAgentClient, error)
+}
+
+// Reconciler preloads models on nodes until each model in the catalog has its
+// minimum number of warm replicas. Replicas on nodes that leave or go stale
+// are replaced on other nodes; if a stale node comes back, the surplus replica
+// is unloaded again. Replicas their node reports missing or degraded are
+// loaded again. It implements scheduler.Scorer so requests prefer nodes
+// holding a warm replica.
+type Reconciler struct {
+	registry node.Registry
+	catalog  *catalog.Catalog
+	dialer   Dialer
+	filter   scheduler.Filter
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	replicas map[string]map[string]bool // model -> IDs of nodes holding a warm replica
+}
+
+// NewReconciler creates a reconciler for the warm replica minimums in c
+func NewReconciler(registry node.Registry, c *catalog.Catalog) *Reconciler {
+	return &Reconciler{
+		registry: registry,
+		catalog:  c,
+		now:      time.Now,
+		replicas: make(map[string]map[string]bool),
+	}
+}
+
+// SetDialer sets how the reconciler connects to node agents
+func (r *Reconciler) SetDialer(dialer Dialer) {
+	r.dialer = dialer
+}
+
+// SetFilter sets a filter candidate nodes must pass, e.g. the deployment
+// manager so nodes reserved for multi-node deployments are left alone
+func (r *Reconciler) SetFilter(f scheduler.Filter) {
+	r.filter = f
+}
+
+// Start reconciles immediately and then every interval until ctx is cancelled
+func (r *Reconciler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			r.Reconcile(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Reconcile brings every model's warm replicas to its configured minimum
+func (r *Reconciler) Reconcile(ctx context.Context) {
+	if r.dialer == nil {
+		return
+	}
+
+	wanted := r.catalog.WarmReplicas()
+	models := make([]string, 0, len(wanted))
+	for model := range wanted {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	for _, model := range models {
+		if ctx.Err() != nil {
+			return
+		}
+		r.reconcileModel(ctx, model, wanted[model])
+	}
+}
+
+// reconcileModel loads or unloads one model until it has want replicas
+func (r *Reconciler) reconcileModel(ctx context.Context, model string, want int) {
+	logger := logging.FromContext(ctx)
+	live := r.healthyReplicas(ctx, model, r.liveReplicas(ctx, model))
+
+	if len(live) < want {
+		for _, n := range r.candidates(model) {
+			if len(live) >= want {
+				break
+			}
+			if err := r.load(ctx, n, model); err != nil {
+				logger.Warn("Failed to preload warm replica", map[string]interface{}{
+					"model":   model,
+					"node_id": n.Id,
+					"error":   err.Error(),
+				})
+				continue
+			}
+			live = append(live, n)
+			r.track(model, n.Id, true)
+			logger.Info("Preloaded warm replica", map[string]interface{}{
+				"model":   model,
+				"node_id": n.Id,
+			})
+		}
+		if len(live) < want {
+			logger.Warn("Not enough nodes for warm replicas", map[string]interface{}{
+				"model":    model,
+				"wanted":   want,
+				"replicas": len(live),
+			})
+		}
+		return
+	}
+
+	// Unload the replicas beyond the minimum, keeping the lowest node IDs
+	sort.Slice(live, func(i, j int) bool { return live[i].Id < live[j].Id })
+	for _, n := range live[want:] {
+		if err := r.unload(ctx, n, model); err != nil {
+			logger.Warn("Failed to unload surplus warm replica", map[string]interface{}{
+				"model":   model,
+				"node_id": n.Id,
+				"error":   err.Error(),
+			})
+			continue
+		}
+		r.track(model, n.Id, false)
+		logger.Info("Unloaded surplus warm replica", map[string]interface{}{
+			"model":   model,
+			"node_id": n.Id,
+		})
+	}
+}
+
+// liveReplicas returns the online nodes holding a replica of model. Replicas
+// on nodes that left the registry are forgotten; replicas on stale nodes are
+// kept but don't count until the node is back online.
+func (r *Reconciler) liveReplicas(ctx context.Context, model string) []*pb.Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var live []*pb.Node
+	for id := range r.replicas[model] {
+		n, ok := r.registry.Get(id)
+		if !ok {
+			delete(r.replicas[model], id)
+			logging.FromContext(ctx).Warn("Lost warm replica", map[string]interface{}{
+				"model":   model,
+				"node_id": id,
+			})
+			continue
+		}
+		if node.Status(n, now) == node.StatusOnline {
+			live = append(live, n)
+		}
+	}
+	return live
+}
+
+// healthyReplicas returns the live replicas whose node reports the model
+// loaded or on its way. Replicas reported missing or degraded, e.g. after the
+// agent restarted or the engine crashed, are forgotten so they're loaded
+// again. Nodes that can't report model states are trusted.
+func (r *Reconciler) healthyReplicas(ctx context.Context, model string, live []*pb.Node) []*pb.Node {
+	var healthy []*pb.Node
+	for _, n := range live {
+		state, err := r.modelState(ctx, n, model)
+		if err != nil || replicaHealthy(state) {
+			healthy = append(healthy, n)
+			continue
+		}
+		r.track(model, n.Id, false)
+		logging.FromContext(ctx).Warn("Lost warm replica", map[string]interface{}{
+			"model":   model,
+			"node_id": n.Id,
+			"state":   state.String(),
+		})
+	}
+	return healthy
+}
+
+// replicaHealthy reports whether a replica in state serves, or soon will
+func replicaHealthy(state pb.ModelState) bool {
+	switch state {
+	case pb.ModelState_MODEL_STATE_DOWNLOADING, pb.ModelState_MODEL_STATE_STARTING, pb.ModelState_MODEL_STATE_READY:
+		return true
+	default:
+		return false
+	}
+}
+
+// candidates returns the online nodes that could take a new replica of model,
+// best first: GPU nodes, then nodes holding the fewest replicas, then by ID
+func (r *Reconciler) candidates(model string) []*pb.Node {
+	r.mu.RLock()
+	hosting := make(map[string]bool, len(r.replicas[model]))
+	for id := range r.replicas[model] {
+		hosting[id] = true
+	}
+	r.mu.RUnlock()
+
+	now := r.now()
+	var nodes []*pb.Node
+	for _, n := range r.registry.List() {
+		if !hosting[n.Id] && node.Status(n, now) == node.StatusOnline {
+			nodes = append(nodes, n)
+		}
+	}
+	if r.filter != nil && len(nodes) > 0 {
+		nodes = r.filter.Filter(&scheduler.Request{Model: model}, nodes)
+	}
+
+	load := r.replicaCounts()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if gpuA, gpuB := node.HasGPU(a), node.HasGPU(b); gpuA != gpuB {
+			return gpuA
+		}
+		if load[a.Id] != load[b.Id] {
+			return load[a.Id] < load[b.Id]
+		}
+		return a.Id < b.Id
+	})
+	return nodes
+}
+
+// replicaCounts returns the number of warm replicas each node holds
+func (r *Reconciler) replicaCounts() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, nodes := range r.replicas {
+		for id := range nodes {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+// track records that a node gained or lost a replica of model
+func (r *Reconciler) track(model, nodeID string, loaded bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !loaded {
+		delete(r.replicas[model], nodeID)
+		return
+	}
+	if r.replicas[model] == nil {
+		r.replicas[model] = make(map[string]bool)
+	}
+	r.replicas[model][nodeID] = true
+}
+
+// load asks a node to load a model
+func (r *Reconciler) load(ctx context.Context, n *pb.Node, model string) error {
+	client, err := r.dialer.NodeClient(n)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, LoadTimeout)
+	defer cancel()
+	_, err = client.LoadModel(ctx, &pb.LoadModelRequest{Model: model})
+	return err
+}
+
+// modelState asks a node for the state of a model, which is unspecified if
+// the node doesn't have it
+func (r *Reconciler) modelState(ctx context.Context, n *pb.Node, model string) (pb.ModelState, error) {
+	client, err := r.dialer.NodeClient(n)
+	if err != nil {
+		return pb.ModelState_MODEL_STATE_UNSPECIFIED, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, StatusTimeout)
+	defer cancel()
+	resp, err := client.GetModelStatus(ctx, &pb.GetModelStatusRequest{Model: model})
+	if err != nil {
+		return pb.ModelState_MODEL_STATE_UNSPECIFIED, err
+	}
+	for _, status := range resp.Models {
+		if status.Model == model {
+			return status.State, nil
+		}
+	}
+	return pb.ModelState_MODEL_STATE_UNSPECIFIED, nil
+}
+
+// unload asks a node to unload a model
+func (r *Reconciler) unload(ctx context.Context, n *pb.Node, model string) error {
+	client, err := r.dialer.NodeClient(n)
+	if err != nil {
+		return err
+	}
+	_, err = client.UnloadModel(ctx, &pb.UnloadModelRequest{Model: model})
+	return err
+}
+
+// Replicas returns the IDs of the nodes holding a warm replica of each model
+func (r *Reconciler) Replicas() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	replicas := make(map[string][]string, len(r.replicas))
+	for model, nodes := range r.replicas {
+		ids := make([]string, 0, len(nodes))
+		for id := range nodes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		replicas[model] = ids
+	}
+	return replicas
+}
+
+// Score returns 1 for nodes holding a warm replica of the requested model, 0 otherwise
+func (r *Reconciler) Score(req *scheduler.Request, n *pb.Node) float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.replicas[req.Model][n.Id] {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+	"github.com/Orchion/Orchion/orchestrator/internal/scheduler"
+)
+
+// MockNodeAgentClient records the models loaded on one node
+type MockNodeAgentClient struct {
+	pb.NodeAgentClient
+	loaded    map[string]bool
+	degraded  map[string]bool
+	loadErr   error
+	statusErr error
+}
+
+func (c *MockNodeAgentClient) LoadModel(ctx context.Context, in *pb.LoadModelRequest, opts ...grpc.CallOption) (*pb.LoadModelResponse, error) {
+	if c.loadErr != nil {
+		return nil, c.loadErr
+	}
+	c.loaded[in.Model] = true
+	return &pb.LoadModelResponse{}, nil
+}
+
+func (c *MockNodeAgentClient) UnloadModel(ctx context.Context, in *pb.UnloadModelRequest, opts ...grpc.CallOption) (*pb.UnloadModelResponse, error) {
+	delete(c.loaded, in.Model)
+	return &pb.UnloadModelResponse{}, nil
+}
+
+func (c *MockNodeAgentClient) GetModelStatus(ctx context.Context, in *pb.GetModelStatusRequest, opts ...grpc.CallOption) (*pb.GetModelStatusResponse, error) {
+	if c.statusErr != nil {
+		return nil, c.statusErr
+	}
+	resp := &pb.GetModelStatusResponse{}
+	if c.loaded[in.Model] {
+		state := pb.ModelState_MODEL_STATE_READY
+		if c.degraded[in.Model] {
+			state = pb.ModelState_MODEL_STATE_DEGRADED
+		}
+		resp.Models = append(resp.Models, &pb.ModelStatus{Model: in.Model, State: state})
+	}
+	return resp, nil
+}
+
+// MockDialer hands out one mock client per node
+type MockDialer struct {
+	clients map[string]*MockNodeAgentClient
+}
+
+func NewMockDialer() *MockDialer {
+	return &MockDialer{clients: make(map[string]*MockNodeAgentClient)}
+}
+
+func (d *MockDialer) NodeClient(n *pb.Node) (pb.NodeAgentClient, error) {
+	return d.client(n.Id), nil
+}
+
+func (d *MockDialer) client(nodeID string) *MockNodeAgentClient {
+	if _, ok := d.clients[nodeID]; !ok {
+		d.clients[nodeID] = &MockNodeAgentClient{loaded: make(map[string]bool)}
+	}
+	return d.clients[nodeID]
+}
+
+// reservedFilter keeps one node out of scheduling
+type reservedFilter string
+
+func (f reservedFilter) Filter(req *scheduler.Request, nodes []*pb.Node) []*pb.Node {
+	var kept []*pb.Node
+	for _, n := range nodes {
+		if n.Id != string(f) {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+func gpuNode(id string) *pb.Node {
+	return &pb.Node{Id: id, Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CUDA}}
+}
+
+func cpuNode(id string) *pb.Node {
+	return &pb.Node{Id: id, Capabilities: &pb.Capabilities{GpuBackend: pb.GpuBackend_GPU_BACKEND_CPU}}
+}
+
+func newReconciler(t *testing.T, replicas map[string]int, nodes ...*pb.Node) (*Reconciler, *node.InMemoryRegistry, *MockDialer) {
+	registry := node.NewInMemoryRegistry()
+	for _, n := range nodes {
+		require.NoError(t, registry.Register(n))
+	}
+
+	c := catalog.New()
+	for model, count := range replicas {
+		c.Models[model] = &catalog.Model{WarmReplicas: count}
+	}
+
+	dialer := NewMockDialer()
+	r := NewReconciler(registry, c)
+	r.SetDialer(dialer)
+	return r, registry, dialer
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("preloads on GPU nodes first", func(t *testing.T) {
+		r, _, dialer := newReconciler(t, map[string]int{"llama3": 2}, cpuNode("cpu-a"), gpuNode("gpu-b"), gpuNode("gpu-a"))
+		r.Reconcile(ctx)
+
+		assert.Equal(t, map[string][]string{"llama3": {"gpu-a", "gpu-b"}}, r.Replicas())
+		assert.True(t, dialer.client("gpu-a").loaded["llama3"])
+		assert.Empty(t, dialer.client("cpu-a").loaded)
+	})
+
+	t.Run("spreads models across nodes", func(t *testing.T) {
+		r, _, _ := newReconciler(t, map[string]int{"llama3": 1, "mistral": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+
+		assert.Equal(t, map[string][]string{"llama3": {"gpu-a"}, "mistral": {"gpu-b"}}, r.Replicas())
+	})
+
+	t.Run("replaces replicas of nodes that leave", func(t *testing.T) {
+		r, registry, _ := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+		require.Equal(t, []string{"gpu-a"}, r.Replicas()["llama3"])
+
+		require.NoError(t, registry.Remove("gpu-a"))
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-b"}, r.Replicas()["llama3"])
+	})
+
+	t.Run("unloads the surplus when a stale node comes back", func(t *testing.T) {
+		r, registry, dialer := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+
+		// gpu-a misses its heartbeats, so gpu-b takes over
+		stale := gpuNode("gpu-a")
+		stale.LastSeenUnix = time.Now().Add(-time.Minute).Unix()
+		require.NoError(t, registry.Register(stale))
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-a", "gpu-b"}, r.Replicas()["llama3"])
+
+		require.NoError(t, registry.UpdateHeartbeat("gpu-a"))
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-a"}, r.Replicas()["llama3"])
+		assert.Empty(t, dialer.client("gpu-b").loaded)
+	})
+
+	t.Run("skips nodes that fail to load and filtered nodes", func(t *testing.T) {
+		r, _, dialer := newReconciler(t, map[string]int{"llama3": 2}, gpuNode("gpu-a"), gpuNode("gpu-b"), gpuNode("gpu-c"))
+		r.SetFilter(reservedFilter("gpu-c"))
+		dialer.client("gpu-a").loadErr = errors.New("CUDA error: out of memory")
+		r.Reconcile(ctx)
+
+		assert.Equal(t, []string{"gpu-b"}, r.Replicas()["llama3"])
+		assert.Empty(t, dialer.client("gpu-c").loaded)
+	})
+
+	t.Run("reloads replicas their node lost", func(t *testing.T) {
+		r, _, dialer := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+		require.Equal(t, []string{"gpu-a"}, r.Replicas()["llama3"])
+
+		// The agent restarted without the model
+		delete(dialer.client("gpu-a").loaded, "llama3")
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-a"}, r.Replicas()["llama3"])
+		assert.True(t, dialer.client("gpu-a").loaded["llama3"])
+	})
+
+	t.Run("replaces degraded replicas", func(t *testing.T) {
+		r, _, dialer := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+
+		dialer.client("gpu-a").degraded = map[string]bool{"llama3": true}
+		dialer.client("gpu-a").loadErr = errors.New("engine crashed")
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-b"}, r.Replicas()["llama3"])
+	})
+
+	t.Run("trusts nodes that can't report model states", func(t *testing.T) {
+		r, _, dialer := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+		r.Reconcile(ctx)
+
+		dialer.client("gpu-a").statusErr = status.Error(codes.Unimplemented, "unknown method GetModelStatus")
+		r.Reconcile(ctx)
+		assert.Equal(t, []string{"gpu-a"}, r.Replicas()["llama3"])
+		assert.Empty(t, dialer.client("gpu-b").loaded)
+	})
+}
+
+func TestReconciler_Score(t *testing.T) {
+	r, _, _ := newReconciler(t, map[string]int{"llama3": 1}, gpuNode("gpu-a"), gpuNode("gpu-b"))
+	r.Reconcile(context.Background())
+
+	assert.Equal(t, 1.0, r.Score(&scheduler.Request{Model: "llama3"}, gpuNode("gpu-a")))
+	assert.Equal(t, 0.0, r.Score(&scheduler.Request{Model: "llama3"}, gpuNode("gpu-b")))
+	assert.Equal(t, 0.0, r.Score(&scheduler.Request{Model: "mistral"}, gpuNode("gpu-a")))
+}
@@ -0,0 +1,147 @@
+package results
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+)
+
+// ProtobufContentType is the media type of serialized job results
+const ProtobufContentType = "application/x-protobuf"
+
+// ErrNoStore is returned for offloaded results when no result store is
+// configured
+var ErrNoStore = errors.New("result store not configured")
+
+// ErrInvalidRange is returned for byte ranges outside a result
+var ErrInvalidRange = errors.New("range not satisfiable")
+
+// MessageType returns the full name of the message a job's result holds, e.g.
+// "orchion.v1.EmbeddingResponse"; empty for unknown job types
+func MessageType(t queue.JobType) string {
+	var m proto.Message
+	switch t {
+	case queue.JobTypeChatCompletion:
+		m = &pb.ChatCompletionResponse{}
+	case queue.JobTypeEmbeddings:
+		m = &pb.EmbeddingResponse{}
+	case queue.JobTypeSpeech:
+		m = &pb.SpeechResponse{}
+	case queue.JobTypePipeline:
+		m = &pb.PipelineResponse{}
+	default:
+		return ""
+	}
+	return string(m.ProtoReflect().Descriptor().FullName())
+}
+
+// ContentType returns the media type of a job's result, naming its message
+// type as a parameter
+func ContentType(t queue.JobType) string {
+	messageType := MessageType(t)
+	if messageType == "" {
+		return ProtobufContentType
+	}
+	return fmt.Sprintf("%s; messageType=%q", ProtobufContentType, messageType)
+}
+
+// OpenJob streams a completed job's result, from memory or from the store it
+// was offloaded to
+func OpenJob(ctx context.Context, store Store, job *queue.Job) (io.ReadCloser, error) {
+	if job.ResultRef == "" {
+		return io.NopCloser(bytes.NewReader(job.Result)), nil
+	}
+	if store == nil {
+		return nil, ErrNoStore
+	}
+	return store.Open(ctx, job.ResultRef)
+}
+
+// OpenJobRange streams length bytes of a completed job's result from offset;
+// to the end if length is 0. Returns ErrInvalidRange if offset is past the end.
+func OpenJobRange(ctx context.Context, store Store, job *queue.Job, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 || (offset > 0 && offset >= job.ResultSize) {
+		return nil, ErrInvalidRange
+	}
+	if length == 0 || offset+length > job.ResultSize {
+		length = job.ResultSize - offset
+	}
+
+	r, err := OpenJob(ctx, store, job)
+	if err != nil {
+		return nil, err
+	}
+	if err := skip(r, offset); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to seek result: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(r, length), r}, nil
+}
+
+// skip advances r by n bytes, seeking where possible, e.g. in result files
+func skip(r io.Reader, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekStart)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// ParseRange parses an HTTP Range header for a result of size bytes into the
+// offset and length of a single range, e.g. "bytes=0-1023", "bytes=1024-" or
+// "bytes=-512". ok is false if the header is empty or asks for several
+// ranges, which are served as the whole result.
+func ParseRange(header string, size int64) (offset, length int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, ErrInvalidRange
+	}
+
+	if first == "" {
+		// Suffix range: the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+
+	offset, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || offset < 0 || offset >= size {
+		return 0, 0, false, ErrInvalidRange
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < offset {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return offset, end - offset + 1, true, nil
+}
@@ -0,0 +1,68 @@
+package results
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Orchion/Orchion/orchestrator/internal/queue"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header         string
+		offset, length int64
+		ok             bool
+		wantErr        bool
+	}{
+		{"", 0, 0, false, false},
+		{"bytes=0-99", 0, 100, true, false},
+		{"bytes=100-", 100, 900, true, false},
+		{"bytes=900-5000", 900, 100, true, false},
+		{"bytes=-10", 990, 10, true, false},
+		{"bytes=-5000", 0, 1000, true, false},
+		{"bytes=0-1,5-9", 0, 0, false, false},
+		{"items=0-9", 0, 0, false, false},
+		{"bytes=1000-", 0, 0, false, true},
+		{"bytes=9-5", 0, 0, false, true},
+		{"bytes=abc", 0, 0, false, true},
+		{"bytes=-0", 0, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			offset, length, ok, err := ParseRange(tt.header, 1000)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRange)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.offset, offset)
+			assert.Equal(t, tt.length, length)
+		})
+	}
+}
+
+func TestOpenJobRange(t *testing.T) {
+	ctx := context.Background()
+	job := &queue.Job{ID: "job-1", Type: queue.JobTypeSpeech, Result: []byte("0123456789"), ResultSize: 10}
+
+	r, err := OpenJobRange(ctx, nil, job, 3, 4)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "3456", string(data))
+	r.Close()
+
+	_, err = OpenJobRange(ctx, nil, job, 10, 0)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	_, err = OpenJobRange(ctx, nil, &queue.Job{ResultRef: "job-1", ResultSize: 10}, 0, 0)
+	assert.ErrorIs(t, err, ErrNoStore)
+
+	assert.Equal(t, `application/x-protobuf; messageType="orchion.v1.SpeechResponse"`, ContentType(job.Type))
+	assert.Equal(t, ProtobufContentType, ContentType(queue.JobTypeUnspecified))
+}
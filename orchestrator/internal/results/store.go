@@ -0,0 +1,110 @@
+package results
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultOffloadThreshold is the result size above which results leave memory
+const DefaultOffloadThreshold = 1 << 20 // 1 MiB
+
+// ErrNotFound is returned when a stored result doesn't exist
+var ErrNotFound = errors.New("result not found")
+
+// Store persists job results outside the orchestrator's memory
+type Store interface {
+	// Put stores a result under key, replacing any previous result
+	Put(ctx context.Context, key string, data []byte) error
+	// Open streams a stored result back
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes a stored result
+	Delete(ctx context.Context, key string) error
+}
+
+// Get reads a whole stored result
+func Get(ctx context.Context, store Store, key string) ([]byte, error) {
+	r, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// DiskStore stores results as files in a directory
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore creates a store writing results to dir, creating it if needed
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create result directory: %w", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// Put implements Store. The result is written to a temporary file first so
+// readers never see a partial result.
+func (s *DiskStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".result-*")
+	if err != nil {
+		return fmt.Errorf("failed to create result file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open implements Store
+func (s *DiskStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Store
+func (s *DiskStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// path maps a key to a file, rejecting keys that would escape the directory
+func (s *DiskStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid result key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
@@ -0,0 +1,55 @@
+package results
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewDiskStore(t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "job-1", []byte("result")))
+
+		data, err := Get(ctx, store, "job-1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("result"), data)
+	})
+
+	t.Run("put replaces existing result", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "job-2", []byte("first")))
+		require.NoError(t, store.Put(ctx, "job-2", []byte("second")))
+
+		data, err := Get(ctx, store, "job-2")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("second"), data)
+	})
+
+	t.Run("missing result", func(t *testing.T) {
+		_, err := store.Open(ctx, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "job-3", []byte("result")))
+		require.NoError(t, store.Delete(ctx, "job-3"))
+
+		_, err := store.Open(ctx, "job-3")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, store.Delete(ctx, "job-3"), ErrNotFound)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", ".", "..", "../escape", `a\b`} {
+			assert.Error(t, store.Put(ctx, key, []byte("x")), key)
+			_, err := store.Open(ctx, key)
+			assert.Error(t, err, key)
+			assert.Error(t, store.Delete(ctx, key), key)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// latencyAlpha is the smoothing factor for the per-node latency moving average
+const latencyAlpha = 0.2
+
+// DefaultLatencyTTL is how long a latency average holds without new samples.
+// Nodes stop getting requests of a kind while their average is too high, so
+// it expires for them to be tried, and measured, again.
+const DefaultLatencyTTL = 5 * time.Minute
+
+// LatencyTracker keeps an exponentially weighted moving average of request
+// latency per node and request kind
+type LatencyTracker struct {
+	mu        sync.RWMutex
+	latencies map[latencyKey]latencyAverage
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+type latencyKey struct {
+	nodeID string
+	kind   RequestKind
+}
+
+type latencyAverage struct {
+	avg      time.Duration
+	observed time.Time // When the last sample was recorded
+}
+
+// NewLatencyTracker creates a new latency tracker
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{
+		latencies: make(map[latencyKey]latencyAverage),
+		ttl:       DefaultLatencyTTL,
+		now:       time.Now,
+	}
+}
+
+// Observe records the latency of a request served by a node
+func (t *LatencyTracker) Observe(nodeID string, kind RequestKind, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	key := latencyKey{nodeID: nodeID, kind: kind}
+	if entry, ok := t.latencies[key]; ok && !t.expired(entry, now) {
+		d = entry.avg + time.Duration(latencyAlpha*float64(d-entry.avg))
+	}
+	t.latencies[key] = latencyAverage{avg: d, observed: now}
+}
+
+// Latency returns the average latency of a node for a request kind, and false
+// if it has none or its last sample is older than the TTL
+func (t *LatencyTracker) Latency(nodeID string, kind RequestKind) (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entry, ok := t.latencies[latencyKey{nodeID: nodeID, kind: kind}]
+	if !ok || t.expired(entry, t.now()) {
+		return 0, false
+	}
+	return entry.avg, true
+}
+
+// ForgetNode drops the averages of a node, e.g. once it's evicted
+func (t *LatencyTracker) ForgetNode(nodeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.latencies {
+		if key.nodeID == nodeID {
+			delete(t.latencies, key)
+		}
+	}
+}
+
+// expired reports whether an average is too old to go by
+func (t *LatencyTracker) expired(entry latencyAverage, now time.Time) bool {
+	return now.Sub(entry.observed) > t.ttl
+}
+
+// EmbeddingAffinityScorer prefers CPU-only nodes for embedding requests so GPU
+// nodes stay free for chat completions. A CPU node loses its preference while
+// its average embedding latency exceeds the configured SLO, and gets it back
+// once the average expires without new samples.
+type EmbeddingAffinityScorer struct {
+	latencies *LatencyTracker
+	slo       time.Duration
+}
+
+// NewEmbeddingAffinityScorer creates a scorer that routes embeddings to CPU nodes
+// as long as their average latency stays within slo
+func NewEmbeddingAffinityScorer(latencies *LatencyTracker, slo time.Duration) *EmbeddingAffinityScorer {
+	return &EmbeddingAffinityScorer{
+		latencies: latencies,
+		slo:       slo,
+	}
+}
+
+// Score returns 1 for CPU-only nodes meeting the latency SLO on embedding requests, 0 otherwise
+func (s *EmbeddingAffinityScorer) Score(req *Request, n *pb.Node) float64 {
+	if req.Kind != KindEmbeddings || node.HasGPU(n) {
+		return 0
+	}
+
+	if s.latencies != nil && s.slo > 0 {
+		if latency, ok := s.latencies.Latency(n.Id, KindEmbeddings); ok && latency > s.slo {
+			return 0
+		}
+	}
+
+	return 1
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := NewLatencyTracker()
+
+	_, ok := tracker.Latency("node-1", KindEmbeddings)
+	assert.False(t, ok)
+
+	tracker.Observe("node-1", KindEmbeddings, 100*time.Millisecond)
+	latency, ok := tracker.Latency("node-1", KindEmbeddings)
+	require.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, latency)
+
+	// Moving average moves towards new observations
+	tracker.Observe("node-1", KindEmbeddings, 200*time.Millisecond)
+	latency, _ = tracker.Latency("node-1", KindEmbeddings)
+	assert.Equal(t, 120*time.Millisecond, latency)
+
+	// Kinds are tracked separately
+	_, ok = tracker.Latency("node-1", KindChatCompletion)
+	assert.False(t, ok)
+
+	t.Run("averages expire", func(t *testing.T) {
+		now := time.Now()
+		tracker.now = func() time.Time { return now }
+		tracker.Observe("node-2", KindEmbeddings, time.Second)
+
+		now = now.Add(DefaultLatencyTTL + time.Second)
+		_, ok := tracker.Latency("node-2", KindEmbeddings)
+		assert.False(t, ok)
+
+		// A new sample starts a new average
+		tracker.Observe("node-2", KindEmbeddings, 100*time.Millisecond)
+		latency, ok := tracker.Latency("node-2", KindEmbeddings)
+		require.True(t, ok)
+		assert.Equal(t, 100*time.Millisecond, latency)
+	})
+
+	t.Run("forget node", func(t *testing.T) {
+		tracker.Observe("node-1", KindSpeech, time.Second)
+		tracker.ForgetNode("node-1")
+		_, ok := tracker.Latency("node-1", KindEmbeddings)
+		assert.False(t, ok)
+		_, ok = tracker.Latency("node-1", KindSpeech)
+		assert.False(t, ok)
+		_, ok = tracker.Latency("node-2", KindEmbeddings)
+		assert.True(t, ok)
+	})
+}
+
+func TestEmbeddingAffinityScorer(t *testing.T) {
+	gpuNode := &pb.Node{Id: "gpu", Capabilities: &pb.Capabilities{GpuType: "NVIDIA GeForce RTX 4090"}}
+	cpuNode := &pb.Node{Id: "cpu", Capabilities: &pb.Capabilities{GpuType: "No GPU detected"}}
+	registry := &MockRegistry{nodes: []*pb.Node{gpuNode, cpuNode}}
+
+	tracker := NewLatencyTracker()
+	s := NewPipelineScheduler(nil, []Scorer{NewEmbeddingAffinityScorer(tracker, 500*time.Millisecond)})
+
+	t.Run("embeddings prefer CPU nodes", func(t *testing.T) {
+		selected, err := s.SelectNode(&Request{Model: "nomic-embed-text", Kind: KindEmbeddings}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "cpu", selected.Id)
+	})
+
+	t.Run("chat is unaffected", func(t *testing.T) {
+		selected, err := s.SelectNode(&Request{Model: "llama3", Kind: KindChatCompletion}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "gpu", selected.Id)
+	})
+
+	t.Run("CPU nodes violating the SLO lose their preference", func(t *testing.T) {
+		tracker.Observe("cpu", KindEmbeddings, 2*time.Second)
+
+		selected, err := s.SelectNode(&Request{Model: "nomic-embed-text", Kind: KindEmbeddings}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "gpu", selected.Id)
+	})
+
+	t.Run("CPU nodes recover once their average expires", func(t *testing.T) {
+		now := time.Now()
+		tracker.now = func() time.Time { return now.Add(DefaultLatencyTTL + time.Second) }
+
+		selected, err := s.SelectNode(&Request{Model: "nomic-embed-text", Kind: KindEmbeddings}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "cpu", selected.Id)
+	})
+}
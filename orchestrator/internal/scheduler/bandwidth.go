@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// networkAlpha is the smoothing factor for the per-node link moving averages
+const networkAlpha = 0.3
+
+// EmbeddingVectorBytes estimates the size of one embedding in a response,
+// e.g. 1024 float32 dimensions
+const EmbeddingVectorBytes = 4096
+
+// DefaultBandwidthMinBytes is the payload size from which requests prefer
+// nodes with fast links to the orchestrator
+const DefaultBandwidthMinBytes = 256 << 10
+
+// Link is a node's measured link to the orchestrator
+type Link struct {
+	RTT        time.Duration
+	UploadMbps float64 // Zero until a bandwidth probe was measured
+}
+
+// TransferTime estimates how long moving bytes over the link takes. Links
+// without a throughput measurement only count their round trip.
+func (l Link) TransferTime(bytes int64) time.Duration {
+	d := l.RTT
+	if l.UploadMbps > 0 {
+		d += time.Duration(float64(bytes*8) / (l.UploadMbps * 1e6) * float64(time.Second))
+	}
+	return d
+}
+
+// NetworkTracker keeps a moving average of each node's link to the
+// orchestrator, as agents report it with their heartbeats
+type NetworkTracker struct {
+	mu    sync.RWMutex
+	links map[string]Link
+}
+
+// NewNetworkTracker creates an empty network tracker
+func NewNetworkTracker() *NetworkTracker {
+	return &NetworkTracker{links: make(map[string]Link)}
+}
+
+// Observe records the link stats a node reported
+func (t *NetworkTracker) Observe(nodeID string, stats *pb.NetworkStats) {
+	if stats == nil || stats.RttMs <= 0 {
+		return
+	}
+	rtt := time.Duration(stats.RttMs * float64(time.Millisecond))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	link, ok := t.links[nodeID]
+	if !ok {
+		t.links[nodeID] = Link{RTT: rtt, UploadMbps: stats.UploadMbps}
+		return
+	}
+	link.RTT += time.Duration(networkAlpha * float64(rtt-link.RTT))
+	if stats.UploadMbps > 0 {
+		if link.UploadMbps > 0 {
+			link.UploadMbps += networkAlpha * (stats.UploadMbps - link.UploadMbps)
+		} else {
+			link.UploadMbps = stats.UploadMbps
+		}
+	}
+	t.links[nodeID] = link
+}
+
+// Link returns a node's averaged link
+func (t *NetworkTracker) Link(nodeID string) (Link, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	link, ok := t.links[nodeID]
+	return link, ok
+}
+
+// Forget drops a node's measurements, e.g. once it's evicted
+func (t *NetworkTracker) Forget(nodeID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.links, nodeID)
+}
+
+// BandwidthScorer keeps requests with large payloads, and embeddings, off
+// nodes with slow links to the orchestrator. Nodes lose a point per second the
+// payload is estimated to take over their link; nodes not measured yet lose
+// nothing.
+type BandwidthScorer struct {
+	network  *NetworkTracker
+	minBytes int64
+}
+
+// NewBandwidthScorer creates a scorer for requests of at least minBytes and
+// all embedding requests
+func NewBandwidthScorer(network *NetworkTracker, minBytes int64) *BandwidthScorer {
+	return &BandwidthScorer{network: network, minBytes: minBytes}
+}
+
+// Score returns minus the seconds the request's payload takes over the node's link
+func (s *BandwidthScorer) Score(req *Request, n *pb.Node) float64 {
+	bytes := req.PayloadBytes
+	if bytes < s.minBytes {
+		if req.Kind != KindEmbeddings {
+			return 0
+		}
+		bytes = s.minBytes
+	}
+
+	link, ok := s.network.Link(n.Id)
+	if !ok {
+		return 0
+	}
+	return -link.TransferTime(bytes).Seconds()
+}
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestNetworkTracker(t *testing.T) {
+	tracker := NewNetworkTracker()
+
+	_, ok := tracker.Link("node-1")
+	assert.False(t, ok)
+
+	// Stats without a round trip are ignored
+	tracker.Observe("node-1", nil)
+	tracker.Observe("node-1", &pb.NetworkStats{UploadMbps: 100})
+	_, ok = tracker.Link("node-1")
+	assert.False(t, ok)
+
+	tracker.Observe("node-1", &pb.NetworkStats{RttMs: 10})
+	link, ok := tracker.Link("node-1")
+	require.True(t, ok)
+	assert.Equal(t, Link{RTT: 10 * time.Millisecond}, link)
+
+	// Heartbeats without a probe keep the last throughput
+	tracker.Observe("node-1", &pb.NetworkStats{RttMs: 20, UploadMbps: 100})
+	tracker.Observe("node-1", &pb.NetworkStats{RttMs: 20})
+	link, _ = tracker.Link("node-1")
+	assert.Equal(t, 15100*time.Microsecond, link.RTT)
+	assert.Equal(t, 100.0, link.UploadMbps)
+
+	tracker.Observe("node-1", &pb.NetworkStats{RttMs: 20, UploadMbps: 200})
+	link, _ = tracker.Link("node-1")
+	assert.InDelta(t, 130.0, link.UploadMbps, 0.001)
+
+	tracker.Forget("node-1")
+	_, ok = tracker.Link("node-1")
+	assert.False(t, ok)
+}
+
+func TestBandwidthScorer(t *testing.T) {
+	wired := &pb.Node{Id: "wired"}
+	wifi := &pb.Node{Id: "wifi"}
+	unmeasured := &pb.Node{Id: "unmeasured"}
+
+	tracker := NewNetworkTracker()
+	tracker.Observe("wired", &pb.NetworkStats{RttMs: 1, UploadMbps: 1000})
+	tracker.Observe("wifi", &pb.NetworkStats{RttMs: 5, UploadMbps: 40})
+	scorer := NewBandwidthScorer(tracker, 1<<20)
+
+	t.Run("small requests are unaffected", func(t *testing.T) {
+		req := &Request{Model: "llama3", Kind: KindChatCompletion, PayloadBytes: 4 << 10}
+		assert.Zero(t, scorer.Score(req, wifi))
+	})
+
+	t.Run("large payloads prefer fast links", func(t *testing.T) {
+		req := &Request{Model: "llava", Kind: KindChatCompletion, PayloadBytes: 10 << 20}
+		assert.InDelta(t, -2.102, scorer.Score(req, wifi), 0.001)
+		assert.Greater(t, scorer.Score(req, wired), scorer.Score(req, wifi))
+		assert.Zero(t, scorer.Score(req, unmeasured))
+	})
+
+	t.Run("embeddings always count", func(t *testing.T) {
+		req := &Request{Model: "nomic-embed-text", Kind: KindEmbeddings}
+		assert.Less(t, scorer.Score(req, wifi), scorer.Score(req, wired))
+
+		s := NewPipelineScheduler(nil, []Scorer{scorer})
+		selected, err := s.SelectNode(req, &MockRegistry{nodes: []*pb.Node{wifi, wired}})
+		require.NoError(t, err)
+		assert.Equal(t, "wired", selected.Id)
+	})
+}
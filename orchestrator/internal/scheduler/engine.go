@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+)
+
+// EngineVersionFilter removes nodes running an engine older than the model's
+// min_engine_version in the catalog. A node's engine version is known once it
+// reports a model loaded on that engine; nodes with an unknown version are
+// kept, so a model can still cold-start on a fresh node.
+type EngineVersionFilter struct {
+	catalog *catalog.Catalog
+}
+
+// NewEngineVersionFilter creates a filter backed by the given model catalog
+func NewEngineVersionFilter(c *catalog.Catalog) *EngineVersionFilter {
+	return &EngineVersionFilter{catalog: c}
+}
+
+// Filter returns the nodes whose engines satisfy the requested model's minimum versions
+func (f *EngineVersionFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	m, ok := f.catalog.Get(req.Model)
+	if !ok || len(m.MinEngineVersion) == 0 {
+		return nodes
+	}
+
+	out := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if engineVersionsSatisfied(n, req.Model, m.MinEngineVersion) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// engineVersionsSatisfied reports whether none of the node's known engine
+// versions is older than the required minimums
+func engineVersionsSatisfied(n *pb.Node, model string, minimums map[string]string) bool {
+	for engine, minimum := range minimums {
+		version, ok := nodeEngineVersion(n, model, engine)
+		if !ok {
+			continue
+		}
+		have, err := catalog.ParseVersion(version)
+		if err != nil {
+			continue
+		}
+		want, err := catalog.ParseVersion(minimum)
+		if err != nil {
+			continue
+		}
+		if have.Compare(want) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeEngineVersion returns the version of engine on a node, preferring the
+// entry of the model itself since each model may run its own container
+func nodeEngineVersion(n *pb.Node, model, engine string) (string, bool) {
+	version := ""
+	for _, m := range n.Models {
+		if m.Engine != engine || m.Version == "" {
+			continue
+		}
+		if m.Model == model {
+			return m.Version, true
+		}
+		if version == "" {
+			version = m.Version
+		}
+	}
+	return version, version != ""
+}
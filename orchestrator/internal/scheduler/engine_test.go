@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+)
+
+func TestEngineVersionFilter(t *testing.T) {
+	c := catalog.New()
+	c.Models["mistralai/Mistral-7B-v0.1"] = &catalog.Model{MinEngineVersion: map[string]string{"vllm": "0.6.3"}}
+
+	nodes := []*pb.Node{
+		{Id: "current", Models: []*pb.ModelEngine{
+			{Model: "mistralai/Mistral-7B-v0.1", Engine: "vllm", Version: "0.6.4.post1"},
+		}},
+		{Id: "outdated", Models: []*pb.ModelEngine{
+			{Model: "mistralai/Mistral-7B-v0.1", Engine: "vllm", Version: "0.5.5"},
+		}},
+		{Id: "outdated-other-model", Models: []*pb.ModelEngine{
+			{Model: "Qwen/Qwen2-7B", Engine: "vllm", Version: "0.6.0"},
+		}},
+		{Id: "unknown"},
+		{Id: "ollama-only", Models: []*pb.ModelEngine{
+			{Model: "llama3", Engine: "ollama", Version: "0.1.0"},
+		}},
+	}
+
+	f := NewEngineVersionFilter(c)
+
+	t.Run("drops nodes with older engines", func(t *testing.T) {
+		filtered := f.Filter(&Request{Model: "mistralai/Mistral-7B-v0.1"}, nodes)
+		ids := make([]string, len(filtered))
+		for i, n := range filtered {
+			ids[i] = n.Id
+		}
+		assert.Equal(t, []string{"current", "unknown", "ollama-only"}, ids)
+	})
+
+	t.Run("models without a minimum keep every node", func(t *testing.T) {
+		assert.Len(t, f.Filter(&Request{Model: "llama3"}, nodes), len(nodes))
+	})
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// DefaultLocalityWeight is the score a node carrying all of a request's
+// locality labels gets: as much as 10 seconds of transfer time lost to the
+// BandwidthScorer
+const DefaultLocalityWeight = 10
+
+// LocalityScorer sends requests to the nodes their data is on, e.g. a job
+// embedding documents from a share mounted on some nodes only, which carry a
+// label saying so. Nodes score weight times the fraction of the request's
+// locality labels they carry, so data is preferred, never required: nodes
+// without it still take the request when the others can't.
+type LocalityScorer struct {
+	weight float64
+}
+
+// NewLocalityScorer creates a locality scorer giving matching nodes weight
+func NewLocalityScorer(weight float64) *LocalityScorer {
+	return &LocalityScorer{weight: weight}
+}
+
+// Score returns weight times the fraction of the request's locality labels
+// the node carries
+func (s *LocalityScorer) Score(req *Request, n *pb.Node) float64 {
+	if len(req.Locality) == 0 {
+		return 0
+	}
+	matched := 0
+	for key, value := range req.Locality {
+		if label, ok := n.Labels[key]; ok && label == value {
+			matched++
+		}
+	}
+	return s.weight * float64(matched) / float64(len(req.Locality))
+}
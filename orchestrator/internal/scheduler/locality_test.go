@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestLocalityScorer(t *testing.T) {
+	nas1 := &pb.Node{Id: "nas1-gpu", Labels: map[string]string{"corpus": "nas1", "zone": "basement"}}
+	nas2 := &pb.Node{Id: "nas2-gpu", Labels: map[string]string{"corpus": "nas2", "zone": "basement"}}
+	unlabeled := &pb.Node{Id: "laptop"}
+	scorer := NewLocalityScorer(DefaultLocalityWeight)
+
+	t.Run("requests without locality are unaffected", func(t *testing.T) {
+		req := &Request{Model: "nomic-embed-text", Kind: KindEmbeddings}
+		assert.Zero(t, scorer.Score(req, nas1))
+	})
+
+	t.Run("nodes score by the labels they carry", func(t *testing.T) {
+		req := &Request{Model: "nomic-embed-text", Locality: map[string]string{"corpus": "nas1", "zone": "basement"}}
+		assert.Equal(t, 10.0, scorer.Score(req, nas1))
+		assert.Equal(t, 5.0, scorer.Score(req, nas2))
+		assert.Zero(t, scorer.Score(req, unlabeled))
+	})
+
+	t.Run("outweighs a slower link", func(t *testing.T) {
+		tracker := NewNetworkTracker()
+		tracker.Observe("nas1-gpu", &pb.NetworkStats{RttMs: 5, UploadMbps: 40})
+		tracker.Observe("laptop", &pb.NetworkStats{RttMs: 1, UploadMbps: 1000})
+		s := NewPipelineScheduler(nil, []Scorer{NewBandwidthScorer(tracker, DefaultBandwidthMinBytes), scorer})
+
+		req := &Request{Model: "nomic-embed-text", Kind: KindEmbeddings, Locality: map[string]string{"corpus": "nas1"}}
+		selected, err := s.SelectNode(req, &MockRegistry{nodes: []*pb.Node{unlabeled, nas2, nas1}})
+		require.NoError(t, err)
+		assert.Equal(t, "nas1-gpu", selected.Id)
+
+		// Without a node holding the data, any node takes the request
+		req.Locality = map[string]string{"corpus": "nas3"}
+		selected, err = s.SelectNode(req, &MockRegistry{nodes: []*pb.Node{nas1, unlabeled}})
+		require.NoError(t, err)
+		assert.Equal(t, "laptop", selected.Id)
+	})
+}
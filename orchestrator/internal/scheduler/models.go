@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"path"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// SupportedModelFilter removes nodes whose agents don't serve the requested
+// model, e.g. a CPU-only node started with -models "nomic-embed-text,phi3*"
+// never receives llama3:70b
+type SupportedModelFilter struct{}
+
+// NewSupportedModelFilter creates a supported model filter
+func NewSupportedModelFilter() *SupportedModelFilter {
+	return &SupportedModelFilter{}
+}
+
+// Filter returns the nodes that serve the requested model
+func (f *SupportedModelFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	if req == nil || req.Model == "" {
+		return nodes
+	}
+
+	out := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if SupportsModel(n, req.Model) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// SupportsModel reports whether a node serves a model. Nodes that report no
+// supported models serve any model; others serve the models matching one of
+// their patterns, in path.Match syntax, so "*" doesn't cross the "/" of
+// Hugging Face names.
+func SupportsModel(n *pb.Node, model string) bool {
+	if len(n.SupportedModels) == 0 {
+		return true
+	}
+	for _, pattern := range n.SupportedModels {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
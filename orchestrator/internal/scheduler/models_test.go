@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestSupportsModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		supported []string
+		model     string
+		want      bool
+	}{
+		{"no list serves any model", nil, "llama3:70b", true},
+		{"exact name", []string{"nomic-embed-text"}, "nomic-embed-text", true},
+		{"tag wildcard", []string{"phi3*"}, "phi3:mini", true},
+		{"other model", []string{"nomic-embed-text", "phi3*"}, "llama3:70b", false},
+		{"wildcard doesn't cross slashes", []string{"*"}, "mistralai/Mistral-7B-v0.1", false},
+		{"organization wildcard", []string{"mistralai/*"}, "mistralai/Mistral-7B-v0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SupportsModel(&pb.Node{SupportedModels: tt.supported}, tt.model))
+		})
+	}
+}
+
+func TestSupportedModelFilter(t *testing.T) {
+	nodes := []*pb.Node{
+		{Id: "cpu-node", SupportedModels: []string{"nomic-embed-text"}},
+		{Id: "gpu-node"},
+	}
+	filter := NewSupportedModelFilter()
+
+	kept := filter.Filter(&Request{Model: "llama3:70b"}, nodes)
+	assert.Equal(t, []*pb.Node{nodes[1]}, kept)
+
+	// Requests without a model aren't narrowed down
+	assert.Equal(t, nodes, filter.Filter(&Request{}, nodes))
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"math"
+	"math/rand"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/orchestrator/internal/node"
+)
+
+// Filter narrows down the candidate nodes for a request
+type Filter interface {
+	Filter(req *Request, nodes []*pb.Node) []*pb.Node
+}
+
+// Explainer is implemented by filters that can describe why they removed
+// every candidate, e.g. that no node has the VRAM a model needs
+type Explainer interface {
+	Explain(req *Request, nodes []*pb.Node) error
+}
+
+// Scorer rates a candidate node for a request; the node with the highest total score wins
+type Scorer interface {
+	Score(req *Request, node *pb.Node) float64
+}
+
+// tieTolerance is how far below the best combined score a node still ties
+// with it for the tie breaker: 0.1 points, or 10% of scores beyond 1 point.
+// Continuous scores such as BandwidthScorer's transfer times practically
+// never tie exactly.
+const tieTolerance = 0.1
+
+// PipelineScheduler selects nodes by running candidates through a chain of
+// filters and then picking the node with the highest combined scorer result.
+// Exact ties go to the first node in registry order; with a tie breaker, it
+// decides between all the nodes within tieTolerance of the best score.
+type PipelineScheduler struct {
+	filters    []Filter
+	scorers    []Scorer
+	tieBreaker Scorer
+}
+
+// NewPipelineScheduler creates a scheduler from the given filters and scorers
+func NewPipelineScheduler(filters []Filter, scorers []Scorer) *PipelineScheduler {
+	return &PipelineScheduler{
+		filters: filters,
+		scorers: scorers,
+	}
+}
+
+// SetTieBreaker sets a scorer that only decides between the nodes the other
+// scorers rate best, so it can't outweigh them whatever its scale, e.g. a
+// WeightedRandomScorer splitting a model's traffic
+func (s *PipelineScheduler) SetTieBreaker(scorer Scorer) {
+	s.tieBreaker = scorer
+}
+
+// SelectNode selects a node for the given request
+func (s *PipelineScheduler) SelectNode(req *Request, registry node.Registry) (*pb.Node, error) {
+	nodes := withoutExcluded(req, registry.List())
+	if len(nodes) == 0 {
+		return nil, ErrNoNodesAvailable
+	}
+
+	for _, f := range s.filters {
+		kept := f.Filter(req, nodes)
+		if len(kept) == 0 {
+			return nil, explain(f, req, nodes)
+		}
+		nodes = kept
+	}
+
+	scores := make([]float64, len(nodes))
+	best := 0
+	for i, n := range nodes {
+		for _, scorer := range s.scorers {
+			scores[i] += scorer.Score(req, n)
+		}
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	if s.tieBreaker == nil {
+		return nodes[best], nil
+	}
+
+	// Nodes the tie breaker rates equally, e.g. all of a model without
+	// weights, keep the order of their scores
+	band := tieTolerance * math.Max(1, math.Abs(scores[best]))
+	winner, winnerScore := best, s.tieBreaker.Score(req, nodes[best])
+	for i, n := range nodes {
+		if i == best || scores[best]-scores[i] > band {
+			continue
+		}
+		score := s.tieBreaker.Score(req, n)
+		if score > winnerScore || score == winnerScore && scores[i] > scores[winner] {
+			winner, winnerScore = i, score
+		}
+	}
+	return nodes[winner], nil
+}
+
+// explain returns why a filter removed all of nodes, ErrNoNodesAvailable if
+// it can't tell
+func explain(f Filter, req *Request, nodes []*pb.Node) error {
+	if e, ok := f.(Explainer); ok {
+		return e.Explain(req, nodes)
+	}
+	return ErrNoNodesAvailable
+}
+
+// WeightedRandomScorer distributes a model's traffic across nodes according to
+// the per-node weights configured in the model catalog. Each node receives a
+// random key u^(1/w) (weighted reservoir sampling), so picking the highest key
+// selects node i with probability w_i / sum(w).
+// Models without configured weights score every node as 0. Its scores are
+// below 1, so it's set as the pipeline's tie breaker rather than added to
+// other scorers' bonuses, which would decide instead of the weights.
+type WeightedRandomScorer struct {
+	catalog *catalog.Catalog
+	rand    func() float64
+}
+
+// NewWeightedRandomScorer creates a scorer backed by the given model catalog
+func NewWeightedRandomScorer(c *catalog.Catalog) *WeightedRandomScorer {
+	return &WeightedRandomScorer{
+		catalog: c,
+		rand:    rand.Float64,
+	}
+}
+
+// Score returns a random key weighted by the node's share of the model's traffic
+func (s *WeightedRandomScorer) Score(req *Request, n *pb.Node) float64 {
+	weight, ok := s.catalog.NodeWeight(req.Model, n.Id, n.Hostname)
+	if !ok || weight <= 0 {
+		return 0
+	}
+	return math.Pow(s.rand(), 1/weight)
+}
@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+)
+
+// excludeFilter removes nodes with the given ID
+type excludeFilter struct {
+	id string
+}
+
+func (f excludeFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	out := []*pb.Node{}
+	for _, n := range nodes {
+		if n.Id != f.id {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func TestPipelineScheduler_SelectNode(t *testing.T) {
+	registry := &MockRegistry{
+		nodes: []*pb.Node{
+			{Id: "node-1", Hostname: "host-1"},
+			{Id: "node-2", Hostname: "host-2"},
+		},
+	}
+
+	t.Run("no filters or scorers picks first node", func(t *testing.T) {
+		s := NewPipelineScheduler(nil, nil)
+		selected, err := s.SelectNode(&Request{Model: "llama3"}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "node-1", selected.Id)
+	})
+
+	t.Run("filters remove candidates", func(t *testing.T) {
+		s := NewPipelineScheduler([]Filter{excludeFilter{id: "node-1"}}, nil)
+		selected, err := s.SelectNode(&Request{Model: "llama3"}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "node-2", selected.Id)
+	})
+
+	t.Run("excluded nodes are skipped", func(t *testing.T) {
+		s := NewPipelineScheduler(nil, nil)
+		selected, err := s.SelectNode(&Request{Model: "llama3", Exclude: []string{"node-1"}}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "node-2", selected.Id)
+
+		_, err = s.SelectNode(&Request{Model: "llama3", Exclude: []string{"node-1", "node-2"}}, registry)
+		assert.Equal(t, ErrNoNodesAvailable, err)
+	})
+
+	t.Run("all candidates filtered", func(t *testing.T) {
+		s := NewPipelineScheduler([]Filter{excludeFilter{id: "node-1"}, excludeFilter{id: "node-2"}}, nil)
+		_, err := s.SelectNode(&Request{Model: "llama3"}, registry)
+		assert.Equal(t, ErrNoNodesAvailable, err)
+	})
+
+	t.Run("empty registry", func(t *testing.T) {
+		s := NewPipelineScheduler(nil, nil)
+		_, err := s.SelectNode(&Request{Model: "llama3"}, &MockRegistry{})
+		assert.Equal(t, ErrNoNodesAvailable, err)
+	})
+}
+
+func TestWeightedRandomScorer(t *testing.T) {
+	c := catalog.New()
+	c.Models["llama3"] = &catalog.Model{NodeWeights: map[string]float64{
+		"gpu-box": 80,
+		"macbook": 20,
+	}}
+
+	registry := &MockRegistry{
+		nodes: []*pb.Node{
+			{Id: "node-0", Hostname: "unweighted"},
+			{Id: "node-1", Hostname: "gpu-box"},
+			{Id: "node-2", Hostname: "macbook"},
+		},
+	}
+
+	s := NewPipelineScheduler(nil, nil)
+	s.SetTieBreaker(NewWeightedRandomScorer(c))
+
+	t.Run("traffic follows weights", func(t *testing.T) {
+		counts := map[string]int{}
+		const iterations = 10000
+		for i := 0; i < iterations; i++ {
+			selected, err := s.SelectNode(&Request{Model: "llama3"}, registry)
+			require.NoError(t, err)
+			counts[selected.Id]++
+		}
+
+		assert.Zero(t, counts["node-0"], "unweighted nodes should not receive traffic")
+		assert.InDelta(t, 0.8, float64(counts["node-1"])/iterations, 0.03)
+		assert.InDelta(t, 0.2, float64(counts["node-2"])/iterations, 0.03)
+	})
+
+	t.Run("weights split traffic between nodes other scorers rate equally", func(t *testing.T) {
+		labeled := &MockRegistry{nodes: []*pb.Node{
+			{Id: "node-0", Hostname: "unweighted", Labels: map[string]string{"corpus": "nas1"}},
+			{Id: "node-1", Hostname: "gpu-box", Labels: map[string]string{"corpus": "nas1"}},
+			{Id: "node-2", Hostname: "macbook", Labels: map[string]string{"corpus": "nas1"}},
+			{Id: "node-3", Hostname: "elsewhere"},
+		}}
+		s := NewPipelineScheduler(nil, []Scorer{NewLocalityScorer(1)})
+		s.SetTieBreaker(NewWeightedRandomScorer(c))
+		req := &Request{Model: "llama3", Locality: map[string]string{"corpus": "nas1"}}
+
+		counts := map[string]int{}
+		const iterations = 10000
+		for i := 0; i < iterations; i++ {
+			selected, err := s.SelectNode(req, labeled)
+			require.NoError(t, err)
+			counts[selected.Id]++
+		}
+		assert.Zero(t, counts["node-0"])
+		assert.Zero(t, counts["node-3"])
+		assert.InDelta(t, 0.8, float64(counts["node-1"])/iterations, 0.03)
+		assert.InDelta(t, 0.2, float64(counts["node-2"])/iterations, 0.03)
+
+		// A node another scorer prefers wins whatever its weight
+		labeled.nodes[1].Labels = nil
+		for i := 0; i < 100; i++ {
+			selected, err := s.SelectNode(req, labeled)
+			require.NoError(t, err)
+			assert.Equal(t, "node-2", selected.Id)
+		}
+	})
+
+	t.Run("weights split traffic between nodes with about as fast links", func(t *testing.T) {
+		c := catalog.New()
+		c.Models["nomic-embed-text"] = &catalog.Model{NodeWeights: map[string]float64{
+			"gpu-box":     80,
+			"macbook":     20,
+			"wifi-laptop": 1000,
+		}}
+		network := NewNetworkTracker()
+		network.Observe("node-1", &pb.NetworkStats{RttMs: 2, UploadMbps: 900})
+		network.Observe("node-2", &pb.NetworkStats{RttMs: 3, UploadMbps: 500})
+		network.Observe("node-3", &pb.NetworkStats{RttMs: 20, UploadMbps: 5})
+		nodes := &MockRegistry{nodes: []*pb.Node{
+			{Id: "node-1", Hostname: "gpu-box"},
+			{Id: "node-2", Hostname: "macbook"},
+			{Id: "node-3", Hostname: "wifi-laptop"},
+		}}
+		s := NewPipelineScheduler(nil, []Scorer{NewBandwidthScorer(network, DefaultBandwidthMinBytes)})
+		s.SetTieBreaker(NewWeightedRandomScorer(c))
+		req := &Request{Model: "nomic-embed-text", Kind: KindEmbeddings, PayloadBytes: 1024}
+
+		counts := map[string]int{}
+		const iterations = 10000
+		for i := 0; i < iterations; i++ {
+			selected, err := s.SelectNode(req, nodes)
+			require.NoError(t, err)
+			counts[selected.Id]++
+		}
+		assert.Zero(t, counts["node-3"], "a much slower link loses whatever its weight")
+		assert.InDelta(t, 0.8, float64(counts["node-1"])/iterations, 0.03)
+		assert.InDelta(t, 0.2, float64(counts["node-2"])/iterations, 0.03)
+	})
+
+	t.Run("models without weights keep registry order", func(t *testing.T) {
+		selected, err := s.SelectNode(&Request{Model: "mistral"}, registry)
+		require.NoError(t, err)
+		assert.Equal(t, "node-0", selected.Id)
+	})
+
+	t.Run("deterministic random source", func(t *testing.T) {
+		scorer := NewWeightedRandomScorer(c)
+		scorer.rand = func() float64 { return 0.5 }
+
+		assert.Equal(t, 0.0, scorer.Score(&Request{Model: "llama3"}, registry.nodes[0]))
+		assert.Greater(t, scorer.Score(&Request{Model: "llama3"}, registry.nodes[1]), scorer.Score(&Request{Model: "llama3"}, registry.nodes[2]))
+	})
+}
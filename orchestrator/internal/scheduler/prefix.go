@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+const (
+	// DefaultPrefixAffinityTTL is how long a prompt cache key stays pinned to a
+	// node after its last request
+	DefaultPrefixAffinityTTL = 10 * time.Minute
+
+	// maxPrefixAffinityEntries bounds the number of tracked prompt cache keys
+	maxPrefixAffinityEntries = 10000
+)
+
+// PrefixCacheStats summarizes prompt prefix caching across the cluster
+type PrefixCacheStats struct {
+	Requests     int64   `json:"requests"`      // Requests that declared a prompt cache key
+	StickyRoutes int64   `json:"sticky_routes"` // Requests routed to the node that served the key before
+	PromptTokens int64   `json:"prompt_tokens"` // Prompt tokens reported by engines
+	CachedTokens int64   `json:"cached_tokens"` // Prompt tokens served from engine prefix caches
+	HitRate      float64 `json:"hit_rate"`      // CachedTokens / PromptTokens
+}
+
+// PrefixAffinity pins requests sharing a prompt cache key to the node that
+// served the key last, so the engine's prefix cache stays warm across turns.
+// It also records how much of the prompt engines actually served from cache.
+type PrefixAffinity struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]prefixEntry
+	stats   PrefixCacheStats
+	now     func() time.Time
+}
+
+type prefixEntry struct {
+	nodeID   string
+	lastUsed time.Time
+}
+
+// NewPrefixAffinity creates a prefix affinity tracker that forgets keys unused for ttl
+func NewPrefixAffinity(ttl time.Duration) *PrefixAffinity {
+	return &PrefixAffinity{
+		ttl:     ttl,
+		entries: make(map[string]prefixEntry),
+		now:     time.Now,
+	}
+}
+
+// Score returns 1 for the node that last served the request's prompt cache key, 0 otherwise
+func (a *PrefixAffinity) Score(req *Request, n *pb.Node) float64 {
+	if req.CacheKey == "" {
+		return 0
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if entry, ok := a.liveEntryLocked(req.CacheKey); ok && entry.nodeID == n.Id {
+		return 1
+	}
+	return 0
+}
+
+// Record notes that a request with the given prompt cache key was routed to a node
+func (a *PrefixAffinity) Record(key, nodeID string) {
+	if key == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats.Requests++
+	if entry, ok := a.liveEntryLocked(key); ok && entry.nodeID == nodeID {
+		a.stats.StickyRoutes++
+	}
+
+	a.entries[key] = prefixEntry{nodeID: nodeID, lastUsed: a.now()}
+	if len(a.entries) > maxPrefixAffinityEntries {
+		a.evictLocked()
+	}
+}
+
+// ObserveUsage records the prompt tokens of a completed request and how many
+// of them the engine served from its prefix cache
+func (a *PrefixAffinity) ObserveUsage(promptTokens, cachedTokens int32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stats.PromptTokens += int64(promptTokens)
+	a.stats.CachedTokens += int64(cachedTokens)
+}
+
+// Stats returns the prefix caching statistics collected so far
+func (a *PrefixAffinity) Stats() PrefixCacheStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := a.stats
+	if stats.PromptTokens > 0 {
+		stats.HitRate = float64(stats.CachedTokens) / float64(stats.PromptTokens)
+	}
+	return stats
+}
+
+// liveEntryLocked returns the entry for a key if it hasn't expired.
+// Callers must hold a.mu.
+func (a *PrefixAffinity) liveEntryLocked(key string) (prefixEntry, bool) {
+	entry, ok := a.entries[key]
+	if !ok || a.now().Sub(entry.lastUsed) > a.ttl {
+		return prefixEntry{}, false
+	}
+	return entry, true
+}
+
+// evictLocked drops expired entries, then the least recently used one if the
+// tracker is still full. Callers must hold a.mu.
+func (a *PrefixAffinity) evictLocked() {
+	now := a.now()
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range a.entries {
+		if now.Sub(entry.lastUsed) > a.ttl {
+			delete(a.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, entry.lastUsed
+		}
+	}
+
+	if len(a.entries) > maxPrefixAffinityEntries {
+		delete(a.entries, oldestKey)
+	}
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestPrefixAffinity(t *testing.T) {
+	now := time.Unix(1000, 0)
+	affinity := NewPrefixAffinity(time.Minute)
+	affinity.now = func() time.Time { return now }
+
+	gpuA := &pb.Node{Id: "gpu-a"}
+	gpuB := &pb.Node{Id: "gpu-b"}
+	req := &Request{Model: "llama3", Kind: KindChatCompletion, CacheKey: "conversation-42"}
+
+	// Unknown keys and requests without a key don't prefer any node
+	assert.Equal(t, 0.0, affinity.Score(req, gpuA))
+	assert.Equal(t, 0.0, affinity.Score(&Request{Model: "llama3"}, gpuA))
+
+	affinity.Record("conversation-42", "gpu-a")
+	assert.Equal(t, 1.0, affinity.Score(req, gpuA))
+	assert.Equal(t, 0.0, affinity.Score(req, gpuB))
+
+	// Following turns on the same node count as sticky routes
+	now = now.Add(30 * time.Second)
+	affinity.Record("conversation-42", "gpu-a")
+	affinity.Record("conversation-7", "gpu-b")
+	affinity.Record("", "gpu-b")
+
+	// Keys expire once unused for the TTL
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, 0.0, affinity.Score(req, gpuA))
+	affinity.Record("conversation-42", "gpu-a")
+
+	affinity.ObserveUsage(100, 0)
+	affinity.ObserveUsage(100, 80)
+
+	assert.Equal(t, PrefixCacheStats{
+		Requests:     4,
+		StickyRoutes: 1,
+		PromptTokens: 200,
+		CachedTokens: 80,
+		HitRate:      0.4,
+	}, affinity.Stats())
+}
+
+func TestPrefixAffinity_Evict(t *testing.T) {
+	now := time.Unix(1000, 0)
+	affinity := NewPrefixAffinity(time.Minute)
+	affinity.now = func() time.Time { return now }
+
+	for i := 0; i <= maxPrefixAffinityEntries; i++ {
+		now = now.Add(time.Millisecond)
+		affinity.Record(fmt.Sprintf("key-%d", i), "gpu-a")
+	}
+
+	assert.Len(t, affinity.entries, maxPrefixAffinityEntries)
+	// The least recently used key was dropped
+	assert.NotContains(t, affinity.entries, "key-0")
+	assert.Contains(t, affinity.entries, "key-1")
+}
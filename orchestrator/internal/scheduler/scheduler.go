@@ -5,12 +5,63 @@ import (
 	"github.com/Orchion/Orchion/orchestrator/internal/node"
 )
 
+// RequestKind describes the type of work a node is being selected for
+type RequestKind int
+
+const (
+	KindUnspecified RequestKind = iota
+	KindChatCompletion
+	KindEmbeddings
+	KindSpeech
+)
+
+// MaxNodeAttempts is how many nodes a request is tried on while nodes reject
+// it with a retryable error, e.g. because it wouldn't fit in free VRAM. Each
+// retry excludes the nodes that rejected it.
+const MaxNodeAttempts = 3
+
+// Request describes the work a node is being selected for
+type Request struct {
+	Model    string
+	Kind     RequestKind
+	CacheKey string   // Client-declared stable prompt prefix, if any
+	Exclude  []string // Node IDs not to select, e.g. nodes that already rejected the request
+
+	// PayloadBytes estimates the bytes sent to and from the node, e.g.
+	// embedding inputs and vectors; 0 if unknown
+	PayloadBytes int64
+
+	// Locality are the node labels marking where the request's data is,
+	// e.g. corpus=nas1
+	Locality map[string]string
+}
+
+// withoutExcluded drops the nodes a request excludes
+func withoutExcluded(req *Request, nodes []*pb.Node) []*pb.Node {
+	if req == nil || len(req.Exclude) == 0 {
+		return nodes
+	}
+
+	excluded := make(map[string]bool, len(req.Exclude))
+	for _, id := range req.Exclude {
+		excluded[id] = true
+	}
+	kept := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if !excluded[n.Id] {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
 // Scheduler selects nodes for model execution
 type Scheduler interface {
-	SelectNode(model string, registry node.Registry) (*pb.Node, error)
+	SelectNode(req *Request, registry node.Registry) (*pb.Node, error)
 }
 
 // SimpleScheduler is a basic scheduler that selects the first available node
+// serving the requested model
 type SimpleScheduler struct{}
 
 // NewSimpleScheduler creates a new simple scheduler
@@ -18,20 +69,19 @@ func NewSimpleScheduler() *SimpleScheduler {
 	return &SimpleScheduler{}
 }
 
-// SelectNode selects a node for the given model
-// For now, it just picks the first available node
-// TODO: Enhance to consider node capabilities, load, and model availability
-func (s *SimpleScheduler) SelectNode(model string, registry node.Registry) (*pb.Node, error) {
-	nodes := registry.List()
+// SelectNode selects a node for the given request
+// For now, it just picks the first node serving the model
+// TODO: Enhance to consider node load
+func (s *SimpleScheduler) SelectNode(req *Request, registry node.Registry) (*pb.Node, error) {
+	nodes := NewSupportedModelFilter().Filter(req, withoutExcluded(req, registry.List()))
 	if len(nodes) == 0 {
 		return nil, ErrNoNodesAvailable
 	}
 
 	// For now, return the first node
 	// In the future, this should:
-	// 1. Filter nodes by model capability
-	// 2. Consider node load/availability
-	// 3. Use load balancing strategies
+	// 1. Consider node load/availability
+	// 2. Use load balancing strategies
 	return nodes[0], nil
 }
 
@@ -24,10 +24,18 @@ func (m *MockRegistry) UpdateCapabilities(nodeID string, capabilities *pb.Capabi
 	return nil
 }
 
+func (m *MockRegistry) UpdateModels(nodeID string, models []*pb.ModelEngine) error {
+	return nil
+}
+
 func (m *MockRegistry) UpdateHeartbeat(nodeID string) error {
 	return nil
 }
 
+func (m *MockRegistry) UpdateThrottle(nodeID string, throttle *pb.NodeThrottle) error {
+	return nil
+}
+
 func (m *MockRegistry) List() []*pb.Node {
 	return m.nodes
 }
@@ -55,6 +63,10 @@ func (m *MockRegistry) CheckHeartbeats(timeout time.Duration) []string {
 	return []string{}
 }
 
+func (m *MockRegistry) Annotate(nodeID string, notes string, annotations map[string]string) error {
+	return nil
+}
+
 func TestNewSimpleScheduler(t *testing.T) {
 	scheduler := NewSimpleScheduler()
 	assert.NotNil(t, scheduler)
@@ -72,7 +84,7 @@ func TestSimpleScheduler_SelectNode(t *testing.T) {
 			},
 		}
 
-		selectedNode, err := scheduler.SelectNode("llama2", mockRegistry)
+		selectedNode, err := scheduler.SelectNode(&Request{Model: "llama2"}, mockRegistry)
 
 		require.NoError(t, err)
 		assert.NotNil(t, selectedNode)
@@ -88,7 +100,7 @@ func TestSimpleScheduler_SelectNode(t *testing.T) {
 			},
 		}
 
-		selectedNode, err := scheduler.SelectNode("gpt-3", mockRegistry)
+		selectedNode, err := scheduler.SelectNode(&Request{Model: "gpt-3"}, mockRegistry)
 
 		require.NoError(t, err)
 		assert.NotNil(t, selectedNode)
@@ -96,12 +108,29 @@ func TestSimpleScheduler_SelectNode(t *testing.T) {
 		assert.Equal(t, "single-host", selectedNode.Hostname)
 	})
 
+	t.Run("nodes not serving the model are skipped", func(t *testing.T) {
+		mockRegistry := &MockRegistry{
+			nodes: []*pb.Node{
+				{Id: "cpu-node", SupportedModels: []string{"nomic-embed-text", "phi3*"}},
+				{Id: "gpu-node"},
+			},
+		}
+
+		selectedNode, err := scheduler.SelectNode(&Request{Model: "llama3:70b"}, mockRegistry)
+		require.NoError(t, err)
+		assert.Equal(t, "gpu-node", selectedNode.Id)
+
+		selectedNode, err = scheduler.SelectNode(&Request{Model: "phi3:mini"}, mockRegistry)
+		require.NoError(t, err)
+		assert.Equal(t, "cpu-node", selectedNode.Id)
+	})
+
 	t.Run("no nodes available", func(t *testing.T) {
 		mockRegistry := &MockRegistry{
 			nodes: []*pb.Node{}, // Empty registry
 		}
 
-		selectedNode, err := scheduler.SelectNode("any-model", mockRegistry)
+		selectedNode, err := scheduler.SelectNode(&Request{Model: "any-model"}, mockRegistry)
 
 		assert.Error(t, err)
 		assert.Nil(t, selectedNode)
@@ -113,7 +142,7 @@ func TestSimpleScheduler_SelectNode(t *testing.T) {
 			nodes: nil, // Nil slice
 		}
 
-		selectedNode, err := scheduler.SelectNode("model", mockRegistry)
+		selectedNode, err := scheduler.SelectNode(&Request{Model: "model"}, mockRegistry)
 
 		assert.Error(t, err)
 		assert.Nil(t, selectedNode)
@@ -144,7 +173,7 @@ func BenchmarkSimpleScheduler_SelectNode(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = scheduler.SelectNode("benchmark-model", mockRegistry)
+		_, _ = scheduler.SelectNode(&Request{Model: "benchmark-model"}, mockRegistry)
 	}
 }
 
@@ -156,6 +185,6 @@ func BenchmarkSimpleScheduler_SelectNode_EmptyRegistry(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = scheduler.SelectNode("benchmark-model", mockRegistry)
+		_, _ = scheduler.SelectNode(&Request{Model: "benchmark-model"}, mockRegistry)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// ThrottleFilter keeps requests off nodes whose agents throttle them because
+// their GPU runs hot. Throttled nodes are only candidates when every node is
+// throttled; their agents then take what their reduced concurrency allows and
+// reject the rest with a retryable error.
+type ThrottleFilter struct{}
+
+// NewThrottleFilter creates a throttle filter
+func NewThrottleFilter() *ThrottleFilter {
+	return &ThrottleFilter{}
+}
+
+// Filter returns the nodes that aren't throttled, or all nodes if every one is
+func (f *ThrottleFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	out := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Throttle == nil {
+			out = append(out, n)
+		}
+	}
+	if len(out) == 0 {
+		return nodes
+	}
+	return out
+}
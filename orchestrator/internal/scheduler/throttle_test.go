@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestThrottleFilter(t *testing.T) {
+	hot := &pb.Node{Id: "hot", Throttle: &pb.NodeThrottle{Reason: "GPU temperature 88°C over 85°C limit"}}
+	cool := &pb.Node{Id: "cool"}
+	filter := NewThrottleFilter()
+
+	assert.Equal(t, []*pb.Node{cool}, filter.Filter(&Request{Model: "llama3"}, []*pb.Node{hot, cool}))
+
+	// With every node throttled, the agents shed what they can't take
+	assert.Equal(t, []*pb.Node{hot}, filter.Filter(&Request{Model: "llama3"}, []*pb.Node{hot}))
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"fmt"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+	"github.com/Orchion/Orchion/shared/units"
+)
+
+// VRAMFilter keeps models off nodes without the free GPU memory to load
+// them, as given by vram_mb in the model catalog. Nodes with the model
+// loaded already are kept, since it fits there. Nodes not reporting their
+// free VRAM, e.g. CPU-only ones, can't load models with a requirement.
+// Distributed models span several nodes and are left to their deployment.
+type VRAMFilter struct {
+	catalog *catalog.Catalog
+}
+
+// NewVRAMFilter creates a filter backed by the given model catalog
+func NewVRAMFilter(c *catalog.Catalog) *VRAMFilter {
+	return &VRAMFilter{catalog: c}
+}
+
+// Filter returns the nodes the requested model fits on
+func (f *VRAMFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	required, ok := f.required(req.Model)
+	if !ok {
+		return nodes
+	}
+
+	out := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if modelLoaded(n, req.Model) {
+			out = append(out, n)
+			continue
+		}
+		if free, ok := FreeVRAMMB(n); ok && free >= required {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Explain describes why the requested model fits on none of the nodes
+func (f *VRAMFilter) Explain(req *Request, nodes []*pb.Node) error {
+	required, _ := f.required(req.Model)
+	err := &InsufficientVRAMError{Model: req.Model, RequiredMB: required}
+	for _, n := range nodes {
+		if free, ok := FreeVRAMMB(n); ok && (err.Node == "" || free > err.FreeMB) {
+			err.Node, err.FreeMB = n.Id, free
+		}
+	}
+	return err
+}
+
+// required returns the VRAM in MB a node needs free to load model
+func (f *VRAMFilter) required(model string) (float64, bool) {
+	m, ok := f.catalog.Get(model)
+	if !ok || m.VRAMMB <= 0 || m.Distributed != nil {
+		return 0, false
+	}
+	return m.VRAMMB, true
+}
+
+// FreeVRAMMB returns the free VRAM in MB a node reports, if any
+func FreeVRAMMB(n *pb.Node) (float64, bool) {
+	return units.ParseMegabytes(n.GetCapabilities().GetGpuVramAvailable())
+}
+
+// modelLoaded reports whether a node has model loaded
+func modelLoaded(n *pb.Node, model string) bool {
+	for _, m := range n.Models {
+		if m.Model == model {
+			return true
+		}
+	}
+	return false
+}
+
+// InsufficientVRAMError is returned when no candidate node has enough free
+// VRAM to load a model. It matches ErrNoNodesAvailable with errors.Is.
+type InsufficientVRAMError struct {
+	Model      string
+	RequiredMB float64
+	Node       string  // Candidate with the most free VRAM; empty if none reports any
+	FreeMB     float64 // Free VRAM on Node
+}
+
+func (e *InsufficientVRAMError) Error() string {
+	if e.Node == "" {
+		return fmt.Sprintf("%s needs %.0f MB of free VRAM but no node reports any", e.Model, e.RequiredMB)
+	}
+	return fmt.Sprintf("%s needs %.0f MB of free VRAM but the most free is %.0f MB on node %s",
+		e.Model, e.RequiredMB, e.FreeMB, e.Node)
+}
+
+// Is reports whether target is ErrNoNodesAvailable
+func (e *InsufficientVRAMError) Is(target error) bool {
+	return target == ErrNoNodesAvailable
+}
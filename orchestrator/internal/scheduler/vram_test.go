@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/catalog"
+)
+
+func TestVRAMFilter(t *testing.T) {
+	c := catalog.New()
+	c.Models["llama3:70b"] = &catalog.Model{VRAMMB: 42000}
+	c.Models["llama3:405b"] = &catalog.Model{VRAMMB: 240000, Distributed: &catalog.Distributed{Nodes: 4}}
+
+	nodes := []*pb.Node{
+		{Id: "laptop", Capabilities: &pb.Capabilities{GpuVramAvailable: "6 GB"}},
+		{Id: "gpu-box", Capabilities: &pb.Capabilities{GpuVramAvailable: "46080 MB"}},
+		{Id: "loaded", Capabilities: &pb.Capabilities{GpuVramAvailable: "2 GB"}, Models: []*pb.ModelEngine{
+			{Model: "llama3:70b", Engine: "ollama"},
+		}},
+		{Id: "cpu-only"},
+	}
+	f := NewVRAMFilter(c)
+
+	t.Run("drops nodes without enough free VRAM", func(t *testing.T) {
+		filtered := f.Filter(&Request{Model: "llama3:70b"}, nodes)
+		ids := make([]string, len(filtered))
+		for i, n := range filtered {
+			ids[i] = n.Id
+		}
+		assert.Equal(t, []string{"gpu-box", "loaded"}, ids)
+	})
+
+	t.Run("models without a requirement keep every node", func(t *testing.T) {
+		assert.Len(t, f.Filter(&Request{Model: "phi3"}, nodes), len(nodes))
+		assert.Len(t, f.Filter(&Request{Model: "llama3:405b"}, nodes), len(nodes))
+	})
+
+	t.Run("explains which node came closest", func(t *testing.T) {
+		s := NewPipelineScheduler([]Filter{f}, nil)
+		_, err := s.SelectNode(&Request{Model: "llama3:70b"}, &MockRegistry{nodes: []*pb.Node{nodes[0], nodes[3]}})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrNoNodesAvailable))
+
+		var vramErr *InsufficientVRAMError
+		require.True(t, errors.As(err, &vramErr))
+		assert.Equal(t, "laptop", vramErr.Node)
+		assert.Equal(t, "llama3:70b needs 42000 MB of free VRAM but the most free is 6144 MB on node laptop", err.Error())
+
+		_, err = s.SelectNode(&Request{Model: "llama3:70b"}, &MockRegistry{nodes: []*pb.Node{nodes[3]}})
+		assert.EqualError(t, err, "llama3:70b needs 42000 MB of free VRAM but no node reports any")
+	})
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"time"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+// WarmupFilter keeps requests off nodes for a warm-up period after they
+// register, e.g. after their agent restarted, so traffic doesn't pile onto
+// their cold engines. A node reporting the requested model loaded, e.g. from
+// a warm pool or replica preload, is warm for it right away. Warming nodes
+// are only candidates when no node is warm, so requests still run while a
+// whole fleet starts.
+type WarmupFilter struct {
+	period time.Duration
+}
+
+// NewWarmupFilter creates a warm-up filter; a period of 0 disables it
+func NewWarmupFilter(period time.Duration) *WarmupFilter {
+	return &WarmupFilter{period: period}
+}
+
+// Filter returns the nodes that are warm for the requested model, or all
+// nodes if none is
+func (f *WarmupFilter) Filter(req *Request, nodes []*pb.Node) []*pb.Node {
+	if f.period <= 0 {
+		return nodes
+	}
+
+	now := time.Now()
+	out := make([]*pb.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if f.warm(n, req.Model, now) {
+			out = append(out, n)
+		}
+	}
+	if len(out) == 0 {
+		return nodes
+	}
+	return out
+}
+
+// warm reports whether a node's warm-up period is over or it has the model loaded
+func (f *WarmupFilter) warm(n *pb.Node, model string, now time.Time) bool {
+	if now.Sub(time.Unix(n.RegisteredUnix, 0)) >= f.period {
+		return true
+	}
+	for _, m := range n.Models {
+		if m.Model == model {
+			return true
+		}
+	}
+	return false
+}
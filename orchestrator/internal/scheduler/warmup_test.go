@@ -0,0 +1,30 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+)
+
+func TestWarmupFilter(t *testing.T) {
+	now := time.Now()
+	fresh := &pb.Node{Id: "fresh", RegisteredUnix: now.Unix()}
+	preloaded := &pb.Node{Id: "preloaded", RegisteredUnix: now.Unix(), Models: []*pb.ModelEngine{{Model: "llama3", Engine: "ollama"}}}
+	old := &pb.Node{Id: "old", RegisteredUnix: now.Add(-time.Minute).Unix()}
+	filter := NewWarmupFilter(30 * time.Second)
+	req := &Request{Model: "llama3", Kind: KindChatCompletion}
+
+	assert.Equal(t, []*pb.Node{preloaded, old}, filter.Filter(req, []*pb.Node{fresh, preloaded, old}))
+
+	// Nodes preloading another model are still warming up
+	assert.Equal(t, []*pb.Node{old}, filter.Filter(&Request{Model: "phi3"}, []*pb.Node{fresh, preloaded, old}))
+
+	// With every node warming up, requests still run
+	assert.Equal(t, []*pb.Node{fresh}, filter.Filter(req, []*pb.Node{fresh}))
+
+	// A period of 0 disables warm-up
+	assert.Equal(t, []*pb.Node{fresh, old}, NewWarmupFilter(0).Filter(req, []*pb.Node{fresh, old}))
+}
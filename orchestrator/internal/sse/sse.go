@@ -0,0 +1,79 @@
+// Package sse writes Server-Sent Events to clients that may read slowly
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultWriteTimeout is how long a client may take to accept one event
+// before its stream is dropped
+const DefaultWriteTimeout = 30 * time.Second
+
+// ErrStreamingUnsupported is returned for responses that can't be flushed
+var ErrStreamingUnsupported = errors.New("streaming not supported")
+
+// Writer writes events to a stream with a deadline on each one, so a client
+// that stops reading fails the stream instead of blocking its writer, and
+// whatever feeds it, indefinitely. Once a write fails, the Writer discards
+// further events and keeps returning the error.
+type Writer struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration // Zero disables write deadlines
+	err     error
+}
+
+// NewWriter sets the SSE headers on w and returns a writer for its events;
+// ErrStreamingUnsupported if w can't be flushed
+func NewWriter(w http.ResponseWriter, timeout time.Duration) (*Writer, error) {
+	if _, ok := w.(http.Flusher); !ok {
+		return nil, ErrStreamingUnsupported
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	return &Writer{w: w, rc: http.NewResponseController(w), timeout: timeout}, nil
+}
+
+// Printf writes formatted event text, sent with the next Flush
+func (s *Writer) Printf(format string, args ...interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.extendDeadline()
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Flush sends the events written so far, failing if the client doesn't
+// accept them within the write timeout
+func (s *Writer) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.extendDeadline()
+	if err := s.rc.Flush(); err != nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Err returns the error that failed the stream, if any
+func (s *Writer) Err() error {
+	return s.err
+}
+
+// extendDeadline gives the next write the full timeout. Deadlines are set
+// before every write, so one left over from an earlier event never cuts off a
+// stream that was merely idle. Writers that don't support deadlines, e.g. in
+// tests, write without one.
+func (s *Writer) extendDeadline() {
+	if s.timeout > 0 {
+		s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+}
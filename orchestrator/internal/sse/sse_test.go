@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	events, err := NewWriter(rec, time.Second)
+	require.NoError(t, err)
+
+	require.NoError(t, events.Printf("data: %s\n\n", "hello"))
+	require.NoError(t, events.Flush())
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "data: hello\n\n", rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
+
+func TestWriter_SlowClient(t *testing.T) {
+	failed := make(chan error, 1)
+	idleOK := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events, err := NewWriter(w, 100*time.Millisecond)
+		require.NoError(t, err)
+
+		// A stream idle for longer than the timeout keeps working
+		events.Printf(": hello\n\n")
+		events.Flush()
+		time.Sleep(200 * time.Millisecond)
+		events.Printf(": still here\n\n")
+		idleOK <- events.Flush()
+
+		// Once the client stops reading, writes fail instead of blocking
+		chunk := strings.Repeat("x", 64<<10)
+		for {
+			events.Printf("data: %s\n\n", chunk)
+			if err := events.Flush(); err != nil {
+				failed <- err
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": hello\n", line)
+
+	require.NoError(t, <-idleOK)
+	select {
+	case err := <-failed:
+		assert.Error(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("write to a client that stopped reading never failed")
+	}
+}
+
+func TestNewWriter_Unsupported(t *testing.T) {
+	_, err := NewWriter(struct{ http.ResponseWriter }{httptest.NewRecorder()}, time.Second)
+	assert.ErrorIs(t, err, ErrStreamingUnsupported)
+}
@@ -0,0 +1,53 @@
+// Package transport holds the gRPC message size and flow-control settings
+// shared by the orchestrator's server and its clients (the gateway calling the
+// orchestrator, the orchestrator calling node agents)
+package transport
+
+import (
+	"google.golang.org/grpc"
+)
+
+// DefaultMaxMessageSize is the default limit on gRPC messages in either
+// direction. gRPC's own 4MB receive limit is too small for large embedding
+// batches.
+const DefaultMaxMessageSize = 16 << 20
+
+// minWindowSize is the smallest flow-control window gRPC accepts; smaller
+// values are ignored
+const minWindowSize = 64 << 10
+
+// Config tunes gRPC transports
+type Config struct {
+	// MaxMessageSize limits messages sent and received, in bytes
+	MaxMessageSize int
+	// InitialWindowSize sets the per-stream and per-connection flow-control
+	// windows in bytes. Zero keeps gRPC's dynamic window sizing.
+	InitialWindowSize int32
+}
+
+// ServerOptions returns options applying the config to a gRPC server
+func (c Config) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if c.MaxMessageSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxMessageSize), grpc.MaxSendMsgSize(c.MaxMessageSize))
+	}
+	if c.InitialWindowSize >= minWindowSize {
+		opts = append(opts, grpc.InitialWindowSize(c.InitialWindowSize), grpc.InitialConnWindowSize(c.InitialWindowSize))
+	}
+	return opts
+}
+
+// DialOptions returns options applying the config to a gRPC client
+func (c Config) DialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if c.MaxMessageSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(c.MaxMessageSize),
+			grpc.MaxCallSendMsgSize(c.MaxMessageSize),
+		))
+	}
+	if c.InitialWindowSize >= minWindowSize {
+		opts = append(opts, grpc.WithInitialWindowSize(c.InitialWindowSize), grpc.WithInitialConnWindowSize(c.InitialWindowSize))
+	}
+	return opts
+}
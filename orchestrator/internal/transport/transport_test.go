@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/orchestrator/internal/llm"
+)
+
+// bigEmbedder returns a 256-dimension embedding (about 1KB) per input
+type bigEmbedder struct {
+	pb.UnimplementedNodeAgentServer
+}
+
+func (bigEmbedder) Embeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pb.EmbeddingResponse, error) {
+	resp := &pb.EmbeddingResponse{Model: req.Model}
+	for i := range req.Input {
+		resp.Data = append(resp.Data, &pb.Embedding{Index: int32(i), Embedding: make([]float32, 256)})
+	}
+	return resp, nil
+}
+
+func embed(t *testing.T, server, client Config, inputs int) (*pb.EmbeddingResponse, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer(server.ServerOptions()...)
+	pb.RegisterNodeAgentServer(srv, bigEmbedder{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, client.DialOptions()...)
+	conn, err := grpc.NewClient(lis.Addr().String(), opts...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return pb.NewNodeAgentClient(conn).Embeddings(ctx, &pb.EmbeddingRequest{Model: "m", Input: make([]string, inputs)})
+}
+
+func TestConfig_MaxMessageSize(t *testing.T) {
+	small := Config{MaxMessageSize: 64 << 10}
+	large := Config{MaxMessageSize: 1 << 20}
+
+	// About 128KB of embeddings
+	_, err := embed(t, large, small, 128)
+	assert.True(t, llm.IsMessageTooLarge(err), "client receive limit: %v", err)
+
+	_, err = embed(t, small, large, 128)
+	assert.True(t, llm.IsMessageTooLarge(err), "server send limit: %v", err)
+
+	resp, err := embed(t, large, large, 128)
+	require.NoError(t, err)
+	assert.Len(t, resp.Data, 128)
+}
+
+func TestConfig_Options(t *testing.T) {
+	assert.Empty(t, Config{}.ServerOptions())
+	assert.Empty(t, Config{}.DialOptions())
+
+	// Windows below gRPC's minimum are left to gRPC
+	assert.Len(t, Config{MaxMessageSize: 1 << 20, InitialWindowSize: 1024}.ServerOptions(), 2)
+	assert.Len(t, Config{MaxMessageSize: 1 << 20, InitialWindowSize: 1 << 20}.ServerOptions(), 4)
+	assert.Len(t, Config{InitialWindowSize: 1 << 20}.DialOptions(), 2)
+}
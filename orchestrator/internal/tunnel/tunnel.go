@@ -0,0 +1,182 @@
+// Package tunnel accepts reverse connections from node agents behind NAT. An
+// agent dials the tunnel port and announces its node ID; the orchestrator then
+// runs its NodeAgent gRPC client over that connection, so HTTP/2 multiplexes
+// requests over it exactly as if the orchestrator had dialed the agent.
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+// Handshake lines. The agent sends Greeting followed by its node ID and a
+// newline. The orchestrator answers "ERR <reason>" right away if it rejects the
+// tunnel, or "OK" once a gRPC client takes the connection, at which point the
+// agent starts serving on it. Until then the agent keeps the connection open
+// and idle.
+const (
+	Greeting         = "ORCHION-TUNNEL/1 "
+	handshakeTimeout = 10 * time.Second
+	maxLineLength    = 512
+)
+
+// ErrClosed is returned by Dial once the server is closed
+var ErrClosed = errors.New("tunnel server closed")
+
+// Server keeps the latest unused tunnel connection per node until the node's
+// gRPC client dials it
+type Server struct {
+	logger logging.Logger
+
+	mu      sync.Mutex
+	pending map[string]net.Conn
+	ready   map[string]chan struct{} // closed when a connection becomes pending
+	closed  bool
+}
+
+// NewServer creates a tunnel server
+func NewServer(logger logging.Logger) *Server {
+	return &Server{
+		logger:  logger,
+		pending: make(map[string]net.Conn),
+		ready:   make(map[string]chan struct{}),
+	}
+}
+
+// Target returns the gRPC target that Dial resolves to the node's tunnel
+func Target(nodeID string) string {
+	return "passthrough:///" + nodeID
+}
+
+// Serve accepts tunnel connections until the listener is closed
+func (s *Server) Serve(lis net.Listener) error {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handshake(conn)
+	}
+}
+
+// handshake reads the agent's node ID and parks the connection for Dial
+func (s *Server) handshake(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	line, err := readLine(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	nodeID, ok := strings.CutPrefix(line, Greeting)
+	nodeID = strings.TrimSpace(nodeID)
+	if !ok || nodeID == "" {
+		fmt.Fprintf(conn, "ERR expected %q followed by a node ID\n", Greeting)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	if !s.park(nodeID, conn) {
+		conn.Close()
+		return
+	}
+	s.logger.Debug("Node agent tunnel connected", map[string]interface{}{
+		"node_id":     nodeID,
+		"remote_addr": conn.RemoteAddr().String(),
+	})
+}
+
+// readLine reads a handshake line a byte at a time, so nothing after it is
+// consumed before gRPC takes over the connection
+func readLine(conn net.Conn) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for len(line) < maxLineLength {
+		if _, err := conn.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, buf[0])
+	}
+	return "", errors.New("handshake line too long")
+}
+
+// park makes conn the node's pending connection, replacing an unused one
+func (s *Server) park(nodeID string, conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if old, ok := s.pending[nodeID]; ok {
+		old.Close()
+	}
+	s.pending[nodeID] = conn
+	if ready, ok := s.ready[nodeID]; ok {
+		close(ready)
+		delete(s.ready, nodeID)
+	}
+	return true
+}
+
+// Dial hands out the node's pending tunnel connection, waiting for the agent
+// to connect if there is none. It has the signature of a gRPC context dialer
+// for targets created by Target.
+func (s *Server) Dial(ctx context.Context, nodeID string) (net.Conn, error) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if conn, ok := s.pending[nodeID]; ok {
+			delete(s.pending, nodeID)
+			s.mu.Unlock()
+			// A tunnel may have died while parked; wait for the agent's next one
+			if _, err := fmt.Fprint(conn, "OK\n"); err != nil {
+				conn.Close()
+				continue
+			}
+			return conn, nil
+		}
+		ready, ok := s.ready[nodeID]
+		if !ok {
+			ready = make(chan struct{})
+			s.ready[nodeID] = ready
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("node %s has no tunnel connection: %w", nodeID, ctx.Err())
+		}
+	}
+}
+
+// Close closes pending connections and fails waiting and future dials.
+// Connections already handed out belong to their gRPC clients.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for nodeID, conn := range s.pending {
+		conn.Close()
+		delete(s.pending, nodeID)
+	}
+	for nodeID, ready := range s.ready {
+		close(ready)
+		delete(s.ready, nodeID)
+	}
+}
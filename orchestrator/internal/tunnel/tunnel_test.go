@@ -0,0 +1,179 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	"github.com/Orchion/Orchion/shared/logging"
+)
+
+func startServer(t *testing.T) (*Server, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := NewServer(logging.Default())
+	go s.Serve(lis)
+	t.Cleanup(func() {
+		lis.Close()
+		s.Close()
+	})
+	return s, lis.Addr().String()
+}
+
+// connectAgent opens a tunnel the way an agent does
+func connectAgent(t *testing.T, addr, greeting string) net.Conn {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = conn.Write([]byte(greeting + "\n"))
+	require.NoError(t, err)
+	return conn
+}
+
+func readReply(t *testing.T, conn net.Conn) string {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reply, err := readLine(conn)
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Time{})
+	return reply
+}
+
+func TestServer_Handshake(t *testing.T) {
+	s, addr := startServer(t)
+
+	agent := connectAgent(t, addr, Greeting+"node-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := s.Dial(ctx, "node-1")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The agent only starts serving once the tunnel is taken
+	assert.Equal(t, "OK", readReply(t, agent))
+}
+
+func TestServer_RejectsBadGreeting(t *testing.T) {
+	_, addr := startServer(t)
+
+	for _, greeting := range []string{"HELLO node-1", Greeting} {
+		agent := connectAgent(t, addr, greeting)
+		assert.Contains(t, readReply(t, agent), "ERR", greeting)
+	}
+}
+
+func TestServer_DialWaitsForAgent(t *testing.T) {
+	s, addr := startServer(t)
+
+	agent := make(chan net.Conn, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Write([]byte(Greeting + "node-1\n"))
+		}
+		agent <- conn
+	}()
+	defer func() {
+		if conn := <-agent; conn != nil {
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := s.Dial(ctx, "node-1")
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestServer_DialTimesOut(t *testing.T) {
+	s, _ := startServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := s.Dial(ctx, "node-1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServer_Close(t *testing.T) {
+	s, _ := startServer(t)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.Dial(context.Background(), "node-1")
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+
+	assert.ErrorIs(t, <-done, ErrClosed)
+}
+
+// fakeAgent answers LoadModel so the test can tell the request arrived
+type fakeAgent struct {
+	pb.UnimplementedNodeAgentServer
+}
+
+func (fakeAgent) LoadModel(ctx context.Context, req *pb.LoadModelRequest) (*pb.LoadModelResponse, error) {
+	return &pb.LoadModelResponse{}, nil
+}
+
+// singleConnListener hands one connection to a gRPC server
+type singleConnListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	addr   net.Addr
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *singleConnListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr { return l.addr }
+
+func TestServer_GRPCOverTunnel(t *testing.T) {
+	s, addr := startServer(t)
+
+	// The agent serves NodeAgent on the connection it opened
+	conn := connectAgent(t, addr, Greeting+"node-1")
+	lis := &singleConnListener{conns: make(chan net.Conn, 1), closed: make(chan struct{}), addr: conn.LocalAddr()}
+	agent := grpc.NewServer()
+	pb.RegisterNodeAgentServer(agent, fakeAgent{})
+	go agent.Serve(lis)
+	defer agent.Stop()
+	go func() {
+		if reply, err := readLine(conn); err == nil && reply == "OK" {
+			lis.conns <- conn
+		}
+	}()
+
+	client, err := grpc.NewClient(Target("node-1"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(s.Dial))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = pb.NewNodeAgentClient(client).LoadModel(ctx, &pb.LoadModelRequest{Model: "llama3"})
+	assert.NoError(t, err)
+}
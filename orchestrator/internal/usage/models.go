@@ -0,0 +1,139 @@
+package usage
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultModelWindow is how far back per-model usage is kept
+const DefaultModelWindow = 24 * time.Hour
+
+// modelBucketWidth is the granularity of per-model usage; windows are rounded
+// up to whole buckets
+const modelBucketWidth = time.Minute
+
+// ModelUsage is a model's gateway usage over a window, for deciding which
+// models to keep preloaded and which to retire
+type ModelUsage struct {
+	Model        string           `json:"model"`
+	Requests     int64            `json:"requests"`
+	Errors       int64            `json:"errors"`
+	PromptTokens int64            `json:"prompt_tokens"`
+	AvgLatencyMs float64          `json:"avg_latency_ms"`
+	Nodes        map[string]int64 `json:"nodes"` // Requests served per node, where known
+}
+
+// modelBucket is a model's usage during one modelBucketWidth
+type modelBucket struct {
+	start        time.Time
+	requests     int64
+	errors       int64
+	promptTokens int64
+	latencyMs    int64
+	nodes        map[string]int64
+}
+
+// SetModelWindow sets how far back per-model usage is kept; zero stops
+// tracking it
+func (t *Tracker) SetModelWindow(window time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.modelWindow = window
+	t.pruneModels(t.now())
+}
+
+// ModelWindow returns how far back per-model usage is kept
+func (t *Tracker) ModelWindow() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.modelWindow
+}
+
+// recordModel adds a request to its model's current bucket. Callers must hold
+// mu.
+func (t *Tracker) recordModel(rec Record) {
+	if rec.Model == "" || t.modelWindow <= 0 {
+		return
+	}
+
+	start := rec.Time.Truncate(modelBucketWidth)
+	buckets := t.models[rec.Model]
+	if n := len(buckets); n == 0 || buckets[n-1].start.Before(start) {
+		buckets = append(buckets, &modelBucket{start: start, nodes: make(map[string]int64)})
+	}
+	// Requests are recorded when they finish, so one that started before the
+	// newest bucket lands in it rather than in the past
+	b := buckets[len(buckets)-1]
+	b.requests++
+	if rec.Error != "" {
+		b.errors++
+	}
+	b.promptTokens += rec.PromptTokens
+	b.latencyMs += rec.LatencyMs
+	if rec.Node != "" {
+		b.nodes[rec.Node]++
+	}
+	t.models[rec.Model] = buckets
+	t.pruneModels(t.now())
+}
+
+// pruneModels drops buckets older than the model window. Callers must hold
+// mu.
+func (t *Tracker) pruneModels(now time.Time) {
+	cutoff := now.Add(-t.modelWindow - modelBucketWidth)
+	for model, buckets := range t.models {
+		i := 0
+		for i < len(buckets) && !buckets[i].start.After(cutoff) {
+			i++
+		}
+		if i == len(buckets) {
+			delete(t.models, model)
+			continue
+		}
+		t.models[model] = buckets[i:]
+	}
+}
+
+// ModelUsage returns each model's usage over the last window, capped at the
+// model window, most requested first
+func (t *Tracker) ModelUsage(window time.Duration) []ModelUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if window <= 0 || window > t.modelWindow {
+		window = t.modelWindow
+	}
+	since := now.Add(-window).Truncate(modelBucketWidth)
+
+	usages := []ModelUsage{}
+	for model, buckets := range t.models {
+		u := ModelUsage{Model: model, Nodes: make(map[string]int64)}
+		var latencyMs int64
+		for _, b := range buckets {
+			if b.start.Before(since) {
+				continue
+			}
+			u.Requests += b.requests
+			u.Errors += b.errors
+			u.PromptTokens += b.promptTokens
+			latencyMs += b.latencyMs
+			for node, requests := range b.nodes {
+				u.Nodes[node] += requests
+			}
+		}
+		if u.Requests == 0 {
+			continue
+		}
+		u.AvgLatencyMs = float64(latencyMs) / float64(u.Requests)
+		usages = append(usages, u)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Requests != usages[j].Requests {
+			return usages[i].Requests > usages[j].Requests
+		}
+		return usages[i].Model < usages[j].Model
+	})
+	return usages
+}
@@ -0,0 +1,56 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelUsage(t *testing.T) {
+	tracker, now := newTestTracker(Quota{})
+	tracker.SetModelWindow(time.Hour)
+
+	tracker.Record(Record{Model: "llama3", Node: "gpu-1", PromptTokens: 10, LatencyMs: 100})
+	tracker.Record(Record{Model: "llama3", Node: "gpu-2", PromptTokens: 20, LatencyMs: 300})
+	tracker.Record(Record{Model: "llama3", Error: "node_unavailable", LatencyMs: 200})
+	tracker.Record(Record{Model: "nomic-embed", PromptTokens: 5, LatencyMs: 10})
+	tracker.Record(Record{Endpoint: "/v1/chat/completions", Error: "invalid_request"}) // No model
+
+	usages := tracker.ModelUsage(0)
+	require.Len(t, usages, 2)
+	assert.Equal(t, ModelUsage{
+		Model:        "llama3",
+		Requests:     3,
+		Errors:       1,
+		PromptTokens: 30,
+		AvgLatencyMs: 200,
+		Nodes:        map[string]int64{"gpu-1": 1, "gpu-2": 1},
+	}, usages[0])
+	assert.Equal(t, "nomic-embed", usages[1].Model)
+	assert.Empty(t, usages[1].Nodes)
+
+	// Older requests leave narrower windows first, then the model window
+	*now = now.Add(30 * time.Minute)
+	for i := 0; i < 3; i++ {
+		tracker.Record(Record{Model: "nomic-embed", LatencyMs: 10})
+	}
+	usages = tracker.ModelUsage(10 * time.Minute)
+	require.Len(t, usages, 1)
+	assert.Equal(t, int64(3), usages[0].Requests)
+	assert.Equal(t, "nomic-embed", tracker.ModelUsage(0)[0].Model, "most requested first")
+	assert.Len(t, tracker.ModelUsage(0), 2)
+
+	*now = now.Add(45 * time.Minute)
+	usages = tracker.ModelUsage(24 * time.Hour)
+	require.Len(t, usages, 1)
+	assert.Equal(t, int64(3), usages[0].Requests)
+
+	// The per-user usage since startup is unaffected
+	require.Len(t, tracker.Usage(), 1)
+	assert.Equal(t, int64(8), tracker.Usage()[0].Requests)
+
+	tracker.SetModelWindow(0)
+	assert.Empty(t, tracker.ModelUsage(0))
+}
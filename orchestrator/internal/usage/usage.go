@@ -0,0 +1,191 @@
+// Package usage tracks gateway usage per end user (the OpenAI "user" field)
+// and per model, writes an audit log of gateway requests and enforces
+// per-user quotas.
+package usage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Allow when a user has used up a quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Record is one gateway request, as written to the audit log
+type Record struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user,omitempty"`
+	APIKeyID     string    `json:"api_key_id,omitempty"` // See APIKeyID; never the key itself
+	ClientIP     string    `json:"client_ip,omitempty"`  // Behind a trusted proxy, the address it forwarded for
+	Endpoint     string    `json:"endpoint"`
+	Model        string    `json:"model"`
+	PromptTokens int64     `json:"prompt_tokens"`
+	Error        string    `json:"error,omitempty"` // Error code, e.g. "node_unavailable"
+	LatencyMs    int64     `json:"latency_ms"`
+
+	// How fast the node generated a chat completion
+	Node            string  `json:"node,omitempty"`
+	TTFTMs          int64   `json:"ttft_ms,omitempty"`
+	TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+}
+
+// APIKeyID identifies an API key in audit records without revealing it
+func APIKeyID(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Quota limits each user. Requests without a user are not limited.
+type Quota struct {
+	RequestsPerMinute int   // 0 = unlimited
+	TokensPerDay      int64 // Prompt tokens per UTC day; 0 = unlimited
+}
+
+// UserUsage is a user's usage since the orchestrator started
+type UserUsage struct {
+	User         string `json:"user"`
+	Requests     int64  `json:"requests"`
+	Errors       int64  `json:"errors"`
+	Rejected     int64  `json:"rejected"` // Requests refused for exceeding a quota
+	PromptTokens int64  `json:"prompt_tokens"`
+	TokensToday  int64  `json:"tokens_today"` // Prompt tokens in the current UTC day
+}
+
+// userState is the usage and quota state of one user
+type userState struct {
+	usage  UserUsage
+	recent []time.Time // Admitted request times within the last minute
+	day    string      // UTC day TokensToday counts
+}
+
+// Tracker accumulates usage per user and per model and enforces quotas
+type Tracker struct {
+	mu          sync.Mutex
+	quota       Quota
+	users       map[string]*userState
+	models      map[string][]*modelBucket // Oldest first
+	modelWindow time.Duration
+	audit       io.Writer
+	now         func() time.Time
+}
+
+// NewTracker creates a tracker enforcing quota
+func NewTracker(quota Quota) *Tracker {
+	return &Tracker{
+		quota:       quota,
+		users:       make(map[string]*userState),
+		models:      make(map[string][]*modelBucket),
+		modelWindow: DefaultModelWindow,
+		now:         time.Now,
+	}
+}
+
+// SetAuditLog writes every recorded request to w as a JSON line
+func (t *Tracker) SetAuditLog(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.audit = w
+}
+
+// state returns the state of a user, rolling TokensToday over at midnight UTC.
+// Callers must hold mu.
+func (t *Tracker) state(user string, now time.Time) *userState {
+	s, ok := t.users[user]
+	if !ok {
+		s = &userState{usage: UserUsage{User: user}}
+		t.users[user] = s
+	}
+	if day := now.UTC().Format("2006-01-02"); s.day != day {
+		s.day = day
+		s.usage.TokensToday = 0
+	}
+	return s
+}
+
+// Allow admits a request from user, or returns an error wrapping
+// ErrQuotaExceeded if the user is over a quota
+func (t *Tracker) Allow(user string) error {
+	if user == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	s := t.state(user, now)
+
+	if t.quota.TokensPerDay > 0 && s.usage.TokensToday >= t.quota.TokensPerDay {
+		s.usage.Rejected++
+		return fmt.Errorf("%w: user %q used its %d prompt tokens for today", ErrQuotaExceeded, user, t.quota.TokensPerDay)
+	}
+
+	if t.quota.RequestsPerMinute > 0 {
+		cutoff := now.Add(-time.Minute)
+		kept := s.recent[:0]
+		for _, at := range s.recent {
+			if at.After(cutoff) {
+				kept = append(kept, at)
+			}
+		}
+		s.recent = kept
+
+		if len(s.recent) >= t.quota.RequestsPerMinute {
+			s.usage.Rejected++
+			return fmt.Errorf("%w: user %q is limited to %d requests per minute", ErrQuotaExceeded, user, t.quota.RequestsPerMinute)
+		}
+		s.recent = append(s.recent, now)
+	}
+	return nil
+}
+
+// Record adds a finished request to the user's and the model's usage and the
+// audit log
+func (t *Tracker) Record(rec Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rec.Time.IsZero() {
+		rec.Time = t.now()
+	}
+
+	s := t.state(rec.User, rec.Time)
+	s.usage.Requests++
+	if rec.Error != "" {
+		s.usage.Errors++
+	}
+	s.usage.PromptTokens += rec.PromptTokens
+	s.usage.TokensToday += rec.PromptTokens
+	t.recordModel(rec)
+
+	if t.audit != nil {
+		if line, err := json.Marshal(rec); err == nil {
+			t.audit.Write(append(line, '\n'))
+		}
+	}
+}
+
+// Usage returns the usage of every user seen, sorted by user. Requests
+// without a user are reported under the empty user.
+func (t *Tracker) Usage() []UserUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	usages := make([]UserUsage, 0, len(t.users))
+	for user := range t.users {
+		usages = append(usages, t.state(user, now).usage)
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].User < usages[j].User })
+	return usages
+}
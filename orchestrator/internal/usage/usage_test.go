@@ -0,0 +1,94 @@
+package usage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTracker(quota Quota) (*Tracker, *time.Time) {
+	now := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	t := NewTracker(quota)
+	t.now = func() time.Time { return now }
+	return t, &now
+}
+
+func TestRequestsPerMinute(t *testing.T) {
+	tracker, now := newTestTracker(Quota{RequestsPerMinute: 2})
+
+	require.NoError(t, tracker.Allow("alice"))
+	require.NoError(t, tracker.Allow("alice"))
+	err := tracker.Allow("alice")
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+
+	// Other users and anonymous requests are unaffected
+	assert.NoError(t, tracker.Allow("bob"))
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, tracker.Allow(""))
+	}
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, tracker.Allow("alice"))
+
+	usage := tracker.Usage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+}
+
+func TestTokensPerDay(t *testing.T) {
+	tracker, now := newTestTracker(Quota{TokensPerDay: 100})
+
+	require.NoError(t, tracker.Allow("alice"))
+	tracker.Record(Record{User: "alice", PromptTokens: 60})
+	require.NoError(t, tracker.Allow("alice"), "under quota")
+	tracker.Record(Record{User: "alice", PromptTokens: 60})
+
+	assert.ErrorIs(t, tracker.Allow("alice"), ErrQuotaExceeded)
+
+	// The daily allowance resets at midnight UTC
+	*now = now.Add(2 * time.Minute)
+	assert.NoError(t, tracker.Allow("alice"))
+
+	usage := tracker.Usage()
+	require.Len(t, usage, 1)
+	assert.Equal(t, int64(120), usage[0].PromptTokens)
+	assert.Equal(t, int64(0), usage[0].TokensToday)
+}
+
+func TestRecordUsageAndAudit(t *testing.T) {
+	tracker, _ := newTestTracker(Quota{})
+	var audit bytes.Buffer
+	tracker.SetAuditLog(&audit)
+
+	tracker.Record(Record{User: "bob", Endpoint: "/v1/embeddings", Model: "nomic", PromptTokens: 7, APIKeyID: APIKeyID("secret")})
+	tracker.Record(Record{User: "alice", Endpoint: "/v1/chat/completions", Error: "node_unavailable"})
+	tracker.Record(Record{Endpoint: "/v1/chat/completions", PromptTokens: 3})
+
+	assert.Equal(t, []UserUsage{
+		{User: "", Requests: 1, PromptTokens: 3, TokensToday: 3},
+		{User: "alice", Requests: 1, Errors: 1},
+		{User: "bob", Requests: 1, PromptTokens: 7, TokensToday: 7},
+	}, tracker.Usage())
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	require.Len(t, lines, 3)
+	var rec Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "bob", rec.User)
+	assert.Equal(t, int64(7), rec.PromptTokens)
+	assert.False(t, rec.Time.IsZero())
+	assert.NotContains(t, audit.String(), "secret")
+}
+
+func TestAPIKeyID(t *testing.T) {
+	assert.Empty(t, APIKeyID(""))
+	assert.Len(t, APIKeyID("secret"), 12)
+	assert.Equal(t, APIKeyID("secret"), APIKeyID("secret"))
+	assert.NotEqual(t, APIKeyID("secret"), APIKeyID("other"))
+}
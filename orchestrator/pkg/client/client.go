@@ -0,0 +1,255 @@
+// Package client is a Go client for Orchion. It wraps the orchestrator's v2
+// gRPC API (jobs and nodes) and the OpenAI-compatible gateway (chat
+// completions and embeddings), so services embedding Orchion don't need to
+// deal with protobuf plumbing or the gateway's wire format.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+)
+
+// DefaultPollInterval is how often WaitForJob checks on a job
+const DefaultPollInterval = 500 * time.Millisecond
+
+// listPageSize is the page size used when listing nodes and jobs
+const listPageSize = 500
+
+var (
+	// ErrNoOrchestrator is returned by gRPC calls when Config.Address is empty
+	ErrNoOrchestrator = errors.New("client: no orchestrator address configured")
+	// ErrNoGateway is returned by gateway calls when Config.GatewayURL is empty
+	ErrNoGateway = errors.New("client: no gateway URL configured")
+	// ErrJobFailed is returned by WaitForJob when the job fails
+	ErrJobFailed = errors.New("job failed")
+)
+
+// Config configures a Client. Either address may be left empty if the
+// corresponding calls aren't used.
+type Config struct {
+	Address     string            // Orchestrator gRPC address, e.g. "localhost:50051"
+	GatewayURL  string            // Gateway base URL, e.g. "http://localhost:8080"
+	APIKey      string            // Gateway API key (optional)
+	HTTPClient  *http.Client      // Defaults to http.DefaultClient
+	DialOptions []grpc.DialOption // Defaults to an insecure connection
+}
+
+// Client talks to an Orchion orchestrator and its gateway. It is safe for
+// concurrent use.
+type Client struct {
+	config       Config
+	conn         *grpc.ClientConn
+	orchestrator pbv2.OrchestratorClient
+	httpClient   *http.Client
+}
+
+// New creates a client. Connections are established lazily on first use.
+func New(config Config) (*Client, error) {
+	c := &Client{
+		config:     config,
+		httpClient: config.HTTPClient,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	if config.Address != "" {
+		opts := config.DialOptions
+		if len(opts) == 0 {
+			opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+		}
+		conn, err := grpc.NewClient(config.Address, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to create orchestrator connection: %w", err)
+		}
+		c.conn = conn
+		c.orchestrator = pbv2.NewOrchestratorClient(conn)
+	}
+
+	return c, nil
+}
+
+// Close closes the orchestrator connection
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// SubmitJob queues a job with a generated ID. payload is the orchion.v1
+// request matching jobType.
+func (c *Client) SubmitJob(ctx context.Context, jobType pbv2.JobType, payload proto.Message) (*pbv2.SubmitJobResponse, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+
+	data, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to encode payload: %w", err)
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.orchestrator.SubmitJob(ctx, &pbv2.SubmitJobRequest{
+		JobId:   id,
+		JobType: jobType,
+		Payload: data,
+	})
+}
+
+// SubmitChat queues a chat completion job
+func (c *Client) SubmitChat(ctx context.Context, req *pb.ChatCompletionRequest) (*pbv2.SubmitJobResponse, error) {
+	return c.SubmitJob(ctx, pbv2.JobType_JOB_TYPE_CHAT_COMPLETION, req)
+}
+
+// SubmitEmbeddings queues an embeddings job
+func (c *Client) SubmitEmbeddings(ctx context.Context, req *pb.EmbeddingRequest) (*pbv2.SubmitJobResponse, error) {
+	return c.SubmitJob(ctx, pbv2.JobType_JOB_TYPE_EMBEDDINGS, req)
+}
+
+// SubmitPipeline queues a pipeline job
+func (c *Client) SubmitPipeline(ctx context.Context, req *pb.PipelineRequest) (*pbv2.SubmitJobResponse, error) {
+	return c.SubmitJob(ctx, pbv2.JobType_JOB_TYPE_PIPELINE, req)
+}
+
+// JobStatus returns the current state of a job
+func (c *Client) JobStatus(ctx context.Context, jobID string) (*pbv2.GetJobStatusResponse, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+	return c.orchestrator.GetJobStatus(ctx, &pbv2.GetJobStatusRequest{JobId: jobID})
+}
+
+// JobGroup returns the current state of a job group and its jobs
+func (c *Client) JobGroup(ctx context.Context, groupID string) (*pbv2.GetJobGroupResponse, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+	return c.orchestrator.GetJobGroup(ctx, &pbv2.GetJobGroupRequest{GroupId: groupID})
+}
+
+// WaitForJob polls a job until it completes or fails, checking every interval
+// (DefaultPollInterval if zero). A failed job returns its status along with
+// an error wrapping ErrJobFailed.
+func (c *Client) WaitForJob(ctx context.Context, jobID string, interval time.Duration) (*pbv2.GetJobStatusResponse, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := c.JobStatus(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.Status {
+		case pbv2.JobStatus_JOB_STATUS_COMPLETED:
+			return resp, nil
+		case pbv2.JobStatus_JOB_STATUS_FAILED:
+			return resp, fmt.Errorf("%w: %s", ErrJobFailed, resp.ErrorMessage)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DecodeResult decodes a completed job's result into the orchion.v1 response
+// matching its type, e.g. a pb.ChatCompletionResponse for chat jobs
+func DecodeResult(status *pbv2.GetJobStatusResponse, result proto.Message) error {
+	if status.Status != pbv2.JobStatus_JOB_STATUS_COMPLETED {
+		return fmt.Errorf("client: job %s is not completed", status.JobId)
+	}
+	if err := proto.Unmarshal(status.Result, result); err != nil {
+		return fmt.Errorf("client: failed to decode result: %w", err)
+	}
+	return nil
+}
+
+// ListNodes returns every node matching req, following pages as needed. req
+// may be nil to list all nodes.
+func (c *Client) ListNodes(ctx context.Context, req *pbv2.ListNodesRequest) ([]*pbv2.Node, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+
+	page := &pbv2.ListNodesRequest{PageSize: listPageSize}
+	if req != nil {
+		page = proto.Clone(req).(*pbv2.ListNodesRequest)
+		if page.PageSize == 0 {
+			page.PageSize = listPageSize
+		}
+	}
+
+	var nodes []*pbv2.Node
+	for {
+		resp, err := c.orchestrator.ListNodes(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, resp.Nodes...)
+		if resp.NextPageToken == "" {
+			return nodes, nil
+		}
+		page.PageToken = resp.NextPageToken
+	}
+}
+
+// Node returns a node with its hardware history, model states and in-flight
+// jobs
+func (c *Client) Node(ctx context.Context, nodeID string) (*pbv2.GetNodeResponse, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+	return c.orchestrator.GetNode(ctx, &pbv2.GetNodeRequest{NodeId: nodeID})
+}
+
+// ListJobs returns every job, oldest first
+func (c *Client) ListJobs(ctx context.Context) ([]*pbv2.JobSummary, error) {
+	if c.orchestrator == nil {
+		return nil, ErrNoOrchestrator
+	}
+
+	var jobs []*pbv2.JobSummary
+	req := &pbv2.ListJobsRequest{PageSize: listPageSize}
+	for {
+		resp, err := c.orchestrator.ListJobs(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, resp.Jobs...)
+		if resp.NextPageToken == "" {
+			return jobs, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("client: failed to generate job ID: %w", err)
+	}
+	return "job-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Orchion/Orchion/orchestrator/api/v1"
+	pbv2 "github.com/Orchion/Orchion/orchestrator/api/v2"
+)
+
+// fakeOrchestrator serves canned v2 responses
+type fakeOrchestrator struct {
+	pbv2.UnimplementedOrchestratorServer
+
+	mu        sync.Mutex
+	submitted *pbv2.SubmitJobRequest
+	polls     int
+	failJob   bool
+}
+
+func (f *fakeOrchestrator) SubmitJob(ctx context.Context, req *pbv2.SubmitJobRequest) (*pbv2.SubmitJobResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.submitted = req
+	return &pbv2.SubmitJobResponse{JobId: req.JobId, Status: pbv2.JobStatus_JOB_STATUS_PENDING}, nil
+}
+
+func (f *fakeOrchestrator) GetJobStatus(ctx context.Context, req *pbv2.GetJobStatusRequest) (*pbv2.GetJobStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	if f.polls < 3 {
+		return &pbv2.GetJobStatusResponse{JobId: req.JobId, Status: pbv2.JobStatus_JOB_STATUS_RUNNING}, nil
+	}
+	if f.failJob {
+		return &pbv2.GetJobStatusResponse{JobId: req.JobId, Status: pbv2.JobStatus_JOB_STATUS_FAILED, ErrorMessage: "engine crashed"}, nil
+	}
+	result, _ := proto.Marshal(&pb.EmbeddingResponse{Model: "nomic-embed-text"})
+	return &pbv2.GetJobStatusResponse{JobId: req.JobId, Status: pbv2.JobStatus_JOB_STATUS_COMPLETED, Result: result}, nil
+}
+
+func (f *fakeOrchestrator) ListNodes(ctx context.Context, req *pbv2.ListNodesRequest) (*pbv2.ListNodesResponse, error) {
+	// Two pages of one node each
+	if req.PageToken == "" {
+		return &pbv2.ListNodesResponse{Nodes: []*pbv2.Node{{Id: "node-1"}}, NextPageToken: "page-2"}, nil
+	}
+	return &pbv2.ListNodesResponse{Nodes: []*pbv2.Node{{Id: "node-2", Labels: map[string]string{"status": req.Status}}}}, nil
+}
+
+func (f *fakeOrchestrator) ListJobs(ctx context.Context, req *pbv2.ListJobsRequest) (*pbv2.ListJobsResponse, error) {
+	return &pbv2.ListJobsResponse{Jobs: []*pbv2.JobSummary{{JobId: "job-1"}}}, nil
+}
+
+// newTestClient starts server in-process and returns a client connected to it
+func newTestClient(t *testing.T, server pbv2.OrchestratorServer) *Client {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pbv2.RegisterOrchestratorServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	c, err := New(Config{
+		Address: "passthrough:///bufnet",
+		DialOptions: []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSubmitAndWait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("completed job", func(t *testing.T) {
+		server := &fakeOrchestrator{}
+		c := newTestClient(t, server)
+
+		submitted, err := c.SubmitEmbeddings(ctx, &pb.EmbeddingRequest{Model: "nomic-embed-text", Input: []string{"hi"}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, submitted.JobId)
+		assert.Equal(t, pbv2.JobType_JOB_TYPE_EMBEDDINGS, server.submitted.JobType)
+
+		var payload pb.EmbeddingRequest
+		require.NoError(t, proto.Unmarshal(server.submitted.Payload, &payload))
+		assert.Equal(t, []string{"hi"}, payload.Input)
+
+		status, err := c.WaitForJob(ctx, submitted.JobId, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 3, server.polls)
+
+		var result pb.EmbeddingResponse
+		require.NoError(t, DecodeResult(status, &result))
+		assert.Equal(t, "nomic-embed-text", result.Model)
+	})
+
+	t.Run("failed job", func(t *testing.T) {
+		c := newTestClient(t, &fakeOrchestrator{failJob: true})
+
+		status, err := c.WaitForJob(ctx, "job-1", time.Millisecond)
+		assert.True(t, errors.Is(err, ErrJobFailed))
+		assert.Contains(t, err.Error(), "engine crashed")
+		require.NotNil(t, status)
+		assert.Error(t, DecodeResult(status, &pb.EmbeddingResponse{}))
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		c := newTestClient(t, &fakeOrchestrator{})
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+
+		_, err := c.WaitForJob(ctx, "job-1", time.Hour)
+		assert.Error(t, err)
+	})
+
+	t.Run("generated job IDs are unique", func(t *testing.T) {
+		a, err := newJobID()
+		require.NoError(t, err)
+		b, err := newJobID()
+		require.NoError(t, err)
+		assert.NotEqual(t, a, b)
+	})
+}
+
+func TestListing(t *testing.T) {
+	c := newTestClient(t, &fakeOrchestrator{})
+	ctx := context.Background()
+
+	nodes, err := c.ListNodes(ctx, &pbv2.ListNodesRequest{Status: "online"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "node-1", nodes[0].Id)
+	assert.Equal(t, "online", nodes[1].Labels["status"], "filters are kept across pages")
+
+	nodes, err = c.ListNodes(ctx, nil)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	jobs, err := c.ListJobs(ctx)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
+
+func TestMissingEndpoints(t *testing.T) {
+	c, err := New(Config{})
+	require.NoError(t, err)
+	defer c.Close()
+	ctx := context.Background()
+
+	_, err = c.ListNodes(ctx, nil)
+	assert.ErrorIs(t, err, ErrNoOrchestrator)
+	_, err = c.Embeddings(ctx, "m", "x")
+	assert.ErrorIs(t, err, ErrNoGateway)
+}
@@ -0,0 +1,299 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ChatMessage is one message of a chat
+type ChatMessage struct {
+	Role      string     `json:"role"` // "system", "user" or "assistant"
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Function calls of an assistant message
+}
+
+// ToolCall is a function call the model asked for. Streamed chunks carry
+// fragments of it, identified by Index, whose arguments add up.
+type ToolCall struct {
+	Index    int              `json:"index"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"` // "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction is the function a tool call invokes
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"` // JSON
+}
+
+// ChatRequest is a chat completion request sent through the gateway
+type ChatRequest struct {
+	Model             string        `json:"model"`
+	Messages          []ChatMessage `json:"messages"`
+	Temperature       float32       `json:"temperature,omitempty"`
+	MaxTokens         int           `json:"max_tokens,omitempty"`
+	Stop              []string      `json:"stop,omitempty"`
+	TopP              float32       `json:"top_p,omitempty"`
+	TopK              int           `json:"top_k,omitempty"`
+	RepetitionPenalty float32       `json:"repetition_penalty,omitempty"`
+	Seed              *int64        `json:"seed,omitempty"`
+	PromptCacheKey    string        `json:"prompt_cache_key,omitempty"` // Keeps requests sharing a prompt prefix on one node
+	Stream            bool          `json:"stream,omitempty"`           // Set by ChatStream
+
+	// EngineOptions are passed to the inference engine under the "orchion"
+	// namespace, e.g. {"num_ctx": 8192, "keep_alive": "30m"} for Ollama
+	EngineOptions map[string]interface{} `json:"orchion,omitempty"`
+
+	// Node pins the request to a node; requires an admin API key
+	Node string `json:"-"`
+
+	// Priority orders the request on saturated nodes: "high", "normal", "low"
+	// or an integer, higher first. Raising it requires an admin API key.
+	Priority string `json:"-"`
+}
+
+// header returns the gateway headers a chat request sets
+func (r ChatRequest) header() http.Header {
+	header := http.Header{}
+	if r.Node != "" {
+		header.Set("X-Orchion-Node", r.Node)
+	}
+	if r.Priority != "" {
+		header.Set("X-Orchion-Priority", r.Priority)
+	}
+	return header
+}
+
+// ChatChoice is one generated completion. Streamed chunks fill Delta
+// instead of Message.
+type ChatChoice struct {
+	Index        int         `json:"index"`
+	Message      ChatMessage `json:"message"`
+	Delta        ChatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+}
+
+// Usage reports token usage
+type Usage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// ChatResponse is a chat completion, or one chunk of a streamed completion
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"` // Unix seconds, as in the OpenAI API
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *Usage       `json:"usage,omitempty"`
+}
+
+// Content returns the text of the first choice
+func (r *ChatResponse) Content() string {
+	if len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content + r.Choices[0].Delta.Content
+}
+
+// APIError is an error returned by the gateway
+type APIError struct {
+	StatusCode int    // HTTP status; 0 for errors reported mid-stream
+	Type       string // OpenAI error type, e.g. "server_error"
+	Code       string // Orchion error code, e.g. "node_unavailable"
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("gateway error %s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("gateway error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// errorResponse is the OpenAI error envelope
+type errorResponse struct {
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+// apiError decodes an error envelope, returning nil if data isn't one
+func apiError(statusCode int, data []byte) *APIError {
+	var resp errorResponse
+	if json.Unmarshal(data, &resp) != nil || resp.Error == nil {
+		return nil
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Type:       resp.Error.Type,
+		Code:       resp.Error.Code,
+		Message:    resp.Error.Message,
+	}
+}
+
+// Chat sends a chat completion request and waits for the whole completion
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req.Stream = false
+	resp, err := c.post(ctx, "/v1/chat/completions", req, req.header())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chat ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("client: failed to decode chat response: %w", err)
+	}
+	return &chat, nil
+}
+
+// ChatStream starts a streamed chat completion. The caller must Close the
+// returned stream.
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest) (*ChatStream, error) {
+	req.Stream = true
+	resp, err := c.post(ctx, "/v1/chat/completions", req, req.header())
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return &ChatStream{body: resp.Body, scanner: scanner}, nil
+}
+
+// ChatStream reads the chunks of a streamed chat completion
+type ChatStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// Recv returns the next chunk, or io.EOF once the completion is finished.
+// Errors reported by the gateway mid-stream are returned as *APIError.
+func (s *ChatStream) Recv() (*ChatResponse, error) {
+	for s.scanner.Scan() {
+		data, ok := strings.CutPrefix(s.scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return nil, io.EOF
+		}
+		if apiErr := apiError(0, []byte(data)); apiErr != nil {
+			return nil, apiErr
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("client: failed to decode chat chunk: %w", err)
+		}
+		return &chunk, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Text reads the rest of the stream and returns the generated text
+func (s *ChatStream) Text() (string, error) {
+	var text strings.Builder
+	for {
+		chunk, err := s.Recv()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return text.String(), err
+		}
+		text.WriteString(chunk.Content())
+	}
+}
+
+// Close releases the stream
+func (s *ChatStream) Close() error {
+	return s.body.Close()
+}
+
+// Embeddings embeds each input with model and returns the vectors in input order
+func (c *Client) Embeddings(ctx context.Context, model string, input ...string) ([][]float32, error) {
+	resp, err := c.post(ctx, "/v1/embeddings", map[string]interface{}{
+		"model": model,
+		"input": input,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("client: failed to decode embeddings response: %w", err)
+	}
+
+	vectors := make([][]float32, len(input))
+	for _, d := range body.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("client: embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// post sends a JSON request with the given extra headers to the gateway,
+// turning error statuses into *APIError
+func (c *Client) post(ctx context.Context, path string, body interface{}, header http.Header) (*http.Response, error) {
+	if c.config.GatewayURL == "" {
+		return nil, ErrNoGateway
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.config.GatewayURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if apiErr := apiError(resp.StatusCode, data); apiErr != nil {
+			return nil, apiErr
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
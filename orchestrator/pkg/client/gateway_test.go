@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGatewayClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(Config{GatewayURL: server.URL, APIKey: "key"})
+	require.NoError(t, err)
+	return c
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]interface{}
+	var header http.Header
+	c := newGatewayClient(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`)
+	})
+
+	resp, err := c.Chat(context.Background(), ChatRequest{
+		Model:    "llama3",
+		Messages: []ChatMessage{{Role: "user", Content: "hi"}},
+		Node:     "node-7",
+		Priority: "low",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Content())
+	assert.Equal(t, "stop", resp.Choices[0].FinishReason)
+
+	assert.Equal(t, "llama3", got["model"])
+	assert.NotContains(t, got, "stream")
+	assert.Equal(t, "Bearer key", header.Get("Authorization"))
+	assert.Equal(t, "node-7", header.Get("X-Orchion-Node"))
+	assert.Equal(t, "low", header.Get("X-Orchion-Priority"))
+}
+
+func TestChatStream(t *testing.T) {
+	t.Run("reads chunks until done", func(t *testing.T) {
+		c := newGatewayClient(t, func(w http.ResponseWriter, r *http.Request) {
+			var req map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&req)
+			assert.Equal(t, true, req["stream"])
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		})
+
+		stream, err := c.ChatStream(context.Background(), ChatRequest{Model: "llama3"})
+		require.NoError(t, err)
+		defer stream.Close()
+
+		chunk, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "hel", chunk.Content())
+
+		text, err := stream.Text()
+		require.NoError(t, err)
+		assert.Equal(t, "lo", text)
+
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("error mid-stream", func(t *testing.T) {
+		c := newGatewayClient(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"error\":{\"message\":\"timed out\",\"type\":\"server_error\",\"code\":\"engine_timeout\"}}\n\n")
+		})
+
+		stream, err := c.ChatStream(context.Background(), ChatRequest{Model: "llama3"})
+		require.NoError(t, err)
+		defer stream.Close()
+
+		text, err := stream.Text()
+		assert.Equal(t, "hel", text)
+		var apiErr *APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "engine_timeout", apiErr.Code)
+	})
+}
+
+func TestEmbeddings(t *testing.T) {
+	c := newGatewayClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Out of order on purpose
+		fmt.Fprint(w, `{"object":"list","data":[{"embedding":[0.3],"index":1},{"embedding":[0.1,0.2],"index":0}]}`)
+	})
+
+	vectors, err := c.Embeddings(context.Background(), "nomic-embed-text", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2}, {0.3}}, vectors)
+}
+
+func TestGatewayErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name:     "OpenAI error",
+			status:   http.StatusServiceUnavailable,
+			body:     `{"error":{"message":"no nodes","type":"server_error","code":"node_unavailable"}}`,
+			wantCode: "node_unavailable",
+			wantMsg:  "no nodes",
+		},
+		{
+			name:    "plain text error",
+			status:  http.StatusBadRequest,
+			body:    "Invalid JSON\n",
+			wantMsg: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newGatewayClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := c.Chat(context.Background(), ChatRequest{Model: "llama3"})
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.wantCode, apiErr.Code)
+			assert.Equal(t, tt.wantMsg, apiErr.Message)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package diagnostics serves Go runtime profiles and process self-metrics, for
+// profiling performance regressions on running orchestrators and agents.
+//
+// Profiles expose internals such as command lines and memory contents, so
+// the handlers belong on an admin-only listener, e.g. bound to loopback, and
+// never on a public API.
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+)
+
+// started is when the process started, as far as this package can tell
+var started = time.Now()
+
+// Metrics is a snapshot of the process's own resource usage
+type Metrics struct {
+	Goroutines    int     `json:"goroutines"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	GoVersion     string  `json:"go_version"`
+
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"` // Live heap objects
+	HeapSysBytes   uint64 `json:"heap_sys_bytes"`   // Heap memory obtained from the OS
+	HeapObjects    uint64 `json:"heap_objects"`
+	SysBytes       uint64 `json:"sys_bytes"` // All memory obtained from the OS
+
+	GCCycles       uint32  `json:"gc_cycles"`
+	GCPauseTotalMs float64 `json:"gc_pause_total_ms"`
+	GCPauseLastMs  float64 `json:"gc_pause_last_ms"`
+	GCPauseMaxMs   float64 `json:"gc_pause_max_ms"` // Longest of the last 256 pauses
+
+	// OpenFDs is the number of open file descriptors, -1 where the OS
+	// doesn't tell (only Linux does)
+	OpenFDs int `json:"open_fds"`
+}
+
+// Collect takes a snapshot of the process's metrics. It briefly stops the
+// world to read memory statistics, so it shouldn't be called in a hot loop.
+func Collect() Metrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m := Metrics{
+		Goroutines:     runtime.NumGoroutine(),
+		UptimeSeconds:  time.Since(started).Seconds(),
+		GoVersion:      runtime.Version(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapSysBytes:   mem.HeapSys,
+		HeapObjects:    mem.HeapObjects,
+		SysBytes:       mem.Sys,
+		GCCycles:       mem.NumGC,
+		GCPauseTotalMs: nsToMs(mem.PauseTotalNs),
+		OpenFDs:        openFDs(),
+	}
+	if mem.NumGC > 0 {
+		m.GCPauseLastMs = nsToMs(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	for _, pause := range mem.PauseNs {
+		if ms := nsToMs(pause); ms > m.GCPauseMaxMs {
+			m.GCPauseMaxMs = ms
+		}
+	}
+	return m
+}
+
+func nsToMs(ns uint64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
+
+// openFDs counts the process's open file descriptors, or returns -1
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// One of them is the directory being read
+	return len(entries) - 1
+}
+
+// MetricsHandler serves Collect's snapshot as JSON
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Collect())
+	})
+}
+
+// Register serves /debug/metrics and the pprof profiles under /debug/pprof/
+// on mux, e.g. `go tool pprof http://127.0.0.1:6060/debug/pprof/heap`
+func Register(mux *http.ServeMux) {
+	mux.Handle("/debug/metrics", MetricsHandler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
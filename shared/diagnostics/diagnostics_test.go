@@ -0,0 +1,54 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	runtime.GC()
+	m := Collect()
+
+	assert.Greater(t, m.Goroutines, 0)
+	assert.Greater(t, m.HeapAllocBytes, uint64(0))
+	assert.GreaterOrEqual(t, m.HeapSysBytes, m.HeapAllocBytes)
+	assert.Greater(t, m.GCCycles, uint32(0))
+	assert.GreaterOrEqual(t, m.GCPauseMaxMs, m.GCPauseLastMs)
+	assert.Equal(t, runtime.Version(), m.GoVersion)
+	if runtime.GOOS == "linux" {
+		assert.Greater(t, m.OpenFDs, 0)
+	} else {
+		assert.Equal(t, -1, m.OpenFDs)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	Register(mux)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/debug/metrics")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	assert.Contains(t, m, "goroutines")
+	assert.Contains(t, m, "gc_pause_total_ms")
+	assert.Contains(t, m, "open_fds")
+
+	assert.Equal(t, http.StatusOK, get("/debug/pprof/").Code)
+	rec = get("/debug/pprof/goroutine?debug=1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+}
@@ -0,0 +1,181 @@
+// Package lifecycle starts the components of a process in dependency order
+// and stops them in reverse, so each one shuts down while the components it
+// relies on are still running. For example, the orchestrator stops accepting
+// gateway requests before it drains its job processor, and drains the
+// processor before it stops serving gRPC.
+//
+// Components are added in the order they depend on each other, as main builds
+// them:
+//
+//	runner := lifecycle.NewRunner(5*time.Second, logger)
+//	runner.Add(lifecycle.Component{Name: "grpc", Serve: ..., Stop: ...})
+//	runner.Add(lifecycle.Component{Name: "gateway", Serve: ..., Stop: ...})
+//	err := runner.Run(ctx) // Until ctx is cancelled, e.g. on SIGTERM
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Logger is the part of the shared logging.Logger the runner logs with
+type Logger interface {
+	Info(msg string, fields map[string]interface{})
+	Error(msg string, fields map[string]interface{})
+}
+
+// Component is a part of a process that is started and stopped with it. All
+// functions are optional.
+type Component struct {
+	Name string
+
+	// Start starts the component. ctx stays valid until every component is
+	// stopped, so background work may run on it; it isn't cancelled by the
+	// shutdown itself.
+	Start func(ctx context.Context) error
+
+	// Serve runs after Start until Stop makes it return, e.g. a server's
+	// accept loop. An error stops the process; http.ErrServerClosed and
+	// errors returned once the component is stopping don't count.
+	Serve func() error
+
+	// Stop stops the component, e.g. draining its work, and gives up once ctx
+	// is done
+	Stop func(ctx context.Context) error
+}
+
+// Runner runs a process's components
+type Runner struct {
+	components  []Component
+	stopTimeout time.Duration
+	logger      Logger
+}
+
+// NewRunner creates a runner allowing stopTimeout for stopping all
+// components; logger may be nil
+func NewRunner(stopTimeout time.Duration, logger Logger) *Runner {
+	return &Runner{stopTimeout: stopTimeout, logger: logger}
+}
+
+// Add adds a component. Components start in the order they were added and
+// stop in reverse.
+func (r *Runner) Add(c Component) {
+	r.components = append(r.components, c)
+}
+
+// Run starts all components and runs them until ctx is done or one fails,
+// then stops the started ones in reverse order. It returns the error that
+// failed a component, or the errors of components that failed to stop.
+func (r *Runner) Run(ctx context.Context) error {
+	// Components run on a context outliving ctx, so work in flight when
+	// shutdown begins isn't cut off before its component drains it
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	failed := make(chan error, len(r.components))
+	var stopping sync.Map // Names of components being stopped
+	var serving sync.WaitGroup
+
+	var err error
+	started := 0
+	for _, c := range r.components {
+		if c.Start != nil {
+			if startErr := c.Start(runCtx); startErr != nil {
+				err = fmt.Errorf("failed to start %s: %w", c.Name, startErr)
+				break
+			}
+		}
+		started++
+
+		if c.Serve != nil {
+			serving.Add(1)
+			go func(c Component) {
+				defer serving.Done()
+				serveErr := c.Serve()
+				if _, ok := stopping.Load(c.Name); ok || serveErr == nil || errors.Is(serveErr, http.ErrServerClosed) {
+					return
+				}
+				failed <- fmt.Errorf("%s failed: %w", c.Name, serveErr)
+			}(c)
+		}
+	}
+
+	if err == nil {
+		select {
+		case <-ctx.Done():
+		case err = <-failed:
+		}
+	}
+	if err != nil {
+		r.log(true, "Stopping after a component failed", map[string]interface{}{"error": err.Error()})
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.WithoutCancel(ctx), r.stopTimeout)
+	defer stopCancel()
+
+	var stopErrs []error
+	for i := started - 1; i >= 0; i-- {
+		c := r.components[i]
+		stopping.Store(c.Name, true)
+		if c.Stop == nil {
+			continue
+		}
+		r.log(false, "Stopping component", map[string]interface{}{"component": c.Name})
+		if stopErr := c.Stop(stopCtx); stopErr != nil {
+			r.log(true, "Failed to stop component", map[string]interface{}{
+				"component": c.Name,
+				"error":     stopErr.Error(),
+			})
+			stopErrs = append(stopErrs, fmt.Errorf("failed to stop %s: %w", c.Name, stopErr))
+		}
+	}
+
+	// Serving loops return once their components are stopped
+	done := make(chan struct{})
+	go func() {
+		serving.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-stopCtx.Done():
+	}
+
+	if err != nil {
+		return err
+	}
+	return errors.Join(stopErrs...)
+}
+
+// Graceful runs a graceful stop, e.g. grpc.Server.GracefulStop, falling back
+// to force once ctx is done; it returns ctx's error in that case
+func Graceful(ctx context.Context, graceful, force func()) error {
+	done := make(chan struct{})
+	go func() {
+		graceful()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		force()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// log logs with the runner's logger, if any
+func (r *Runner) log(isError bool, msg string, fields map[string]interface{}) {
+	switch {
+	case r.logger == nil:
+	case isError:
+		r.logger.Error(msg, fields)
+	default:
+		r.logger.Info(msg, fields)
+	}
+}
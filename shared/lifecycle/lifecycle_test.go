@@ -0,0 +1,159 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records what components do, in order
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// component records its start and stop, serving until stopped
+func (r *recorder) component(name string) Component {
+	stopped := make(chan struct{})
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			r.add("start " + name)
+			return nil
+		},
+		Serve: func() error {
+			<-stopped
+			return http.ErrServerClosed
+		},
+		Stop: func(ctx context.Context) error {
+			r.add("stop " + name)
+			close(stopped)
+			return nil
+		},
+	}
+}
+
+func TestRunner_StopsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	runner := NewRunner(time.Second, nil)
+	runner.Add(rec.component("registries"))
+	runner.Add(rec.component("grpc"))
+	runner.Add(rec.component("processor"))
+	runner.Add(rec.component("gateway"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for len(rec.get()) < 4 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	require.NoError(t, runner.Run(ctx))
+	assert.Equal(t, []string{
+		"start registries", "start grpc", "start processor", "start gateway",
+		"stop gateway", "stop processor", "stop grpc", "stop registries",
+	}, rec.get())
+}
+
+func TestRunner_StartContextOutlivesShutdown(t *testing.T) {
+	var runCtx context.Context
+	var errAtStop error
+	runner := NewRunner(time.Second, nil)
+	runner.Add(Component{
+		Name: "processor",
+		Start: func(ctx context.Context) error {
+			runCtx = ctx
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			// Work in flight can still finish while draining
+			errAtStop = runCtx.Err()
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, runner.Run(ctx))
+	assert.NoError(t, errAtStop)
+	assert.Error(t, runCtx.Err())
+}
+
+func TestRunner_ServeFailureStopsProcess(t *testing.T) {
+	rec := &recorder{}
+	runner := NewRunner(time.Second, nil)
+	runner.Add(rec.component("grpc"))
+	runner.Add(Component{
+		Name:  "gateway",
+		Serve: func() error { return errors.New("address in use") },
+	})
+
+	err := runner.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gateway failed: address in use")
+	assert.Equal(t, []string{"start grpc", "stop grpc"}, rec.get())
+}
+
+func TestRunner_StartFailureStopsStarted(t *testing.T) {
+	rec := &recorder{}
+	runner := NewRunner(time.Second, nil)
+	runner.Add(rec.component("grpc"))
+	runner.Add(Component{
+		Name:  "processor",
+		Start: func(ctx context.Context) error { return errors.New("no queue") },
+	})
+	runner.Add(rec.component("gateway"))
+
+	err := runner.Run(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start processor: no queue")
+	assert.Equal(t, []string{"start grpc", "stop grpc"}, rec.get())
+}
+
+func TestRunner_StopTimeout(t *testing.T) {
+	runner := NewRunner(20*time.Millisecond, nil)
+	runner.Add(Component{
+		Name: "processor",
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := runner.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to stop processor")
+}
+
+func TestGraceful(t *testing.T) {
+	assert.NoError(t, Graceful(context.Background(), func() {}, func() { t.Error("forced") }))
+
+	// A graceful stop that hangs is forced once ctx is done
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := Graceful(ctx, func() { <-release }, func() { close(release) })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"context"
+	"sync"
+)
+
+// contextKey is the context key for the logger carried by a context
+type contextKey struct{}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = NewLogger(Config{Level: InfoLevel})
+)
+
+// Default returns the logger used when a context carries none
+func Default() Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the logger used when a context carries none
+func SetDefault(logger Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = logger
+}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return logger
+	}
+	return Default()
+}
+
+// ContextWithFields returns a copy of ctx whose logger adds fields to every
+// entry, so logs emitted while handling a request (e.g. a job) can be
+// correlated without passing the fields to each call
+func ContextWithFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
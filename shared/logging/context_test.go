@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("default logger", func(t *testing.T) {
+		assert.Equal(t, Default(), FromContext(context.Background()))
+	})
+
+	t.Run("context logger", func(t *testing.T) {
+		logger := NewLogger(Config{Source: "test"})
+		ctx := NewContext(context.Background(), logger)
+		assert.Equal(t, logger, FromContext(ctx))
+	})
+}
+
+func TestContextWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(Config{Level: InfoLevel, Source: "test"})
+	logger.SetOutput(&buf)
+
+	ctx := NewContext(context.Background(), logger)
+	ctx = ContextWithFields(ctx, map[string]interface{}{"job_id": "job-1"})
+	ctx = ContextWithFields(ctx, map[string]interface{}{"node_id": "node-1", "model": "llama3"})
+
+	FromContext(ctx).Info("processing", map[string]interface{}{"step": 1})
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "job-1", entry["job_id"])
+	assert.Equal(t, "node-1", entry["node_id"])
+	assert.Equal(t, "llama3", entry["model"])
+	assert.Equal(t, float64(1), entry["step"])
+}
+
+func TestSetDefault(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	logger := NewLogger(Config{Source: "test"})
+	SetDefault(logger)
+	assert.Equal(t, logger, FromContext(context.Background()))
+}
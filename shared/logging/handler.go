@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LevelHandler serves a logger's level over HTTP: GET returns the current level
+// and PUT with {"level": "debug"} changes it without a restart
+type LevelHandler struct {
+	logger Logger
+}
+
+// NewLevelHandler creates a handler adjusting the level of the given logger
+func NewLevelHandler(logger Logger) *LevelHandler {
+	return &LevelHandler{logger: logger}
+}
+
+// ServeHTTP implements http.Handler
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Add CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		level, err := ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		previous := h.logger.GetLevel()
+		h.logger.SetLevel(level)
+		h.logger.Info("Log level changed", map[string]interface{}{
+			"from": previous.String(),
+			"to":   level.String(),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"level": h.logger.GetLevel().String(),
+	})
+}
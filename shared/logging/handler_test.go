@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	logger := NewLogger(Config{Level: InfoLevel, Source: "test"})
+	logger.SetOutput(&bytes.Buffer{})
+	handler := NewLevelHandler(logger)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantLevel  Level
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, InfoLevel},
+		{"set debug", http.MethodPut, `{"level": "debug"}`, http.StatusOK, DebugLevel},
+		{"set error", http.MethodPut, `{"level": "ERROR"}`, http.StatusOK, ErrorLevel},
+		{"unknown level", http.MethodPut, `{"level": "loud"}`, http.StatusBadRequest, ErrorLevel},
+		{"invalid body", http.MethodPut, `level=debug`, http.StatusBadRequest, ErrorLevel},
+		{"wrong method", http.MethodPost, `{"level": "debug"}`, http.StatusMethodNotAllowed, ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/admin/loglevel", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantLevel, logger.GetLevel())
+			if tt.wantStatus == http.StatusOK {
+				assert.JSONEq(t, `{"level": "`+tt.wantLevel.String()+`"}`, rec.Body.String())
+			}
+		})
+	}
+}
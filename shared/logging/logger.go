@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -35,6 +36,22 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel parses a level name ("debug", "info", "warn" or "error")
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", s)
+	}
+}
+
 // LogStreamer defines the interface for streaming log entries
 type LogStreamer interface {
 	Stream(entry *LogEntry) error
@@ -60,16 +77,19 @@ type Logger interface {
 	WithField(key string, value interface{}) Logger
 	WithFields(fields map[string]interface{}) Logger
 	SetLevel(level Level)
+	GetLevel() Level
 	SetOutput(w io.Writer)
 	SetStreamer(streamer LogStreamer)
+	StreamStats() StreamStats
 	Close()
 }
 
 // Config holds logger configuration
 type Config struct {
 	Level            Level
-	Source           string // Component identifier (e.g., "orchestrator", "node-agent:node123")
-	OrchestratorAddr string // Address to stream logs to orchestrator (empty to disable streaming)
+	Source           string       // Component identifier (e.g., "orchestrator", "node-agent:node123")
+	OrchestratorAddr string       // Address to stream logs to orchestrator (empty to disable streaming)
+	Stream           StreamConfig // Queueing and rate limiting of streamed entries
 }
 
 // orchionLogger implements the Logger interface
@@ -77,6 +97,8 @@ type orchionLogger struct {
 	logger   *logrus.Logger
 	source   string
 	streamer LogStreamer
+	queue    *streamQueue
+	config   StreamConfig
 	fields   map[string]interface{}
 }
 
@@ -85,6 +107,7 @@ func NewLogger(config Config) Logger {
 	logger := &orchionLogger{
 		logger: logrus.New(),
 		source: config.Source,
+		config: config.Stream,
 		fields: make(map[string]interface{}),
 	}
 
@@ -111,9 +134,22 @@ func NewLogger(config Config) Logger {
 	return logger
 }
 
-// SetStreamer sets the log streamer for this logger
+// SetStreamer sets the log streamer for this logger. Entries reach the
+// streamer through a bounded, rate limited queue so logging never blocks on it.
 func (l *orchionLogger) SetStreamer(streamer LogStreamer) {
 	l.streamer = streamer
+	l.queue = nil
+	if streamer != nil {
+		l.queue = newStreamQueue(streamer, l.config)
+	}
+}
+
+// StreamStats returns counters of streamed and dropped log entries
+func (l *orchionLogger) StreamStats() StreamStats {
+	if l.queue == nil {
+		return StreamStats{}
+	}
+	return l.queue.stats()
 }
 
 // log sends a log entry both to local output and streamer
@@ -142,8 +178,8 @@ func (l *orchionLogger) log(level Level, msg string, fields map[string]interface
 		entry.Error(msg)
 	}
 
-	// Send to streamer if available
-	if l.streamer != nil {
+	// Queue for the streamer if available
+	if l.queue != nil {
 		logEntry := &LogEntry{
 			ID:        fmt.Sprintf("%d-%s", time.Now().UnixMilli(), l.source),
 			Timestamp: time.Now().UnixMilli(),
@@ -153,13 +189,9 @@ func (l *orchionLogger) log(level Level, msg string, fields map[string]interface
 			Fields:    l.convertFields(allFields),
 		}
 
-		// Send asynchronously (don't block logging on network issues)
-		go func() {
-			if err := l.streamer.Stream(logEntry); err != nil {
-				// Log locally that streaming failed, but don't spam
-				l.logger.WithError(err).WithField("source", l.source).Debug("Failed to stream log entry")
-			}
-		}()
+		// Entries that are rate limited or don't fit in the queue are counted
+		// and reported to the streamer once it catches up
+		l.queue.enqueue(logEntry)
 	}
 }
 
@@ -193,6 +225,8 @@ func (l *orchionLogger) WithField(key string, value interface{}) Logger {
 		logger:   l.logger,
 		source:   l.source,
 		streamer: l.streamer,
+		queue:    l.queue,
+		config:   l.config,
 		fields:   make(map[string]interface{}),
 	}
 
@@ -212,6 +246,8 @@ func (l *orchionLogger) WithFields(fields map[string]interface{}) Logger {
 		logger:   l.logger,
 		source:   l.source,
 		streamer: l.streamer,
+		queue:    l.queue,
+		config:   l.config,
 		fields:   make(map[string]interface{}),
 	}
 
@@ -245,12 +281,25 @@ func (l *orchionLogger) SetLevel(level Level) {
 	l.logger.SetLevel(logrusLevel)
 }
 
+func (l *orchionLogger) GetLevel() Level {
+	switch l.logger.GetLevel() {
+	case logrus.DebugLevel, logrus.TraceLevel:
+		return DebugLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
 func (l *orchionLogger) SetOutput(w io.Writer) {
 	l.logger.SetOutput(w)
 }
 
 func (l *orchionLogger) Close() {
-	if l.streamer != nil {
-		l.streamer.Close()
+	if l.queue != nil {
+		l.queue.close()
 	}
 }
@@ -425,4 +425,44 @@ func BenchmarkOrchionLogger_WithField(b *testing.B) {
 		fieldLogger := logger.WithField("request_id", i)
 		fieldLogger.Info("benchmark with field", nil)
 	}
-}
\ No newline at end of file
+}
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", DebugLevel, false},
+		{"INFO", InfoLevel, false},
+		{"warn", WarnLevel, false},
+		{"warning", WarnLevel, false},
+		{" error ", ErrorLevel, false},
+		{"verbose", InfoLevel, true},
+		{"", InfoLevel, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLevel(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOrchionLogger_GetLevel(t *testing.T) {
+	logger := NewLogger(Config{Level: WarnLevel, Source: "test"})
+	assert.Equal(t, WarnLevel, logger.GetLevel())
+
+	logger.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+
+	// Derived loggers share the level
+	derived := logger.WithField("component", "test")
+	derived.SetLevel(ErrorLevel)
+	assert.Equal(t, ErrorLevel, logger.GetLevel())
+}
@@ -0,0 +1,270 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StreamConfig controls how log entries are queued for the streamer. Zero
+// values select the defaults.
+type StreamConfig struct {
+	QueueSize     int     // Entries buffered for the streamer; newer entries are dropped when full
+	BatchSize     int     // Maximum entries handed to the streamer at once
+	RatePerSource float64 // Entries per second streamed per source (negative for unlimited)
+	Burst         int     // Entries a source may stream at once above its rate
+}
+
+// DefaultStreamConfig is used for zero StreamConfig values
+var DefaultStreamConfig = StreamConfig{
+	QueueSize:     1024,
+	BatchSize:     64,
+	RatePerSource: 100,
+	Burst:         200,
+}
+
+// withDefaults fills zero values from DefaultStreamConfig
+func (c StreamConfig) withDefaults() StreamConfig {
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultStreamConfig.QueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultStreamConfig.BatchSize
+	}
+	if c.RatePerSource == 0 {
+		c.RatePerSource = DefaultStreamConfig.RatePerSource
+	}
+	if c.Burst <= 0 {
+		c.Burst = DefaultStreamConfig.Burst
+	}
+	return c
+}
+
+// BatchStreamer is implemented by streamers that can send several entries at once
+type BatchStreamer interface {
+	StreamBatch(entries []*LogEntry) error
+}
+
+// StreamStats counts what happened to log entries sent to the streamer
+type StreamStats struct {
+	Streamed    uint64 `json:"streamed"`     // Entries accepted by the streamer
+	Dropped     uint64 `json:"dropped"`      // Entries dropped because the queue was full
+	RateLimited uint64 `json:"rate_limited"` // Entries dropped by the per-source rate limit
+	Failed      uint64 `json:"failed"`       // Entries the streamer returned an error for
+}
+
+// streamQueue decouples logging from a streamer. Entries are rate limited per
+// source and buffered in a bounded queue drained by a single worker, so a slow
+// or unreachable streamer never blocks logging and an error storm can't spawn
+// unbounded goroutines.
+type streamQueue struct {
+	streamer LogStreamer
+	config   StreamConfig
+	entries  chan *LogEntry
+	stop     chan struct{}
+	done     chan struct{}
+	closed   atomic.Bool
+	once     sync.Once
+	now      func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*tokenBucket
+
+	streamed    atomic.Uint64
+	dropped     atomic.Uint64
+	rateLimited atomic.Uint64
+	failed      atomic.Uint64
+
+	// Losses already reported to the streamer
+	reportedDropped     uint64
+	reportedRateLimited uint64
+}
+
+// newStreamQueue starts a queue feeding the given streamer
+func newStreamQueue(streamer LogStreamer, config StreamConfig) *streamQueue {
+	config = config.withDefaults()
+	q := &streamQueue{
+		streamer: streamer,
+		config:   config,
+		entries:  make(chan *LogEntry, config.QueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		now:      time.Now,
+		limiters: make(map[string]*tokenBucket),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues an entry without blocking. It returns false if the entry was
+// rate limited or the queue is full.
+func (q *streamQueue) enqueue(entry *LogEntry) bool {
+	if q.closed.Load() {
+		q.dropped.Add(1)
+		return false
+	}
+	if !q.allow(entry.Source) {
+		q.rateLimited.Add(1)
+		return false
+	}
+
+	select {
+	case q.entries <- entry:
+		return true
+	default:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// allow applies the per-source rate limit
+func (q *streamQueue) allow(source string) bool {
+	if q.config.RatePerSource < 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	bucket, ok := q.limiters[source]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(q.config.Burst), last: q.now()}
+		q.limiters[source] = bucket
+	}
+	return bucket.take(q.now(), q.config.RatePerSource, float64(q.config.Burst))
+}
+
+// run sends queued entries to the streamer in batches until the queue is closed
+func (q *streamQueue) run() {
+	defer close(q.done)
+
+	for {
+		select {
+		case entry := <-q.entries:
+			q.send(q.collect(entry))
+		case <-q.stop:
+			// Flush what is already queued
+			for {
+				select {
+				case entry := <-q.entries:
+					q.send(q.collect(entry))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect builds a batch from first plus any entries already waiting
+func (q *streamQueue) collect(first *LogEntry) []*LogEntry {
+	batch := []*LogEntry{first}
+	for len(batch) < q.config.BatchSize {
+		select {
+		case entry := <-q.entries:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// send hands a batch to the streamer, preceded by a warning if entries were
+// lost since the last batch. The warning isn't counted in the stats.
+func (q *streamQueue) send(batch []*LogEntry) {
+	notice := q.lossNotice(batch[0].Source)
+
+	if bs, ok := q.streamer.(BatchStreamer); ok {
+		entries := batch
+		if notice != nil {
+			entries = append([]*LogEntry{notice}, batch...)
+		}
+		if err := bs.StreamBatch(entries); err != nil {
+			q.failed.Add(uint64(len(batch)))
+			return
+		}
+		q.streamed.Add(uint64(len(batch)))
+		return
+	}
+
+	if notice != nil {
+		q.streamer.Stream(notice)
+	}
+	for _, entry := range batch {
+		if err := q.streamer.Stream(entry); err != nil {
+			q.failed.Add(1)
+			continue
+		}
+		q.streamed.Add(1)
+	}
+}
+
+// lossNotice returns a warning entry summarizing entries dropped since the
+// last notice, or nil if none were
+func (q *streamQueue) lossNotice(source string) *LogEntry {
+	dropped := q.dropped.Load() - q.reportedDropped
+	rateLimited := q.rateLimited.Load() - q.reportedRateLimited
+	if dropped == 0 && rateLimited == 0 {
+		return nil
+	}
+	q.reportedDropped += dropped
+	q.reportedRateLimited += rateLimited
+
+	now := q.now()
+	return &LogEntry{
+		ID:        fmt.Sprintf("%d-%s", now.UnixMilli(), source),
+		Timestamp: now.UnixMilli(),
+		Level:     WarnLevel,
+		Source:    source,
+		Message:   "Log entries were dropped before streaming",
+		Fields: map[string]string{
+			"dropped":      fmt.Sprintf("%d", dropped),
+			"rate_limited": fmt.Sprintf("%d", rateLimited),
+		},
+	}
+}
+
+// stats returns the queue counters
+func (q *streamQueue) stats() StreamStats {
+	return StreamStats{
+		Streamed:    q.streamed.Load(),
+		Dropped:     q.dropped.Load(),
+		RateLimited: q.rateLimited.Load(),
+		Failed:      q.failed.Load(),
+	}
+}
+
+// close flushes queued entries and closes the streamer
+func (q *streamQueue) close() error {
+	var err error
+	q.once.Do(func() {
+		q.closed.Store(true)
+		close(q.stop)
+		<-q.done
+		err = q.streamer.Close()
+	})
+	return err
+}
+
+// tokenBucket is a simple token bucket rate limiter
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time elapsed and takes one token if available
+func (b *tokenBucket) take(now time.Time, rate, burst float64) bool {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStreamer records streamed entries. If gate is set, each call waits
+// for it so tests can hold the worker.
+type recordingStreamer struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+	batches int
+	gate    chan struct{}
+	closed  bool
+}
+
+func (s *recordingStreamer) Stream(entry *LogEntry) error {
+	return s.StreamBatch([]*LogEntry{entry})
+}
+
+func (s *recordingStreamer) StreamBatch(entries []*LogEntry) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	s.batches++
+	return nil
+}
+
+func (s *recordingStreamer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *recordingStreamer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []string
+	for _, e := range s.entries {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func TestStreamQueue_RateLimitPerSource(t *testing.T) {
+	streamer := &recordingStreamer{}
+	q := newStreamQueue(streamer, StreamConfig{RatePerSource: 1, Burst: 2})
+
+	now := time.Unix(1700000000, 0)
+	q.now = func() time.Time { return now }
+
+	assert.True(t, q.allow("node-a"))
+	assert.True(t, q.allow("node-a"))
+	assert.False(t, q.allow("node-a"), "burst exhausted")
+	assert.True(t, q.allow("node-b"), "sources are limited independently")
+
+	now = now.Add(time.Second)
+	assert.True(t, q.allow("node-a"), "refilled after a second")
+	assert.False(t, q.allow("node-a"))
+
+	require.NoError(t, q.close())
+}
+
+func TestStreamQueue_DropsWhenFull(t *testing.T) {
+	streamer := &recordingStreamer{gate: make(chan struct{})}
+	q := newStreamQueue(streamer, StreamConfig{QueueSize: 2, BatchSize: 1, RatePerSource: -1})
+
+	// The first entry is picked up by the worker, which then blocks on the gate
+	require.True(t, q.enqueue(&LogEntry{Source: "test", Message: "first"}))
+	assert.Eventually(t, func() bool { return len(q.entries) == 0 }, time.Second, time.Millisecond)
+
+	assert.True(t, q.enqueue(&LogEntry{Source: "test", Message: "second"}))
+	assert.True(t, q.enqueue(&LogEntry{Source: "test", Message: "third"}))
+	assert.False(t, q.enqueue(&LogEntry{Source: "test", Message: "fourth"}))
+
+	close(streamer.gate)
+	require.NoError(t, q.close())
+
+	// The loss is reported ahead of the next batch
+	assert.Equal(t, []string{"first", "Log entries were dropped before streaming", "second", "third"}, streamer.messages())
+	assert.Equal(t, "1", streamer.entries[1].Fields["dropped"])
+
+	stats := q.stats()
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, uint64(3), stats.Streamed)
+	assert.True(t, streamer.closed)
+}
+
+func TestStreamQueue_Batches(t *testing.T) {
+	streamer := &recordingStreamer{gate: make(chan struct{})}
+	q := newStreamQueue(streamer, StreamConfig{BatchSize: 10, RatePerSource: -1})
+
+	require.True(t, q.enqueue(&LogEntry{Source: "test", Message: "first"}))
+	assert.Eventually(t, func() bool { return len(q.entries) == 0 }, time.Second, time.Millisecond)
+	for i := 0; i < 5; i++ {
+		require.True(t, q.enqueue(&LogEntry{Source: "test", Message: "queued"}))
+	}
+
+	close(streamer.gate)
+	require.NoError(t, q.close())
+
+	assert.Len(t, streamer.entries, 6)
+	assert.Equal(t, 2, streamer.batches, "entries queued behind a slow streamer are sent together")
+}
+
+func TestStreamQueue_EnqueueAfterClose(t *testing.T) {
+	q := newStreamQueue(&recordingStreamer{}, StreamConfig{})
+	require.NoError(t, q.close())
+	require.NoError(t, q.close(), "close is idempotent")
+
+	assert.False(t, q.enqueue(&LogEntry{Source: "test"}))
+	assert.Equal(t, uint64(1), q.stats().Dropped)
+}
+
+func TestOrchionLogger_StreamStats(t *testing.T) {
+	logger := NewLogger(Config{
+		Level:  InfoLevel,
+		Source: "test",
+		Stream: StreamConfig{RatePerSource: 1, Burst: 1},
+	})
+	logger.SetOutput(&recordingWriter{})
+	assert.Equal(t, StreamStats{}, logger.StreamStats())
+
+	logger.SetStreamer(&recordingStreamer{})
+	logger.Info("allowed", nil)
+	logger.Info("limited", nil)
+	logger.Close()
+
+	stats := logger.StreamStats()
+	assert.Equal(t, uint64(1), stats.RateLimited)
+	assert.Equal(t, uint64(1), stats.Streamed)
+}
+
+type recordingWriter struct{}
+
+func (recordingWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
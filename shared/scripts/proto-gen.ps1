@@ -15,7 +15,7 @@ if (-not (Test-ProtocInstalled)) {
 
 # Generate protobuf for Go components
 foreach ($component in $script:Components.Go) {
-    if ($component -ne 'shared/logging') {  # shared/logging doesn't have protobuf
+    if ($component -notlike 'shared/*') {  # shared libraries don't have protobuf
         Generate-Protobuf -Component $component
         Write-Host ""
     }
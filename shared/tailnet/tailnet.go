@@ -0,0 +1,186 @@
+// Package tailnet finds this host's addresses on WireGuard overlay networks,
+// Tailscale's or plain WireGuard interfaces, so the orchestrator and agents
+// can advertise them, prefer them and listen on them only.
+//
+// Traffic between tailnet addresses is encrypted by WireGuard, so services
+// bound to them are reachable by the tailnet's peers only, without TLS.
+package tailnet
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Name is the bind host meaning this host's tailnet address
+const Name = "tailnet"
+
+// Kinds of tailnet
+const (
+	KindTailscale = "tailscale"
+	KindWireGuard = "wireguard"
+)
+
+var (
+	// tailscaleV4 and tailscaleV6 are the ranges Tailscale assigns node
+	// addresses from
+	tailscaleV4 = netip.MustParsePrefix("100.64.0.0/10")
+	tailscaleV6 = netip.MustParsePrefix("fd7a:115c:a1e0::/48")
+)
+
+// Interface is a network interface and its addresses
+type Interface struct {
+	Name  string
+	Up    bool
+	Addrs []netip.Addr
+}
+
+// Address is an address on a tailnet
+type Address struct {
+	Interface string
+	IP        netip.Addr
+	Kind      string
+}
+
+// interfaces lists this host's network interfaces, replaced in tests
+var interfaces = func() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	result := make([]Interface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		i := Interface{Name: iface.Name, Up: iface.Flags&net.FlagUp != 0}
+		for _, addr := range addrs {
+			prefix, err := netip.ParsePrefix(addr.String())
+			if err != nil {
+				continue
+			}
+			i.Addrs = append(i.Addrs, prefix.Addr().Unmap())
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// IsTailscale reports whether ip is in the ranges Tailscale assigns
+// addresses from
+func IsTailscale(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return tailscaleV4.Contains(ip) || tailscaleV6.Contains(ip)
+}
+
+// isWireGuard reports whether an interface name is one WireGuard tools
+// give their interfaces, e.g. wg0
+func isWireGuard(name string) bool {
+	return strings.HasPrefix(name, "wg")
+}
+
+// Find returns the tailnet addresses of the interfaces that are up:
+// addresses in Tailscale's ranges, whatever the interface is called (utun
+// on macOS, Tailscale on Windows), and those of WireGuard interfaces.
+// Tailscale addresses come first, and IPv4 addresses before IPv6 ones.
+func Find(ifaces []Interface) []Address {
+	var found []Address
+	for _, iface := range ifaces {
+		if !iface.Up {
+			continue
+		}
+		for _, ip := range iface.Addrs {
+			if ip.IsLinkLocalUnicast() || ip.IsLoopback() {
+				continue
+			}
+			switch {
+			case IsTailscale(ip):
+				found = append(found, Address{Interface: iface.Name, IP: ip, Kind: KindTailscale})
+			case isWireGuard(iface.Name):
+				found = append(found, Address{Interface: iface.Name, IP: ip, Kind: KindWireGuard})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Kind != found[j].Kind {
+			return found[i].Kind == KindTailscale
+		}
+		return found[i].IP.Is4() && !found[j].IP.Is4()
+	})
+	return found
+}
+
+// Detect returns this host's tailnet addresses, as Find orders them
+func Detect() ([]Address, error) {
+	ifaces, err := interfaces()
+	if err != nil {
+		return nil, err
+	}
+	return Find(ifaces), nil
+}
+
+// ResolveHost resolves the host part of a listen address. Name resolves to
+// this host's first tailnet address and an interface name such as
+// tailscale0 or wg0 to the interface's first address, IPv4 preferred. IPs,
+// hostnames and the empty host, meaning all interfaces, are returned as
+// they are.
+func ResolveHost(host string) (string, error) {
+	if host == "" {
+		return "", nil
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return host, nil
+	}
+
+	ifaces, err := interfaces()
+	if err != nil {
+		return "", err
+	}
+	if host == Name {
+		found := Find(ifaces)
+		if len(found) == 0 {
+			return "", errors.New("no tailnet address found: is Tailscale or WireGuard up on this host?")
+		}
+		return found[0].IP.String(), nil
+	}
+	for _, iface := range ifaces {
+		if iface.Name != host {
+			continue
+		}
+		var first netip.Addr
+		for _, ip := range iface.Addrs {
+			if ip.IsLinkLocalUnicast() {
+				continue
+			}
+			if ip.Is4() {
+				return ip.String(), nil
+			}
+			if !first.IsValid() {
+				first = ip
+			}
+		}
+		if !first.IsValid() {
+			return "", fmt.Errorf("interface %s has no address", host)
+		}
+		return first.String(), nil
+	}
+	return host, nil
+}
+
+// ResolveAddr resolves the host part of a host:port listen address with
+// ResolveHost
+func ResolveAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	host, err = ResolveHost(host)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,120 @@
+package tailnet
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addrs(ips ...string) []netip.Addr {
+	result := make([]netip.Addr, len(ips))
+	for i, ip := range ips {
+		result[i] = netip.MustParseAddr(ip)
+	}
+	return result
+}
+
+// fakeInterfaces replaces this host's interfaces for the test
+func fakeInterfaces(t *testing.T, ifaces []Interface, err error) {
+	t.Helper()
+	original := interfaces
+	interfaces = func() ([]Interface, error) { return ifaces, err }
+	t.Cleanup(func() { interfaces = original })
+}
+
+var host = []Interface{
+	{Name: "lo", Up: true, Addrs: addrs("127.0.0.1", "::1")},
+	{Name: "eth0", Up: true, Addrs: addrs("192.168.1.20", "fe80::1")},
+	{Name: "wg0", Up: true, Addrs: addrs("10.8.0.2")},
+	{Name: "tailscale0", Up: true, Addrs: addrs("fd7a:115c:a1e0::12", "fe80::2", "100.101.102.103")},
+	{Name: "wg1", Up: false, Addrs: addrs("10.9.0.2")},
+}
+
+func TestIsTailscale(t *testing.T) {
+	assert.True(t, IsTailscale(netip.MustParseAddr("100.64.0.1")))
+	assert.True(t, IsTailscale(netip.MustParseAddr("100.127.255.254")))
+	assert.True(t, IsTailscale(netip.MustParseAddr("::ffff:100.100.1.1")))
+	assert.True(t, IsTailscale(netip.MustParseAddr("fd7a:115c:a1e0:ab12::1")))
+	assert.False(t, IsTailscale(netip.MustParseAddr("100.128.0.1")))
+	assert.False(t, IsTailscale(netip.MustParseAddr("10.0.0.1")))
+	assert.False(t, IsTailscale(netip.MustParseAddr("fd00::1")))
+}
+
+func TestFind(t *testing.T) {
+	found := Find(host)
+
+	assert.Equal(t, []Address{
+		{Interface: "tailscale0", IP: netip.MustParseAddr("100.101.102.103"), Kind: KindTailscale},
+		{Interface: "tailscale0", IP: netip.MustParseAddr("fd7a:115c:a1e0::12"), Kind: KindTailscale},
+		{Interface: "wg0", IP: netip.MustParseAddr("10.8.0.2"), Kind: KindWireGuard},
+	}, found)
+}
+
+func TestFind_tailscaleOnAnyInterface(t *testing.T) {
+	found := Find([]Interface{{Name: "utun4", Up: true, Addrs: addrs("100.70.1.2")}})
+
+	require.Len(t, found, 1)
+	assert.Equal(t, KindTailscale, found[0].Kind)
+	assert.Empty(t, Find([]Interface{{Name: "utun4", Up: true, Addrs: addrs("10.0.0.2")}}))
+}
+
+func TestResolveHost(t *testing.T) {
+	fakeInterfaces(t, host, nil)
+
+	for in, want := range map[string]string{
+		"":                 "",
+		"0.0.0.0":          "0.0.0.0",
+		"10.0.0.1":         "10.0.0.1",
+		"::1":              "::1",
+		"localhost":        "localhost",
+		"tailnet":          "100.101.102.103",
+		"tailscale0":       "100.101.102.103",
+		"wg0":              "10.8.0.2",
+		"eth0":             "192.168.1.20",
+		"orchestrator.lan": "orchestrator.lan",
+	} {
+		got, err := ResolveHost(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+}
+
+func TestResolveHost_errors(t *testing.T) {
+	fakeInterfaces(t, []Interface{
+		{Name: "eth0", Up: true, Addrs: addrs("192.168.1.20")},
+		{Name: "wg0", Up: true, Addrs: addrs("fe80::3")},
+	}, nil)
+
+	_, err := ResolveHost(Name)
+	assert.Error(t, err, "no tailnet is up")
+	_, err = ResolveHost("wg0")
+	assert.Error(t, err, "the interface has a link-local address only")
+
+	fakeInterfaces(t, nil, errors.New("permission denied"))
+	_, err = ResolveHost(Name)
+	assert.Error(t, err)
+}
+
+func TestResolveAddr(t *testing.T) {
+	fakeInterfaces(t, host, nil)
+
+	addr, err := ResolveAddr("tailnet:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "100.101.102.103:8080", addr)
+
+	addr, err = ResolveAddr(":8080")
+	require.NoError(t, err)
+	assert.Equal(t, ":8080", addr)
+
+	_, err = ResolveAddr("tailnet")
+	assert.Error(t, err, "a port is required")
+}
+
+func TestDetect(t *testing.T) {
+	// This host's interfaces can be listed, whether or not it's on a tailnet
+	_, err := Detect()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,154 @@
+// Package units parses and formats the human-readable readings agents report
+// as capabilities, such as "15.9 GB", "8 cores", "65°C" or "120.5 W".
+//
+// Parsing is lenient about how the reading was written: "15.9 GB", "15,9 GB",
+// "15.9GB", "15.9 GiB" and "15,9 Go" all read the same, so readings produced
+// by tools running under another locale don't silently turn into garbage.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Binary size multiples, which is what agents mean by KB, MB, GB and TB
+const (
+	KB = 1 << 10
+	MB = 1 << 20
+	GB = 1 << 30
+	TB = 1 << 40
+)
+
+// sizeUnits maps lowercase size units, including the French octet spellings,
+// to their size in bytes
+var sizeUnits = map[string]float64{
+	"b": 1, "byte": 1, "bytes": 1, "o": 1,
+	"k": KB, "kb": KB, "kib": KB, "ko": KB,
+	"m": MB, "mb": MB, "mib": MB, "mo": MB,
+	"g": GB, "gb": GB, "gib": GB, "go": GB,
+	"t": TB, "tb": TB, "tib": TB, "to": TB,
+}
+
+// Unknown reports whether a reading says it isn't available, as agents do
+// with "N/A" or "Unknown"
+func Unknown(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "n/a", "na", "unknown", "none":
+		return true
+	}
+	return false
+}
+
+// ParseNumber parses the number at the start of a reading such as "65°C",
+// "120.5 W" or "15,9 GB"
+func ParseNumber(s string) (float64, bool) {
+	value, _, ok := parse(s)
+	return value, ok
+}
+
+// ParseMegabytes parses a size such as "7.5 GB" or "512 MB" into megabytes.
+// Sizes without a unit are taken to be in megabytes, as nvidia-smi reports
+// them.
+func ParseMegabytes(s string) (float64, bool) {
+	bytes, ok := parseSize(s, MB)
+	return bytes / MB, ok
+}
+
+// ParseBytes parses a size such as "15.9 GB" into bytes. Sizes without a unit
+// are taken to be in bytes.
+func ParseBytes(s string) (int64, bool) {
+	bytes, ok := parseSize(s, 1)
+	if !ok || math.Abs(bytes) > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(math.Round(bytes)), true
+}
+
+// FormatMegabytes formats a size in megabytes the way agents report sizes,
+// e.g. "15.9 GB"
+func FormatMegabytes(mb float64) string {
+	return fmt.Sprintf("%.1f GB", mb/1024)
+}
+
+// FormatBytes formats a size in bytes the way agents report sizes, e.g.
+// "15.9 GB"
+func FormatBytes(bytes int64) string {
+	return FormatMegabytes(float64(bytes) / MB)
+}
+
+// ParseCores parses a CPU reading such as "8 cores" into the number of cores.
+// Fractional counts don't parse.
+func ParseCores(s string) (int, bool) {
+	value, ok := ParseNumber(s)
+	if !ok || value != math.Trunc(value) || math.Abs(value) > math.MaxInt32 {
+		return 0, false
+	}
+	return int(value), true
+}
+
+// FormatCores formats a number of cores the way agents report it, e.g.
+// "8 cores"
+func FormatCores(cores int) string {
+	return fmt.Sprintf("%d cores", cores)
+}
+
+// parseSize parses a size into bytes, using defaultUnit for sizes without a
+// unit. Unknown units don't parse.
+func parseSize(s string, defaultUnit float64) (float64, bool) {
+	value, unit, ok := parse(s)
+	if !ok {
+		return 0, false
+	}
+	if unit == "" {
+		return value * defaultUnit, true
+	}
+	multiple, ok := sizeUnits[unit]
+	if !ok {
+		return 0, false
+	}
+	return value * multiple, true
+}
+
+// parse splits a reading into its leading number and the lowercase unit
+// following it, if any, e.g. 15.9 and "gb" for "15,9 GB (approximate)"
+func parse(s string) (float64, string, bool) {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && strings.IndexByte("0123456789.,+-", s[end]) >= 0 {
+		end++
+	}
+	if end == 0 {
+		return 0, "", false
+	}
+
+	value, err := strconv.ParseFloat(normalizeSeparators(s[:end]), 64)
+	if err != nil || math.IsInf(value, 0) {
+		return 0, "", false
+	}
+
+	rest := strings.TrimLeftFunc(s[end:], unicode.IsSpace)
+	unitEnd := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if unitEnd < 0 {
+		unitEnd = len(rest)
+	}
+	return value, strings.ToLower(rest[:unitEnd]), true
+}
+
+// normalizeSeparators rewrites a number using either "." or "," as its
+// decimal separator into the form strconv expects. A separator appearing
+// more than once, or before the last different one, groups thousands: both
+// "1,024.5" and "1.024,5" read as 1024.5, and "1.024.000" as 1024000.
+func normalizeSeparators(num string) string {
+	last := strings.LastIndexAny(num, ".,")
+	if last < 0 {
+		return num
+	}
+	if strings.Count(num, num[last:last+1]) > 1 {
+		return strings.NewReplacer(".", "", ",", "").Replace(num)
+	}
+	whole := strings.NewReplacer(".", "", ",", "").Replace(num[:last])
+	return whole + "." + num[last+1:]
+}
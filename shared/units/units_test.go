@@ -0,0 +1,138 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"65°C", 65, true},
+		{"120.5 W", 120.5, true},
+		{"120,5 W", 120.5, true},
+		{" -3.5", -3.5, true},
+		{"1,024.5 MB", 1024.5, true},
+		{"1.024,5 MB", 1024.5, true},
+		{"1.024.000", 1024000, true},
+		{"N/A", 0, false},
+		{"Unknown", 0, false},
+		{"", 0, false},
+		{"-", 0, false},
+		{"1-2", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseNumber(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 0.001)
+		})
+	}
+}
+
+func TestParseMegabytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"7.5 GB", 7680, true},
+		{"7,5 GB", 7680, true},
+		{"7.5GB", 7680, true},
+		{"7.5 GiB", 7680, true},
+		{"7,5 Go", 7680, true},
+		{"7.5 gb", 7680, true},
+		{"512 MB", 512, true},
+		{"512 MiB", 512, true},
+		{"512", 512, true},
+		{"2048 KB", 2, true},
+		{"1 TB", 1024 * 1024, true},
+		{"15.50 GB (approximate)", 15872, true},
+		{"8 cores", 0, false},
+		{"N/A", 0, false},
+		{"Unknown", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseMegabytes(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 0.001)
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"15.9 GB", 17072495002, true},
+		{"15,9 GB", 17072495002, true},
+		{"1 KB", 1024, true},
+		{"512", 512, true},
+		{"512 B", 512, true},
+		{"99999999999 TB", 0, false},
+		{"N/A", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseBytes(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatSizes(t *testing.T) {
+	assert.Equal(t, "15.9 GB", FormatBytes(17072495002))
+	assert.Equal(t, "7.5 GB", FormatMegabytes(7680))
+	assert.Equal(t, "0.0 GB", FormatMegabytes(0))
+
+	// Formatted sizes parse back to what they say
+	mb, ok := ParseMegabytes(FormatMegabytes(40960))
+	assert.True(t, ok)
+	assert.InDelta(t, 40960, mb, 0.001)
+}
+
+func TestParseCores(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"8 cores", 8, true},
+		{"8", 8, true},
+		{"16 Kerne", 16, true},
+		{"0 cores", 0, true},
+		{"2.5 cores", 0, false},
+		{"99999999999 cores", 0, false},
+		{"many", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseCores(tt.in)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	assert.Equal(t, "8 cores", FormatCores(8))
+}
+
+func TestUnknown(t *testing.T) {
+	for _, s := range []string{"", " ", "N/A", "na", "Unknown", "none"} {
+		assert.True(t, Unknown(s), s)
+	}
+	for _, s := range []string{"0 GB", "8 cores", "65°C"} {
+		assert.False(t, Unknown(s), s)
+	}
+}
@@ -101,9 +101,10 @@ func TestAPI_Contract_Validation(t *testing.T) {
 	// Test SubmitJob contract
 	t.Run("SubmitJob contract", func(t *testing.T) {
 		jobReq := &pb.SubmitJobRequest{
-			JobId:   "contract-test-job",
-			JobType: pb.JobType_JOB_TYPE_CHAT_COMPLETION,
-			Payload: []byte(`{"model":"test","messages":[{"role":"user","content":"test"}]}`),
+			JobId:       "contract-test-job",
+			JobType:     pb.JobType_JOB_TYPE_CHAT_COMPLETION,
+			Payload:     []byte(`{"model":"test","messages":[{"role":"user","content":"test"}]}`),
+			ContentType: "application/json",
 		}
 
 		resp, err := ts.service.SubmitJob(ctx, jobReq)